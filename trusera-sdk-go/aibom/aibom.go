@@ -0,0 +1,207 @@
+// Package aibom assembles an AI bill of materials: a CycloneDX 1.6 ML-BOM
+// describing the models, datasets, tools and external services an agent
+// uses. A Builder collects them from explicit registrations and from the
+// events the agent tracks:
+//
+//	bom := aibom.NewBuilder("support-agent", "1.4.0")
+//	client := trusera.NewClient(apiKey, trusera.WithEventHook(bom.Hook()))
+//	...
+//	bom.Build().WriteJSON(os.Stdout)
+package aibom
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// SpecVersion is the CycloneDX version of generated documents
+const SpecVersion = "1.6"
+
+const xmlNamespace = "http://cyclonedx.org/schema/bom/" + SpecVersion
+
+// Component types used in the BOM
+const (
+	TypeApplication = "application"
+	TypeModel       = "machine-learning-model"
+	TypeData        = "data"
+)
+
+// BOM is a CycloneDX document
+type BOM struct {
+	XMLName      xml.Name   `json:"-" xml:"bom"`
+	XMLNS        string     `json:"-" xml:"xmlns,attr"`
+	BOMFormat    string     `json:"bomFormat" xml:"-"`
+	SpecVersion  string     `json:"specVersion" xml:"-"`
+	SerialNumber string     `json:"serialNumber" xml:"serialNumber,attr"`
+	Version      int        `json:"version" xml:"version,attr"`
+	Metadata     Metadata   `json:"metadata" xml:"metadata"`
+	Components   Components `json:"components,omitempty" xml:"components,omitempty"`
+	Services     Services   `json:"services,omitempty" xml:"services,omitempty"`
+}
+
+// Metadata describes the BOM and the agent it covers
+type Metadata struct {
+	Timestamp string     `json:"timestamp" xml:"timestamp"`
+	Tools     *Tools     `json:"tools,omitempty" xml:"tools,omitempty"`
+	Component *Component `json:"component,omitempty" xml:"component,omitempty"`
+}
+
+// Tools lists the software that produced the BOM
+type Tools struct {
+	Components Components `json:"components" xml:"components"`
+}
+
+// Component is a model, dataset, tool or the agent itself
+type Component struct {
+	Type        string     `json:"type" xml:"type,attr"`
+	BOMRef      string     `json:"bom-ref,omitempty" xml:"bom-ref,attr,omitempty"`
+	Publisher   string     `json:"publisher,omitempty" xml:"publisher,omitempty"`
+	Name        string     `json:"name" xml:"name"`
+	Version     string     `json:"version,omitempty" xml:"version,omitempty"`
+	Description string     `json:"description,omitempty" xml:"description,omitempty"`
+	Hashes      Hashes     `json:"hashes,omitempty" xml:"hashes,omitempty"`
+	Licenses    Licenses   `json:"licenses,omitempty" xml:"licenses,omitempty"`
+	Properties  Properties `json:"properties,omitempty" xml:"properties,omitempty"`
+}
+
+// Service is an external endpoint the agent calls
+type Service struct {
+	BOMRef     string     `json:"bom-ref,omitempty" xml:"bom-ref,attr,omitempty"`
+	Name       string     `json:"name" xml:"name"`
+	Endpoints  Endpoints  `json:"endpoints,omitempty" xml:"endpoints,omitempty"`
+	Properties Properties `json:"properties,omitempty" xml:"properties,omitempty"`
+}
+
+// Hash is a digest of a component, e.g. of model weights
+type Hash struct {
+	Alg     string `json:"alg" xml:"alg,attr"`
+	Content string `json:"content" xml:",chardata"`
+}
+
+// Property is a name-value pair; the SDK's names start with "trusera:"
+type Property struct {
+	Name  string `json:"name" xml:"name,attr"`
+	Value string `json:"value" xml:",chardata"`
+}
+
+// License identifies a license by SPDX ID or by name
+type License struct {
+	ID   string `json:"id,omitempty" xml:"id,omitempty"`
+	Name string `json:"name,omitempty" xml:"name,omitempty"`
+}
+
+// LicenseChoice wraps a License as CycloneDX's JSON format requires
+type LicenseChoice struct {
+	License License `json:"license"`
+}
+
+// The list types below encode as a wrapper element around one element per
+// item in XML, e.g. <hashes><hash>…</hash></hashes>, and as arrays in JSON.
+// Unlike "hashes>hash" field tags, empty lists are omitted.
+type (
+	Components []Component
+	Services   []Service
+	Hashes     []Hash
+	Licenses   []LicenseChoice
+	Properties []Property
+	Endpoints  []string
+)
+
+func (l Components) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeList(e, start, "component", l)
+}
+
+func (l Services) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeList(e, start, "service", l)
+}
+
+func (l Hashes) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeList(e, start, "hash", l)
+}
+
+func (l Licenses) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	licenses := make([]License, len(l))
+	for i, c := range l {
+		licenses[i] = c.License
+	}
+	return encodeList(e, start, "license", licenses)
+}
+
+func (l Properties) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeList(e, start, "property", l)
+}
+
+func (l Endpoints) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeList(e, start, "endpoint", l)
+}
+
+// encodeList writes items as name elements inside start
+func encodeList[T any](e *xml.Encoder, start xml.StartElement, name string, items []T) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := e.EncodeElement(item, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Property returns the value of a component property
+func (c Component) Property(name string) string {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// WriteJSON writes the BOM in CycloneDX's JSON format
+func (b *BOM) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return fmt.Errorf("failed to encode BOM: %w", err)
+	}
+	return nil
+}
+
+// WriteXML writes the BOM in CycloneDX's XML format
+func (b *BOM) WriteXML(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return fmt.Errorf("failed to encode BOM: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadJSON parses a BOM in CycloneDX's JSON format
+func ReadJSON(r io.Reader) (*BOM, error) {
+	var b BOM
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("failed to decode BOM: %w", err)
+	}
+	if b.BOMFormat != "CycloneDX" {
+		return nil, fmt.Errorf("not a CycloneDX document: bomFormat %q", b.BOMFormat)
+	}
+	return &b, nil
+}
+
+// newSerialNumber returns a random urn:uuid serial number
+func newSerialNumber() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
@@ -0,0 +1,74 @@
+package aibom
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testBOM() *BOM {
+	b := NewBuilder("agent", "1.0.0")
+	b.AddModel(Model{Name: "gpt-4o", Provider: "openai", WeightsHash: strings.Repeat("a", 64), License: "MIT"})
+	b.AddEndpoint(Endpoint{Name: "api.openai.com", URL: "https://api.openai.com"})
+	return b.Build()
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBOM().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["bomFormat"] != "CycloneDX" || doc["specVersion"] != "1.6" {
+		t.Errorf("unexpected header %v", doc)
+	}
+	if !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(doc["serialNumber"].(string)) {
+		t.Errorf("invalid serial number %v", doc["serialNumber"])
+	}
+	for _, want := range []string{`"type": "machine-learning-model"`, `"bom-ref": "model:gpt-4o"`, `"alg": "SHA-256"`, `"license": {`, `"id": "MIT"`, `"endpoints": [`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s in %s", want, buf.String())
+		}
+	}
+
+	parsed, err := ReadJSON(&buf)
+	if err != nil || len(parsed.Components) != 1 || parsed.Components[0].Licenses[0].License.ID != "MIT" {
+		t.Errorf("expected the document to round-trip, got %+v (%v)", parsed, err)
+	}
+	if _, err := ReadJSON(strings.NewReader(`{"bomFormat":"SPDX"}`)); err == nil {
+		t.Error("expected an error for other formats")
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testBOM().WriteXML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<bom xmlns="http://cyclonedx.org/schema/bom/1.6" serialNumber="urn:uuid:`,
+		`<component type="machine-learning-model" bom-ref="model:gpt-4o">`,
+		`<hash alg="SHA-256">` + strings.Repeat("a", 64) + `</hash>`,
+		`<licenses>`, `<license>`, `<id>MIT</id>`,
+		`<property name="trusera:provider">openai</property>`,
+		`<endpoint>https://api.openai.com</endpoint>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "bomFormat") {
+		t.Error("bomFormat is JSON only")
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Errorf("invalid XML: %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+package aibom
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// Model is a model the agent depends on
+type Model = trusera.ModelInfo
+
+// Dataset is a dataset or knowledge source the agent uses
+type Dataset = trusera.DatasetInfo
+
+// Tool is a tool the agent can call
+type Tool struct {
+	Name        string
+	Description string
+	Risk        string // e.g. "high"; see trusera.RiskTier
+}
+
+// Endpoint is an external service the agent calls
+type Endpoint struct {
+	Name string // Usually the host
+	URL  string
+}
+
+// Builder collects the contents of a BOM. It is safe for concurrent use.
+type Builder struct {
+	name, version string
+
+	mu        sync.Mutex
+	models    map[string]*entry[Model]
+	datasets  map[string]*entry[Dataset]
+	tools     map[string]*entry[Tool]
+	endpoints map[string]*entry[Endpoint]
+}
+
+// entry is a registered or observed BOM item
+type entry[T any] struct {
+	item     T
+	declared bool
+	observed int
+}
+
+// NewBuilder creates a builder for the agent with the given name and version
+func NewBuilder(agentName, agentVersion string) *Builder {
+	return &Builder{
+		name:      agentName,
+		version:   agentVersion,
+		models:    make(map[string]*entry[Model]),
+		datasets:  make(map[string]*entry[Dataset]),
+		tools:     make(map[string]*entry[Tool]),
+		endpoints: make(map[string]*entry[Endpoint]),
+	}
+}
+
+// AddModel declares a model
+func (b *Builder) AddModel(m Model) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	add(b.models, m.Name, m)
+}
+
+// AddClient declares the models, datasets and tools registered with a client
+func (b *Builder) AddClient(c *trusera.Client) {
+	for _, m := range c.Models() {
+		b.AddModel(m)
+	}
+	for _, d := range c.Datasets() {
+		b.AddDataset(d)
+	}
+	for _, t := range c.Tools() {
+		b.AddTool(Tool{Name: t.Name, Description: t.Description, Risk: string(t.Risk)})
+	}
+}
+
+// AddDataset declares a dataset
+func (b *Builder) AddDataset(d Dataset) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	add(b.datasets, d.Name, d)
+}
+
+// AddTool declares a tool
+func (b *Builder) AddTool(t Tool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	add(b.tools, t.Name, t)
+}
+
+// AddEndpoint declares an external service
+func (b *Builder) AddEndpoint(ep Endpoint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	add(b.endpoints, ep.Name, ep)
+}
+
+func add[T any](m map[string]*entry[T], key string, item T) {
+	e := m[key]
+	if e == nil {
+		e = &entry[T]{}
+		m[key] = e
+	}
+	e.item, e.declared = item, true
+}
+
+// observe counts a use of an item, creating it if it was not declared
+func observe[T any](m map[string]*entry[T], key string, item T) {
+	e := m[key]
+	if e == nil {
+		e = &entry[T]{item: item}
+		m[key] = e
+	}
+	e.observed++
+}
+
+// Observe adds what a tracked event reveals: the model of an LLM
+// invocation, the tool of a tool call, the dataset of a dataset access and
+// the host of any call with a URL
+func (b *Builder) Observe(event trusera.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch event.Type {
+	case trusera.EventLLMInvoke:
+		if model, _ := event.Payload["model"].(string); model != "" {
+			provider, _ := event.Payload["provider"].(string)
+			observe(b.models, b.modelKey(model), Model{Name: model, Provider: provider})
+		}
+	case trusera.EventToolCall:
+		if event.Name != "" {
+			observe(b.tools, event.Name, Tool{Name: event.Name})
+		}
+	case trusera.EventDatasetAccess:
+		if event.Name != "" {
+			source, _ := event.Payload["source"].(string)
+			version, _ := event.Payload["version"].(string)
+			observe(b.datasets, event.Name, Dataset{Name: event.Name, Source: source, Version: version})
+		}
+	}
+
+	if raw, _ := event.Payload["url"].(string); raw != "" {
+		if u, err := url.Parse(raw); err == nil && u.Host != "" {
+			observe(b.endpoints, u.Host, Endpoint{Name: u.Host, URL: u.Scheme + "://" + u.Host})
+		}
+	} else if host, _ := event.Payload["host"].(string); host != "" {
+		observe(b.endpoints, host, Endpoint{Name: host})
+	}
+}
+
+// modelKey maps an observed model name to a declared model it is a
+// snapshot of, such as gpt-4o-2024-08-06 of gpt-4o
+func (b *Builder) modelKey(name string) string {
+	if _, ok := b.models[name]; ok {
+		return name
+	}
+	best := ""
+	for key, e := range b.models {
+		if e.declared && strings.HasPrefix(name, key+"-") && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return name
+	}
+	return best
+}
+
+// Hook returns an event hook that observes every tracked event
+func (b *Builder) Hook() trusera.EventHook {
+	return func(e *trusera.Event) (*trusera.Event, error) {
+		b.Observe(*e)
+		return e, nil
+	}
+}
+
+// Build assembles the BOM
+func (b *Builder) Build() *BOM {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	bom := &BOM{
+		XMLNS:        xmlNamespace,
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  SpecVersion,
+		SerialNumber: newSerialNumber(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: &Tools{Components: []Component{
+				{Type: TypeApplication, Publisher: "Trusera", Name: "trusera-sdk-go"},
+			}},
+			Component: &Component{Type: TypeApplication, BOMRef: "agent", Name: b.name, Version: b.version},
+		},
+	}
+
+	for _, key := range sortedKeys(b.models) {
+		e := b.models[key]
+		m := e.item
+		c := Component{
+			Type:      TypeModel,
+			BOMRef:    "model:" + key,
+			Publisher: m.Provider,
+			Name:      m.Name,
+			Version:   m.Version,
+			Hashes:    hashes(m.WeightsHash),
+			Licenses:  licenses(m.License),
+		}
+		if m.Provider != "" {
+			c.Properties = append(c.Properties, Property{"trusera:provider", m.Provider})
+		}
+		c.Properties = append(c.Properties, usage(e.declared, e.observed)...)
+		bom.Components = append(bom.Components, c)
+	}
+
+	for _, key := range sortedKeys(b.datasets) {
+		e := b.datasets[key]
+		d := e.item
+		c := Component{
+			Type:     TypeData,
+			BOMRef:   "dataset:" + key,
+			Name:     d.Name,
+			Version:  d.Version,
+			Hashes:   hashes(d.Hash),
+			Licenses: licenses(d.License),
+		}
+		if d.Source != "" {
+			c.Properties = append(c.Properties, Property{"trusera:source", d.Source})
+		}
+		c.Properties = append(c.Properties, usage(e.declared, e.observed)...)
+		bom.Components = append(bom.Components, c)
+	}
+
+	for _, key := range sortedKeys(b.tools) {
+		e := b.tools[key]
+		c := Component{
+			Type:        TypeApplication,
+			BOMRef:      "tool:" + key,
+			Name:        e.item.Name,
+			Description: e.item.Description,
+			Properties:  []Property{{"trusera:kind", "tool"}},
+		}
+		if e.item.Risk != "" {
+			c.Properties = append(c.Properties, Property{"trusera:risk_tier", e.item.Risk})
+		}
+		c.Properties = append(c.Properties, usage(e.declared, e.observed)...)
+		bom.Components = append(bom.Components, c)
+	}
+
+	for _, key := range sortedKeys(b.endpoints) {
+		e := b.endpoints[key]
+		s := Service{
+			BOMRef:     "service:" + key,
+			Name:       e.item.Name,
+			Properties: usage(e.declared, e.observed),
+		}
+		if e.item.URL != "" {
+			s.Endpoints = []string{e.item.URL}
+		}
+		bom.Services = append(bom.Services, s)
+	}
+	return bom
+}
+
+// usage describes how an item came into the BOM
+func usage(declared bool, observed int) []Property {
+	return []Property{
+		{"trusera:declared", strconv.FormatBool(declared)},
+		{"trusera:observed", strconv.Itoa(observed)},
+	}
+}
+
+func sortedKeys[T any](m map[string]*entry[T]) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hashPrefixes and hashLengths map digest prefixes and hex lengths to
+// CycloneDX hash algorithms
+var (
+	hashPrefixes = map[string]string{"sha256": "SHA-256", "sha-256": "SHA-256", "sha384": "SHA-384", "sha-384": "SHA-384", "sha512": "SHA-512", "sha-512": "SHA-512", "sha1": "SHA-1", "sha-1": "SHA-1", "md5": "MD5"}
+	hashLengths  = map[int]string{32: "MD5", 40: "SHA-1", 64: "SHA-256", 96: "SHA-384", 128: "SHA-512"}
+)
+
+// hashes parses a digest such as sha256:abc… or a bare hex digest
+func hashes(digest string) []Hash {
+	if digest == "" {
+		return nil
+	}
+	if prefix, content, ok := strings.Cut(digest, ":"); ok {
+		if alg, ok := hashPrefixes[strings.ToLower(prefix)]; ok {
+			return []Hash{{Alg: alg, Content: strings.ToLower(content)}}
+		}
+	}
+	if alg, ok := hashLengths[len(digest)]; ok {
+		return []Hash{{Alg: alg, Content: strings.ToLower(digest)}}
+	}
+	return nil
+}
+
+// spdxIDs are the licenses common for models and datasets that are written
+// as SPDX IDs; others are written as names
+var spdxIDs = map[string]bool{
+	"Apache-2.0": true, "MIT": true, "BSD-2-Clause": true, "BSD-3-Clause": true,
+	"CC-BY-4.0": true, "CC-BY-SA-4.0": true, "CC-BY-NC-4.0": true, "CC0-1.0": true,
+	"GPL-3.0-only": true, "LGPL-3.0-only": true, "MPL-2.0": true, "ODbL-1.0": true,
+	"OpenRAIL": true, "CreativeML-OpenRAIL-M": true,
+}
+
+func licenses(license string) Licenses {
+	if license == "" {
+		return nil
+	}
+	if spdxIDs[license] {
+		return Licenses{{License{ID: license}}}
+	}
+	return Licenses{{License{Name: license}}}
+}
@@ -0,0 +1,104 @@
+package aibom
+
+import (
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func component(t *testing.T, bom *BOM, ref string) Component {
+	t.Helper()
+	for _, c := range bom.Components {
+		if c.BOMRef == ref {
+			return c
+		}
+	}
+	t.Fatalf("no component %s in %+v", ref, bom.Components)
+	return Component{}
+}
+
+func TestBuilderCombinesRegistrationsAndEvents(t *testing.T) {
+	b := NewBuilder("support-agent", "1.4.0")
+	b.AddModel(Model{Name: "gpt-4o", Provider: "openai", Version: "2024-08-06", License: "proprietary"})
+	b.AddModel(Model{Name: "llama-3-8b", Provider: "meta", WeightsHash: "sha256:ABC123", License: "Apache-2.0"})
+	b.AddDataset(Dataset{Name: "kb", Source: "s3://docs/kb", Version: "7", Hash: "d41d8cd98f00b204e9800998ecf8427e"})
+	b.AddTool(Tool{Name: "search", Description: "Searches the web"})
+
+	b.Observe(trusera.NewEvent(trusera.EventLLMInvoke, "x").WithPayload("model", "gpt-4o-2024-08-06").WithPayload("provider", "openai"))
+	b.Observe(trusera.NewEvent(trusera.EventLLMInvoke, "x").WithPayload("model", "claude-haiku-4-5").WithPayload("provider", "anthropic"))
+	b.Observe(trusera.NewEvent(trusera.EventToolCall, "search"))
+	b.Observe(trusera.NewEvent(trusera.EventToolCall, "calculator"))
+	b.Observe(trusera.NewEvent(trusera.EventAPICall, "GET").WithPayload("url", "https://api.stripe.com/v1/charges?x=1"))
+	b.Observe(trusera.NewEvent(trusera.EventAPICall, "dial").WithPayload("host", "db.internal"))
+
+	bom := b.Build()
+	if bom.Metadata.Component.Name != "support-agent" || bom.Metadata.Component.Version != "1.4.0" {
+		t.Errorf("unexpected agent %+v", bom.Metadata.Component)
+	}
+
+	gpt := component(t, bom, "model:gpt-4o")
+	if gpt.Type != TypeModel || gpt.Property("trusera:observed") != "1" || gpt.Property("trusera:declared") != "true" {
+		t.Errorf("expected the snapshot to count towards gpt-4o, got %+v", gpt)
+	}
+	if gpt.Licenses[0].License.Name != "proprietary" {
+		t.Errorf("expected a license name, got %+v", gpt.Licenses)
+	}
+	llama := component(t, bom, "model:llama-3-8b")
+	if llama.Hashes[0] != (Hash{Alg: "SHA-256", Content: "abc123"}) || llama.Licenses[0].License.ID != "Apache-2.0" {
+		t.Errorf("unexpected hashes or licenses %+v", llama)
+	}
+	claude := component(t, bom, "model:claude-haiku-4-5")
+	if claude.Publisher != "anthropic" || claude.Property("trusera:declared") != "false" {
+		t.Errorf("expected an observed model, got %+v", claude)
+	}
+
+	kb := component(t, bom, "dataset:kb")
+	if kb.Type != TypeData || kb.Hashes[0].Alg != "MD5" || kb.Property("trusera:source") != "s3://docs/kb" {
+		t.Errorf("unexpected dataset %+v", kb)
+	}
+	if tool := component(t, bom, "tool:search"); tool.Description != "Searches the web" || tool.Property("trusera:observed") != "1" {
+		t.Errorf("unexpected tool %+v", tool)
+	}
+	component(t, bom, "tool:calculator")
+
+	if len(bom.Services) != 2 || bom.Services[0].Name != "api.stripe.com" || bom.Services[0].Endpoints[0] != "https://api.stripe.com" ||
+		bom.Services[1].Name != "db.internal" {
+		t.Errorf("unexpected services %+v", bom.Services)
+	}
+}
+
+func TestBuilderHook(t *testing.T) {
+	b := NewBuilder("agent", "")
+	event := trusera.NewEvent(trusera.EventToolCall, "search")
+	out, err := b.Hook()(&event)
+	if err != nil || out != &event {
+		t.Fatal("the hook should pass events through")
+	}
+	component(t, b.Build(), "tool:search")
+}
+
+func TestBuilderAddClient(t *testing.T) {
+	client := trusera.NewClient("test-key")
+	defer client.Close()
+	client.RegisterModel(trusera.ModelInfo{Name: "gpt-4o", Provider: "openai", License: "proprietary"})
+	client.RegisterDataset(trusera.DatasetInfo{Name: "kb", Source: "s3://docs/kb"})
+	client.RegisterTool(trusera.ToolInfo{Name: "refund", Description: "Refunds an order", Risk: trusera.RiskHigh})
+
+	b := NewBuilder("agent", "1.0.0")
+	b.AddClient(client)
+	b.Observe(trusera.NewEvent(trusera.EventDatasetAccess, "kb"))
+	b.Observe(trusera.NewEvent(trusera.EventDatasetAccess, "faq").WithPayload("source", "https://example.com/faq"))
+	bom := b.Build()
+	if gpt := component(t, bom, "model:gpt-4o"); gpt.Publisher != "openai" || gpt.Property("trusera:declared") != "true" {
+		t.Errorf("expected the registered model, got %+v", gpt)
+	}
+	if kb := component(t, bom, "dataset:kb"); kb.Property("trusera:declared") != "true" || kb.Property("trusera:observed") != "1" {
+		t.Errorf("expected the registered dataset to be observed, got %+v", kb)
+	}
+	if refund := component(t, bom, "tool:refund"); refund.Description != "Refunds an order" || refund.Property("trusera:risk_tier") != "high" {
+		t.Errorf("expected the registered tool, got %+v", refund)
+	}
+	if faq := component(t, bom, "dataset:faq"); faq.Property("trusera:source") != "https://example.com/faq" || faq.Property("trusera:declared") != "false" {
+		t.Errorf("expected an observed dataset, got %+v", faq)
+	}
+}
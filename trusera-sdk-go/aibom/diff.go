@@ -0,0 +1,215 @@
+package aibom
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is how an item differs between two BOMs
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Item categories of a Change
+const (
+	CategoryModel   = "model"
+	CategoryDataset = "dataset"
+	CategoryTool    = "tool"
+	CategoryService = "service"
+	CategoryOther   = "component"
+)
+
+// categoryOrder sorts the changes of a Report
+var categoryOrder = map[string]int{CategoryModel: 0, CategoryDataset: 1, CategoryTool: 2, CategoryService: 3, CategoryOther: 4}
+
+// Change is a model, dataset, tool or external service that was added,
+// removed or changed
+type Change struct {
+	Kind     ChangeKind
+	Category string // One of the Category constants
+	Ref      string // bom-ref
+	Name     string
+	Fields   []FieldChange // What changed, for Changed
+}
+
+// FieldChange is a changed attribute, such as the version or a hash
+type FieldChange struct {
+	Field    string
+	Old, New string
+}
+
+// Report lists the differences between two BOMs
+type Report struct {
+	// OldAgent and NewAgent are the agent versions from the BOMs' metadata
+	OldAgent, NewAgent string
+	Changes            []Change
+}
+
+// Diff reports the models, datasets, tools and external services added,
+// removed or changed between old and new, e.g. two releases of an agent.
+// Items are matched by bom-ref. Usage counts (trusera:observed) are not
+// compared since they differ between every run.
+func Diff(old, new *BOM) *Report {
+	r := &Report{OldAgent: agentVersion(old), NewAgent: agentVersion(new)}
+	before, after := items(old), items(new)
+	for ref, a := range after {
+		b, ok := before[ref]
+		if !ok {
+			r.Changes = append(r.Changes, Change{Kind: Added, Category: a.category, Ref: ref, Name: a.name})
+		} else if fields := compareFields(b.fields, a.fields); len(fields) > 0 {
+			r.Changes = append(r.Changes, Change{Kind: Changed, Category: a.category, Ref: ref, Name: a.name, Fields: fields})
+		}
+	}
+	for ref, b := range before {
+		if _, ok := after[ref]; !ok {
+			r.Changes = append(r.Changes, Change{Kind: Removed, Category: b.category, Ref: ref, Name: b.name})
+		}
+	}
+	sort.Slice(r.Changes, func(i, j int) bool {
+		ci, cj := r.Changes[i], r.Changes[j]
+		if ci.Category != cj.Category {
+			return categoryOrder[ci.Category] < categoryOrder[cj.Category]
+		}
+		return ci.Ref < cj.Ref
+	})
+	return r
+}
+
+// Empty reports whether the BOMs list the same items
+func (r *Report) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+// Filter returns the changes of a category
+func (r *Report) Filter(category string) []Change {
+	var changes []Change
+	for _, c := range r.Changes {
+		if c.Category == category {
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// WriteText writes the report for review, one line per change prefixed with
+// +, - or ~ and one indented line per changed field
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	if r.OldAgent != r.NewAgent {
+		fmt.Fprintf(&b, "agent %s -> %s\n", orNone(r.OldAgent), orNone(r.NewAgent))
+	}
+	for _, c := range r.Changes {
+		sign := map[ChangeKind]string{Added: "+", Removed: "-", Changed: "~"}[c.Kind]
+		fmt.Fprintf(&b, "%s %s %s\n", sign, c.Category, c.Name)
+		for _, f := range c.Fields {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", f.Field, orNone(f.Old), orNone(f.New))
+		}
+	}
+	if r.Empty() {
+		b.WriteString("no changes\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+// item is a BOM entry flattened for comparison
+type item struct {
+	category, name string
+	fields         map[string]string
+}
+
+func agentVersion(b *BOM) string {
+	if b == nil || b.Metadata.Component == nil {
+		return ""
+	}
+	return b.Metadata.Component.Version
+}
+
+// items indexes the components and services of a BOM by bom-ref
+func items(b *BOM) map[string]item {
+	m := make(map[string]item)
+	if b == nil {
+		return m
+	}
+	for _, c := range b.Components {
+		ref := c.BOMRef
+		if ref == "" {
+			ref = c.Type + ":" + c.Name
+		}
+		fields := map[string]string{
+			"version":     c.Version,
+			"publisher":   c.Publisher,
+			"description": c.Description,
+		}
+		for _, h := range c.Hashes {
+			fields["hash "+h.Alg] = h.Content
+		}
+		var licenses []string
+		for _, l := range c.Licenses {
+			licenses = append(licenses, l.License.ID+l.License.Name)
+		}
+		fields["licenses"] = strings.Join(licenses, ", ")
+		addProperties(fields, c.Properties)
+		m[ref] = item{category: componentCategory(c), name: c.Name, fields: fields}
+	}
+	for _, s := range b.Services {
+		ref := s.BOMRef
+		if ref == "" {
+			ref = "service:" + s.Name
+		}
+		fields := map[string]string{"endpoints": strings.Join(s.Endpoints, ", ")}
+		addProperties(fields, s.Properties)
+		m[ref] = item{category: CategoryService, name: s.Name, fields: fields}
+	}
+	return m
+}
+
+func addProperties(fields map[string]string, props Properties) {
+	for _, p := range props {
+		if p.Name != "trusera:observed" {
+			fields[p.Name] = p.Value
+		}
+	}
+}
+
+func componentCategory(c Component) string {
+	switch {
+	case c.Type == TypeModel:
+		return CategoryModel
+	case c.Type == TypeData:
+		return CategoryDataset
+	case strings.HasPrefix(c.BOMRef, "tool:") || c.Property("trusera:kind") == "tool":
+		return CategoryTool
+	}
+	return CategoryOther
+}
+
+// compareFields lists the fields that differ, sorted by name
+func compareFields(old, new map[string]string) []FieldChange {
+	var changes []FieldChange
+	for field, v := range new {
+		if old[field] != v {
+			changes = append(changes, FieldChange{Field: field, Old: old[field], New: v})
+		}
+	}
+	for field, v := range old {
+		if _, ok := new[field]; !ok && v != "" {
+			changes = append(changes, FieldChange{Field: field, Old: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
@@ -0,0 +1,78 @@
+package aibom
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func TestDiff(t *testing.T) {
+	v1 := NewBuilder("support-agent", "1.4.0")
+	v1.AddModel(Model{Name: "gpt-4o", Provider: "openai", Version: "2024-05-13"})
+	v1.AddModel(Model{Name: "llama-3-8b", WeightsHash: "sha256:aaa"})
+	v1.AddTool(Tool{Name: "search"})
+	v1.AddTool(Tool{Name: "refund", Risk: "medium"})
+	v1.AddEndpoint(Endpoint{Name: "api.stripe.com", URL: "https://api.stripe.com"})
+	v1.Observe(trusera.NewEvent(trusera.EventToolCall, "search"))
+
+	v2 := NewBuilder("support-agent", "1.5.0")
+	v2.AddModel(Model{Name: "gpt-4o", Provider: "openai", Version: "2024-08-06"})
+	v2.AddModel(Model{Name: "claude-haiku-4-5", Provider: "anthropic"})
+	v2.AddTool(Tool{Name: "search"})
+	v2.AddTool(Tool{Name: "refund", Risk: "high"})
+	v2.Observe(trusera.NewEvent(trusera.EventAPICall, "GET").WithPayload("url", "https://api.stripe.com/v1"))
+	v2.Observe(trusera.NewEvent(trusera.EventAPICall, "dial").WithPayload("host", "db.internal"))
+
+	r := Diff(v1.Build(), v2.Build())
+	var got []string
+	for _, c := range r.Changes {
+		got = append(got, string(c.Kind)+" "+c.Ref)
+	}
+	want := []string{
+		"added model:claude-haiku-4-5",
+		"changed model:gpt-4o",
+		"removed model:llama-3-8b",
+		"changed tool:refund",
+		"changed service:api.stripe.com",
+		"added service:db.internal",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	gpt := r.Filter(CategoryModel)[1]
+	if len(gpt.Fields) != 1 || gpt.Fields[0] != (FieldChange{Field: "version", Old: "2024-05-13", New: "2024-08-06"}) {
+		t.Errorf("unexpected fields %+v", gpt.Fields)
+	}
+	if stripe := r.Filter(CategoryService)[0]; len(stripe.Fields) != 1 || stripe.Fields[0].Field != "trusera:declared" {
+		t.Errorf("expected only the declaration to change, got %+v", stripe.Fields)
+	}
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	for _, line := range []string{"agent 1.4.0 -> 1.5.0\n", "+ model claude-haiku-4-5\n", "- model llama-3-8b\n",
+		"~ tool refund\n    trusera:risk_tier: medium -> high\n"} {
+		if !bytes.Contains(buf.Bytes(), []byte(line)) {
+			t.Errorf("expected %q in\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestDiffIgnoresUsage(t *testing.T) {
+	b := NewBuilder("agent", "1.0.0")
+	b.AddTool(Tool{Name: "search"})
+	before := b.Build()
+	b.Observe(trusera.NewEvent(trusera.EventToolCall, "search"))
+
+	r := Diff(before, b.Build())
+	if !r.Empty() {
+		t.Errorf("expected usage counts to be ignored, got %+v", r.Changes)
+	}
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	if buf.String() != "no changes\n" {
+		t.Errorf("unexpected text %q", buf.String())
+	}
+}
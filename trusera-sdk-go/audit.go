@@ -0,0 +1,12 @@
+package trusera
+
+// defaultAuditBodyLimit caps captured bodies when AuditBodyLimit is unset
+const defaultAuditBodyLimit = 64 << 10
+
+// defaultAuditRedactor scrubs captured bodies when AuditRedactor is unset
+var defaultAuditRedactor = NewRedactor(append(DefaultDetectors(), SecretDetectors()...)...)
+
+// auditing reports whether full request and response capture is enabled
+func (t *interceptingTransport) auditing() bool {
+	return t.options().Enforcement == ModeAudit
+}
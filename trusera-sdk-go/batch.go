@@ -0,0 +1,158 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// WithMaxBatchSize sets the most events sent in a single request. Reaching
+// it in the buffer triggers a flush, and larger flushes are split into
+// several requests. It replaces WithBatchSize, which remains as an alias.
+func WithMaxBatchSize(n int) Option {
+	return WithBatchSize(n)
+}
+
+// WithMaxBatchBytes caps the serialized size of a request body, e.g. to
+// stay under the ingestion endpoint's payload limit. Buffering that many
+// bytes triggers a flush, and larger flushes are split into several
+// requests. An event that alone exceeds the cap is split into EventChunk
+// events sent in consecutive requests, which the API reassembles; see
+// ChunkAssembler. Secondary sinks and a primary sink set with
+// WithPrimarySink receive the event whole.
+func WithMaxBatchBytes(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBatchBytes = n
+		}
+	}
+}
+
+// WithMaxBatchAge bounds how long an event waits in the buffer. The first
+// event buffered after a flush starts a timer that flushes when it fires,
+// independently of WithFlushInterval.
+func WithMaxBatchAge(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.maxBatchAge = d
+		}
+	}
+}
+
+// bufferedLocked updates the batch accounting after an event is appended
+// and reports whether the buffer should be flushed. It must be called with
+// c.mu held.
+func (c *Client) bufferedLocked(event Event) bool {
+	if c.maxBatchAge > 0 && c.ageTimer == nil {
+		c.ageTimer = time.AfterFunc(c.maxBatchAge, func() {
+			c.handleError(c.Flush())
+		})
+	}
+
+	full := len(c.events) >= c.flushSize
+	if c.maxBatchBytes > 0 {
+		c.bufferedBytes += eventSize(event) + 1
+		full = full || c.bufferedBytes+c.envelopeSize() >= c.maxBatchBytes
+	}
+	return full
+}
+
+// resetBatchLocked clears the batch accounting once the buffer is taken
+// for a flush. It must be called with c.mu held.
+func (c *Client) resetBatchLocked() {
+	c.bufferedBytes = 0
+	if c.ageTimer != nil {
+		c.ageTimer.Stop()
+		c.ageTimer = nil
+	}
+}
+
+// envelopeSize is the size of a request body without any events
+func (c *Client) envelopeSize() int {
+	id, _ := json.Marshal(c.agentID)
+	return len(`{"agent_id":,"events":[],"clock":}`) + len(id) + envelopeReserve
+}
+
+// batches splits events into requests that respect the batch limits and
+// carry the events of a single project and delivery stream each
+func (c *Client) batches(events []Event) [][]Event {
+	runs := projectRuns(events)
+	if len(runs) <= 1 {
+		return c.splitBatches(events)
+	}
+	var out [][]Event
+	for _, run := range runs {
+		out = append(out, c.splitBatches(run)...)
+	}
+	return out
+}
+
+// splitBatches splits the events of a project by the batch limits
+func (c *Client) splitBatches(events []Event) [][]Event {
+	if len(events) <= c.flushSize && c.maxBatchBytes <= 0 {
+		return [][]Event{events}
+	}
+
+	var (
+		out      [][]Event
+		start    int
+		size     int
+		envelope = c.envelopeSize()
+	)
+	if len(events) > 0 && projectOf(events[0]) != "" {
+		id, _ := json.Marshal(projectOf(events[0]))
+		envelope += len(`,"project_id":`) + len(id)
+	}
+	for i, event := range events {
+		n := 0
+		if c.maxBatchBytes > 0 {
+			n = eventSize(event) + 1
+		}
+		count := i - start
+		if count > 0 && (count >= c.flushSize || (c.maxBatchBytes > 0 && envelope+size+n > c.maxBatchBytes)) {
+			out = append(out, events[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(out, events[start:])
+}
+
+// deliverBatches sends events in batches, on concurrent workers if
+// WithFlushWorkers is set, each stopping at its first failed request. It
+// returns the events that were not delivered. Events vetoed by
+// a send hook and batches moved to the dead-letter file count as handled;
+// their errors are reported with the export errors.
+func (c *Client) deliverBatches(ctx context.Context, events []Event) (undelivered []Event, sendErr, exportErr error) {
+	events, exportErr = c.beforeSend(events)
+	if len(events) == 0 {
+		return nil, nil, exportErr
+	}
+	if c.flushWorkers > 1 && c.ordered == nil {
+		undelivered, sendErr, err := c.deliverConcurrently(ctx, events)
+		return undelivered, sendErr, errors.Join(exportErr, err)
+	}
+	undelivered, sendErr, err := c.deliverInOrder(ctx, events)
+	return undelivered, sendErr, errors.Join(exportErr, err)
+}
+
+// deliverInOrder sends events in batches, one request at a time, stopping
+// at the first failed request
+func (c *Client) deliverInOrder(ctx context.Context, events []Event) (undelivered []Event, sendErr, exportErr error) {
+	sent := 0
+	for _, batch := range c.batches(events) {
+		_, s, e := c.deliver(ctx, batch)
+		exportErr = errors.Join(exportErr, e)
+		if s != nil {
+			stored, err := c.deadLetter(batch, s)
+			if !stored {
+				sent += ackedIn(batch, s)
+				return events[sent:], errors.Join(s, err), exportErr
+			}
+			exportErr = errors.Join(exportErr, s)
+		}
+		sent += len(batch)
+	}
+	return nil, nil, exportErr
+}
@@ -0,0 +1,174 @@
+package trusera
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchAPI records the size and event count of every request it receives,
+// counting a chunked event once, in the request that completes it
+type batchAPI struct {
+	server *httptest.Server
+	chunks *ChunkAssembler
+	mu     sync.Mutex
+	sizes  []int
+	counts []int
+}
+
+func newBatchAPI(t *testing.T) *batchAPI {
+	a := &batchAPI{chunks: NewChunkAssembler()}
+	a.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload struct {
+			Events []Event `json:"events"`
+		}
+		json.Unmarshal(body, &payload)
+		count := 0
+		for _, e := range payload.Events {
+			if _, ok, _ := a.chunks.Add(e); ok {
+				count++
+			}
+		}
+		a.mu.Lock()
+		a.sizes = append(a.sizes, len(body))
+		a.counts = append(a.counts, count)
+		a.mu.Unlock()
+	}))
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+func (a *batchAPI) requests() (sizes, counts []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]int(nil), a.sizes...), append([]int(nil), a.counts...)
+}
+
+func TestMaxBatchSizeSplitsFlush(t *testing.T) {
+	api := newBatchAPI(t)
+	client := NewClient("test-key", WithBaseURL(api.server.URL), WithMaxBatchSize(4))
+	defer client.Close()
+
+	// Fill the buffer directly so no auto-flush runs
+	client.mu.Lock()
+	for i := 0; i < 10; i++ {
+		client.events = append(client.events, NewEvent(EventToolCall, "tool"))
+	}
+	client.mu.Unlock()
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	_, counts := api.requests()
+	if len(counts) != 3 || counts[0] != 4 || counts[1] != 4 || counts[2] != 2 {
+		t.Errorf("expected batches of 4, 4 and 2, got %v", counts)
+	}
+}
+
+func TestMaxBatchBytes(t *testing.T) {
+	api := newBatchAPI(t)
+	const limit = 1024
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithAgentID("agent-1"),
+		WithMaxBatchBytes(limit))
+	defer client.Close()
+
+	big := strings.Repeat("x", 200)
+	for i := 0; i < 12; i++ {
+		client.Track(NewEvent(EventLLMInvoke, "llm").WithPayload("prompt", big))
+	}
+	client.Track(NewEvent(EventLLMInvoke, "huge").WithPayload("prompt", strings.Repeat("y", 2*limit)))
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		sizes, counts := api.requests()
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		if total == 13 {
+			for i, size := range sizes {
+				if size > limit {
+					t.Errorf("request %d has %d bytes and %d events, exceeding %d", i, size, counts[i], limit)
+				}
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 13 events delivered, got counts %v", counts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMaxBatchBytesTriggersFlush(t *testing.T) {
+	api := newBatchAPI(t)
+	client := NewClient("test-key", WithBaseURL(api.server.URL), WithMaxBatchBytes(512))
+	defer client.Close()
+
+	for i := 0; i < 4; i++ {
+		client.Track(NewEvent(EventLLMInvoke, "llm").WithPayload("prompt", strings.Repeat("x", 200)))
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, counts := api.requests(); len(counts) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected buffered bytes to trigger a flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMaxBatchAge(t *testing.T) {
+	api := newBatchAPI(t)
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithFlushInterval(time.Hour),
+		WithMaxBatchAge(20*time.Millisecond))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, counts := api.requests(); len(counts) == 1 && counts[0] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the event to be flushed once it reached the max batch age")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.mu.Lock()
+	timer := client.ageTimer
+	client.mu.Unlock()
+	if timer != nil {
+		t.Error("expected the age timer to be cleared by the flush")
+	}
+}
+
+func TestBatchesWithinLimits(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	events := []Event{NewEvent(EventToolCall, "a")}
+	if got := client.batches(events); len(got) != 1 || len(got[0]) != 1 {
+		t.Errorf("expected a single batch, got %v", got)
+	}
+}
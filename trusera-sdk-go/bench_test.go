@@ -0,0 +1,74 @@
+package trusera
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// discardSink accepts and forgets every batch
+type discardSink struct{}
+
+func (discardSink) Send(ctx context.Context, events []Event) error {
+	return nil
+}
+
+func benchmarkClient(b *testing.B, opts ...Option) *Client {
+	opts = append([]Option{WithFlushInterval(time.Hour), WithPrimarySink(discardSink{})}, opts...)
+	client := NewClient("test-key", opts...)
+	b.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func BenchmarkNewEvent(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	}
+}
+
+func BenchmarkTrack(b *testing.B) {
+	client := benchmarkClient(b)
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.Track(event)
+	}
+}
+
+func BenchmarkTrackParallel(b *testing.B) {
+	client := benchmarkClient(b)
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			client.Track(event)
+		}
+	})
+}
+
+func BenchmarkTrackWithBatchBytes(b *testing.B) {
+	client := benchmarkClient(b, WithMaxBatchBytes(1<<20))
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.Track(event)
+	}
+}
+
+func BenchmarkTrackParallelSharded(b *testing.B) {
+	client := benchmarkClient(b, WithBufferShards(16))
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			client.Track(event)
+		}
+	})
+}
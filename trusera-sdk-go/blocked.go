@@ -0,0 +1,102 @@
+package trusera
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrBlocked matches every request, call, statement or connection rejected
+// by enforcement: errors.Is(err, trusera.ErrBlocked)
+var ErrBlocked = errors.New("request blocked by Trusera policy")
+
+// BlockReason says why enforcement rejected a request. The values are
+// stable, for callers to branch on.
+type BlockReason string
+
+const (
+	BlockReasonRule             BlockReason = "rule"              // A Rule, host policy or Decide callback blocks it
+	BlockReasonPattern          BlockReason = "block_pattern"     // It matches BlockPatterns
+	BlockReasonNotAllowed       BlockReason = "not_allow_listed"  // It matches no AllowPatterns in ModeAllowList
+	BlockReasonRateLimit        BlockReason = "rate_limit"        // It exceeds its rule's RateLimit
+	BlockReasonPolicy           BlockReason = "policy"            // The RequestPolicy denied it
+	BlockReasonGuardrail        BlockReason = "guardrail"         // A guardrail flagged its prompt
+	BlockReasonCredentialEgress BlockReason = "credential_egress" // It carries credentials to an unapproved host
+	BlockReasonReputation       BlockReason = "reputation"        // Its host has a malicious reputation
+	BlockReasonTLSAnomaly       BlockReason = "tls_anomaly"       // Its connection breaks the host's TLSPolicy
+	BlockReasonStatement        BlockReason = "statement"         // Its SQL statement type or table is blocked
+)
+
+// remediations suggest how to unblock a request, by reason
+var remediations = map[BlockReason]string{
+	BlockReasonRule:             "change or remove the matching rule, or run the interceptor in warn mode",
+	BlockReasonPattern:          "remove the matching block pattern or add an exclude pattern",
+	BlockReasonNotAllowed:       "add the target to AllowPatterns or allow it with a rule",
+	BlockReasonRateLimit:        "retry later, or raise the rule's rate limit",
+	BlockReasonPolicy:           "review the request policy's deny reasons",
+	BlockReasonGuardrail:        "review the prompt flagged by the guardrail",
+	BlockReasonCredentialEgress: "add the host to CredentialPolicy.AllowedHosts or do not send it credentials",
+	BlockReasonReputation:       "check whether the host is compromised; exclude it if the verdict is wrong",
+	BlockReasonTLSAnomaly:       "check for TLS interception, or update the host's pins after a certificate rotation",
+	BlockReasonStatement:        "remove the statement type or table from the blocked lists",
+}
+
+// BlockedError is returned for a request, call, statement or connection
+// rejected by enforcement. Use errors.As to inspect it; errors.Is matches
+// it against ErrBlocked.
+type BlockedError struct {
+	Reason        BlockReason
+	Target        string          // The URL, gRPC method, statement fingerprint, key or address
+	RuleID        string          // The rule that matched, if any
+	Mode          EnforcementMode // The enforcement mode in effect
+	PolicyVersion string          // The remote policy version, if one applied
+	Details       []string        // e.g. policy reasons, TLS anomalies or credential headers
+	Remediation   string          // A hint for unblocking it
+}
+
+func (e *BlockedError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrBlocked.Error())
+	b.WriteString(": ")
+	b.WriteString(string(e.Reason))
+	if e.RuleID != "" {
+		b.WriteString(" (rule " + e.RuleID + ")")
+	}
+	if len(e.Details) > 0 {
+		b.WriteString(": " + strings.Join(e.Details, "; "))
+	}
+	return b.String()
+}
+
+// Is reports whether target is ErrBlocked
+func (e *BlockedError) Is(target error) bool {
+	return target == ErrBlocked
+}
+
+// blockCause is a check that may have blocked a request, with its details
+type blockCause struct {
+	reason  BlockReason
+	details []string
+}
+
+// blockedError describes why enforcement rejected a target: the verdict's
+// reason if it blocked, otherwise the first cause with details
+func (v verdict) blockedError(target string, causes ...blockCause) *BlockedError {
+	err := &BlockedError{
+		Reason:        v.reason,
+		Target:        target,
+		RuleID:        v.ruleID,
+		Mode:          v.mode,
+		PolicyVersion: v.version,
+	}
+	if !v.blocked {
+		err.RuleID = ""
+		for _, c := range causes {
+			if len(c.details) > 0 {
+				err.Reason, err.Details = c.reason, c.details
+				break
+			}
+		}
+	}
+	err.Remediation = remediations[err.Reason]
+	return err
+}
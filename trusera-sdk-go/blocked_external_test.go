@@ -0,0 +1,36 @@
+package trusera_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func TestErrBlockedMatchesOutsideThePackage(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := trusera.NewClient("test-key", trusera.WithFlushInterval(time.Hour), trusera.WithPrimarySink(
+		trusera.SinkFunc(func(context.Context, []trusera.Event) error { return nil })))
+	defer client.Close()
+
+	httpClient := trusera.CreateInterceptedClient(client, trusera.InterceptorOptions{
+		Enforcement:   trusera.ModeBlock,
+		BlockPatterns: []string{"/internal"},
+	})
+	_, err := httpClient.Get(backend.URL + "/internal")
+	if !errors.Is(err, trusera.ErrBlocked) {
+		t.Fatalf("expected errors.Is(err, ErrBlocked), got %v", err)
+	}
+	if wrapped := fmt.Errorf("calling tool: %w", err); !errors.Is(wrapped, trusera.ErrBlocked) {
+		t.Error("expected a wrapped BlockedError to match ErrBlocked")
+	}
+	if errors.Is(errors.New("request blocked by Trusera policy"), trusera.ErrBlocked) {
+		t.Error("only enforcement errors should match ErrBlocked")
+	}
+}
@@ -0,0 +1,77 @@
+package trusera
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockedError(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	client.ApplyPolicy(&RemotePolicy{
+		Version: "v3",
+		Rules:   []Rule{{ID: "no-admin", Match: "/admin", Action: RuleBlock, Reason: "admin API"}},
+	})
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"/internal"},
+	})
+
+	_, err := httpClient.Get(backend.URL + "/admin")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("expected a BlockedError, got %v", err)
+	}
+	if !errors.Is(err, ErrBlocked) {
+		t.Error("expected the BlockedError to match ErrBlocked")
+	}
+	if blocked.Reason != BlockReasonRule || blocked.RuleID != "no-admin" || blocked.Mode != ModeBlock ||
+		blocked.PolicyVersion != "v3" || blocked.Target != backend.URL+"/admin" || blocked.Remediation == "" {
+		t.Errorf("unexpected error %+v", blocked)
+	}
+	if !strings.Contains(err.Error(), "rule (rule no-admin)") {
+		t.Errorf("unexpected message %q", err)
+	}
+
+	_, err = httpClient.Get(backend.URL + "/internal/metrics")
+	if !errors.As(err, &blocked) || blocked.Reason != BlockReasonPattern || blocked.RuleID != "" {
+		t.Errorf("expected a block pattern error, got %v", err)
+	}
+}
+
+func TestBlockedErrorReasons(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	allowList := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:   ModeAllowList,
+		AllowPatterns: []string{"api.example.com"},
+	})
+	_, err := allowList.Get(backend.URL + "/x")
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.Reason != BlockReasonNotAllowed || blocked.Mode != ModeAllowList {
+		t.Errorf("expected a not allow-listed error, got %v", err)
+	}
+
+	egress := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:      ModeBlock,
+		CredentialEgress: &CredentialPolicy{AllowedHosts: []string{"api.example.com"}},
+	})
+	req, _ := http.NewRequest(http.MethodGet, backend.URL+"/collect", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	_, err = egress.Do(req)
+	if !errors.As(err, &blocked) || blocked.Reason != BlockReasonCredentialEgress ||
+		!slices.Equal(blocked.Details, []string{"X-Api-Key"}) {
+		t.Errorf("expected a credential egress error, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Flush while the circuit breaker is open
+var ErrCircuitOpen = errors.New("trusera: circuit breaker open")
+
+// CircuitState is the state of the ingestion circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Requests flow normally
+	CircuitOpen     CircuitState = "open"      // Requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen CircuitState = "half_open" // A single probe request is allowed through
+)
+
+// CircuitBreakerOptions configures WithCircuitBreaker
+type CircuitBreakerOptions struct {
+	// FailureThreshold is how many consecutive failed flushes open the circuit (default 5)
+	FailureThreshold int
+	// OpenDuration is how long the circuit stays open before probing (default 30s)
+	OpenDuration time.Duration
+	// OnStateChange, if set, is called asynchronously after every state transition
+	OnStateChange func(from, to CircuitState)
+}
+
+// WithCircuitBreaker stops sending to an unreachable Trusera endpoint. After
+// FailureThreshold consecutive transient failures, flushes fail immediately
+// with ErrCircuitOpen instead of waiting on timeouts and retries. Once
+// OpenDuration has passed, one flush is let through to probe recovery: if it
+// succeeds the circuit closes, otherwise it opens again.
+func WithCircuitBreaker(opts CircuitBreakerOptions) Option {
+	return func(c *Client) {
+		if opts.FailureThreshold <= 0 {
+			opts.FailureThreshold = 5
+		}
+		if opts.OpenDuration <= 0 {
+			opts.OpenDuration = 30 * time.Second
+		}
+		c.breaker = &circuitBreaker{opts: opts, state: CircuitClosed, now: time.Now}
+	}
+}
+
+// CircuitState returns the breaker's current state, or CircuitClosed when
+// no breaker is configured
+func (c *Client) CircuitState() CircuitState {
+	if c.breaker == nil {
+		return CircuitClosed
+	}
+	return c.breaker.current()
+}
+
+type circuitBreaker struct {
+	opts CircuitBreakerOptions
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow reports whether a request may be sent
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.opts.OpenDuration {
+			return false
+		}
+		b.setLocked(CircuitHalfOpen)
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of an allowed request. Only
+// transient failures count; a rejected request still proves the endpoint is up.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return // abandoned by the caller; says nothing about the endpoint
+	}
+	if err == nil || !isTransient(err) {
+		b.failures = 0
+		if b.state != CircuitClosed {
+			b.setLocked(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.opts.FailureThreshold {
+		b.openedAt = b.now()
+		if b.state != CircuitOpen {
+			b.setLocked(CircuitOpen)
+		}
+	}
+}
+
+func (b *circuitBreaker) current() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) setLocked(to CircuitState) {
+	from := b.state
+	b.state = to
+	if b.opts.OnStateChange != nil {
+		go b.opts.OnStateChange(from, to)
+	}
+}
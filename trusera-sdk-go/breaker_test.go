@@ -0,0 +1,118 @@
+package trusera
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	var requests atomic.Int32
+	inner := api.server.Config.Handler
+	api.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		inner.ServeHTTP(w, r)
+	})
+
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithCircuitBreaker(CircuitBreakerOptions{FailureThreshold: 2, OpenDuration: time.Hour}))
+	defer client.Close()
+
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		client.Track(NewEvent(EventToolCall, "tool"))
+		client.Flush()
+	}
+	if state := client.CircuitState(); state != CircuitOpen {
+		t.Fatalf("expected circuit to open after 2 failures, got %s", state)
+	}
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+	if err := client.Flush(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected no request while open, got %d requests", got)
+	}
+
+	// Once the open period has passed a probe goes through and closes the circuit
+	api.fail.Store(false)
+	now = now.Add(2 * time.Hour)
+	client.Track(NewEvent(EventToolCall, "probe"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("probe flush failed: %v", err)
+	}
+	if state := client.CircuitState(); state != CircuitClosed {
+		t.Errorf("expected circuit to close after a successful probe, got %s", state)
+	}
+
+	if shorted := client.Stats().FlushShortCircuited; shorted != 1 {
+		t.Errorf("expected 1 short-circuited flush, got %d", shorted)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	transitions := make(chan CircuitState, 8)
+	now := time.Now()
+	b := &circuitBreaker{
+		opts: CircuitBreakerOptions{
+			FailureThreshold: 1,
+			OpenDuration:     time.Minute,
+			OnStateChange:    func(_, to CircuitState) { transitions <- to },
+		},
+		state: CircuitClosed,
+		now:   func() time.Time { return now },
+	}
+	transient := &statusError{code: http.StatusBadGateway}
+
+	b.record(transient)
+	if b.allow() {
+		t.Fatal("expected open circuit to reject requests")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("expected a probe once the open period elapsed")
+	}
+	if b.allow() {
+		t.Fatal("expected only one probe while half-open")
+	}
+
+	b.record(transient)
+	if b.current() != CircuitOpen {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %s", b.current())
+	}
+	if b.allow() {
+		t.Fatal("expected reopened circuit to reject requests")
+	}
+
+	// Callbacks run asynchronously, so only the totals are deterministic
+	counts := map[CircuitState]int{}
+	for i := 0; i < 3; i++ {
+		select {
+		case to := <-transitions:
+			counts[to]++
+		case <-time.After(time.Second):
+			t.Fatalf("expected 3 transitions, got %v", counts)
+		}
+	}
+	if counts[CircuitOpen] != 2 || counts[CircuitHalfOpen] != 1 {
+		t.Errorf("unexpected transitions: %v", counts)
+	}
+}
+
+func TestCircuitBreakerIgnoresRejections(t *testing.T) {
+	b := &circuitBreaker{opts: CircuitBreakerOptions{FailureThreshold: 1}, state: CircuitClosed, now: time.Now}
+	b.record(&statusError{code: http.StatusBadRequest})
+	if b.current() != CircuitClosed {
+		t.Errorf("a 4xx response should not open the circuit, got %s", b.current())
+	}
+}
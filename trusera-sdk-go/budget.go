@@ -0,0 +1,145 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrBudgetExhausted is wrapped by the error returned for an LLM call
+// refused because its budget is spent
+var ErrBudgetExhausted = errors.New("trusera: budget exhausted")
+
+// EventBudgetExceeded is tracked for every LLM call made past a budget
+const EventBudgetExceeded EventType = "budget_exceeded"
+
+// Budget caps the USD an agent may spend on LLM calls, as accrued from the
+// cost_usd of the EventLLMInvoke events it tracks
+type Budget struct {
+	PerAgent   float64 // For everything the client tracks; zero is unlimited
+	PerSession float64 // For each session_id; zero is unlimited
+
+	// Approve, if set, is asked whether a call past the budget may go
+	// ahead, e.g. by asking a human. Without it such calls are refused.
+	Approve func(ctx context.Context, req BudgetRequest) bool
+}
+
+// BudgetRequest describes an LLM call past a budget
+type BudgetRequest struct {
+	Provider  string
+	Model     string
+	SessionID string  // Empty for the agent's budget
+	Spent     float64 // USD spent so far
+	Limit     float64 // USD allowed
+}
+
+// WithBudget enforces a Budget on the LLM integrations, which check it
+// with CheckBudget before each call
+func WithBudget(b Budget) Option {
+	return func(c *Client) {
+		c.budget = &budgetState{Budget: b, sessions: map[string]float64{}}
+	}
+}
+
+// budgetState is the spend accrued against a client's budget
+type budgetState struct {
+	Budget
+
+	mu       sync.Mutex
+	agent    float64
+	sessions map[string]float64
+}
+
+// accrue adds the cost of a tracked LLM invocation to the spend
+func (c *Client) accrue(event Event) {
+	if c.budget == nil || event.Type != EventLLMInvoke {
+		return
+	}
+	usd, _ := event.Payload["cost_usd"].(float64)
+	if usd <= 0 {
+		return
+	}
+	session, _ := event.Metadata["session_id"].(string)
+
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	c.budget.agent += usd
+	if session != "" && c.budget.PerSession > 0 {
+		c.budget.sessions[session] += usd
+	}
+}
+
+// Spent returns the USD accrued by the client and by the session of ctx,
+// if any, since WithBudget was applied
+func (c *Client) Spent(ctx context.Context) (agent, session float64) {
+	if c.budget == nil {
+		return 0, 0
+	}
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	return c.budget.agent, c.budget.sessions[SessionIDFromContext(ctx)]
+}
+
+// CheckBudget reports whether an LLM call may be made under the budget set
+// by WithBudget. A call past the agent's or its session's budget is
+// refused with an error wrapping ErrBudgetExhausted, unless the budget's
+// Approve allows it; either way an EventBudgetExceeded is tracked. Custom
+// LLM clients call it before each call; the OpenAI and Anthropic
+// integrations do so themselves.
+func (c *Client) CheckBudget(ctx context.Context, provider, model string) error {
+	if c.budget == nil {
+		return nil
+	}
+	req := BudgetRequest{Provider: provider, Model: model}
+	session := SessionIDFromContext(ctx)
+
+	c.budget.mu.Lock()
+	switch {
+	case c.budget.PerAgent > 0 && c.budget.agent >= c.budget.PerAgent:
+		req.Spent, req.Limit = c.budget.agent, c.budget.PerAgent
+	case session != "" && c.budget.PerSession > 0 && c.budget.sessions[session] >= c.budget.PerSession:
+		req.SessionID = session
+		req.Spent, req.Limit = c.budget.sessions[session], c.budget.PerSession
+	}
+	c.budget.mu.Unlock()
+	if req.Limit == 0 {
+		return nil
+	}
+
+	approved := c.budget.Approve != nil && c.budget.Approve(ctx, req)
+	action := "blocked"
+	if approved {
+		action = "approved"
+	}
+	event := NewEvent(EventBudgetExceeded, model).
+		WithPayload("provider", provider).
+		WithPayload("model", model).
+		WithPayload("spent_usd", req.Spent).
+		WithPayload("limit_usd", req.Limit).
+		WithPayload("action", action)
+	if req.SessionID != "" {
+		event = event.WithPayload("scope", "session")
+	} else {
+		event = event.WithPayload("scope", "agent")
+	}
+	c.handleError(c.TrackCtx(ctx, event))
+
+	if approved {
+		return nil
+	}
+	if req.SessionID != "" {
+		return fmt.Errorf("%w: session %s spent $%.4f of $%.4f", ErrBudgetExhausted, req.SessionID, req.Spent, req.Limit)
+	}
+	return fmt.Errorf("%w: agent spent $%.4f of $%.4f", ErrBudgetExhausted, req.Spent, req.Limit)
+}
+
+// endBudget forgets the spend of an ended session
+func (c *Client) endBudget(session string) {
+	if c.budget == nil {
+		return
+	}
+	c.budget.mu.Lock()
+	delete(c.budget.sessions, session)
+	c.budget.mu.Unlock()
+}
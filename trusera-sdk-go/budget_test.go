@@ -0,0 +1,90 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func llmEvent(usd float64) Event {
+	return NewEvent(EventLLMInvoke, "gpt-4o").WithPayload("model", "gpt-4o").WithPayload("cost_usd", usd)
+}
+
+func TestBudgetPerAgent(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithBudget(Budget{PerAgent: 1}))
+	defer client.Close()
+	ctx := context.Background()
+
+	client.Track(llmEvent(0.6))
+	if err := client.CheckBudget(ctx, "openai", "gpt-4o"); err != nil {
+		t.Fatalf("expected a call within the budget allowed: %v", err)
+	}
+	client.Track(llmEvent(0.6))
+	err := client.CheckBudget(ctx, "openai", "gpt-4o")
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected the call refused, got %v", err)
+	}
+	if agent, _ := client.Spent(ctx); agent != 1.2 {
+		t.Errorf("expected $1.20 spent, got %v", agent)
+	}
+
+	exceeded := trackedEvents(client, EventBudgetExceeded)
+	if len(exceeded) != 1 || exceeded[0].Payload["action"] != "blocked" || exceeded[0].Payload["scope"] != "agent" {
+		t.Errorf("expected one blocked budget event, got %v", exceeded)
+	}
+}
+
+func TestBudgetPerSession(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithBudget(Budget{PerSession: 0.5}))
+	defer client.Close()
+
+	spender := client.StartSession(context.Background(), "spender")
+	other := client.StartSession(context.Background(), "other")
+	spender.Track(spender.Context(), llmEvent(0.5))
+
+	if err := client.CheckBudget(spender.Context(), "openai", "gpt-4o"); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("expected the spending session refused, got %v", err)
+	}
+	if err := client.CheckBudget(other.Context(), "openai", "gpt-4o"); err != nil {
+		t.Errorf("expected another session allowed: %v", err)
+	}
+
+	spender.End(nil)
+	if _, session := client.Spent(spender.Context()); session != 0 {
+		t.Errorf("expected an ended session's spend forgotten, got %v", session)
+	}
+}
+
+func TestBudgetApproval(t *testing.T) {
+	var asked BudgetRequest
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithBudget(Budget{PerAgent: 0.1, Approve: func(ctx context.Context, req BudgetRequest) bool {
+			asked = req
+			return true
+		}}))
+	defer client.Close()
+
+	client.Track(llmEvent(0.2))
+	if err := client.CheckBudget(context.Background(), "anthropic", "claude-sonnet-4"); err != nil {
+		t.Fatalf("expected the approved call allowed: %v", err)
+	}
+	if asked.Provider != "anthropic" || asked.Spent != 0.2 || asked.Limit != 0.1 {
+		t.Errorf("unexpected approval request: %+v", asked)
+	}
+	if e := trackedEvents(client, EventBudgetExceeded); len(e) != 1 || e[0].Payload["action"] != "approved" {
+		t.Errorf("expected an approved budget event, got %v", e)
+	}
+}
+
+func TestNoBudget(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	client.Track(llmEvent(100))
+	if err := client.CheckBudget(context.Background(), "openai", "gpt-4o"); err != nil {
+		t.Errorf("expected no budget enforced: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package trusera
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// canary applies the EnforcePercent rollout to a verdict in a rejecting
+// mode. Sessions outside the enforced share are downgraded to ModeWarn, so
+// what they would have blocked is recorded but let through. Calls outside a
+// session are bucketed by target instead.
+func (t *interceptingTransport) canary(ctx context.Context, target string, v verdict) verdict {
+	percent := t.options().EnforcePercent
+	if percent <= 0 || percent >= 100 || !v.mode.rejects() {
+		return v
+	}
+	key := SessionIDFromContext(ctx)
+	if key == "" {
+		key = target
+	}
+	v.canaryPercent = percent
+	v.canaryEnforced = canaryBucket(key) < percent
+	if !v.canaryEnforced {
+		v.mode = ModeWarn
+	}
+	return v
+}
+
+// canaryBucket places a key in [0, 100). The same key always lands in the
+// same place, so raising the percentage only adds sessions to enforcement.
+func canaryBucket(key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,122 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// canarySessions returns a session inside and one outside the enforced
+// share of a rollout
+func canarySessions(percent float64) (in, out string) {
+	for i := 0; in == "" || out == ""; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		if canaryBucket(id) < percent {
+			in = id
+		} else {
+			out = id
+		}
+	}
+	return in, out
+}
+
+func TestCanaryRollout(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:    ModeBlock,
+		BlockPatterns:  []string{"/admin"},
+		EnforcePercent: 25,
+	})
+	get := func(session string) error {
+		ctx := ContextWithSessionID(context.Background(), session)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL+"/admin", nil)
+		resp, err := httpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	in, out := canarySessions(25)
+	for i := 0; i < 3; i++ {
+		if err := get(in); !errors.Is(err, ErrBlocked) {
+			t.Fatalf("expected the canary session blocked every time, got %v", err)
+		}
+		if err := get(out); err != nil {
+			t.Fatalf("expected the other session only warned, got %v", err)
+		}
+	}
+
+	var events []Event
+	for _, e := range trackedEvents(client, EventAPICall) {
+		if e.Payload["enforcement_action"] != nil {
+			events = append(events, e)
+		}
+	}
+	if len(events) != 6 {
+		t.Fatalf("expected 6 enforcement events, got %d", len(events))
+	}
+	enforced := 0
+	for _, e := range events {
+		if e.Metadata["canary_percent"] != 25.0 {
+			t.Errorf("unexpected canary metadata %v", e.Metadata)
+		}
+		switch e.Metadata["canary_enforced"] {
+		case true:
+			enforced++
+			if e.Metadata["enforcement_mode"] != "block" {
+				t.Errorf("expected an enforced session in block mode, got %v", e.Metadata)
+			}
+		case false:
+			if e.Metadata["enforcement_mode"] != "warn" || e.Payload["blocked"] != true {
+				t.Errorf("expected the other session warned, got %v %v", e.Payload, e.Metadata)
+			}
+		}
+	}
+	if enforced != 3 {
+		t.Errorf("expected 3 enforced requests, got %d", enforced)
+	}
+}
+
+func TestCanaryBucket(t *testing.T) {
+	if canaryBucket("session-1") != canaryBucket("session-1") {
+		t.Fatal("expected the bucket to be deterministic")
+	}
+	enforced := 0
+	for i := 0; i < 10000; i++ {
+		if canaryBucket(fmt.Sprintf("session-%d", i)) < 10 {
+			enforced++
+		}
+	}
+	if enforced < 800 || enforced > 1200 {
+		t.Errorf("expected about 10%% of sessions enforced, got %d in 10000", enforced)
+	}
+}
+
+func TestCanaryRolloutFromRemotePolicy(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	transport := &interceptingTransport{client: client, opts: InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{"evil"}}}
+
+	in, out := canarySessions(50)
+	client.ApplyPolicy(&RemotePolicy{EnforcePercent: 50})
+	if v := transport.canary(ContextWithSessionID(context.Background(), in), "https://evil.example", transport.evaluate("https://evil.example")); v.mode != ModeBlock || !v.canaryEnforced {
+		t.Errorf("expected the session enforced, got %+v", v)
+	}
+	if v := transport.canary(ContextWithSessionID(context.Background(), out), "https://evil.example", transport.evaluate("https://evil.example")); v.mode != ModeWarn || v.canaryEnforced {
+		t.Errorf("expected the session warned, got %+v", v)
+	}
+
+	client.ApplyPolicy(&RemotePolicy{EnforcePercent: 100})
+	if v := transport.canary(ContextWithSessionID(context.Background(), out), "https://evil.example", transport.evaluate("https://evil.example")); v.mode != ModeBlock || v.canaryPercent != 0 {
+		t.Errorf("expected a full rollout to enforce every session, got %+v", v)
+	}
+}
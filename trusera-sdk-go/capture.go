@@ -0,0 +1,166 @@
+package trusera
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CaptureOptions selects the headers and bodies recorded on the events of
+// intercepted HTTP requests, for audits that need the payloads. Captured
+// bodies are cut to MaxBodyBytes and then redacted; sensitive headers such
+// as Authorization are always masked. ModeAudit captures everything.
+type CaptureOptions struct {
+	// RequestHeaders and ResponseHeaders list the header names to record,
+	// case-insensitively; "*" records all of them. When RequestHeaders is
+	// empty every request header is recorded, as without CaptureOptions;
+	// when ResponseHeaders is empty none are.
+	RequestHeaders  []string
+	ResponseHeaders []string
+
+	RequestBody  bool // Record the request body in place of the body snippet
+	ResponseBody bool // Record the response body; event streams are never captured
+
+	// ContentTypes limits body capture to these media types, such as
+	// "application/json" or "text/*"; any type when empty
+	ContentTypes []string
+
+	MaxBodyBytes int       // 64 KiB when zero
+	Redactor     *Redactor // Scrubs bodies; nil uses the default PII and secret detectors
+}
+
+// captureOptions returns what to record of a request: everything in
+// ModeAudit, otherwise the Capture option, which may be nil
+func (t *interceptingTransport) captureOptions() *CaptureOptions {
+	if t.auditing() {
+		opts := t.localOptions()
+		return &CaptureOptions{
+			RequestHeaders:  []string{"*"},
+			ResponseHeaders: []string{"*"},
+			RequestBody:     true,
+			ResponseBody:    true,
+			MaxBodyBytes:    opts.AuditBodyLimit,
+			Redactor:        opts.AuditRedactor,
+		}
+	}
+	return t.localOptions().Capture
+}
+
+// captureRequest adds the request's headers and body, or a snippet of the
+// body, to its event
+func (t *interceptingTransport) captureRequest(event Event, req *http.Request, body []byte) Event {
+	capture := t.captureOptions()
+	if capture == nil || len(capture.RequestHeaders) == 0 {
+		event = event.WithPayload("headers", sanitizeHeaders(req.Header))
+	} else {
+		event = event.WithPayload("headers", selectHeaders(req.Header, capture.RequestHeaders))
+	}
+
+	if capture != nil && capture.RequestBody {
+		if body == nil || !capture.capturesType(req.Header) {
+			return event
+		}
+		captured, truncated := capture.body(body)
+		return event.
+			WithPayload("body", captured).
+			WithPayload("body_size", len(body)).
+			WithPayload("body_truncated", truncated)
+	}
+
+	if len(body) > maxBodySnippet {
+		return event.WithPayload("body_snippet", string(body[:maxBodySnippet])+"...")
+	} else if len(body) > 0 {
+		return event.WithPayload("body_snippet", string(body))
+	}
+	return event
+}
+
+// captureResponse adds the selected response headers and the captured
+// response body to a response event. The body is read up to the limit and
+// restored, so the caller still sees all of it. Event streams are left
+// alone; the stream parser records their content.
+func (t *interceptingTransport) captureResponse(event Event, resp *http.Response) Event {
+	capture := t.captureOptions()
+	if capture == nil {
+		return event
+	}
+	if len(capture.ResponseHeaders) > 0 {
+		event = event.WithPayload("response_headers", selectHeaders(resp.Header, capture.ResponseHeaders))
+	}
+	if !capture.ResponseBody || resp.Body == nil || resp.Body == http.NoBody ||
+		isEventStream(resp.Header.Get("Content-Type")) || !capture.capturesType(resp.Header) {
+		return event
+	}
+
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, int64(capture.limit())+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+	captured, truncated := capture.body(head)
+	return event.
+		WithPayload("response_body", captured).
+		WithPayload("response_body_truncated", truncated)
+}
+
+// selectHeaders returns the named headers, with sensitive ones masked
+func selectHeaders(h http.Header, names []string) map[string]string {
+	all := sanitizeHeaders(h)
+	for _, name := range names {
+		if name == "*" {
+			return all
+		}
+	}
+	selected := make(map[string]string)
+	for key, value := range all {
+		for _, name := range names {
+			if strings.EqualFold(key, name) {
+				selected[key] = value
+			}
+		}
+	}
+	return selected
+}
+
+// capturesType reports whether a body of the header's content type is
+// captured
+func (c *CaptureOptions) capturesType(h http.Header) bool {
+	if len(c.ContentTypes) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	for _, t := range c.ContentTypes {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// body redacts a body and cuts it to the limit
+func (c *CaptureOptions) body(body []byte) (string, bool) {
+	limit := c.limit()
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+
+	redactor := c.Redactor
+	if redactor == nil {
+		redactor = defaultAuditRedactor
+	}
+	return redactor.RedactString(string(body)), truncated
+}
+
+func (c *CaptureOptions) limit() int {
+	if c.MaxBodyBytes > 0 {
+		return c.MaxBodyBytes
+	}
+	return defaultAuditBodyLimit
+}
@@ -0,0 +1,272 @@
+package trusera
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode says whether a Cassette records or replays traffic
+type CassetteMode string
+
+const (
+	CassetteRecord CassetteMode = "record" // Send requests and record them, replacing the file
+	CassetteReplay CassetteMode = "replay" // Answer requests from the file; never send them
+	CassetteAuto   CassetteMode = "auto"   // Replay if the file exists, record otherwise
+)
+
+// ErrNoInteraction is returned when a replaying cassette has no recorded
+// response left for a request
+var ErrNoInteraction = errors.New("trusera: no recorded interaction")
+
+// Cassette records an interceptor's HTTP traffic to a file and replays it,
+// so tests of an instrumented agent run deterministically and offline. Set
+// it as InterceptorOptions.Cassette: interception, enforcement and events
+// are unaffected; only the forwarding to the network is replaced.
+//
+// Recordings are redacted before they are written. Authorization, cookie
+// and API key headers are replaced, and URLs and text bodies are scrubbed
+// with the cassette's redactor. Requests are matched on method, URL and
+// body after the same redaction, each recorded interaction being replayed
+// once, in order. Response bodies are read in full when recording, so
+// streams are replayed in one piece.
+type Cassette struct {
+	path     string
+	mode     CassetteMode
+	redactor *Redactor
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the redacted form of a request in a cassette
+type RecordedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    RecordedBody      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the redacted form of a response in a cassette
+type RecordedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       RecordedBody      `json:"body,omitempty"`
+}
+
+// RecordedBody is a body in a cassette. Text is stored as a JSON string,
+// binary data as {"base64": ...}.
+type RecordedBody []byte
+
+func (b RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *RecordedBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = RecordedBody(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	if err != nil {
+		return fmt.Errorf("failed to decode body: %w", err)
+	}
+	*b = raw
+	return nil
+}
+
+// NewCassette opens a cassette file. In replay mode, or auto mode with an
+// existing file, its interactions are loaded. redactor scrubs recordings;
+// when nil, DefaultDetectors and SecretDetectors are used.
+func NewCassette(path string, mode CassetteMode, redactor *Redactor) (*Cassette, error) {
+	if redactor == nil {
+		redactor = NewRedactor(append(DefaultDetectors(), SecretDetectors()...)...)
+	}
+	c := &Cassette{path: path, mode: mode, redactor: redactor}
+
+	switch mode {
+	case CassetteRecord:
+		return c, nil
+	case CassetteReplay, CassetteAuto:
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q", mode)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == CassetteAuto {
+		c.mode = CassetteRecord
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var file struct {
+		Interactions []Interaction `json:"interactions"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	c.mode = CassetteReplay
+	c.interactions = file.Interactions
+	c.used = make([]bool, len(file.Interactions))
+	return c, nil
+}
+
+// Recording reports whether the cassette sends and records requests rather
+// than replaying them
+func (c *Cassette) Recording() bool {
+	return c.mode == CassetteRecord
+}
+
+// Interactions returns the interactions recorded or loaded so far
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// roundTrip replays a request or sends it with base and records it
+func (c *Cassette) roundTrip(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := RecordedRequest{
+		Method:  req.Method,
+		URL:     c.redactor.RedactString(req.URL.String()),
+		Headers: sanitizeHeaders(req.Header),
+		Body:    c.redactBody(body),
+	}
+
+	if c.mode == CassetteReplay {
+		return c.replay(req, recorded)
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Headers:    sanitizeHeaders(resp.Header),
+			Body:       c.redactBody(respBody),
+		},
+	})
+	if err := c.saveLocked(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// replay answers a request with the first unused matching interaction
+func (c *Cassette) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.interactions {
+		if c.used[i] || in.Request.Method != recorded.Method || in.Request.URL != recorded.URL ||
+			!bytes.Equal(in.Request.Body, recorded.Body) {
+			continue
+		}
+		c.used[i] = true
+
+		header := make(http.Header, len(in.Response.Headers))
+		for k, v := range in.Response.Headers {
+			if isCassetteHeader(k) {
+				header.Set(k, v)
+			}
+		}
+		return &http.Response{
+			StatusCode:    in.Response.StatusCode,
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, recorded.Method, recorded.URL)
+}
+
+// saveLocked writes the recorded interactions to the file. Called with mu
+// held.
+func (c *Cassette) saveLocked() error {
+	data, err := json.MarshalIndent(map[string]any{"interactions": c.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// redactBody scrubs a text body; binary bodies are kept as they are
+func (c *Cassette) redactBody(body []byte) RecordedBody {
+	if len(body) == 0 || !utf8.Valid(body) {
+		return body
+	}
+	return RecordedBody(c.redactor.RedactString(string(body)))
+}
+
+// readBody reads a request body and puts a fresh copy back on the request
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// isCassetteHeader reports whether a header name is worth keeping; hop and
+// framing headers would confuse a replayed response
+func isCassetteHeader(name string) bool {
+	switch strings.ToLower(name) {
+	case "content-length", "transfer-encoding", "connection":
+		return false
+	}
+	return true
+}
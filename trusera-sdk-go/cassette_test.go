@@ -0,0 +1,103 @@
+package trusera
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/binary" {
+			w.Write([]byte{0xff, 0x00, 0xfe})
+			return
+		}
+		io.WriteString(w, `{"reply": "contact jane@example.com"}`)
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	send := func(client *http.Client, path string) (string, error) {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(`{"user": "bob@example.com"}`))
+		req.Header.Set("Authorization", "Bearer sk-secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), nil
+	}
+
+	recorder, err := NewCassette(path, CassetteAuto, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.Recording() {
+		t.Fatal("expected a missing cassette to be recorded")
+	}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := WrapHTTPClient(nil, client, InterceptorOptions{Cassette: recorder})
+	if body, err := send(httpClient, "/chat"); err != nil || !strings.Contains(body, "jane@example.com") {
+		t.Fatalf("expected the live response while recording, got %q, %v", body, err)
+	}
+	if _, err := send(httpClient, "/binary"); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"sk-secret", "bob@example.com", "jane@example.com"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("expected %q to be redacted from the cassette:\n%s", leaked, data)
+		}
+	}
+
+	replayer, err := NewCassette(path, CassetteAuto, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayer.Recording() || len(replayer.Interactions()) != 2 {
+		t.Fatalf("expected 2 interactions to replay, got %d", len(replayer.Interactions()))
+	}
+	sink := &memorySink{}
+	client = NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink))
+	defer client.Close()
+	httpClient = WrapHTTPClient(nil, client, InterceptorOptions{Cassette: replayer})
+
+	body, err := send(httpClient, "/chat")
+	if err != nil || body != `{"reply": "contact [REDACTED:email]"}` {
+		t.Errorf("expected the recorded response, got %q, %v", body, err)
+	}
+	if body, err := send(httpClient, "/binary"); err != nil || body != "\xff\x00\xfe" {
+		t.Errorf("expected the binary body to round-trip, got %q, %v", body, err)
+	}
+	if _, err := send(httpClient, "/chat"); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("expected each interaction to replay once, got %v", err)
+	}
+
+	client.Flush()
+	if names := sink.names(); len(names) != 6 || names[1] != "response" || names[5] != "error" {
+		t.Errorf("expected replayed requests to be intercepted as usual, got %v", names)
+	}
+}
+
+func TestCassetteReplayMissingFile(t *testing.T) {
+	_, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay, nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+	if _, err := NewCassette("cassette.json", "rewind", nil); err == nil {
+		t.Error("expected an unknown mode error")
+	}
+}
@@ -0,0 +1,1202 @@
+package trusera
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CELProgram is a compiled CEL expression that can be evaluated against variables.
+//
+// The SDK implements the commonly used subset of CEL without external dependencies:
+// literals (int, double, string, bool, null, lists), field selection and indexing,
+// the operators ! - * / % + < <= > >= == != in && || and ?:, the string methods
+// contains, startsWith, endsWith, matches, lowerAscii and upperAscii, and the
+// functions size, has, int, double and string. Other functions and methods,
+// including the macros all, exists, exists_one, map and filter, are
+// rejected when compiling. Integer arithmetic that overflows is an error.
+type CELProgram struct {
+	source string
+	root   celNode
+	vars   []string // variables referenced, in order of first use
+}
+
+// celFunctions and celMethods are the functions and methods the evaluator
+// implements; calls to anything else fail to compile. celMacros are CEL's
+// comprehension macros, which are not implemented.
+var (
+	celFunctions = map[string]bool{"size": true, "string": true, "int": true, "double": true}
+	celMethods   = map[string]bool{
+		"contains": true, "startsWith": true, "endsWith": true, "matches": true,
+		"lowerAscii": true, "upperAscii": true, "size": true,
+	}
+	celMacros = map[string]bool{"all": true, "exists": true, "exists_one": true, "map": true, "filter": true}
+)
+
+// CompileCEL parses a CEL expression into an evaluable program
+func CompileCEL(expr string) (*CELProgram, error) {
+	tokens, err := celLex(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CEL expression %q: %w", expr, err)
+	}
+
+	p := &celParser{tokens: tokens}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CEL expression %q: %w", expr, err)
+	}
+	if p.peek().kind != celTokEOF {
+		return nil, fmt.Errorf("invalid CEL expression %q: unexpected %q", expr, p.peek().text)
+	}
+
+	return &CELProgram{source: expr, root: root, vars: p.vars}, nil
+}
+
+// MustCompileCEL compiles a CEL expression or panics on error
+func MustCompileCEL(expr string) *CELProgram {
+	prog, err := CompileCEL(expr)
+	if err != nil {
+		panic(err)
+	}
+	return prog
+}
+
+// String returns the original expression source
+func (p *CELProgram) String() string {
+	return p.source
+}
+
+// Eval evaluates the program and returns the resulting value
+func (p *CELProgram) Eval(vars map[string]any) (any, error) {
+	return p.root.eval(vars)
+}
+
+// EvalBool evaluates the program and requires a boolean result
+func (p *CELProgram) EvalBool(vars map[string]any) (bool, error) {
+	v, err := p.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression %q returned %T, expected bool", p.source, v)
+	}
+	return b, nil
+}
+
+// errCELNoSuchKey is returned when a selected field does not exist
+var errCELNoSuchKey = errors.New("no such key")
+
+// errCELOverflow is returned when integer arithmetic overflows
+var errCELOverflow = errors.New("integer overflow")
+
+// Lexer
+
+type celTokenKind int
+
+const (
+	celTokEOF celTokenKind = iota
+	celTokIdent
+	celTokInt
+	celTokFloat
+	celTokString
+	celTokOp
+)
+
+type celToken struct {
+	kind celTokenKind
+	text string
+	pos  int
+}
+
+// celOperators lists multi- and single-character operators, longest first
+var celOperators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"<", ">", "!", "+", "-", "*", "/", "%", "?", ":", ".", ",", "(", ")", "[", "]",
+}
+
+func celLex(src string) ([]celToken, error) {
+	var tokens []celToken
+	i := 0
+
+	for i < len(src) {
+		c := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, celToken{kind: celTokIdent, text: src[start:i], pos: start})
+
+		case unicode.IsDigit(c):
+			start := i
+			kind := celTokInt
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.' || src[i] == 'e' || src[i] == 'E') {
+				if src[i] == '.' || src[i] == 'e' || src[i] == 'E' {
+					// Stop at ".method" so that 1.size() style selections still lex
+					if src[i] == '.' && (i+1 >= len(src) || !unicode.IsDigit(rune(src[i+1]))) {
+						break
+					}
+					kind = celTokFloat
+				}
+				i++
+			}
+			tokens = append(tokens, celToken{kind: kind, text: src[start:i], pos: start})
+
+		case c == '"' || c == '\'':
+			start := i
+			quote := src[i]
+			i++
+			var sb strings.Builder
+			for {
+				if i >= len(src) {
+					return nil, fmt.Errorf("unterminated string at offset %d", start)
+				}
+				if src[i] == quote {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					switch src[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case 'r':
+						sb.WriteByte('\r')
+					default:
+						sb.WriteByte(src[i])
+					}
+					i++
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, celToken{kind: celTokString, text: sb.String(), pos: start})
+
+		default:
+			matched := false
+			for _, op := range celOperators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, celToken{kind: celTokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+
+	tokens = append(tokens, celToken{kind: celTokEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// Parser
+
+type celParser struct {
+	tokens []celToken
+	pos    int
+	vars   []string // identifiers referenced as variables
+}
+
+// checkCall rejects a call the evaluator does not implement
+func checkCall(name string, method bool) error {
+	switch {
+	case celMacros[name]:
+		return fmt.Errorf("unsupported macro %s()", name)
+	case method && !celMethods[name]:
+		return fmt.Errorf("unsupported method %s()", name)
+	case !method && !celFunctions[name]:
+		return fmt.Errorf("unsupported function %s()", name)
+	}
+	return nil
+}
+
+func (p *celParser) peek() celToken {
+	return p.tokens[p.pos]
+}
+
+func (p *celParser) next() celToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != celTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *celParser) acceptOp(op string) bool {
+	tok := p.peek()
+	if tok.kind == celTokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *celParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		tok := p.peek()
+		if tok.kind == celTokEOF {
+			return fmt.Errorf("expected %q at end of expression", op)
+		}
+		return fmt.Errorf("expected %q at offset %d, got %q", op, tok.pos, tok.text)
+	}
+	return nil
+}
+
+// parseExpr handles the conditional operator: or ? expr : expr
+func (p *celParser) parseExpr() (celNode, error) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.acceptOp("?") {
+		return cond, nil
+	}
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectOp(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &celTernary{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+func (p *celParser) parseOr() (celNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &celLogical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *celParser) parseAnd() (celNode, error) {
+	left, err := p.parseRelation()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseRelation()
+		if err != nil {
+			return nil, err
+		}
+		left = &celLogical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *celParser) parseRelation() (celNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		op := ""
+		switch {
+		case tok.kind == celTokOp && (tok.text == "==" || tok.text == "!=" || tok.text == "<" ||
+			tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+			op = tok.text
+		case tok.kind == celTokIdent && tok.text == "in":
+			op = "in"
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &celBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *celParser) parseAdditive() (celNode, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != celTokOp || (tok.text != "+" && tok.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &celBinary{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *celParser) parseMultiplicative() (celNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != celTokOp || (tok.text != "*" && tok.text != "/" && tok.text != "%") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &celBinary{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *celParser) parseUnary() (celNode, error) {
+	if p.acceptOp("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &celNot{operand: operand}, nil
+	}
+	if p.acceptOp("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &celNegate{operand: operand}, nil
+	}
+	return p.parseMember()
+}
+
+func (p *celParser) parseMember() (celNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.acceptOp("."):
+			tok := p.next()
+			if tok.kind != celTokIdent {
+				return nil, fmt.Errorf("expected field name at offset %d", tok.pos)
+			}
+			if p.acceptOp("(") {
+				if err := checkCall(tok.text, true); err != nil {
+					return nil, err
+				}
+				args, err := p.parseArgs()
+				if err != nil {
+					return nil, err
+				}
+				call := &celCall{name: tok.text, target: node, args: args}
+				if err := call.compilePattern(); err != nil {
+					return nil, err
+				}
+				node = call
+			} else {
+				node = &celSelect{operand: node, field: tok.text}
+			}
+
+		case p.acceptOp("["):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			node = &celIndex{operand: node, index: index}
+
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *celParser) parseArgs() ([]celNode, error) {
+	var args []celNode
+	if p.acceptOp(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.acceptOp(")") {
+			return args, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *celParser) parsePrimary() (celNode, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case celTokInt:
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", tok.text)
+		}
+		return &celLiteral{value: v}, nil
+
+	case celTokFloat:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return &celLiteral{value: v}, nil
+
+	case celTokString:
+		return &celLiteral{value: tok.text}, nil
+
+	case celTokIdent:
+		switch tok.text {
+		case "true":
+			return &celLiteral{value: true}, nil
+		case "false":
+			return &celLiteral{value: false}, nil
+		case "null":
+			return &celLiteral{value: nil}, nil
+		}
+		if p.acceptOp("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			if tok.text == "has" {
+				if len(args) != 1 {
+					return nil, errors.New("has() takes exactly one argument")
+				}
+				sel, ok := args[0].(*celSelect)
+				if !ok {
+					return nil, errors.New("has() argument must be a field selection")
+				}
+				return &celHas{selection: sel}, nil
+			}
+			if err := checkCall(tok.text, false); err != nil {
+				return nil, err
+			}
+			return &celCall{name: tok.text, args: args}, nil
+		}
+		found := false
+		for _, v := range p.vars {
+			found = found || v == tok.text
+		}
+		if !found {
+			p.vars = append(p.vars, tok.text)
+		}
+		return &celIdent{name: tok.text}, nil
+
+	case celTokOp:
+		switch tok.text {
+		case "(":
+			node, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			var elems []celNode
+			if p.acceptOp("]") {
+				return &celList{elems: elems}, nil
+			}
+			for {
+				elem, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+				if p.acceptOp("]") {
+					return &celList{elems: elems}, nil
+				}
+				if err := p.expectOp(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case celTokEOF:
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+// AST nodes
+
+type celNode interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type celLiteral struct{ value any }
+
+func (n *celLiteral) eval(map[string]any) (any, error) { return n.value, nil }
+
+type celIdent struct{ name string }
+
+func (n *celIdent) eval(vars map[string]any) (any, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %q", n.name)
+	}
+	return celNormalize(v), nil
+}
+
+type celList struct{ elems []celNode }
+
+func (n *celList) eval(vars map[string]any) (any, error) {
+	out := make([]any, 0, len(n.elems))
+	for _, e := range n.elems {
+		v, err := e.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+type celSelect struct {
+	operand celNode
+	field   string
+}
+
+func (n *celSelect) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	field, ok := celLookup(v, n.field)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errCELNoSuchKey, n.field)
+	}
+	return field, nil
+}
+
+type celHas struct{ selection *celSelect }
+
+func (n *celHas) eval(vars map[string]any) (any, error) {
+	v, err := n.selection.operand.eval(vars)
+	if err != nil {
+		if errors.Is(err, errCELNoSuchKey) {
+			return false, nil
+		}
+		return nil, err
+	}
+	_, ok := celLookup(v, n.selection.field)
+	return ok, nil
+}
+
+type celIndex struct {
+	operand celNode
+	index   celNode
+}
+
+func (n *celIndex) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if list, ok := v.([]any); ok {
+		i, ok := idx.(int64)
+		if !ok {
+			return nil, fmt.Errorf("list index must be int, got %T", idx)
+		}
+		if i < 0 || int(i) >= len(list) {
+			return nil, fmt.Errorf("index %d out of range", i)
+		}
+		return list[i], nil
+	}
+
+	key, ok := idx.(string)
+	if !ok {
+		return nil, fmt.Errorf("map key must be string, got %T", idx)
+	}
+	field, ok := celLookup(v, key)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errCELNoSuchKey, key)
+	}
+	return field, nil
+}
+
+type celNot struct{ operand celNode }
+
+func (n *celNot) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator ! requires bool, got %T", v)
+	}
+	return !b, nil
+}
+
+type celNegate struct{ operand celNode }
+
+func (n *celNegate) eval(vars map[string]any) (any, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case int64:
+		if x == math.MinInt64 {
+			return nil, errCELOverflow
+		}
+		return -x, nil
+	case float64:
+		return -x, nil
+	}
+	return nil, fmt.Errorf("operator - requires a number, got %T", v)
+}
+
+type celTernary struct {
+	cond, then, otherwise celNode
+}
+
+func (n *celTernary) eval(vars map[string]any) (any, error) {
+	v, err := n.cond.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("conditional requires bool, got %T", v)
+	}
+	if b {
+		return n.then.eval(vars)
+	}
+	return n.otherwise.eval(vars)
+}
+
+// celLogical implements CEL's commutative && and ||: an error on one side is
+// absorbed when the other side alone determines the result.
+type celLogical struct {
+	op          string
+	left, right celNode
+}
+
+func (n *celLogical) eval(vars map[string]any) (any, error) {
+	short := n.op == "||"
+
+	lv, lerr := n.left.eval(vars)
+	if lerr == nil {
+		if b, ok := lv.(bool); ok && b == short {
+			return short, nil
+		}
+	}
+
+	rv, rerr := n.right.eval(vars)
+	if rerr == nil {
+		if b, ok := rv.(bool); ok && b == short {
+			return short, nil
+		}
+	}
+
+	if lerr != nil {
+		return nil, lerr
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	if _, ok := lv.(bool); !ok {
+		return nil, fmt.Errorf("operator %s requires bool, got %T", n.op, lv)
+	}
+	if _, ok := rv.(bool); !ok {
+		return nil, fmt.Errorf("operator %s requires bool, got %T", n.op, rv)
+	}
+	return !short, nil
+}
+
+type celBinary struct {
+	op          string
+	left, right celNode
+}
+
+func (n *celBinary) eval(vars map[string]any) (any, error) {
+	lv, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return celEqual(lv, rv), nil
+	case "!=":
+		return !celEqual(lv, rv), nil
+	case "<", "<=", ">", ">=":
+		return celCompare(n.op, lv, rv)
+	case "in":
+		return celIn(lv, rv)
+	case "+":
+		return celAdd(lv, rv)
+	default:
+		return celArith(n.op, lv, rv)
+	}
+}
+
+type celCall struct {
+	name   string
+	target celNode // nil for global functions
+	args   []celNode
+	re     *regexp.Regexp // literal pattern of matches(), compiled once
+}
+
+// compilePattern compiles the pattern of a matches() call when it is a
+// string literal, so it is not compiled again on every evaluation
+func (n *celCall) compilePattern() error {
+	if n.name != "matches" || len(n.args) != 1 {
+		return nil
+	}
+	lit, ok := n.args[0].(*celLiteral)
+	if !ok {
+		return nil
+	}
+	pattern, ok := lit.value.(string)
+	if !ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("matches(): %w", err)
+	}
+	n.re = re
+	return nil
+}
+
+func (n *celCall) eval(vars map[string]any) (any, error) {
+	var target any
+	if n.target != nil {
+		v, err := n.target.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		target = v
+	}
+
+	if n.re != nil {
+		s, ok := target.(string)
+		if !ok {
+			return nil, fmt.Errorf("method %s() not supported on %T", n.name, target)
+		}
+		return n.re.MatchString(s), nil
+	}
+
+	args := make([]any, 0, len(n.args))
+	for _, a := range n.args {
+		v, err := a.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	if n.target == nil {
+		return celGlobalFunc(n.name, args)
+	}
+	return celMethod(n.name, target, args)
+}
+
+func celGlobalFunc(name string, args []any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s() takes exactly one argument", name)
+	}
+	arg := args[0]
+
+	switch name {
+	case "size":
+		return celSize(arg)
+	case "string":
+		switch v := arg.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case "int":
+		switch v := arg.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return nil, fmt.Errorf("int(): %w", errCELOverflow)
+			}
+			return int64(v), nil
+		case string:
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("int(): %w", err)
+			}
+			return i, nil
+		}
+	case "double":
+		switch v := arg.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("double(): %w", err)
+			}
+			return f, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	return nil, fmt.Errorf("%s() does not accept %T", name, arg)
+}
+
+func celMethod(name string, target any, args []any) (any, error) {
+	if name == "size" && len(args) == 0 {
+		return celSize(target)
+	}
+
+	s, ok := target.(string)
+	if !ok {
+		return nil, fmt.Errorf("method %s() not supported on %T", name, target)
+	}
+
+	switch name {
+	case "lowerAscii", "upperAscii":
+		if len(args) != 0 {
+			return nil, fmt.Errorf("%s() takes no arguments", name)
+		}
+		if name == "lowerAscii" {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s() takes exactly one argument", name)
+	}
+	arg, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s() requires a string argument, got %T", name, args[0])
+	}
+
+	switch name {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	case "matches":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("matches(): %w", err)
+		}
+		return re.MatchString(s), nil
+	}
+	return nil, fmt.Errorf("unknown method %s()", name)
+}
+
+// Value helpers
+
+// celNormalize converts Go values into the small set of types the evaluator understands
+func celNormalize(v any) any {
+	switch x := v.(type) {
+	case nil, bool, string, int64, float64, []any, map[string]any:
+		return x
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint:
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		return int64(x)
+	case float32:
+		return float64(x)
+	case EventType:
+		return string(x)
+	case map[string]string:
+		out := make(map[string]any, len(x))
+		for k, val := range x {
+			out[k] = val
+		}
+		return out
+	case []string:
+		out := make([]any, len(x))
+		for i, val := range x {
+			out[i] = val
+		}
+		return out
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = celNormalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[iter.Key().String()] = iter.Value().Interface()
+		}
+		return out
+	}
+	return v
+}
+
+func celLookup(v any, key string) (any, bool) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	field, ok := m[key]
+	if !ok {
+		return nil, false
+	}
+	return celNormalize(field), true
+}
+
+func celNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+func celEqual(a, b any) bool {
+	if an, ok := celNumber(a); ok {
+		if bn, ok := celNumber(b); ok {
+			return an == bn
+		}
+		return false
+	}
+
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !celEqual(av[i], celNormalize(bv[i])) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func celCompare(op string, a, b any) (any, error) {
+	var cmp int
+
+	if an, ok := celNumber(a); ok {
+		bn, ok := celNumber(b)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %T with %T", a, b)
+		}
+		switch {
+		case an < bn:
+			cmp = -1
+		case an > bn:
+			cmp = 1
+		}
+	} else if as, ok := a.(string); ok {
+		bs, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %T with %T", a, b)
+		}
+		cmp = strings.Compare(as, bs)
+	} else {
+		return nil, fmt.Errorf("operator %s not supported on %T", op, a)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func celIn(elem, container any) (any, error) {
+	switch c := container.(type) {
+	case []any:
+		for _, item := range c {
+			if celEqual(elem, celNormalize(item)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := elem.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := c[key]
+		return found, nil
+	}
+	return nil, fmt.Errorf("operator in requires a list or map, got %T", container)
+}
+
+func celAdd(a, b any) (any, error) {
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return av + bv, nil
+		}
+	case []any:
+		if bv, ok := b.([]any); ok {
+			out := make([]any, 0, len(av)+len(bv))
+			out = append(out, av...)
+			return append(out, bv...), nil
+		}
+	}
+	return celArith("+", a, b)
+}
+
+func celArith(op string, a, b any) (any, error) {
+	ai, aInt := a.(int64)
+	bi, bInt := b.(int64)
+	if aInt && bInt {
+		switch op {
+		case "+":
+			if (bi > 0 && ai > math.MaxInt64-bi) || (bi < 0 && ai < math.MinInt64-bi) {
+				return nil, errCELOverflow
+			}
+			return ai + bi, nil
+		case "-":
+			if (bi < 0 && ai > math.MaxInt64+bi) || (bi > 0 && ai < math.MinInt64+bi) {
+				return nil, errCELOverflow
+			}
+			return ai - bi, nil
+		case "*":
+			if ai != 0 && bi != 0 {
+				p := ai * bi
+				if p/bi != ai || (ai == -1 && bi == math.MinInt64) || (bi == -1 && ai == math.MinInt64) {
+					return nil, errCELOverflow
+				}
+				return p, nil
+			}
+			return int64(0), nil
+		case "/", "%":
+			if bi == 0 {
+				return nil, errors.New("division by zero")
+			}
+			if bi == -1 && ai == math.MinInt64 {
+				return nil, errCELOverflow
+			}
+			if op == "/" {
+				return ai / bi, nil
+			}
+			return ai % bi, nil
+		}
+	}
+
+	af, ok1 := celNumber(a)
+	bf, ok2 := celNumber(b)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("operator %s not supported on %T and %T", op, a, b)
+	}
+	switch op {
+	case "+":
+		return af + bf, nil
+	case "-":
+		return af - bf, nil
+	case "*":
+		return af * bf, nil
+	case "/":
+		if bf == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return af / bf, nil
+	}
+	return nil, fmt.Errorf("operator %s not supported on doubles", op)
+}
+
+func celSize(v any) (any, error) {
+	switch x := v.(type) {
+	case string:
+		return int64(len([]rune(x))), nil
+	case []any:
+		return int64(len(x)), nil
+	case map[string]any:
+		return int64(len(x)), nil
+	}
+	return nil, fmt.Errorf("size() not supported on %T", v)
+}
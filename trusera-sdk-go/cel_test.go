@@ -0,0 +1,150 @@
+package trusera
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCELEvalBool(t *testing.T) {
+	vars := map[string]any{
+		"event": map[string]any{
+			"type": "data_access",
+			"name": "query_users",
+		},
+		"payload": map[string]any{
+			"sensitivity": "high",
+			"rows":        42,
+			"tables":      []string{"users", "orders"},
+			"ratio":       0.5,
+		},
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{"equality and", `event.type == "data_access" && payload.sensitivity == "high"`, true},
+		{"single quotes", `event.type == 'data_access'`, true},
+		{"inequality", `event.type != "tool_call"`, true},
+		{"int comparison", `payload.rows > 40`, true},
+		{"int vs double", `payload.rows >= 42.0`, true},
+		{"double comparison", `payload.ratio < 1`, true},
+		{"arithmetic", `payload.rows * 2 - 4 == 80`, true},
+		{"or", `event.type == "tool_call" || payload.rows == 42`, true},
+		{"not", `!(payload.rows == 42)`, false},
+		{"in list", `"users" in payload.tables`, true},
+		{"not in list", `"secrets" in payload.tables`, false},
+		{"in literal list", `event.type in ["data_access", "file_write"]`, true},
+		{"in map", `"rows" in payload`, true},
+		{"startsWith", `event.name.startsWith("query_")`, true},
+		{"endsWith", `event.name.endsWith("users")`, true},
+		{"contains", `event.name.contains("_us")`, true},
+		{"matches", `event.name.matches("^query_[a-z]+$")`, true},
+		{"matches computed pattern", `event.name.matches("^" + "query")`, true},
+		{"lowerAscii", `"ABC".lowerAscii() == "abc"`, true},
+		{"size function", `size(payload.tables) == 2`, true},
+		{"size method", `event.name.size() == 11`, true},
+		{"has present", `has(payload.sensitivity)`, true},
+		{"has missing", `has(payload.owner)`, false},
+		{"index", `payload.tables[1] == "orders"`, true},
+		{"map index", `payload["sensitivity"] == "high"`, true},
+		{"ternary", `(payload.rows > 10 ? "many" : "few") == "many"`, true},
+		{"conversion", `string(payload.rows) == "42" && int("7") == 7 && double(1) == 1.0`, true},
+		{"missing field absorbed by or", `payload.owner == "x" || true`, true},
+		{"missing field absorbed by and", `false && payload.owner == "x"`, false},
+		{"null", `null == null`, true},
+		{"string concat", `"a" + "b" == "ab"`, true},
+		{"negative", `-payload.rows < 0`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := CompileCEL(tt.expr)
+			if err != nil {
+				t.Fatalf("compile failed: %v", err)
+			}
+
+			got, err := prog.EvalBool(vars)
+			if err != nil {
+				t.Fatalf("eval failed: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCELCompileErrors(t *testing.T) {
+	tests := []string{
+		`event.type ==`,
+		`"unterminated`,
+		`(a && b`,
+		`a @ b`,
+		`has(a)`,
+		`[1, 2`,
+		`a b`,
+		`event.name.matches("[")`,
+		`unknownFunc(1)`,
+		`payload.tables.exists(t, t == "users")`,
+		`payload.tables.all(t, t != "secrets")`,
+		`event.name.reverse()`,
+	}
+
+	for _, expr := range tests {
+		if _, err := CompileCEL(expr); err == nil {
+			t.Errorf("expected compile error for %q", expr)
+		}
+	}
+}
+
+func TestCELEvalErrors(t *testing.T) {
+	vars := map[string]any{"payload": map[string]any{"n": 1, "big": int64(math.MaxInt64), "small": int64(math.MinInt64 + 1)}}
+
+	tests := []string{
+		`missing == 1`,
+		`payload.absent == 1`,
+		`payload.n / 0 == 1`,
+		`payload.n`,
+		`"a" < 1`,
+		`payload.big + 1 > 0`,
+		`payload.big * 2 > 0`,
+		`payload.small - 2 < 0`,
+		`-(payload.small - 1) > 0`,
+		`int(1e19) > 0`,
+		`payload.n.matches("1")`,
+	}
+
+	for _, expr := range tests {
+		prog, err := CompileCEL(expr)
+		if err != nil {
+			t.Fatalf("unexpected compile error for %q: %v", expr, err)
+		}
+		if _, err := prog.EvalBool(vars); err == nil {
+			t.Errorf("expected eval error for %q", expr)
+		}
+	}
+}
+
+func TestCELMatchesPrecompiled(t *testing.T) {
+	prog := MustCompileCEL(`event.name.matches("^query_")`)
+	call, ok := prog.root.(*celCall)
+	if !ok || call.re == nil {
+		t.Fatalf("expected the literal pattern compiled, got %#v", prog.root)
+	}
+	if got, _ := prog.EvalBool(map[string]any{"event": map[string]any{"name": "query_users"}}); !got {
+		t.Error("expected the precompiled pattern to match")
+	}
+}
+
+func TestMustCompileCELPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for invalid expression")
+		}
+	}()
+
+	MustCompileCEL("(")
+}
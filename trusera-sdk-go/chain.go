@@ -0,0 +1,286 @@
+package trusera
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrChainBroken is returned by VerifyChain when events are missing,
+// reordered or modified
+var ErrChainBroken = errors.New("hash chain broken")
+
+// HashChainOptions configures WithHashChain
+type HashChainOptions struct {
+	// CheckpointEvery emits an EventChainCheckpoint after every n events of
+	// a chain (default 100). Chains are also checkpointed when their session
+	// ends and when the client closes.
+	CheckpointEvery int
+	// LogPath, if set, is a file the chained events are appended to as JSON
+	// lines, a local audit trail that does not depend on delivery. Read it
+	// back with ReadAuditLog.
+	LogPath string
+}
+
+// WithHashChain links every event to the one before it in its chain: the
+// events of a session, or of the agent outside of sessions. Each event's
+// metadata gets chain_id (the session ID, or "agent"), chain_seq and
+// prev_hash, the SHA-256 of the previous event's canonical form (see
+// CanonicalEvent). Removing, reordering or modifying an event breaks the
+// chain, which VerifyChain detects. Combined with WithEventSigning, the chain
+// fields are signed too.
+func WithHashChain(opts HashChainOptions) Option {
+	return func(c *Client) {
+		if opts.CheckpointEvery <= 0 {
+			opts.CheckpointEvery = 100
+		}
+		c.chain = &hashChain{opts: opts, heads: make(map[string]*chainHead)}
+	}
+}
+
+// agentChain is the chain ID of events tracked outside of sessions
+const agentChain = "agent"
+
+// hashChain holds the head of every chain
+type hashChain struct {
+	opts HashChainOptions
+
+	mu     sync.Mutex
+	heads  map[string]*chainHead
+	log    *os.File
+	logErr error
+	cipher *lineCipher
+}
+
+type chainHead struct {
+	seq  int64
+	hash string
+}
+
+// link adds an event to its chain. It returns the checkpoint to track when
+// one is due.
+func (h *hashChain) link(event Event) (Event, *Event, error) {
+	if event.Type == EventChainCheckpoint {
+		return event, nil, nil
+	}
+	id, _ := event.Metadata["session_id"].(string)
+	if id == "" {
+		id = agentChain
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	head := h.heads[id]
+	if head == nil {
+		head = &chainHead{}
+		h.heads[id] = head
+	}
+	// The metadata map may be shared with the caller's copy of the event,
+	// which must not change the chained one if it is tracked again
+	event.Metadata = maps.Clone(event.Metadata)
+	event = event.WithMetadata("chain_id", id).
+		WithMetadata("chain_seq", head.seq).
+		WithMetadata("prev_hash", head.hash)
+	hash, err := eventHash(event)
+	if err != nil {
+		return event, nil, err
+	}
+	head.seq++
+	head.hash = hash
+
+	ended := event.Type == EventSession && event.Payload["action"] == "end" && id != agentChain
+	if !ended && head.seq%int64(h.opts.CheckpointEvery) != 0 {
+		return event, nil, nil
+	}
+	checkpoint := newCheckpoint(id, head)
+	if ended {
+		delete(h.heads, id)
+	}
+	return event, &checkpoint, nil
+}
+
+// checkpoints returns a checkpoint of every chain with events since its last
+func (h *hashChain) checkpoints() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]string, 0, len(h.heads))
+	for id, head := range h.heads {
+		if head.seq%int64(h.opts.CheckpointEvery) != 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	events := make([]Event, len(ids))
+	for i, id := range ids {
+		events[i] = newCheckpoint(id, h.heads[id])
+	}
+	return events
+}
+
+func newCheckpoint(id string, head *chainHead) Event {
+	return NewEvent(EventChainCheckpoint, id).
+		WithPayload("chain_id", id).
+		WithPayload("events", head.seq).
+		WithPayload("head_hash", head.hash)
+}
+
+// record appends an event to the audit log
+func (h *hashChain) record(event Event) {
+	if h.opts.LogPath == "" {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err == nil {
+		data, err = h.cipher.seal(data)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil && h.log == nil {
+		h.log, err = os.OpenFile(h.opts.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	}
+	if err == nil {
+		_, err = h.log.Write(append(data, '\n'))
+	}
+	if err != nil && h.logErr == nil {
+		h.logErr = fmt.Errorf("failed to write audit log: %w", err)
+	}
+}
+
+// close closes the audit log, returning the first write error
+func (h *hashChain) close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := h.logErr
+	if h.log != nil {
+		err = errors.Join(err, h.log.Close())
+		h.log = nil
+	}
+	return err
+}
+
+// eventHash is the hex SHA-256 of an event's canonical form
+func eventHash(event Event) (string, error) {
+	data, err := CanonicalEvent(event)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyChain checks the hash chains of a set of events, e.g. read from an
+// audit log or exported from Trusera, in any order. It returns an error
+// wrapping ErrChainBroken when an event of a chain is missing or was
+// modified, or when a checkpoint does not match its chain. Events without
+// chain metadata are ignored.
+func VerifyChain(events []Event) error {
+	chains := make(map[string][]Event)
+	var checkpoints []Event
+	for _, e := range events {
+		if e.Type == EventChainCheckpoint {
+			checkpoints = append(checkpoints, e)
+			continue
+		}
+		if id, _ := e.Metadata["chain_id"].(string); id != "" {
+			chains[id] = append(chains[id], e)
+		}
+	}
+
+	// hashes[id][n] is the hash of the chain's first n events
+	hashes := make(map[string][]string)
+	for id, chain := range chains {
+		sort.SliceStable(chain, func(i, j int) bool { return chainSeq(chain[i]) < chainSeq(chain[j]) })
+		prev := []string{""}
+		for i, e := range chain {
+			if seq := chainSeq(e); seq != int64(i) {
+				return fmt.Errorf("%w: chain %s is missing event %d", ErrChainBroken, id, i)
+			}
+			if got, _ := e.Metadata["prev_hash"].(string); got != prev[i] {
+				return fmt.Errorf("%w: chain %s event %d (%s) does not follow event %d", ErrChainBroken, id, i, e.ID, i-1)
+			}
+			hash, err := eventHash(e)
+			if err != nil {
+				return err
+			}
+			prev = append(prev, hash)
+		}
+		hashes[id] = prev
+	}
+
+	for _, cp := range checkpoints {
+		id, _ := cp.Payload["chain_id"].(string)
+		n := int64(-1)
+		if v, ok := toInt64(cp.Payload["events"]); ok {
+			n = v
+		}
+		head, _ := cp.Payload["head_hash"].(string)
+		known := hashes[id]
+		if n < 0 || n >= int64(len(known)) {
+			return fmt.Errorf("%w: checkpoint %s covers %d events of chain %s, found %d", ErrChainBroken, cp.ID, n, id, len(known)-1)
+		}
+		if known[n] != head {
+			return fmt.Errorf("%w: checkpoint %s does not match chain %s", ErrChainBroken, cp.ID, id)
+		}
+	}
+	return nil
+}
+
+// chainSeq reads chain_seq, which is a float64 once decoded from JSON
+func chainSeq(e Event) int64 {
+	if v, ok := toInt64(e.Metadata["chain_seq"]); ok {
+		return v
+	}
+	return -1
+}
+
+func toInt64(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// ReadAuditLog reads the events written to HashChainOptions.LogPath. key
+// is the WithEncryptionAtRest key the log was written with, or nil.
+func ReadAuditLog(path string, key []byte) ([]Event, error) {
+	var cipher *lineCipher
+	if key != nil {
+		cipher = newLineCipher(key)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line, err := cipher.open(scanner.Bytes())
+		if err != nil {
+			return events, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return events, fmt.Errorf("failed to parse audit log: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,102 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHashChain(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(&memorySink{}),
+		WithHashChain(HashChainOptions{CheckpointEvery: 3, LogPath: logPath}),
+		WithEventSigning(HMACSigner{KeyID: "agent-1", Key: []byte("secret")}))
+
+	for i := 0; i < 4; i++ {
+		client.Track(NewEvent(EventToolCall, "agent-step"))
+	}
+	sess := client.StartSession(context.Background(), "run")
+	sess.Track(context.Background(), NewEvent(EventToolCall, "session-step"))
+	sess.End(nil)
+
+	first, _ := trackedEventWhere(client, func(e Event) bool { return e.Metadata["chain_seq"] == int64(0) && e.Metadata["chain_id"] == "agent" })
+	second, _ := trackedEventWhere(client, func(e Event) bool { return e.Metadata["chain_seq"] == int64(1) && e.Metadata["chain_id"] == "agent" })
+	if first.Metadata["prev_hash"] != "" {
+		t.Errorf("expected the first event to start the chain, got %v", first.Metadata)
+	}
+	if hash, _ := eventHash(first); second.Metadata["prev_hash"] != hash {
+		t.Errorf("expected the second event to link to the first, got %v", second.Metadata)
+	}
+	if second.Signature == nil || VerifyEvent(second, HMACSigner{KeyID: "agent-1", Key: []byte("secret")}) != nil {
+		t.Error("expected the chain fields to be signed")
+	}
+
+	// One checkpoint after 3 agent events and one at the session's end
+	if n := len(trackedEvents(client, EventChainCheckpoint)); n != 2 {
+		t.Errorf("expected 2 checkpoints before closing, got %d", n)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ReadAuditLog(logPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 10 {
+		t.Fatalf("expected 7 events and 3 checkpoints in the audit log, got %d", len(events))
+	}
+	if err := VerifyChain(events); err != nil {
+		t.Fatalf("expected the logged chain to verify, got %v", err)
+	}
+}
+
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	chain := func() []Event {
+		sink := &memorySink{}
+		client := NewClient("test-key",
+			WithFlushInterval(time.Hour),
+			WithPrimarySink(sink),
+			WithHashChain(HashChainOptions{CheckpointEvery: 10}))
+		for _, name := range []string{"a", "b", "c"} {
+			client.Track(NewEvent(EventToolCall, name))
+		}
+		client.Close()
+		var events []Event
+		for _, batch := range sink.batches {
+			events = append(events, batch...)
+		}
+		return events
+	}
+
+	events := chain()
+	if len(events) != 4 || events[3].Type != EventChainCheckpoint {
+		t.Fatalf("expected 3 events and a checkpoint on close, got %d", len(events))
+	}
+	if err := VerifyChain([]Event{events[2], events[0], events[3], events[1]}); err != nil {
+		t.Errorf("expected order not to matter, got %v", err)
+	}
+
+	events = chain()
+	events[1].Payload["tampered"] = true
+	if err := VerifyChain(events); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected a modified event to break the chain, got %v", err)
+	}
+
+	events = chain()
+	if err := VerifyChain(append(events[:1:1], events[2:]...)); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected a removed event to break the chain, got %v", err)
+	}
+
+	events = chain()
+	if err := VerifyChain(events[:2:2]); err != nil {
+		t.Errorf("expected a prefix without its checkpoint to verify, got %v", err)
+	}
+	if err := VerifyChain(append(events[:2:2], events[3])); !errors.Is(err, ErrChainBroken) {
+		t.Errorf("expected a truncated chain to fail its checkpoint, got %v", err)
+	}
+}
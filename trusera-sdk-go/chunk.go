@@ -0,0 +1,163 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// EventChunk is the type of the events an oversized event is split into
+const EventChunk EventType = "event_chunk"
+
+// envelopeReserve covers the clock and delivery objects of an events
+// request, whose sizes vary slightly from batch to batch
+const envelopeReserve = 256
+
+// ErrChunkMismatch is returned when reassembled chunks do not match the
+// hash of the event they were split from
+var ErrChunkMismatch = errors.New("trusera: chunks do not match their event")
+
+// chunkBodies splits the request body of a single event that exceeds
+// WithMaxBatchBytes into the bodies of several requests, each carrying one
+// EventChunk with a piece of the event's JSON encoding. It returns nil if
+// the limit leaves no room for a chunk.
+func (c *Client) chunkBodies(event Event) ([][]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	empty, err := c.marshalBatch(c.agentID, []Event{chunkEvent(event, digest, 0, len(data), nil)})
+	if err != nil {
+		return nil, err
+	}
+	room := (c.maxBatchBytes - len(empty) - envelopeReserve) / 4 * 3 // base64 grows data by 4/3
+	if room <= 0 {
+		return nil, nil
+	}
+
+	count := (len(data) + room - 1) / room
+	bodies := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		part := data[i*room : min((i+1)*room, len(data))]
+		body, err := c.marshalBatch(c.agentID, []Event{chunkEvent(event, digest, i, count, part)})
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+// chunkEvent builds chunk index of count of an event. Chunks keep the
+// event's project and delivery sequence, so they are routed and
+// acknowledged like the event.
+func chunkEvent(event Event, digest string, index, count int, part []byte) Event {
+	chunk := Event{
+		ID:        fmt.Sprintf("%s-chunk-%d", event.ID, index),
+		Type:      EventChunk,
+		Name:      event.Name,
+		Timestamp: event.Timestamp,
+		Monotonic: event.Monotonic,
+		Stream:    event.Stream,
+		Sequence:  event.Sequence,
+		Payload: map[string]any{
+			"event_id": event.ID,
+			"index":    index,
+			"count":    count,
+			"sha256":   digest,
+			"data":     base64.StdEncoding.EncodeToString(part),
+		},
+	}
+	if project := projectOf(event); project != "" {
+		chunk = chunk.WithProject(project)
+	}
+	return chunk
+}
+
+// ChunkAssembler reassembles events that were split into EventChunk events
+// because they exceeded WithMaxBatchBytes. Receivers of the SDK's requests,
+// such as test servers and proxies, feed it every event they receive. It is
+// safe for concurrent use.
+type ChunkAssembler struct {
+	mu      sync.Mutex
+	pending map[string][]string // Chunk data by event ID
+}
+
+// NewChunkAssembler creates an empty assembler
+func NewChunkAssembler() *ChunkAssembler {
+	return &ChunkAssembler{pending: map[string][]string{}}
+}
+
+// Add takes a received event. An event that is not a chunk is returned as
+// it is. A chunk is held until all chunks of its event have arrived, when
+// the event is returned; until then Add reports false. Chunks received
+// again are ignored.
+func (a *ChunkAssembler) Add(e Event) (Event, bool, error) {
+	if e.Type != EventChunk {
+		return e, true, nil
+	}
+	id, _ := e.Payload["event_id"].(string)
+	digest, _ := e.Payload["sha256"].(string)
+	data, _ := e.Payload["data"].(string)
+	index, count := payloadInt(e.Payload["index"]), payloadInt(e.Payload["count"])
+	if id == "" || count <= 0 || index < 0 || index >= count {
+		return Event{}, false, fmt.Errorf("malformed chunk %s", e.ID)
+	}
+
+	a.mu.Lock()
+	parts := a.pending[id]
+	if parts == nil {
+		parts = make([]string, count)
+		a.pending[id] = parts
+	}
+	if index < len(parts) {
+		parts[index] = data
+	}
+	for _, p := range parts {
+		if p == "" {
+			a.mu.Unlock()
+			return Event{}, false, nil
+		}
+	}
+	delete(a.pending, id)
+	a.mu.Unlock()
+
+	var full []byte
+	for _, p := range parts {
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err != nil {
+			return Event{}, false, fmt.Errorf("failed to decode chunk of %s: %w", id, err)
+		}
+		full = append(full, b...)
+	}
+	if sum := sha256.Sum256(full); hex.EncodeToString(sum[:]) != digest {
+		return Event{}, false, fmt.Errorf("%w: %s", ErrChunkMismatch, id)
+	}
+	var event Event
+	if err := json.Unmarshal(full, &event); err != nil {
+		return Event{}, false, fmt.Errorf("failed to decode event %s: %w", id, err)
+	}
+	return event, true, nil
+}
+
+// payloadInt reads an integer payload field, which is a float64 once
+// decoded from JSON
+func payloadInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return int(i)
+	}
+	return -1
+}
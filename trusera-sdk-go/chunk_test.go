@@ -0,0 +1,98 @@
+package trusera
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeChunks reads the chunk event out of each request body
+func decodeChunks(t *testing.T, bodies [][]byte) []Event {
+	t.Helper()
+	var chunks []Event
+	for _, body := range bodies {
+		var batch struct {
+			Events []Event `json:"events"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch.Events) != 1 {
+			t.Fatalf("expected one event per chunk request, got %s", body)
+		}
+		chunks = append(chunks, batch.Events[0])
+	}
+	return chunks
+}
+
+func TestChunkBodiesRoundTrip(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithAgentID("agent-1"), WithMaxBatchBytes(1024))
+	defer client.Close()
+
+	event := NewEvent(EventLLMInvoke, "huge").WithPayload("prompt", strings.Repeat("é", 3000)).WithProject("proj-1")
+	bodies, err := client.chunkBodies(event)
+	if err != nil || len(bodies) < 2 {
+		t.Fatalf("expected several chunks, got %d: %v", len(bodies), err)
+	}
+	for i, body := range bodies {
+		if len(body) > 1024 {
+			t.Errorf("chunk %d has %d bytes, exceeding the limit", i, len(body))
+		}
+	}
+
+	chunks := decodeChunks(t, bodies)
+	if chunks[0].Type != EventChunk || projectOf(chunks[0]) != "proj-1" {
+		t.Errorf("expected project-routed chunk events, got %+v", chunks[0])
+	}
+	a := NewChunkAssembler()
+	for i := len(chunks) - 1; i > 0; i-- {
+		if _, ok, err := a.Add(chunks[i]); ok || err != nil {
+			t.Fatalf("expected chunk %d held, got %v, %v", i, ok, err)
+		}
+	}
+	got, ok, err := a.Add(chunks[0])
+	if !ok || err != nil {
+		t.Fatalf("expected the event reassembled, got %v, %v", ok, err)
+	}
+	if got.ID != event.ID || got.Payload["prompt"] != event.Payload["prompt"] {
+		t.Errorf("reassembled event differs: %s", got.ID)
+	}
+}
+
+func TestChunkAssemblerRejectsTampering(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithMaxBatchBytes(1024))
+	defer client.Close()
+
+	bodies, _ := client.chunkBodies(NewEvent(EventLLMInvoke, "huge").WithPayload("prompt", strings.Repeat("x", 2000)))
+	chunks := decodeChunks(t, bodies)
+	chunks[1].Payload["data"] = "eHh4"
+
+	a := NewChunkAssembler()
+	var err error
+	for _, c := range chunks {
+		_, _, err = a.Add(c)
+	}
+	if !errors.Is(err, ErrChunkMismatch) {
+		t.Errorf("expected ErrChunkMismatch, got %v", err)
+	}
+}
+
+func TestChunkAssemblerPassesEventsThrough(t *testing.T) {
+	event := NewEvent(EventToolCall, "search")
+	got, ok, err := NewChunkAssembler().Add(event)
+	if !ok || err != nil || got.ID != event.ID {
+		t.Errorf("expected a regular event returned as it is, got %v, %v", ok, err)
+	}
+	if _, _, err := NewChunkAssembler().Add(Event{Type: EventChunk, ID: "bad"}); err == nil {
+		t.Error("expected a malformed chunk rejected")
+	}
+}
+
+func TestChunkBodiesLimitTooSmall(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithMaxBatchBytes(100))
+	defer client.Close()
+
+	bodies, err := client.chunkBodies(NewEvent(EventLLMInvoke, "huge").WithPayload("prompt", strings.Repeat("x", 2000)))
+	if bodies != nil || err != nil {
+		t.Errorf("expected no chunks when the limit leaves no room, got %d, %v", len(bodies), err)
+	}
+}
@@ -0,0 +1,85 @@
+package trusera
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithClock sets the clock the client reads instead of time.Now. Tracked
+// events are stamped with it when they are tracked, and it drives their
+// monotonic offsets, span and session durations, interceptor latencies,
+// the batch clock, deduplication windows and the circuit breaker. With
+// WithIDGenerator, it makes event streams byte-identical across runs; see
+// truseratest.NewClock for a clock that steps on every reading.
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.clock = now
+	}
+}
+
+// now reads the client's clock
+func (c *Client) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// applyClock hands a configured clock to the parts that keep time
+func (c *Client) applyClock() {
+	c.started = c.now()
+	if c.clock == nil {
+		return
+	}
+	if c.dedup != nil {
+		c.dedup.now = c.clock
+	}
+	if c.breaker != nil {
+		c.breaker.now = c.clock
+	}
+}
+
+// monotonic returns the time since the client started, read from the
+// monotonic clock, which wall-clock adjustments do not affect
+func (c *Client) monotonic() time.Duration {
+	return c.now().Sub(c.started)
+}
+
+// ClockSkew returns how far the API's clock is ahead of this host's, as
+// estimated from the Date header of the last events response, and whether
+// there is an estimate yet. The header has a resolution of one second.
+func (c *Client) ClockSkew() (time.Duration, bool) {
+	if !c.skewKnown.Load() {
+		return 0, false
+	}
+	return time.Duration(c.skew.Load()), true
+}
+
+// observeSkew estimates the clock skew from a response sent between start
+// and now
+func (c *Client) observeSkew(resp *http.Response, start time.Time) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// The server stamped the response about halfway through the round
+	// trip, and its Date is truncated to the second
+	mid := start.Add(time.Since(start) / 2)
+	skew := date.Add(500 * time.Millisecond).Sub(mid)
+	c.skew.Store(int64(skew))
+	c.skewKnown.Store(true)
+}
+
+// batchClock describes the client's clock in an events request, so the API
+// can order events by their monotonic offsets and correct their timestamps
+func (c *Client) batchClock() map[string]any {
+	clock := map[string]any{
+		"started_at":   c.started.UTC().Format(time.RFC3339Nano),
+		"sent_at":      c.now().UTC().Format(time.RFC3339Nano),
+		"monotonic_ns": int64(c.monotonic()),
+	}
+	if skew, ok := c.ClockSkew(); ok {
+		clock["skew_estimate_ms"] = skew.Milliseconds()
+	}
+	return clock
+}
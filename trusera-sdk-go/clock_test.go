@@ -0,0 +1,99 @@
+package trusera
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMonotonicOffsets(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "first"))
+	time.Sleep(time.Millisecond)
+	client.Track(NewEvent(EventToolCall, "second"))
+
+	first, _ := trackedEvent(client, "first")
+	second, _ := trackedEvent(client, "second")
+	if first.Monotonic <= 0 || second.Monotonic <= first.Monotonic {
+		t.Errorf("expected increasing monotonic offsets, got %d and %d", first.Monotonic, second.Monotonic)
+	}
+}
+
+func TestBatchClockAndSkew(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		clock map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Clock map[string]any `json:"clock"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		clock = body.Clock
+		mu.Unlock()
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+	if _, ok := client.ClockSkew(); ok {
+		t.Error("expected no skew estimate before the first response")
+	}
+
+	for i := 0; i < 2; i++ {
+		client.Track(NewEvent(EventToolCall, "search"))
+		if err := client.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	skew, ok := client.ClockSkew()
+	if !ok || skew < 59*time.Minute || skew > 61*time.Minute {
+		t.Errorf("expected a skew of about an hour, got %v, %v", skew, ok)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, key := range []string{"started_at", "sent_at", "monotonic_ns", "skew_estimate_ms"} {
+		if clock[key] == nil {
+			t.Errorf("expected %s in the batch clock, got %v", key, clock)
+		}
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var mu sync.Mutex
+	now := base
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}), WithClock(clock))
+	defer client.Close()
+
+	span := client.StartSpan(EventToolCall, "search")
+	span.End(nil)
+
+	event, ok := trackedEvent(client, "search")
+	if !ok {
+		t.Fatal("expected the span's event to be tracked")
+	}
+	if event.Timestamp != "2024-01-02T03:04:09Z" {
+		t.Errorf("expected the event stamped from the clock, got %s", event.Timestamp)
+	}
+	if event.Payload["duration_ms"] != float64(1000) {
+		t.Errorf("expected a duration of one tick, got %v", event.Payload["duration_ms"])
+	}
+	if event.Monotonic != int64(4*time.Second) {
+		t.Errorf("expected a monotonic offset of four ticks, got %d", event.Monotonic)
+	}
+}
@@ -0,0 +1,116 @@
+// Command trusera-proxy is an HTTP/HTTPS forward proxy that applies Trusera
+// enforcement to every request passing through it and reports them as
+// events. Point HTTP_PROXY and HTTPS_PROXY of any agent process at it:
+//
+//	TRUSERA_API_KEY=... trusera-proxy -agent-id agent-123 -enforcement block -block pastebin.com
+//	HTTPS_PROXY=http://127.0.0.1:8080 python agent.py
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func main() {
+	var (
+		listen      = flag.String("listen", "127.0.0.1:8080", "address to listen on")
+		apiURL      = flag.String("api-url", "", "Trusera API base URL (default https://api.trusera.io)")
+		agentID     = flag.String("agent-id", "", "agent ID to report events under")
+		agentName   = flag.String("agent-name", "", "register an agent with this name when -agent-id is not set")
+		enforcement = flag.String("enforcement", "log", "enforcement mode: log, warn, block, allow_list or audit")
+		block       = flag.String("block", "", "comma-separated URL patterns to block")
+		allow       = flag.String("allow", "", "comma-separated URL patterns to permit in allow_list mode")
+		exclude     = flag.String("exclude", "", "comma-separated URL patterns to pass through untracked")
+		policySync  = flag.Duration("policy-sync", 0, "fetch the agent's policy from Trusera at this interval (0 disables)")
+		policyCache = flag.String("policy-cache", "", "file to cache the synced policy in")
+		flush       = flag.Duration("flush-interval", 5*time.Second, "how often to send events")
+	)
+	flag.Parse()
+
+	apiKey := os.Getenv("TRUSERA_API_KEY")
+	if apiKey == "" {
+		log.Fatal("TRUSERA_API_KEY must be set")
+	}
+
+	mode := trusera.EnforcementMode(*enforcement)
+	switch mode {
+	case trusera.ModeLog, trusera.ModeWarn, trusera.ModeBlock, trusera.ModeAllowList, trusera.ModeAudit:
+	default:
+		log.Fatalf("unknown enforcement mode %q", *enforcement)
+	}
+
+	opts := []trusera.Option{trusera.WithFlushInterval(*flush)}
+	if *apiURL != "" {
+		opts = append(opts, trusera.WithBaseURL(*apiURL))
+	}
+	if *agentID != "" {
+		opts = append(opts, trusera.WithAgentID(*agentID))
+	}
+	if *policySync > 0 {
+		opts = append(opts, trusera.WithPolicySync(*policySync))
+		if *policyCache != "" {
+			opts = append(opts, trusera.WithPolicyCache(*policyCache, 0))
+		}
+	}
+	client := trusera.NewClient(apiKey, opts...)
+
+	if *agentID == "" && *agentName != "" {
+		id, err := client.RegisterAgent(*agentName, "trusera-proxy")
+		if err != nil {
+			log.Fatalf("failed to register agent: %v", err)
+		}
+		log.Printf("registered agent %s", id)
+	}
+
+	server := &http.Server{
+		Addr: *listen,
+		Handler: trusera.NewProxy(client, trusera.InterceptorOptions{
+			Enforcement:     mode,
+			BlockPatterns:   splitList(*block),
+			AllowPatterns:   splitList(*allow),
+			ExcludePatterns: splitList(*exclude),
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("trusera-proxy listening on %s (%s mode)", *listen, mode)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("proxy failed: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to flush events: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// splitList parses a comma-separated flag value
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+// Command trusera works with Trusera event data outside of an agent process.
+//
+// The upload subcommand sends the files written by WithOfflineMode, e.g.
+// once an air-gapped run or CI job has finished:
+//
+//	TRUSERA_API_KEY=... trusera upload ./trusera-events
+//
+// Files written with WithEncryptionAtRest need the key in
+// TRUSERA_ENCRYPTION_KEY, base64 or hex encoded.
+//
+// The bom-diff subcommand compares two AI-BOMs in CycloneDX JSON, e.g. of
+// two agent releases, and exits with status 1 when they differ:
+//
+//	trusera bom-diff bom-v1.4.0.json bom-v1.5.0.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/Trusera/ai-bom/trusera-sdk-go/aibom"
+)
+
+const usage = `usage: trusera <command> [flags]
+
+commands:
+  upload [-api-url URL] [-agent-id ID] DIR   send offline event files to Trusera
+  bom-diff OLD NEW                           compare two AI-BOMs
+`
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "upload":
+		upload(os.Args[2:])
+	case "bom-diff":
+		bomDiff(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// upload sends the finished files of an offline directory
+func upload(args []string) {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	var (
+		apiURL  = fs.String("api-url", "", "Trusera API base URL (default https://api.trusera.io)")
+		agentID = fs.String("agent-id", "", "agent ID for events written without one")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: trusera upload [-api-url URL] [-agent-id ID] DIR")
+	}
+
+	apiKey := os.Getenv("TRUSERA_API_KEY")
+	if apiKey == "" {
+		log.Fatal("TRUSERA_API_KEY must be set")
+	}
+
+	opts := []trusera.Option{trusera.WithFlushInterval(time.Hour)}
+	if *apiURL != "" {
+		opts = append(opts, trusera.WithBaseURL(*apiURL))
+	}
+	if *agentID != "" {
+		opts = append(opts, trusera.WithAgentID(*agentID))
+	}
+	if os.Getenv("TRUSERA_ENCRYPTION_KEY") != "" {
+		opts = append(opts, trusera.WithEncryptionAtRest(trusera.EnvKey("TRUSERA_ENCRYPTION_KEY")))
+	}
+	client := trusera.NewClient(apiKey, opts...)
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	n, err := client.Upload(ctx, fs.Arg(0))
+	if err != nil {
+		client.Close()
+		log.Fatalf("uploaded %d events before failing: %v", n, err)
+	}
+	log.Printf("uploaded %d events", n)
+}
+
+// bomDiff prints the changes between two BOMs
+func bomDiff(args []string) {
+	if len(args) != 2 {
+		log.Fatal("usage: trusera bom-diff OLD NEW")
+	}
+	old, err := readBOM(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	new, err := readBOM(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := aibom.Diff(old, new)
+	if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if !report.Empty() {
+		os.Exit(1)
+	}
+}
+
+func readBOM(path string) (*aibom.BOM, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := aibom.ReadJSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,75 @@
+package trusera
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Compressor encodes request bodies for a Content-Encoding
+type Compressor interface {
+	// Encoding is the Content-Encoding token, such as "gzip" or "zstd"
+	Encoding() string
+	// NewWriter returns a writer that compresses into w
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// Gzip compresses flush requests with gzip at the default level
+var Gzip Compressor = GzipLevel(gzip.DefaultCompression)
+
+// GzipLevel returns a gzip Compressor using the given compress/gzip level
+func GzipLevel(level int) Compressor {
+	return NewCompressor("gzip", func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	})
+}
+
+// NewCompressor adapts any streaming encoder to a Compressor. The SDK has no
+// dependencies, so zstd is plugged in this way, for example with
+// github.com/klauspost/compress/zstd:
+//
+//	trusera.NewCompressor("zstd", func(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	})
+func NewCompressor(encoding string, newWriter func(io.Writer) (io.WriteCloser, error)) Compressor {
+	return funcCompressor{encoding: encoding, newWriter: newWriter}
+}
+
+type funcCompressor struct {
+	encoding  string
+	newWriter func(io.Writer) (io.WriteCloser, error)
+}
+
+func (f funcCompressor) Encoding() string { return f.encoding }
+
+func (f funcCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return f.newWriter(w)
+}
+
+// WithCompression compresses flush requests and sets Content-Encoding. If
+// the API answers 415 Unsupported Media Type, the batch is resent
+// uncompressed and compression is turned off for the rest of the client's
+// lifetime.
+func WithCompression(comp Compressor) Option {
+	return func(c *Client) {
+		c.compressor = comp
+	}
+}
+
+// compress encodes body with comp
+func compress(comp Compressor, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := comp.NewWriter(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s writer: %w", comp.Encoding(), err)
+	}
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to compress events: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress events: %w", err)
+	}
+	return buf.Bytes(), nil
+}
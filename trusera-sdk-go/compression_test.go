@@ -0,0 +1,100 @@
+package trusera
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestWithCompressionGzip(t *testing.T) {
+	var encoding string
+	var received []Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("body is not gzip: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var payload struct {
+			Events []Event `json:"events"`
+		}
+		json.NewDecoder(zr).Decode(&payload)
+		received = payload.Events
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithCompression(Gzip))
+	defer client.Close()
+
+	client.Track(NewEvent(EventLLMInvoke, "gpt-4"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if encoding != "gzip" {
+		t.Errorf("expected Content-Encoding gzip, got %q", encoding)
+	}
+	if len(received) != 1 || received[0].Name != "gpt-4" {
+		t.Errorf("unexpected events: %v", received)
+	}
+}
+
+func TestWithCompressionFallsBackOn415(t *testing.T) {
+	var mu sync.Mutex
+	var encodings []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := r.Header.Get("Content-Encoding")
+		mu.Lock()
+		encodings = append(encodings, enc)
+		mu.Unlock()
+		if enc != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithCompression(Gzip))
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		client.Track(NewEvent(EventToolCall, "tool"))
+		if err := client.Flush(); err != nil {
+			t.Fatalf("flush %d failed: %v", i, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(encodings) != 3 || encodings[0] != "gzip" || encodings[1] != "" || encodings[2] != "" {
+		t.Errorf("expected one rejected gzip request then plain requests, got %q", encodings)
+	}
+}
+
+func TestNewCompressor(t *testing.T) {
+	comp := NewCompressor("deflate", func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestSpeed)
+	})
+	if comp.Encoding() != "deflate" {
+		t.Errorf("unexpected encoding %q", comp.Encoding())
+	}
+
+	body := []byte(`{"events":[]}`)
+	out, err := compress(comp, body)
+	if err != nil {
+		t.Fatalf("compress failed: %v", err)
+	}
+	got, _ := io.ReadAll(flate.NewReader(bytes.NewReader(out)))
+	if string(got) != string(body) {
+		t.Errorf("round trip mismatch: %q", got)
+	}
+}
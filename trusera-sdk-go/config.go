@@ -0,0 +1,445 @@
+package trusera
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultReloadInterval = 5 * time.Second
+
+// Config is the contents of a configuration file; see NewClientFromConfig.
+// Files are YAML, or JSON when the name ends in .json, with the field names
+// given in the json tags.
+type Config struct {
+	APIKey             string   `json:"api_key,omitempty"` // Falls back to TRUSERA_API_KEY
+	AgentID            string   `json:"agent_id,omitempty"`
+	BaseURL            string   `json:"base_url,omitempty"`
+	Region             Region   `json:"region,omitempty"`
+	Project            string   `json:"project,omitempty"`
+	FlushInterval      Duration `json:"flush_interval,omitempty"`
+	BatchSize          int      `json:"batch_size,omitempty"`
+	MaxBatchBytes      int      `json:"max_batch_bytes,omitempty"`
+	MaxBufferSize      int      `json:"max_buffer_size,omitempty"`
+	QueueDir           string   `json:"queue_dir,omitempty"`
+	OfflineDir         string   `json:"offline_dir,omitempty"`
+	DeadLetterFile     string   `json:"dead_letter_file,omitempty"`
+	PolicySyncInterval Duration `json:"policy_sync_interval,omitempty"`
+
+	// ReloadInterval is how often the file is checked for changes (5s when
+	// zero); DisableReload turns hot reload off
+	ReloadInterval Duration `json:"reload_interval,omitempty"`
+	DisableReload  bool     `json:"disable_reload,omitempty"`
+
+	// The sections below are applied again whenever the file changes
+	Interceptor InterceptorConfig `json:"interceptor"`
+	Redaction   RedactionConfig   `json:"redaction"`
+	Policy      *PolicyConfig     `json:"policy,omitempty"`
+}
+
+// InterceptorConfig configures the enforcement of every interceptor built
+// on the client. It is layered under a remote policy the same way a remote
+// policy is layered over InterceptorOptions.
+type InterceptorConfig struct {
+	Enforcement     EnforcementMode `json:"enforcement,omitempty"`
+	Rules           []Rule          `json:"rules,omitempty"`
+	CandidateRules  []Rule          `json:"candidate_rules,omitempty"`
+	EnforcePercent  float64         `json:"enforce_percent,omitempty"`
+	ExcludePatterns []string        `json:"exclude_patterns,omitempty"`
+	BlockPatterns   []string        `json:"block_patterns,omitempty"`
+	AllowPatterns   []string        `json:"allow_patterns,omitempty"`
+}
+
+// RedactionConfig configures PII redaction of tracked events
+type RedactionConfig struct {
+	Enabled   bool              `json:"enabled,omitempty"`
+	Detectors []string          `json:"detectors,omitempty"` // Built-in detectors by name; all when empty
+	Patterns  map[string]string `json:"patterns,omitempty"`  // Custom detectors: name to regular expression
+}
+
+// PolicyConfig is a local CEL policy evaluated for every tracked event, as
+// set by WithPolicy. When the section is present the file owns the client's
+// policy, and removing every rule removes it.
+type PolicyConfig struct {
+	Rules []CELRule `json:"rules"`
+}
+
+// Duration is a time.Duration written as a string such as "30s"
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s: use a string such as \"30s\"", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LoadConfig reads and validates a configuration file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cfg.compile(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseConfig decodes a configuration file, rejecting unknown fields
+func parseConfig(path string, data []byte) (*Config, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		v, err := decodeYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+		if v == nil {
+			v = map[string]any{}
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// configState is what a configuration file changes on reload
+type configState struct {
+	interceptor *RemotePolicy
+	redactor    *Redactor
+	policy      *CELPolicy
+	hasPolicy   bool
+}
+
+// compile validates the configuration and builds its reloadable state
+func (cfg *Config) compile() (*configState, error) {
+	if cfg.Region != "" {
+		if _, ok := regionURLs[Region(strings.ToLower(string(cfg.Region)))]; !ok {
+			return nil, fmt.Errorf("unknown region %q", cfg.Region)
+		}
+	}
+	for name, d := range map[string]Duration{
+		"flush_interval":       cfg.FlushInterval,
+		"policy_sync_interval": cfg.PolicySyncInterval,
+		"reload_interval":      cfg.ReloadInterval,
+	} {
+		if d < 0 {
+			return nil, fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	state := &configState{}
+	if ic := cfg.Interceptor; !reflect.DeepEqual(ic, InterceptorConfig{}) {
+		switch ic.Enforcement {
+		case "", ModeLog, ModeWarn, ModeBlock, ModeAllowList, ModeAudit, ModeLearn, ModeShadow:
+		default:
+			return nil, fmt.Errorf("unknown enforcement mode %q", ic.Enforcement)
+		}
+		if ic.EnforcePercent < 0 || ic.EnforcePercent > 100 {
+			return nil, fmt.Errorf("enforce_percent must be between 0 and 100")
+		}
+		var patterns []string
+		patterns = append(append(append(patterns, ic.ExcludePatterns...), ic.BlockPatterns...), ic.AllowPatterns...)
+		for _, rule := range append(append([]Rule{}, ic.Rules...), ic.CandidateRules...) {
+			patterns = append(patterns, rule.Match)
+		}
+		for _, pattern := range patterns {
+			if _, err := CompilePattern(pattern); err != nil {
+				return nil, err
+			}
+		}
+		state.interceptor = &RemotePolicy{
+			Enforcement:     ic.Enforcement,
+			Rules:           ic.Rules,
+			CandidateRules:  ic.CandidateRules,
+			EnforcePercent:  ic.EnforcePercent,
+			ExcludePatterns: ic.ExcludePatterns,
+			BlockPatterns:   ic.BlockPatterns,
+			AllowPatterns:   ic.AllowPatterns,
+		}
+	}
+
+	if r := cfg.Redaction; r.Enabled {
+		builtin := make(map[string]Detector)
+		for _, d := range DefaultDetectors() {
+			builtin[d.Name] = d
+		}
+		var detectors []Detector
+		for _, name := range r.Detectors {
+			d, ok := builtin[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown redaction detector %q", name)
+			}
+			detectors = append(detectors, d)
+		}
+		if len(detectors) == 0 {
+			detectors = DefaultDetectors()
+		}
+		names := make([]string, 0, len(r.Patterns))
+		for name := range r.Patterns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			re, err := regexp.Compile(r.Patterns[name])
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile redaction pattern %s: %w", name, err)
+			}
+			detectors = append(detectors, PatternDetector(name, re))
+		}
+		state.redactor = NewRedactor(detectors...)
+	}
+
+	if cfg.Policy != nil {
+		state.hasPolicy = true
+		if len(cfg.Policy.Rules) > 0 {
+			policy, err := NewCELPolicy(cfg.Policy.Rules...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile policy: %w", err)
+			}
+			state.policy = policy
+		}
+	}
+	return state, nil
+}
+
+// options returns the client options the configuration sets
+func (cfg *Config) options() []Option {
+	var opts []Option
+	if cfg.AgentID != "" {
+		opts = append(opts, WithAgentID(cfg.AgentID))
+	}
+	if cfg.Region != "" {
+		opts = append(opts, WithRegion(cfg.Region))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.Project != "" {
+		opts = append(opts, WithProject(cfg.Project))
+	}
+	if cfg.FlushInterval > 0 {
+		opts = append(opts, WithFlushInterval(time.Duration(cfg.FlushInterval)))
+	}
+	if cfg.BatchSize > 0 {
+		opts = append(opts, WithMaxBatchSize(cfg.BatchSize))
+	}
+	if cfg.MaxBatchBytes > 0 {
+		opts = append(opts, WithMaxBatchBytes(cfg.MaxBatchBytes))
+	}
+	if cfg.MaxBufferSize > 0 {
+		opts = append(opts, WithMaxBufferSize(cfg.MaxBufferSize))
+	}
+	if cfg.QueueDir != "" {
+		opts = append(opts, WithPersistentQueue(cfg.QueueDir))
+	}
+	if cfg.OfflineDir != "" {
+		opts = append(opts, WithOfflineMode(cfg.OfflineDir))
+	}
+	if cfg.DeadLetterFile != "" {
+		opts = append(opts, WithDeadLetterFile(cfg.DeadLetterFile))
+	}
+	if cfg.PolicySyncInterval > 0 {
+		opts = append(opts, WithPolicySync(time.Duration(cfg.PolicySyncInterval)))
+	}
+	return opts
+}
+
+// static returns the configuration without its reloadable sections
+func (cfg Config) static() Config {
+	cfg.Interceptor, cfg.Redaction, cfg.Policy = InterceptorConfig{}, RedactionConfig{}, nil
+	return cfg
+}
+
+// NewClientFromConfig creates a client from a YAML or JSON configuration
+// file covering the client, interceptor, redaction and policy settings (see
+// Config). opts are applied after the file and take precedence over it.
+//
+// Unless disable_reload is set, the file is checked for changes every
+// reload_interval and its interceptor, redaction and policy sections are
+// applied again, so enforcement can be reconfigured without restarting the
+// agent. The file is polled rather than watched with fsnotify, keeping the
+// SDK free of dependencies, and a change is a change of its SHA-256 content
+// hash: edits that keep the size and modification time are picked up, and
+// touching the file is not a change. A file that fails to parse or validate is ignored and the previous
+// settings stay in effect. Every reload is tracked as an EventConfigReload.
+// Other settings take effect on the next start.
+func NewClientFromConfig(path string, opts ...Option) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := parseConfig(path, data)
+	if err != nil {
+		return nil, err
+	}
+	state, err := cfg.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("TRUSERA_API_KEY")
+	}
+	if apiKey == "" {
+		return nil, errors.New("api_key is not set in the config or TRUSERA_API_KEY")
+	}
+
+	configOpts := append(cfg.options(), func(c *Client) {
+		c.configPath = path
+		c.configSum = sha256.Sum256(data)
+		c.configStatic = cfg.static()
+		c.applyConfig(state)
+		// Config redaction runs first, like WithRedaction
+		c.trackHooks = append([]EventHook{c.configRedaction}, c.trackHooks...)
+	})
+	c := NewClient(apiKey, append(configOpts, opts...)...)
+
+	if !cfg.DisableReload {
+		interval := time.Duration(cfg.ReloadInterval)
+		if interval == 0 {
+			interval = defaultReloadInterval
+		}
+		c.wg.Add(1)
+		go c.configWatcher(interval)
+	}
+	return c, nil
+}
+
+// applyConfig installs the reloadable state of a configuration
+func (c *Client) applyConfig(state *configState) {
+	c.configPolicy.Store(state.interceptor)
+	c.redactor.Store(state.redactor)
+	if state.hasPolicy {
+		c.policy.Store(state.policy)
+	}
+}
+
+func (c *Client) configRedaction(e *Event) (*Event, error) {
+	if r := c.redactor.Load(); r != nil {
+		return r.hook(e)
+	}
+	return e, nil
+}
+
+// configWatcher reloads the config file every interval until the client is
+// closed
+func (c *Client) configWatcher(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.handleError(c.ReloadConfig())
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// ReloadConfig reads the client's config file now and applies it if it
+// changed, e.g. on SIGHUP. It returns an error, and keeps the current
+// settings, if the file cannot be read or is invalid, and does nothing for
+// clients not created by NewClientFromConfig.
+func (c *Client) ReloadConfig() error {
+	if c.configPath == "" {
+		return nil
+	}
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+
+	data, err := os.ReadFile(c.configPath)
+	if err != nil {
+		// Editors and config maps briefly remove files while replacing them
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	if sum == c.configSum {
+		return nil
+	}
+	c.configSum = sum
+
+	event := NewEvent(EventConfigReload, "config reload").
+		WithPayload("path", c.configPath)
+
+	cfg, err := parseConfig(c.configPath, data)
+	var state *configState
+	if err == nil {
+		state, err = cfg.compile()
+	}
+	if err != nil {
+		c.Track(event.WithPayload("applied", false).WithPayload("error", err.Error()))
+		return err
+	}
+
+	c.applyConfig(state)
+	event = event.WithPayload("applied", true)
+	restart := !reflect.DeepEqual(cfg.static(), c.configStatic)
+	if restart {
+		event = event.WithPayload("restart_required", true)
+	}
+	c.log(slog.LevelInfo, "config reloaded", "path", c.configPath, "restart_required", restart)
+	c.Track(event)
+	return nil
+}
+
+// effectivePolicy layers the remote policy over the config file's
+// interceptor settings
+func (c *Client) effectivePolicy() *RemotePolicy {
+	local, remote := c.configPolicy.Load(), c.remotePolicy.Load()
+	if local == nil {
+		return remote
+	}
+	if remote == nil {
+		return local
+	}
+	p := *remote
+	if p.Enforcement == "" {
+		p.Enforcement = local.Enforcement
+	}
+	if p.EnforcePercent == 0 {
+		p.EnforcePercent = local.EnforcePercent
+	}
+	p.Rules = append(append([]Rule{}, remote.Rules...), local.Rules...)
+	p.CandidateRules = append(append([]Rule{}, remote.CandidateRules...), local.CandidateRules...)
+	p.ExcludePatterns = append(append([]string{}, local.ExcludePatterns...), remote.ExcludePatterns...)
+	p.BlockPatterns = append(append([]string{}, local.BlockPatterns...), remote.BlockPatterns...)
+	p.AllowPatterns = append(append([]string{}, local.AllowPatterns...), remote.AllowPatterns...)
+	return &p
+}
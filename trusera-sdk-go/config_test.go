@@ -0,0 +1,227 @@
+package trusera
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+# trusera.yaml
+api_key: file-key
+agent_id: agent-1
+project: platform
+flush_interval: 1h
+batch_size: 50
+disable_reload: true
+
+interceptor:
+  enforcement: block
+  block_patterns: [evil.example]
+  rules:
+    - match: metrics.internal
+      action: exclude
+
+redaction:
+  enabled: true
+  detectors: [email]
+  patterns:
+    employee_id: 'EMP-\d{6}'
+
+policy:
+  rules:
+    - id: no-prod-writes
+      action: forbid
+      expression: event.type == "file_write"
+`
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "trusera.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), testConfig)
+	client, err := NewClientFromConfig(path, WithPrimarySink(&memorySink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.apiKey != "file-key" || client.agentID != "agent-1" || client.project != "platform" || client.flushSize != 50 {
+		t.Errorf("unexpected client settings: key %q, agent %q, project %q, batch %d",
+			client.apiKey, client.agentID, client.project, client.flushSize)
+	}
+
+	stub := &stubTransport{}
+	httpClient := WrapHTTPClient(&http.Client{Transport: stub}, client, InterceptorOptions{})
+	if _, err := httpClient.Get("https://evil.example/x"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected the config's block pattern to be enforced, got %v", err)
+	}
+	if _, err := httpClient.Get("https://api.example/x"); err != nil {
+		t.Errorf("expected other requests to pass, got %v", err)
+	}
+
+	client.Track(NewEvent(EventToolCall, "lookup").
+		WithPayload("query", "mail jane@example.com about EMP-123456, call 555-867-5309"))
+	e, _ := trackedEvent(client, "lookup")
+	query := e.Payload["query"].(string)
+	if !strings.Contains(query, "[REDACTED:email]") || !strings.Contains(query, "[REDACTED:employee_id]") {
+		t.Errorf("expected the email and employee ID to be redacted, got %q", query)
+	}
+	if !strings.Contains(query, "555-867-5309") {
+		t.Errorf("expected only the configured detectors to run, got %q", query)
+	}
+
+	if d := client.CheckEvent(NewEvent(EventFileWrite, "/etc/passwd")); d.Decision != "Deny" {
+		t.Errorf("expected the config's policy to deny file writes, got %s", d.Decision)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, testConfig)
+	client, err := NewClientFromConfig(path, WithPrimarySink(&memorySink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	httpClient := WrapHTTPClient(&http.Client{Transport: &stubTransport{}}, client, InterceptorOptions{})
+
+	// An invalid file is rejected and the previous settings stay in effect
+	writeConfig(t, dir, strings.Replace(testConfig, "enforcement: block", "enforcement: strict", 1))
+	if err := client.ReloadConfig(); err == nil || !strings.Contains(err.Error(), `unknown enforcement mode "strict"`) {
+		t.Fatalf("expected the invalid mode to be rejected, got %v", err)
+	}
+	if _, err := httpClient.Get("https://evil.example/x"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected the previous enforcement to stay in effect, got %v", err)
+	}
+
+	writeConfig(t, dir, strings.Replace(strings.Replace(testConfig, "enforcement: block", "enforcement: warn", 1), "agent_id: agent-1", "agent_id: agent-2", 1))
+	if err := client.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := httpClient.Get("https://evil.example/x"); err != nil {
+		t.Errorf("expected the reloaded warn mode to allow the request, got %v", err)
+	}
+	if client.agentID != "agent-1" {
+		t.Errorf("expected the agent ID to need a restart, got %q", client.agentID)
+	}
+
+	reloads := trackedEvents(client, EventConfigReload)
+	if len(reloads) != 2 {
+		t.Fatalf("expected 2 reload events, got %d", len(reloads))
+	}
+	if reloads[0].Payload["applied"] != false || reloads[0].Payload["error"] == nil {
+		t.Errorf("expected the rejected reload to be reported, got %v", reloads[0].Payload)
+	}
+	if reloads[1].Payload["applied"] != true || reloads[1].Payload["restart_required"] != true {
+		t.Errorf("expected the applied reload to flag the agent ID change, got %v", reloads[1].Payload)
+	}
+
+	// Unchanged files are not applied again
+	if err := client.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(trackedEvents(client, EventConfigReload)); n != 2 {
+		t.Errorf("expected no reload of an unchanged file, got %d events", n)
+	}
+}
+
+func TestConfigHotReload(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Replace(testConfig, "disable_reload: true", "reload_interval: 10ms", 1)
+	path := writeConfig(t, dir, content)
+	client, err := NewClientFromConfig(path, WithPrimarySink(&memorySink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	writeConfig(t, dir, content[:strings.Index(content, "policy:")]+"policy:\n  rules: []\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for client.CheckEvent(NewEvent(EventFileWrite, "/etc/passwd")).Decision == "Deny" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the emptied policy to be picked up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConfigReloadComparesContent(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, testConfig)
+	client, err := NewClientFromConfig(path, WithPrimarySink(&memorySink{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Touching the file is not a change
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(trackedEvents(client, EventConfigReload)); n != 0 {
+		t.Errorf("expected no reload of a touched file, got %d events", n)
+	}
+
+	// An edit keeping the size and modification time is
+	writeConfig(t, dir, strings.Replace(testConfig, "enforcement: block", "enforcement: audit", 1))
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if reloads := trackedEvents(client, EventConfigReload); len(reloads) != 1 || reloads[0].Payload["applied"] != true {
+		t.Errorf("expected the same-size edit to be applied, got %v", reloads)
+	}
+}
+
+func TestNewClientFromConfigJSON(t *testing.T) {
+	t.Setenv("TRUSERA_API_KEY", "env-key")
+	path := filepath.Join(t.TempDir(), "trusera.json")
+	os.WriteFile(path, []byte(`{"agent_id": "agent-json", "flush_interval": "1h", "disable_reload": true}`), 0o600)
+
+	client, err := NewClientFromConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if client.apiKey != "env-key" || client.agentID != "agent-json" {
+		t.Errorf("expected the env API key and the file's agent, got %q and %q", client.apiKey, client.agentID)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	for content, want := range map[string]string{
+		"agent: x\n":           `unknown field "agent"`,
+		"flush_interval: 30\n": "invalid duration 30",
+		"region: mars\n":       `unknown region "mars"`,
+		"redaction:\n  enabled: true\n  detectors: [iban]\n":                `unknown redaction detector "iban"`,
+		"policy:\n  rules:\n    - action: deny\n      expression: 'true'\n": `invalid action "deny"`,
+		"interceptor:\n\tenforcement: block\n":                              "line 2: tabs are not allowed",
+		"interceptor:\n  block_patterns: ['re:(']\n":                        `invalid pattern "re:("`,
+		"interceptor:\n  enforce_percent: 150\n":                            "enforce_percent must be between 0 and 100",
+	} {
+		if _, err := LoadConfig(writeConfig(t, dir, content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", content, want, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package trusera
+
+import (
+	"context"
+)
+
+type contextKey int
+
+const (
+	sessionIDKey contextKey = iota
+	userIDKey
+	metadataKey
+	traceContextKey
+	agentIDKey
+	activeSessionKey
+	parentEventKey
+	projectKey
+)
+
+// traceContext is a trace and span recorded by ContextWithTrace
+type traceContext struct {
+	traceID, spanID string
+}
+
+// ContextWithSessionID returns a context whose events are tagged with the
+// agent session they belong to
+func ContextWithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, id)
+}
+
+// SessionIDFromContext returns the session ID stored in ctx, if any
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey).(string)
+	return id
+}
+
+// ContextWithUserID returns a context whose events are tagged with the end
+// user the agent is acting for
+func ContextWithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserIDFromContext returns the user ID stored in ctx, if any
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey).(string)
+	return id
+}
+
+// ContextWithTrace returns a context whose events belong to the given trace
+// and span (lowercase hex). It is used when no SpanContextFunc is set or
+// when it finds no active span.
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceContextKey, traceContext{traceID, spanID})
+}
+
+// TraceFromContext returns the trace and span stored in ctx, if any
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	tc, _ := ctx.Value(traceContextKey).(traceContext)
+	return tc.traceID, tc.spanID
+}
+
+// AgentIDFromContext returns the ID of the agent whose events a Sink is
+// sending. The client sets it on the context passed to Send.
+func AgentIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(agentIDKey).(string)
+	return id
+}
+
+// ContextWithMetadata returns a context that adds key to the metadata of
+// every event tracked with it. Values set on inner contexts win.
+func ContextWithMetadata(ctx context.Context, key string, value any) context.Context {
+	parent, _ := ctx.Value(metadataKey).(map[string]any)
+	md := make(map[string]any, len(parent)+1)
+	for k, v := range parent {
+		md[k] = v
+	}
+	md[key] = value
+	return context.WithValue(ctx, metadataKey, md)
+}
+
+// MetadataFromContext returns a copy of the metadata stored in ctx
+func MetadataFromContext(ctx context.Context) map[string]any {
+	md, _ := ctx.Value(metadataKey).(map[string]any)
+	out := make(map[string]any, len(md))
+	for k, v := range md {
+		out[k] = v
+	}
+	return out
+}
+
+// TrackCtx queues an event like Track, first enriching it with the trace,
+// session, parent event, user and metadata carried by ctx. Metadata already set on the
+// event is kept. It returns ctx's error without tracking when ctx is done,
+// including while BlockCaller waits for buffer space.
+func (c *Client) TrackCtx(ctx context.Context, event Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.track(ctx, c.enrich(ctx, event))
+}
+
+// enrich attaches everything known about ctx to an event
+func (c *Client) enrich(ctx context.Context, event Event) Event {
+	if _, ok := event.Metadata["trace_id"]; !ok {
+		event = c.correlate(ctx, event)
+	}
+
+	setDefault := func(key string, value any) {
+		if _, ok := event.Metadata[key]; !ok {
+			event = event.WithMetadata(key, value)
+		}
+	}
+	if id := SessionIDFromContext(ctx); id != "" {
+		setDefault("session_id", id)
+	}
+	if id := ParentEventFromContext(ctx); id != "" && id != event.ID {
+		setDefault("parent_event_id", id)
+	}
+	if s := sessionFromContext(ctx); s != nil {
+		if _, ok := event.Metadata["sequence"]; !ok {
+			event = event.WithMetadata("sequence", s.next())
+		}
+	}
+	if id := UserIDFromContext(ctx); id != "" {
+		setDefault("user_id", id)
+	}
+	if id := ProjectFromContext(ctx); id != "" {
+		setDefault("project_id", id)
+	}
+	md, _ := ctx.Value(metadataKey).(map[string]any)
+	for k, v := range md {
+		setDefault(k, v)
+	}
+	return event
+}
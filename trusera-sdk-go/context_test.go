@@ -0,0 +1,156 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrackCtxEnrichesEvents(t *testing.T) {
+	client := NewClient("test-key", WithSpanContext(func(ctx context.Context) (string, string) {
+		return "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	}))
+	defer client.Close()
+
+	ctx := ContextWithSessionID(context.Background(), "sess-1")
+	ctx = ContextWithUserID(ctx, "user-9")
+	ctx = ContextWithMetadata(ctx, "tenant", "acme")
+	ctx = ContextWithMetadata(ctx, "env", "prod")
+
+	event := NewEvent(EventToolCall, "search").WithMetadata("env", "staging")
+	if err := client.TrackCtx(ctx, event); err != nil {
+		t.Fatalf("TrackCtx failed: %v", err)
+	}
+
+	client.mu.Lock()
+	md := client.events[0].Metadata
+	client.mu.Unlock()
+
+	want := map[string]any{
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"session_id": "sess-1",
+		"user_id":    "user-9",
+		"tenant":     "acme",
+		"env":        "staging", // set on the event, so not overridden
+	}
+	for k, v := range want {
+		if md[k] != v {
+			t.Errorf("metadata %s = %v, want %v", k, md[k], v)
+		}
+	}
+}
+
+func TestContextMetadataIsScoped(t *testing.T) {
+	outer := ContextWithMetadata(context.Background(), "a", 1)
+	inner := ContextWithMetadata(outer, "b", 2)
+
+	if md := MetadataFromContext(outer); len(md) != 1 {
+		t.Errorf("inner values leaked into the outer context: %v", md)
+	}
+	if md := MetadataFromContext(inner); md["a"] != 1 || md["b"] != 2 {
+		t.Errorf("unexpected inner metadata: %v", md)
+	}
+}
+
+func TestContextWithTrace(t *testing.T) {
+	ctx := ContextWithTrace(context.Background(), testTraceID, testSpanID)
+
+	plain := NewClient("test-key")
+	defer plain.Close()
+	event := plain.enrich(ctx, NewEvent(EventToolCall, "x"))
+	if event.Metadata["trace_id"] != testTraceID || event.Metadata["span_id"] != testSpanID {
+		t.Errorf("expected the context trace, got %v", event.Metadata)
+	}
+
+	otelSpan := "1111111111111111"
+	withOTel := NewClient("test-key", WithSpanContext(func(ctx context.Context) (string, string) {
+		return testTraceID, otelSpan
+	}))
+	defer withOTel.Close()
+	event = withOTel.enrich(ctx, NewEvent(EventToolCall, "x"))
+	if event.Metadata["span_id"] != otelSpan {
+		t.Errorf("the SpanContextFunc should win, got %v", event.Metadata)
+	}
+
+	noSpan := NewClient("test-key", WithSpanContext(func(ctx context.Context) (string, string) { return "", "" }))
+	defer noSpan.Close()
+	event = noSpan.enrich(ctx, NewEvent(EventToolCall, "x"))
+	if event.Metadata["span_id"] != testSpanID {
+		t.Errorf("expected a fallback to the context trace, got %v", event.Metadata)
+	}
+}
+
+func TestTrackCtxCancelled(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.TrackCtx(ctx, NewEvent(EventToolCall, "x")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if depth := client.Stats().BufferDepth; depth != 0 {
+		t.Errorf("cancelled event should not be buffered, got %d", depth)
+	}
+}
+
+func TestTrackCtxBlockCallerDeadline(t *testing.T) {
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithMaxBufferSize(1),
+		WithOverflowPolicy(BlockCaller))
+	defer client.Close()
+
+	// A zero capacity never frees up, so the caller waits until its deadline
+	client.mu.Lock()
+	client.maxBuffer = 0
+	client.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- client.TrackCtx(ctx, NewEvent(EventToolCall, "late")) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("TrackCtx ignored its deadline")
+	}
+}
+
+func TestFlushCtxCancelsRetryWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithRetry(RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "x"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.FlushCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("FlushCtx kept waiting after its deadline")
+	}
+}
@@ -0,0 +1,184 @@
+// Package costs prices LLM calls from their token counts.
+//
+// The OpenAI and Anthropic integrations price their events with these
+// tables. For other LLM events, such as those from the langchaingo handler,
+// add a calculator's hook to the client:
+//
+//	calc := costs.NewCostCalculator(nil)
+//	client := trusera.NewClient(apiKey, trusera.WithEventHook(calc.Hook()))
+package costs
+
+import (
+	"strings"
+	"sync"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// Price is the USD cost per one million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Table maps model names to prices
+type Table map[string]Price
+
+// OpenAIPrices lists OpenAI list prices per one million tokens
+var OpenAIPrices = Table{
+	"gpt-5":                  {Input: 1.25, Output: 10.00},
+	"gpt-5-mini":             {Input: 0.25, Output: 2.00},
+	"gpt-5-nano":             {Input: 0.05, Output: 0.40},
+	"gpt-4.1":                {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":           {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":           {Input: 0.10, Output: 0.40},
+	"gpt-4o":                 {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.60},
+	"o3":                     {Input: 2.00, Output: 8.00},
+	"o4-mini":                {Input: 1.10, Output: 4.40},
+	"gpt-4-turbo":            {Input: 10.00, Output: 30.00},
+	"gpt-4":                  {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+}
+
+// AnthropicPrices lists Anthropic list prices per one million tokens
+var AnthropicPrices = Table{
+	"claude-opus-4-5":   {Input: 5.00, Output: 25.00},
+	"claude-opus-4-1":   {Input: 15.00, Output: 75.00},
+	"claude-opus-4":     {Input: 15.00, Output: 75.00},
+	"claude-sonnet-4-5": {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-haiku-4-5":  {Input: 1.00, Output: 5.00},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+}
+
+// GooglePrices lists Gemini list prices per one million tokens, for prompts
+// of up to 200k tokens
+var GooglePrices = Table{
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10.00},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+}
+
+// DefaultPrices combines the provider tables
+var DefaultPrices = merge(OpenAIPrices, AnthropicPrices, GooglePrices)
+
+func merge(tables ...Table) Table {
+	out := Table{}
+	for _, t := range tables {
+		for model, p := range t {
+			out[model] = p
+		}
+	}
+	return out
+}
+
+// Lookup finds the price for a model, falling back to the longest matching
+// prefix so dated snapshots such as gpt-4o-2024-08-06 and aliases such as
+// claude-3-7-sonnet-latest resolve
+func (t Table) Lookup(model string) (Price, bool) {
+	if p, ok := t[model]; ok {
+		return p, true
+	}
+
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return t[best], true
+}
+
+// Cost computes the USD cost of a call
+func (t Table) Cost(model string, inputTokens, outputTokens int) (float64, bool) {
+	p, ok := t.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6, true
+}
+
+// CostCalculator prices calls from a table that can be updated at runtime,
+// e.g. with negotiated prices or new models. It is safe for concurrent use.
+type CostCalculator struct {
+	mu     sync.RWMutex
+	prices Table
+}
+
+// NewCostCalculator creates a calculator from a copy of prices; nil uses
+// DefaultPrices
+func NewCostCalculator(prices Table) *CostCalculator {
+	if prices == nil {
+		prices = DefaultPrices
+	}
+	return &CostCalculator{prices: merge(prices)}
+}
+
+// SetPrice adds or replaces the price of a model
+func (c *CostCalculator) SetPrice(model string, p Price) {
+	c.mu.Lock()
+	c.prices[model] = p
+	c.mu.Unlock()
+}
+
+// Cost computes the USD cost of a call. It returns false for unknown models.
+func (c *CostCalculator) Cost(model string, inputTokens, outputTokens int) (float64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.prices.Cost(model, inputTokens, outputTokens)
+}
+
+// Annotate adds cost_usd to an EventLLMInvoke that reports its model and
+// token counts, unless the event already has a cost
+func (c *CostCalculator) Annotate(event trusera.Event) trusera.Event {
+	if event.Type != trusera.EventLLMInvoke || event.Payload["cost_usd"] != nil {
+		return event
+	}
+	model, _ := event.Payload["model"].(string)
+	if model == "" {
+		model = event.Name
+	}
+	input, ok1 := tokens(event.Payload, "prompt_tokens", "input_tokens")
+	output, ok2 := tokens(event.Payload, "completion_tokens", "output_tokens")
+	if !ok1 && !ok2 {
+		return event
+	}
+	if usd, ok := c.Cost(model, input, output); ok {
+		event = event.WithPayload("cost_usd", usd)
+	}
+	return event
+}
+
+// Hook returns an event hook that annotates events with Annotate
+func (c *CostCalculator) Hook() trusera.EventHook {
+	return func(e *trusera.Event) (*trusera.Event, error) {
+		annotated := c.Annotate(*e)
+		return &annotated, nil
+	}
+}
+
+// tokens reads the first of keys present in a payload as a token count
+func tokens(payload map[string]any, keys ...string) (int, bool) {
+	for _, key := range keys {
+		switch n := payload[key].(type) {
+		case int:
+			return n, true
+		case int64:
+			return int(n), true
+		case float64:
+			return int(n), true
+		}
+	}
+	return 0, false
+}
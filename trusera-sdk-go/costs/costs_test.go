@@ -0,0 +1,80 @@
+package costs
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func TestTableLookup(t *testing.T) {
+	p, ok := DefaultPrices.Lookup("gpt-4o-mini-2024-07-18")
+	if !ok || p != OpenAIPrices["gpt-4o-mini"] {
+		t.Errorf("expected the gpt-4o-mini price, got %+v", p)
+	}
+	p, ok = DefaultPrices.Lookup("claude-sonnet-4-5-20250929")
+	if !ok || p != AnthropicPrices["claude-sonnet-4-5"] {
+		t.Errorf("expected the claude-sonnet-4-5 price, got %+v", p)
+	}
+	if _, ok := DefaultPrices.Lookup("llama-3"); ok {
+		t.Error("expected unknown models to have no price")
+	}
+	if len(DefaultPrices) != len(OpenAIPrices)+len(AnthropicPrices)+len(GooglePrices) {
+		t.Error("expected model names to be unique across providers")
+	}
+}
+
+func TestCostCalculator(t *testing.T) {
+	calc := NewCostCalculator(nil)
+	usd, ok := calc.Cost("gemini-2.5-flash", 1_000_000, 100_000)
+	if !ok || math.Abs(usd-0.55) > 1e-9 {
+		t.Errorf("expected 0.55, got %v", usd)
+	}
+
+	calc.SetPrice("internal-llm", Price{Input: 1, Output: 1})
+	if _, ok := calc.Cost("internal-llm", 1, 1); !ok {
+		t.Error("expected SetPrice to add a model")
+	}
+	if _, ok := DefaultPrices["internal-llm"]; ok {
+		t.Error("SetPrice must not change the shared table")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			calc.SetPrice("gpt-4o", Price{Input: 2, Output: 8})
+			calc.Cost("gpt-4o", 10, 10)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestAnnotate(t *testing.T) {
+	calc := NewCostCalculator(Table{"m": {Input: 2, Output: 4}})
+
+	event := trusera.NewEvent(trusera.EventLLMInvoke, "m").
+		WithPayload("input_tokens", 500_000).
+		WithPayload("output_tokens", float64(250_000))
+	if got := calc.Annotate(event).Payload["cost_usd"]; got != 2.0 {
+		t.Errorf("expected cost_usd 2, got %v", got)
+	}
+
+	priced := trusera.NewEvent(trusera.EventLLMInvoke, "m").WithPayload("prompt_tokens", 1).WithPayload("cost_usd", 0.5)
+	if got := calc.Annotate(priced).Payload["cost_usd"]; got != 0.5 {
+		t.Errorf("expected an existing cost to be kept, got %v", got)
+	}
+	if _, ok := calc.Annotate(trusera.NewEvent(trusera.EventLLMInvoke, "m")).Payload["cost_usd"]; ok {
+		t.Error("expected no cost without token counts")
+	}
+	if _, ok := calc.Annotate(trusera.NewEvent(trusera.EventToolCall, "m").WithPayload("prompt_tokens", 1)).Payload["cost_usd"]; ok {
+		t.Error("expected only LLM events to be priced")
+	}
+
+	hooked, err := calc.Hook()(&event)
+	if err != nil || hooked.Payload["cost_usd"] != 2.0 {
+		t.Errorf("expected the hook to annotate, got %v (%v)", hooked.Payload, err)
+	}
+}
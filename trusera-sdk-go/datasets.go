@@ -0,0 +1,81 @@
+package trusera
+
+import (
+	"errors"
+	"sync"
+)
+
+// DatasetInfo describes a dataset or knowledge source the agent uses, e.g.
+// a RAG corpus or fine-tuning set
+type DatasetInfo struct {
+	Name    string `json:"name"`
+	Source  string `json:"source,omitempty"` // Where it comes from, e.g. a URL or bucket
+	Version string `json:"version,omitempty"`
+	License string `json:"license,omitempty"` // SPDX ID or license name
+	Hash    string `json:"hash,omitempty"`    // Content hash, in the format of ModelInfo.WeightsHash
+}
+
+// datasetRegistry holds the registered datasets by name
+type datasetRegistry struct {
+	mu       sync.Mutex
+	datasets []DatasetInfo
+}
+
+// RegisterDataset declares a dataset the agent uses. Registered datasets are
+// listed by Datasets, for the AI-BOM, and EventDatasetAccess events named
+// after one get its source, version, license and hash in their payload,
+// unless the event sets them.
+func (c *Client) RegisterDataset(info DatasetInfo) error {
+	if info.Name == "" {
+		return errors.New("dataset name is required")
+	}
+	c.datasets.mu.Lock()
+	defer c.datasets.mu.Unlock()
+	for i, d := range c.datasets.datasets {
+		if d.Name == info.Name {
+			c.datasets.datasets[i] = info
+			return nil
+		}
+	}
+	c.datasets.datasets = append(c.datasets.datasets, info)
+	return nil
+}
+
+// Datasets returns the registered datasets
+func (c *Client) Datasets() []DatasetInfo {
+	c.datasets.mu.Lock()
+	defer c.datasets.mu.Unlock()
+	return append([]DatasetInfo(nil), c.datasets.datasets...)
+}
+
+// describeDataset adds a registered dataset's lineage to an access event
+func (c *Client) describeDataset(event Event) Event {
+	if event.Type != EventDatasetAccess {
+		return event
+	}
+	c.datasets.mu.Lock()
+	var info DatasetInfo
+	found := false
+	for _, d := range c.datasets.datasets {
+		if d.Name == event.Name {
+			info, found = d, true
+			break
+		}
+	}
+	c.datasets.mu.Unlock()
+	if !found {
+		return event
+	}
+
+	for key, value := range map[string]string{
+		"source":  info.Source,
+		"version": info.Version,
+		"license": info.License,
+		"hash":    info.Hash,
+	} {
+		if _, set := event.Payload[key]; !set && value != "" {
+			event = event.WithPayload(key, value)
+		}
+	}
+	return event
+}
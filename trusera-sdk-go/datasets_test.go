@@ -0,0 +1,44 @@
+package trusera
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterDataset(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if err := client.RegisterDataset(DatasetInfo{Source: "s3://docs"}); err == nil {
+		t.Error("expected a dataset without a name to be rejected")
+	}
+	client.RegisterDataset(DatasetInfo{Name: "kb", Source: "s3://docs/kb", Version: "6"})
+	client.RegisterDataset(DatasetInfo{Name: "kb", Source: "s3://docs/kb", Version: "7", License: "CC-BY-4.0", Hash: "sha256:abc"})
+	if datasets := client.Datasets(); len(datasets) != 1 || datasets[0].Version != "7" {
+		t.Errorf("expected re-registering to replace the dataset, got %+v", datasets)
+	}
+}
+
+func TestDatasetAccessLineage(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+	client.RegisterDataset(DatasetInfo{Name: "kb", Source: "s3://docs/kb", Version: "7", License: "CC-BY-4.0", Hash: "sha256:abc"})
+
+	client.Track(NewEvent(EventDatasetAccess, "kb").WithPayload("version", "8").WithPayload("documents", 3))
+	client.Track(NewEvent(EventDatasetAccess, "unregistered"))
+
+	events := trackedEvents(client, EventDatasetAccess)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 accesses, got %d", len(events))
+	}
+	kb := events[0].Payload
+	if kb["source"] != "s3://docs/kb" || kb["license"] != "CC-BY-4.0" || kb["hash"] != "sha256:abc" || kb["documents"] != 3 {
+		t.Errorf("expected the registered lineage, got %v", kb)
+	}
+	if kb["version"] != "8" {
+		t.Errorf("expected the event's own version to be kept, got %v", kb["version"])
+	}
+	if len(events[1].Payload) != 0 {
+		t.Errorf("expected an unregistered dataset to be left alone, got %v", events[1].Payload)
+	}
+}
@@ -0,0 +1,193 @@
+package trusera
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// WithDeadLetterFile writes batches the API permanently rejects (a 4xx
+// response other than 429, after any retries) to a JSONL file at path
+// together with the rejection reason, instead of dropping them. Use
+// ReplayDeadLetters to submit them again once the cause is fixed.
+func WithDeadLetterFile(path string) Option {
+	return func(c *Client) {
+		c.deadLetters = &deadLetterFile{path: path}
+	}
+}
+
+// DeadLetter is one rejected batch in the dead-letter file
+type DeadLetter struct {
+	RejectedAt string  `json:"rejected_at"`
+	Status     int     `json:"status"`
+	Reason     string  `json:"reason"`
+	AgentID    string  `json:"agent_id,omitempty"`
+	Events     []Event `json:"events"`
+}
+
+// deadLetterFile appends rejected batches to a JSONL file
+type deadLetterFile struct {
+	path   string
+	cipher *lineCipher
+	mu     sync.Mutex
+}
+
+func (d *deadLetterFile) append(entries ...DeadLetter) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err == nil {
+			line, err = d.cipher.seal(line)
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return f.Close()
+}
+
+// take moves the current entries out of the file so they can be replayed
+// while new rejections keep being appended
+func (d *deadLetterFile) take() ([]DeadLetter, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	f, err := os.Open(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []DeadLetter
+	plaintext := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line, err := d.cipher.open(scanner.Bytes())
+		if errors.Is(err, ErrEncryptionKey) {
+			return nil, fmt.Errorf("failed to read dead-letter file: %w", err)
+		}
+		if errors.Is(err, errPlaintextLine) {
+			plaintext++
+		}
+		var e DeadLetter
+		if err != nil || json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead-letter file: %w", err)
+	}
+
+	if err := os.Remove(d.path); err != nil {
+		return nil, fmt.Errorf("failed to remove dead-letter file: %w", err)
+	}
+	return entries, unencryptedErr(d.path, plaintext)
+}
+
+// deadLetter records a permanently rejected batch, reporting whether it was
+// stored. The rejection is still returned so the caller can surface it.
+func (c *Client) deadLetter(batch []Event, sendErr error) (bool, error) {
+	var se *statusError
+	if c.deadLetters == nil || !errors.As(sendErr, &se) || !se.permanent() {
+		return false, nil
+	}
+
+	entry := DeadLetter{
+		RejectedAt: c.now().UTC().Format(time.RFC3339),
+		Status:     se.code,
+		Reason:     se.reason(),
+		AgentID:    c.agentID,
+		Events:     batch,
+	}
+	if err := c.deadLetters.append(entry); err != nil {
+		return false, err
+	}
+	c.metrics.observeDeadLetter(len(batch))
+	return true, nil
+}
+
+// ReplayDeadLetters submits the batches in the dead-letter file again and
+// returns how many events were accepted. Batches that fail again stay in the
+// file, with the latest rejection reason for 4xx responses. It stops early,
+// keeping the remaining batches, when ctx is done.
+func (c *Client) ReplayDeadLetters(ctx context.Context) (int, error) {
+	if c.deadLetters == nil {
+		return 0, errors.New("no dead-letter file configured")
+	}
+
+	entries, err := c.deadLetters.take()
+	if err != nil && !errors.Is(err, ErrUnencrypted) {
+		return 0, err
+	}
+
+	var (
+		replayed int
+		failed   []DeadLetter
+		errs     = err
+	)
+	for i, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			failed = append(failed, entries[i:]...)
+			errs = errors.Join(errs, err)
+			break
+		}
+
+		_, err := c.send(ctx, entry.Events)
+		if err == nil {
+			replayed += len(entry.Events)
+			continue
+		}
+		errs = errors.Join(errs, err)
+
+		var se *statusError
+		if errors.As(err, &se) && se.permanent() {
+			entry.RejectedAt = time.Now().UTC().Format(time.RFC3339)
+			entry.Status = se.code
+			entry.Reason = se.reason()
+		}
+		failed = append(failed, entry)
+	}
+
+	return replayed, errors.Join(errs, c.deadLetters.append(failed...))
+}
+
+// permanent reports whether resending the same request cannot succeed
+func (e *statusError) permanent() bool {
+	return e.code >= 400 && e.code < 500 && e.code != http.StatusTooManyRequests
+}
+
+// reason describes the rejection, preferring the API's response body
+func (e *statusError) reason() string {
+	if e.body != "" {
+		return e.body
+	}
+	return http.StatusText(e.code)
+}
@@ -0,0 +1,121 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeadLetterAndReplay(t *testing.T) {
+	var reject atomic.Bool
+	reject.Store(true)
+	var accepted atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reject.Load() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, "unknown event type\n")
+			return
+		}
+		accepted.Add(1)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	client := NewClient("test-key", WithBaseURL(server.URL), WithDeadLetterFile(path))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected the rejection to be reported")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected a dead-letter file: %v", err)
+	}
+	if !strings.Contains(string(data), `"status":422`) || !strings.Contains(string(data), `"reason":"unknown event type"`) {
+		t.Errorf("dead letter is missing the rejection reason: %s", data)
+	}
+
+	stats := client.Stats()
+	if stats.EventsDeadLettered != 2 || stats.EventsDropped != 0 {
+		t.Errorf("expected 2 dead-lettered and no dropped events, got %+v", stats)
+	}
+
+	// Still rejected: the batch stays in the file
+	if n, err := client.ReplayDeadLetters(context.Background()); err == nil || n != 0 {
+		t.Fatalf("expected replay to fail, got %d, %v", n, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the batch to remain dead-lettered: %v", err)
+	}
+
+	reject.Store(false)
+	n, err := client.ReplayDeadLetters(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 events replayed, got %d, %v", n, err)
+	}
+	if accepted.Load() != 1 {
+		t.Errorf("expected one replayed request, got %d", accepted.Load())
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the dead-letter file to be emptied, got %v", err)
+	}
+}
+
+func TestDeadLetterSkipsTransientFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	client := NewClient("test-key", WithBaseURL(server.URL), WithDeadLetterFile(path))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Flush()
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("transient failures should not be dead-lettered, got %v", err)
+	}
+}
+
+func TestDeadLetterUnblocksPersistentQueue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithPersistentQueue(filepath.Join(dir, "queue")),
+		WithDeadLetterFile(filepath.Join(dir, "dead.jsonl")))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Flush()
+
+	if left := segments(t, filepath.Join(dir, "queue")); len(left) != 0 {
+		t.Errorf("expected the rejected segment to move to the dead-letter file, got %v", left)
+	}
+}
+
+func TestReplayDeadLettersRequiresFile(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	if _, err := client.ReplayDeadLetters(context.Background()); err == nil {
+		t.Error("expected an error without WithDeadLetterFile")
+	}
+}
@@ -0,0 +1,56 @@
+package trusera
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// RequestDecision is what an InterceptorOptions.Decide callback wants done
+// with a request. It acts like a Rule matching the request ahead of all
+// others; an empty Action leaves the request to the rules and patterns.
+type RequestDecision struct {
+	Action   RuleAction // RuleExclude, RuleAllow, RuleLog, RuleWarn or RuleBlock
+	RuleID   string     // Reported as rule_id; defaults to "decide"
+	Reason   string     // Reported as rule_reason
+	Severity string
+}
+
+// decide consults the Decide callback, if any, whose decision takes
+// precedence over the verdict of the rules and patterns. The callback may
+// read the request body, which is never nil and is restored for the
+// request itself.
+func (t *interceptingTransport) decide(req *http.Request, v verdict) verdict {
+	decide := t.localOptions().Decide
+	if decide == nil {
+		return v
+	}
+	switch req.Body {
+	case nil:
+		req.Body = http.NoBody
+		defer func() { req.Body = nil }()
+	case http.NoBody:
+	default:
+		body, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		defer func() { req.Body = io.NopCloser(bytes.NewReader(body)) }()
+	}
+
+	d := decide(req)
+	if d.Action == "" {
+		return v
+	}
+	rule := &Rule{ID: d.RuleID, Action: d.Action, Reason: d.Reason, Severity: d.Severity}
+	if rule.ID == "" {
+		rule.ID = "decide"
+	}
+	decided := ruleVerdict(rule, rule.ID, t.options().Enforcement, v.version)
+	if v.learning {
+		return decided.observe()
+	}
+	if v.shadow {
+		return decided.shadowed()
+	}
+	return pause(decided, t.remotePolicy())
+}
@@ -0,0 +1,81 @@
+package trusera
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+	var received []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+	}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"/admin"},
+		Decide: func(r *http.Request) RequestDecision {
+			body, _ := io.ReadAll(r.Body)
+			switch {
+			case strings.Contains(string(body), "transfer_all"):
+				return RequestDecision{Action: RuleBlock, RuleID: "no-sweeps", Reason: "sweeping transfer"}
+			case r.Header.Get("X-Dry-Run") != "":
+				return RequestDecision{Action: RuleAllow}
+			}
+			return RequestDecision{}
+		},
+	})
+
+	if _, err := httpClient.Post(backend.URL+"/pay", "application/json", strings.NewReader(`{"op":"transfer_all"}`)); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected the body-inspected request blocked, got %v", err)
+	}
+	resp, err := httpClient.Post(backend.URL+"/pay", "application/json", strings.NewReader(`{"op":"refund"}`))
+	if err != nil {
+		t.Fatalf("expected an undecided request to pass: %v", err)
+	}
+	resp.Body.Close()
+	if _, err := httpClient.Get(backend.URL + "/admin"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected an undecided request left to the patterns, got %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, backend.URL+"/admin", nil)
+	req.Header.Set("X-Dry-Run", "1")
+	if resp, err := httpClient.Do(req); err != nil {
+		t.Errorf("expected the decision to override the patterns: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	if len(received) != 2 || received[0] != `{"op":"refund"}` {
+		t.Errorf("expected the inspected body restored for the request, got %q", received)
+	}
+	blocked, ok := trackedEventWhere(client, func(e Event) bool { return e.Payload["rule_id"] == "no-sweeps" })
+	if !ok || blocked.Payload["rule_reason"] != "sweeping transfer" || blocked.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("expected the decision recorded on the event, got %v", blocked.Payload)
+	}
+}
+
+func TestDecidePausedEnforcement(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	client.ApplyPolicy(&RemotePolicy{PauseEnforcement: true})
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Decide: func(r *http.Request) RequestDecision { return RequestDecision{Action: RuleBlock} },
+	})
+	resp, err := httpClient.Get(backend.URL)
+	if err != nil {
+		t.Fatalf("expected paused enforcement to let the request through: %v", err)
+	}
+	resp.Body.Close()
+}
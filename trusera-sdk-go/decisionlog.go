@@ -0,0 +1,75 @@
+package trusera
+
+import "context"
+
+// WithDecisionLog tracks an EventPolicyDecision for every enforcement
+// evaluation by the client's interceptors, whether the target was allowed,
+// warned about or blocked. Each event records the rule that decided, the
+// inputs considered and the outcome, a complete decision log for auditors.
+// Excluded targets are not evaluated, so they are not logged.
+func WithDecisionLog() Option {
+	return func(c *Client) {
+		c.decisionLog = true
+	}
+}
+
+// Decision outcomes recorded by EventPolicyDecision
+const (
+	DecisionAllow = "allow" // The target passed every check
+	DecisionWarn  = "warn"  // A check flagged it, but the enforcement mode let it through
+	DecisionBlock = "block" // It was rejected
+
+	DecisionWouldBlock = "would_block" // ModeShadow let it through, but ModeBlock would have rejected it
+)
+
+// logDecision tracks an EventPolicyDecision if WithDecisionLog is set. The
+// causes are the checks beyond the verdict that were run, with what they
+// found.
+func (t *interceptingTransport) logDecision(ctx context.Context, protocol, method, target string, v verdict, blocked bool, causes ...blockCause) {
+	if !t.client.decisionLog {
+		return
+	}
+
+	outcome := DecisionAllow
+	if blocked {
+		switch {
+		case v.mode.rejects():
+			outcome = DecisionBlock
+		case v.mode == ModeShadow:
+			outcome = DecisionWouldBlock
+		default:
+			outcome = DecisionWarn
+		}
+	}
+	inputs := map[string]any{"protocol": protocol, "target": target}
+	if method != "" {
+		inputs["method"] = method
+	}
+	if host, port := targetAddr(target); host != "" {
+		inputs["host"] = host
+		if port != "" {
+			inputs["port"] = port
+		}
+	}
+
+	event := NewEvent(EventPolicyDecision, outcome+" "+target).
+		WithPayload("outcome", outcome).
+		WithPayload("inputs", inputs)
+	if blocked {
+		err := v.blockedError(target, causes...)
+		event = event.WithPayload("reason", string(err.Reason))
+		if len(err.Details) > 0 {
+			event = event.WithPayload("details", err.Details)
+		}
+	}
+	findings := map[string][]string{}
+	for _, c := range causes {
+		if len(c.details) > 0 {
+			findings[string(c.reason)] = c.details
+		}
+	}
+	if len(findings) > 0 {
+		event = event.WithPayload("findings", findings)
+	}
+	t.client.Track(t.client.enrich(ctx, v.describe(event)))
+}
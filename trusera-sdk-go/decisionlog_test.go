@@ -0,0 +1,107 @@
+package trusera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDecisionLog(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}), WithDecisionLog())
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Rules: []Rule{
+			{ID: "no-admin", Match: "/admin", Action: RuleBlock},
+			{ID: "soft", Match: "/beta", Action: RuleWarn},
+			{ID: "health", Match: "/health", Action: RuleExclude},
+		},
+	})
+
+	resp, err := httpClient.Get(backend.URL + "/public")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	resp, err = httpClient.Get(backend.URL + "/beta")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	resp, err = httpClient.Get(backend.URL + "/health")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if _, err := httpClient.Post(backend.URL+"/admin", "text/plain", nil); err == nil {
+		t.Fatal("expected /admin blocked")
+	}
+
+	events := trackedEvents(client, EventPolicyDecision)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 decisions (excluded targets are not logged), got %d", len(events))
+	}
+	allow, warn, block := events[0], events[1], events[2]
+	if allow.Payload["outcome"] != DecisionAllow || allow.Payload["reason"] != nil {
+		t.Errorf("unexpected allow decision %v", allow.Payload)
+	}
+	if warn.Payload["outcome"] != DecisionWarn || warn.Payload["rule_id"] != "soft" || warn.Metadata["enforcement_mode"] != "warn" {
+		t.Errorf("unexpected warn decision %v %v", warn.Payload, warn.Metadata)
+	}
+	if block.Payload["outcome"] != DecisionBlock || block.Payload["rule_id"] != "no-admin" || block.Payload["reason"] != "rule" {
+		t.Errorf("unexpected block decision %v", block.Payload)
+	}
+	inputs := block.Payload["inputs"].(map[string]any)
+	if inputs["method"] != http.MethodPost || inputs["host"] != "127.0.0.1" || inputs["target"] != backend.URL+"/admin" || inputs["protocol"] != "http" {
+		t.Errorf("unexpected inputs %v", inputs)
+	}
+}
+
+func TestDecisionLogFindings(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}), WithDecisionLog())
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:      ModeWarn,
+		CredentialEgress: &CredentialPolicy{AllowedHosts: []string{"api.example.com"}},
+	})
+	req, _ := http.NewRequest(http.MethodGet, backend.URL, nil)
+	req.Header.Set("Authorization", "Bearer x")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected warn mode to allow the request, got %v", err)
+	}
+	resp.Body.Close()
+
+	events := trackedEvents(client, EventPolicyDecision)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 decision, got %d", len(events))
+	}
+	findings := events[0].Payload["findings"].(map[string][]string)
+	if events[0].Payload["outcome"] != DecisionWarn || events[0].Payload["reason"] != "credential_egress" ||
+		len(findings["credential_egress"]) != 1 {
+		t.Errorf("unexpected decision %v", events[0].Payload)
+	}
+}
+
+func TestDecisionLogDisabled(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	resp, err := CreateInterceptedClient(client, InterceptorOptions{}).Get(backend.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if events := trackedEvents(client, EventPolicyDecision); len(events) != 0 {
+		t.Errorf("expected no decisions without WithDecisionLog, got %d", len(events))
+	}
+}
@@ -0,0 +1,88 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// WithIdempotencyKey gives an event an ID derived from key, so an event
+// tracked again under the same key, e.g. by a retried job, carries the
+// same ID and the API records it once. The key is kept in metadata
+// idempotency_key.
+func (e Event) WithIdempotencyKey(key string) Event {
+	sum := sha256.Sum256([]byte("trusera-event:" + key))
+	e.ID = hex.EncodeToString(sum[:16])
+	return e.WithMetadata("idempotency_key", key)
+}
+
+// WithDeduplication drops an event when an identical one was tracked within
+// window: one with the same ID, as set by Event.WithIdempotencyKey, or
+// with the same type, name, payload and metadata. It guards against
+// instrumentation that fires twice and against at-least-once callers.
+// Dropped events count under the reason "duplicate".
+func WithDeduplication(window time.Duration) Option {
+	return func(c *Client) {
+		c.dedup = &deduplicator{window: window, seen: map[string]time.Time{}, now: time.Now}
+	}
+}
+
+// deduplicator remembers the events seen within the window
+type deduplicator struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // Fingerprint to expiry
+	lastSweep time.Time
+}
+
+// duplicate reports whether an identical event was seen within the window,
+// and remembers this one
+func (d *deduplicator) duplicate(event Event) bool {
+	keys := []string{"id:" + event.ID}
+	content, err := json.Marshal(struct {
+		Type     EventType      `json:"type"`
+		Name     string         `json:"name"`
+		Payload  map[string]any `json:"payload"`
+		Metadata map[string]any `json:"metadata"`
+	}{event.Type, event.Name, event.Payload, event.Metadata})
+	if err == nil {
+		sum := sha256.Sum256(content)
+		keys = append(keys, "content:"+hex.EncodeToString(sum[:]))
+	}
+
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastSweep) >= d.window {
+		for k, expiry := range d.seen {
+			if !now.Before(expiry) {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	duplicate := false
+	for _, k := range keys {
+		if expiry, ok := d.seen[k]; ok && now.Before(expiry) {
+			duplicate = true
+		}
+	}
+	if !duplicate {
+		for _, k := range keys {
+			d.seen[k] = now.Add(d.window)
+		}
+	}
+	return duplicate
+}
+
+// idempotencyKey identifies an events request body, so the API can
+// recognize a retried batch
+func idempotencyKey(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,77 @@
+package trusera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithIdempotencyKey(t *testing.T) {
+	a := NewEvent(EventDecision, "refund").WithIdempotencyKey("order-42")
+	b := NewEvent(EventDecision, "refund").WithIdempotencyKey("order-42")
+	c := NewEvent(EventDecision, "refund").WithIdempotencyKey("order-43")
+	if a.ID != b.ID || a.ID == c.ID || len(a.ID) != 32 {
+		t.Errorf("expected IDs derived from the key, got %q, %q, %q", a.ID, b.ID, c.ID)
+	}
+	if a.Metadata["idempotency_key"] != "order-42" {
+		t.Errorf("expected the key in the metadata, got %v", a.Metadata)
+	}
+}
+
+func TestWithDeduplication(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithDeduplication(time.Minute))
+	defer client.Close()
+	now := time.Now()
+	client.dedup.now = func() time.Time { return now }
+
+	event := func(name string) Event {
+		return NewEvent(EventToolCall, name).WithPayload("query", "weather")
+	}
+	client.Track(event("search"))
+	client.Track(event("search"))                            // Same content, new ID
+	client.Track(event("fetch"))                             // Different name
+	client.Track(event("fetch").WithIdempotencyKey("job-1")) // Different content
+	client.Track(event("other").WithIdempotencyKey("job-1")) // Same ID
+	now = now.Add(2 * time.Minute)
+	client.Track(event("search")) // Window passed
+
+	if got := len(trackedEvents(client, EventToolCall)); got != 4 {
+		t.Errorf("expected 4 events, got %d", got)
+	}
+	if got := client.Stats().EventsDroppedDuplicate; got != 2 {
+		t.Errorf("expected 2 duplicates, got %d", got)
+	}
+}
+
+func TestRetriedBatchKeepsIdempotencyKey(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour),
+		WithRetry(RetryPolicy{InitialBackoff: time.Millisecond}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected the retry to reuse the key, got %q", keys)
+	}
+}
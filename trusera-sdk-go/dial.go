@@ -0,0 +1,143 @@
+package trusera
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WrapDialContext returns a dial function that tracks and enforces every
+// outbound connection, whatever protocol runs over it: HTTP clients with their
+// own transports, database drivers, SMTP or raw TCP. Rules and patterns are
+// matched against "network://host:port" (for example
+// "tcp://db.internal:5432"), and a request policy sees a CONNECT to that
+// address. A closed connection is reported with the bytes it carried. A nil
+// dial uses net.Dialer.
+func WrapDialContext(dial DialContextFunc, truseraClient *Client, opts InterceptorOptions) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t := &interceptingTransport{client: truseraClient, opts: opts}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target := network + "://" + addr
+		v := t.evaluate(target)
+		if v.excluded {
+			return dial(ctx, network, addr)
+		}
+		v = t.canary(ctx, target, v)
+
+		req := (&http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Scheme: network, Host: addr},
+			Host:   addr,
+			Header: http.Header{},
+		}).WithContext(ctx)
+		blocked := v.blocked
+		policyReasons := t.policyReasons(req)
+		if len(policyReasons) > 0 {
+			blocked = true
+		}
+
+		event := connEvent(NewEvent(EventAPICall, "connect "+target), network, addr).
+			WithPayload("blocked", blocked)
+		event = truseraClient.correlate(ctx, v.describe(event))
+		if len(policyReasons) > 0 {
+			event = event.WithPayload("policy_reasons", policyReasons)
+		}
+
+		t.logDecision(ctx, network, "", target, v, blocked, blockCause{BlockReasonPolicy, policyReasons})
+		if blocked {
+			event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+			switch v.mode {
+			case ModeBlock, ModeAllowList:
+				truseraClient.Track(event)
+				return nil, v.blockedError(target, blockCause{BlockReasonPolicy, policyReasons})
+			case ModeWarn:
+				event = event.WithMetadata("warning", "connection matches block pattern but allowed in warn mode")
+			}
+		} else {
+			event = event.WithPayload("enforcement_action", "allowed")
+		}
+		truseraClient.Track(event)
+
+		start := time.Now()
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			errorEvent := connEvent(NewEvent(EventAPICall, "error"), network, addr).
+				WithPayload("error", err.Error())
+			truseraClient.Track(truseraClient.correlate(ctx, errorEvent))
+			return nil, err
+		}
+
+		return &trackedConn{Conn: conn, client: truseraClient, network: network, addr: addr, start: start, ctx: ctx}, nil
+	}
+}
+
+// Transport returns a clone of http.DefaultTransport whose connections are
+// dialed through WrapDialContext. Use it for clients that cannot be wrapped
+// with WrapHTTPClient. Behind an HTTP proxy the connection observed is the
+// one to the proxy.
+func Transport(truseraClient *Client, opts InterceptorOptions) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport.DialContext = WrapDialContext(dialer.DialContext, truseraClient, opts)
+	return transport
+}
+
+// connEvent describes the address of a connection
+func connEvent(event Event, network, addr string) Event {
+	event = event.
+		WithPayload("protocol", network).
+		WithPayload("address", addr)
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		event = event.WithPayload("host", host)
+		if n, err := strconv.Atoi(port); err == nil {
+			event = event.WithPayload("port", n)
+		}
+	}
+	return event
+}
+
+// trackedConn counts the bytes on a dialed connection and reports them on close
+type trackedConn struct {
+	net.Conn
+	client   *Client
+	network  string
+	addr     string
+	start    time.Time
+	ctx      context.Context
+	sent     atomic.Int64
+	received atomic.Int64
+	once     sync.Once
+}
+
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.Add(int64(n))
+	return n, err
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		event := connEvent(NewEvent(EventAPICall, "connection_close"), c.network, c.addr).
+			WithPayload("bytes_sent", c.sent.Load()).
+			WithPayload("bytes_received", c.received.Load()).
+			WithPayload("duration_ms", float64(time.Since(c.start).Microseconds())/1000)
+		c.client.Track(c.client.correlate(c.ctx, event))
+	})
+	return err
+}
@@ -0,0 +1,166 @@
+package trusera
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dialClient(t *testing.T) *Client {
+	t.Helper()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(api.Close)
+	client := NewClient("test-key", WithBaseURL(api.URL), WithFlushInterval(time.Hour))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// echoListener echoes every connection back until closed
+func echoListener(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestWrapDialContextTracksConnections(t *testing.T) {
+	client := dialClient(t)
+	ln := echoListener(t)
+
+	dial := WrapDialContext(nil, client, InterceptorOptions{Enforcement: ModeLog})
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	conn.Write([]byte("hello"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	conn.Close()
+	conn.Close()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	connect, ok := trackedEvent(client, "connect tcp://"+ln.Addr().String())
+	if !ok {
+		t.Fatal("connect event not tracked")
+	}
+	if connect.Payload["host"] != "127.0.0.1" || connect.Payload["enforcement_action"] != "allowed" {
+		t.Errorf("unexpected connect payload %v", connect.Payload)
+	}
+	if want, _ := strconv.Atoi(port); connect.Payload["port"] != want {
+		t.Errorf("expected port %d, got %v", want, connect.Payload["port"])
+	}
+
+	closed := 0
+	client.mu.Lock()
+	for _, e := range client.events {
+		if e.Name == "connection_close" {
+			closed++
+			if e.Payload["bytes_sent"] != int64(5) || e.Payload["bytes_received"] != int64(5) {
+				t.Errorf("unexpected byte counts %v", e.Payload)
+			}
+		}
+	}
+	client.mu.Unlock()
+	if closed != 1 {
+		t.Errorf("expected one close event, got %d", closed)
+	}
+}
+
+func TestWrapDialContextEnforcesByHostPort(t *testing.T) {
+	client := dialClient(t)
+	ln := echoListener(t)
+	addr := ln.Addr().String()
+
+	dialed := 0
+	base := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed++
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+	dial := WrapDialContext(base, client, InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules: []Rule{
+			{ID: "no-smtp", Match: ":25", Action: RuleBlock},
+			{ID: "echo", Match: "tcp://" + addr, Action: RuleBlock, Severity: "high"},
+		},
+	})
+
+	if _, err := dial(context.Background(), "tcp", addr); err == nil || !strings.Contains(err.Error(), "blocked by Trusera policy") {
+		t.Fatalf("expected the connection to be blocked, got %v", err)
+	}
+	if dialed != 0 {
+		t.Error("a blocked connection should never be dialed")
+	}
+
+	event, _ := trackedEvent(client, "connect tcp://"+addr)
+	if event.Payload["rule_id"] != "echo" || event.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("unexpected blocked event %v", event.Payload)
+	}
+}
+
+func TestWrapDialContextExcludeAndDialError(t *testing.T) {
+	client := dialClient(t)
+	ln := echoListener(t)
+
+	dial := WrapDialContext(nil, client, InterceptorOptions{
+		Enforcement:     ModeBlock,
+		ExcludePatterns: []string{ln.Addr().String()},
+	})
+	conn, err := dial(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if _, ok := conn.(*trackedConn); ok {
+		t.Error("excluded connections should not be wrapped")
+	}
+	conn.Close()
+
+	failing := WrapDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: io.EOF}
+	}, client, InterceptorOptions{})
+	if _, err := failing(context.Background(), "tcp", "db.internal:5432"); err == nil {
+		t.Fatal("expected the dial error to be returned")
+	}
+	if _, ok := trackedEventWhere(client, func(e Event) bool { return e.Name == "error" && e.Payload["address"] == "db.internal:5432" }); !ok {
+		t.Error("expected a dial error event")
+	}
+}
+
+func TestTransportInterceptsHTTP(t *testing.T) {
+	client := dialClient(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	transport := Transport(client, InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{backend.Listener.Addr().String()}})
+	httpClient := &http.Client{Transport: transport}
+	if _, err := httpClient.Get(backend.URL); err == nil {
+		t.Fatal("expected the transport to block the connection")
+	}
+	if transport == http.DefaultTransport {
+		t.Error("Transport should return a clone")
+	}
+}
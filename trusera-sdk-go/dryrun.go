@@ -0,0 +1,108 @@
+package trusera
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// dryRunAgentID is the agent ID RegisterAgent returns in dry-run mode
+const dryRunAgentID = "dry-run-agent"
+
+// WithDryRun runs the SDK without network access, to verify instrumentation
+// before granting it any. Events are buffered, batched, redacted, signed and
+// checked against policy as usual, but every request the client would make
+// to Trusera is printed to stdout instead: the method, the URL and the
+// indented JSON body. RegisterAgent returns a placeholder agent ID, policy
+// syncs find the policy unchanged, and other requests succeed. The primary
+// sink is replaced, secondary sinks are not called and batches are not
+// compressed. Interceptors still enforce on the application's own requests.
+func WithDryRun() Option {
+	return func(c *Client) {
+		c.dryRunOut = os.Stdout
+	}
+}
+
+// applyDryRun routes the client's requests to the dry-run printer
+func (c *Client) applyDryRun() {
+	c.primary = nil
+	c.sinks = nil
+	c.compressor = nil
+	c.httpClient.Transport = &dryRunTransport{w: c.dryRunOut}
+}
+
+// dryRunTransport prints requests instead of sending them and answers them
+// locally
+type dryRunTransport struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (t *dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body, ack []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		ack = dryRunAck(body)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "trusera dry run: %s %s\n", req.Method, req.URL)
+	if len(body) > 0 {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", "  ") == nil {
+			body = indented.Bytes()
+		}
+		out.Write(body)
+		out.WriteByte('\n')
+	}
+	t.mu.Lock()
+	_, err := t.w.Write(out.Bytes())
+	t.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to print request: %w", err)
+	}
+
+	status, reply := http.StatusOK, "{}"
+	switch {
+	case req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, "/policy"):
+		status, reply = http.StatusNotModified, ""
+	case req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/v1/agents"):
+		reply = `{"agent_id": "` + dryRunAgentID + `"}`
+	case ack != nil:
+		reply = string(ack)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(reply)),
+		Request:    req,
+	}, nil
+}
+
+// dryRunAck acknowledges the sequence numbers of an events request sent
+// with WithOrderedDelivery, or returns nil
+func dryRunAck(body []byte) []byte {
+	var batch struct {
+		Delivery struct {
+			LastSequence uint64 `json:"last_sequence"`
+		} `json:"delivery"`
+	}
+	if json.Unmarshal(body, &batch) != nil || batch.Delivery.LastSequence == 0 {
+		return nil
+	}
+	return []byte(fmt.Sprintf(`{"acked_sequence": %d}`, batch.Delivery.LastSequence))
+}
@@ -0,0 +1,57 @@
+package trusera
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRun(t *testing.T) {
+	out := &logBuffer{}
+	sink := &memorySink{}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(sink),
+		WithCompression(Gzip),
+		WithDryRun(),
+		func(c *Client) { c.dryRunOut = out },
+		WithEventHook(func(e *Event) (*Event, error) {
+			hooked := e.WithMetadata("hooked", true)
+			return &hooked, nil
+		}),
+	)
+	defer client.Close()
+
+	agentID, err := client.RegisterAgent("payments", "custom")
+	if err != nil || agentID != dryRunAgentID {
+		t.Fatalf("expected the placeholder agent ID, got %q, %v", agentID, err)
+	}
+	if err := client.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("expected the policy sync to succeed, got %v", err)
+	}
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if names := sink.names(); len(names) != 0 {
+		t.Errorf("expected nothing to reach the sink, got %v", names)
+	}
+	printed := out.buf.String()
+	for _, want := range []string{
+		"trusera dry run: POST https://api.trusera.io/v1/agents\n",
+		"trusera dry run: GET https://api.trusera.io/v1/agents/dry-run-agent/policy\n",
+		"trusera dry run: POST https://api.trusera.io/v1/events\n{\n",
+		`"name": "search"`,
+		`"hooked": true`,
+	} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("expected %q in the output:\n%s", want, printed)
+		}
+	}
+	if stats := client.Stats(); stats.EventsFlushed != 1 {
+		t.Errorf("expected the batch to count as flushed, got %+v", stats)
+	}
+}
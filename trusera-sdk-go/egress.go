@@ -0,0 +1,77 @@
+package trusera
+
+import (
+	"net/http"
+	"slices"
+)
+
+// EventCredentialEgress is tracked for every request that carries
+// credentials to a host outside a CredentialPolicy's approved list
+const EventCredentialEgress EventType = "credential_egress"
+
+// CredentialPolicy catches credentials leaking to hosts they were not meant
+// for, such as an agent tricked into sending its API key to an attacker's
+// endpoint. A request carrying any of the Headers to a host not in
+// AllowedHosts is treated like a blocked URL under the enforcement mode.
+type CredentialPolicy struct {
+	// AllowedHosts are the hosts credentials may be sent to: host names,
+	// matched exactly, or host globs, IPs and CIDR ranges as accepted by
+	// CompilePattern, such as "*.corp.example.com"
+	AllowedHosts []string
+
+	// Headers are the credential headers to look for, case-insensitively.
+	// When empty, Authorization, Proxy-Authorization, Cookie, X-Api-Key,
+	// Api-Key, X-Auth-Token and X-Goog-Api-Key are.
+	Headers []string
+}
+
+var defaultCredentialHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie",
+	"X-Api-Key", "Api-Key", "X-Auth-Token", "X-Goog-Api-Key",
+}
+
+// credentialEgress returns the names of the credential headers a request
+// carries to a host that is not approved for them, tracking an
+// EventCredentialEgress if there are any
+func (t *interceptingTransport) credentialEgress(req *http.Request, mode EnforcementMode) []string {
+	policy := t.localOptions().CredentialEgress
+	if policy == nil {
+		return nil
+	}
+	target := req.URL.String()
+	if slices.ContainsFunc(policy.AllowedHosts, func(host string) bool { return matchHost(target, host) }) {
+		return nil
+	}
+
+	names := policy.Headers
+	if len(names) == 0 {
+		names = defaultCredentialHeaders
+	}
+	var leaked []string
+	for _, name := range names {
+		if req.Header.Get(name) != "" {
+			leaked = append(leaked, http.CanonicalHeaderKey(name))
+		}
+	}
+	if len(leaked) == 0 {
+		return nil
+	}
+
+	action := "flagged"
+	if mode.rejects() {
+		action = "blocked"
+	}
+	host, _ := targetAddr(target)
+	event := NewEvent(EventCredentialEgress, host).
+		WithPayload("host", host).
+		WithPayload("url", target).
+		WithPayload("method", req.Method).
+		WithPayload("headers", leaked).
+		WithPayload("action", action).
+		WithMetadata("enforcement_mode", string(mode))
+	if mode == ModeWarn {
+		event = event.WithMetadata("warning", "credentials sent to an unapproved host but allowed in warn mode")
+	}
+	t.client.Track(t.client.correlateRequest(req, event))
+	return leaked
+}
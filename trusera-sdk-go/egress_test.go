@@ -0,0 +1,104 @@
+package trusera
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCredentialEgress(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Rules:       []Rule{{Match: "127.0.0.1", Action: RuleAllow}},
+		CredentialEgress: &CredentialPolicy{
+			AllowedHosts: []string{"api.example.com", "*.corp.example.com"},
+		},
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, backend.URL+"/collect", nil)
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("Cookie", "session=1")
+	if _, err := httpClient.Do(req); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected credentials to an unapproved host blocked, got %v", err)
+	}
+	resp, err := httpClient.Get(backend.URL + "/public")
+	if err != nil {
+		t.Fatalf("expected a request without credentials allowed, got %v", err)
+	}
+	resp.Body.Close()
+
+	events := trackedEvents(client, EventCredentialEgress)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 credential egress event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Payload["host"] != "127.0.0.1" || e.Payload["action"] != "blocked" ||
+		!slices.Equal(e.Payload["headers"].([]string), []string{"Authorization", "Cookie"}) {
+		t.Errorf("unexpected event payload %v", e.Payload)
+	}
+	call, _ := trackedEvent(client, "GET "+backend.URL+"/collect")
+	if call.Payload["blocked"] != true || call.Payload["credential_headers"] == nil {
+		t.Errorf("expected the api_call event to record the leak, got %v", call.Payload)
+	}
+	for _, v := range e.Payload {
+		if v == "Bearer sk-secret" {
+			t.Error("credential value recorded")
+		}
+	}
+}
+
+func TestCredentialEgressApprovedHosts(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:      ModeBlock,
+		CredentialEgress: &CredentialPolicy{AllowedHosts: []string{"127.0.0.0/8"}},
+	})
+	req, _ := http.NewRequest(http.MethodGet, backend.URL, nil)
+	req.Header.Set("X-Api-Key", "secret")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected credentials to an approved host allowed, got %v", err)
+	}
+	resp.Body.Close()
+	if n := len(trackedEvents(client, EventCredentialEgress)); n != 0 {
+		t.Errorf("expected no credential egress events, got %d", n)
+	}
+}
+
+func TestCredentialEgressWarnsOnCustomHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:      ModeWarn,
+		CredentialEgress: &CredentialPolicy{Headers: []string{"x-internal-token"}},
+	})
+	req, _ := http.NewRequest(http.MethodGet, backend.URL, nil)
+	req.Header.Set("Authorization", "Bearer x")
+	req.Header.Set("X-Internal-Token", "secret")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("expected the request allowed in warn mode, got %v", err)
+	}
+	resp.Body.Close()
+
+	events := trackedEvents(client, EventCredentialEgress)
+	if len(events) != 1 || events[0].Payload["action"] != "flagged" || events[0].Metadata["warning"] == nil ||
+		!slices.Equal(events[0].Payload["headers"].([]string), []string{"X-Internal-Token"}) {
+		t.Errorf("unexpected events %v", events)
+	}
+}
@@ -0,0 +1,197 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// KeyProvider supplies the AES key used for encryption at rest, e.g. by
+// decrypting a data key with a KMS. Keys are 16, 24 or 32 bytes long.
+type KeyProvider interface {
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// KeyProviderFunc adapts a function to the KeyProvider interface
+type KeyProviderFunc func(ctx context.Context) ([]byte, error)
+
+// Key calls f(ctx)
+func (f KeyProviderFunc) Key(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// StaticKey provides a fixed key
+func StaticKey(key []byte) KeyProvider {
+	return KeyProviderFunc(func(context.Context) ([]byte, error) {
+		return key, nil
+	})
+}
+
+// EnvKey provides a key from an environment variable, base64 or hex encoded,
+// e.g. generated with `openssl rand -base64 32`
+func EnvKey(name string) KeyProvider {
+	return KeyProviderFunc(func(context.Context) ([]byte, error) {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			return nil, fmt.Errorf("%s is not set", name)
+		}
+		if key, err := hex.DecodeString(value); err == nil {
+			return key, nil
+		}
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s is neither base64 nor hex", name)
+		}
+		return key, nil
+	})
+}
+
+// WithEncryptionAtRest encrypts every event the client writes to disk with
+// AES-GCM: the persistent queue, the spill directory, the dead-letter file,
+// offline files and the hash chain's audit log. Each line of these files is
+// encrypted on its own. Unencrypted lines read back from them are dropped
+// and reported with ErrUnencrypted, so that events cannot be injected by
+// writing plaintext into the files; see WithPlaintextMigration for files
+// written before encryption was enabled.
+//
+// The key is loaded once by NewClient. If that fails, nothing is written to
+// disk and the error is reported by Flush.
+func WithEncryptionAtRest(p KeyProvider) Option {
+	return func(c *Client) {
+		c.keyProvider = p
+	}
+}
+
+// WithPlaintextMigration accepts the unencrypted lines of files written
+// before WithEncryptionAtRest was enabled, such as a persistent queue left
+// by the previous version of the agent. Events read back are written again
+// encrypted or removed once sent, so it is meant for the first start with
+// encryption only and should then be removed.
+func WithPlaintextMigration() Option {
+	return func(c *Client) {
+		c.plaintextMigration = true
+	}
+}
+
+// keyLoadTimeout bounds how long NewClient waits for a KeyProvider
+const keyLoadTimeout = 10 * time.Second
+
+// encryptedPrefix starts every encrypted line, followed by the key's ID and
+// the base64 nonce and ciphertext: enc1:<key id>:<data>
+const encryptedPrefix = "enc1:"
+
+// ErrEncryptionKey is returned when reading a file encrypted with another
+// key, or without one
+var ErrEncryptionKey = errors.New("events are encrypted with another key")
+
+// ErrUnencrypted is reported when unencrypted lines were dropped from a file
+// written with encryption at rest; the events on the other lines are kept
+var ErrUnencrypted = errors.New("unencrypted events dropped")
+
+// lineCipher encrypts the lines of JSONL files. A nil lineCipher writes
+// plaintext; one whose key failed to load refuses to write.
+type lineCipher struct {
+	aead    cipher.AEAD
+	keyID   string
+	err     error
+	migrate bool // Accept plaintext lines; see WithPlaintextMigration
+}
+
+// newLineCipher creates a cipher for key; errors surface on use
+func newLineCipher(key []byte) *lineCipher {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return &lineCipher{err: fmt.Errorf("invalid encryption key: %w", err)}
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return &lineCipher{err: fmt.Errorf("invalid encryption key: %w", err)}
+	}
+	sum := sha256.Sum256(key)
+	return &lineCipher{aead: aead, keyID: hex.EncodeToString(sum[:4])}
+}
+
+// loadLineCipher gets a key from p
+func loadLineCipher(p KeyProvider) *lineCipher {
+	ctx, cancel := context.WithTimeout(context.Background(), keyLoadTimeout)
+	defer cancel()
+	key, err := p.Key(ctx)
+	if err != nil {
+		return &lineCipher{err: fmt.Errorf("failed to load encryption key: %w", err)}
+	}
+	return newLineCipher(key)
+}
+
+// seal encrypts a line, which must not end in a newline
+func (l *lineCipher) seal(line []byte) ([]byte, error) {
+	if l == nil {
+		return line, nil
+	}
+	if l.err != nil {
+		return nil, l.err
+	}
+	nonce := make([]byte, l.aead.NonceSize(), l.aead.NonceSize()+len(line)+l.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to encrypt event: %w", err)
+	}
+	data := l.aead.Seal(nonce, nonce, line, nil)
+
+	return []byte(encryptedPrefix + l.keyID + ":" + base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// open decrypts a line written by seal. Plaintext lines pass through only
+// without a cipher or while migrating. It returns ErrEncryptionKey for lines
+// encrypted with another key, errPlaintextLine for plaintext lines and
+// errCorruptLine for lines that are torn or were modified.
+func (l *lineCipher) open(line []byte) ([]byte, error) {
+	rest, ok := bytes.CutPrefix(line, []byte(encryptedPrefix))
+	if !ok {
+		if l != nil && !l.migrate {
+			return nil, errPlaintextLine
+		}
+		return line, nil
+	}
+	keyID, encoded, ok := bytes.Cut(rest, []byte(":"))
+	if !ok {
+		return nil, errCorruptLine
+	}
+	if l == nil || l.err != nil || string(keyID) != l.keyID {
+		return nil, ErrEncryptionKey
+	}
+	data, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil || len(data) < l.aead.NonceSize() {
+		return nil, errCorruptLine
+	}
+	nonce, ciphertext := data[:l.aead.NonceSize()], data[l.aead.NonceSize():]
+	plain, err := l.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errCorruptLine
+	}
+	return plain, nil
+}
+
+// errCorruptLine marks an encrypted line that cannot be decrypted with the
+// right key; readers skip it like a malformed plaintext line
+var errCorruptLine = errors.New("corrupt encrypted line")
+
+// errPlaintextLine marks an unencrypted line read with a cipher. Readers
+// skip it and count it for unencryptedErr.
+var errPlaintextLine = fmt.Errorf("unencrypted line: %w", ErrUnencrypted)
+
+// unencryptedErr reports the plaintext lines dropped from a file, if any
+func unencryptedErr(path string, dropped int) error {
+	if dropped == 0 {
+		return nil
+	}
+	return fmt.Errorf("dropped %d unencrypted lines from %s: %w", dropped, path, ErrUnencrypted)
+}
@@ -0,0 +1,263 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestLineCipher(t *testing.T) {
+	c := newLineCipher(testKey)
+	line := []byte(`{"name":"secret prompt"}`)
+	sealed, err := c.seal(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) || !bytes.HasPrefix(sealed, []byte(encryptedPrefix)) {
+		t.Fatalf("expected an encrypted line, got %s", sealed)
+	}
+	if again, _ := c.seal(line); bytes.Equal(again, sealed) {
+		t.Error("expected a fresh nonce per line")
+	}
+	if plain, err := c.open(sealed); err != nil || !bytes.Equal(plain, line) {
+		t.Errorf("expected the line back, got %s (%v)", plain, err)
+	}
+	if _, err := c.open(line); !errors.Is(err, ErrUnencrypted) {
+		t.Errorf("expected plaintext lines to be rejected, got %v", err)
+	}
+	migrating := newLineCipher(testKey)
+	migrating.migrate = true
+	if plain, err := migrating.open(line); err != nil || !bytes.Equal(plain, line) {
+		t.Errorf("expected plaintext lines to pass through while migrating, got %s (%v)", plain, err)
+	}
+
+	if _, err := newLineCipher(bytes.Repeat([]byte{8}, 32)).open(sealed); !errors.Is(err, ErrEncryptionKey) {
+		t.Errorf("expected another key to be detected, got %v", err)
+	}
+	var none *lineCipher
+	if _, err := none.open(sealed); !errors.Is(err, ErrEncryptionKey) {
+		t.Errorf("expected a missing key to be detected, got %v", err)
+	}
+	if _, err := c.open(sealed[:len(sealed)-8]); !errors.Is(err, errCorruptLine) {
+		t.Errorf("expected a torn line to be corrupt, got %v", err)
+	}
+	if _, err := newLineCipher([]byte("short")).seal(line); err == nil {
+		t.Error("expected an invalid key to refuse writing")
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	t.Setenv("TEST_TRUSERA_KEY", base64.StdEncoding.EncodeToString(testKey))
+	if key, err := EnvKey("TEST_TRUSERA_KEY").Key(context.Background()); err != nil || !bytes.Equal(key, testKey) {
+		t.Errorf("expected the base64 key, got %x (%v)", key, err)
+	}
+	t.Setenv("TEST_TRUSERA_KEY", strings.Repeat("07", 32))
+	if key, err := EnvKey("TEST_TRUSERA_KEY").Key(context.Background()); err != nil || !bytes.Equal(key, testKey) {
+		t.Errorf("expected the hex key, got %x (%v)", key, err)
+	}
+	if _, err := EnvKey("TEST_TRUSERA_UNSET").Key(context.Background()); err == nil {
+		t.Error("expected an unset variable to fail")
+	}
+}
+
+func TestEncryptedPersistentQueue(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	first := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithPersistentQueue(dir),
+		WithEncryptionAtRest(StaticKey(testKey)))
+	first.Track(NewEvent(EventLLMInvoke, "chat").WithPayload("prompt", "my card is 4111"))
+	first.Close()
+
+	for _, path := range segments(t, dir) {
+		data, _ := os.ReadFile(path)
+		if bytes.Contains(data, []byte("4111")) || bytes.Contains(data, []byte("chat")) {
+			t.Fatalf("expected the segment to be encrypted, got %s", data)
+		}
+	}
+
+	api.fail.Store(false)
+	wrongKey := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir), WithFlushInterval(time.Hour))
+	if err := wrongKey.Flush(); !errors.Is(err, ErrEncryptionKey) {
+		t.Errorf("expected the segment to need the key, got %v", err)
+	}
+	wrongKey.Close()
+	if len(segments(t, dir)) == 0 {
+		t.Fatal("expected the unreadable segment to be kept")
+	}
+
+	second := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithPersistentQueue(dir),
+		WithFlushInterval(time.Hour),
+		WithEncryptionAtRest(StaticKey(testKey)))
+	defer second.Close()
+	if err := second.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(api.received(), ","); got != "chat" {
+		t.Errorf("expected the event to be delivered, got %v", got)
+	}
+}
+
+func TestEncryptedQueueDropsPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	// Left by a version without encryption, or written by someone else
+	plain := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir))
+	plain.Track(NewEvent(EventToolCall, "plaintext"))
+	plain.Close()
+	api.fail.Store(false)
+
+	// The segment is resumed by the background flusher or by Flush
+	var reported atomic.Bool
+	report := func(err error) {
+		if errors.Is(err, ErrUnencrypted) {
+			reported.Store(true)
+		}
+	}
+	encrypted := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithPersistentQueue(dir),
+		WithFlushInterval(time.Hour),
+		WithEncryptionAtRest(StaticKey(testKey)),
+		WithErrorHandler(report))
+	encrypted.Track(NewEvent(EventToolCall, "sealed"))
+	report(encrypted.Flush())
+	encrypted.Close()
+	if !reported.Load() {
+		t.Error("expected the plaintext event to be reported")
+	}
+	if got := strings.Join(api.received(), ","); got != "sealed" {
+		t.Errorf("expected only the encrypted event delivered, got %v", got)
+	}
+	if n := len(segments(t, dir)); n != 0 {
+		t.Errorf("expected the plaintext segment removed, got %d segments", n)
+	}
+}
+
+func TestPlaintextMigration(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	plain := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir))
+	plain.Track(NewEvent(EventToolCall, "before-upgrade"))
+	plain.Close()
+	api.fail.Store(false)
+
+	migrating := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithPersistentQueue(dir),
+		WithFlushInterval(time.Hour),
+		WithEncryptionAtRest(StaticKey(testKey)),
+		WithPlaintextMigration())
+	defer migrating.Close()
+	if err := migrating.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(api.received(), ","); got != "before-upgrade" {
+		t.Errorf("expected the plaintext event migrated, got %v", got)
+	}
+}
+
+func TestEncryptedDeadLettersAndOfflineFiles(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad schema", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	deadLetters := filepath.Join(dir, "dead.jsonl")
+	client := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithFlushInterval(time.Hour),
+		WithDeadLetterFile(deadLetters),
+		WithEncryptionAtRest(StaticKey(testKey)))
+	client.Track(NewEvent(EventToolCall, "rejected-event"))
+	client.Flush()
+	client.Close()
+	if data, _ := os.ReadFile(deadLetters); len(data) == 0 || bytes.Contains(data, []byte("rejected-event")) {
+		t.Errorf("expected an encrypted dead letter, got %s", data)
+	}
+
+	offlineDir := filepath.Join(dir, "offline")
+	offline := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithOfflineMode(offlineDir),
+		WithEncryptionAtRest(StaticKey(testKey)))
+	offline.Track(NewEvent(EventToolCall, "offline-event"))
+	offline.Close()
+	files, _ := offlineFiles(offlineDir)
+	if len(files) != 1 {
+		t.Fatalf("expected one offline file, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); bytes.Contains(data, []byte("offline-event")) {
+		t.Errorf("expected an encrypted offline file, got %s", data)
+	}
+	if _, err := readOfflineFile(files[0], nil); !errors.Is(err, ErrEncryptionKey) {
+		t.Errorf("expected reading without the key to fail, got %v", err)
+	}
+	if events, err := readOfflineFile(files[0], newLineCipher(testKey)); err != nil || len(events) != 1 || events[0].Name != "offline-event" {
+		t.Errorf("expected the event back with the key, got %+v (%v)", events, err)
+	}
+}
+
+func TestEncryptionKeyFailureWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPersistentQueue(dir),
+		WithEncryptionAtRest(KeyProviderFunc(func(context.Context) ([]byte, error) {
+			return nil, errors.New("kms unavailable")
+		})))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "x"))
+	if err := client.Flush(); err == nil || !strings.Contains(err.Error(), "kms unavailable") {
+		t.Errorf("expected the key failure to be reported, got %v", err)
+	}
+	for _, path := range segments(t, dir) {
+		if data, _ := os.ReadFile(path); len(data) > 0 {
+			t.Errorf("expected nothing written without a key, got %s", data)
+		}
+	}
+}
+
+func TestEncryptedAuditLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(&memorySink{}),
+		WithHashChain(HashChainOptions{LogPath: logPath}),
+		WithEncryptionAtRest(StaticKey(testKey)))
+	client.Track(NewEvent(EventToolCall, "audited"))
+	client.Close()
+
+	if _, err := ReadAuditLog(logPath, nil); !errors.Is(err, ErrEncryptionKey) {
+		t.Errorf("expected reading without the key to fail, got %v", err)
+	}
+	events, err := ReadAuditLog(logPath, testKey)
+	if err != nil || len(events) != 2 || events[0].Name != "audited" {
+		t.Fatalf("expected the event and its checkpoint, got %+v (%v)", events, err)
+	}
+	if err := VerifyChain(events); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,134 @@
+package trusera
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewClientFromEnv creates a client configured by environment variables, so
+// that orchestrators can inject configuration without code changes:
+//
+//	TRUSERA_API_KEY               API key (required)
+//	TRUSERA_AGENT_ID              WithAgentID
+//	TRUSERA_BASE_URL              WithBaseURL
+//	TRUSERA_REGION                WithRegion
+//	TRUSERA_PROJECT               WithProject
+//	TRUSERA_FLUSH_INTERVAL        WithFlushInterval, e.g. "10s"
+//	TRUSERA_BATCH_SIZE            WithMaxBatchSize
+//	TRUSERA_MAX_BATCH_BYTES       WithMaxBatchBytes
+//	TRUSERA_MAX_BATCH_AGE         WithMaxBatchAge
+//	TRUSERA_MAX_BUFFER_SIZE       WithMaxBufferSize
+//	TRUSERA_QUEUE_DIR             WithPersistentQueue
+//	TRUSERA_OFFLINE_DIR           WithOfflineMode
+//	TRUSERA_DEAD_LETTER_FILE      WithDeadLetterFile
+//	TRUSERA_ENCRYPTION_KEY        WithEncryptionAtRest(EnvKey(...))
+//	TRUSERA_POLICY_SYNC_INTERVAL  WithPolicySync
+//	TRUSERA_CLIENT_CERT           WithClientCertificate, with TRUSERA_CLIENT_KEY
+//	TRUSERA_STDOUT                WithStdoutSink when true
+//	TRUSERA_DRY_RUN               WithDryRun when true
+//
+// Unset or empty variables keep the defaults. opts are applied after the
+// variables and take precedence over them. Invalid values are reported
+// together rather than ignored.
+func NewClientFromEnv(opts ...Option) (*Client, error) {
+	apiKey := os.Getenv("TRUSERA_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("TRUSERA_API_KEY is not set")
+	}
+	envOpts, err := optionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(apiKey, append(envOpts, opts...)...), nil
+}
+
+// optionsFromEnv turns the TRUSERA_* environment variables into options
+func optionsFromEnv() ([]Option, error) {
+	var (
+		opts []Option
+		errs []error
+	)
+	str := func(name string, opt func(string) Option) {
+		if v := os.Getenv(name); v != "" {
+			opts = append(opts, opt(v))
+		}
+	}
+	duration := func(name string, opt func(time.Duration) Option) {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				errs = append(errs, fmt.Errorf("%s: invalid duration %q", name, v))
+				return
+			}
+			opts = append(opts, opt(d))
+		}
+	}
+	number := func(name string, opt func(int) Option) {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				errs = append(errs, fmt.Errorf("%s: invalid number %q", name, v))
+				return
+			}
+			opts = append(opts, opt(n))
+		}
+	}
+	flag := func(name string, opt func() Option) {
+		if v := os.Getenv(name); v != "" {
+			on, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid boolean %q", name, v))
+			} else if on {
+				opts = append(opts, opt())
+			}
+		}
+	}
+
+	str("TRUSERA_AGENT_ID", WithAgentID)
+	if v := os.Getenv("TRUSERA_REGION"); v != "" {
+		if _, ok := regionURLs[Region(strings.ToLower(v))]; !ok {
+			errs = append(errs, fmt.Errorf("TRUSERA_REGION: unknown region %q", v))
+		}
+		opts = append(opts, WithRegion(Region(v)))
+	}
+	str("TRUSERA_BASE_URL", WithBaseURL)
+	str("TRUSERA_PROJECT", WithProject)
+	duration("TRUSERA_FLUSH_INTERVAL", WithFlushInterval)
+	number("TRUSERA_BATCH_SIZE", WithMaxBatchSize)
+	number("TRUSERA_MAX_BATCH_BYTES", WithMaxBatchBytes)
+	duration("TRUSERA_MAX_BATCH_AGE", WithMaxBatchAge)
+	number("TRUSERA_MAX_BUFFER_SIZE", WithMaxBufferSize)
+	str("TRUSERA_QUEUE_DIR", WithPersistentQueue)
+	str("TRUSERA_OFFLINE_DIR", WithOfflineMode)
+	str("TRUSERA_DEAD_LETTER_FILE", WithDeadLetterFile)
+	if os.Getenv("TRUSERA_ENCRYPTION_KEY") != "" {
+		opts = append(opts, WithEncryptionAtRest(EnvKey("TRUSERA_ENCRYPTION_KEY")))
+	}
+	duration("TRUSERA_POLICY_SYNC_INTERVAL", WithPolicySync)
+
+	certFile, keyFile := os.Getenv("TRUSERA_CLIENT_CERT"), os.Getenv("TRUSERA_CLIENT_KEY")
+	switch {
+	case certFile != "" && keyFile != "":
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load client certificate: %w", err))
+		} else {
+			opts = append(opts, WithClientCertificate(cert))
+		}
+	case certFile != "" || keyFile != "":
+		errs = append(errs, errors.New("TRUSERA_CLIENT_CERT and TRUSERA_CLIENT_KEY must be set together"))
+	}
+
+	flag("TRUSERA_STDOUT", WithStdoutSink)
+	flag("TRUSERA_DRY_RUN", WithDryRun)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
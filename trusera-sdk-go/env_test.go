@@ -0,0 +1,91 @@
+package trusera
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv("TRUSERA_API_KEY", "env-key")
+	t.Setenv("TRUSERA_AGENT_ID", "agent-env")
+	t.Setenv("TRUSERA_REGION", "EU")
+	t.Setenv("TRUSERA_PROJECT", "platform")
+	t.Setenv("TRUSERA_FLUSH_INTERVAL", "1h")
+	t.Setenv("TRUSERA_BATCH_SIZE", "25")
+	t.Setenv("TRUSERA_MAX_BATCH_BYTES", "4096")
+	t.Setenv("TRUSERA_MAX_BUFFER_SIZE", "500")
+	t.Setenv("TRUSERA_QUEUE_DIR", t.TempDir())
+
+	client, err := NewClientFromEnv(WithAgentID("agent-code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if client.apiKey != "env-key" {
+		t.Errorf("expected the API key from the environment, got %q", client.apiKey)
+	}
+	if client.agentID != "agent-code" {
+		t.Errorf("expected explicit options to take precedence, got agent %q", client.agentID)
+	}
+	if got, _ := client.endpoint(""); got != "https://api.eu.trusera.io" {
+		t.Errorf("expected the EU region, got %s", got)
+	}
+	if client.project != "platform" || client.flushSize != 25 || client.maxBatchBytes != 4096 || client.maxBuffer != 500 {
+		t.Errorf("unexpected configuration: project %q, batch %d, bytes %d, buffer %d",
+			client.project, client.flushSize, client.maxBatchBytes, client.maxBuffer)
+	}
+	if client.queue == nil {
+		t.Error("expected a persistent queue")
+	}
+}
+
+func TestNewClientFromEnvBaseURLOverridesRegion(t *testing.T) {
+	t.Setenv("TRUSERA_API_KEY", "env-key")
+	t.Setenv("TRUSERA_REGION", "eu")
+	t.Setenv("TRUSERA_BASE_URL", "https://trusera.internal/")
+
+	client, err := NewClientFromEnv(WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if got, _ := client.endpoint(""); got != "https://trusera.internal" {
+		t.Errorf("expected the base URL, got %s", got)
+	}
+}
+
+func TestNewClientFromEnvRequiresAPIKey(t *testing.T) {
+	t.Setenv("TRUSERA_API_KEY", "")
+	if _, err := NewClientFromEnv(); err == nil || !strings.Contains(err.Error(), "TRUSERA_API_KEY") {
+		t.Errorf("expected a missing API key error, got %v", err)
+	}
+}
+
+func TestNewClientFromEnvInvalidValues(t *testing.T) {
+	t.Setenv("TRUSERA_API_KEY", "env-key")
+	t.Setenv("TRUSERA_FLUSH_INTERVAL", "30")
+	t.Setenv("TRUSERA_BATCH_SIZE", "many")
+	t.Setenv("TRUSERA_REGION", "mars")
+	t.Setenv("TRUSERA_CLIENT_CERT", "cert.pem")
+	t.Setenv("TRUSERA_STDOUT", "maybe")
+	t.Setenv("TRUSERA_DRY_RUN", "sure")
+
+	_, err := NewClientFromEnv()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{
+		`TRUSERA_FLUSH_INTERVAL: invalid duration "30"`,
+		`TRUSERA_BATCH_SIZE: invalid number "many"`,
+		`TRUSERA_REGION: unknown region "mars"`,
+		"TRUSERA_CLIENT_CERT and TRUSERA_CLIENT_KEY must be set together",
+		`TRUSERA_STDOUT: invalid boolean "maybe"`,
+		`TRUSERA_DRY_RUN: invalid boolean "sure"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -1,10 +1,6 @@
 package trusera
 
-import (
-	"crypto/rand"
-	"encoding/hex"
-	"time"
-)
+import "time"
 
 // EventType defines the type of agent event
 type EventType string
@@ -16,6 +12,18 @@ const (
 	EventAPICall    EventType = "api_call"
 	EventFileWrite  EventType = "file_write"
 	EventDecision   EventType = "decision"
+
+	EventSecretExposure  EventType = "secret_exposure"  // A credential was found in agent traffic
+	EventPromptInjection EventType = "prompt_injection" // A guardrail flagged an LLM input
+	EventPolicyFallback  EventType = "policy_fallback"  // The remote policy was unavailable; see WithPolicyCache
+	EventSession         EventType = "session"          // A Session started or ended
+	EventModelDrift      EventType = "model_drift"      // An LLM invocation used a model that was not registered; see RegisterModel
+	EventDatasetAccess   EventType = "dataset_access"   // A dataset or knowledge source was read; see RegisterDataset
+	EventChainCheckpoint EventType = "chain_checkpoint" // The head of a hash chain; see WithHashChain
+	EventConfigReload    EventType = "config_reload"    // A config file changed; see NewClientFromConfig
+	EventLifecycle       EventType = "lifecycle"        // The agent paused, resumed or deregistered
+	EventRateLimit       EventType = "rate_limit"       // A call exceeded a rule's rate limit; see RateLimit
+	EventPolicyDecision  EventType = "policy_decision"  // An interceptor allowed, warned about or blocked a target; see WithDecisionLog
 )
 
 // Event represents an agent action tracked by Trusera
@@ -26,13 +34,15 @@ type Event struct {
 	Payload   map[string]any `json:"payload"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
 	Timestamp string         `json:"timestamp"`
+	Monotonic int64          `json:"monotonic_ns,omitempty"` // Nanoseconds from the client's start to Track, on the monotonic clock
+	Stream    string         `json:"stream,omitempty"`       // See WithOrderedDelivery
+	Sequence  uint64         `json:"sequence,omitempty"`     // See WithOrderedDelivery
+	Signature *Signature     `json:"signature,omitempty"`    // See WithEventSigning
 }
 
 // generateID creates a random hex ID
 func generateID() string {
-	b := make([]byte, 16)
-	rand.Read(b)
-	return hex.EncodeToString(b)
+	return randomID()
 }
 
 // NewEvent creates a new event with generated ID and timestamp
@@ -43,7 +53,7 @@ func NewEvent(eventType EventType, name string) Event {
 		Name:      name,
 		Payload:   make(map[string]any),
 		Metadata:  make(map[string]any),
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: timestamp(time.Now()),
 	}
 }
 
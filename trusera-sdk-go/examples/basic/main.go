@@ -18,43 +18,40 @@ func main() {
 	defer client.Close()
 
 	// Track a tool call event
-	toolEvent := trusera.NewEvent(trusera.EventToolCall, "calculator").
-		WithPayload("operation", "multiply").
-		WithPayload("args", []int{5, 7}).
-		WithPayload("result", 35)
-
-	client.Track(toolEvent)
+	client.Track(trusera.ToolCall{
+		Name:   "calculator",
+		Args:   map[string]any{"operation": "multiply", "operands": []int{5, 7}},
+		Result: 35,
+	}.Event())
 	fmt.Println("Tracked tool call event")
 
 	// Track an LLM invocation
-	llmEvent := trusera.NewEvent(trusera.EventLLMInvoke, "gpt-4").
-		WithPayload("model", "gpt-4-turbo").
-		WithPayload("prompt_tokens", 150).
-		WithPayload("completion_tokens", 75).
-		WithPayload("total_cost", 0.0045).
-		WithMetadata("user_id", "user-456")
-
-	client.Track(llmEvent)
+	client.Track(trusera.LLMInvocation{
+		Model:            "gpt-4-turbo",
+		Provider:         "openai",
+		PromptTokens:     150,
+		CompletionTokens: 75,
+		Cost:             0.0045,
+	}.Event().WithMetadata("user_id", "user-456"))
 	fmt.Println("Tracked LLM invocation event")
 
 	// Track a data access event
-	dataEvent := trusera.NewEvent(trusera.EventDataAccess, "database_query").
-		WithPayload("query", "SELECT * FROM users WHERE role = 'admin'").
-		WithPayload("rows_returned", 3).
-		WithPayload("database", "postgres").
-		WithMetadata("sensitivity", "high")
-
-	client.Track(dataEvent)
+	client.Track(trusera.DataAccess{
+		Name:     "database_query",
+		Database: "postgres",
+		Query:    "SELECT * FROM users WHERE role = 'admin'",
+		Rows:     3,
+	}.Event().WithMetadata("sensitivity", "high"))
 	fmt.Println("Tracked data access event")
 
 	// Track a decision event
-	decisionEvent := trusera.NewEvent(trusera.EventDecision, "approve_purchase").
-		WithPayload("amount", 1500.00).
-		WithPayload("approved", true).
-		WithPayload("confidence", 0.95).
-		WithPayload("reasoning", "User has sufficient credit and good history")
-
-	client.Track(decisionEvent)
+	client.Track(trusera.Decision{
+		Name:       "approve_purchase",
+		Outcome:    "approved",
+		Confidence: 0.95,
+		Reasoning:  "User has sufficient credit and good history",
+		Inputs:     map[string]any{"amount": 1500.00},
+	}.Event())
 	fmt.Println("Tracked decision event")
 
 	// Manually flush events (optional - will auto-flush on interval or batch size)
@@ -0,0 +1,163 @@
+package trusera
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// grpcConn is satisfied by *grpc.ClientConn
+type grpcConn interface {
+	Target() string
+}
+
+// UnaryClientInterceptor returns a gRPC unary client interceptor that tracks
+// calls and applies the same log/warn/block enforcement as WrapHTTPClient.
+//
+// The SDK does not import grpc; instantiate it with grpc's types so that the
+// result is a grpc.UnaryClientInterceptor:
+//
+//	grpc.WithUnaryInterceptor(trusera.UnaryClientInterceptor[*grpc.ClientConn, grpc.UnaryInvoker](client, opts))
+//
+// Rules and exclude/block patterns are matched against "target/method" (for example
+// "dns:///payments.internal:443/payments.v1.Payments/Refund"). A request policy
+// sees the call as a POST to grpc://target/method.
+func UnaryClientInterceptor[CC grpcConn, I ~func(context.Context, string, any, any, CC, ...O) error, O any](
+	truseraClient *Client, opts InterceptorOptions,
+) func(context.Context, string, any, any, CC, I, ...O) error {
+	g := &grpcInterceptor{t: &interceptingTransport{client: truseraClient, opts: opts}}
+
+	return func(ctx context.Context, method string, req, reply any, cc CC, invoker I, callOpts ...O) error {
+		call, err := g.before(ctx, cc.Target(), method, false)
+		if err != nil {
+			return err
+		}
+
+		err = invoker(ctx, method, req, reply, cc, callOpts...)
+		if call != nil {
+			g.after(call, err)
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a gRPC stream client interceptor that tracks
+// stream establishment and enforces policy before the stream is opened.
+// Instantiate it with grpc's types so that the result is a grpc.StreamClientInterceptor:
+//
+//	grpc.WithStreamInterceptor(trusera.StreamClientInterceptor[*grpc.StreamDesc, *grpc.ClientConn, grpc.ClientStream, grpc.Streamer](client, opts))
+func StreamClientInterceptor[D any, CC grpcConn, S any, St ~func(context.Context, D, CC, string, ...O) (S, error), O any](
+	truseraClient *Client, opts InterceptorOptions,
+) func(context.Context, D, CC, string, St, ...O) (S, error) {
+	g := &grpcInterceptor{t: &interceptingTransport{client: truseraClient, opts: opts}}
+
+	return func(ctx context.Context, desc D, cc CC, method string, streamer St, callOpts ...O) (S, error) {
+		call, err := g.before(ctx, cc.Target(), method, true)
+		if err != nil {
+			var zero S
+			return zero, err
+		}
+
+		stream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if call != nil {
+			g.after(call, err)
+		}
+		return stream, err
+	}
+}
+
+// grpcInterceptor holds the enforcement logic shared by unary and stream interceptors
+type grpcInterceptor struct {
+	t *interceptingTransport
+}
+
+// grpcCall describes an in-flight call that should be reported when it completes
+type grpcCall struct {
+	target string
+	method string
+	stream bool
+	start  time.Time
+	ctx    context.Context
+}
+
+// before applies exclusion and enforcement. It returns a nil call when the
+// method is excluded and an error when the call is blocked.
+func (g *grpcInterceptor) before(ctx context.Context, target, method string, stream bool) (*grpcCall, error) {
+	v := g.t.evaluateRequest(http.MethodPost, target+method)
+	if v.excluded {
+		return nil, nil
+	}
+	v = g.t.canary(ctx, target+method, v)
+	v = g.t.limitCalls(ctx, target+method, v)
+
+	req := (&http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Scheme: "grpc", Host: grpcAuthority(target), Path: method},
+		Header: http.Header{},
+	}).WithContext(ctx)
+
+	blocked := v.blocked
+	policyReasons := g.t.policyReasons(req)
+	if len(policyReasons) > 0 {
+		blocked = true
+	}
+
+	event := NewEvent(EventAPICall, "grpc "+method).
+		WithPayload("protocol", "grpc").
+		WithPayload("target", target).
+		WithPayload("method", method).
+		WithPayload("stream", stream).
+		WithPayload("blocked", blocked)
+	event = g.t.client.correlate(ctx, v.describe(event))
+
+	if len(policyReasons) > 0 {
+		event = event.WithPayload("policy_reasons", policyReasons)
+	}
+
+	g.t.logDecision(ctx, "grpc", http.MethodPost, target+method, v, blocked, blockCause{BlockReasonPolicy, policyReasons})
+	if blocked {
+		event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+		switch v.mode {
+		case ModeBlock, ModeAllowList:
+			g.t.client.Track(event)
+			return nil, v.blockedError(target+method, blockCause{BlockReasonPolicy, policyReasons})
+		case ModeWarn:
+			event = event.WithMetadata("warning", "gRPC method matches block pattern but allowed in warn mode")
+		}
+	} else {
+		event = event.WithPayload("enforcement_action", "allowed")
+	}
+	g.t.client.Track(event)
+
+	return &grpcCall{target: target, method: method, stream: stream, start: time.Now(), ctx: ctx}, nil
+}
+
+// after records the outcome of a call
+func (g *grpcInterceptor) after(call *grpcCall, err error) {
+	event := NewEvent(EventAPICall, "response").
+		WithPayload("protocol", "grpc").
+		WithPayload("target", call.target).
+		WithPayload("method", call.method).
+		WithPayload("stream", call.stream).
+		WithPayload("duration_ms", float64(time.Since(call.start).Microseconds())/1000)
+
+	if err != nil {
+		event = event.WithPayload("error", err.Error())
+	}
+
+	g.t.client.Track(g.t.client.correlate(call.ctx, event))
+}
+
+// grpcAuthority strips resolver schemes such as "dns:///" from a dial target
+func grpcAuthority(target string) string {
+	if i := strings.Index(target, ":///"); i >= 0 {
+		return target[i+4:]
+	}
+	if i := strings.Index(target, "://"); i >= 0 {
+		return target[i+3:]
+	}
+	return target
+}
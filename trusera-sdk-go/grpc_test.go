@@ -0,0 +1,214 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Stand-ins mirroring the shapes of grpc.ClientConn, grpc.UnaryInvoker,
+// grpc.Streamer and friends, so the generic interceptors are exercised exactly
+// as they would be with the real grpc package.
+type fakeClientConn struct{ target string }
+
+func (c *fakeClientConn) Target() string { return c.target }
+
+type fakeCallOption interface{}
+
+type fakeUnaryInvoker func(ctx context.Context, method string, req, reply any, cc *fakeClientConn, opts ...fakeCallOption) error
+
+type fakeUnaryClientInterceptor func(ctx context.Context, method string, req, reply any, cc *fakeClientConn, invoker fakeUnaryInvoker, opts ...fakeCallOption) error
+
+type fakeStreamDesc struct{ name string }
+
+type fakeClientStream interface{ Method() string }
+
+type fakeStream struct{ method string }
+
+func (s *fakeStream) Method() string { return s.method }
+
+type fakeStreamer func(ctx context.Context, desc *fakeStreamDesc, cc *fakeClientConn, method string, opts ...fakeCallOption) (fakeClientStream, error)
+
+type fakeStreamClientInterceptor func(ctx context.Context, desc *fakeStreamDesc, cc *fakeClientConn, method string, streamer fakeStreamer, opts ...fakeCallOption) (fakeClientStream, error)
+
+func countEvents(c *Client) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.events)
+}
+
+func TestUnaryClientInterceptorAssignable(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	// Must compile: the instantiated interceptor has grpc's exact signature
+	var interceptor fakeUnaryClientInterceptor = UnaryClientInterceptor[*fakeClientConn, fakeUnaryInvoker](client, InterceptorOptions{})
+
+	invoked := false
+	invoker := func(ctx context.Context, method string, req, reply any, cc *fakeClientConn, opts ...fakeCallOption) error {
+		invoked = true
+		return nil
+	}
+
+	cc := &fakeClientConn{target: "dns:///api.example.com:443"}
+	if err := interceptor(context.Background(), "/search.v1.Search/Query", nil, nil, cc, invoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !invoked {
+		t.Error("expected invoker to be called")
+	}
+
+	if got := countEvents(client); got != 2 {
+		t.Errorf("expected request and response events, got %d", got)
+	}
+}
+
+func TestUnaryClientInterceptorEnforcement(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        EnforcementMode
+		wantErr     bool
+		wantInvoked bool
+	}{
+		{"block mode rejects", ModeBlock, true, false},
+		{"warn mode allows", ModeWarn, false, true},
+		{"log mode allows", ModeLog, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("test-key")
+			defer client.Close()
+
+			interceptor := UnaryClientInterceptor[*fakeClientConn, fakeUnaryInvoker](client, InterceptorOptions{
+				Enforcement:   tt.mode,
+				BlockPatterns: []string{"Payments/Refund"},
+			})
+
+			invoked := false
+			invoker := fakeUnaryInvoker(func(ctx context.Context, method string, req, reply any, cc *fakeClientConn, opts ...fakeCallOption) error {
+				invoked = true
+				return nil
+			})
+
+			cc := &fakeClientConn{target: "payments.internal:443"}
+			err := interceptor(context.Background(), "/payments.v1.Payments/Refund", nil, nil, cc, invoker)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if invoked != tt.wantInvoked {
+				t.Errorf("invoked = %v, want %v", invoked, tt.wantInvoked)
+			}
+		})
+	}
+}
+
+func TestUnaryClientInterceptorExcludeAndErrors(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	interceptor := UnaryClientInterceptor[*fakeClientConn, fakeUnaryInvoker](client, InterceptorOptions{
+		ExcludePatterns: []string{"grpc.health.v1"},
+	})
+
+	cc := &fakeClientConn{target: "localhost:50051"}
+	ok := fakeUnaryInvoker(func(context.Context, string, any, any, *fakeClientConn, ...fakeCallOption) error { return nil })
+
+	if err := interceptor(context.Background(), "/grpc.health.v1.Health/Check", nil, nil, cc, ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := countEvents(client); got != 0 {
+		t.Errorf("expected excluded method to produce no events, got %d", got)
+	}
+
+	failing := fakeUnaryInvoker(func(context.Context, string, any, any, *fakeClientConn, ...fakeCallOption) error {
+		return errors.New("unavailable")
+	})
+
+	if err := interceptor(context.Background(), "/svc.v1.Svc/Call", nil, nil, cc, failing); err == nil {
+		t.Fatal("expected invoker error to be returned")
+	}
+
+	client.mu.Lock()
+	last := client.events[len(client.events)-1]
+	client.mu.Unlock()
+
+	if last.Payload["error"] != "unavailable" {
+		t.Errorf("expected error recorded on response event, got %v", last.Payload["error"])
+	}
+}
+
+func TestUnaryClientInterceptorPolicy(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	policy, err := NewCELPolicy(CELRule{
+		Action:     ActionForbid,
+		Expression: `request.scheme == "grpc" && request.path.endsWith("/Delete")`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interceptor := UnaryClientInterceptor[*fakeClientConn, fakeUnaryInvoker](client, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Policy:      policy,
+	})
+
+	cc := &fakeClientConn{target: "db.internal:443"}
+	invoker := fakeUnaryInvoker(func(context.Context, string, any, any, *fakeClientConn, ...fakeCallOption) error { return nil })
+
+	if err := interceptor(context.Background(), "/db.v1.Records/Delete", nil, nil, cc, invoker); err == nil {
+		t.Error("expected policy to block Delete")
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	var interceptor fakeStreamClientInterceptor = StreamClientInterceptor[*fakeStreamDesc, *fakeClientConn, fakeClientStream, fakeStreamer](client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"/Exfiltrate"},
+	})
+
+	streamer := fakeStreamer(func(ctx context.Context, desc *fakeStreamDesc, cc *fakeClientConn, method string, opts ...fakeCallOption) (fakeClientStream, error) {
+		return &fakeStream{method: method}, nil
+	})
+
+	cc := &fakeClientConn{target: "llm.internal:443"}
+	stream, err := interceptor(context.Background(), &fakeStreamDesc{name: "Generate"}, cc, "/llm.v1.LLM/Generate", streamer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if stream.Method() != "/llm.v1.LLM/Generate" {
+		t.Errorf("expected underlying stream to be returned, got %s", stream.Method())
+	}
+
+	stream, err = interceptor(context.Background(), &fakeStreamDesc{}, cc, "/llm.v1.LLM/Exfiltrate", streamer)
+	if err == nil {
+		t.Error("expected blocked stream")
+	}
+
+	if stream != nil {
+		t.Error("expected nil stream when blocked")
+	}
+}
+
+func TestGRPCAuthority(t *testing.T) {
+	tests := map[string]string{
+		"dns:///api.example.com:443": "api.example.com:443",
+		"localhost:50051":            "localhost:50051",
+	}
+
+	for target, want := range tests {
+		if got := grpcAuthority(target); got != want {
+			t.Errorf("grpcAuthority(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
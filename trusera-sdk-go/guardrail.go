@@ -0,0 +1,205 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrGuardrailBlocked is wrapped by the error returned when a guardrail
+// flags an LLM input in block mode
+var ErrGuardrailBlocked = errors.New("request blocked by Trusera guardrail")
+
+// GuardrailInput is the LLM input a guardrail inspects
+type GuardrailInput struct {
+	Provider string // e.g. "openai", "anthropic" or the request host
+	Model    string
+	Text     string // User, assistant and tool content; see PromptText
+}
+
+// GuardrailResult is a guardrail's verdict on an input
+type GuardrailResult struct {
+	Score   float64  // Likelihood of an attack, from 0 to 1
+	Flagged bool     // Whether the input should be treated as an attack
+	Reasons []string // What triggered the verdict
+}
+
+// Guardrail inspects LLM inputs before they are sent. Implementations can
+// wrap a classifier model or a hosted detection API; NewPromptInjectionGuard
+// provides a local heuristic.
+type Guardrail interface {
+	Name() string
+	Check(ctx context.Context, in GuardrailInput) (GuardrailResult, error)
+}
+
+// injectionRule is a weighted phrase typical of prompt injection
+type injectionRule struct {
+	reason string
+	weight float64
+	re     *regexp.Regexp
+}
+
+var injectionRules = []injectionRule{
+	{"ignore previous instructions", 0.6, regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+|my\s+)?(previous|prior|above|earlier|preceding|system)\s+(instructions|prompts?|rules|directions|context)`)},
+	{"system prompt extraction", 0.6, regexp.MustCompile(`(?i)\b(reveal|show|print|repeat|output|leak|tell me)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions|initial\s+instructions|instructions\s+above)`)},
+	{"persona override", 0.5, regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(DAN|in\s+developer\s+mode|unrestricted|jailbroken|an?\s+unfiltered)`)},
+	{"jailbreak mode", 0.4, regexp.MustCompile(`(?i)\b(developer|god|jailbreak|DAN)\s+mode\b|\bdo\s+anything\s+now\b`)},
+	{"safety override", 0.5, regexp.MustCompile(`(?i)\b(bypass|override|disable|ignore)\s+(your\s+|the\s+|all\s+)?(safety|content)\s+(guidelines|filters|rules|policies)`)},
+	{"injected instructions", 0.3, regexp.MustCompile(`(?i)(^|\n)\s*(new|updated|real)\s+instructions\s*:`)},
+	{"fake system message", 0.3, regexp.MustCompile(`(?i)</?\s*(system|instructions)\s*>|\[\s*system\s*\]|<\|im_start\|>\s*system`)},
+}
+
+// promptInjectionGuard scores text against injectionRules
+type promptInjectionGuard struct {
+	threshold float64
+}
+
+// NewPromptInjectionGuard returns a heuristic guardrail that flags inputs
+// containing common prompt injection phrasing, such as instructions to
+// ignore previous instructions or to reveal the system prompt. Each matched
+// rule raises the score; inputs scoring at least threshold (0.5 when zero)
+// are flagged.
+func NewPromptInjectionGuard(threshold float64) Guardrail {
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+	return &promptInjectionGuard{threshold: threshold}
+}
+
+func (g *promptInjectionGuard) Name() string { return "prompt_injection_heuristic" }
+
+func (g *promptInjectionGuard) Check(_ context.Context, in GuardrailInput) (GuardrailResult, error) {
+	clean := 1.0
+	var reasons []string
+	for _, rule := range injectionRules {
+		if rule.re.MatchString(in.Text) {
+			clean *= 1 - rule.weight
+			reasons = append(reasons, rule.reason)
+		}
+	}
+	score := 1 - clean
+	return GuardrailResult{Score: score, Flagged: score >= g.threshold, Reasons: reasons}, nil
+}
+
+// Guard runs guardrails against an LLM input. Each flagged verdict is
+// tracked as an EventPromptInjection. In ModeBlock and ModeAllowList a flagged
+// input returns an error wrapping ErrGuardrailBlocked and the call should not
+// be made; in other modes Guard only records. Guardrails that fail are skipped, so an
+// unavailable classifier never blocks traffic.
+func (c *Client) Guard(ctx context.Context, guardrails []Guardrail, mode EnforcementMode, in GuardrailInput) error {
+	reasons := c.runGuardrails(ctx, guardrails, mode, in)
+	if len(reasons) > 0 && mode.rejects() {
+		return fmt.Errorf("%w: %s", ErrGuardrailBlocked, strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// runGuardrails checks an input and tracks a security event per flagged
+// verdict, returning the reasons of all flagged verdicts
+func (c *Client) runGuardrails(ctx context.Context, guardrails []Guardrail, mode EnforcementMode, in GuardrailInput) []string {
+	if in.Text == "" {
+		return nil
+	}
+
+	var flagged []string
+	for _, g := range guardrails {
+		result, err := g.Check(ctx, in)
+		if err != nil || !result.Flagged {
+			continue
+		}
+
+		action := "flagged"
+		if mode.rejects() {
+			action = "blocked"
+		}
+		event := NewEvent(EventPromptInjection, g.Name()).
+			WithPayload("guardrail", g.Name()).
+			WithPayload("score", result.Score).
+			WithPayload("reasons", result.Reasons).
+			WithPayload("provider", in.Provider).
+			WithPayload("action", action).
+			WithMetadata("enforcement_mode", string(mode))
+		if in.Model != "" {
+			event = event.WithPayload("model", in.Model)
+		}
+		if mode == ModeWarn {
+			event = event.WithMetadata("warning", "prompt injection suspected but allowed in warn mode")
+		}
+		c.Track(c.enrich(ctx, event))
+
+		if len(result.Reasons) == 0 {
+			flagged = append(flagged, g.Name())
+		}
+		for _, r := range result.Reasons {
+			flagged = append(flagged, g.Name()+": "+r)
+		}
+	}
+	return flagged
+}
+
+// PromptText extracts the text of an OpenAI- or Anthropic-style request body
+// for guardrails: message contents (including tool results), prompt and
+// input. System prompts are skipped since they come from the developer, not
+// from untrusted input.
+func PromptText(body []byte) string {
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Prompt json.RawMessage `json:"prompt"`
+		Input  json.RawMessage `json:"input"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, m := range req.Messages {
+		if m.Role == "system" || m.Role == "developer" {
+			continue
+		}
+		parts = appendText(parts, m.Content)
+	}
+	parts = appendText(parts, req.Prompt)
+	parts = appendText(parts, req.Input)
+	return strings.Join(parts, "\n")
+}
+
+// appendText collects the strings in a content value: a plain string, a list
+// of strings, or content blocks carrying text or nested content
+func appendText(parts []string, raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return parts
+	}
+
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return parts
+	}
+	var walk func(any)
+	walk = func(v any) {
+		switch val := v.(type) {
+		case string:
+			if val != "" {
+				parts = append(parts, val)
+			}
+		case []any:
+			for _, item := range val {
+				walk(item)
+			}
+		case map[string]any:
+			if t, ok := val["text"].(string); ok && t != "" {
+				parts = append(parts, t)
+			}
+			if c, ok := val["content"]; ok {
+				walk(c)
+			}
+		}
+	}
+	walk(v)
+	return parts
+}
@@ -0,0 +1,82 @@
+package trusera
+
+import "log/slog"
+
+// DropReason says why an event was discarded. The values match the reason
+// label of trusera_events_dropped_total.
+type DropReason string
+
+const (
+	DropReasonFlushError  DropReason = "flush_error"  // The flush failed and the event could not be kept
+	DropReasonOverflow    DropReason = "overflow"     // The buffer or disk queue was full; see WithOverflowPolicy and WithMaxQueueBytes
+	DropReasonHook        DropReason = "hook"         // An event hook dropped or vetoed the event
+	DropReasonSampled     DropReason = "sampled"      // The sampler rejected the event
+	DropReasonPaused      DropReason = "paused"       // The control plane paused emission
+	DropReasonSchema      DropReason = "schema"       // The payload failed a SchemaReject schema
+	DropReasonDuplicate   DropReason = "duplicate"    // An identical event was tracked recently; see WithDeduplication
+	DropReasonRateLimited DropReason = "rate_limited" // The event exceeded WithMaxEventsPerSecond
+)
+
+// WithErrorHandler calls h with errors that have no caller to return to:
+// failed background flushes, policy syncs and config reloads, and events
+// that Track could not sign or chain. h is called synchronously from the
+// SDK's goroutines, so it must be quick and must not call the client.
+func WithErrorHandler(h func(error)) Option {
+	return func(c *Client) {
+		c.errorHandler = h
+	}
+}
+
+// WithDropHandler calls h with every event the client discards and why,
+// e.g. to log or count them. h may be called with the client's lock held,
+// so it must be quick and must not call the client.
+func WithDropHandler(h func(Event, DropReason)) Option {
+	return func(c *Client) {
+		c.dropHandler = h
+	}
+}
+
+// handleError passes a non-nil error to the error handler
+func (c *Client) handleError(err error) {
+	if err == nil {
+		return
+	}
+	c.log(slog.LevelWarn, "background operation failed", "error", err)
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+	}
+}
+
+// drop counts discarded events and passes them to the drop handler
+func (c *Client) drop(reason DropReason, events ...Event) {
+	switch reason {
+	case DropReasonFlushError:
+		c.metrics.observeDropped(len(events))
+	case DropReasonOverflow:
+		c.metrics.observeOverflow(len(events))
+	case DropReasonHook:
+		c.metrics.observeHookDrop(len(events))
+	case DropReasonSampled:
+		c.metrics.observeSampledOut(len(events))
+	case DropReasonPaused:
+		c.metrics.observePaused(len(events))
+	case DropReasonSchema:
+		c.metrics.observeInvalid(len(events))
+	case DropReasonDuplicate:
+		c.metrics.observeDuplicate(len(events))
+	case DropReasonRateLimited:
+		c.metrics.observeRateLimited(len(events))
+	}
+	// The log arguments allocate, so they are only built for a logger
+	if c.logger != nil && len(events) == 1 {
+		c.log(dropLevel(reason), "event dropped", "reason", string(reason),
+			"event_type", string(events[0].Type), "event_name", events[0].Name)
+	} else if c.logger != nil && len(events) > 1 {
+		c.log(dropLevel(reason), "events dropped", "reason", string(reason), "count", len(events))
+	}
+	if c.dropHandler != nil {
+		for _, e := range events {
+			c.dropHandler(e, reason)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package trusera
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDropHandler(t *testing.T) {
+	var mu sync.Mutex
+	dropped := map[string]DropReason{}
+	sink := &memorySink{}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(sink),
+		WithMaxBufferSize(2),
+		WithOverflowPolicy(DropNewest),
+		WithEventHook(func(e *Event) (*Event, error) {
+			if e.Name == "vetoed" {
+				return nil, errors.New("vetoed")
+			}
+			return e, nil
+		}),
+		WithSampler(SamplerFunc(func(e Event) bool { return e.Name != "sampled" })),
+		WithDropHandler(func(e Event, reason DropReason) {
+			mu.Lock()
+			defer mu.Unlock()
+			dropped[e.Name] = reason
+		}),
+	)
+	defer client.Close()
+
+	for _, name := range []string{"vetoed", "sampled", "kept-1", "kept-2", "overflow"} {
+		client.Track(NewEvent(EventToolCall, name))
+	}
+	sink.mu.Lock()
+	sink.fail = errors.New("unavailable")
+	sink.mu.Unlock()
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]DropReason{
+		"vetoed":   DropReasonHook,
+		"sampled":  DropReasonSampled,
+		"overflow": DropReasonOverflow,
+		"kept-1":   DropReasonFlushError,
+		"kept-2":   DropReasonFlushError,
+	}
+	for name, reason := range want {
+		if dropped[name] != reason {
+			t.Errorf("%s: expected reason %q, got %q", name, reason, dropped[name])
+		}
+	}
+	if stats := client.Stats(); stats.EventsDropped != 5 {
+		t.Errorf("expected the metrics to still count drops, got %+v", stats)
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	errs := make(chan error, 10)
+	client := NewClient("test-key",
+		WithFlushInterval(10*time.Millisecond),
+		WithPrimarySink(&memorySink{fail: errors.New("unavailable")}),
+		WithErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
+	)
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	select {
+	case err := <-errs:
+		if err.Error() != "unavailable" {
+			t.Errorf("expected the background flush error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the error handler to be called")
+	}
+}
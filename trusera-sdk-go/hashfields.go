@@ -0,0 +1,70 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// WithHashedFields replaces the values of the given top-level payload keys
+// with "sha256:<hex>", the SHA-256 of the value, before the event is
+// buffered, so content that must not leave the process, such as prompts
+// holding customer data, can still be correlated and checked for integrity.
+// Strings are hashed as they are, as by WithTruncation; other values are
+// hashed over their JSON encoding. Hashed events get metadata hashed_fields
+// listing the keys. Hashing runs ahead of every hook, including redaction.
+//
+// A hash does not hide values that are easy to guess, such as email
+// addresses or short answers; use WithRedaction for those.
+func WithHashedFields(keys ...string) Option {
+	return func(c *Client) {
+		hashed := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			hashed[k] = true
+		}
+		c.trackHooks = append([]EventHook{func(e *Event) (*Event, error) {
+			hashedEvent := hashFields(*e, hashed)
+			return &hashedEvent, nil
+		}}, c.trackHooks...)
+	}
+}
+
+// hashFields hashes the payload values under the given keys
+func hashFields(e Event, keys map[string]bool) Event {
+	var hashed []string
+	for k := range e.Payload {
+		if keys[k] {
+			hashed = append(hashed, k)
+		}
+	}
+	if len(hashed) == 0 {
+		return e
+	}
+
+	payload := make(map[string]any, len(e.Payload))
+	for k, v := range e.Payload {
+		if keys[k] {
+			v = hashValue(v)
+		}
+		payload[k] = v
+	}
+	e.Payload = payload
+	sort.Strings(hashed)
+	return e.WithMetadata("hashed_fields", hashed)
+}
+
+// hashValue returns "sha256:<hex>" of a value
+func hashValue(v any) string {
+	data, ok := v.(string)
+	if !ok {
+		if encoded, err := json.Marshal(v); err == nil {
+			data = string(encoded)
+		} else {
+			data = fmt.Sprint(v)
+		}
+	}
+	sum := sha256.Sum256([]byte(data))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,53 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithHashedFields(t *testing.T) {
+	var seen string
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithEventHook(func(e *Event) (*Event, error) {
+			seen, _ = e.Payload["prompt"].(string)
+			return e, nil
+		}),
+		WithHashedFields("prompt", "messages"))
+	defer client.Close()
+
+	prompt := "Customer 4411 asks about their refund"
+	client.Track(NewEvent(EventLLMInvoke, "chat").
+		WithPayload("prompt", prompt).
+		WithPayload("messages", []string{"a", "b"}).
+		WithPayload("model", "gpt-4o"))
+
+	e, _ := trackedEvent(client, "chat")
+	sum := sha256.Sum256([]byte(prompt))
+	want := "sha256:" + hex.EncodeToString(sum[:])
+	if e.Payload["prompt"] != want || seen != want {
+		t.Errorf("expected the prompt hashed before any hook, got %v (hook saw %q)", e.Payload["prompt"], seen)
+	}
+	messages := sha256.Sum256([]byte(`["a","b"]`))
+	if e.Payload["messages"] != "sha256:"+hex.EncodeToString(messages[:]) {
+		t.Errorf("expected other values hashed over their JSON, got %v", e.Payload["messages"])
+	}
+	if e.Payload["model"] != "gpt-4o" {
+		t.Errorf("expected other fields to be kept, got %v", e.Payload["model"])
+	}
+	if !reflect.DeepEqual(e.Metadata["hashed_fields"], []string{"messages", "prompt"}) {
+		t.Errorf("expected the hashed keys in the metadata, got %v", e.Metadata)
+	}
+}
+
+func TestHashedFieldsMatchTruncationHash(t *testing.T) {
+	long := strings.Repeat("z", 100)
+	hashed := hashValue(long)
+	truncated := truncateString(long, 10)
+	if !strings.HasSuffix(truncated, strings.TrimPrefix(hashed, "sha256:")) {
+		t.Errorf("expected the same hash from both, got %q and %q", hashed, truncated)
+	}
+}
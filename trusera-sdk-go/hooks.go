@@ -0,0 +1,78 @@
+package trusera
+
+import (
+	"errors"
+)
+
+// ErrEventDropped is returned by TrackSync when a hook dropped the event
+var ErrEventDropped = errors.New("trusera: event dropped by hook")
+
+// EventHook inspects or rewrites an event. Returning a nil event drops it
+// silently; returning an error vetoes it, and the error is reported to the
+// caller where there is one (TrackCtx, TrackSync, Flush).
+type EventHook func(*Event) (*Event, error)
+
+// WithEventHook adds a hook that runs on every event before it is buffered,
+// in the order hooks were added and before the local policy is evaluated.
+// Use it to enrich, redact, drop or veto events in one place rather than at
+// every call site.
+func WithEventHook(hook EventHook) Option {
+	return func(c *Client) {
+		c.trackHooks = append(c.trackHooks, hook)
+	}
+}
+
+// WithSendHook adds a hook that runs on every event right before it is sent,
+// including events read back from a persistent queue or spill directory
+func WithSendHook(hook EventHook) Option {
+	return func(c *Client) {
+		c.sendHooks = append(c.sendHooks, hook)
+	}
+}
+
+// runHooks passes an event through a hook chain. It returns false when a
+// hook dropped or vetoed the event.
+func runHooks(hooks []EventHook, event Event) (Event, bool, error) {
+	if len(hooks) == 0 {
+		return event, true, nil
+	}
+	return runHookChain(hooks, event)
+}
+
+// runHookChain runs hooks in order. It is split from runHooks so that the
+// event only moves to the heap when there are hooks to run.
+func runHookChain(hooks []EventHook, event Event) (Event, bool, error) {
+	e := &event
+	for _, hook := range hooks {
+		next, err := hook(e)
+		if err != nil {
+			return event, false, err
+		}
+		if next == nil {
+			return event, false, nil
+		}
+		e = next
+	}
+	return *e, true, nil
+}
+
+// beforeSend applies the send hooks to a batch, returning the events that
+// remain and any veto errors
+func (c *Client) beforeSend(events []Event) ([]Event, error) {
+	if len(c.sendHooks) == 0 {
+		return events, nil
+	}
+
+	var errs error
+	kept := make([]Event, 0, len(events))
+	for _, event := range events {
+		e, ok, err := runHooks(c.sendHooks, event)
+		if !ok {
+			c.drop(DropReasonHook, event)
+			errs = errors.Join(errs, err)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	return kept, errs
+}
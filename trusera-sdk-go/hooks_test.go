@@ -0,0 +1,118 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEventHookChain(t *testing.T) {
+	errSecret := errors.New("secret tool")
+
+	client := NewClient("test-key",
+		WithEventHook(func(e *Event) (*Event, error) {
+			enriched := e.WithMetadata("team", "payments")
+			return &enriched, nil
+		}),
+		WithEventHook(func(e *Event) (*Event, error) {
+			switch {
+			case e.Name == "noise":
+				return nil, nil
+			case strings.HasPrefix(e.Name, "secret"):
+				return nil, errSecret
+			}
+			return e, nil
+		}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	client.Track(NewEvent(EventToolCall, "noise"))
+	if err := client.TrackCtx(context.Background(), NewEvent(EventToolCall, "secret_export")); !errors.Is(err, errSecret) {
+		t.Errorf("expected the veto error from TrackCtx, got %v", err)
+	}
+
+	client.mu.Lock()
+	events := append([]Event(nil), client.events...)
+	client.mu.Unlock()
+
+	if len(events) != 1 || events[0].Name != "search" || events[0].Metadata["team"] != "payments" {
+		t.Errorf("expected only the enriched search event, got %v", events)
+	}
+
+	stats := client.Stats()
+	if stats.EventsDroppedHook != 2 || stats.EventsTracked[EventToolCall] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestEventHookRunsBeforePolicy(t *testing.T) {
+	policy, err := NewCELPolicy(CELRule{
+		ID:         "no-blocked",
+		Action:     ActionForbid,
+		Expression: `event.name == "blocked"`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+	client := NewClient("test-key", WithPolicy(policy), WithEventHook(func(e *Event) (*Event, error) {
+		renamed := *e
+		renamed.Name = "blocked"
+		return &renamed, nil
+	}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "harmless"))
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if client.events[0].Metadata["policy_decision"] != "Deny" {
+		t.Errorf("expected the policy to see the hooked event, got %v", client.events[0].Metadata)
+	}
+}
+
+func TestSendHook(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Events []Event `json:"events"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received = append(received, payload.Events...)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithSendHook(func(e *Event) (*Event, error) {
+		if e.Name == "drop-me" {
+			return nil, errors.New("vetoed at send")
+		}
+		return e, nil
+	}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "keep"))
+	client.Track(NewEvent(EventToolCall, "drop-me"))
+
+	if err := client.Flush(); err == nil || !strings.Contains(err.Error(), "vetoed at send") {
+		t.Errorf("expected the send hook veto to be reported, got %v", err)
+	}
+	if len(received) != 1 || received[0].Name != "keep" {
+		t.Errorf("expected only the kept event to be sent, got %v", received)
+	}
+
+	if _, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "drop-me")); err == nil {
+		t.Error("expected TrackSync to report the veto")
+	}
+}
+
+func TestTrackSyncDropped(t *testing.T) {
+	client := NewClient("test-key", WithEventHook(func(*Event) (*Event, error) { return nil, nil }))
+	defer client.Close()
+
+	if _, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "x")); !errors.Is(err, ErrEventDropped) {
+		t.Errorf("expected ErrEventDropped, got %v", err)
+	}
+}
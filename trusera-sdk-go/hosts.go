@@ -0,0 +1,89 @@
+package trusera
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostPolicy is the enforcement for the requests to one host, as set in
+// InterceptorOptions.Hosts, so one interceptor can block internal hosts,
+// warn on unknown services and fully allow its own APIs
+type HostPolicy struct {
+	// Action is applied to every request to the host that its Rules do not
+	// match, ahead of the interceptor's own rules and patterns. When empty
+	// such requests fall through to them.
+	Action RuleAction `json:"action,omitempty"`
+
+	// Enforcement replaces the interceptor's mode for the host; empty keeps it
+	Enforcement EnforcementMode `json:"enforcement,omitempty"`
+
+	Rules    []Rule `json:"rules,omitempty"`    // Checked before the interceptor's rules
+	Reason   string `json:"reason,omitempty"`   // Reported as rule_reason for the Action
+	Severity string `json:"severity,omitempty"` // Reported as severity for the Action
+
+	// TLS, if set, is what the host's HTTPS connections are expected to
+	// look like, such as pinned keys; see TLSPolicy
+	TLS *TLSPolicy `json:"tls,omitempty"`
+}
+
+// hostPolicy returns the policy for a host and the key it is set under:
+// the host itself, else the longest "*." wildcard matching it, else "*"
+func (o InterceptorOptions) hostPolicy(host string) (string, HostPolicy, bool) {
+	if len(o.Hosts) == 0 || host == "" {
+		return "", HostPolicy{}, false
+	}
+	var key string
+	for k := range o.Hosts {
+		pattern := strings.ToLower(k)
+		switch {
+		case pattern == host:
+			return k, o.Hosts[k], true
+		case strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]):
+			if len(k) > len(key) {
+				key = k
+			}
+		case pattern == "*" && key == "":
+			key = k
+		}
+	}
+	if key == "" {
+		return "", HostPolicy{}, false
+	}
+	return key, o.Hosts[key], true
+}
+
+// forHost applies the policy of the host a target is on, if any. Its rules
+// are checked after the first remoteRules rules, which come from the remote
+// policy, and before the interceptor's own.
+func (o InterceptorOptions) forHost(target string, remoteRules int) InterceptorOptions {
+	name, _ := targetAddr(target)
+	key, hp, ok := o.hostPolicy(name)
+	if !ok {
+		return o
+	}
+	if hp.Enforcement != "" {
+		o.Enforcement = hp.Enforcement
+	}
+
+	// Name the rules by their index before inserting the host's, so their
+	// IDs do not depend on the host
+	rules := make([]Rule, 0, len(o.Rules))
+	for i, rule := range o.Rules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i)
+		}
+		rules = append(rules, rule)
+	}
+	host := make([]Rule, 0, len(hp.Rules)+1)
+	for i, rule := range hp.Rules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("host:%s/rule-%d", key, i)
+		}
+		host = append(host, rule)
+	}
+	if hp.Action != "" {
+		host = append(host, Rule{ID: "host:" + key, Action: hp.Action, Reason: hp.Reason, Severity: hp.Severity})
+	}
+	o.Rules = append(append(rules[:remoteRules:remoteRules], host...), rules[remoteRules:]...)
+	return o
+}
@@ -0,0 +1,71 @@
+package trusera
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostPolicies(t *testing.T) {
+	transport := &interceptingTransport{opts: InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules: []Rule{
+			{Match: "/healthz", Action: RuleExclude},
+		},
+		Hosts: map[string]HostPolicy{
+			"*.internal":         {Action: RuleBlock, Reason: "internal network", Severity: "high"},
+			"*.vault.internal":   {Action: RuleExclude},
+			"api.example.com":    {Action: RuleAllow},
+			"files.example.com":  {Enforcement: ModeBlock, Rules: []Rule{{Match: "/upload", Action: RuleBlock}}},
+			"*":                  {Action: RuleWarn},
+			"Mixed.Example.COM":  {Action: RuleLog},
+			"no-action.test.com": {Enforcement: ModeAllowList},
+		},
+		AllowPatterns: []string{"no-action.test.com/ok"},
+	}}
+
+	tests := []struct {
+		target   string
+		ruleID   string
+		mode     EnforcementMode
+		blocked  bool
+		excluded bool
+	}{
+		{"https://db.internal/query", "host:*.internal", ModeBlock, true, false},
+		{"grpc://a.b.internal:443/pkg.Svc/Call", "host:*.internal", ModeBlock, true, false},
+		{"https://secrets.vault.internal/v1", "host:*.vault.internal", ModeLog, false, true},
+		{"https://api.example.com/v1/users", "host:api.example.com", ModeLog, false, false},
+		{"https://api.example.com/healthz", "host:api.example.com", ModeLog, false, false},
+		{"https://files.example.com/upload", "host:files.example.com/rule-0", ModeBlock, true, false},
+		{"https://files.example.com/healthz", "rule-0", ModeBlock, false, true},
+		{"https://files.example.com/download", "", ModeBlock, false, false},
+		{"https://saas.io/api", "host:*", ModeWarn, true, false},
+		{"https://mixed.example.com", "host:Mixed.Example.COM", ModeLog, true, false},
+		{"https://no-action.test.com/ok", "", ModeAllowList, false, false},
+		{"https://no-action.test.com/other", "", ModeAllowList, true, false},
+		{"SELECT * FROM users", "", ModeLog, false, false},
+	}
+	for _, tt := range tests {
+		v := transport.evaluate(tt.target)
+		if v.ruleID != tt.ruleID || v.mode != tt.mode || v.blocked != tt.blocked || v.excluded != tt.excluded {
+			t.Errorf("%s: got rule %q mode %s blocked %v excluded %v, want rule %q mode %s blocked %v excluded %v",
+				tt.target, v.ruleID, v.mode, v.blocked, v.excluded, tt.ruleID, tt.mode, tt.blocked, tt.excluded)
+		}
+	}
+}
+
+func TestHostPoliciesAfterRemoteRules(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+	client.remotePolicy.Store(&RemotePolicy{Rules: []Rule{{ID: "remote", Match: "/admin", Action: RuleBlock}}})
+
+	transport := &interceptingTransport{client: client, opts: InterceptorOptions{
+		Rules: []Rule{{Match: "/public", Action: RuleAllow}},
+		Hosts: map[string]HostPolicy{"api.example.com": {Action: RuleAllow}},
+	}}
+	if v := transport.evaluate("https://api.example.com/admin"); v.ruleID != "remote" || !v.blocked {
+		t.Errorf("got rule %q blocked %v, want the remote rule to block", v.ruleID, v.blocked)
+	}
+	if v := transport.evaluate("https://other.example.com/public"); v.ruleID != "rule-1" || v.blocked {
+		t.Errorf("got rule %q blocked %v, want the local rule to keep its ID", v.ruleID, v.blocked)
+	}
+}
@@ -0,0 +1,86 @@
+package trusera
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// entropy is a block of random bytes handed out in IDs. Reading the
+// system's random source once per block rather than once per event keeps
+// NewEvent off the syscall path.
+type entropy struct {
+	buf [1024]byte
+	off int
+}
+
+var entropyPool = sync.Pool{New: func() any {
+	return &entropy{off: len(entropy{}.buf)}
+}}
+
+// randomID returns 16 random bytes, hex-encoded
+func randomID() string {
+	e := entropyPool.Get().(*entropy)
+	if e.off+16 > len(e.buf) {
+		rand.Read(e.buf[:])
+		e.off = 0
+	}
+	var out [32]byte
+	hex.Encode(out[:], e.buf[e.off:e.off+16])
+	clear(e.buf[e.off : e.off+16]) // IDs must not linger in the pool
+	e.off += 16
+	entropyPool.Put(e)
+	return string(out[:])
+}
+
+// stampCache holds the RFC 3339 form of the current second, which every
+// event created within that second shares
+type stampCache struct {
+	unix int64
+	text string
+}
+
+var lastStamp atomic.Pointer[stampCache]
+
+// timestamp formats t in UTC to the second, reusing the previous result
+// while the second has not changed
+func timestamp(t time.Time) string {
+	unix := t.Unix()
+	if s := lastStamp.Load(); s != nil && s.unix == unix {
+		return s.text
+	}
+	s := &stampCache{unix: unix, text: t.UTC().Format(time.RFC3339)}
+	lastStamp.Store(s)
+	return s.text
+}
+
+// sizer measures JSON encodings without keeping them
+type sizer struct {
+	n   int
+	enc *json.Encoder
+}
+
+func (s *sizer) Write(p []byte) (int, error) {
+	s.n += len(p)
+	return len(p), nil
+}
+
+var sizerPool = sync.Pool{New: func() any {
+	s := &sizer{}
+	s.enc = json.NewEncoder(s)
+	return s
+}}
+
+// eventSize returns the serialized size of an event
+func eventSize(event Event) int {
+	s := sizerPool.Get().(*sizer)
+	defer sizerPool.Put(s)
+	s.n = 0
+	if err := s.enc.Encode(event); err != nil {
+		return 0
+	}
+	return s.n - 1 // Encode ends with a newline
+}
@@ -0,0 +1,57 @@
+package trusera
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestRandomID(t *testing.T) {
+	hexID := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id := randomID()
+		if !hexID.MatchString(id) {
+			t.Fatalf("expected 32 hex characters, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestTimestampCache(t *testing.T) {
+	base := time.Date(2024, 5, 6, 7, 8, 9, 0, time.FixedZone("CEST", 2*3600))
+	if got := timestamp(base); got != "2024-05-06T05:08:09Z" {
+		t.Errorf("expected UTC to the second, got %s", got)
+	}
+	if got := timestamp(base.Add(500 * time.Millisecond)); got != "2024-05-06T05:08:09Z" {
+		t.Errorf("expected the same second reused, got %s", got)
+	}
+	if got := timestamp(base.Add(time.Second)); got != "2024-05-06T05:08:10Z" {
+		t.Errorf("expected the next second, got %s", got)
+	}
+}
+
+func TestEventSize(t *testing.T) {
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "<weather> & more")
+	data, _ := json.Marshal(event)
+	if got := eventSize(event); got != len(data) {
+		t.Errorf("expected size %d, got %d", len(data), got)
+	}
+}
+
+func TestTrackDoesNotAllocate(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(discardSink{}))
+	defer client.Close()
+	event := NewEvent(EventToolCall, "search")
+
+	allocs := testing.AllocsPerRun(50, func() {
+		client.Track(event)
+	})
+	if allocs > 0 {
+		t.Errorf("expected Track not to allocate, got %v allocations", allocs)
+	}
+}
@@ -0,0 +1,60 @@
+package trusera
+
+import "time"
+
+// WithIDGenerator sets the function that assigns tracked events their IDs,
+// replacing the random IDs given by NewEvent, so that event streams are
+// reproducible for golden-file tests and replay tooling; combine it with
+// WithClock. IDs are assigned when events are tracked, and parent_event_id
+// links are rewritten to match, so spans and their children stay linked.
+// Events keyed with Event.WithIdempotencyKey keep their IDs. Session IDs
+// come from the generator too. The client remembers every ID it replaces,
+// so this is meant for tests and tooling rather than long-running agents.
+func WithIDGenerator(next func() string) Option {
+	return func(c *Client) {
+		c.nextID = next
+	}
+}
+
+// newID returns an ID from the generator, or a random one
+func (c *Client) newID() string {
+	if c.nextID == nil {
+		return generateID()
+	}
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+	return c.nextID()
+}
+
+// assignID returns the generated ID standing in for id, drawing a new one
+// the first time id is seen
+func (c *Client) assignID(id string) string {
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+	if assigned, ok := c.assignedIDs[id]; ok {
+		return assigned
+	}
+	assigned := c.nextID()
+	if c.assignedIDs == nil {
+		c.assignedIDs = make(map[string]string)
+	}
+	c.assignedIDs[id] = assigned
+	return assigned
+}
+
+// stamp gives an event its ID and timestamp from the configured generator
+// and clock
+func (c *Client) stamp(event Event) Event {
+	if c.nextID != nil {
+		if _, keyed := event.Metadata["idempotency_key"]; !keyed {
+			event.ID = c.assignID(event.ID)
+		}
+		if parent, ok := event.Metadata["parent_event_id"].(string); ok && parent != "" {
+			event = event.WithMetadata("parent_event_id", c.assignID(parent))
+		}
+	}
+	if c.clock != nil {
+		event.Timestamp = c.now().UTC().Format(time.RFC3339)
+	}
+	return event
+}
@@ -0,0 +1,59 @@
+package trusera
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func sequentialIDs() func() string {
+	n := 0
+	return func() string {
+		n++
+		return fmt.Sprintf("id-%d", n)
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithIDGenerator(sequentialIDs()))
+	defer client.Close()
+
+	span := client.StartSpan(EventToolCall, "parent")
+	client.TrackCtx(span.Context(), NewEvent(EventToolCall, "child"))
+	span.End(nil)
+	client.Track(NewEvent(EventDecision, "keyed").WithIdempotencyKey("order-1"))
+
+	child, _ := trackedEvent(client, "child")
+	parent, _ := trackedEvent(client, "parent")
+	if child.ID != "id-1" || parent.ID != "id-2" {
+		t.Errorf("expected generated IDs in order of first use, got parent %s and child %s", parent.ID, child.ID)
+	}
+	if child.Metadata["parent_event_id"] != parent.ID {
+		t.Errorf("expected the child linked to %s, got %v", parent.ID, child.Metadata["parent_event_id"])
+	}
+	if keyed, _ := trackedEvent(client, "keyed"); keyed.ID != NewEvent(EventDecision, "").WithIdempotencyKey("order-1").ID {
+		t.Errorf("expected the idempotency key to keep its ID, got %s", keyed.ID)
+	}
+}
+
+func TestWithIDGeneratorSessions(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithIDGenerator(sequentialIDs()))
+	defer client.Close()
+
+	session := client.StartSession(context.Background(), "run")
+	session.End(nil)
+
+	events := trackedEvents(client, EventSession)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 session events, got %d", len(events))
+	}
+	if events[0].Metadata["session_id"] != "id-1" {
+		t.Errorf("expected the session ID from the generator, got %v", events[0].Metadata["session_id"])
+	}
+	if events[0].ID != "id-2" || events[1].ID != "id-3" {
+		t.Errorf("expected generated event IDs, got %s and %s", events[0].ID, events[1].ID)
+	}
+}
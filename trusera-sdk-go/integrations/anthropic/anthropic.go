@@ -0,0 +1,327 @@
+// Package anthropic instruments Anthropic API clients such as
+// github.com/anthropics/anthropic-sdk-go.
+//
+// The SDK accepts a custom HTTP client, so plugging in the client returned by
+// WrapHTTPClient is enough to emit an EventLLMInvoke for each Messages API
+// call plus one EventToolCall per tool_use block in the response:
+//
+//	client := anthropic.NewClient(
+//		option.WithHTTPClient(truseraanthropic.WrapHTTPClient(nil, truseraClient, truseraanthropic.Options{})),
+//	)
+//
+// Tool call events carry the parent invocation's event ID in the
+// parent_event_id metadata field so the two can be joined downstream.
+//
+// Calls past the budget set with trusera.WithBudget fail before they are
+// sent, with an error wrapping trusera.ErrBudgetExhausted.
+package anthropic
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// Options configures the Anthropic instrumentation
+type Options struct {
+	// CapturePrompts includes the system prompt and messages in the event
+	// payload. Prompts often contain sensitive data, so this is off by default.
+	CapturePrompts bool
+	// CaptureCompletions includes the generated text in the event payload
+	CaptureCompletions bool
+	// Prices overrides the pricing table used for cost_usd; nil uses DefaultPrices
+	Prices map[string]Price
+
+	// Guardrails inspect the prompt before the request is sent, and
+	// Enforcement decides what a flagged prompt does: ModeBlock fails the
+	// call with an error wrapping trusera.ErrGuardrailBlocked.
+	Guardrails  []trusera.Guardrail
+	Enforcement trusera.EnforcementMode
+}
+
+// WrapHTTPClient returns an HTTP client whose requests are instrumented.
+// A nil httpClient uses a new http.Client with the default transport.
+func WrapHTTPClient(httpClient *http.Client, client *trusera.Client, opts Options) *http.Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	wrapped := *httpClient
+	wrapped.Transport = NewTransport(httpClient.Transport, client, opts)
+	return &wrapped
+}
+
+// Transport is an http.RoundTripper that records Anthropic Messages API calls
+type Transport struct {
+	base   http.RoundTripper
+	client *trusera.Client
+	opts   Options
+}
+
+// NewTransport wraps base (http.DefaultTransport when nil)
+func NewTransport(base http.RoundTripper, client *trusera.Client, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Prices == nil {
+		opts.Prices = DefaultPrices
+	}
+	return &Transport{base: base, client: client, opts: opts}
+}
+
+// apiRequest holds the request fields relevant to tracking
+type apiRequest struct {
+	Model    string          `json:"model"`
+	Stream   bool            `json:"stream"`
+	System   json.RawMessage `json:"system,omitempty"`
+	Messages json.RawMessage `json:"messages,omitempty"`
+	Tools    []any           `json:"tools,omitempty"`
+}
+
+// apiResponse is a Messages API response or error body
+type apiResponse struct {
+	ID         string `json:"id"`
+	Model      string `json:"model"`
+	StopReason string `json:"stop_reason"`
+	Content    []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id"`
+		Name  string          `json:"name"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage struct {
+		InputTokens              int `json:"input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toolUse is a tool invocation requested by the model
+type toolUse struct {
+	ID    string
+	Name  string
+	Input any
+}
+
+// RoundTrip forwards the request and tracks the invocation and its tool calls
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/messages") {
+		return t.base.RoundTrip(req)
+	}
+
+	var parsed apiRequest
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		_ = json.Unmarshal(body, &parsed)
+
+		if len(t.opts.Guardrails) > 0 {
+			in := trusera.GuardrailInput{Provider: "anthropic", Model: parsed.Model, Text: trusera.PromptText(body)}
+			if err := t.client.Guard(req.Context(), t.opts.Guardrails, t.opts.Enforcement, in); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := t.client.CheckBudget(req.Context(), "anthropic", parsed.Model); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	event := trusera.NewEvent(trusera.EventLLMInvoke, parsed.Model).
+		WithPayload("provider", "anthropic").
+		WithPayload("operation", "messages").
+		WithPayload("model", parsed.Model).
+		WithPayload("stream", parsed.Stream)
+
+	if len(parsed.Tools) > 0 {
+		event = event.WithPayload("tools_offered", len(parsed.Tools))
+	}
+	if t.opts.CapturePrompts {
+		event = withPrompt(event, parsed)
+	}
+
+	if err != nil {
+		t.client.Track(event.
+			WithPayload("latency_ms", millis(latency)).
+			WithPayload("error", err.Error()))
+		return resp, err
+	}
+
+	event = event.WithPayload("status_code", resp.StatusCode)
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		resp.Body = trusera.WrapEventStream(resp.Body, t.client, req, trusera.EventStreamOptions{
+			Enrich: func(streamed trusera.Event) trusera.Event {
+				return t.enrichStream(streamed, parsed)
+			},
+		})
+		return resp, nil
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if readErr != nil {
+		t.client.Track(event.WithPayload("error", readErr.Error()))
+		return resp, nil
+	}
+
+	var parsedResp apiResponse
+	_ = json.Unmarshal(respBody, &parsedResp)
+
+	event, tools := t.describe(event, parsed.Model, parsedResp, latency)
+	t.client.Track(event)
+	t.trackToolUses(event, tools)
+	return resp, nil
+}
+
+// describe fills the event from a non-streamed response and returns its tool_use blocks
+func (t *Transport) describe(event trusera.Event, requestModel string, r apiResponse, latency time.Duration) (trusera.Event, []toolUse) {
+	model := r.Model
+	if model == "" {
+		model = requestModel
+	}
+	event.Name = model
+
+	input := r.Usage.InputTokens
+	output := r.Usage.OutputTokens
+
+	event = event.
+		WithPayload("model", model).
+		WithPayload("prompt_tokens", input).
+		WithPayload("completion_tokens", output).
+		WithPayload("total_tokens", input+output).
+		WithPayload("latency_ms", millis(latency))
+
+	if r.Usage.CacheCreationInputTokens > 0 {
+		event = event.WithPayload("cache_creation_tokens", r.Usage.CacheCreationInputTokens)
+	}
+	if r.Usage.CacheReadInputTokens > 0 {
+		event = event.WithPayload("cache_read_tokens", r.Usage.CacheReadInputTokens)
+	}
+	if r.ID != "" {
+		event = event.WithPayload("response_id", r.ID)
+	}
+	if r.StopReason != "" {
+		event = event.WithPayload("finish_reason", r.StopReason)
+	}
+	if usd, ok := cost(t.opts.Prices, model, input, output); ok {
+		event = event.WithPayload("cost_usd", usd)
+	}
+
+	var tools []toolUse
+	var names []string
+	var text strings.Builder
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			var input any
+			_ = json.Unmarshal(block.Input, &input)
+			tools = append(tools, toolUse{ID: block.ID, Name: block.Name, Input: input})
+			names = append(names, block.Name)
+		}
+	}
+
+	if len(names) > 0 {
+		event = event.WithPayload("tool_calls", names)
+	}
+	if t.opts.CaptureCompletions && text.Len() > 0 {
+		event = event.WithPayload("completion", text.String())
+	}
+	if r.Error != nil {
+		event = event.WithPayload("error", r.Error.Message).
+			WithPayload("error_type", r.Error.Type)
+	}
+
+	return event, tools
+}
+
+// enrichStream adds provider details and cost to an aggregated stream event
+// and tracks the streamed tool_use blocks as children of it
+func (t *Transport) enrichStream(event trusera.Event, parsed apiRequest) trusera.Event {
+	model, _ := event.Payload["model"].(string)
+	if model == "" {
+		model = parsed.Model
+	}
+	event.Name = model
+	event = event.WithPayload("provider", "anthropic").
+		WithPayload("operation", "messages").
+		WithPayload("model", model)
+
+	if t.opts.CapturePrompts {
+		event = withPrompt(event, parsed)
+	}
+
+	input, _ := event.Payload["prompt_tokens"].(int)
+	output, _ := event.Payload["completion_tokens"].(int)
+	if usd, ok := cost(t.opts.Prices, model, input, output); ok && input+output > 0 {
+		event = event.WithPayload("cost_usd", usd)
+	}
+
+	calls, _ := event.Payload["tool_calls"].([]map[string]any)
+	tools := make([]toolUse, 0, len(calls))
+	for _, call := range calls {
+		id, _ := call["id"].(string)
+		name, _ := call["name"].(string)
+		tools = append(tools, toolUse{ID: id, Name: name, Input: call["input"]})
+	}
+	t.trackToolUses(event, tools)
+
+	return event
+}
+
+// trackToolUses emits one EventToolCall per tool_use block, linked to parent
+func (t *Transport) trackToolUses(parent trusera.Event, tools []toolUse) {
+	for _, tool := range tools {
+		event := trusera.NewEvent(trusera.EventToolCall, tool.Name).
+			WithPayload("provider", "anthropic").
+			WithPayload("tool_use_id", tool.ID).
+			WithPayload("model", parent.Name).
+			WithMetadata("parent_event_id", parent.ID)
+		if tool.Input != nil {
+			event = event.WithPayload("input", tool.Input)
+		}
+		t.client.Track(event)
+	}
+}
+
+func withPrompt(event trusera.Event, parsed apiRequest) trusera.Event {
+	if len(parsed.System) > 0 {
+		var v any
+		if json.Unmarshal(parsed.System, &v) == nil {
+			event = event.WithPayload("system", v)
+		}
+	}
+	if len(parsed.Messages) > 0 {
+		var v any
+		if json.Unmarshal(parsed.Messages, &v) == nil {
+			event = event.WithPayload("prompt", v)
+		}
+	}
+	return event
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
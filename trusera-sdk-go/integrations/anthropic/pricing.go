@@ -0,0 +1,14 @@
+package anthropic
+
+import "github.com/Trusera/ai-bom/trusera-sdk-go/costs"
+
+// Price is the USD cost per one million tokens
+type Price = costs.Price
+
+// DefaultPrices lists Anthropic list prices per one million tokens
+var DefaultPrices = costs.AnthropicPrices
+
+// cost computes the USD cost of a call
+func cost(prices map[string]Price, model string, inputTokens, outputTokens int) (float64, bool) {
+	return costs.Table(prices).Cost(model, inputTokens, outputTokens)
+}
@@ -0,0 +1,36 @@
+// Package chi adapts trusera.Middleware to the Chi router,
+// github.com/go-chi/chi/v5:
+//
+//	r := chi.NewRouter()
+//	r.Use(truserachi.Middleware(truseraClient, trusera.MiddlewareOptions{}))
+//
+// Each request gets its session and trace in its context, so track events
+// with r.Context(). The matched route pattern, such as "/users/{id}", is
+// recorded as route, including for routes in mounted subrouters.
+//
+// It is a module of its own, so the SDK itself stays free of dependencies.
+package chi
+
+import (
+	"net/http"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware returns Chi middleware that tracks every request as
+// trusera.Middleware does. opts.Route, if set, is replaced by Chi's route
+// pattern.
+func Middleware(truseraClient *trusera.Client, opts trusera.MiddlewareOptions) func(http.Handler) http.Handler {
+	opts.Route = routePattern
+	return trusera.Middleware(truseraClient, opts)
+}
+
+// routePattern returns the pattern Chi routed a request by. Chi fills it in
+// while routing, so it is complete once the handler has run.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/Trusera/ai-bom/trusera-sdk-go/truseratest"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMiddleware(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	r := chi.NewRouter()
+	r.Use(Middleware(client, trusera.MiddlewareOptions{}))
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			client.TrackCtx(r.Context(), trusera.NewEvent(trusera.EventToolCall, "lookup"))
+			w.WriteHeader(http.StatusTeapot)
+		})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users/7", nil))
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("expected 418, got %d", w.Code)
+	}
+
+	events := rec.Find(trusera.EventAPICall, "inbound GET /api/users/7")
+	if len(events) != 1 {
+		t.Fatalf("expected 1 inbound event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Payload["route"] != "/api/users/{id}" || e.Payload["status_code"] != http.StatusTeapot {
+		t.Errorf("unexpected payload %v", e.Payload)
+	}
+	tool := rec.Find(trusera.EventToolCall, "lookup")
+	if len(tool) != 1 || tool[0].Metadata["session_id"] != e.Metadata["session_id"] {
+		t.Errorf("expected the handler's event in the request's session, got %v", tool)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/Trusera/ai-bom/trusera-sdk-go/integrations/chi
+
+go 1.21
+
+require (
+	github.com/Trusera/ai-bom/trusera-sdk-go v0.0.0
+	github.com/go-chi/chi/v5 v5.0.12
+)
+
+replace github.com/Trusera/ai-bom/trusera-sdk-go => ../..
@@ -0,0 +1,52 @@
+// Package echo adapts trusera.Middleware to the Echo web framework,
+// github.com/labstack/echo/v4:
+//
+//	e := echo.New()
+//	e.Use(truseraecho.Middleware(truseraClient, trusera.MiddlewareOptions{}))
+//
+// Each request gets its session and trace in c.Request()'s context, so
+// track events with c.Request().Context(). The matched route, such as
+// "/users/:id", is recorded as route. A handler error is passed to Echo's
+// error handler before the request is recorded, so its status is the one
+// tracked.
+//
+// It is a module of its own, so the SDK itself stays free of dependencies.
+package echo
+
+import (
+	"context"
+	"net/http"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/labstack/echo/v4"
+)
+
+// routeKey carries the route matched by Echo to the middleware
+type routeKey struct{}
+
+// Middleware returns Echo middleware that tracks every request as
+// trusera.Middleware does. opts.Route, if set, is replaced by Echo's route.
+func Middleware(truseraClient *trusera.Client, opts trusera.MiddlewareOptions) echo.MiddlewareFunc {
+	opts.Route = func(r *http.Request) string {
+		route, _ := r.Context().Value(routeKey{}).(string)
+		return route
+	}
+	mw := trusera.Middleware(truseraClient, opts)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request().WithContext(context.WithValue(c.Request().Context(), routeKey{}, c.Path()))
+			res := c.Response()
+			writer := res.Writer
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				res.Writer = w
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			})).ServeHTTP(writer, req)
+			res.Writer = writer
+			return nil
+		}
+	}
+}
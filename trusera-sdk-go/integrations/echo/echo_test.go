@@ -0,0 +1,85 @@
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/Trusera/ai-bom/trusera-sdk-go/truseratest"
+	"github.com/labstack/echo/v4"
+)
+
+func TestMiddleware(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	e := echo.New()
+	e.Use(Middleware(client, trusera.MiddlewareOptions{}))
+	e.GET("/users/:id", func(c echo.Context) error {
+		if trusera.SessionIDFromContext(c.Request().Context()) == "" {
+			t.Error("expected a session in the request context")
+		}
+		client.TrackCtx(c.Request().Context(), trusera.NewEvent(trusera.EventToolCall, "lookup"))
+		return c.String(http.StatusTeapot, "short and stout")
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("expected 418, got %d", w.Code)
+	}
+
+	events := rec.Find(trusera.EventAPICall, "inbound GET /users/7")
+	if len(events) != 1 {
+		t.Fatalf("expected 1 inbound event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Payload["route"] != "/users/:id" || ev.Payload["status_code"] != http.StatusTeapot || ev.Payload["response_bytes"] != 15 {
+		t.Errorf("unexpected payload %v", ev.Payload)
+	}
+	tool := rec.Find(trusera.EventToolCall, "lookup")
+	if len(tool) != 1 || tool[0].Metadata["session_id"] != ev.Metadata["session_id"] {
+		t.Errorf("expected the handler's event in the request's session, got %v", tool)
+	}
+}
+
+func TestMiddlewareRecordsHandlerErrors(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	e := echo.New()
+	e.Use(Middleware(client, trusera.MiddlewareOptions{}))
+	e.GET("/missing", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusNotFound, "no such thing")
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+	if events := rec.Find(trusera.EventAPICall, "inbound GET /missing"); len(events) != 1 || events[0].Payload["status_code"] != http.StatusNotFound {
+		t.Errorf("expected the error status tracked, got %v", events)
+	}
+}
+
+func TestMiddlewareRejectsBlockedRequests(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	e := echo.New()
+	e.Use(Middleware(client, trusera.MiddlewareOptions{
+		Enforcement: trusera.ModeBlock,
+		Authenticate: func(r *http.Request) (string, error) {
+			return "", http.ErrNoCookie
+		},
+	}))
+	e.GET("/admin", func(c echo.Context) error {
+		t.Error("the handler should not run")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if events := rec.Find(trusera.EventAPICall, "inbound GET /admin"); len(events) != 1 || events[0].Payload["blocked"] != true {
+		t.Errorf("expected the blocked request tracked, got %v", events)
+	}
+}
@@ -0,0 +1,47 @@
+// Package gin adapts trusera.Middleware to the Gin web framework,
+// github.com/gin-gonic/gin:
+//
+//	r := gin.New()
+//	r.Use(truseragin.Middleware(truseraClient, trusera.MiddlewareOptions{}))
+//
+// Each request gets its session and trace in c.Request's context, so track
+// events with c.Request.Context(). The matched route, such as "/users/:id",
+// is recorded as route. Requests rejected by authentication or policy in
+// block mode are aborted.
+//
+// It is a module of its own, so the SDK itself stays free of dependencies.
+package gin
+
+import (
+	"context"
+	"net/http"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/gin-gonic/gin"
+)
+
+// routeKey carries the route matched by Gin to the middleware
+type routeKey struct{}
+
+// Middleware returns Gin middleware that tracks every request as
+// trusera.Middleware does. opts.Route, if set, is replaced by Gin's route.
+func Middleware(truseraClient *trusera.Client, opts trusera.MiddlewareOptions) gin.HandlerFunc {
+	opts.Route = func(r *http.Request) string {
+		route, _ := r.Context().Value(routeKey{}).(string)
+		return route
+	}
+	mw := trusera.Middleware(truseraClient, opts)
+
+	return func(c *gin.Context) {
+		served := false
+		req := c.Request.WithContext(context.WithValue(c.Request.Context(), routeKey{}, c.FullPath()))
+		mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served = true
+			c.Request = r
+			c.Next()
+		})).ServeHTTP(c.Writer, req)
+		if !served {
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/Trusera/ai-bom/trusera-sdk-go/truseratest"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestMiddleware(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	r := gin.New()
+	r.Use(Middleware(client, trusera.MiddlewareOptions{}))
+	r.GET("/users/:id", func(c *gin.Context) {
+		if trusera.SessionIDFromContext(c.Request.Context()) == "" {
+			t.Error("expected a session in the request context")
+		}
+		client.TrackCtx(c.Request.Context(), trusera.NewEvent(trusera.EventToolCall, "lookup"))
+		c.String(http.StatusTeapot, "short and stout")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("expected 418, got %d", w.Code)
+	}
+
+	events := rec.Find(trusera.EventAPICall, "inbound GET /users/7")
+	if len(events) != 1 {
+		t.Fatalf("expected 1 inbound event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Payload["route"] != "/users/:id" || e.Payload["status_code"] != http.StatusTeapot {
+		t.Errorf("unexpected payload %v", e.Payload)
+	}
+	tool := rec.Find(trusera.EventToolCall, "lookup")
+	if len(tool) != 1 || tool[0].Metadata["session_id"] != e.Metadata["session_id"] {
+		t.Errorf("expected the handler's event in the request's session, got %v", tool)
+	}
+}
+
+func TestMiddlewareAbortsBlockedRequests(t *testing.T) {
+	client, rec := truseratest.NewClient(t)
+	r := gin.New()
+	r.Use(Middleware(client, trusera.MiddlewareOptions{
+		Enforcement: trusera.ModeBlock,
+		Authenticate: func(r *http.Request) (string, error) {
+			return "", http.ErrNoCookie
+		},
+	}))
+	r.GET("/admin", func(c *gin.Context) {
+		t.Error("the handler should not run")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+	if events := rec.Find(trusera.EventAPICall, "inbound GET /admin"); len(events) != 1 || events[0].Payload["blocked"] != true {
+		t.Errorf("expected the blocked request tracked, got %v", events)
+	}
+}
@@ -0,0 +1,382 @@
+// Package openai instruments OpenAI API clients such as
+// github.com/sashabaranov/go-openai.
+//
+// go-openai sends every request through ClientConfig.HTTPClient, so plugging
+// in the client returned by WrapHTTPClient is enough to emit an
+// EventLLMInvoke for each chat completion, completion, embedding and
+// response call:
+//
+//	cfg := openai.DefaultConfig(apiKey)
+//	cfg.HTTPClient = truseraopenai.WrapHTTPClient(nil, truseraClient, truseraopenai.Options{})
+//	client := openai.NewClientWithConfig(cfg)
+//
+// The integration parses the OpenAI wire format rather than go-openai's Go
+// types, so it works with any OpenAI-compatible client or gateway.
+//
+// Calls past the budget set with trusera.WithBudget fail before they are
+// sent, with an error wrapping trusera.ErrBudgetExhausted.
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+	"github.com/Trusera/ai-bom/trusera-sdk-go/tokens"
+)
+
+// Options configures the OpenAI instrumentation
+type Options struct {
+	// CapturePrompts includes request messages/input in the event payload.
+	// Prompts often contain sensitive data, so this is off by default.
+	CapturePrompts bool
+	// CaptureCompletions includes the generated text in the event payload
+	CaptureCompletions bool
+	// Prices overrides the pricing table used for cost_usd; nil uses DefaultPrices
+	Prices map[string]Price
+
+	// Guardrails inspect the prompt before the request is sent, and
+	// Enforcement decides what a flagged prompt does: ModeBlock fails the
+	// call with an error wrapping trusera.ErrGuardrailBlocked.
+	Guardrails  []trusera.Guardrail
+	Enforcement trusera.EnforcementMode
+}
+
+// WrapHTTPClient returns an HTTP client whose requests are instrumented.
+// A nil httpClient uses a new http.Client with the default transport.
+func WrapHTTPClient(httpClient *http.Client, client *trusera.Client, opts Options) *http.Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	wrapped := *httpClient
+	wrapped.Transport = NewTransport(httpClient.Transport, client, opts)
+	return &wrapped
+}
+
+// Transport is an http.RoundTripper that records OpenAI API calls
+type Transport struct {
+	base   http.RoundTripper
+	client *trusera.Client
+	opts   Options
+}
+
+// NewTransport wraps base (http.DefaultTransport when nil)
+func NewTransport(base http.RoundTripper, client *trusera.Client, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Prices == nil {
+		opts.Prices = DefaultPrices
+	}
+	return &Transport{base: base, client: client, opts: opts}
+}
+
+// apiRequest holds the request fields relevant to tracking
+type apiRequest struct {
+	Model    string          `json:"model"`
+	Stream   bool            `json:"stream"`
+	Messages json.RawMessage `json:"messages,omitempty"`
+	Prompt   json.RawMessage `json:"prompt,omitempty"`
+	Input    json.RawMessage `json:"input,omitempty"`
+	Tools    []any           `json:"tools,omitempty"`
+}
+
+// apiResponse covers chat completions, completions, embeddings and responses
+type apiResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		FinishReason string `json:"finish_reason"`
+		Text         string `json:"text"`
+		Message      struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		InputTokens      int `json:"input_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+	} `json:"usage"`
+	Status string `json:"status"`
+	Error  *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
+}
+
+// operation maps an API path to a short operation name
+func operation(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/chat/completions"):
+		return "chat.completions"
+	case strings.HasSuffix(path, "/completions"):
+		return "completions"
+	case strings.HasSuffix(path, "/embeddings"):
+		return "embeddings"
+	case strings.HasSuffix(path, "/responses"):
+		return "responses"
+	}
+	return ""
+}
+
+// RoundTrip forwards the request and tracks an EventLLMInvoke for model calls
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	op := operation(req.URL.Path)
+	if op == "" || req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	var parsed apiRequest
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		_ = json.Unmarshal(body, &parsed)
+
+		if len(t.opts.Guardrails) > 0 {
+			in := trusera.GuardrailInput{Provider: "openai", Model: parsed.Model, Text: trusera.PromptText(body)}
+			if err := t.client.Guard(req.Context(), t.opts.Guardrails, t.opts.Enforcement, in); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := t.client.CheckBudget(req.Context(), "openai", parsed.Model); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start)
+
+	event := trusera.NewEvent(trusera.EventLLMInvoke, parsed.Model).
+		WithPayload("provider", "openai").
+		WithPayload("operation", op).
+		WithPayload("model", parsed.Model).
+		WithPayload("stream", parsed.Stream)
+
+	if len(parsed.Tools) > 0 {
+		event = event.WithPayload("tools_offered", len(parsed.Tools))
+	}
+	if t.opts.CapturePrompts {
+		event = withPrompt(event, parsed)
+	}
+
+	if err != nil {
+		t.client.Track(event.
+			WithPayload("latency_ms", millis(latency)).
+			WithPayload("error", err.Error()))
+		return resp, err
+	}
+
+	event = event.WithPayload("status_code", resp.StatusCode)
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		resp.Body = trusera.WrapEventStream(resp.Body, t.client, req, trusera.EventStreamOptions{
+			CountTokens: tokens.ForModel(parsed.Model).Count,
+			Enrich: func(streamed trusera.Event) trusera.Event {
+				return t.enrichStream(streamed, parsed)
+			},
+		})
+		return resp, nil
+	}
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	if readErr != nil {
+		t.client.Track(event.WithPayload("error", readErr.Error()))
+		return resp, nil
+	}
+
+	var parsedResp apiResponse
+	_ = json.Unmarshal(respBody, &parsedResp)
+
+	t.client.Track(t.describe(event, parsed, parsedResp, latency))
+	return resp, nil
+}
+
+// describe fills the event from a non-streamed response
+func (t *Transport) describe(event trusera.Event, parsed apiRequest, r apiResponse, latency time.Duration) trusera.Event {
+	model := r.Model
+	if model == "" {
+		model = parsed.Model
+	}
+	event.Name = model
+
+	prompt := r.Usage.PromptTokens + r.Usage.InputTokens
+	completion := r.Usage.CompletionTokens + r.Usage.OutputTokens
+	if prompt+completion == 0 && r.Error == nil {
+		// OpenAI-compatible servers do not always report usage
+		counter := tokens.ForModel(model)
+		prompt, completion = promptTokens(counter, parsed), counter.Count(r.text())
+		event = event.WithPayload("tokens_estimated", true)
+	}
+
+	event = event.
+		WithPayload("model", model).
+		WithPayload("prompt_tokens", prompt).
+		WithPayload("completion_tokens", completion).
+		WithPayload("total_tokens", prompt+completion).
+		WithPayload("latency_ms", millis(latency))
+
+	if r.ID != "" {
+		event = event.WithPayload("response_id", r.ID)
+	}
+
+	if usd, ok := cost(t.opts.Prices, model, prompt, completion); ok {
+		event = event.WithPayload("cost_usd", usd)
+	}
+
+	var finishReasons, toolCalls []string
+	for _, c := range r.Choices {
+		if c.FinishReason != "" {
+			finishReasons = append(finishReasons, c.FinishReason)
+		}
+		for _, tc := range c.Message.ToolCalls {
+			toolCalls = append(toolCalls, tc.Function.Name)
+		}
+	}
+
+	if len(finishReasons) > 0 {
+		event = event.WithPayload("finish_reason", finishReasons[0])
+	} else if r.Status != "" {
+		event = event.WithPayload("finish_reason", r.Status)
+	}
+	if len(toolCalls) > 0 {
+		event = event.WithPayload("tool_calls", toolCalls)
+	}
+	if text := r.text(); t.opts.CaptureCompletions && text != "" {
+		event = event.WithPayload("completion", text)
+	}
+	if r.Error != nil {
+		event = event.WithPayload("error", r.Error.Message).
+			WithPayload("error_type", r.Error.Type)
+		if r.Error.Code != nil {
+			event = event.WithPayload("error_code", fmt.Sprint(r.Error.Code))
+		}
+	}
+
+	return event
+}
+
+// enrichStream adds provider details and cost to an aggregated stream event
+func (t *Transport) enrichStream(event trusera.Event, parsed apiRequest) trusera.Event {
+	model, _ := event.Payload["model"].(string)
+	if model == "" {
+		model = parsed.Model
+	}
+	event.Name = model
+	event = event.WithPayload("provider", "openai").WithPayload("model", model)
+
+	if t.opts.CapturePrompts {
+		event = withPrompt(event, parsed)
+	}
+
+	prompt, _ := event.Payload["prompt_tokens"].(int)
+	completion, _ := event.Payload["completion_tokens"].(int)
+	if estimated, _ := event.Payload["tokens_estimated"].(bool); estimated {
+		// Streams report usage only with stream_options.include_usage
+		prompt = promptTokens(tokens.ForModel(model), parsed)
+		event = event.WithPayload("prompt_tokens", prompt).
+			WithPayload("total_tokens", prompt+completion)
+	}
+	if usd, ok := cost(t.opts.Prices, model, prompt, completion); ok && prompt+completion > 0 {
+		event = event.WithPayload("cost_usd", usd)
+	}
+	return event
+}
+
+// text concatenates the generated text of all choices
+func (r apiResponse) text() string {
+	var b strings.Builder
+	for _, c := range r.Choices {
+		b.WriteString(c.Message.Content)
+		b.WriteString(c.Text)
+	}
+	return b.String()
+}
+
+// promptTokens estimates the prompt size of a request, counting message
+// framing the way OpenAI bills it: 3 tokens per message plus 3 to prime the
+// reply
+func promptTokens(counter tokens.Counter, parsed apiRequest) int {
+	var messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(parsed.Messages, &messages) == nil && len(messages) > 0 {
+		n := 3
+		for _, m := range messages {
+			n += 3 + counter.Count(m.Role) + counter.Count(strings.Join(texts(m.Content), "\n"))
+		}
+		return n
+	}
+
+	n := 0
+	for _, raw := range []json.RawMessage{parsed.Prompt, parsed.Input} {
+		n += counter.Count(strings.Join(texts(raw), "\n"))
+	}
+	return n
+}
+
+// texts collects the strings of a content value: a string, a list of
+// strings or content parts with text
+func texts(raw json.RawMessage) []string {
+	var v any
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return nil
+	}
+	var out []string
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			out = append(out, v)
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		case map[string]any:
+			for _, key := range []string{"text", "content"} {
+				walk(v[key])
+			}
+		}
+	}
+	walk(v)
+	return out
+}
+
+func withPrompt(event trusera.Event, parsed apiRequest) trusera.Event {
+	for _, raw := range []json.RawMessage{parsed.Messages, parsed.Prompt, parsed.Input} {
+		if len(raw) > 0 {
+			var v any
+			if json.Unmarshal(raw, &v) == nil {
+				return event.WithPayload("prompt", v)
+			}
+		}
+	}
+	return event
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,14 @@
+package openai
+
+import "github.com/Trusera/ai-bom/trusera-sdk-go/costs"
+
+// Price is the USD cost per one million tokens
+type Price = costs.Price
+
+// DefaultPrices lists OpenAI list prices per one million tokens
+var DefaultPrices = costs.OpenAIPrices
+
+// cost computes the USD cost of a call
+func cost(prices map[string]Price, model string, promptTokens, completionTokens int) (float64, bool) {
+	return costs.Table(prices).Cost(model, promptTokens, completionTokens)
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 )
 
 const maxBodySnippet = 500
@@ -18,13 +21,99 @@ const (
 	ModeLog   EnforcementMode = "log"   // Record but allow all requests
 	ModeWarn  EnforcementMode = "warn"  // Log warnings for blocked patterns but allow
 	ModeBlock EnforcementMode = "block" // Reject blocked requests with error
+
+	// ModeAllowList rejects every request that does not match AllowPatterns,
+	// as well as those matching BlockPatterns
+	ModeAllowList EnforcementMode = "allow_list"
+
+	// ModeAudit records like ModeLog and also captures the full request and
+	// response of every intercepted call for forensics
+	ModeAudit EnforcementMode = "audit"
+
+	// ModeLearn records like ModeLog, never rejecting a request, and learns
+	// the hosts contacted for Client.ProposedPolicy; see WithLearning
+	ModeLearn EnforcementMode = "learn"
+
+	// ModeShadow evaluates the policy as ModeBlock would, including the
+	// CandidateRules, and records what it would have blocked without ever
+	// rejecting a request
+	ModeShadow EnforcementMode = "shadow"
 )
 
+// rejects reports whether the mode stops blocked requests
+func (m EnforcementMode) rejects() bool {
+	return m == ModeBlock || m == ModeAllowList
+}
+
+// blockAction is the enforcement_action recorded for a blocked request
+func (m EnforcementMode) blockAction() string {
+	if m == ModeShadow {
+		return "would_block"
+	}
+	return "blocked"
+}
+
 // InterceptorOptions configures the HTTP interceptor
 type InterceptorOptions struct {
-	Enforcement     EnforcementMode
-	ExcludePatterns []string // URL patterns to skip interception
-	BlockPatterns   []string // URL patterns to block (for testing enforcement)
+	Enforcement    EnforcementMode
+	Rules          []Rule // Per-pattern actions, checked before the pattern lists below
+	CandidateRules []Rule // Rules being trialled, checked before Rules in ModeShadow only
+
+	// EnforcePercent, when between 0 and 100, rolls out ModeBlock or
+	// ModeAllowList to that percentage of sessions only; the others are
+	// handled as in ModeWarn. Each session is enforced or not for its whole
+	// length.
+	EnforcePercent  float64
+	ExcludePatterns []string      // URL patterns to skip interception
+	BlockPatterns   []string      // URL patterns to block (for testing enforcement)
+	AllowPatterns   []string      // URL patterns permitted in ModeAllowList; everything else is blocked
+	Policy          RequestPolicy // Optional CEL or OPA policy; a Deny decision is treated as a block
+
+	// Hosts sets the enforcement for particular hosts, keyed by host name,
+	// "*.example.com" for its subdomains, or "*" for every other host; see
+	// HostPolicy. The most specific key applies.
+	Hosts map[string]HostPolicy
+
+	// AuditBodyLimit caps the bytes of each body captured in ModeAudit
+	// (64 KiB when zero)
+	AuditBodyLimit int
+
+	// AuditRedactor scrubs bodies captured in ModeAudit. When nil the
+	// default PII and secret detectors are used.
+	AuditRedactor *Redactor
+
+	// StreamChunkEvents emits an event for every chunk of a text/event-stream
+	// response in addition to the aggregated llm_stream event
+	StreamChunkEvents bool
+
+	// Guardrails inspect the prompt text of JSON request bodies (see
+	// PromptText). A flagged request is treated like a blocked URL under the
+	// enforcement mode and an EventPromptInjection is tracked.
+	Guardrails []Guardrail
+
+	// Cassette, if set, records forwarded requests and their responses, or
+	// replays recorded responses instead of sending the requests
+	Cassette *Cassette
+
+	// Capture selects the request and response headers and bodies to
+	// record; see CaptureOptions
+	Capture *CaptureOptions
+
+	// CredentialEgress, if set, flags requests carrying credentials to hosts
+	// outside its approved list; see CredentialPolicy
+	CredentialEgress *CredentialPolicy
+
+	// Reputation, if set, is consulted for the host of every HTTP request.
+	// A host it reports as malicious is treated like a blocked URL under
+	// the enforcement mode. Reputations are cached per interceptor.
+	Reputation ReputationProvider
+
+	// Decide, if set, is called with every HTTP request for logic the rules
+	// cannot express, such as inspecting the method, headers or body. Its
+	// decision takes precedence over the rules and patterns; see
+	// RequestDecision. It may read the body, which is restored afterwards.
+	// It is called concurrently and must not keep the request.
+	Decide func(*http.Request) RequestDecision
 }
 
 // WrapHTTPClient wraps an http.Client to intercept all outbound requests
@@ -51,31 +140,125 @@ func WrapHTTPClient(client *http.Client, truseraClient *Client, opts Interceptor
 type interceptingTransport struct {
 	base   http.RoundTripper
 	client *Client
-	opts   InterceptorOptions
+
+	mu   sync.RWMutex // guards opts against UpdateOptions
+	opts InterceptorOptions
+
+	reputations reputationCache
+}
+
+// localOptions returns the interceptor's own options, without the client's
+// remote policy
+func (t *interceptingTransport) localOptions() InterceptorOptions {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.opts
+}
+
+// Interceptor is a handle on a live HTTP interceptor, used to reconfigure
+// it without recreating the client it wraps
+type Interceptor struct {
+	t *interceptingTransport
+}
+
+// InterceptorOf returns the interceptor installed by WrapHTTPClient,
+// CreateInterceptedClient or InterceptDefault, or nil if client is not
+// intercepted
+func InterceptorOf(client *http.Client) *Interceptor {
+	if client == nil {
+		return nil
+	}
+	t, ok := client.Transport.(*interceptingTransport)
+	if !ok {
+		return nil
+	}
+	return &Interceptor{t: t}
+}
+
+// Options returns the interceptor's current options
+func (i *Interceptor) Options() InterceptorOptions {
+	return i.t.localOptions()
+}
+
+// UpdateOptions changes the options of the live interceptor, e.g. to add
+// BlockPatterns or switch Enforcement to ModeBlock mid-incident. update
+// receives a copy of the current options and may modify it freely;
+// concurrent updates are applied one after another. Requests already past
+// enforcement are unaffected.
+//
+//	trusera.InterceptorOf(httpClient).UpdateOptions(func(o *trusera.InterceptorOptions) {
+//		o.Enforcement = trusera.ModeBlock
+//		o.BlockPatterns = append(o.BlockPatterns, "pastebin.com")
+//	})
+func (i *Interceptor) UpdateOptions(update func(*InterceptorOptions)) {
+	i.t.mu.Lock()
+	defer i.t.mu.Unlock()
+	opts := i.t.opts
+	opts.Rules = slices.Clone(opts.Rules)
+	opts.ExcludePatterns = slices.Clone(opts.ExcludePatterns)
+	opts.BlockPatterns = slices.Clone(opts.BlockPatterns)
+	opts.AllowPatterns = slices.Clone(opts.AllowPatterns)
+	opts.Guardrails = slices.Clone(opts.Guardrails)
+	opts.Hosts = maps.Clone(opts.Hosts)
+	update(&opts)
+	i.t.opts = opts
+	i.t.reputations.clear()
+}
+
+// forward sends a request on to the base transport, through the cassette
+// if one is set
+func (t *interceptingTransport) forward(req *http.Request) (*http.Response, error) {
+	if c := t.localOptions().Cassette; c != nil {
+		return c.roundTrip(t.base, req)
+	}
+	return t.base.RoundTrip(req)
 }
 
 // RoundTrip intercepts and records HTTP requests
 func (t *interceptingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Check if URL should be excluded from interception
-	if t.shouldExclude(req.URL.String()) {
-		return t.base.RoundTrip(req)
+	// Match the URL against the rules and exclude/block patterns
+	v := t.decide(req, t.evaluateRequest(req.Method, req.URL.String()))
+	if v.excluded {
+		return t.forward(req)
+	}
+	v = t.canary(req.Context(), req.URL.String(), v)
+	v = t.limitCalls(req.Context(), req.URL.String(), v)
+	blocked := v.blocked
+
+	// Evaluate the request policy, if any
+	policyReasons := t.policyReasons(req)
+	if len(policyReasons) > 0 {
+		blocked = true
 	}
 
-	// Check if URL matches block patterns (for enforcement)
-	blocked := t.isBlocked(req.URL.String())
+	// Check for credentials sent to unapproved hosts
+	leaked := t.credentialEgress(req, v.mode)
+	if len(leaked) > 0 {
+		blocked = true
+	}
+
+	// Consult the host's reputation, if a provider is set
+	reputation, malicious := t.checkReputation(req.Context(), req.URL.String(), v.mode)
+	if malicious {
+		blocked = true
+	}
 
 	// Read and restore request body for logging
-	var bodySnippet string
+	var requestBody []byte
+	var guardrailReasons []string
 	if req.Body != nil {
 		bodyBytes, err := io.ReadAll(req.Body)
 		if err == nil {
 			req.Body.Close()
 			req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-
-			if len(bodyBytes) > maxBodySnippet {
-				bodySnippet = string(bodyBytes[:maxBodySnippet]) + "..."
-			} else {
-				bodySnippet = string(bodyBytes)
+			requestBody = bodyBytes
+
+			if len(t.localOptions().Guardrails) > 0 {
+				in := GuardrailInput{Provider: req.URL.Host, Text: PromptText(bodyBytes)}
+				guardrailReasons = t.client.runGuardrails(req.Context(), t.localOptions().Guardrails, v.mode, in)
+				if len(guardrailReasons) > 0 {
+					blocked = true
+				}
 			}
 		}
 	}
@@ -84,30 +267,51 @@ func (t *interceptingTransport) RoundTrip(req *http.Request) (*http.Response, er
 	event := NewEvent(EventAPICall, req.Method+" "+req.URL.String()).
 		WithPayload("method", req.Method).
 		WithPayload("url", req.URL.String()).
-		WithPayload("headers", sanitizeHeaders(req.Header)).
-		WithPayload("blocked", blocked).
-		WithMetadata("enforcement_mode", string(t.opts.Enforcement))
+		WithPayload("blocked", blocked)
+	event = t.client.correlateRequest(req, v.describe(event))
+	event = t.captureRequest(event, req, requestBody)
 
-	if bodySnippet != "" {
-		event = event.WithPayload("body_snippet", bodySnippet)
+	if len(policyReasons) > 0 {
+		event = event.WithPayload("policy_reasons", policyReasons)
 	}
+	if len(guardrailReasons) > 0 {
+		event = event.WithPayload("guardrail_reasons", guardrailReasons)
+	}
+	if len(leaked) > 0 {
+		event = event.WithPayload("credential_headers", leaked)
+	}
+	if malicious {
+		event = event.WithPayload("reputation", reputationSummary(reputation))
+	}
+
+	var rep []string
+	if malicious {
+		rep = []string{reputationSummary(reputation)}
+	}
+	causes := []blockCause{
+		{BlockReasonPolicy, policyReasons},
+		{BlockReasonCredentialEgress, leaked},
+		{BlockReasonReputation, rep},
+		{BlockReasonGuardrail, guardrailReasons},
+	}
+	t.logDecision(req.Context(), "http", req.Method, req.URL.String(), v, blocked, causes...)
 
 	// Handle enforcement modes
 	if blocked {
-		event = event.WithPayload("enforcement_action", "blocked")
+		event = event.WithPayload("enforcement_action", v.mode.blockAction())
 
-		switch t.opts.Enforcement {
-		case ModeBlock:
+		switch v.mode {
+		case ModeBlock, ModeAllowList:
 			t.client.Track(event)
-			return nil, errors.New("request blocked by Trusera policy")
+			return nil, v.blockedError(req.URL.String(), causes...)
 
 		case ModeWarn:
 			event = event.WithMetadata("warning", "URL matches block pattern but allowed in warn mode")
 			t.client.Track(event)
 			// Continue with request
 
-		case ModeLog:
-			// Just record, no action
+		default:
+			// Log and audit modes just record, no action
 			t.client.Track(event)
 		}
 	} else {
@@ -115,49 +319,119 @@ func (t *interceptingTransport) RoundTrip(req *http.Request) (*http.Response, er
 		t.client.Track(event)
 	}
 
-	// Forward request to base transport
-	resp, err := t.base.RoundTrip(req)
+	// Forward request to base transport, timing its phases and checking
+	// its connection against the host's TLS policy
+	req, timing := t.traceRequest(req)
+	req, tlsCheck := t.checkTLS(req, v)
+	start := t.client.now()
+	resp, err := t.forward(req)
+	if tlsCheck != nil {
+		if resp, err = tlsCheck.finish(t, req, resp, err); errors.Is(err, ErrBlocked) {
+			return nil, err
+		}
+	}
 	if err != nil {
 		// Track the error
 		errorEvent := NewEvent(EventAPICall, "error").
 			WithPayload("method", req.Method).
 			WithPayload("url", req.URL.String()).
 			WithPayload("error", err.Error())
-		t.client.Track(errorEvent)
+		errorEvent = timing.describe(errorEvent, start)
+		t.client.Track(t.client.correlateRequest(req, errorEvent))
 		return resp, err
 	}
 
-	// Record response status
+	// Record response status, timing and sizes
 	responseEvent := NewEvent(EventAPICall, "response").
 		WithPayload("method", req.Method).
 		WithPayload("url", req.URL.String()).
 		WithPayload("status_code", resp.StatusCode).
-		WithPayload("status", resp.Status)
-	t.client.Track(responseEvent)
+		WithPayload("status", resp.Status).
+		WithPayload("request_bytes", len(requestBody))
+	responseEvent = timing.describe(responseEvent, start)
+	if resp.ContentLength >= 0 {
+		responseEvent = responseEvent.WithPayload("response_bytes", int(resp.ContentLength))
+	}
+	responseEvent = t.captureResponse(responseEvent, resp)
+
+	// Streamed LLM responses are parsed as the caller consumes them
+	if resp.Body != nil && isEventStream(resp.Header.Get("Content-Type")) {
+		responseEvent = responseEvent.WithPayload("streaming", true)
+		stream := newSSEBody(resp.Body, t.client, req.Method, req.URL.String(), t.localOptions().StreamChunkEvents, start)
+		stream.enrich = func(e Event) Event {
+			return t.client.correlateRequest(req, e)
+		}
+		resp.Body = stream
+	}
+	t.client.Track(t.client.correlateRequest(req, responseEvent))
 
 	return resp, nil
 }
 
 // shouldExclude checks if URL matches any exclude patterns
 func (t *interceptingTransport) shouldExclude(url string) bool {
-	for _, pattern := range t.opts.ExcludePatterns {
-		if strings.Contains(url, pattern) {
-			return true
-		}
-	}
-	return false
+	return matchesAny(url, t.options().ExcludePatterns)
 }
 
-// isBlocked checks if URL matches any block patterns
+// isBlocked checks if URL matches any block patterns or, in allow-list
+// mode, matches no allow pattern
 func (t *interceptingTransport) isBlocked(url string) bool {
-	for _, pattern := range t.opts.BlockPatterns {
-		if strings.Contains(url, pattern) {
+	return t.options().blocks(url)
+}
+
+// blocks reports whether the options block a URL by pattern
+func (o InterceptorOptions) blocks(url string) bool {
+	return o.blockReason(url) != ""
+}
+
+// blockReason returns why the options block a URL by pattern, or "" if
+// they do not
+func (o InterceptorOptions) blockReason(url string) BlockReason {
+	if o.Enforcement == ModeAllowList && !matchesAny(url, o.AllowPatterns) {
+		return BlockReasonNotAllowed
+	}
+	if matchesAny(url, o.BlockPatterns) {
+		return BlockReasonPattern
+	}
+	return ""
+}
+
+// options returns the interceptor options with the client's remote policy,
+// if any, applied
+func (t *interceptingTransport) options() InterceptorOptions {
+	return t.localOptions().merge(t.remotePolicy())
+}
+
+func (t *interceptingTransport) remotePolicy() *RemotePolicy {
+	if t.client == nil {
+		return nil
+	}
+	return t.client.effectivePolicy()
+}
+
+// matchesAny reports whether url matches any of the patterns; see
+// CompilePattern
+func matchesAny(url string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(url, pattern) {
 			return true
 		}
 	}
 	return false
 }
 
+// policyReasons evaluates the request policy and returns the deny reasons, if any
+func (t *interceptingTransport) policyReasons(req *http.Request) []string {
+	if t.localOptions().Policy == nil {
+		return nil
+	}
+	decision := t.localOptions().Policy.EvaluateRequest(req)
+	if decision.Decision != "Deny" {
+		return nil
+	}
+	return decision.Reasons
+}
+
 // sanitizeHeaders removes sensitive headers from logging
 func sanitizeHeaders(headers http.Header) map[string]string {
 	sanitized := make(map[string]string)
@@ -190,9 +464,10 @@ func InterceptDefault(truseraClient *Client, opts InterceptorOptions) {
 	http.DefaultClient = WrapHTTPClient(http.DefaultClient, truseraClient, opts)
 }
 
-// MustRegisterAndIntercept is a convenience function that registers an agent and returns an intercepted client
-func MustRegisterAndIntercept(apiKey, agentName, framework string, opts InterceptorOptions) (*Client, *http.Client, error) {
-	client := NewClient(apiKey)
+// MustRegisterAndIntercept is a convenience function that registers an agent and returns an intercepted client.
+// clientOpts configure the Trusera client, e.g. WithManifest to register the agent's capabilities.
+func MustRegisterAndIntercept(apiKey, agentName, framework string, opts InterceptorOptions, clientOpts ...Option) (*Client, *http.Client, error) {
+	client := NewClient(apiKey, clientOpts...)
 
 	_, err := client.RegisterAgent(agentName, framework)
 	if err != nil {
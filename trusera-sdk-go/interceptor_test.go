@@ -1,6 +1,7 @@
 package trusera
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -179,6 +180,90 @@ func TestWarnModeAllowsBlockedRequests(t *testing.T) {
 	}
 }
 
+func TestAllowListModeBlocksUnlistedRequests(t *testing.T) {
+	var backendCalls []string
+	var mu sync.Mutex
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		backendCalls = append(backendCalls, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	truseraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer truseraServer.Close()
+
+	truseraClient := NewClient("test-key", WithBaseURL(truseraServer.URL), WithFlushInterval(time.Hour))
+	defer truseraClient.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{
+		Enforcement:   ModeAllowList,
+		AllowPatterns: []string{"/v1/"},
+		BlockPatterns: []string{"/v1/admin"},
+	})
+
+	resp, err := httpClient.Get(backend.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("allow-listed request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	for _, path := range []string{"/exfil", "/v1/admin/keys"} {
+		resp, err := httpClient.Get(backend.URL + path)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatalf("expected %s to be blocked", path)
+		}
+		if !strings.Contains(err.Error(), "blocked by Trusera policy") {
+			t.Errorf("expected policy error message, got: %v", err)
+		}
+	}
+
+	mu.Lock()
+	if len(backendCalls) != 1 || backendCalls[0] != "/v1/models" {
+		t.Errorf("expected only /v1/models to reach the backend, got %v", backendCalls)
+	}
+	mu.Unlock()
+
+	truseraClient.mu.Lock()
+	actions := make(map[string]string)
+	for _, e := range truseraClient.events {
+		if action, ok := e.Payload["enforcement_action"].(string); ok {
+			actions[e.Payload["url"].(string)] = action
+		}
+	}
+	truseraClient.mu.Unlock()
+	want := map[string]string{
+		backend.URL + "/v1/models":     "allowed",
+		backend.URL + "/exfil":         "blocked",
+		backend.URL + "/v1/admin/keys": "blocked",
+	}
+	for url, action := range want {
+		if actions[url] != action {
+			t.Errorf("expected %s to be audited as %q, got %q", url, action, actions[url])
+		}
+	}
+	if got := truseraClient.Stats().InterceptorDecision["block"]; got != 2 {
+		t.Errorf("expected 2 block decisions, got %d", got)
+	}
+}
+
+func TestAllowListModeWithoutPatternsBlocksEverything(t *testing.T) {
+	transport := &interceptingTransport{opts: InterceptorOptions{Enforcement: ModeAllowList}}
+	if !transport.isBlocked("https://api.openai.com/v1/chat/completions") {
+		t.Error("expected an empty allow list to block every URL")
+	}
+
+	transport.opts.Enforcement = ModeBlock
+	transport.opts.AllowPatterns = nil
+	if transport.isBlocked("https://api.openai.com/v1/chat/completions") {
+		t.Error("expected allow patterns to be ignored outside allow-list mode")
+	}
+}
+
 func TestLogModeAllowsAllRequests(t *testing.T) {
 	backendCalled := false
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -333,3 +418,66 @@ func TestConcurrentRequests(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestInterceptorUpdateOptions(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := WrapHTTPClient(&http.Client{Transport: &stubTransport{}}, client, InterceptorOptions{
+		Enforcement:   ModeWarn,
+		BlockPatterns: []string{"evil.example"},
+	})
+
+	interceptor := InterceptorOf(httpClient)
+	if interceptor == nil {
+		t.Fatal("expected the wrapped client to have an interceptor")
+	}
+	if InterceptorOf(&http.Client{}) != nil {
+		t.Error("expected no interceptor on a plain client")
+	}
+
+	if _, err := httpClient.Get("https://evil.example/x"); err != nil {
+		t.Fatalf("expected warn mode to allow the request, got %v", err)
+	}
+
+	before := interceptor.Options()
+	interceptor.UpdateOptions(func(o *InterceptorOptions) {
+		o.Enforcement = ModeBlock
+		o.BlockPatterns = append(o.BlockPatterns, "pastebin.com")
+		o.ExcludePatterns = []string{"health.internal"}
+	})
+	if len(before.BlockPatterns) != 1 {
+		t.Errorf("expected earlier copies of the options to be unaffected, got %v", before.BlockPatterns)
+	}
+
+	for url, blocked := range map[string]bool{
+		"https://evil.example/x":       true,
+		"https://pastebin.com/raw/1":   true,
+		"https://api.example/x":        false,
+		"https://health.internal/ping": false,
+	} {
+		_, err := httpClient.Get(url)
+		if got := errors.Is(err, ErrBlocked); got != blocked {
+			t.Errorf("%s: expected blocked=%v, got %v", url, blocked, err)
+		}
+	}
+
+	// Updates race with requests
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			interceptor.UpdateOptions(func(o *InterceptorOptions) {
+				o.BlockPatterns = append(o.BlockPatterns, "other.example")
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			httpClient.Get("https://api.example/x")
+		}()
+	}
+	wg.Wait()
+	if n := len(interceptor.Options().BlockPatterns); n != 6 {
+		t.Errorf("expected every concurrent update to apply, got %d patterns", n)
+	}
+}
@@ -0,0 +1,294 @@
+package trusera
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// jsonSchema is the subset of JSON Schema used to describe tool arguments:
+// type, enum, const, properties, required, additionalProperties, items,
+// the numeric, string and array bounds, pattern, allOf, anyOf and oneOf.
+// Other keywords, such as format and $ref, are accepted and ignored.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                *any                   `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	AllOf                []*jsonSchema          `json:"allOf"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+
+	// reject is set for the schema false, which no value matches
+	reject  bool
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is the type keyword, a single type or a list of them
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = many
+	return nil
+}
+
+// UnmarshalJSON accepts the boolean schemas true and false
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = jsonSchema{reject: !b}
+		return nil
+	}
+	type plain jsonSchema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// compileSchema parses a schema and its patterns
+func compileSchema(data []byte) (*jsonSchema, error) {
+	var s jsonSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.compile(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *jsonSchema) compile() error {
+	if s == nil {
+		return nil
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	children := []*jsonSchema{s.AdditionalProperties, s.Items}
+	for _, p := range s.Properties {
+		children = append(children, p)
+	}
+	children = append(children, s.AllOf...)
+	children = append(children, s.AnyOf...)
+	children = append(children, s.OneOf...)
+	for _, child := range children {
+		if err := child.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate returns the ways in which v, a decoded JSON value, violates the
+// schema, each prefixed with its path below path
+func (s *jsonSchema) validate(v any, path string) []string {
+	if s == nil {
+		return nil
+	}
+	if s.reject {
+		return []string{path + ": not allowed"}
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, joinTypes(s.Type), jsonType(v))}
+	}
+	var errs []string
+	if len(s.Enum) > 0 && !containsJSON(s.Enum, v) {
+		errs = append(errs, fmt.Sprintf("%s: must be one of %s", path, compactJSON(s.Enum)))
+	}
+	if s.Const != nil && !reflect.DeepEqual(*s.Const, v) {
+		errs = append(errs, fmt.Sprintf("%s: must be %s", path, compactJSON(*s.Const)))
+	}
+
+	switch v := v.(type) {
+	case float64:
+		errs = append(errs, s.validateNumber(v, path)...)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("%s: shorter than %d characters", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s: longer than %d characters", path, *s.MaxLength))
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs = append(errs, fmt.Sprintf("%s: does not match %s", path, s.Pattern))
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, fmt.Sprintf("%s: fewer than %d items", path, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, fmt.Sprintf("%s: more than %d items", path, *s.MaxItems))
+		}
+		for i, item := range v {
+			errs = append(errs, s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case map[string]any:
+		errs = append(errs, s.validateObject(v, path)...)
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, sub.validate(v, path)...)
+	}
+	if len(s.AnyOf) > 0 && countMatches(s.AnyOf, v, path) == 0 {
+		errs = append(errs, path+": matches none of anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := countMatches(s.OneOf, v, path); n != 1 {
+			errs = append(errs, fmt.Sprintf("%s: matches %d of oneOf", path, n))
+		}
+	}
+	return errs
+}
+
+func (s *jsonSchema) validateNumber(v float64, path string) []string {
+	var errs []string
+	if s.Minimum != nil && v < *s.Minimum {
+		errs = append(errs, fmt.Sprintf("%s: less than %v", path, *s.Minimum))
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		errs = append(errs, fmt.Sprintf("%s: greater than %v", path, *s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+		errs = append(errs, fmt.Sprintf("%s: not greater than %v", path, *s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+		errs = append(errs, fmt.Sprintf("%s: not less than %v", path, *s.ExclusiveMaximum))
+	}
+	return errs
+}
+
+func (s *jsonSchema) validateObject(v map[string]any, path string) []string {
+	var errs []string
+	for _, name := range s.Required {
+		if _, ok := v[name]; !ok {
+			errs = append(errs, fmt.Sprintf("%s.%s: required", path, name))
+		}
+	}
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if prop, ok := s.Properties[k]; ok {
+			errs = append(errs, prop.validate(v[k], path+"."+k)...)
+		} else {
+			errs = append(errs, s.AdditionalProperties.validate(v[k], path+"."+k)...)
+		}
+	}
+	return errs
+}
+
+func (s *jsonSchema) matchesType(v any) bool {
+	actual := jsonType(v)
+	for _, t := range s.Type {
+		if t == actual || t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func countMatches(schemas []*jsonSchema, v any, path string) int {
+	n := 0
+	for _, s := range schemas {
+		if len(s.validate(v, path)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return compactJSON(types)
+}
+
+func containsJSON(values []any, v any) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func compactJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// toJSONValue converts a Go value to its decoded JSON form, the form
+// validate expects. Strings holding a JSON object or array are decoded.
+func toJSONValue(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		var decoded any
+		if json.Unmarshal([]byte(s), &decoded) == nil {
+			switch decoded.(type) {
+			case map[string]any, []any:
+				return decoded, nil
+			}
+		}
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	err = json.Unmarshal(data, &decoded)
+	return decoded, err
+}
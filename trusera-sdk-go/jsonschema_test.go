@@ -0,0 +1,36 @@
+package trusera
+
+import "testing"
+
+func TestJSONSchemaKeywords(t *testing.T) {
+	for _, tc := range []struct {
+		schema string
+		value  any
+		valid  bool
+	}{
+		{`{"type": "integer"}`, 3, true},
+		{`{"type": "integer"}`, 3.5, false},
+		{`{"type": "number"}`, 3, true},
+		{`{"type": ["string", "null"]}`, nil, true},
+		{`{"type": "string", "minLength": 2, "maxLength": 3}`, "héé", true},
+		{`{"type": "string", "maxLength": 2}`, "abc", false},
+		{`{"type": "array", "items": {"type": "string"}, "maxItems": 2}`, []string{"a", "b"}, true},
+		{`{"type": "array", "items": {"type": "string"}}`, []any{"a", 1}, false},
+		{`{"type": "array", "minItems": 1}`, []int{}, false},
+		{`{"const": "x"}`, "y", false},
+		{`{"anyOf": [{"type": "string"}, {"minimum": 10}]}`, 12, true},
+		{`{"oneOf": [{"type": "number"}, {"minimum": 10}]}`, 12, false},
+		{`{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, 3, false},
+		{`{"properties": {"a": true}, "additionalProperties": {"type": "integer"}}`, map[string]any{"a": "x", "b": 1}, true},
+		{`{"format": "email", "$ref": "#/x"}`, "anything", true},
+	} {
+		s, err := compileSchema([]byte(tc.schema))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.schema, err)
+		}
+		v, _ := toJSONValue(tc.value)
+		if errs := s.validate(v, "v"); (len(errs) == 0) != tc.valid {
+			t.Errorf("%s with %v: expected valid=%v, got %v", tc.schema, tc.value, tc.valid, errs)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package trusera
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// KafkaMessage is a record for a KafkaProducer
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer publishes records to Kafka. The SDK does not depend on a
+// Kafka client; adapt the one you use, for example segmentio/kafka-go:
+//
+//	type kafkaGo struct{ w *kafka.Writer }
+//
+//	func (p kafkaGo) Produce(ctx context.Context, msgs []trusera.KafkaMessage) error {
+//		out := make([]kafka.Message, len(msgs))
+//		for i, m := range msgs {
+//			out[i] = kafka.Message{Topic: m.Topic, Key: m.Key, Value: m.Value}
+//			for k, v := range m.Headers {
+//				out[i].Headers = append(out[i].Headers, kafka.Header{Key: k, Value: []byte(v)})
+//			}
+//		}
+//		return p.w.WriteMessages(ctx, out...)
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, msgs []KafkaMessage) error
+}
+
+// KafkaKey selects the partition key of event records
+type KafkaKey string
+
+const (
+	KafkaKeyAgent   KafkaKey = "agent"   // The client's agent ID (default)
+	KafkaKeySession KafkaKey = "session" // The event's session_id, falling back to the agent ID
+	KafkaKeyNone    KafkaKey = "none"    // No key; the producer picks partitions
+)
+
+// KafkaSerialization is the encoding of event records
+type KafkaSerialization string
+
+const (
+	KafkaJSON     KafkaSerialization = "json"     // The event as JSON, with an agent_id field (default)
+	KafkaProtobuf KafkaSerialization = "protobuf" // The TruseraEvent message documented on KafkaSink
+)
+
+// KafkaOptions configures a KafkaSink
+type KafkaOptions struct {
+	Topic         string // Required
+	Key           KafkaKey
+	Serialization KafkaSerialization
+}
+
+// KafkaSink publishes each event as one Kafka record, for use with WithSink.
+// Records carry the headers trusera-event-type and content-type
+// (application/json or application/x-protobuf).
+//
+// Protobuf records encode this message, with payload and metadata as JSON
+// so that they keep their free-form structure:
+//
+//	message TruseraEvent {
+//	  string id = 1;
+//	  string type = 2;
+//	  string name = 3;
+//	  bytes payload_json = 4;
+//	  bytes metadata_json = 5;
+//	  string timestamp = 6;
+//	  string agent_id = 7;
+//	}
+type KafkaSink struct {
+	producer KafkaProducer
+	opts     KafkaOptions
+}
+
+// NewKafkaSink creates a sink that publishes through producer
+func NewKafkaSink(producer KafkaProducer, opts KafkaOptions) (*KafkaSink, error) {
+	if producer == nil {
+		return nil, errors.New("kafka producer is required")
+	}
+	if opts.Topic == "" {
+		return nil, errors.New("kafka topic is required")
+	}
+	if opts.Key == "" {
+		opts.Key = KafkaKeyAgent
+	}
+	if opts.Serialization == "" {
+		opts.Serialization = KafkaJSON
+	}
+	switch opts.Key {
+	case KafkaKeyAgent, KafkaKeySession, KafkaKeyNone:
+	default:
+		return nil, fmt.Errorf("unknown kafka key %q", opts.Key)
+	}
+	switch opts.Serialization {
+	case KafkaJSON, KafkaProtobuf:
+	default:
+		return nil, fmt.Errorf("unknown kafka serialization %q", opts.Serialization)
+	}
+	return &KafkaSink{producer: producer, opts: opts}, nil
+}
+
+// Send publishes a batch of events
+func (s *KafkaSink) Send(ctx context.Context, events []Event) error {
+	agentID := AgentIDFromContext(ctx)
+	contentType := "application/json"
+	if s.opts.Serialization == KafkaProtobuf {
+		contentType = "application/x-protobuf"
+	}
+
+	msgs := make([]KafkaMessage, 0, len(events))
+	for _, event := range events {
+		value, err := s.encode(agentID, event)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, KafkaMessage{
+			Topic: s.opts.Topic,
+			Key:   s.key(agentID, event),
+			Value: value,
+			Headers: map[string]string{
+				"trusera-event-type": string(event.Type),
+				"content-type":       contentType,
+			},
+		})
+	}
+	if err := s.producer.Produce(ctx, msgs); err != nil {
+		return fmt.Errorf("failed to produce to %s: %w", s.opts.Topic, err)
+	}
+	return nil
+}
+
+// key returns the partition key of an event's record
+func (s *KafkaSink) key(agentID string, event Event) []byte {
+	switch s.opts.Key {
+	case KafkaKeyNone:
+		return nil
+	case KafkaKeySession:
+		if id, _ := event.Metadata["session_id"].(string); id != "" {
+			return []byte(id)
+		}
+	}
+	if agentID == "" {
+		return nil
+	}
+	return []byte(agentID)
+}
+
+// encode serializes an event
+func (s *KafkaSink) encode(agentID string, event Event) ([]byte, error) {
+	if s.opts.Serialization == KafkaJSON {
+		return marshalAgentEvent(agentID, event)
+	}
+
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var metadata []byte
+	if len(event.Metadata) > 0 {
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+	}
+
+	var b []byte
+	b = appendProtoBytes(b, 1, []byte(event.ID))
+	b = appendProtoBytes(b, 2, []byte(event.Type))
+	b = appendProtoBytes(b, 3, []byte(event.Name))
+	b = appendProtoBytes(b, 4, payload)
+	b = appendProtoBytes(b, 5, metadata)
+	b = appendProtoBytes(b, 6, []byte(event.Timestamp))
+	b = appendProtoBytes(b, 7, []byte(agentID))
+	return b, nil
+}
+
+// marshalAgentEvent encodes an event as JSON with an agent_id field, the
+// record format of the streaming sinks
+func marshalAgentEvent(agentID string, event Event) ([]byte, error) {
+	b, err := json.Marshal(struct {
+		Event
+		AgentID string `json:"agent_id,omitempty"`
+	}{event, agentID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	return b, nil
+}
+
+// appendProtoBytes appends a length-delimited protobuf field, omitting empty
+// values as proto3 does
+func appendProtoBytes(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
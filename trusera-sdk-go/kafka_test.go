@@ -0,0 +1,134 @@
+package trusera
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeProducer struct {
+	mu   sync.Mutex
+	msgs []KafkaMessage
+	err  error
+}
+
+func (p *fakeProducer) Produce(ctx context.Context, msgs []KafkaMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+// decodeProto reads the length-delimited fields of a protobuf message
+func decodeProto(t *testing.T, b []byte) map[int]string {
+	t.Helper()
+	fields := map[int]string{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		if tag&7 != 2 {
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+		size, n := binary.Uvarint(b)
+		b = b[n:]
+		fields[int(tag>>3)] = string(b[:size])
+		b = b[size:]
+	}
+	return fields
+}
+
+func TestKafkaSinkJSON(t *testing.T) {
+	api := newFlakyAPI(t)
+	producer := &fakeProducer{}
+	sink, err := NewKafkaSink(producer, KafkaOptions{Topic: "agent-events", Key: KafkaKeySession})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithAgentID("agent-7"),
+		WithFlushInterval(time.Hour),
+		WithSink("kafka", sink))
+	defer client.Close()
+
+	client.TrackCtx(ContextWithSessionID(context.Background(), "sess-1"), NewEvent(EventToolCall, "search"))
+	client.Track(NewEvent(EventLLMInvoke, "chat"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(producer.msgs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(producer.msgs))
+	}
+	first := producer.msgs[0]
+	if first.Topic != "agent-events" || string(first.Key) != "sess-1" ||
+		first.Headers["trusera-event-type"] != string(EventToolCall) || first.Headers["content-type"] != "application/json" {
+		t.Errorf("unexpected record %+v", first)
+	}
+	var decoded struct {
+		Name    string `json:"name"`
+		AgentID string `json:"agent_id"`
+	}
+	if err := json.Unmarshal(first.Value, &decoded); err != nil || decoded.Name != "search" || decoded.AgentID != "agent-7" {
+		t.Errorf("unexpected value %s (%v)", first.Value, err)
+	}
+	if key := string(producer.msgs[1].Key); key != "agent-7" {
+		t.Errorf("events without a session should fall back to the agent key, got %q", key)
+	}
+}
+
+func TestKafkaSinkProtobuf(t *testing.T) {
+	producer := &fakeProducer{}
+	sink, err := NewKafkaSink(producer, KafkaOptions{Topic: "t", Key: KafkaKeyNone, Serialization: KafkaProtobuf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := NewEvent(EventDataAccess, "users").WithPayload("rows", 3)
+	ctx := context.WithValue(context.Background(), agentIDKey, "agent-1")
+	if err := sink.Send(ctx, []Event{event}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := producer.msgs[0]
+	if msg.Key != nil || msg.Headers["content-type"] != "application/x-protobuf" {
+		t.Errorf("unexpected record %+v", msg)
+	}
+	fields := decodeProto(t, msg.Value)
+	want := map[int]string{1: event.ID, 2: "data_access", 3: "users", 4: `{"rows":3}`, 6: event.Timestamp, 7: "agent-1"}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("field %d = %q, want %q", k, fields[k], v)
+		}
+	}
+	if _, ok := fields[5]; ok {
+		t.Error("empty metadata should be omitted")
+	}
+}
+
+func TestKafkaSinkErrors(t *testing.T) {
+	if _, err := NewKafkaSink(&fakeProducer{}, KafkaOptions{}); err == nil {
+		t.Error("expected an error without a topic")
+	}
+	if _, err := NewKafkaSink(&fakeProducer{}, KafkaOptions{Topic: "t", Serialization: "avro"}); err == nil {
+		t.Error("expected an error for an unknown serialization")
+	}
+	if _, err := NewKafkaSink(nil, KafkaOptions{Topic: "t"}); err == nil {
+		t.Error("expected an error without a producer")
+	}
+
+	broker := errors.New("leader not available")
+	sink, _ := NewKafkaSink(&fakeProducer{err: broker}, KafkaOptions{Topic: "t"})
+	err := sink.Send(context.Background(), []Event{NewEvent(EventToolCall, "x")})
+	if !errors.Is(err, broker) || !strings.Contains(err.Error(), "failed to produce to t") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
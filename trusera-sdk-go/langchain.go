@@ -0,0 +1,366 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// LangChainOptions configures a LangChainCallback
+type LangChainOptions struct {
+	// CaptureInputs includes prompts, chain inputs, tool inputs and retriever
+	// queries in event payloads. These often contain sensitive data, so this
+	// is off by default.
+	CaptureInputs bool
+	// CaptureOutputs includes completions, chain outputs and tool outputs
+	CaptureOutputs bool
+}
+
+// LangChainCallback implements langchaingo's callbacks.Handler, tracking
+// chains, LLM calls, agent actions, tools and retrievers as events. Each
+// event records the enclosing chain's event ID in its parent_event_id
+// metadata field so a run can be reassembled as a tree.
+//
+// The SDK does not import langchaingo; instantiate it with langchaingo's
+// types so that the result satisfies callbacks.Handler:
+//
+//	handler := trusera.NewLangChainCallback[llms.MessageContent, *llms.ContentResponse,
+//		schema.AgentAction, schema.AgentFinish, schema.Document](truseraClient, trusera.LangChainOptions{})
+//	llm, err := openai.New(openai.WithCallback(handler))
+//
+// langchaingo does not pass run identifiers to callbacks, so nesting is
+// inferred from call order. Share a handler between concurrent runs only if
+// approximate parent links are acceptable.
+type LangChainCallback[M, R, A, F, D any] struct {
+	client *Client
+	opts   LangChainOptions
+
+	mu      sync.Mutex
+	stack   []*lcSpan
+	prompts []string // from HandleLLMStart, consumed by the next LLM span
+	tool    string   // tool named by the last agent action
+}
+
+// lcSpan is an event that is tracked once its matching end callback fires
+type lcSpan struct {
+	kind   string
+	event  Event
+	start  time.Time
+	chunks int
+}
+
+// NewLangChainCallback creates a callback handler that tracks to client
+func NewLangChainCallback[M, R, A, F, D any](client *Client, opts LangChainOptions) *LangChainCallback[M, R, A, F, D] {
+	return &LangChainCallback[M, R, A, F, D]{client: client, opts: opts}
+}
+
+// push starts a span nested under the innermost open span
+func (h *LangChainCallback[M, R, A, F, D]) push(kind string, event Event) *lcSpan {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	event = h.linkLocked(event)
+	span := &lcSpan{kind: kind, event: event, start: time.Now()}
+	h.stack = append(h.stack, span)
+	return span
+}
+
+// pop removes the innermost open span of the given kind
+func (h *LangChainCallback[M, R, A, F, D]) pop(kind string) *lcSpan {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		if h.stack[i].kind == kind {
+			span := h.stack[i]
+			h.stack = append(h.stack[:i], h.stack[i+1:]...)
+			return span
+		}
+	}
+	return nil
+}
+
+// linkLocked tags an event with the innermost open span as its parent
+func (h *LangChainCallback[M, R, A, F, D]) linkLocked(event Event) Event {
+	event = event.WithMetadata("source", "langchaingo")
+	if n := len(h.stack); n > 0 {
+		event = event.WithMetadata("parent_event_id", h.stack[n-1].event.ID)
+	}
+	return event
+}
+
+// end finishes the innermost span of kind and tracks it
+func (h *LangChainCallback[M, R, A, F, D]) end(kind string, fill func(Event) Event) {
+	span := h.pop(kind)
+	if span == nil {
+		return
+	}
+	event := span.event.WithPayload("duration_ms", float64(time.Since(span.start).Microseconds())/1000)
+	if span.chunks > 0 {
+		event = event.WithPayload("stream_chunks", span.chunks)
+	}
+	h.client.Track(fill(event))
+}
+
+// fail finishes the innermost span of kind with an error
+func (h *LangChainCallback[M, R, A, F, D]) fail(kind string, err error) {
+	h.end(kind, func(e Event) Event {
+		return e.WithPayload("error", err.Error())
+	})
+}
+
+// HandleText is a no-op; free-form text carries no structure worth tracking
+func (h *LangChainCallback[M, R, A, F, D]) HandleText(ctx context.Context, text string) {}
+
+// HandleLLMStart records prompts for the LLM call that follows
+func (h *LangChainCallback[M, R, A, F, D]) HandleLLMStart(ctx context.Context, prompts []string) {
+	if !h.opts.CaptureInputs {
+		return
+	}
+	h.mu.Lock()
+	h.prompts = prompts
+	h.mu.Unlock()
+}
+
+// HandleLLMGenerateContentStart opens an LLM invocation
+func (h *LangChainCallback[M, R, A, F, D]) HandleLLMGenerateContentStart(ctx context.Context, ms []M) {
+	event := NewEvent(EventLLMInvoke, "llm").WithPayload("messages_count", len(ms))
+
+	h.mu.Lock()
+	prompts := h.prompts
+	h.prompts = nil
+	h.mu.Unlock()
+
+	if h.opts.CaptureInputs {
+		if prompts != nil {
+			event = event.WithPayload("prompts", prompts)
+		}
+		event = event.WithPayload("messages", jsonValue(ms))
+	}
+	h.push("llm", event)
+}
+
+// HandleLLMGenerateContentEnd closes the LLM invocation with token usage
+func (h *LangChainCallback[M, R, A, F, D]) HandleLLMGenerateContentEnd(ctx context.Context, res R) {
+	h.end("llm", func(e Event) Event {
+		return h.describeResponse(e, res)
+	})
+}
+
+// HandleLLMError closes the LLM invocation with an error
+func (h *LangChainCallback[M, R, A, F, D]) HandleLLMError(ctx context.Context, err error) {
+	h.fail("llm", err)
+}
+
+// HandleChainStart opens a chain; events until the matching end nest under it
+func (h *LangChainCallback[M, R, A, F, D]) HandleChainStart(ctx context.Context, inputs map[string]any) {
+	event := NewEvent(EventDecision, "chain")
+	if h.opts.CaptureInputs {
+		event = event.WithPayload("inputs", inputs)
+	}
+	h.push("chain", event)
+}
+
+// HandleChainEnd closes the innermost chain
+func (h *LangChainCallback[M, R, A, F, D]) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	h.end("chain", func(e Event) Event {
+		if h.opts.CaptureOutputs {
+			e = e.WithPayload("outputs", outputs)
+		}
+		return e
+	})
+}
+
+// HandleChainError closes the innermost chain with an error
+func (h *LangChainCallback[M, R, A, F, D]) HandleChainError(ctx context.Context, err error) {
+	h.fail("chain", err)
+}
+
+// HandleToolStart opens a tool call, named after the preceding agent action
+func (h *LangChainCallback[M, R, A, F, D]) HandleToolStart(ctx context.Context, input string) {
+	h.mu.Lock()
+	name := h.tool
+	h.tool = ""
+	h.mu.Unlock()
+
+	if name == "" {
+		name = "tool"
+	}
+	event := NewEvent(EventToolCall, name)
+	if h.opts.CaptureInputs {
+		event = event.WithPayload("input", input)
+	}
+	h.push("tool", event)
+}
+
+// HandleToolEnd closes the innermost tool call
+func (h *LangChainCallback[M, R, A, F, D]) HandleToolEnd(ctx context.Context, output string) {
+	h.end("tool", func(e Event) Event {
+		if h.opts.CaptureOutputs {
+			e = e.WithPayload("output", output)
+		}
+		return e
+	})
+}
+
+// HandleToolError closes the innermost tool call with an error
+func (h *LangChainCallback[M, R, A, F, D]) HandleToolError(ctx context.Context, err error) {
+	h.fail("tool", err)
+}
+
+// HandleAgentAction tracks the agent's decision to call a tool
+func (h *LangChainCallback[M, R, A, F, D]) HandleAgentAction(ctx context.Context, action A) {
+	fields := jsonObject(action)
+	tool, _ := fields["Tool"].(string)
+
+	event := NewEvent(EventDecision, "agent_action").WithPayload("tool", tool)
+	if id, ok := fields["ToolID"].(string); ok && id != "" {
+		event = event.WithPayload("tool_id", id)
+	}
+	if h.opts.CaptureInputs {
+		event = event.WithPayload("tool_input", fields["ToolInput"])
+	}
+
+	h.mu.Lock()
+	h.tool = tool
+	event = h.linkLocked(event)
+	h.mu.Unlock()
+
+	h.client.Track(event)
+}
+
+// HandleAgentFinish tracks the agent's final answer
+func (h *LangChainCallback[M, R, A, F, D]) HandleAgentFinish(ctx context.Context, finish F) {
+	event := NewEvent(EventDecision, "agent_finish")
+	if h.opts.CaptureOutputs {
+		event = event.WithPayload("return_values", jsonObject(finish)["ReturnValues"])
+	}
+
+	h.mu.Lock()
+	event = h.linkLocked(event)
+	h.mu.Unlock()
+
+	h.client.Track(event)
+}
+
+// HandleRetrieverStart opens a retrieval
+func (h *LangChainCallback[M, R, A, F, D]) HandleRetrieverStart(ctx context.Context, query string) {
+	event := NewEvent(EventDataAccess, "retriever")
+	if h.opts.CaptureInputs {
+		event = event.WithPayload("query", query)
+	}
+	h.push("retriever", event)
+}
+
+// HandleRetrieverEnd closes the retrieval with the number of documents returned
+func (h *LangChainCallback[M, R, A, F, D]) HandleRetrieverEnd(ctx context.Context, query string, documents []D) {
+	h.end("retriever", func(e Event) Event {
+		return e.WithPayload("documents", len(documents))
+	})
+}
+
+// HandleStreamingFunc counts streamed chunks on the open LLM invocation
+func (h *LangChainCallback[M, R, A, F, D]) HandleStreamingFunc(ctx context.Context, chunk []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		if h.stack[i].kind == "llm" {
+			h.stack[i].chunks++
+			return
+		}
+	}
+}
+
+// describeResponse extracts stop reason, tool calls and token usage from an
+// llms.ContentResponse
+func (h *LangChainCallback[M, R, A, F, D]) describeResponse(event Event, res R) Event {
+	var resp struct {
+		Choices []struct {
+			Content        string
+			StopReason     string
+			GenerationInfo map[string]any
+			ToolCalls      []struct {
+				FunctionCall *struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			}
+		}
+	}
+	if raw, err := json.Marshal(res); err == nil {
+		_ = json.Unmarshal(raw, &resp)
+	}
+
+	event = event.WithPayload("choices", len(resp.Choices))
+	if len(resp.Choices) == 0 {
+		return event
+	}
+
+	first := resp.Choices[0]
+	if first.StopReason != "" {
+		event = event.WithPayload("finish_reason", first.StopReason)
+	}
+	if model, ok := first.GenerationInfo["model"].(string); ok && model != "" {
+		event.Name = model
+		event = event.WithPayload("model", model)
+	}
+
+	usage := make(map[string]any)
+	for key, val := range first.GenerationInfo {
+		if n, ok := val.(float64); ok {
+			usage[lcUsageKey(key)] = int(n)
+		}
+	}
+	if prompt, completion := tokenCounts(usage); prompt+completion > 0 {
+		event = event.WithPayload("prompt_tokens", prompt).
+			WithPayload("completion_tokens", completion).
+			WithPayload("total_tokens", prompt+completion)
+	}
+
+	var tools []string
+	for _, c := range resp.Choices {
+		for _, tc := range c.ToolCalls {
+			if tc.FunctionCall != nil {
+				tools = append(tools, tc.FunctionCall.Name)
+			}
+		}
+	}
+	if len(tools) > 0 {
+		event = event.WithPayload("tool_calls", tools)
+	}
+	if h.opts.CaptureOutputs {
+		event = event.WithPayload("completion", first.Content)
+	}
+	return event
+}
+
+// lcUsageKey maps langchaingo GenerationInfo keys such as PromptTokens and
+// InputTokens onto the snake_case names understood by tokenCounts
+func lcUsageKey(key string) string {
+	switch key {
+	case "PromptTokens":
+		return "prompt_tokens"
+	case "CompletionTokens":
+		return "completion_tokens"
+	case "InputTokens":
+		return "input_tokens"
+	case "OutputTokens":
+		return "output_tokens"
+	}
+	return key
+}
+
+// jsonValue converts v to its generic JSON representation
+func jsonValue(v any) any {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+// jsonObject converts a struct to a map keyed by its JSON field names
+func jsonObject(v any) map[string]any {
+	out, _ := jsonValue(v).(map[string]any)
+	return out
+}
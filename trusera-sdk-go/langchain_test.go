@@ -0,0 +1,196 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Stand-ins mirroring the langchaingo types the callback is instantiated with
+type (
+	lcMessageContent struct {
+		Role  string `json:"role"`
+		Parts []any  `json:"parts"`
+	}
+	lcFunctionCall struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	}
+	lcToolCall struct {
+		ID           string          `json:"id"`
+		Type         string          `json:"type"`
+		FunctionCall *lcFunctionCall `json:"function,omitempty"`
+	}
+	lcContentChoice struct {
+		Content        string
+		StopReason     string
+		GenerationInfo map[string]any
+		ToolCalls      []lcToolCall
+	}
+	lcContentResponse struct {
+		Choices []*lcContentChoice
+	}
+	lcAgentAction struct {
+		Tool      string
+		ToolInput string
+		Log       string
+		ToolID    string
+	}
+	lcAgentFinish struct {
+		ReturnValues map[string]any
+		Log          string
+	}
+	lcDocument struct {
+		PageContent string
+		Metadata    map[string]any
+		Score       float32
+	}
+)
+
+// lcHandler mirrors langchaingo's callbacks.Handler
+type lcHandler interface {
+	HandleText(ctx context.Context, text string)
+	HandleLLMStart(ctx context.Context, prompts []string)
+	HandleLLMGenerateContentStart(ctx context.Context, ms []lcMessageContent)
+	HandleLLMGenerateContentEnd(ctx context.Context, res *lcContentResponse)
+	HandleLLMError(ctx context.Context, err error)
+	HandleChainStart(ctx context.Context, inputs map[string]any)
+	HandleChainEnd(ctx context.Context, outputs map[string]any)
+	HandleChainError(ctx context.Context, err error)
+	HandleToolStart(ctx context.Context, input string)
+	HandleToolEnd(ctx context.Context, output string)
+	HandleToolError(ctx context.Context, err error)
+	HandleAgentAction(ctx context.Context, action lcAgentAction)
+	HandleAgentFinish(ctx context.Context, finish lcAgentFinish)
+	HandleRetrieverStart(ctx context.Context, query string)
+	HandleRetrieverEnd(ctx context.Context, query string, documents []lcDocument)
+	HandleStreamingFunc(ctx context.Context, chunk []byte)
+}
+
+func newTestLangChainCallback(c *Client, opts LangChainOptions) lcHandler {
+	return NewLangChainCallback[lcMessageContent, *lcContentResponse, lcAgentAction, lcAgentFinish, lcDocument](c, opts)
+}
+
+func TestLangChainCallbackHierarchy(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	h := newTestLangChainCallback(client, LangChainOptions{CaptureInputs: true, CaptureOutputs: true})
+	ctx := context.Background()
+
+	h.HandleChainStart(ctx, map[string]any{"input": "weather in Paris?"})
+	h.HandleLLMGenerateContentStart(ctx, []lcMessageContent{{Role: "human"}})
+	h.HandleStreamingFunc(ctx, []byte("Let"))
+	h.HandleStreamingFunc(ctx, []byte(" me check"))
+	h.HandleLLMGenerateContentEnd(ctx, &lcContentResponse{Choices: []*lcContentChoice{{
+		Content:    "Let me check",
+		StopReason: "tool_calls",
+		GenerationInfo: map[string]any{
+			"PromptTokens":     40,
+			"CompletionTokens": 8,
+		},
+		ToolCalls: []lcToolCall{{ID: "call_1", FunctionCall: &lcFunctionCall{Name: "weather"}}},
+	}}})
+	h.HandleAgentAction(ctx, lcAgentAction{Tool: "weather", ToolInput: "Paris", ToolID: "call_1"})
+	h.HandleToolStart(ctx, "Paris")
+	h.HandleToolEnd(ctx, "18C and sunny")
+	h.HandleAgentFinish(ctx, lcAgentFinish{ReturnValues: map[string]any{"output": "18C"}})
+	h.HandleChainEnd(ctx, map[string]any{"output": "18C"})
+
+	chain, ok := findEvent(client, "chain")
+	if !ok {
+		t.Fatal("expected chain event")
+	}
+	if _, ok := chain.Metadata["parent_event_id"]; ok {
+		t.Error("root chain should have no parent")
+	}
+	if chain.Payload["outputs"] == nil {
+		t.Error("expected chain outputs when CaptureOutputs is set")
+	}
+
+	llm, ok := findEvent(client, "llm")
+	if !ok {
+		t.Fatal("expected llm event")
+	}
+	checks := map[string]any{
+		"finish_reason":     "tool_calls",
+		"prompt_tokens":     40,
+		"completion_tokens": 8,
+		"total_tokens":      48,
+		"stream_chunks":     2,
+		"completion":        "Let me check",
+	}
+	for key, want := range checks {
+		if llm.Payload[key] != want {
+			t.Errorf("llm payload[%s] = %v, want %v", key, llm.Payload[key], want)
+		}
+	}
+
+	tool, ok := findEvent(client, "weather")
+	if !ok {
+		t.Fatal("expected tool event named after the agent action")
+	}
+	if tool.Type != EventToolCall || tool.Payload["output"] != "18C and sunny" {
+		t.Errorf("unexpected tool event: %+v", tool)
+	}
+
+	for _, name := range []string{"llm", "agent_action", "weather", "agent_finish"} {
+		e, _ := findEvent(client, name)
+		if e.Metadata["parent_event_id"] != chain.ID {
+			t.Errorf("%s not linked to chain: %v", name, e.Metadata)
+		}
+	}
+}
+
+func TestLangChainCallbackNestedChains(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	h := newTestLangChainCallback(client, LangChainOptions{})
+	ctx := context.Background()
+
+	h.HandleChainStart(ctx, nil)
+	h.HandleChainStart(ctx, nil)
+	h.HandleRetrieverStart(ctx, "query")
+	h.HandleRetrieverEnd(ctx, "query", []lcDocument{{PageContent: "a"}, {PageContent: "b"}})
+	h.HandleChainError(ctx, errors.New("inner failed"))
+	h.HandleChainEnd(ctx, nil)
+
+	client.mu.Lock()
+	events := append([]Event(nil), client.events...)
+	client.mu.Unlock()
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	retriever, inner, outer := events[0], events[1], events[2]
+	if retriever.Type != EventDataAccess || retriever.Payload["documents"] != 2 {
+		t.Errorf("unexpected retriever event: %+v", retriever)
+	}
+	if retriever.Payload["query"] != nil {
+		t.Error("query should not be captured by default")
+	}
+	if retriever.Metadata["parent_event_id"] != inner.ID {
+		t.Error("retriever should nest under the inner chain")
+	}
+	if inner.Metadata["parent_event_id"] != outer.ID {
+		t.Error("inner chain should nest under the outer chain")
+	}
+	if inner.Payload["error"] != "inner failed" {
+		t.Errorf("expected inner chain error, got %v", inner.Payload)
+	}
+}
+
+func TestLangChainCallbackUnmatchedEnd(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	h := newTestLangChainCallback(client, LangChainOptions{})
+	h.HandleToolEnd(context.Background(), "orphan")
+	h.HandleLLMError(context.Background(), errors.New("boom"))
+
+	if n := countEvents(client); n != 0 {
+		t.Errorf("expected unmatched end callbacks to be ignored, got %d events", n)
+	}
+}
@@ -0,0 +1,187 @@
+package trusera
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithLearning puts every interceptor built on the client in ModeLearn for
+// d, then writes the allow-list learned from their traffic to path, as by
+// WriteProposedPolicy. If the client is closed sooner, what was learned so
+// far is written then. Review the file, then load it with
+// NewClientFromConfig to move the agent to default-deny.
+func WithLearning(d time.Duration, path string) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.learner.period, c.learner.path = d, path
+		}
+	}
+}
+
+// learner records the hosts contacted by interceptors in ModeLearn
+type learner struct {
+	period time.Duration // Set by WithLearning
+	path   string
+
+	mu    sync.Mutex
+	hosts map[string]map[string]bool // Host to the methods used with it; "" for targets without one
+	first time.Time
+	last  time.Time
+	done  bool // The WithLearning period is over
+}
+
+// learning reports whether the WithLearning period is under way
+func (c *Client) learning() bool {
+	if c == nil || c.learner.period == 0 {
+		return false
+	}
+	c.learner.mu.Lock()
+	defer c.learner.mu.Unlock()
+	return !c.learner.done
+}
+
+// learn records that an interceptor in ModeLearn contacted a target
+func (c *Client) learn(method, target string) {
+	host, _ := targetAddr(target)
+	if c == nil || host == "" {
+		return
+	}
+	now := c.now()
+	l := &c.learner
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hosts == nil {
+		l.hosts = map[string]map[string]bool{}
+		l.first = now
+	}
+	if l.hosts[host] == nil {
+		l.hosts[host] = map[string]bool{}
+	}
+	l.hosts[host][strings.ToUpper(method)] = true
+	l.last = now
+}
+
+// ProposedPolicy returns an allow-list policy permitting exactly the hosts
+// contacted so far by interceptors in ModeLearn, each limited to the HTTP
+// methods it was used with. Hosts also reached without a method, such as
+// by DNS lookups or raw connections, allow every method.
+func (c *Client) ProposedPolicy() InterceptorConfig {
+	c.learner.mu.Lock()
+	defer c.learner.mu.Unlock()
+
+	hosts := make([]string, 0, len(c.learner.hosts))
+	for host := range c.learner.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	policy := InterceptorConfig{Enforcement: ModeAllowList, Rules: []Rule{}}
+	for _, host := range hosts {
+		rule := Rule{ID: "learned:" + host, Match: host, Action: RuleAllow}
+		if methods := c.learner.hosts[host]; !methods[""] {
+			for method := range methods {
+				rule.Methods = append(rule.Methods, method)
+			}
+			slices.Sort(rule.Methods)
+		}
+		policy.Rules = append(policy.Rules, rule)
+	}
+	return policy
+}
+
+// WriteProposedPolicy writes ProposedPolicy to path as a configuration
+// file: JSON when the name ends in .json, otherwise YAML. Only its
+// interceptor section is set.
+func (c *Client) WriteProposedPolicy(path string) error {
+	policy := c.ProposedPolicy()
+	c.learner.mu.Lock()
+	first, last := c.learner.first, c.learner.last
+	c.learner.mu.Unlock()
+
+	var data []byte
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var err error
+		data, err = json.MarshalIndent(map[string]any{"interceptor": policy}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode policy: %w", err)
+		}
+		data = append(data, '\n')
+	} else {
+		data = proposalYAML(policy, first, last)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write policy: %w", err)
+	}
+	return nil
+}
+
+// proposalYAML renders a proposed policy as a YAML configuration file.
+// Strings are written as JSON, which is valid YAML.
+func proposalYAML(policy InterceptorConfig, first, last time.Time) []byte {
+	quote := func(v any) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	var b strings.Builder
+	b.WriteString("# Allow-list proposed by Trusera learning mode")
+	if !first.IsZero() {
+		fmt.Fprintf(&b, " from traffic seen %s to %s", first.UTC().Format(time.RFC3339), last.UTC().Format(time.RFC3339))
+	}
+	b.WriteString("\ninterceptor:\n")
+	fmt.Fprintf(&b, "  enforcement: %s\n", policy.Enforcement)
+	if len(policy.Rules) == 0 {
+		b.WriteString("  rules: []\n")
+		return []byte(b.String())
+	}
+	b.WriteString("  rules:\n")
+	for _, rule := range policy.Rules {
+		fmt.Fprintf(&b, "    - id: %s\n", quote(rule.ID))
+		fmt.Fprintf(&b, "      match: %s\n", quote(rule.Match))
+		if len(rule.Methods) > 0 {
+			fmt.Fprintf(&b, "      methods: %s\n", quote(rule.Methods))
+		}
+		fmt.Fprintf(&b, "      action: %s\n", rule.Action)
+	}
+	return []byte(b.String())
+}
+
+// learnFor ends the WithLearning period after its duration, or when the
+// client closes, and writes the proposed policy
+func (c *Client) learnFor() {
+	defer c.wg.Done()
+
+	timer := time.NewTimer(c.learner.period)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.done:
+	}
+
+	defer func() {
+		c.learner.mu.Lock()
+		c.learner.done = true
+		c.learner.mu.Unlock()
+	}()
+	if c.learner.path == "" {
+		return
+	}
+	if err := c.WriteProposedPolicy(c.learner.path); err != nil {
+		c.handleError(err)
+		return
+	}
+	c.log(slog.LevelInfo, "wrote proposed policy", "path", c.learner.path)
+}
+
+// observe puts a verdict in ModeLearn, which never rejects
+func (v verdict) observe() verdict {
+	v.mode, v.learning = ModeLearn, true
+	return v
+}
@@ -0,0 +1,123 @@
+package trusera
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLearnMode(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:     ModeLearn,
+		Rules:           []Rule{{ID: "admin", Match: "/admin", Action: RuleBlock}},
+		ExcludePatterns: []string{"/healthz"},
+	})
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/a"}, {http.MethodPost, "/b"}, {http.MethodGet, "/admin"}, {http.MethodGet, "/healthz"},
+	} {
+		r, _ := http.NewRequest(req.method, backend.URL+req.path, nil)
+		resp, err := httpClient.Do(r)
+		if err != nil {
+			t.Fatalf("%s %s: expected learning mode to allow, got %v", req.method, req.path, err)
+		}
+		resp.Body.Close()
+	}
+	admin, _ := trackedEvent(client, "GET "+backend.URL+"/admin")
+	if admin.Payload["rule_id"] != "admin" || admin.Metadata["enforcement_mode"] != "learn" {
+		t.Errorf("expected the rule match recorded in learn mode, got %v %v", admin.Payload, admin.Metadata)
+	}
+
+	dns := &interceptingTransport{client: client, opts: InterceptorOptions{Enforcement: ModeLearn}}
+	dns.evaluate("api.example.com")
+	dns.evaluate("SELECT * FROM users")
+
+	want := InterceptorConfig{Enforcement: ModeAllowList, Rules: []Rule{
+		{ID: "learned:127.0.0.1", Match: "127.0.0.1", Methods: []string{"GET", "POST"}, Action: RuleAllow},
+		{ID: "learned:api.example.com", Match: "api.example.com", Action: RuleAllow},
+	}}
+	if got := client.ProposedPolicy(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got proposed policy %+v, want %+v", got, want)
+	}
+}
+
+func TestWithLearningWritesPolicy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithLearning(time.Hour, path))
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"/admin"},
+	})
+	resp, err := httpClient.Post(backend.URL+"/admin", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("expected the request allowed while learning, got %v", err)
+	}
+	resp.Body.Close()
+	client.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected the policy written on close: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# Allow-list proposed by Trusera learning mode from traffic seen ") {
+		t.Errorf("unexpected header in\n%s", data)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load the proposed policy: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(cfg.Interceptor, client.ProposedPolicy()) {
+		t.Errorf("loaded %+v, want %+v", cfg.Interceptor, client.ProposedPolicy())
+	}
+
+	jsonPath := filepath.Join(dir, "policy.json")
+	if err := client.WriteProposedPolicy(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := LoadConfig(jsonPath); err != nil || !reflect.DeepEqual(cfg.Interceptor, client.ProposedPolicy()) {
+		t.Errorf("loaded %+v, %v from JSON", cfg, err)
+	}
+}
+
+func TestWithLearningEnds(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithLearning(10*time.Millisecond, path))
+	defer client.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.learning() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the policy written when learning ends: %v", err)
+	}
+	if cfg, err := LoadConfig(path); err != nil || len(cfg.Interceptor.Rules) != 0 {
+		t.Errorf("expected an empty allow-list, got %+v, %v", cfg, err)
+	}
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"/admin"},
+	})
+	if _, err := httpClient.Get(backend.URL + "/admin"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected enforcement after learning, got %v", err)
+	}
+}
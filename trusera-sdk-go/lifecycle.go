@@ -0,0 +1,158 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// AgentState is an agent's lifecycle state as announced to Trusera
+type AgentState string
+
+const (
+	AgentActive       AgentState = "active"
+	AgentPaused       AgentState = "paused"
+	AgentDeregistered AgentState = "deregistered"
+)
+
+// ErrDeregistered is returned by lifecycle calls after Deregister
+var ErrDeregistered = errors.New("agent is deregistered")
+
+// Pause announces that the agent is pausing, e.g. for maintenance or while
+// waiting for a human, so that its silence is not mistaken for a failure.
+// The client keeps tracking events. The transition is also tracked as an
+// EventLifecycle.
+func (c *Client) Pause(ctx context.Context, reason string) error {
+	return c.announce(ctx, AgentPaused, reason)
+}
+
+// Resume announces that a paused agent is active again
+func (c *Client) Resume(ctx context.Context) error {
+	return c.announce(ctx, AgentActive, "")
+}
+
+// Deregister announces that the agent is shutting down permanently. The
+// EventLifecycle and every event tracked before it are flushed first, then
+// the agent is removed from Trusera. Close the client afterwards.
+func (c *Client) Deregister(ctx context.Context) error {
+	c.mu.Lock()
+	agentID, from := c.agentID, c.lifecycleLocked()
+	c.mu.Unlock()
+	if agentID == "" {
+		return errors.New("agent ID is required to deregister")
+	}
+	if from == AgentDeregistered {
+		return ErrDeregistered
+	}
+
+	c.Track(lifecycleEvent(from, AgentDeregistered, ""))
+	if err := c.FlushCtx(ctx); err != nil {
+		return fmt.Errorf("failed to flush before deregistering: %w", err)
+	}
+	if err := c.lifecycleRequest(ctx, http.MethodDelete, agentID, nil); err != nil {
+		return fmt.Errorf("failed to deregister agent: %w", err)
+	}
+
+	c.mu.Lock()
+	c.state = AgentDeregistered
+	c.mu.Unlock()
+	return nil
+}
+
+// State returns the lifecycle state last announced
+func (c *Client) State() AgentState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lifecycleLocked()
+}
+
+func (c *Client) lifecycleLocked() AgentState {
+	if c.state == "" {
+		return AgentActive
+	}
+	return c.state
+}
+
+// announce reports a transition to the API and tracks it
+func (c *Client) announce(ctx context.Context, to AgentState, reason string) error {
+	c.mu.Lock()
+	agentID, from := c.agentID, c.lifecycleLocked()
+	c.mu.Unlock()
+	if agentID == "" {
+		return fmt.Errorf("agent ID is required to announce %s", to)
+	}
+	if from == AgentDeregistered {
+		return ErrDeregistered
+	}
+
+	body, err := json.Marshal(map[string]string{"state": string(to), "reason": reason})
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if err := c.lifecycleRequest(ctx, http.MethodPut, agentID, body); err != nil {
+		return fmt.Errorf("failed to announce %s: %w", to, err)
+	}
+
+	c.mu.Lock()
+	c.state = to
+	c.mu.Unlock()
+	c.log(slog.LevelInfo, "agent state changed", "from", string(from), "to", string(to))
+	c.Track(lifecycleEvent(from, to, reason))
+	return nil
+}
+
+// lifecycleRequest sends a request about the agent: PUT of its state or
+// DELETE of the agent
+func (c *Client) lifecycleRequest(ctx context.Context, method, agentID string, body []byte) error {
+	path := "/v1/agents/" + url.PathEscape(agentID)
+	if method == http.MethodPut {
+		path += "/state"
+	}
+	endpoint, err := c.endpoint(path)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func lifecycleEvent(from, to AgentState, reason string) Event {
+	event := NewEvent(EventLifecycle, string(to)).
+		WithPayload("state", string(to)).
+		WithPayload("previous_state", string(from))
+	if reason != "" {
+		event = event.WithPayload("reason", reason)
+	}
+	return event
+}
+
+// emissionPaused reports whether the control plane paused event emission;
+// lifecycle events are still sent so the pause is visible
+func (c *Client) emissionPaused(event Event) bool {
+	p := c.remotePolicy.Load()
+	return p != nil && p.PauseEvents && event.Type != EventLifecycle
+}
@@ -0,0 +1,132 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		entry := r.Method + " " + r.URL.Path
+		if r.Method == http.MethodPut {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			entry += " " + body["state"] + " " + body["reason"]
+		}
+		requests = append(requests, entry)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithAgentID("a1"), WithFlushInterval(time.Hour))
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.Pause(ctx, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if client.State() != AgentPaused {
+		t.Errorf("expected paused, got %s", client.State())
+	}
+	events := trackedEvents(client, EventLifecycle)
+	if len(events) != 1 || events[0].Payload["state"] != "paused" || events[0].Payload["previous_state"] != "active" || events[0].Payload["reason"] != "maintenance" {
+		t.Errorf("expected the pause to be tracked, got %v", events)
+	}
+
+	if err := client.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	client.Track(NewEvent(EventToolCall, "search"))
+	if err := client.Deregister(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.State() != AgentDeregistered {
+		t.Errorf("expected deregistered, got %s", client.State())
+	}
+	if err := client.Resume(ctx); !errors.Is(err, ErrDeregistered) {
+		t.Errorf("expected ErrDeregistered, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"PUT /v1/agents/a1/state paused maintenance",
+		"PUT /v1/agents/a1/state active ",
+		"POST /v1/events",
+		"DELETE /v1/agents/a1",
+	}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected requests\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(requests, "\n"))
+	}
+}
+
+func TestLifecycleRequiresAgentID(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithHTTPTransport(&stubTransport{}))
+	defer client.Close()
+
+	if err := client.Pause(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "agent ID is required") {
+		t.Errorf("expected a missing agent ID error, got %v", err)
+	}
+	if client.State() != AgentActive {
+		t.Errorf("expected the state to be unchanged, got %s", client.State())
+	}
+}
+
+func TestRemotePauseEvents(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	client.ApplyPolicy(&RemotePolicy{PauseEvents: true})
+	client.Track(NewEvent(EventToolCall, "search"))
+	client.Track(lifecycleEvent(AgentActive, AgentPaused, "remote"))
+	if _, ok := trackedEvent(client, "search"); ok {
+		t.Error("expected events to be dropped while emission is paused")
+	}
+	if len(trackedEvents(client, EventLifecycle)) != 1 {
+		t.Error("expected lifecycle events to still be tracked")
+	}
+	if got := client.Stats().EventsDroppedPaused; got != 1 {
+		t.Errorf("expected 1 paused drop, got %d", got)
+	}
+
+	client.ApplyPolicy(&RemotePolicy{})
+	client.Track(NewEvent(EventToolCall, "search"))
+	if _, ok := trackedEvent(client, "search"); !ok {
+		t.Error("expected events to be tracked again after the pause was lifted")
+	}
+}
+
+func TestRemotePauseEnforcement(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := WrapHTTPClient(&http.Client{Transport: &stubTransport{}}, client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"evil.example"},
+	})
+
+	client.ApplyPolicy(&RemotePolicy{PauseEnforcement: true})
+	if _, err := httpClient.Get("https://evil.example/x"); err != nil {
+		t.Fatalf("expected the paused enforcement to allow the request, got %v", err)
+	}
+	e, _ := trackedEvent(client, "GET https://evil.example/x")
+	if e.Payload["blocked"] != true || e.Metadata["enforcement_paused"] != true || e.Metadata["enforcement_mode"] != "log" {
+		t.Errorf("expected the violation to be recorded as paused, got %v %v", e.Payload, e.Metadata)
+	}
+
+	client.ApplyPolicy(nil)
+	if _, err := httpClient.Get("https://evil.example/x"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected blocking to resume, got %v", err)
+	}
+}
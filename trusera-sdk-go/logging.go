@@ -0,0 +1,74 @@
+package trusera
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sends the SDK's own diagnostics to handler: failed background
+// flushes, syncs and reloads, dropped events, retries and circuit breaker
+// transitions. The SDK logs nothing by default. Records carry the
+// attribute component=trusera; handler's level, or WithLogLevel, controls
+// verbosity. For zap, logrus and others, pass their slog.Handler adapter.
+func WithLogger(handler slog.Handler) Option {
+	return func(c *Client) {
+		c.logHandler = handler
+	}
+}
+
+// WithLogLevel sets the minimum level of the SDK's log records, independent
+// of the handler's own level. Warn covers failures and lost events, Info
+// adds retries, recoveries and config reloads, and Debug adds flushed
+// batches and events dropped on purpose by hooks, sampling or a pause.
+func WithLogLevel(level slog.Leveler) Option {
+	return func(c *Client) {
+		c.logLevel = level
+	}
+}
+
+// newLogger builds the client's logger, or nil when logging is disabled
+func newLogger(handler slog.Handler, level slog.Leveler) *slog.Logger {
+	if handler == nil {
+		return nil
+	}
+	if level != nil {
+		handler = &levelHandler{Handler: handler, level: level}
+	}
+	return slog.New(handler).With("component", "trusera")
+}
+
+// levelHandler drops records below a minimum level
+type levelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(ctx, level)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// log writes a record if a logger is configured
+func (c *Client) log(level slog.Level, msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.Log(context.Background(), level, msg, args...)
+	}
+}
+
+// dropLevel is the level dropped events are logged at: intentional drops
+// are routine, lost events are not
+func dropLevel(reason DropReason) slog.Level {
+	switch reason {
+	case DropReasonFlushError, DropReasonOverflow, DropReasonSchema, DropReasonRateLimited:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
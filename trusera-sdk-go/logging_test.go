@@ -0,0 +1,98 @@
+package trusera
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logBuffer collects JSON log records written from any goroutine
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) records(t *testing.T) []map[string]any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	logs := &logBuffer{}
+	sink := &memorySink{}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(sink),
+		WithMaxBufferSize(1),
+		WithOverflowPolicy(DropNewest),
+		WithLogger(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	)
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "kept"))
+	client.Track(NewEvent(EventToolCall, "overflow"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := logs.records(t)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %v", records)
+	}
+	if r := records[0]; r["level"] != "WARN" || r["msg"] != "event dropped" || r["reason"] != "overflow" ||
+		r["event_name"] != "overflow" || r["component"] != "trusera" {
+		t.Errorf("unexpected drop record: %v", r)
+	}
+	if r := records[1]; r["level"] != "DEBUG" || r["msg"] != "flushed events" || r["count"] != 1.0 {
+		t.Errorf("unexpected flush record: %v", r)
+	}
+}
+
+func TestWithLogLevel(t *testing.T) {
+	logs := &logBuffer{}
+	sink := &memorySink{}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(sink),
+		WithLogger(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		WithLogLevel(slog.LevelWarn),
+	)
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if records := logs.records(t); len(records) != 0 {
+		t.Errorf("expected debug records to be filtered, got %v", records)
+	}
+
+	client.handleError(errors.New("sync failed"))
+	records := logs.records(t)
+	if len(records) != 1 || records[0]["level"] != "WARN" || records[0]["error"] != "sync failed" {
+		t.Errorf("expected the background error to be logged, got %v", records)
+	}
+}
@@ -0,0 +1,86 @@
+package trusera
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// LogSink writes each event as a structured log record, so that a log
+// collector, e.g. on Kubernetes, picks events up from stdout without the
+// pod reaching the network. Records have the message "trusera event", the
+// event's timestamp as their time and these attributes:
+//
+//	trusera.event_id, trusera.event_type, trusera.event_name, trusera.agent_id,
+//	trusera.payload (group), trusera.metadata (group)
+type LogSink struct {
+	logger *slog.Logger
+}
+
+// NewLogSink creates a sink writing to handler. A nil handler writes JSON
+// lines to stdout.
+func NewLogSink(handler slog.Handler) *LogSink {
+	if handler == nil {
+		handler = slog.NewJSONHandler(os.Stdout, nil)
+	}
+	return &LogSink{logger: slog.New(handler)}
+}
+
+// WithStdoutSink adds a secondary sink named "stdout" that writes events as
+// JSON lines to stdout. To log events instead of sending them to the API,
+// use WithPrimarySink(NewLogSink(nil)).
+func WithStdoutSink() Option {
+	return WithSink("stdout", NewLogSink(nil))
+}
+
+// Send logs a batch of events
+func (s *LogSink) Send(ctx context.Context, events []Event) error {
+	agentID := AgentIDFromContext(ctx)
+	handler := s.logger.Handler()
+	if !handler.Enabled(ctx, slog.LevelInfo) {
+		return nil
+	}
+
+	for _, event := range events {
+		ts, err := time.Parse(time.RFC3339Nano, event.Timestamp)
+		if err != nil {
+			ts = time.Now()
+		}
+		record := slog.NewRecord(ts, slog.LevelInfo, "trusera event", 0)
+		record.AddAttrs(
+			slog.String("trusera.event_id", event.ID),
+			slog.String("trusera.event_type", string(event.Type)),
+			slog.String("trusera.event_name", event.Name),
+		)
+		if agentID != "" {
+			record.AddAttrs(slog.String("trusera.agent_id", agentID))
+		}
+		if len(event.Payload) > 0 {
+			record.AddAttrs(mapGroup("trusera.payload", event.Payload))
+		}
+		if len(event.Metadata) > 0 {
+			record.AddAttrs(mapGroup("trusera.metadata", event.Metadata))
+		}
+		if err := handler.Handle(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapGroup converts a map to a group attribute with sorted keys
+func mapGroup(name string, m map[string]any) slog.Attr {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, m[k]))
+	}
+	return slog.Group(name, attrs...)
+}
@@ -0,0 +1,63 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogSinkWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink(slog.NewJSONHandler(&buf, nil))
+	client := NewClient("test-key",
+		WithAgentID("agent-1"),
+		WithFlushInterval(time.Hour),
+		WithPrimarySink(sink))
+	defer client.Close()
+
+	event := NewEvent(EventToolCall, "search").WithPayload("query", "weather")
+	event.Timestamp = "2026-01-02T03:04:05Z"
+	client.Track(event)
+	client.Track(NewEvent(EventLLMInvoke, "chat"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var record struct {
+		Time    string         `json:"time"`
+		Msg     string         `json:"msg"`
+		ID      string         `json:"trusera.event_id"`
+		Type    string         `json:"trusera.event_type"`
+		AgentID string         `json:"trusera.agent_id"`
+		Payload map[string]any `json:"trusera.payload"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Msg != "trusera event" || record.ID != event.ID || record.Type != "tool_call" ||
+		record.AgentID != "agent-1" || record.Payload["query"] != "weather" {
+		t.Errorf("unexpected record %s", lines[0])
+	}
+	if !strings.HasPrefix(record.Time, "2026-01-02T03:04:05") {
+		t.Errorf("expected the event timestamp as the record time, got %q", record.Time)
+	}
+}
+
+func TestLogSinkRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if err := sink.Send(context.Background(), []Event{NewEvent(EventToolCall, "x")}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing below the handler's level, got %q", buf.String())
+	}
+}
@@ -0,0 +1,93 @@
+package trusera
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Manifest declares an agent's capabilities: the tools, models and
+// datasets it uses, the domains it may call and the team that owns it. It
+// is sent with RegisterAgent and becomes the baseline the server compares
+// the agent's runtime behavior against.
+type Manifest struct {
+	Owner       string        `json:"owner,omitempty"`   // Team responsible for the agent
+	Version     string        `json:"version,omitempty"` // Agent version
+	Description string        `json:"description,omitempty"`
+	Tools       []ToolInfo    `json:"tools,omitempty"`
+	Models      []ModelInfo   `json:"models,omitempty"`
+	Datasets    []DatasetInfo `json:"datasets,omitempty"`
+	Domains     []string      `json:"domains,omitempty"` // Hosts the agent may call; "*.example.com" covers subdomains
+}
+
+// WithManifest declares the agent's capabilities. Its tools, models and
+// datasets are registered with the client as by RegisterTool, RegisterModel
+// and RegisterDataset, so tracked events are checked against them locally,
+// and API calls to hosts outside Domains get payload domain_declared=false.
+// RegisterAgent sends the manifest, or returns the error of an invalid one.
+func WithManifest(m Manifest) Option {
+	return func(c *Client) {
+		c.manifest = &m
+	}
+}
+
+// applyManifest registers the manifest's tools, models and datasets
+func (c *Client) applyManifest() error {
+	var errs []error
+	for _, t := range c.manifest.Tools {
+		errs = append(errs, c.RegisterTool(t))
+	}
+	for _, m := range c.manifest.Models {
+		errs = append(errs, c.RegisterModel(m))
+	}
+	for _, d := range c.manifest.Datasets {
+		errs = append(errs, c.RegisterDataset(d))
+	}
+	for _, domain := range c.manifest.Domains {
+		if domain == "" || strings.Contains(domain, "/") {
+			errs = append(errs, fmt.Errorf("invalid domain %q", domain))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid manifest: %w", err)
+	}
+	return nil
+}
+
+// checkDomain flags an API call to a host the manifest does not declare
+func (c *Client) checkDomain(event Event) Event {
+	if event.Type != EventAPICall || c.manifest == nil || len(c.manifest.Domains) == 0 {
+		return event
+	}
+	raw, _ := event.Payload["url"].(string)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return event
+	}
+	if !domainDeclared(c.manifest.Domains, u.Host) {
+		event = event.WithPayload("domain_declared", false)
+	}
+	return event
+}
+
+// domainDeclared reports whether host, with or without a port, matches one
+// of the domains
+func domainDeclared(domains []string, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if suffix, ok := strings.CutPrefix(d, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == d {
+			return true
+		}
+	}
+	return false
+}
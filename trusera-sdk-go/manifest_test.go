@@ -0,0 +1,101 @@
+package trusera
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testManifest = Manifest{
+	Owner:   "payments-team",
+	Version: "1.4.0",
+	Tools: []ToolInfo{{
+		Name:        "refund",
+		Risk:        RiskHigh,
+		InputSchema: json.RawMessage(`{"type": "object", "required": ["amount"]}`),
+	}},
+	Models:   []ModelInfo{{Name: "gpt-4o", Provider: "openai"}},
+	Datasets: []DatasetInfo{{Name: "orders", Source: "s3://orders"}},
+	Domains:  []string{"api.openai.com", "*.stripe.com"},
+}
+
+func TestMustRegisterAndInterceptSendsManifest(t *testing.T) {
+	var got struct {
+		Name     string   `json:"name"`
+		Manifest Manifest `json:"manifest"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/agents" {
+			json.NewDecoder(r.Body).Decode(&got)
+			json.NewEncoder(w).Encode(map[string]string{"agent_id": "agent-1"})
+		}
+	}))
+	defer server.Close()
+
+	client, _, err := MustRegisterAndIntercept("test-key", "payments", "custom", InterceptorOptions{},
+		WithBaseURL(server.URL), WithFlushInterval(time.Hour), WithManifest(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	m := got.Manifest
+	if got.Name != "payments" || m.Owner != "payments-team" || m.Version != "1.4.0" {
+		t.Errorf("unexpected registration: %+v", got)
+	}
+	if len(m.Tools) != 1 || m.Tools[0].Risk != RiskHigh || !strings.Contains(string(m.Tools[0].InputSchema), "amount") {
+		t.Errorf("expected the tool with its schema, got %+v", m.Tools)
+	}
+	if len(m.Models) != 1 || len(m.Datasets) != 1 || len(m.Domains) != 2 {
+		t.Errorf("expected the models, datasets and domains, got %+v", m)
+	}
+}
+
+func TestManifestRegistersCapabilities(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithManifest(testManifest))
+	defer client.Close()
+
+	if len(client.Tools()) != 1 || len(client.Models()) != 1 || len(client.Datasets()) != 1 {
+		t.Fatalf("expected the manifest to be registered, got %d tools, %d models, %d datasets",
+			len(client.Tools()), len(client.Models()), len(client.Datasets()))
+	}
+
+	client.Track(NewEvent(EventToolCall, "refund").WithPayload("arguments", map[string]any{}))
+	if e, _ := trackedEvent(client, "refund"); e.Payload["risk_tier"] != "high" || e.Payload["schema_violations"] == nil {
+		t.Errorf("expected the tool call to be checked against the manifest, got %v", e.Payload)
+	}
+
+	for url, declared := range map[string]bool{
+		"https://api.openai.com/v1/chat/completions": true,
+		"https://api.stripe.com:443/v1/refunds":      true,
+		"https://stripe.com/":                        false,
+		"https://pastebin.com/raw/1":                 false,
+	} {
+		client.Track(NewEvent(EventAPICall, url).WithPayload("url", url))
+		e, _ := trackedEvent(client, url)
+		if _, flagged := e.Payload["domain_declared"]; flagged == declared {
+			t.Errorf("%s: expected declared=%v, got %v", url, declared, e.Payload)
+		}
+	}
+}
+
+func TestInvalidManifestFailsRegistration(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithManifest(Manifest{
+		Tools:   []ToolInfo{{Name: "refund", InputSchema: json.RawMessage(`{"type": 1}`)}},
+		Domains: []string{"https://api.openai.com/"},
+	}))
+	defer client.Close()
+
+	_, err := client.RegisterAgent("payments", "custom")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"invalid manifest", "input schema of refund", `invalid domain "https://api.openai.com/"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,694 @@
+package trusera
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// mcpBlockedCode is the JSON-RPC error code returned for blocked tool calls
+const mcpBlockedCode = -32001
+
+// MCPOptions configures MCP instrumentation
+type MCPOptions struct {
+	Enforcement  EnforcementMode
+	BlockedTools []string // Tool names to enforce on; path.Match globs such as "fs_*" are allowed
+
+	// CaptureArguments includes tools/call arguments in tool call events
+	CaptureArguments bool
+}
+
+// MCPInterceptor tracks Model Context Protocol traffic: tool discovery
+// (tools/list), tool invocations (tools/call) and resource reads
+// (resources/read). It speaks the JSON-RPC wire format, so it works with any
+// MCP SDK by wrapping the stdio stream or HTTP transport underneath it.
+type MCPInterceptor struct {
+	client *Client
+	opts   MCPOptions
+}
+
+// NewMCPInterceptor creates an MCP interceptor
+func NewMCPInterceptor(truseraClient *Client, opts MCPOptions) *MCPInterceptor {
+	return &MCPInterceptor{client: truseraClient, opts: opts}
+}
+
+// WrapClientConn wraps the newline-delimited JSON-RPC stream an MCP client
+// uses to talk to a server, such as a subprocess's stdin/stdout. In block
+// mode a blocked tools/call is never sent; the client receives a JSON-RPC
+// error response instead.
+func (m *MCPInterceptor) WrapClientConn(rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	pr, pw := io.Pipe()
+	c := &mcpClientConn{rwc: rwc, s: m.newSession("client"), pr: pr, pw: pw}
+	c.rcond = sync.NewCond(&c.rmu)
+	return c
+}
+
+// WrapServerConn wraps the newline-delimited JSON-RPC stream an MCP server
+// reads requests from and writes responses to. In block mode a blocked
+// tools/call never reaches the server's handlers; the client receives a
+// JSON-RPC error response instead.
+func (m *MCPInterceptor) WrapServerConn(rwc io.ReadWriteCloser) io.ReadWriteCloser {
+	s := m.newSession("server")
+	return &mcpServerConn{rwc: rwc, s: s, obs: s.observer(), tmp: make([]byte, 32*1024)}
+}
+
+// WrapTransport returns a RoundTripper for MCP clients using the Streamable
+// HTTP transport. A nil base uses http.DefaultTransport.
+func (m *MCPInterceptor) WrapTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &mcpTransport{base: base, m: m}
+}
+
+// Handler wraps an MCP server's Streamable HTTP handler
+func (m *MCPInterceptor) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		s := m.newSession("server")
+		if reply, blocked := s.request(body); blocked {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(reply)
+			return
+		}
+
+		mw := &mcpResponseWriter{ResponseWriter: w, obs: s.observer()}
+		next.ServeHTTP(mw, r)
+		mw.obs.flush()
+	})
+}
+
+// isBlockedTool reports whether a tool name matches BlockedTools
+func (m *MCPInterceptor) isBlockedTool(name string) bool {
+	for _, pattern := range m.opts.BlockedTools {
+		if pattern == name {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mcpMessage is a JSON-RPC 2.0 request, notification or response
+type mcpMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// mcpPending is a tracked request awaiting its response
+type mcpPending struct {
+	method string
+	event  Event
+	start  time.Time
+}
+
+// mcpSession correlates requests with responses on one connection
+type mcpSession struct {
+	m    *MCPInterceptor
+	side string
+
+	mu      sync.Mutex
+	pending map[string]mcpPending
+}
+
+func (m *MCPInterceptor) newSession(side string) *mcpSession {
+	return &mcpSession{m: m, side: side, pending: make(map[string]mcpPending)}
+}
+
+// request inspects an outgoing (client) or incoming (server) message. When a
+// tools/call is blocked in block mode it returns the error reply to deliver
+// in place of forwarding the request.
+func (s *mcpSession) request(line []byte) ([]byte, bool) {
+	if batch, ok := mcpBatch(line); ok {
+		return s.requestBatch(batch)
+	}
+	return s.requestOne(line)
+}
+
+// requestBatch inspects each message of a JSON-RPC batch. A batch is
+// forwarded whole or not at all, so when a call in it is blocked the batch is
+// answered in full: blocked calls get the blocked error and the other
+// requests an error saying the batch was rejected.
+func (s *mcpSession) requestBatch(batch []json.RawMessage) ([]byte, bool) {
+	replies := make([][]byte, len(batch))
+	rejected := false
+	for i, line := range batch {
+		if reply, blocked := s.requestOne(line); blocked {
+			replies[i] = reply
+			rejected = true
+		}
+	}
+	if !rejected {
+		return nil, false
+	}
+
+	out := make([]json.RawMessage, 0, len(batch))
+	for i, line := range batch {
+		reply := replies[i]
+		if reply == nil {
+			var msg mcpMessage
+			if json.Unmarshal(line, &msg) != nil || msg.Method == "" || len(msg.ID) == 0 {
+				continue
+			}
+			reply = mcpErrorReply(msg.ID, mcpBlockedCode, "batch rejected by Trusera policy")
+			s.response(reply)
+		}
+		out = append(out, bytes.TrimSpace(reply))
+	}
+	reply, _ := json.Marshal(out)
+	return append(reply, '\n'), true
+}
+
+// requestOne inspects a single message of a request line
+func (s *mcpSession) requestOne(line []byte) ([]byte, bool) {
+	var msg mcpMessage
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Method == "" || len(msg.ID) == 0 {
+		return nil, false
+	}
+
+	var event Event
+	switch msg.Method {
+	case "tools/list":
+		event = NewEvent(EventAPICall, "mcp tools/list")
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments any    `json:"arguments"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		event = NewEvent(EventToolCall, params.Name).WithPayload("tool", params.Name)
+		if s.m.opts.CaptureArguments {
+			event = event.WithPayload("arguments", params.Arguments)
+		}
+
+		blocked := s.m.isBlockedTool(params.Name)
+		event = event.WithPayload("blocked", blocked).
+			WithMetadata("enforcement_mode", string(s.m.opts.Enforcement))
+		if blocked {
+			event = event.WithPayload("enforcement_action", s.m.opts.Enforcement.blockAction())
+			switch s.m.opts.Enforcement {
+			case ModeBlock, ModeAllowList:
+				s.m.client.Track(s.describe(event, msg.Method))
+				return mcpErrorReply(msg.ID, mcpBlockedCode, "request blocked by Trusera policy"), true
+			case ModeWarn:
+				event = event.WithMetadata("warning", "MCP tool matches blocked tools but allowed in warn mode")
+			}
+		} else {
+			event = event.WithPayload("enforcement_action", "allowed")
+		}
+	case "resources/read":
+		var params struct {
+			URI string `json:"uri"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		event = NewEvent(EventDataAccess, params.URI).WithPayload("uri", params.URI)
+	default:
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.pending[string(msg.ID)] = mcpPending{method: msg.Method, event: s.describe(event, msg.Method), start: time.Now()}
+	s.mu.Unlock()
+	return nil, false
+}
+
+// response completes the tracked requests a response or batch of responses
+// belongs to
+func (s *mcpSession) response(line []byte) {
+	if batch, ok := mcpBatch(line); ok {
+		for _, msg := range batch {
+			s.responseOne(msg)
+		}
+		return
+	}
+	s.responseOne(line)
+}
+
+// responseOne completes the tracked request a single response belongs to
+func (s *mcpSession) responseOne(line []byte) {
+	var msg mcpMessage
+	if err := json.Unmarshal(line, &msg); err != nil || msg.Method != "" || len(msg.ID) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.pending[string(msg.ID)]
+	delete(s.pending, string(msg.ID))
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	event := p.event.WithPayload("duration_ms", float64(time.Since(p.start).Microseconds())/1000)
+	if msg.Error != nil {
+		event = event.WithPayload("error", msg.Error.Message).
+			WithPayload("error_code", msg.Error.Code)
+		s.m.client.Track(event)
+		return
+	}
+
+	switch p.method {
+	case "tools/list":
+		var result struct {
+			Tools []struct {
+				Name string `json:"name"`
+			} `json:"tools"`
+		}
+		_ = json.Unmarshal(msg.Result, &result)
+		names := make([]string, 0, len(result.Tools))
+		for _, t := range result.Tools {
+			names = append(names, t.Name)
+		}
+		event = event.WithPayload("tools", names).WithPayload("tool_count", len(names))
+	case "tools/call":
+		var result struct {
+			IsError bool `json:"isError"`
+		}
+		_ = json.Unmarshal(msg.Result, &result)
+		event = event.WithPayload("is_error", result.IsError)
+	case "resources/read":
+		var result struct {
+			Contents []struct {
+				MimeType string `json:"mimeType"`
+			} `json:"contents"`
+		}
+		_ = json.Unmarshal(msg.Result, &result)
+		var mimeTypes []string
+		for _, c := range result.Contents {
+			if c.MimeType != "" {
+				mimeTypes = append(mimeTypes, c.MimeType)
+			}
+		}
+		event = event.WithPayload("contents", len(result.Contents))
+		if len(mimeTypes) > 0 {
+			event = event.WithPayload("mime_types", mimeTypes)
+		}
+	}
+	s.m.client.Track(event)
+}
+
+// describe adds the fields common to every MCP event
+func (s *mcpSession) describe(event Event, method string) Event {
+	return event.WithPayload("protocol", "mcp").
+		WithPayload("method", method).
+		WithPayload("side", s.side)
+}
+
+// observer returns a line observer that completes requests from responses,
+// accepting both plain JSON and server-sent event framing
+func (s *mcpSession) observer() *mcpObserver {
+	return &mcpObserver{fn: s.response}
+}
+
+// mcpBatch splits a JSON-RPC batch into its messages, reporting false for a
+// line that is not a batch
+func mcpBatch(line []byte) ([]json.RawMessage, bool) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || line[0] != '[' {
+		return nil, false
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		return nil, false
+	}
+	return batch, true
+}
+
+// mcpRequestIDs returns the IDs of the requests in a message or batch
+func mcpRequestIDs(line []byte) []json.RawMessage {
+	batch, ok := mcpBatch(line)
+	if !ok {
+		batch = []json.RawMessage{line}
+	}
+	var ids []json.RawMessage
+	for _, raw := range batch {
+		var msg mcpMessage
+		if json.Unmarshal(raw, &msg) == nil && msg.Method != "" && len(msg.ID) > 0 {
+			ids = append(ids, msg.ID)
+		}
+	}
+	return ids
+}
+
+// mcpErrorReply builds a JSON-RPC error response line
+func mcpErrorReply(id json.RawMessage, code int, message string) []byte {
+	reply, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error":   map[string]any{"code": code, "message": message},
+	})
+	return append(reply, '\n')
+}
+
+// jsonLines splits a byte stream into newline-terminated lines
+type jsonLines struct {
+	buf []byte
+}
+
+// split appends p and returns the complete lines, newline included
+func (l *jsonLines) split(p []byte) [][]byte {
+	l.buf = append(l.buf, p...)
+	var lines [][]byte
+	for {
+		idx := bytes.IndexByte(l.buf, '\n')
+		if idx < 0 {
+			return lines
+		}
+		lines = append(lines, append([]byte(nil), l.buf[:idx+1]...))
+		l.buf = l.buf[idx+1:]
+	}
+}
+
+// rest returns and clears any unterminated trailing data
+func (l *jsonLines) rest() []byte {
+	rest := l.buf
+	l.buf = nil
+	return rest
+}
+
+// mcpObserver feeds each JSON message in a stream to fn without altering it
+type mcpObserver struct {
+	mu    sync.Mutex
+	lines jsonLines
+	fn    func([]byte)
+}
+
+func (o *mcpObserver) feed(p []byte) {
+	o.mu.Lock()
+	lines := o.lines.split(p)
+	o.mu.Unlock()
+	for _, line := range lines {
+		o.handle(line)
+	}
+}
+
+// flush handles a trailing message that was not newline-terminated
+func (o *mcpObserver) flush() {
+	o.mu.Lock()
+	rest := o.lines.rest()
+	o.mu.Unlock()
+	o.handle(rest)
+}
+
+func (o *mcpObserver) handle(line []byte) {
+	line = bytes.TrimSpace(line)
+	if bytes.HasPrefix(line, []byte("data:")) {
+		line = bytes.TrimSpace(line[len("data:"):])
+	}
+	if len(line) > 0 && line[0] == '{' {
+		o.fn(line)
+	}
+}
+
+// mcpClientConn observes requests written by a client and responses read
+// back. Reads are served from a pipe so that error replies for blocked calls
+// can be delivered to a reader that is already waiting. The replies are
+// queued and delivered in order by a single goroutine, so that Write does
+// not wait for the reader.
+type mcpClientConn struct {
+	rwc io.ReadWriteCloser
+	s   *mcpSession
+
+	wmu    sync.Mutex
+	wlines jsonLines
+
+	once sync.Once
+	pr   *io.PipeReader
+	pw   *io.PipeWriter
+	pmu  sync.Mutex
+
+	replyOnce sync.Once
+	rmu       sync.Mutex
+	rcond     *sync.Cond // signalled when replies grow or the conn closes
+	replies   [][]byte   // Blocked replies not yet delivered, oldest first
+	closed    bool
+}
+
+func (c *mcpClientConn) Read(p []byte) (int, error) {
+	c.once.Do(func() { go c.pump() })
+	return c.pr.Read(p)
+}
+
+// pump copies server output into the pipe one complete line at a time
+func (c *mcpClientConn) pump() {
+	var lines jsonLines
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := c.rwc.Read(buf)
+		for _, line := range lines.split(buf[:n]) {
+			c.s.response(line)
+			if !c.deliver(line) {
+				c.stopReplies()
+				return
+			}
+		}
+		if err != nil {
+			if rest := lines.rest(); len(rest) > 0 {
+				c.s.response(rest)
+				c.deliver(rest)
+			}
+			c.pw.CloseWithError(err)
+			c.stopReplies()
+			return
+		}
+	}
+}
+
+// deliver writes a line to the reader, reporting false once the pipe is closed
+func (c *mcpClientConn) deliver(line []byte) bool {
+	c.pmu.Lock()
+	defer c.pmu.Unlock()
+	_, err := c.pw.Write(line)
+	return err == nil
+}
+
+func (c *mcpClientConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	for _, line := range c.wlines.split(p) {
+		if reply, blocked := c.s.request(line); blocked {
+			c.once.Do(func() { go c.pump() })
+			c.replyOnce.Do(func() { go c.reply() })
+			c.rmu.Lock()
+			if !c.closed {
+				c.replies = append(c.replies, reply)
+				c.rcond.Signal()
+			}
+			c.rmu.Unlock()
+			continue
+		}
+		if _, err := c.rwc.Write(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// reply delivers the queued blocked replies until the conn is closed
+func (c *mcpClientConn) reply() {
+	for {
+		c.rmu.Lock()
+		for len(c.replies) == 0 && !c.closed {
+			c.rcond.Wait()
+		}
+		if c.closed {
+			c.rmu.Unlock()
+			return
+		}
+		line := c.replies[0]
+		c.replies[0] = nil
+		c.replies = c.replies[1:]
+		c.rmu.Unlock()
+
+		if !c.deliver(line) {
+			return
+		}
+	}
+}
+
+// stopReplies discards the queued replies and ends reply
+func (c *mcpClientConn) stopReplies() {
+	c.rmu.Lock()
+	c.closed, c.replies = true, nil
+	c.rcond.Broadcast()
+	c.rmu.Unlock()
+}
+
+func (c *mcpClientConn) Close() error {
+	c.stopReplies()
+	err := c.rwc.Close()
+	c.pr.Close()
+	return err
+}
+
+// mcpServerConn observes requests read by a server and responses it writes,
+// dropping blocked requests before the server sees them
+type mcpServerConn struct {
+	rwc io.ReadWriteCloser
+	s   *mcpSession
+
+	rmu    sync.Mutex
+	rlines jsonLines
+	tmp    []byte
+	out    []byte
+	rerr   error
+
+	wmu sync.Mutex
+	obs *mcpObserver
+}
+
+func (c *mcpServerConn) Read(p []byte) (int, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	for len(c.out) == 0 {
+		if c.rerr != nil {
+			return 0, c.rerr
+		}
+
+		n, err := c.rwc.Read(c.tmp)
+		for _, line := range c.rlines.split(c.tmp[:n]) {
+			if reply, blocked := c.s.request(line); blocked {
+				c.wmu.Lock()
+				_, werr := c.rwc.Write(reply)
+				c.wmu.Unlock()
+				if werr != nil {
+					return 0, werr
+				}
+				continue
+			}
+			c.out = append(c.out, line...)
+		}
+		if err != nil {
+			c.out = append(c.out, c.rlines.rest()...)
+			c.rerr = err
+		}
+	}
+
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+func (c *mcpServerConn) Write(p []byte) (int, error) {
+	c.obs.feed(p)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.rwc.Write(p)
+}
+
+func (c *mcpServerConn) Close() error {
+	return c.rwc.Close()
+}
+
+// mcpTransport instruments Streamable HTTP client requests
+type mcpTransport struct {
+	base http.RoundTripper
+	m    *MCPInterceptor
+}
+
+func (t *mcpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodPost || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	s := t.m.newSession("client")
+	if reply, blocked := s.request(body); blocked {
+		return &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader(reply)),
+			ContentLength: int64(len(reply)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		for _, id := range mcpRequestIDs(body) {
+			s.response(mcpErrorReply(id, -32603, err.Error()))
+		}
+		return resp, err
+	}
+
+	resp.Body = &mcpObservedBody{ReadCloser: resp.Body, obs: s.observer()}
+	return resp, nil
+}
+
+// mcpObservedBody feeds a response body to an observer as it is read
+type mcpObservedBody struct {
+	io.ReadCloser
+	obs  *mcpObserver
+	once sync.Once
+}
+
+func (b *mcpObservedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.obs.feed(p[:n])
+	}
+	if err != nil {
+		b.once.Do(b.obs.flush)
+	}
+	return n, err
+}
+
+func (b *mcpObservedBody) Close() error {
+	b.once.Do(b.obs.flush)
+	return b.ReadCloser.Close()
+}
+
+// mcpResponseWriter feeds a server's HTTP response to an observer
+type mcpResponseWriter struct {
+	http.ResponseWriter
+	obs *mcpObserver
+}
+
+func (w *mcpResponseWriter) Write(p []byte) (int, error) {
+	w.obs.feed(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush supports server-sent event responses
+func (w *mcpResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
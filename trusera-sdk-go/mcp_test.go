@@ -0,0 +1,410 @@
+package trusera
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeConn joins the ends of two pipes into one stream
+type pipeConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (p pipeConn) Close() error {
+	if c, ok := p.Writer.(io.Closer); ok {
+		c.Close()
+	}
+	if c, ok := p.Reader.(io.Closer); ok {
+		c.Close()
+	}
+	return nil
+}
+
+// mcpStreams returns connected client and server ends of an in-memory stdio link
+func mcpStreams() (client, server io.ReadWriteCloser) {
+	c2sR, c2sW := io.Pipe()
+	s2cR, s2cW := io.Pipe()
+	return pipeConn{Reader: s2cR, Writer: c2sW}, pipeConn{Reader: c2sR, Writer: s2cW}
+}
+
+// fakeMCPServer answers tools/list, tools/call and resources/read, recording
+// the tool calls it actually executes
+type fakeMCPServer struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeMCPServer) reply(req mcpMessage) any {
+	switch req.Method {
+	case "tools/list":
+		return map[string]any{"tools": []map[string]any{{"name": "search"}, {"name": "delete_file"}}}
+	case "tools/call":
+		var params struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(req.Params, &params)
+		f.mu.Lock()
+		f.calls = append(f.calls, params.Name)
+		f.mu.Unlock()
+		return map[string]any{"content": []any{}, "isError": false}
+	case "resources/read":
+		return map[string]any{"contents": []map[string]any{{"uri": "file:///a.txt", "mimeType": "text/plain", "text": "hi"}}}
+	}
+	return map[string]any{}
+}
+
+func (f *fakeMCPServer) serve(conn io.ReadWriteCloser) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req mcpMessage
+		if json.Unmarshal(scanner.Bytes(), &req) != nil || len(req.ID) == 0 {
+			continue
+		}
+		out, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": f.reply(req)})
+		conn.Write(append(out, '\n'))
+	}
+}
+
+func (f *fakeMCPServer) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// mcpCall sends a request over a client stream and waits for its response
+func mcpCall(t *testing.T, conn io.ReadWriter, r *bufio.Reader, id int, method string, params any) mcpMessage {
+	t.Helper()
+	req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	if _, err := conn.Write(append(req, '\n')); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var resp mcpMessage
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", line, err)
+	}
+	return resp
+}
+
+func waitForEvents(t *testing.T, c *Client, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for countEvents(c) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d events, got %d", n, countEvents(c))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMCPClientConn(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	m := NewMCPInterceptor(client, MCPOptions{
+		Enforcement:      ModeBlock,
+		BlockedTools:     []string{"delete_*"},
+		CaptureArguments: true,
+	})
+
+	clientEnd, serverEnd := mcpStreams()
+	server := &fakeMCPServer{}
+	go server.serve(serverEnd)
+
+	conn := m.WrapClientConn(clientEnd)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	mcpCall(t, conn, r, 1, "tools/list", map[string]any{})
+	mcpCall(t, conn, r, 2, "tools/call", map[string]any{"name": "search", "arguments": map[string]any{"q": "go"}})
+	mcpCall(t, conn, r, 3, "resources/read", map[string]any{"uri": "file:///a.txt"})
+
+	blocked := mcpCall(t, conn, r, 4, "tools/call", map[string]any{"name": "delete_file"})
+	if blocked.Error == nil || blocked.Error.Code != mcpBlockedCode {
+		t.Fatalf("expected blocked error response, got %+v", blocked)
+	}
+	if string(blocked.ID) != "4" {
+		t.Errorf("expected reply to request 4, got %s", blocked.ID)
+	}
+
+	if calls := server.executed(); len(calls) != 1 || calls[0] != "search" {
+		t.Errorf("expected only search to reach the server, got %v", calls)
+	}
+
+	waitForEvents(t, client, 4)
+
+	list, _ := findEvent(client, "mcp tools/list")
+	if list.Payload["tool_count"] != 2 {
+		t.Errorf("unexpected discovery event: %v", list.Payload)
+	}
+
+	search, _ := findEvent(client, "search")
+	if search.Type != EventToolCall || search.Payload["is_error"] != false || search.Payload["side"] != "client" {
+		t.Errorf("unexpected tool call event: %+v", search)
+	}
+	if search.Payload["arguments"] == nil {
+		t.Error("expected arguments when CaptureArguments is set")
+	}
+
+	read, _ := findEvent(client, "file:///a.txt")
+	if read.Type != EventDataAccess || read.Payload["contents"] != 1 {
+		t.Errorf("unexpected resource event: %+v", read)
+	}
+
+	denied, _ := findEvent(client, "delete_file")
+	if denied.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("expected blocked tool event, got %v", denied.Payload)
+	}
+}
+
+func TestMCPClientConnOrdersBlockedReplies(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	m := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeBlock, BlockedTools: []string{"delete_*"}})
+
+	clientEnd, serverEnd := mcpStreams()
+	go (&fakeMCPServer{}).serve(serverEnd)
+	conn := m.WrapClientConn(clientEnd)
+
+	// The replies queue up while nobody reads
+	const n = 50
+	for id := 1; id <= n; id++ {
+		req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": "tools/call", "params": map[string]any{"name": "delete_file"}})
+		if _, err := conn.Write(append(req, '\n')); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := bufio.NewReader(conn)
+	for id := 1; id <= n; id++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		var resp mcpMessage
+		json.Unmarshal(line, &resp)
+		if string(resp.ID) != fmt.Sprint(id) {
+			t.Fatalf("expected the reply to request %d, got %s", id, line)
+		}
+	}
+
+	// Replies still queued when the conn closes are not delivered
+	req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": n + 1, "method": "tools/call", "params": map[string]any{"name": "delete_file"}})
+	conn.Write(append(req, '\n'))
+	conn.Close()
+	if _, err := conn.Write(append(req, '\n')); err != nil {
+		t.Fatal(err)
+	}
+	if line, err := r.ReadBytes('\n'); err == nil {
+		t.Errorf("expected no reply after Close, got %s", line)
+	}
+}
+
+func TestMCPServerConnBlocks(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	m := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeBlock, BlockedTools: []string{"delete_file"}})
+
+	clientEnd, serverEnd := mcpStreams()
+	server := &fakeMCPServer{}
+	go server.serve(m.WrapServerConn(serverEnd))
+	defer clientEnd.Close()
+
+	r := bufio.NewReader(clientEnd)
+
+	resp := mcpCall(t, clientEnd, r, 1, "tools/call", map[string]any{"name": "delete_file"})
+	if resp.Error == nil {
+		t.Fatal("expected blocked tool call to return an error")
+	}
+
+	resp = mcpCall(t, clientEnd, r, 2, "tools/call", map[string]any{"name": "search"})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error.Message)
+	}
+
+	if calls := server.executed(); len(calls) != 1 || calls[0] != "search" {
+		t.Errorf("blocked call should not reach server handlers, got %v", calls)
+	}
+
+	waitForEvents(t, client, 2)
+	search, _ := findEvent(client, "search")
+	if search.Payload["side"] != "server" || search.Payload["enforcement_action"] != "allowed" {
+		t.Errorf("unexpected server-side event: %v", search.Payload)
+	}
+}
+
+func TestMCPWarnModeForwards(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	m := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeWarn, BlockedTools: []string{"delete_file"}})
+
+	clientEnd, serverEnd := mcpStreams()
+	server := &fakeMCPServer{}
+	go server.serve(serverEnd)
+
+	conn := m.WrapClientConn(clientEnd)
+	defer conn.Close()
+
+	resp := mcpCall(t, conn, bufio.NewReader(conn), 1, "tools/call", map[string]any{"name": "delete_file"})
+	if resp.Error != nil {
+		t.Fatal("warn mode should forward the call")
+	}
+
+	waitForEvents(t, client, 1)
+	e, _ := findEvent(client, "delete_file")
+	if e.Metadata["warning"] == nil {
+		t.Error("expected warning metadata in warn mode")
+	}
+}
+
+func TestMCPStreamableHTTP(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	m := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeBlock, BlockedTools: []string{"delete_file"}})
+	server := &fakeMCPServer{}
+
+	backend := httptest.NewServer(m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req mcpMessage
+		json.NewDecoder(r.Body).Decode(&req)
+		out, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": server.reply(req)})
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("event: message\ndata: " + string(out) + "\n\n"))
+	})))
+	defer backend.Close()
+
+	httpClient := &http.Client{Transport: m.WrapTransport(nil)}
+
+	post := func(body string) string {
+		resp, err := httpClient.Post(backend.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return string(out)
+	}
+
+	post(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+
+	blocked := post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_file"}}`)
+	if !strings.Contains(blocked, "blocked by Trusera policy") {
+		t.Errorf("expected blocked reply, got %s", blocked)
+	}
+
+	if calls := server.executed(); len(calls) != 1 {
+		t.Errorf("expected one executed call, got %v", calls)
+	}
+
+	// search is seen by both the client transport and the server handler
+	waitForEvents(t, client, 3)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	sides := map[string]bool{}
+	for _, e := range client.events {
+		if e.Name == "search" {
+			sides[e.Payload["side"].(string)] = true
+			if _, ok := e.Payload["duration_ms"]; !ok {
+				t.Error("expected duration once the SSE response is observed")
+			}
+		}
+	}
+	if !sides["client"] || !sides["server"] {
+		t.Errorf("expected client and server events, got %v", sides)
+	}
+}
+
+func TestMCPIsBlockedTool(t *testing.T) {
+	m := NewMCPInterceptor(nil, MCPOptions{BlockedTools: []string{"exec", "fs_*"}})
+
+	tests := map[string]bool{
+		"exec":     true,
+		"fs_write": true,
+		"fs":       false,
+		"search":   false,
+	}
+	for name, want := range tests {
+		if got := m.isBlockedTool(name); got != want {
+			t.Errorf("isBlockedTool(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMCPBatchBlocks(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	m := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeBlock, BlockedTools: []string{"delete_file"}})
+
+	clientEnd, serverEnd := mcpStreams()
+	server := &fakeMCPServer{}
+	go server.serve(m.WrapServerConn(serverEnd))
+	defer clientEnd.Close()
+
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}},` +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete_file"}},` +
+		`{"jsonrpc":"2.0","method":"notifications/progress"}]`
+	if _, err := clientEnd.Write([]byte(batch + "\n")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	line, err := bufio.NewReader(clientEnd).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	var replies []mcpMessage
+	if err := json.Unmarshal(line, &replies); err != nil {
+		t.Fatalf("expected a batch reply, got %q: %v", line, err)
+	}
+	if len(replies) != 2 || string(replies[0].ID) != "1" || string(replies[1].ID) != "2" {
+		t.Fatalf("expected a reply to each request, got %s", line)
+	}
+	for _, r := range replies {
+		if r.Error == nil || r.Error.Code != mcpBlockedCode {
+			t.Errorf("expected the whole batch rejected, got %s", line)
+		}
+	}
+
+	if calls := server.executed(); len(calls) != 0 {
+		t.Errorf("expected no call of a rejected batch executed, got %v", calls)
+	}
+	waitForEvents(t, client, 2)
+	if e, _ := findEvent(client, "delete_file"); e.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("unexpected blocked event %v", e.Payload)
+	}
+	if e, _ := findEvent(client, "search"); e.Payload["error_code"] != mcpBlockedCode {
+		t.Errorf("expected the rejected call tracked with its error, got %v", e.Payload)
+	}
+}
+
+func TestMCPBatchForwarded(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	s := NewMCPInterceptor(client, MCPOptions{Enforcement: ModeBlock, BlockedTools: []string{"delete_file"}}).newSession("client")
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}},` +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`
+	if _, blocked := s.request([]byte(batch)); blocked {
+		t.Fatal("expected a batch of allowed calls forwarded")
+	}
+
+	s.response([]byte(`[{"jsonrpc":"2.0","id":2,"result":{"tools":[]}},{"jsonrpc":"2.0","id":1,"result":{"content":[]}}]`))
+	waitForEvents(t, client, 2)
+	if e, _ := findEvent(client, "search"); e.Payload["duration_ms"] == nil {
+		t.Errorf("expected the batched call completed by its response, got %v", e.Payload)
+	}
+}
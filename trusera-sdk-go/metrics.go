@@ -0,0 +1,417 @@
+package trusera
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// flushBuckets are the upper bounds, in seconds, of the flush latency histogram
+var flushBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// clientMetrics accumulates SDK self-observability counters
+type clientMetrics struct {
+	mu            sync.Mutex
+	tracked       map[EventType]uint64
+	decisions     map[string]uint64
+	flushed       uint64
+	dropped       uint64 // lost to failed flushes
+	overflowed    uint64 // discarded by the overflow policy
+	hookDropped   uint64 // dropped or vetoed by an event hook
+	sampledOut    uint64 // rejected by the sampler
+	paused        uint64 // dropped while the control plane paused emission
+	invalid       uint64 // rejected by a payload schema
+	duplicates    uint64 // dropped by deduplication
+	rateLimited   uint64 // dropped for exceeding the event rate
+	spilled       uint64
+	flushes       uint64
+	flushErrors   uint64
+	retries       uint64
+	shorted       uint64 // flushes rejected by the open circuit breaker
+	deadLettered  uint64
+	sinks         map[string]*SinkStats
+	flushCounts   []uint64 // per bucket, non-cumulative; last entry is +Inf
+	flushSum      float64
+	lastFlushUnix int64
+}
+
+func newClientMetrics() *clientMetrics {
+	return &clientMetrics{
+		tracked:     make(map[EventType]uint64),
+		decisions:   make(map[string]uint64),
+		sinks:       make(map[string]*SinkStats),
+		flushCounts: make([]uint64, len(flushBuckets)+1),
+	}
+}
+
+// observeTrack counts a tracked event and, for intercepted calls, the
+// enforcement decision recorded in its payload
+func (m *clientMetrics) observeTrack(event Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracked[event.Type]++
+
+	action, ok := event.Payload["enforcement_action"].(string)
+	if !ok {
+		return
+	}
+	decision := "allow"
+	switch action {
+	case "would_block":
+		decision = "would_block"
+	case "blocked":
+		switch event.Metadata["enforcement_mode"] {
+		case string(ModeBlock), string(ModeAllowList):
+			decision = "block"
+		case string(ModeWarn):
+			decision = "warn"
+		default:
+			decision = "log"
+		}
+	}
+	m.decisions[decision]++
+}
+
+// observeFlush records the outcome of sending a batch
+func (m *clientMetrics) observeFlush(events int, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flushes++
+	if err != nil {
+		m.flushErrors++
+	} else {
+		m.flushed += uint64(events)
+		m.lastFlushUnix = time.Now().Unix()
+	}
+
+	seconds := d.Seconds()
+	m.flushSum += seconds
+	i := sort.SearchFloat64s(flushBuckets, seconds)
+	m.flushCounts[i]++
+}
+
+// observeDropped counts events discarded after a failed flush
+func (m *clientMetrics) observeDropped(events int) {
+	m.mu.Lock()
+	m.dropped += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeOverflow counts events discarded because the buffer was full
+func (m *clientMetrics) observeOverflow(events int) {
+	m.mu.Lock()
+	m.overflowed += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeHookDrop counts events dropped or vetoed by a hook
+func (m *clientMetrics) observeHookDrop(events int) {
+	m.mu.Lock()
+	m.hookDropped += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeSampledOut counts events rejected by the sampler
+func (m *clientMetrics) observeSampledOut(events int) {
+	m.mu.Lock()
+	m.sampledOut += uint64(events)
+	m.mu.Unlock()
+}
+
+// observePaused counts events dropped while emission is paused
+func (m *clientMetrics) observePaused(events int) {
+	m.mu.Lock()
+	m.paused += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeInvalid counts events rejected by a payload schema
+func (m *clientMetrics) observeInvalid(events int) {
+	m.mu.Lock()
+	m.invalid += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeDuplicate counts events dropped as duplicates
+func (m *clientMetrics) observeDuplicate(events int) {
+	m.mu.Lock()
+	m.duplicates += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeRateLimited counts events dropped for exceeding the event rate
+func (m *clientMetrics) observeRateLimited(events int) {
+	m.mu.Lock()
+	m.rateLimited += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeSpill counts events written to disk because the buffer was full
+func (m *clientMetrics) observeSpill(events int) {
+	m.mu.Lock()
+	m.spilled += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeRetry counts a retried flush request
+func (m *clientMetrics) observeRetry() {
+	m.mu.Lock()
+	m.retries++
+	m.mu.Unlock()
+}
+
+// observeShortCircuit counts a flush rejected by the circuit breaker
+func (m *clientMetrics) observeShortCircuit() {
+	m.mu.Lock()
+	m.shorted++
+	m.mu.Unlock()
+}
+
+// observeDeadLetter counts events written to the dead-letter file
+func (m *clientMetrics) observeDeadLetter(events int) {
+	m.mu.Lock()
+	m.deadLettered += uint64(events)
+	m.mu.Unlock()
+}
+
+// observeSink records a delivery to a secondary sink
+func (m *clientMetrics) observeSink(name string, events int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.sinks[name]
+	if s == nil {
+		s = &SinkStats{}
+		m.sinks[name] = s
+	}
+	if err != nil {
+		s.EventsFailed += uint64(events)
+		s.Failures++
+		s.LastError = err.Error()
+	} else {
+		s.EventsSent += uint64(events)
+	}
+}
+
+// MetricsSnapshot is a point-in-time copy of the SDK's metrics
+type MetricsSnapshot struct {
+	EventsTracked            map[EventType]uint64
+	EventsFlushed            uint64
+	EventsDropped            uint64 // Total of all EventsDropped* counters
+	EventsDroppedFlush       uint64 // Lost because a flush failed
+	EventsDroppedOverflow    uint64 // Discarded by the overflow policy
+	EventsDroppedHook        uint64 // Dropped or vetoed by an event hook
+	EventsDroppedSampled     uint64 // Rejected by the sampler
+	EventsDroppedPaused      uint64 // Dropped while the control plane paused emission
+	EventsDroppedSchema      uint64 // Rejected by a payload schema
+	EventsDroppedDuplicate   uint64 // Dropped by WithDeduplication
+	EventsDroppedRateLimited uint64 // Dropped for exceeding WithMaxEventsPerSecond
+	EventsSpilled            uint64 // Written to disk by SpillToDisk
+	EventsDeadLettered       uint64 // Rejected by the API and written to the dead-letter file
+	Flushes                  uint64
+	FlushErrors              uint64
+	FlushRetries             uint64
+	FlushShortCircuited      uint64 // Rejected while the circuit breaker was open
+	CircuitState             CircuitState
+	FlushDurationSum         time.Duration
+	BufferDepth              int
+	InterceptorDecision      map[string]uint64    // keyed by allow, log, warn, block, would_block
+	Sinks                    map[string]SinkStats // Secondary sinks, keyed by name
+}
+
+// MetricsCollector exposes a client's metrics. It serves the Prometheus text
+// exposition format, so Prometheus (or an OpenTelemetry collector's
+// Prometheus receiver) can scrape it without the SDK depending on
+// client_golang:
+//
+//	http.Handle("/metrics/trusera", client.Collector())
+type MetricsCollector struct {
+	c *Client
+}
+
+// Collector returns the client's metrics collector
+func (c *Client) Collector() *MetricsCollector {
+	return &MetricsCollector{c: c}
+}
+
+// Stats returns the client's current counters; it is shorthand for
+// Collector().Snapshot()
+func (c *Client) Stats() MetricsSnapshot {
+	return c.Collector().Snapshot()
+}
+
+// Snapshot returns the current metric values
+func (mc *MetricsCollector) Snapshot() MetricsSnapshot {
+	mc.c.mu.Lock()
+	depth := len(mc.c.events) + mc.c.shardedDepth()
+	mc.c.mu.Unlock()
+
+	m := mc.c.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MetricsSnapshot{
+		EventsTracked:            make(map[EventType]uint64, len(m.tracked)),
+		EventsFlushed:            m.flushed,
+		EventsDropped:            m.dropped + m.overflowed + m.hookDropped + m.sampledOut + m.paused + m.invalid + m.duplicates + m.rateLimited,
+		EventsDroppedFlush:       m.dropped,
+		EventsDroppedOverflow:    m.overflowed,
+		EventsDroppedHook:        m.hookDropped,
+		EventsDroppedSampled:     m.sampledOut,
+		EventsDroppedPaused:      m.paused,
+		EventsDroppedSchema:      m.invalid,
+		EventsDroppedDuplicate:   m.duplicates,
+		EventsDroppedRateLimited: m.rateLimited,
+		EventsSpilled:            m.spilled,
+		EventsDeadLettered:       m.deadLettered,
+		Flushes:                  m.flushes,
+		FlushErrors:              m.flushErrors,
+		FlushRetries:             m.retries,
+		FlushShortCircuited:      m.shorted,
+		CircuitState:             mc.c.CircuitState(),
+		FlushDurationSum:         time.Duration(m.flushSum * float64(time.Second)),
+		BufferDepth:              depth,
+		InterceptorDecision:      make(map[string]uint64, len(m.decisions)),
+		Sinks:                    make(map[string]SinkStats, len(m.sinks)),
+	}
+	for k, v := range m.tracked {
+		s.EventsTracked[k] = v
+	}
+	for k, v := range m.decisions {
+		s.InterceptorDecision[k] = v
+	}
+	for k, v := range m.sinks {
+		s.Sinks[k] = *v
+	}
+	return s
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format
+func (mc *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	mc.c.mu.Lock()
+	depth := len(mc.c.events) + mc.c.shardedDepth()
+	mc.c.mu.Unlock()
+	circuit := mc.c.CircuitState()
+
+	m := mc.c.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	header := func(name, kind, help string) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("trusera_events_tracked_total", "counter", "Events queued by Track, by event type.")
+	types := make([]string, 0, len(m.tracked))
+	for t := range m.tracked {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(cw, "trusera_events_tracked_total{type=%q} %d\n", t, m.tracked[EventType(t)])
+	}
+
+	header("trusera_events_flushed_total", "counter", "Events delivered to the Trusera API.")
+	fmt.Fprintf(cw, "trusera_events_flushed_total %d\n", m.flushed)
+
+	header("trusera_events_dropped_total", "counter", "Events discarded, by reason.")
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"flush_error\"} %d\n", m.dropped)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"overflow\"} %d\n", m.overflowed)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"hook\"} %d\n", m.hookDropped)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"sampled\"} %d\n", m.sampledOut)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"paused\"} %d\n", m.paused)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"schema\"} %d\n", m.invalid)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"duplicate\"} %d\n", m.duplicates)
+	fmt.Fprintf(cw, "trusera_events_dropped_total{reason=\"rate_limited\"} %d\n", m.rateLimited)
+
+	header("trusera_events_spilled_total", "counter", "Events written to disk because the buffer was full.")
+	fmt.Fprintf(cw, "trusera_events_spilled_total %d\n", m.spilled)
+
+	header("trusera_events_dead_lettered_total", "counter", "Events rejected by the API and written to the dead-letter file.")
+	fmt.Fprintf(cw, "trusera_events_dead_lettered_total %d\n", m.deadLettered)
+
+	header("trusera_flush_errors_total", "counter", "Flushes that failed.")
+	fmt.Fprintf(cw, "trusera_flush_errors_total %d\n", m.flushErrors)
+
+	header("trusera_flush_retries_total", "counter", "Flush requests retried after a transient failure.")
+	fmt.Fprintf(cw, "trusera_flush_retries_total %d\n", m.retries)
+
+	header("trusera_flush_short_circuited_total", "counter", "Flushes rejected while the circuit breaker was open.")
+	fmt.Fprintf(cw, "trusera_flush_short_circuited_total %d\n", m.shorted)
+
+	header("trusera_circuit_open", "gauge", "1 while the circuit breaker is open or half-open.")
+	circuitOpen := 0
+	if circuit != CircuitClosed {
+		circuitOpen = 1
+	}
+	fmt.Fprintf(cw, "trusera_circuit_open %d\n", circuitOpen)
+
+	header("trusera_flush_duration_seconds", "histogram", "Time taken to send a batch of events.")
+	var cumulative uint64
+	for i, le := range flushBuckets {
+		cumulative += m.flushCounts[i]
+		fmt.Fprintf(cw, "trusera_flush_duration_seconds_bucket{le=\"%g\"} %d\n", le, cumulative)
+	}
+	cumulative += m.flushCounts[len(flushBuckets)]
+	fmt.Fprintf(cw, "trusera_flush_duration_seconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(cw, "trusera_flush_duration_seconds_sum %g\n", m.flushSum)
+	fmt.Fprintf(cw, "trusera_flush_duration_seconds_count %d\n", m.flushes)
+
+	header("trusera_buffer_events", "gauge", "Events waiting to be flushed.")
+	fmt.Fprintf(cw, "trusera_buffer_events %d\n", depth)
+
+	header("trusera_last_flush_timestamp_seconds", "gauge", "Unix time of the last successful flush.")
+	fmt.Fprintf(cw, "trusera_last_flush_timestamp_seconds %d\n", m.lastFlushUnix)
+
+	header("trusera_interceptor_decisions_total", "counter", "Interceptor enforcement decisions.")
+	for _, d := range []string{"allow", "log", "warn", "block", "would_block"} {
+		fmt.Fprintf(cw, "trusera_interceptor_decisions_total{decision=%q} %d\n", d, m.decisions[d])
+	}
+
+	header("trusera_sink_events_total", "counter", "Events delivered to secondary sinks, by sink and result.")
+	sinks := make([]string, 0, len(m.sinks))
+	for name := range m.sinks {
+		sinks = append(sinks, name)
+	}
+	sort.Strings(sinks)
+	for _, name := range sinks {
+		fmt.Fprintf(cw, "trusera_sink_events_total{sink=%q,result=\"sent\"} %d\n", name, m.sinks[name].EventsSent)
+		fmt.Fprintf(cw, "trusera_sink_events_total{sink=%q,result=\"failed\"} %d\n", name, m.sinks[name].EventsFailed)
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics for scraping
+func (mc *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mc.WriteTo(w)
+}
+
+// countingWriter tracks bytes written and the first error
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,127 @@
+package trusera
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b"))
+	client.Track(NewEvent(EventLLMInvoke, "c"))
+
+	s := client.Collector().Snapshot()
+	if s.EventsTracked[EventToolCall] != 2 || s.EventsTracked[EventLLMInvoke] != 1 {
+		t.Errorf("unexpected tracked counts: %v", s.EventsTracked)
+	}
+	if s.BufferDepth != 3 {
+		t.Errorf("expected buffer depth 3, got %d", s.BufferDepth)
+	}
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	s = client.Collector().Snapshot()
+	if s.EventsFlushed != 3 || s.Flushes != 1 || s.BufferDepth != 0 {
+		t.Errorf("unexpected snapshot after flush: %+v", s)
+	}
+}
+
+func TestMetricsDroppedOnFlushError(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL))
+	defer client.Close()
+
+	client.Track(NewEvent(EventAPICall, "a"))
+	client.Flush()
+
+	s := client.Collector().Snapshot()
+	if s.EventsDropped != 1 || s.FlushErrors != 1 || s.EventsFlushed != 0 {
+		t.Errorf("unexpected snapshot: %+v", s)
+	}
+}
+
+func TestMetricsInterceptorDecisions(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	client := NewClient("test-key")
+	defer client.Close()
+
+	block := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{"/admin"}})
+	warn := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{Enforcement: ModeWarn, BlockPatterns: []string{"/admin"}})
+	shadow := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{Enforcement: ModeShadow, BlockPatterns: []string{"/admin"}})
+
+	if resp, err := block.Get(backend.URL + "/ok"); err == nil {
+		resp.Body.Close()
+	}
+	block.Get(backend.URL + "/admin")
+	if resp, err := warn.Get(backend.URL + "/admin"); err == nil {
+		resp.Body.Close()
+	}
+	if resp, err := shadow.Get(backend.URL + "/admin"); err == nil {
+		resp.Body.Close()
+	}
+
+	s := client.Collector().Snapshot()
+	want := map[string]uint64{"allow": 1, "block": 1, "warn": 1, "would_block": 1}
+	for k, v := range want {
+		if s.InterceptorDecision[k] != v {
+			t.Errorf("decision %s = %d, want %d", k, s.InterceptorDecision[k], v)
+		}
+	}
+}
+
+func TestMetricsExposition(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Flush()
+
+	scrape := httptest.NewServer(client.Collector())
+	defer scrape.Close()
+
+	resp, err := http.Get(scrape.URL)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	for _, line := range []string{
+		"# TYPE trusera_events_tracked_total counter",
+		`trusera_events_tracked_total{type="tool_call"} 1`,
+		"trusera_events_flushed_total 1",
+		`trusera_flush_duration_seconds_bucket{le="+Inf"} 1`,
+		"trusera_flush_duration_seconds_count 1",
+		"trusera_buffer_events 0",
+		`trusera_interceptor_decisions_total{decision="block"} 0`,
+		`trusera_interceptor_decisions_total{decision="would_block"} 0`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("missing %q in exposition:\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// MiddlewareOptions configures the inbound HTTP middleware
+type MiddlewareOptions struct {
+	Enforcement  EnforcementMode
+	ExcludePaths []string // Paths, and the paths below them, to serve without tracking or enforcement, e.g. "/healthz"
+
+	// Authenticate identifies the caller, e.g. from a bearer token or client
+	// certificate. An error means the caller is not authenticated: in block
+	// mode the request is rejected with 401. The identity becomes the user ID
+	// of every event tracked with the request context.
+	Authenticate func(*http.Request) (string, error)
+
+	// Policy authorizes requests; a Deny decision is rejected with 403 in
+	// block mode. It sees the inbound request as is.
+	Policy RequestPolicy
+
+	// CaptureBody records the start of the request body
+	CaptureBody bool
+
+	// SessionHeader carries the caller's session ID, so that several requests
+	// can form one session. Requests without it start a new session, whose ID
+	// is returned in the same response header. Default "X-Trusera-Session-ID".
+	SessionHeader string
+
+	// Route names the route a request matched, such as "/users/{id}", and
+	// is recorded as route. It is called after the handler, so routers
+	// that match inside it can report the route too. The framework
+	// packages under integrations set it.
+	Route func(*http.Request) string
+}
+
+// DefaultSessionHeader is the header used when MiddlewareOptions.SessionHeader is empty
+const DefaultSessionHeader = "X-Trusera-Session-ID"
+
+// Middleware returns HTTP middleware that tracks the requests an agent
+// receives: who called it, with what, and how it responded. Together with
+// the outbound interceptors this gives visibility in both directions.
+//
+// Each request gets a session and a trace in its context: the trace
+// continues the caller's traceparent header or starts a new one. Events
+// tracked downstream with TrackCtx, and requests made with the request
+// context through an interceptor, are attached to both.
+//
+// Use it with net/http or any router that accepts
+// func(http.Handler) http.Handler. The packages under integrations adapt it
+// to Gin, Echo and Chi. Status and size are read from writers that report
+// them, like gin's, when the handler bypasses the middleware's writer.
+func Middleware(truseraClient *Client, opts MiddlewareOptions) func(http.Handler) http.Handler {
+	if opts.SessionHeader == "" {
+		opts.SessionHeader = DefaultSessionHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if excludedPath(r.URL.Path, opts.ExcludePaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+
+			event := NewEvent(EventAPICall, "inbound "+r.Method+" "+r.URL.Path).
+				WithPayload("direction", "inbound").
+				WithPayload("method", r.Method).
+				WithPayload("path", r.URL.Path).
+				WithPayload("remote_addr", r.RemoteAddr).
+				WithPayload("user_agent", r.UserAgent()).
+				WithPayload("headers", sanitizeHeaders(r.Header)).
+				WithMetadata("enforcement_mode", string(opts.Enforcement))
+			if r.URL.RawQuery != "" {
+				event = event.WithPayload("query", r.URL.RawQuery)
+			}
+			if opts.CaptureBody && r.Body != nil {
+				if snippet := peekBody(r); snippet != "" {
+					event = event.WithPayload("body_snippet", snippet)
+				}
+			}
+
+			ctx := requestContext(w, r, opts.SessionHeader)
+			status := 0
+			if opts.Authenticate != nil {
+				principal, err := opts.Authenticate(r)
+				if err != nil {
+					status = http.StatusUnauthorized
+					event = event.WithPayload("auth_error", err.Error())
+				} else if principal != "" {
+					event = event.WithPayload("principal", principal)
+					ctx = ContextWithUserID(ctx, principal)
+				}
+			}
+			if status == 0 && opts.Policy != nil {
+				decision := opts.Policy.EvaluateRequest(r)
+				if decision.Decision == "Deny" {
+					status = http.StatusForbidden
+					event = event.WithPayload("policy_reasons", decision.Reasons)
+				}
+			}
+			r = r.WithContext(ctx)
+
+			event = event.WithPayload("blocked", status != 0)
+			if status != 0 {
+				event = event.WithPayload("enforcement_action", opts.Enforcement.blockAction())
+
+				switch opts.Enforcement {
+				case ModeBlock, ModeAllowList:
+					http.Error(w, http.StatusText(status), status)
+					event = event.
+						WithPayload("status_code", status).
+						WithPayload("duration_ms", float64(time.Since(start).Microseconds())/1000)
+					truseraClient.Track(truseraClient.enrich(ctx, truseraClient.correlateRequest(r, event)))
+					return
+				case ModeWarn:
+					event = event.WithMetadata("warning", "inbound request fails authentication or policy but allowed in warn mode")
+				}
+			} else {
+				event = event.WithPayload("enforcement_action", "allowed")
+			}
+
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+				if sw, ok := w.(interface{ Status() int }); ok && sw.Status() != 0 {
+					rec.status = sw.Status()
+				}
+			}
+			if sw, ok := w.(interface{ Size() int }); ok && rec.bytes == 0 && sw.Size() > 0 {
+				rec.bytes = sw.Size()
+			}
+			event = event.
+				WithPayload("status_code", rec.status).
+				WithPayload("response_bytes", rec.bytes).
+				WithPayload("duration_ms", float64(time.Since(start).Microseconds())/1000)
+			if opts.Route != nil {
+				if route := opts.Route(r); route != "" {
+					event = event.WithPayload("route", route)
+				}
+			}
+			truseraClient.Track(truseraClient.enrich(ctx, truseraClient.correlateRequest(r, event)))
+		})
+	}
+}
+
+// excludedPath reports whether a request path is one of the excluded paths
+// or below one, compared by whole segments after cleaning, so excluding
+// "/healthz" does not exclude "/admin/healthz" or "/healthz-admin"
+func excludedPath(p string, excluded []string) bool {
+	p = path.Clean("/" + p)
+	for _, e := range excluded {
+		e = path.Clean("/" + e)
+		if p == e || strings.HasPrefix(p, strings.TrimSuffix(e, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestContext starts the session and trace of an inbound request
+func requestContext(w http.ResponseWriter, r *http.Request, sessionHeader string) context.Context {
+	ctx := r.Context()
+
+	sessionID := r.Header.Get(sessionHeader)
+	if sessionID == "" {
+		sessionID = generateID()
+		w.Header().Set(sessionHeader, sessionID)
+	}
+	ctx = ContextWithSessionID(ctx, sessionID)
+
+	traceID, _ := parseTraceParent(r.Header.Get("traceparent"))
+	if traceID == "" {
+		traceID = randomHex(16)
+	}
+	return ContextWithTrace(ctx, traceID, randomHex(8))
+}
+
+// peekBody returns the start of a request body, leaving the body intact
+func peekBody(r *http.Request) string {
+	head, err := io.ReadAll(io.LimitReader(r.Body, maxBodySnippet+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+	if len(head) > maxBodySnippet {
+		return string(head[:maxBodySnippet]) + "..."
+	}
+	return string(head)
+}
+
+// responseRecorder captures the status and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+// Flush supports streaming handlers
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
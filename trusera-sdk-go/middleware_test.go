@@ -0,0 +1,276 @@
+package trusera
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func bearerAuth(r *http.Request) (string, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return "", errors.New("missing bearer token")
+	}
+	return "user-" + token, nil
+}
+
+func TestMiddlewareTracksInboundRequests(t *testing.T) {
+	client := dialClient(t)
+
+	handler := Middleware(client, MiddlewareOptions{
+		Enforcement:  ModeBlock,
+		Authenticate: bearerAuth,
+		CaptureBody:  true,
+		ExcludePaths: []string{"/healthz"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"task":"summarize"}` {
+			t.Errorf("handler should see the full body, got %q", body)
+		}
+		if UserIDFromContext(r.Context()) != "user-42" {
+			t.Errorf("expected the caller identity in the context, got %q", UserIDFromContext(r.Context()))
+		}
+		client.TrackCtx(r.Context(), NewEvent(EventToolCall, "downstream"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/agent/run?verbose=1", strings.NewReader(`{"task":"summarize"}`))
+	req.Header.Set("Authorization", "Bearer 42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+
+	event, ok := trackedEvent(client, "inbound POST /agent/run")
+	if !ok {
+		t.Fatal("inbound request not tracked")
+	}
+	checks := map[string]any{
+		"direction":          "inbound",
+		"principal":          "user-42",
+		"status_code":        http.StatusCreated,
+		"response_bytes":     4,
+		"query":              "verbose=1",
+		"body_snippet":       `{"task":"summarize"}`,
+		"enforcement_action": "allowed",
+	}
+	for k, want := range checks {
+		if event.Payload[k] != want {
+			t.Errorf("payload %s = %v, want %v", k, event.Payload[k], want)
+		}
+	}
+	if event.Payload["headers"].(map[string]string)["Authorization"] != "[REDACTED]" {
+		t.Error("expected the Authorization header to be redacted")
+	}
+	if event.Metadata["user_id"] != "user-42" {
+		t.Errorf("expected user_id metadata, got %v", event.Metadata)
+	}
+	downstream, _ := trackedEvent(client, "downstream")
+	if downstream.Metadata["user_id"] != "user-42" {
+		t.Errorf("expected downstream events to carry the caller, got %v", downstream.Metadata)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if _, ok := trackedEvent(client, "inbound GET /healthz"); ok {
+		t.Error("excluded paths should not be tracked")
+	}
+}
+
+func TestMiddlewareEnforcesAuthnAndAuthz(t *testing.T) {
+	client := dialClient(t)
+	policy, err := NewCELPolicy(CELRule{
+		ID:         "no-admin",
+		Action:     ActionForbid,
+		Expression: `request.path.startsWith("/admin")`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called++ })
+	handler := Middleware(client, MiddlewareOptions{
+		Enforcement:  ModeBlock,
+		Authenticate: bearerAuth,
+		Policy:       policy,
+	})(next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agent", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without credentials, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	req.Header.Set("Authorization", "Bearer 7")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a denied request, got %d", rec.Code)
+	}
+	if called != 0 {
+		t.Errorf("blocked requests should not reach the handler, got %d calls", called)
+	}
+
+	denied, _ := trackedEvent(client, "inbound GET /admin/users")
+	if denied.Payload["enforcement_action"] != "blocked" || denied.Payload["policy_reasons"] == nil || denied.Metadata["user_id"] != "user-7" {
+		t.Errorf("unexpected denied event %v %v", denied.Payload, denied.Metadata)
+	}
+	unauth, _ := trackedEvent(client, "inbound GET /agent")
+	if unauth.Payload["auth_error"] != "missing bearer token" || unauth.Payload["status_code"] != http.StatusUnauthorized {
+		t.Errorf("unexpected unauthenticated event %v", unauth.Payload)
+	}
+}
+
+func TestMiddlewareExcludePathsBySegment(t *testing.T) {
+	client := dialClient(t)
+	called := 0
+	handler := Middleware(client, MiddlewareOptions{
+		Enforcement:  ModeBlock,
+		Authenticate: bearerAuth,
+		ExcludePaths: []string{"/healthz"},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called++ }))
+
+	for _, p := range []string{"/healthz", "/healthz/ready"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, p, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected %s excluded, got %d", p, rec.Code)
+		}
+	}
+	for _, p := range []string{"/admin/healthz", "/healthz-admin", "/healthz/../admin"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = p
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("expected %s enforced, got %d", p, rec.Code)
+		}
+	}
+	if called != 2 {
+		t.Errorf("expected only the excluded paths served, got %d calls", called)
+	}
+}
+
+func TestMiddlewareWarnModeServes(t *testing.T) {
+	client := dialClient(t)
+	handler := Middleware(client, MiddlewareOptions{Enforcement: ModeWarn, Authenticate: bearerAuth})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agent", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("warn mode should serve the request, got %d", rec.Code)
+	}
+	event, _ := trackedEvent(client, "inbound GET /agent")
+	if event.Payload["enforcement_action"] != "blocked" || event.Metadata["warning"] == nil || event.Payload["status_code"] != http.StatusOK {
+		t.Errorf("unexpected warn event %v %v", event.Payload, event.Metadata)
+	}
+}
+
+func TestMiddlewareStartsSessionAndTrace(t *testing.T) {
+	client := dialClient(t)
+	var sessions, traces []string
+	handler := Middleware(client, MiddlewareOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, spanID := TraceFromContext(r.Context())
+		if len(traceID) != 32 || len(spanID) != 16 {
+			t.Errorf("expected a trace in the request context, got %q %q", traceID, spanID)
+		}
+		sessions = append(sessions, SessionIDFromContext(r.Context()))
+		traces = append(traces, traceID)
+		client.TrackCtx(r.Context(), NewEvent(EventToolCall, "step-"+traceID))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+	if got := rec.Header().Get(DefaultSessionHeader); got == "" || got != sessions[0] {
+		t.Errorf("expected the new session ID %q in the response, got %q", sessions[0], got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/b", nil)
+	req.Header.Set(DefaultSessionHeader, sessions[0])
+	req.Header.Set("traceparent", "00-"+testTraceID+"-"+testSpanID+"-01")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if sessions[1] != sessions[0] {
+		t.Errorf("expected the caller's session to continue, got %q and %q", sessions[0], sessions[1])
+	}
+	if traces[1] != testTraceID {
+		t.Errorf("expected the caller's trace to continue, got %q", traces[1])
+	}
+	if rec.Header().Get(DefaultSessionHeader) != "" {
+		t.Error("an existing session should not be echoed back")
+	}
+	if traces[0] == traces[1] {
+		t.Error("requests without traceparent should start a new trace")
+	}
+
+	inbound, _ := trackedEvent(client, "inbound GET /a")
+	step, _ := trackedEvent(client, "step-"+traces[0])
+	if inbound.Metadata["trace_id"] != traces[0] || step.Metadata["trace_id"] != traces[0] ||
+		inbound.Metadata["span_id"] != step.Metadata["span_id"] ||
+		inbound.Metadata["session_id"] != sessions[0] || step.Metadata["session_id"] != sessions[0] {
+		t.Errorf("expected inbound and downstream events to share session and trace, got %v and %v", inbound.Metadata, step.Metadata)
+	}
+}
+
+// sizedWriter reports status and size like gin's ResponseWriter
+type sizedWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w sizedWriter) Status() int { return w.Code }
+func (w sizedWriter) Size() int   { return w.Body.Len() }
+
+func TestMiddlewareReadsStatusFromWriter(t *testing.T) {
+	client := dialClient(t)
+	rec := sizedWriter{httptest.NewRecorder()}
+
+	// Frameworks like gin keep writing to their own writer, bypassing ours
+	handler := Middleware(client, MiddlewareOptions{})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		rec.WriteHeader(http.StatusTeapot)
+		rec.Write([]byte("short and stout"))
+	}))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/pot", nil))
+
+	event, _ := trackedEvent(client, "inbound GET /pot")
+	if event.Payload["status_code"] != http.StatusTeapot || event.Payload["response_bytes"] != 15 {
+		t.Errorf("expected status and size from the writer, got %v", event.Payload)
+	}
+}
+
+func TestMiddlewareRecordsRoute(t *testing.T) {
+	client := dialClient(t)
+	route := ""
+	handler := Middleware(client, MiddlewareOptions{
+		Route: func(*http.Request) string { return route },
+	})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		route = "/users/{id}" // Matched by the router inside the handler
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	event, _ := trackedEvent(client, "inbound GET /users/7")
+	if event.Payload["route"] != "/users/{id}" {
+		t.Errorf("expected the route recorded, got %v", event.Payload)
+	}
+}
+
+func TestResponseRecorderSupportsFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var w http.ResponseWriter = &responseRecorder{ResponseWriter: rec}
+	w.(http.Flusher).Flush()
+	if !rec.Flushed {
+		t.Error("expected Flush to reach the underlying writer")
+	}
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		t.Errorf("ResponseController flush failed: %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package trusera
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model the agent depends on
+type ModelInfo struct {
+	Name        string `json:"name"`
+	Provider    string `json:"provider,omitempty"`
+	Version     string `json:"version,omitempty"`      // Snapshot, e.g. "2024-08-06" for gpt-4o-2024-08-06
+	WeightsHash string `json:"weights_hash,omitempty"` // e.g. "sha256:…"; a bare hex digest is identified by its length
+	License     string `json:"license,omitempty"`      // SPDX ID or license name
+}
+
+// modelRegistry holds the registered models and the drifted ones reported
+type modelRegistry struct {
+	mu       sync.Mutex
+	models   []ModelInfo
+	reported map[string]bool
+}
+
+// snapshotSuffix matches the part of a model name after the registered name
+// that denotes a snapshot rather than another model, e.g. -2024-08-06 but
+// not -mini
+var snapshotSuffix = regexp.MustCompile(`^(latest|\d{4}-\d{2}-\d{2}|\d{8}|\d{4}|v?\d+(\.\d+)*)$`)
+
+// RegisterModel declares a model the agent depends on. Registered models
+// are listed by Models, for the AI-BOM, and once any is registered, LLM
+// invocations are checked against them. An invocation of an unregistered
+// model, or of another snapshot than a registered Version, is drift: the
+// event gets model_drift ("unregistered" or "version") in its payload, and
+// an EventModelDrift is tracked the first time each model drifts.
+func (c *Client) RegisterModel(info ModelInfo) error {
+	if info.Name == "" {
+		return errors.New("model name is required")
+	}
+	c.models.mu.Lock()
+	defer c.models.mu.Unlock()
+	for i, m := range c.models.models {
+		if m.Name == info.Name && strings.EqualFold(m.Provider, info.Provider) {
+			c.models.models[i] = info
+			return nil
+		}
+	}
+	c.models.models = append(c.models.models, info)
+	return nil
+}
+
+// Models returns the registered models
+func (c *Client) Models() []ModelInfo {
+	c.models.mu.Lock()
+	defer c.models.mu.Unlock()
+	return append([]ModelInfo(nil), c.models.models...)
+}
+
+// checkModel flags an LLM invocation of a model that was not registered
+func (c *Client) checkModel(event Event) Event {
+	if event.Type != EventLLMInvoke {
+		return event
+	}
+	name, _ := event.Payload["model"].(string)
+	if name == "" {
+		return event
+	}
+	provider, _ := event.Payload["provider"].(string)
+
+	c.models.mu.Lock()
+	if len(c.models.models) == 0 {
+		c.models.mu.Unlock()
+		return event
+	}
+	reason, expected := "unregistered", ""
+	for _, m := range c.models.models {
+		if m.Provider != "" && provider != "" && !strings.EqualFold(m.Provider, provider) {
+			continue
+		}
+		if name == m.Name {
+			reason = ""
+			break
+		}
+		suffix, ok := strings.CutPrefix(name, m.Name+"-")
+		if !ok || !snapshotSuffix.MatchString(suffix) {
+			continue
+		}
+		if m.Version == "" || suffix == m.Version {
+			reason = ""
+			break
+		}
+		reason, expected = "version", m.Name+"-"+m.Version
+	}
+	report := false
+	if reason != "" {
+		key := provider + "/" + name
+		if c.models.reported == nil {
+			c.models.reported = make(map[string]bool)
+		}
+		report = !c.models.reported[key]
+		c.models.reported[key] = true
+	}
+	c.models.mu.Unlock()
+
+	if reason == "" {
+		return event
+	}
+	event = event.WithPayload("model_drift", reason)
+	if report {
+		drift := NewEvent(EventModelDrift, name).
+			WithPayload("model", name).
+			WithPayload("reason", reason).
+			WithMetadata("parent_event_id", event.ID)
+		if provider != "" {
+			drift = drift.WithPayload("provider", provider)
+		}
+		if expected != "" {
+			drift = drift.WithPayload("expected", expected)
+		}
+		c.Track(drift)
+	}
+	return event
+}
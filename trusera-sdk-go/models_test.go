@@ -0,0 +1,63 @@
+package trusera
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterModel(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if err := client.RegisterModel(ModelInfo{}); err == nil {
+		t.Error("expected a model without a name to be rejected")
+	}
+	client.RegisterModel(ModelInfo{Name: "gpt-4o", Provider: "openai", Version: "2024-05-13"})
+	client.RegisterModel(ModelInfo{Name: "gpt-4o", Provider: "OpenAI", Version: "2024-08-06"})
+	client.RegisterModel(ModelInfo{Name: "llama-3-8b", Provider: "meta"})
+
+	models := client.Models()
+	if len(models) != 2 || models[0].Version != "2024-08-06" {
+		t.Errorf("expected re-registering to replace the model, got %+v", models)
+	}
+}
+
+func TestModelDrift(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	invoke := func(model, provider string) Event {
+		e := NewEvent(EventLLMInvoke, "chat").WithPayload("model", model).WithPayload("provider", provider)
+		client.Track(e)
+		return e
+	}
+	if e := invoke("gpt-4o-mini", "openai"); e.Payload["model_drift"] != nil {
+		t.Error("nothing should drift before a model is registered")
+	}
+
+	client.RegisterModel(ModelInfo{Name: "gpt-4o", Provider: "openai", Version: "2024-08-06"})
+	client.RegisterModel(ModelInfo{Name: "llama-3-8b"})
+	for _, tc := range []struct{ model, provider, drift string }{
+		{"gpt-4o", "openai", ""},
+		{"gpt-4o-2024-08-06", "openai", ""},
+		{"llama-3-8b", "ollama", ""},
+		{"gpt-4o-2024-11-20", "openai", "version"},
+		{"gpt-4o-mini", "openai", "unregistered"},
+		{"gpt-4o", "azure", "unregistered"},
+		{"gpt-4o-mini", "openai", "unregistered"},
+	} {
+		got, _ := invoke(tc.model, tc.provider).Payload["model_drift"].(string)
+		if got != tc.drift {
+			t.Errorf("%s/%s: expected drift %q, got %q", tc.provider, tc.model, tc.drift, got)
+		}
+	}
+
+	drifts := trackedEvents(client, EventModelDrift)
+	if len(drifts) != 3 {
+		t.Fatalf("expected one drift event per model, got %d", len(drifts))
+	}
+	if d := drifts[0]; d.Name != "gpt-4o-2024-11-20" || d.Payload["reason"] != "version" ||
+		d.Payload["expected"] != "gpt-4o-2024-08-06" || d.Payload["provider"] != "openai" {
+		t.Errorf("unexpected drift event %+v", d.Payload)
+	}
+}
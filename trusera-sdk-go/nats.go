@@ -0,0 +1,125 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NATSPublisher publishes a message to JetStream and waits for the stream's
+// acknowledgement. The SDK does not depend on nats.go; adapt it with:
+//
+//	type jetStream struct{ js jetstream.JetStream }
+//
+//	func (p jetStream) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+//		msg := nats.NewMsg(subject)
+//		msg.Data = data
+//		for k, v := range headers {
+//			msg.Header.Set(k, v)
+//		}
+//		_, err := p.js.PublishMsg(ctx, msg)
+//		return err
+//	}
+type NATSPublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error
+}
+
+// NATSOptions configures a NATSSink
+type NATSOptions struct {
+	// Subject may contain {agent} and {type}, replaced by the agent ID and
+	// the event type, e.g. "trusera.events.{agent}.{type}". Required.
+	Subject string
+	// Attempts is how many times each message is published before giving
+	// up; default 3
+	Attempts int
+	// Backoff is the wait before the first retry, doubling after each one;
+	// default 100ms
+	Backoff time.Duration
+}
+
+// NATSSink publishes each event to a JetStream subject as JSON with an
+// agent_id field, for use with WithSink or WithPrimarySink.
+//
+// Delivery is at least once: every message waits for the stream's
+// acknowledgement and is retried on failure, and a batch that still fails
+// is reported so it can be sent again. Messages carry the event ID in the
+// Nats-Msg-Id header, so JetStream discards the duplicates that retries
+// produce within the stream's duplicate window.
+type NATSSink struct {
+	publisher NATSPublisher
+	opts      NATSOptions
+}
+
+// NewNATSSink creates a sink that publishes through publisher
+func NewNATSSink(publisher NATSPublisher, opts NATSOptions) (*NATSSink, error) {
+	if publisher == nil {
+		return nil, errors.New("NATS publisher is required")
+	}
+	if opts.Subject == "" {
+		return nil, errors.New("NATS subject is required")
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 100 * time.Millisecond
+	}
+	return &NATSSink{publisher: publisher, opts: opts}, nil
+}
+
+// Send publishes a batch of events in order, stopping at the first message
+// that cannot be published
+func (s *NATSSink) Send(ctx context.Context, events []Event) error {
+	agentID := AgentIDFromContext(ctx)
+	for _, event := range events {
+		data, err := marshalAgentEvent(agentID, event)
+		if err != nil {
+			return err
+		}
+		subject := s.subject(agentID, event)
+		headers := map[string]string{
+			"Nats-Msg-Id":        event.ID,
+			"Trusera-Event-Type": string(event.Type),
+			"Content-Type":       "application/json",
+		}
+
+		err = s.publisher.Publish(ctx, subject, data, headers)
+		backoff := s.opts.Backoff
+		for attempt := 1; err != nil && attempt < s.opts.Attempts; attempt++ {
+			if werr := sleepCtx(ctx, backoff); werr != nil {
+				err = errors.Join(err, werr)
+				break
+			}
+			backoff *= 2
+			err = s.publisher.Publish(ctx, subject, data, headers)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", subject, err)
+		}
+	}
+	return nil
+}
+
+// subject expands the subject template for an event
+func (s *NATSSink) subject(agentID string, event Event) string {
+	if agentID == "" {
+		agentID = "unknown"
+	}
+	return strings.NewReplacer(
+		"{agent}", natsToken(agentID),
+		"{type}", natsToken(string(event.Type)),
+	).Replace(s.opts.Subject)
+}
+
+// natsToken makes a value safe to use as one subject token
+func natsToken(v string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, v)
+}
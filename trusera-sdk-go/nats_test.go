@@ -0,0 +1,108 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type natsMsg struct {
+	subject string
+	data    []byte
+	headers map[string]string
+}
+
+// fakeJetStream fails the first failures publish attempts
+type fakeJetStream struct {
+	failures int
+	attempts int
+	msgs     []natsMsg
+}
+
+func (js *fakeJetStream) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+	js.attempts++
+	if js.failures > 0 {
+		js.failures--
+		return errors.New("nats: timeout")
+	}
+	js.msgs = append(js.msgs, natsMsg{subject, data, headers})
+	return nil
+}
+
+func TestNATSSinkPublishes(t *testing.T) {
+	js := &fakeJetStream{}
+	sink, err := NewNATSSink(js, NATSOptions{Subject: "trusera.events.{agent}.{type}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newFlakyAPI(t)
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithAgentID("fleet.agent-1"),
+		WithFlushInterval(time.Hour),
+		WithSink("nats", sink))
+	defer client.Close()
+
+	event := NewEvent(EventToolCall, "search")
+	client.Track(event)
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(js.msgs) != 1 {
+		t.Fatalf("expected one message, got %d", len(js.msgs))
+	}
+	msg := js.msgs[0]
+	if msg.subject != "trusera.events.fleet_agent-1.tool_call" {
+		t.Errorf("unexpected subject %q", msg.subject)
+	}
+	if msg.headers["Nats-Msg-Id"] != event.ID || msg.headers["Trusera-Event-Type"] != "tool_call" {
+		t.Errorf("unexpected headers %v", msg.headers)
+	}
+	var decoded struct {
+		ID      string `json:"id"`
+		AgentID string `json:"agent_id"`
+	}
+	if json.Unmarshal(msg.data, &decoded); decoded.ID != event.ID || decoded.AgentID != "fleet.agent-1" {
+		t.Errorf("unexpected data %s", msg.data)
+	}
+}
+
+func TestNATSSinkRetries(t *testing.T) {
+	js := &fakeJetStream{failures: 2}
+	sink, _ := NewNATSSink(js, NATSOptions{Subject: "events", Backoff: time.Millisecond})
+
+	events := []Event{NewEvent(EventToolCall, "a"), NewEvent(EventToolCall, "b")}
+	if err := sink.Send(context.Background(), events); err != nil {
+		t.Fatalf("expected the retries to succeed: %v", err)
+	}
+	if js.attempts != 4 || len(js.msgs) != 2 {
+		t.Errorf("expected 4 attempts for 2 messages, got %d attempts and %d messages", js.attempts, len(js.msgs))
+	}
+
+	js = &fakeJetStream{failures: 10}
+	sink, _ = NewNATSSink(js, NATSOptions{Subject: "events", Attempts: 2, Backoff: time.Millisecond})
+	err := sink.Send(context.Background(), events)
+	if err == nil || !strings.Contains(err.Error(), "failed to publish to events") || js.attempts != 2 {
+		t.Errorf("expected the batch to fail after 2 attempts, got %v after %d", err, js.attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sink, _ = NewNATSSink(&fakeJetStream{failures: 10}, NATSOptions{Subject: "events", Backoff: time.Hour})
+	if err := sink.Send(ctx, events); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected retries to stop with the context, got %v", err)
+	}
+}
+
+func TestNewNATSSinkValidates(t *testing.T) {
+	if _, err := NewNATSSink(&fakeJetStream{}, NATSOptions{}); err == nil {
+		t.Error("expected an error without a subject")
+	}
+	if _, err := NewNATSSink(nil, NATSOptions{Subject: "s"}); err == nil {
+		t.Error("expected an error without a publisher")
+	}
+}
@@ -0,0 +1,336 @@
+package trusera
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	offlineSuffix       = ".jsonl"
+	offlineActiveSuffix = ".jsonl.active"
+	defaultFileMaxBytes = 10 << 20
+)
+
+// FileSinkOptions configures a FileSink
+type FileSinkOptions struct {
+	MaxBytes int64         // Rotate once the current file reaches this size; default 10 MiB
+	MaxAge   time.Duration // Rotate files older than this; 0 rotates by size only
+	MaxFiles int           // Delete the oldest finished files beyond this many; 0 keeps all
+
+	// EncryptionKey, if set, encrypts each line with AES-GCM; a client's
+	// WithEncryptionAtRest key takes precedence in offline mode
+	EncryptionKey []byte
+}
+
+// FileSink writes events as JSON lines, with an agent_id field, to rotating
+// files in a directory. The file being written ends in .jsonl.active and is
+// renamed to .jsonl when it is rotated or the sink is closed; files left
+// active by a crashed process are finished when the next sink starts.
+// Finished files can be sent with Client.Upload or `trusera upload`.
+type FileSink struct {
+	dir    string
+	opts   FileSinkOptions
+	cipher *lineCipher
+
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	path    string
+	size    int64
+	opened  time.Time
+	started bool
+	seq     int
+}
+
+// NewFileSink creates a sink writing to dir, which is created on first use
+func NewFileSink(dir string, opts FileSinkOptions) *FileSink {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultFileMaxBytes
+	}
+	s := &FileSink{dir: dir, opts: opts}
+	if opts.EncryptionKey != nil {
+		s.cipher = newLineCipher(opts.EncryptionKey)
+	}
+	return s
+}
+
+// WithOfflineMode writes all events to rotating JSONL files in dir instead
+// of sending them over the network, for air-gapped and CI environments.
+// The files are uploaded later with Client.Upload or `trusera upload`.
+// Retries, dead letters and the persistent queue apply to the files as
+// they would to the API.
+func WithOfflineMode(dir string) Option {
+	return func(c *Client) {
+		sink := NewFileSink(dir, FileSinkOptions{})
+		c.primary = sink
+		c.offline = sink
+	}
+}
+
+// Send appends a batch of events to the current file
+func (s *FileSink) Send(ctx context.Context, events []Event) error {
+	agentID := AgentIDFromContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.startLocked(); err != nil {
+		return err
+	}
+	if s.f != nil && s.dueLocked() {
+		if err := s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	if s.f == nil {
+		if err := s.openLocked(); err != nil {
+			return err
+		}
+	}
+
+	for _, event := range events {
+		line, err := marshalAgentEvent(agentID, event)
+		if err != nil {
+			return err
+		}
+		if line, err = s.cipher.seal(line); err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if _, err := s.w.Write(line); err != nil {
+			return fmt.Errorf("failed to write events: %w", err)
+		}
+		s.size += int64(len(line))
+	}
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write events: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync events: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotateLocked()
+}
+
+// startLocked creates the directory and finishes files a previous process
+// left active
+func (s *FileSink) startLocked() error {
+	if s.started {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create offline directory: %w", err)
+	}
+	leftover, err := filepath.Glob(filepath.Join(s.dir, "*"+offlineActiveSuffix))
+	if err != nil {
+		return err
+	}
+	for _, path := range leftover {
+		if err := os.Rename(path, strings.TrimSuffix(path, ".active")); err != nil {
+			return fmt.Errorf("failed to finish offline file: %w", err)
+		}
+	}
+	s.started = true
+	return nil
+}
+
+// dueLocked reports whether the current file should be rotated
+func (s *FileSink) dueLocked() bool {
+	return s.size >= s.opts.MaxBytes || s.opts.MaxAge > 0 && time.Since(s.opened) >= s.opts.MaxAge
+}
+
+func (s *FileSink) openLocked() error {
+	s.seq++
+	name := fmt.Sprintf("events-%s-%04d%s", time.Now().UTC().Format("20060102T150405.000000000"), s.seq, offlineActiveSuffix)
+	path := filepath.Join(s.dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open offline file: %w", err)
+	}
+	s.f, s.w, s.path, s.size, s.opened = f, bufio.NewWriter(f), path, 0, time.Now()
+	return nil
+}
+
+// rotateLocked finishes the current file, if any, and applies MaxFiles
+func (s *FileSink) rotateLocked() error {
+	if s.f == nil {
+		return nil
+	}
+	err := errors.Join(s.w.Flush(), s.f.Close())
+	if err == nil {
+		err = os.Rename(s.path, strings.TrimSuffix(s.path, ".active"))
+	}
+	s.f, s.w = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to finish offline file: %w", err)
+	}
+
+	if s.opts.MaxFiles > 0 {
+		files, err := offlineFiles(s.dir)
+		if err != nil {
+			return err
+		}
+		for len(files) > s.opts.MaxFiles {
+			if err := os.Remove(files[0]); err != nil {
+				return fmt.Errorf("failed to remove old offline file: %w", err)
+			}
+			files = files[1:]
+		}
+	}
+	return nil
+}
+
+// offlineFiles returns the finished files in dir, oldest first
+func offlineFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+offlineSuffix))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// offlineEvent is one line of an offline file
+type offlineEvent struct {
+	Event
+	AgentID string `json:"agent_id,omitempty"`
+}
+
+// Upload sends the events in the finished files of an offline directory to
+// the Trusera API and removes each file once it is delivered. It returns how
+// many events were uploaded. Events keep the agent ID they were written
+// with; those without one are sent under the client's. When a request
+// fails, the file keeps only the events that were not sent and Upload stops.
+func (c *Client) Upload(ctx context.Context, dir string) (int, error) {
+	files, err := offlineFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	uploaded := 0
+	var dropped error
+	for _, path := range files {
+		events, err := readOfflineFile(path, c.cipher)
+		if err != nil && !errors.Is(err, ErrUnencrypted) {
+			return uploaded, errors.Join(dropped, err)
+		}
+		dropped = errors.Join(dropped, err)
+
+		sent := 0
+		for sent < len(events) {
+			// Each request carries the events of one agent
+			agentID := events[sent].AgentID
+			end := sent
+			for end < len(events) && events[end].AgentID == agentID {
+				end++
+			}
+			if agentID == "" {
+				agentID = c.agentID
+			}
+
+			batch := make([]Event, 0, end-sent)
+			for _, e := range events[sent:end] {
+				batch = append(batch, e.Event)
+			}
+			for _, b := range c.batches(batch) {
+				if err := c.uploadBatch(ctx, agentID, b); err != nil {
+					return uploaded, errors.Join(dropped, err, rewriteOfflineFile(path, events[sent:], c.cipher))
+				}
+				sent += len(b)
+				uploaded += len(b)
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return uploaded, errors.Join(dropped, fmt.Errorf("failed to remove uploaded file: %w", err))
+		}
+	}
+	return uploaded, dropped
+}
+
+// uploadBatch posts a batch to the API with the client's retry policy
+func (c *Client) uploadBatch(ctx context.Context, agentID string, events []Event) error {
+	body, err := c.marshalBatch(agentID, events)
+	if err != nil {
+		return err
+	}
+	_, err = c.retrying(ctx, func() ([]byte, error) {
+		return c.postNegotiated(ctx, body)
+	})
+	return err
+}
+
+// readOfflineFile parses an offline file, skipping malformed lines and
+// dropping plaintext lines of an encrypted file with ErrUnencrypted
+func readOfflineFile(path string, cipher *lineCipher) ([]offlineEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline file: %w", err)
+	}
+	defer f.Close()
+
+	var events []offlineEvent
+	plaintext := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line, err := cipher.open(scanner.Bytes())
+		if errors.Is(err, ErrEncryptionKey) {
+			return nil, fmt.Errorf("failed to read offline file: %w", err)
+		}
+		if errors.Is(err, errPlaintextLine) {
+			plaintext++
+		}
+		var e offlineEvent
+		if err != nil || json.Unmarshal(line, &e) != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read offline file: %w", err)
+	}
+	return events, unencryptedErr(path, plaintext)
+}
+
+// rewriteOfflineFile atomically replaces an offline file with the events
+// that remain to be uploaded
+func rewriteOfflineFile(path string, events []offlineEvent, cipher *lineCipher) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite offline file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range events {
+		line, err := json.Marshal(e)
+		if err == nil {
+			line, err = cipher.seal(line)
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("failed to rewrite offline file: %w", err)
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := errors.Join(w.Flush(), f.Close()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to rewrite offline file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
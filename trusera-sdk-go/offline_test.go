@@ -0,0 +1,192 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOfflineModeWritesFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "events")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key",
+		WithBaseURL(server.URL),
+		WithAgentID("agent-1"),
+		WithFlushInterval(time.Hour),
+		WithOfflineMode(dir))
+	client.Track(NewEvent(EventToolCall, "search"))
+	client.Track(NewEvent(EventLLMInvoke, "chat"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	active, _ := filepath.Glob(filepath.Join(dir, "*"+offlineActiveSuffix))
+	if len(active) != 1 {
+		t.Fatalf("expected one active file, got %v", active)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("offline mode should not reach the network, got %d requests", requests)
+	}
+
+	files, _ := offlineFiles(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected the file to be finished on close, got %v", files)
+	}
+	events, err := readOfflineFile(files[0], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Name != "search" || events[0].AgentID != "agent-1" {
+		t.Errorf("unexpected events %+v", events)
+	}
+	if info, _ := os.Stat(files[0]); info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file to be private, got %v", info.Mode().Perm())
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink(dir, FileSinkOptions{MaxBytes: 1, MaxFiles: 2})
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := sink.Send(context.Background(), []Event{NewEvent(EventToolCall, name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := offlineFiles(dir)
+	if len(files) != 2 {
+		t.Fatalf("expected MaxFiles to keep 2 files, got %v", files)
+	}
+	var names []string
+	for _, f := range files {
+		events, _ := readOfflineFile(f, nil)
+		for _, e := range events {
+			names = append(names, e.Name)
+		}
+	}
+	if strings.Join(names, ",") != "c,d" {
+		t.Errorf("expected the newest events to be kept, got %v", names)
+	}
+}
+
+func TestFileSinkFinishesLeftoverFiles(t *testing.T) {
+	dir := t.TempDir()
+	crashed := NewFileSink(dir, FileSinkOptions{})
+	if err := crashed.Send(context.Background(), []Event{NewEvent(EventToolCall, "before-crash")}); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := NewFileSink(dir, FileSinkOptions{})
+	if err := sink.Send(context.Background(), []Event{NewEvent(EventToolCall, "after")}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := offlineFiles(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected the crashed file to be finished, got %v", files)
+	}
+	sink.Close()
+}
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink(dir, FileSinkOptions{})
+	ctx1 := context.WithValue(context.Background(), agentIDKey, "agent-1")
+	ctx2 := context.WithValue(context.Background(), agentIDKey, "agent-2")
+	sink.Send(ctx1, []Event{NewEvent(EventToolCall, "a"), NewEvent(EventToolCall, "b")})
+	sink.Send(ctx2, []Event{NewEvent(EventToolCall, "c")})
+	sink.Send(context.Background(), []Event{NewEvent(EventToolCall, "d")})
+	sink.Close()
+
+	var mu sync.Mutex
+	batches := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			AgentID string  `json:"agent_id"`
+			Events  []Event `json:"events"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		for _, e := range payload.Events {
+			batches[payload.AgentID] = append(batches[payload.AgentID], e.Name)
+		}
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithAgentID("uploader"), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	n, err := client.Upload(context.Background(), dir)
+	if err != nil || n != 4 {
+		t.Fatalf("expected 4 events uploaded, got %d (%v)", n, err)
+	}
+	if strings.Join(batches["agent-1"], ",") != "a,b" || strings.Join(batches["agent-2"], ",") != "c" ||
+		strings.Join(batches["uploader"], ",") != "d" {
+		t.Errorf("events should be sent under their agent IDs, got %v", batches)
+	}
+	if files, _ := offlineFiles(dir); len(files) != 0 {
+		t.Errorf("uploaded files should be removed, got %v", files)
+	}
+}
+
+func TestUploadKeepsUnsentEvents(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink(dir, FileSinkOptions{})
+	sink.Send(context.WithValue(context.Background(), agentIDKey, "agent-1"), []Event{NewEvent(EventToolCall, "a")})
+	sink.Send(context.WithValue(context.Background(), agentIDKey, "agent-2"), []Event{NewEvent(EventToolCall, "b")})
+	sink.Close()
+
+	api := newFlakyAPI(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 1 {
+			api.fail.Store(true)
+		}
+		api.server.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	n, err := client.Upload(context.Background(), dir)
+	if err == nil || n != 1 {
+		t.Fatalf("expected the second request to fail after 1 event, got %d (%v)", n, err)
+	}
+	files, _ := offlineFiles(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected the file to be kept, got %v", files)
+	}
+	events, _ := readOfflineFile(files[0], nil)
+	if len(events) != 1 || events[0].Name != "b" || events[0].AgentID != "agent-2" {
+		t.Errorf("expected only the unsent event to remain, got %+v", events)
+	}
+
+	api.fail.Store(false)
+	requests = 0
+	if n, err := client.Upload(context.Background(), dir); err != nil || n != 1 {
+		t.Errorf("expected the retry to upload the rest, got %d (%v)", n, err)
+	}
+	if got := strings.Join(api.received(), ","); got != "a,b" {
+		t.Errorf("expected each event once, got %v", got)
+	}
+}
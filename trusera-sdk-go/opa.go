@@ -0,0 +1,425 @@
+package trusera
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestPolicy decides whether an outbound HTTP request is allowed.
+// Both *CELPolicy and *OPAEvaluator implement it.
+type RequestPolicy interface {
+	EvaluateRequest(req *http.Request) PolicyDecision
+}
+
+// OPABundle holds the Rego modules and data documents of an OPA bundle
+type OPABundle struct {
+	Modules  map[string]string // Rego source keyed by bundle-relative path
+	Data     map[string]any    // Merged data.json documents
+	Manifest map[string]any    // Contents of .manifest, if present
+}
+
+// LoadOPABundle reads an OPA bundle from a .tar.gz archive or a directory
+func LoadOPABundle(bundlePath string) (*OPABundle, error) {
+	info, err := os.Stat(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	bundle := &OPABundle{
+		Modules: make(map[string]string),
+		Data:    make(map[string]any),
+	}
+
+	if info.IsDir() {
+		err = filepath.WalkDir(bundlePath, func(p string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil || d.IsDir() {
+				return walkErr
+			}
+			rel, err := filepath.Rel(bundlePath, p)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return bundle.addFile(filepath.ToSlash(rel), content)
+		})
+	} else {
+		err = bundle.readArchive(bundlePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load bundle: %w", err)
+	}
+
+	if len(bundle.Modules) == 0 {
+		return nil, errors.New("bundle contains no Rego modules")
+	}
+
+	return bundle, nil
+}
+
+// readArchive reads a gzipped tarball bundle
+func (b *OPABundle) readArchive(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := b.addFile(strings.TrimPrefix(path.Clean("/"+hdr.Name), "/"), content); err != nil {
+			return err
+		}
+	}
+}
+
+// addFile classifies a bundle file as a module, data document or manifest
+func (b *OPABundle) addFile(name string, content []byte) error {
+	switch {
+	case strings.HasSuffix(name, ".rego"):
+		b.Modules[name] = string(content)
+
+	case path.Base(name) == ".manifest":
+		if err := json.Unmarshal(content, &b.Manifest); err != nil {
+			return fmt.Errorf("invalid manifest: %w", err)
+		}
+
+	case path.Base(name) == "data.json":
+		var doc any
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return fmt.Errorf("invalid data file %s: %w", name, err)
+		}
+		dir := path.Dir(name)
+		if dir == "." {
+			if m, ok := doc.(map[string]any); ok {
+				for k, v := range m {
+					b.Data[k] = v
+				}
+				return nil
+			}
+			return fmt.Errorf("root data file %s must be an object", name)
+		}
+		setDataPath(b.Data, strings.Split(dir, "/"), doc)
+	}
+	return nil
+}
+
+// setDataPath stores value at the nested key path, creating objects as needed
+func setDataPath(root map[string]any, keys []string, value any) {
+	node := root
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := node[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			node[key] = child
+		}
+		node = child
+	}
+	node[keys[len(keys)-1]] = value
+}
+
+// OPAEvaluator evaluates Rego policies through an OPA server's REST API
+type OPAEvaluator struct {
+	// ServerURL is the OPA base URL, e.g. http://localhost:8181
+	ServerURL string
+	// DecisionPath is the rule queried for decisions, e.g. "trusera/egress/allow".
+	// A boolean result allows or denies; a set/array result lists deny reasons;
+	// an object result may carry "allow" and "reasons" fields.
+	DecisionPath string
+	// FailClosed denies requests when OPA cannot be reached or returns an
+	// unusable result. By default such errors allow the request.
+	FailClosed bool
+	// HTTPClient is used to reach OPA; defaults to a client with a 2s timeout
+	HTTPClient *http.Client
+}
+
+// NewOPAEvaluator creates an evaluator for the given OPA server and decision path
+func NewOPAEvaluator(serverURL, decisionPath string) *OPAEvaluator {
+	return &OPAEvaluator{
+		ServerURL:    strings.TrimRight(serverURL, "/"),
+		DecisionPath: strings.Trim(decisionPath, "/"),
+		HTTPClient:   &http.Client{Timeout: 2 * time.Second},
+	}
+}
+
+func (o *OPAEvaluator) httpClient() *http.Client {
+	if o.HTTPClient != nil {
+		return o.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// PushBundle uploads the bundle's modules and data to the OPA server so the
+// server evaluates the same policies the organization already maintains.
+// Data is written under each root of the manifest, or each top-level key
+// without one, so documents outside the bundle are left in place.
+func (o *OPAEvaluator) PushBundle(ctx context.Context, bundle *OPABundle) error {
+	roots := bundle.roots()
+	if p := dataOutsideRoots(bundle.Data, nil, roots); p != "" {
+		return fmt.Errorf("bundle data %s is outside the manifest roots", p)
+	}
+
+	names := make([]string, 0, len(bundle.Modules))
+	for name := range bundle.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		endpoint := o.ServerURL + "/v1/policies/" + url.PathEscape(name)
+		if err := o.put(ctx, endpoint, "text/plain", []byte(bundle.Modules[name])); err != nil {
+			return fmt.Errorf("failed to upload module %s: %w", name, err)
+		}
+	}
+
+	for _, root := range roots {
+		keys := strings.Split(root, "/")
+		doc, ok := dataAt(bundle.Data, keys)
+		if !ok {
+			continue
+		}
+		body, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal bundle data %s: %w", root, err)
+		}
+		for i, key := range keys {
+			keys[i] = url.PathEscape(key)
+		}
+		if err := o.put(ctx, o.ServerURL+"/v1/data/"+strings.Join(keys, "/"), "application/json", body); err != nil {
+			return fmt.Errorf("failed to upload bundle data %s: %w", root, err)
+		}
+	}
+
+	return nil
+}
+
+// roots returns the data paths the bundle owns: the manifest's roots, or
+// the top-level data keys when it declares none or the whole tree
+func (b *OPABundle) roots() []string {
+	var roots []string
+	declared, _ := b.Manifest["roots"].([]any)
+	for _, r := range declared {
+		root, _ := r.(string)
+		root = strings.Trim(root, "/")
+		if root == "" {
+			roots = nil
+			break
+		}
+		roots = append(roots, root)
+	}
+	if len(roots) == 0 {
+		for key := range b.Data {
+			roots = append(roots, key)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// dataAt returns the document at the nested key path
+func dataAt(root map[string]any, keys []string) (any, bool) {
+	var node any = root
+	for _, key := range keys {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// dataOutsideRoots returns the path of the first document under node that
+// no root covers, or "" if every document is covered
+func dataOutsideRoots(node map[string]any, prefix []string, roots []string) string {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := append(prefix[:len(prefix):len(prefix)], key)
+		p := strings.Join(keyPath, "/")
+		covered, parent := false, false
+		for _, root := range roots {
+			covered = covered || p == root || strings.HasPrefix(p, root+"/")
+			parent = parent || strings.HasPrefix(root, p+"/")
+		}
+		if covered {
+			continue
+		}
+		child, ok := node[key].(map[string]any)
+		if !parent || !ok {
+			return p
+		}
+		if q := dataOutsideRoots(child, keyPath, roots); q != "" {
+			return q
+		}
+	}
+	return ""
+}
+
+func (o *OPAEvaluator) put(ctx context.Context, endpoint, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySnippet))
+		return fmt.Errorf("OPA returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Query evaluates the decision path with the given input document
+func (o *OPAEvaluator) Query(ctx context.Context, input any) (PolicyDecision, error) {
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to marshal input: %w", err)
+	}
+
+	endpoint := o.ServerURL + "/v1/data/" + o.DecisionPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient().Do(req)
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to query OPA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return PolicyDecision{}, fmt.Errorf("OPA returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Result any `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to decode OPA response: %w", err)
+	}
+
+	return o.interpret(result.Result)
+}
+
+// interpret converts an OPA result document into a PolicyDecision
+func (o *OPAEvaluator) interpret(result any) (PolicyDecision, error) {
+	matched := []string{o.DecisionPath}
+
+	switch v := result.(type) {
+	case bool:
+		if v {
+			return PolicyDecision{Decision: "Allow", Reasons: []string{o.DecisionPath + " = true"}, Matched: matched}, nil
+		}
+		return PolicyDecision{Decision: "Deny", Reasons: []string{o.DecisionPath + " = false"}, Matched: matched}, nil
+
+	case []any:
+		if len(v) == 0 {
+			return PolicyDecision{Decision: "Allow", Reasons: []string{"No deny rules matched"}, Matched: []string{}}, nil
+		}
+		return PolicyDecision{Decision: "Deny", Reasons: stringsOf(v), Matched: matched}, nil
+
+	case map[string]any:
+		allow, ok := v["allow"].(bool)
+		if !ok {
+			return PolicyDecision{}, fmt.Errorf("OPA result for %s has no boolean allow field", o.DecisionPath)
+		}
+		reasons := stringsOf(v["reasons"])
+		if len(reasons) == 0 {
+			reasons = []string{fmt.Sprintf("%s.allow = %t", o.DecisionPath, allow)}
+		}
+		decision := "Deny"
+		if allow {
+			decision = "Allow"
+		}
+		return PolicyDecision{Decision: decision, Reasons: reasons, Matched: matched}, nil
+
+	case nil:
+		return PolicyDecision{}, fmt.Errorf("OPA decision %s is undefined", o.DecisionPath)
+	}
+
+	return PolicyDecision{}, fmt.Errorf("unsupported OPA result type %T", result)
+}
+
+// EvaluateRequest queries OPA with the request as input. The input document
+// has the same shape as the CEL request variable.
+func (o *OPAEvaluator) EvaluateRequest(req *http.Request) PolicyDecision {
+	decision, err := o.Query(req.Context(), requestVars(req)["request"])
+	if err == nil {
+		return decision
+	}
+
+	if o.FailClosed {
+		return PolicyDecision{Decision: "Deny", Reasons: []string{"OPA unavailable (fail closed): " + err.Error()}, Matched: []string{}}
+	}
+	return PolicyDecision{Decision: "Allow", Reasons: []string{"OPA unavailable (fail open): " + err.Error()}, Matched: []string{}}
+}
+
+// stringsOf converts a JSON array into strings
+func stringsOf(v any) []string {
+	items, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		} else {
+			b, _ := json.Marshal(item)
+			out = append(out, string(b))
+		}
+	}
+	return out
+}
@@ -0,0 +1,291 @@
+package trusera
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testRego = `package trusera.egress
+
+default allow := false
+
+allow if input.host == "api.openai.com"
+`
+
+func writeTestBundle(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	files := map[string]string{
+		"policies/egress.rego":     testRego,
+		"egress/allowed/data.json": `["api.openai.com"]`,
+		".manifest":                `{"revision":"abc123"}`,
+	}
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write tar content: %v", err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := os.WriteFile(bundlePath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+	return bundlePath
+}
+
+func TestLoadOPABundleArchive(t *testing.T) {
+	bundle, err := LoadOPABundle(writeTestBundle(t))
+	if err != nil {
+		t.Fatalf("LoadOPABundle failed: %v", err)
+	}
+
+	if bundle.Modules["policies/egress.rego"] != testRego {
+		t.Errorf("expected rego module to be loaded, got %v", bundle.Modules)
+	}
+
+	egress, ok := bundle.Data["egress"].(map[string]any)
+	if !ok || egress["allowed"] == nil {
+		t.Errorf("expected nested data document, got %v", bundle.Data)
+	}
+
+	if bundle.Manifest["revision"] != "abc123" {
+		t.Errorf("expected manifest revision, got %v", bundle.Manifest)
+	}
+}
+
+func TestLoadOPABundleDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "egress.rego"), []byte(testRego), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.json"), []byte(`{"threshold": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := LoadOPABundle(dir)
+	if err != nil {
+		t.Fatalf("LoadOPABundle failed: %v", err)
+	}
+
+	if len(bundle.Modules) != 1 {
+		t.Errorf("expected 1 module, got %d", len(bundle.Modules))
+	}
+
+	if bundle.Data["threshold"] != float64(3) {
+		t.Errorf("expected root data merged, got %v", bundle.Data)
+	}
+}
+
+func TestLoadOPABundleWithoutModules(t *testing.T) {
+	if _, err := LoadOPABundle(t.TempDir()); err == nil {
+		t.Error("expected error for bundle without modules")
+	}
+}
+
+// fakeOPA emulates the OPA policy and data APIs for a single boolean rule
+func fakeOPA(t *testing.T, result func(input map[string]any) any) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	uploads := &sync.Map{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			uploads.Store(r.URL.Path, string(body))
+			w.WriteHeader(http.StatusOK)
+
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/v1/data/"):
+			var req struct {
+				Input map[string]any `json:"input"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"result": result(req.Input)})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, uploads
+}
+
+func TestOPAEvaluatorPushBundle(t *testing.T) {
+	server, uploads := fakeOPA(t, func(map[string]any) any { return true })
+	defer server.Close()
+
+	bundle, err := LoadOPABundle(writeTestBundle(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opa := NewOPAEvaluator(server.URL, "trusera/egress/allow")
+	if err := opa.PushBundle(context.Background(), bundle); err != nil {
+		t.Fatalf("PushBundle failed: %v", err)
+	}
+
+	if _, ok := uploads.Load("/v1/policies/policies/egress.rego"); !ok {
+		t.Error("expected module upload")
+	}
+
+	if _, ok := uploads.Load("/v1/data/egress"); !ok {
+		t.Error("expected data upload under its root")
+	}
+}
+
+func TestOPAEvaluatorPushBundleKeepsOtherData(t *testing.T) {
+	var mu sync.Mutex
+	data := map[string]any{
+		"other":  map[string]any{"keep": true},
+		"egress": map[string]any{"limits": map[string]any{"max": 5.0}},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var doc any
+		if r.Method != http.MethodPut || json.NewDecoder(r.Body).Decode(&doc) != nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if keys := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/data"), "/"); keys != "" {
+			setDataPath(data, strings.Split(keys, "/"), doc)
+		} else {
+			data = doc.(map[string]any)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	bundle := &OPABundle{
+		Modules:  map[string]string{"egress.rego": testRego},
+		Data:     map[string]any{"egress": map[string]any{"allowed": []any{"api.openai.com"}}},
+		Manifest: map[string]any{"roots": []any{"egress/allowed"}},
+	}
+	if err := NewOPAEvaluator(server.URL, "trusera/egress/allow").PushBundle(context.Background(), bundle); err != nil {
+		t.Fatalf("PushBundle failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if other, _ := data["other"].(map[string]any); other["keep"] != true {
+		t.Errorf("expected unrelated data to survive, got %v", data)
+	}
+	egress, _ := data["egress"].(map[string]any)
+	if egress["limits"] == nil || egress["allowed"] == nil {
+		t.Errorf("expected the bundle root written beside existing data, got %v", egress)
+	}
+}
+
+func TestOPAEvaluatorPushBundleDataOutsideRoots(t *testing.T) {
+	server, uploads := fakeOPA(t, func(map[string]any) any { return true })
+	defer server.Close()
+
+	bundle := &OPABundle{
+		Modules:  map[string]string{"egress.rego": testRego},
+		Data:     map[string]any{"egress": map[string]any{"allowed": []any{}, "denied": []any{}}},
+		Manifest: map[string]any{"roots": []any{"egress/allowed"}},
+	}
+	err := NewOPAEvaluator(server.URL, "trusera/egress/allow").PushBundle(context.Background(), bundle)
+	if err == nil || !strings.Contains(err.Error(), "egress/denied") {
+		t.Errorf("expected an error for data outside the roots, got %v", err)
+	}
+	if _, ok := uploads.Load("/v1/policies/egress.rego"); ok {
+		t.Error("expected nothing uploaded")
+	}
+}
+
+func TestOPAEvaluatorResultShapes(t *testing.T) {
+	opa := NewOPAEvaluator("http://unused", "trusera/egress")
+
+	tests := []struct {
+		name     string
+		result   any
+		decision string
+		wantErr  bool
+	}{
+		{"boolean allow", true, "Allow", false},
+		{"boolean deny", false, "Deny", false},
+		{"empty deny set", []any{}, "Allow", false},
+		{"deny set", []any{"host not allowed"}, "Deny", false},
+		{"object", map[string]any{"allow": false, "reasons": []any{"nope"}}, "Deny", false},
+		{"object without allow", map[string]any{"x": 1}, "", true},
+		{"undefined", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := opa.interpret(tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if decision.Decision != tt.decision {
+				t.Errorf("expected %q, got %q", tt.decision, decision.Decision)
+			}
+		})
+	}
+}
+
+func TestOPAEvaluatorInInterceptor(t *testing.T) {
+	opaServer, _ := fakeOPA(t, func(input map[string]any) any {
+		return input["path"] != "/forbidden"
+	})
+	defer opaServer.Close()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Policy:      NewOPAEvaluator(opaServer.URL, "trusera/egress/allow"),
+	})
+
+	resp, err := httpClient.Get(backend.URL + "/allowed")
+	if err != nil {
+		t.Fatalf("expected request to be allowed: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := httpClient.Get(backend.URL + "/forbidden"); err == nil {
+		t.Error("expected request to be blocked by OPA")
+	}
+}
+
+func TestOPAEvaluatorFailureModes(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+
+	opa := NewOPAEvaluator("http://127.0.0.1:1", "trusera/egress/allow")
+	if decision := opa.EvaluateRequest(req); decision.Decision != "Allow" {
+		t.Errorf("expected fail open by default, got %s", decision.Decision)
+	}
+
+	opa.FailClosed = true
+	if decision := opa.EvaluateRequest(req); decision.Decision != "Deny" {
+		t.Errorf("expected fail closed, got %s", decision.Decision)
+	}
+}
@@ -0,0 +1,198 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ErrNotAcknowledged is returned when the API accepted an events request
+// without acknowledging every sequence number in it
+var ErrNotAcknowledged = errors.New("trusera: events not acknowledged")
+
+// AckError reports a batch the API acknowledged only in part. It wraps
+// ErrNotAcknowledged.
+type AckError struct {
+	Stream string
+	Acked  uint64 // The highest sequence number acknowledged
+	Last   uint64 // The highest sequence number sent
+}
+
+func (e *AckError) Error() string {
+	return fmt.Sprintf("API acknowledged stream %s up to sequence %d of %d", e.Stream, e.Acked, e.Last)
+}
+
+func (e *AckError) Unwrap() error {
+	return ErrNotAcknowledged
+}
+
+// GapError reports sequence numbers the API never received, From to To
+// inclusive, as the API reported them. It is passed to the error handler.
+type GapError struct {
+	Stream string
+	From   uint64
+	To     uint64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("API is missing sequence %d to %d of stream %s", e.From, e.To, e.Stream)
+}
+
+// WithOrderedDelivery turns on acknowledged, in-order delivery for
+// deployments that must account for every event. Each client numbers its
+// events 1, 2, 3... in the order they are buffered, under a stream ID
+// unique to the client, set in the events' stream and sequence fields.
+// Batches are sent one at a time in sequence order, and the API must
+// answer each with {"acked_sequence": n}; a batch that is not fully
+// acknowledged is retried, and while the client is open its unacknowledged
+// events go back to the front of the buffer rather than being dropped, so
+// delivery is at least once. The API may answer {"expected_sequence": n}
+// when a batch starts past n; the missing range is reported to the error
+// handler as a *GapError. TrackSync flushes the buffer along with its
+// event, so it never overtakes earlier events.
+//
+// Sequence numbers are not covered by event signatures. Events spilled to
+// disk by SpillToDisk are sent after the buffer, out of order; the API can
+// restore the order from their sequence numbers.
+func WithOrderedDelivery() Option {
+	return func(c *Client) {
+		c.ordered = &orderedDelivery{}
+	}
+}
+
+// orderedDelivery is the state of a client's ordered delivery
+type orderedDelivery struct {
+	stream string
+	next   uint64 // Guarded by the client's mu
+	acked  atomic.Uint64
+}
+
+// sequenceLocked numbers an event about to be buffered. It must be called
+// with c.mu held.
+func (c *Client) sequenceLocked(event Event) Event {
+	if c.ordered == nil {
+		return event
+	}
+	c.ordered.next++
+	event.Stream = c.ordered.stream
+	event.Sequence = c.ordered.next
+	return event
+}
+
+// Acknowledged returns the highest sequence number of this client's stream
+// the API has acknowledged; see WithOrderedDelivery
+func (c *Client) Acknowledged() uint64 {
+	if c.ordered == nil {
+		return 0
+	}
+	return c.ordered.acked.Load()
+}
+
+// delivery describes the sequence numbers of a batch in an events request,
+// or returns nil if its events are not numbered
+func delivery(events []Event) map[string]any {
+	if len(events) == 0 || events[0].Sequence == 0 {
+		return nil
+	}
+	return map[string]any{
+		"stream":         events[0].Stream,
+		"first_sequence": events[0].Sequence,
+		"last_sequence":  events[len(events)-1].Sequence,
+	}
+}
+
+// checkAck verifies that an events reply acknowledges a batch, reporting
+// any gap the API found before it
+func (c *Client) checkAck(reply []byte, events []Event) error {
+	if len(events) == 0 || events[0].Sequence == 0 {
+		return nil
+	}
+	first, last := events[0], events[len(events)-1]
+
+	var ack struct {
+		Acked    uint64 `json:"acked_sequence"`
+		Expected uint64 `json:"expected_sequence"`
+	}
+	json.Unmarshal(reply, &ack)
+	if ack.Expected > 0 && ack.Expected < first.Sequence {
+		gap := &GapError{Stream: first.Stream, From: ack.Expected, To: first.Sequence - 1}
+		c.log(slog.LevelWarn, "sequence gap", "stream", gap.Stream, "from", gap.From, "to", gap.To)
+		if c.errorHandler != nil {
+			c.errorHandler(gap)
+		}
+	}
+	c.acknowledge(first.Stream, min(ack.Acked, last.Sequence))
+	if ack.Acked < last.Sequence {
+		return &AckError{Stream: first.Stream, Acked: ack.Acked, Last: last.Sequence}
+	}
+	return nil
+}
+
+// acknowledge records the highest acknowledged sequence of the client's
+// own stream; replayed events of earlier runs belong to other streams
+func (c *Client) acknowledge(stream string, seq uint64) {
+	if c.ordered == nil || stream != c.ordered.stream {
+		return
+	}
+	for {
+		acked := c.ordered.acked.Load()
+		if seq <= acked || c.ordered.acked.CompareAndSwap(acked, seq) {
+			return
+		}
+	}
+}
+
+// ackedIn returns how many events at the start of a failed batch the API
+// acknowledged anyway
+func ackedIn(batch []Event, err error) int {
+	var ae *AckError
+	if !errors.As(err, &ae) {
+		return 0
+	}
+	n := 0
+	for n < len(batch) && batch[n].Sequence <= ae.Acked {
+		n++
+	}
+	return n
+}
+
+// requeue puts undelivered events back at the front of the buffer, ahead
+// of the events tracked since they were taken. It reports false once the
+// client is closed, when the events can no longer be kept.
+func (c *Client) requeue(events []Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	c.events = append(events[:len(events):len(events)], c.events...)
+	if c.maxBatchBytes > 0 {
+		for _, e := range events {
+			c.bufferedBytes += eventSize(e) + 1
+		}
+	}
+	return true
+}
+
+// trackSyncOrdered buffers an event behind the events tracked before it
+// and flushes, returning once the API has acknowledged it
+func (c *Client) trackSyncOrdered(ctx context.Context, event Event) (string, error) {
+	event, ok, err := c.prepare(c.enrich(ctx, event))
+	if !ok {
+		return "", dropErr(err)
+	}
+	event, ok, err = c.enqueue(ctx, event)
+	if !ok {
+		return "", dropErr(err)
+	}
+	if err := c.FlushCtx(ctx); err != nil {
+		return "", err
+	}
+	if c.Acknowledged() < event.Sequence {
+		return "", ErrNotAcknowledged
+	}
+	return event.ID, nil
+}
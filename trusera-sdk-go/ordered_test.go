@@ -0,0 +1,216 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ackingAPI acknowledges events requests, acking at most ackLimit when set
+type ackingAPI struct {
+	server *httptest.Server
+
+	mu        sync.Mutex
+	fail      bool
+	ackLimit  uint64
+	expected  uint64
+	sequences []uint64
+	delivery  []map[string]any
+}
+
+func newAckingAPI(t *testing.T) *ackingAPI {
+	a := &ackingAPI{}
+	a.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Events   []Event        `json:"events"`
+			Delivery map[string]any `json:"delivery"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		a.delivery = append(a.delivery, body.Delivery)
+		var acked uint64
+		for _, e := range body.Events {
+			if a.ackLimit > 0 && e.Sequence > a.ackLimit {
+				break
+			}
+			a.sequences = append(a.sequences, e.Sequence)
+			acked = e.Sequence
+		}
+		reply := map[string]any{"acked_sequence": acked}
+		if a.expected > 0 {
+			reply["expected_sequence"] = a.expected
+		}
+		json.NewEncoder(w).Encode(reply)
+	}))
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+func (a *ackingAPI) set(f func(a *ackingAPI)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f(a)
+}
+
+func (a *ackingAPI) received() []uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]uint64(nil), a.sequences...)
+}
+
+func orderedClient(api *ackingAPI, opts ...Option) *Client {
+	opts = append([]Option{WithBaseURL(api.server.URL), WithFlushInterval(time.Hour), WithOrderedDelivery()}, opts...)
+	return NewClient("test-key", opts...)
+}
+
+func TestOrderedDeliverySequences(t *testing.T) {
+	api := newAckingAPI(t)
+	client := orderedClient(api)
+	defer client.Close()
+
+	for i := 0; i < 3; i++ {
+		client.Track(NewEvent(EventToolCall, fmt.Sprintf("tool-%d", i)))
+	}
+	events := trackedEvents(client, EventToolCall)
+	for i, e := range events {
+		if e.Sequence != uint64(i+1) || e.Stream == "" || e.Stream != events[0].Stream {
+			t.Errorf("expected event %d numbered %d in one stream, got %d in %q", i, i+1, e.Sequence, e.Stream)
+		}
+	}
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := client.Acknowledged(); got != 3 {
+		t.Errorf("expected sequence 3 acknowledged, got %d", got)
+	}
+	d := api.delivery[0]
+	if d["stream"] != events[0].Stream || d["first_sequence"] != float64(1) || d["last_sequence"] != float64(3) {
+		t.Errorf("unexpected delivery envelope: %v", d)
+	}
+}
+
+func TestOrderedDeliveryRequeuesUndelivered(t *testing.T) {
+	api := newAckingAPI(t)
+	client := orderedClient(api)
+	defer client.Close()
+
+	api.set(func(a *ackingAPI) { a.fail = true })
+	client.Track(NewEvent(EventToolCall, "first"))
+	client.Track(NewEvent(EventToolCall, "second"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	if n := client.Stats().EventsDropped; n != 0 {
+		t.Errorf("expected no events dropped, got %d", n)
+	}
+
+	api.set(func(a *ackingAPI) { a.fail = false })
+	client.Track(NewEvent(EventToolCall, "third"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := api.received(); fmt.Sprint(got) != "[1 2 3]" {
+		t.Errorf("expected sequences delivered in order, got %v", got)
+	}
+}
+
+func TestOrderedDeliveryPartialAck(t *testing.T) {
+	api := newAckingAPI(t)
+	client := orderedClient(api)
+	defer client.Close()
+
+	api.set(func(a *ackingAPI) { a.ackLimit = 2 })
+	for i := 0; i < 4; i++ {
+		client.Track(NewEvent(EventToolCall, fmt.Sprintf("tool-%d", i)))
+	}
+	err := client.Flush()
+	var ackErr *AckError
+	if !errors.As(err, &ackErr) || !errors.Is(err, ErrNotAcknowledged) {
+		t.Fatalf("expected an AckError, got %v", err)
+	}
+	if ackErr.Acked != 2 || ackErr.Last != 4 || client.Acknowledged() != 2 {
+		t.Errorf("expected sequence 2 of 4 acknowledged, got %+v", ackErr)
+	}
+
+	api.set(func(a *ackingAPI) { a.ackLimit = 0 })
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := api.received(); fmt.Sprint(got) != "[1 2 3 4]" {
+		t.Errorf("expected only the unacknowledged events resent, got %v", got)
+	}
+}
+
+func TestOrderedDeliveryReportsGaps(t *testing.T) {
+	api := newAckingAPI(t)
+	var (
+		mu   sync.Mutex
+		gaps []*GapError
+	)
+	client := orderedClient(api, WithErrorHandler(func(err error) {
+		var gap *GapError
+		if errors.As(err, &gap) {
+			mu.Lock()
+			gaps = append(gaps, gap)
+			mu.Unlock()
+		}
+	}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "first"))
+	client.Flush()
+	client.Track(NewEvent(EventToolCall, "second"))
+	client.Track(NewEvent(EventToolCall, "third"))
+	api.set(func(a *ackingAPI) { a.expected = 1 })
+	client.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gaps) != 1 || gaps[0].From != 1 || gaps[0].To != 1 {
+		t.Errorf("expected a gap of sequence 1, got %v", gaps)
+	}
+}
+
+func TestOrderedTrackSync(t *testing.T) {
+	api := newAckingAPI(t)
+	client := orderedClient(api)
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "buffered"))
+	id, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "approve"))
+	if err != nil || id == "" {
+		t.Fatalf("TrackSync failed: %q, %v", id, err)
+	}
+	if got := api.received(); fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("expected the buffered event delivered first, got %v", got)
+	}
+
+	api.set(func(a *ackingAPI) { a.fail = true })
+	if _, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "deny")); err == nil {
+		t.Error("expected TrackSync to fail without an acknowledgment")
+	}
+}
+
+func TestSequenceNotSigned(t *testing.T) {
+	signer := HMACSigner{KeyID: "agent-1", Key: []byte("k")}
+	e := NewEvent(EventToolCall, "search")
+	if err := SignEvent(&e, signer); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	e.Stream, e.Sequence = "stream-1", 7
+	if err := VerifyEvent(e, signer); err != nil {
+		t.Errorf("expected the signature to hold after sequencing: %v", err)
+	}
+}
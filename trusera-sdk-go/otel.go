@@ -0,0 +1,331 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpanContextFunc returns the active trace and span IDs (lowercase hex) for a
+// context, or empty strings when there is none. With OpenTelemetry:
+//
+//	func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+type SpanContextFunc func(ctx context.Context) (traceID, spanID string)
+
+// WithSpanContext sets the function used to correlate events with the
+// caller's distributed trace. Intercepted requests fall back to the W3C
+// traceparent header when it returns no trace.
+func WithSpanContext(fn SpanContextFunc) Option {
+	return func(c *Client) {
+		c.spanContext = fn
+	}
+}
+
+// WithOTLPExport exports every flushed batch to an OpenTelemetry collector in
+// addition to the Trusera API. The exporter is a secondary sink named "otlp".
+func WithOTLPExport(e *OTLPExporter) Option {
+	return func(c *Client) {
+		c.sinks = append(c.sinks, namedSink{name: "otlp", sink: SinkFunc(func(ctx context.Context, events []Event) error {
+			return e.Export(ctx, c.agentID, events)
+		})})
+	}
+}
+
+// WithTrace records the trace and span an event belongs to (builder pattern)
+func (e Event) WithTrace(traceID, spanID string) Event {
+	if traceID == "" {
+		return e
+	}
+	e = e.WithMetadata("trace_id", traceID)
+	if spanID != "" {
+		e = e.WithMetadata("span_id", spanID)
+	}
+	return e
+}
+
+// correlate attaches the trace active in ctx to an event, preferring the
+// SpanContextFunc over a trace set with ContextWithTrace
+func (c *Client) correlate(ctx context.Context, event Event) Event {
+	if ctx == nil {
+		return event
+	}
+	if c.spanContext != nil {
+		if traceID, spanID := c.spanContext(ctx); traceID != "" {
+			return event.WithTrace(traceID, spanID)
+		}
+	}
+	return event.WithTrace(TraceFromContext(ctx))
+}
+
+// correlateRequest attaches the trace of an outbound request to an event,
+// preferring the request context and falling back to its traceparent header
+func (c *Client) correlateRequest(req *http.Request, event Event) Event {
+	event = c.correlate(req.Context(), event)
+	if _, ok := event.Metadata["trace_id"]; ok {
+		return event
+	}
+	return event.WithTrace(parseTraceParent(req.Header.Get("traceparent")))
+}
+
+// parseTraceParent extracts IDs from a W3C traceparent header
+// ("00-<32 hex trace id>-<16 hex span id>-<flags>")
+func parseTraceParent(header string) (traceID, spanID string) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", ""
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", ""
+	}
+	if strings.Trim(parts[1], "0") == "" || strings.Trim(parts[2], "0") == "" {
+		return "", ""
+	}
+	return strings.ToLower(parts[1]), strings.ToLower(parts[2])
+}
+
+// OTLPSignal selects how events are represented in OTLP
+type OTLPSignal string
+
+const (
+	OTLPLogs  OTLPSignal = "logs"  // One log record per event
+	OTLPSpans OTLPSignal = "spans" // One span per event, parented to the event's trace
+)
+
+// OTLPExporter sends events to an OpenTelemetry collector using OTLP/HTTP
+// with JSON encoding, for example to Grafana Tempo or Loki via an
+// OpenTelemetry Collector
+type OTLPExporter struct {
+	Endpoint    string            // Collector base URL, e.g. http://localhost:4318
+	Signal      OTLPSignal        // Defaults to OTLPLogs
+	ServiceName string            // service.name resource attribute
+	Headers     map[string]string // Extra request headers, e.g. authentication
+	HTTPClient  *http.Client
+}
+
+// NewOTLPExporter creates an exporter that sends log records to endpoint
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		Endpoint:    strings.TrimRight(endpoint, "/"),
+		Signal:      OTLPLogs,
+		ServiceName: "trusera-agent",
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export sends a batch of events to the collector
+func (e *OTLPExporter) Export(ctx context.Context, agentID string, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var body any
+	path := "/v1/logs"
+	if e.Signal == OTLPSpans {
+		path = "/v1/traces"
+		body = e.traces(agentID, events)
+	} else {
+		body = e.logs(agentID, events)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+
+	httpClient := e.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export OTLP %s: %w", e.signal(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("OTLP collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) signal() OTLPSignal {
+	if e.Signal == "" {
+		return OTLPLogs
+	}
+	return e.Signal
+}
+
+func (e *OTLPExporter) resource(agentID string) map[string]any {
+	attrs := []otlpKeyValue{otlpAttr("service.name", e.ServiceName)}
+	if agentID != "" {
+		attrs = append(attrs, otlpAttr("trusera.agent_id", agentID))
+	}
+	return map[string]any{"attributes": attrs}
+}
+
+var otlpScope = map[string]any{"name": "github.com/Trusera/ai-bom/trusera-sdk-go"}
+
+// logs builds an ExportLogsServiceRequest
+func (e *OTLPExporter) logs(agentID string, events []Event) map[string]any {
+	records := make([]map[string]any, 0, len(events))
+	for _, event := range events {
+		record := map[string]any{
+			"timeUnixNano": strconv.FormatInt(eventTime(event).UnixNano(), 10),
+			"severityText": "INFO",
+			"body":         otlpValue(event.Name),
+			"attributes":   eventAttributes(event),
+		}
+		if traceID, ok := event.Metadata["trace_id"].(string); ok {
+			record["traceId"] = traceID
+			if spanID, ok := event.Metadata["span_id"].(string); ok {
+				record["spanId"] = spanID
+			}
+		}
+		records = append(records, record)
+	}
+
+	return map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource":  e.resource(agentID),
+			"scopeLogs": []any{map[string]any{"scope": otlpScope, "logRecords": records}},
+		}},
+	}
+}
+
+// traces builds an ExportTraceServiceRequest. Events that carry a trace
+// become children of the recorded span; others start a trace of their own.
+func (e *OTLPExporter) traces(agentID string, events []Event) map[string]any {
+	spans := make([]map[string]any, 0, len(events))
+	for _, event := range events {
+		start := eventTime(event)
+		end := start
+		for _, key := range []string{"duration_ms", "latency_ms"} {
+			if ms, ok := event.Payload[key].(float64); ok {
+				end = start.Add(time.Duration(ms * float64(time.Millisecond)))
+				break
+			}
+		}
+
+		traceID, _ := event.Metadata["trace_id"].(string)
+		if traceID == "" {
+			traceID = event.ID
+		}
+
+		span := map[string]any{
+			"traceId":           traceID,
+			"spanId":            randomHex(8),
+			"name":              string(event.Type) + " " + event.Name,
+			"kind":              1, // SPAN_KIND_INTERNAL
+			"startTimeUnixNano": strconv.FormatInt(start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(end.UnixNano(), 10),
+			"attributes":        eventAttributes(event),
+		}
+		if parent, ok := event.Metadata["span_id"].(string); ok {
+			span["parentSpanId"] = parent
+		}
+		if errMsg, ok := event.Payload["error"].(string); ok {
+			span["status"] = map[string]any{"code": 2, "message": errMsg} // STATUS_CODE_ERROR
+		}
+		spans = append(spans, span)
+	}
+
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource":   e.resource(agentID),
+			"scopeSpans": []any{map[string]any{"scope": otlpScope, "spans": spans}},
+		}},
+	}
+}
+
+// otlpKeyValue is an OTLP attribute
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpAttr(key string, value any) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpValue(value)}
+}
+
+// otlpValue converts a Go value to an OTLP AnyValue; composite values are
+// encoded as JSON strings
+func otlpValue(v any) map[string]any {
+	switch val := v.(type) {
+	case string:
+		return map[string]any{"stringValue": val}
+	case bool:
+		return map[string]any{"boolValue": val}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(val)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		return map[string]any{"doubleValue": val}
+	}
+	data, _ := json.Marshal(v)
+	return map[string]any{"stringValue": string(data)}
+}
+
+// eventAttributes flattens an event into sorted OTLP attributes
+func eventAttributes(event Event) []otlpKeyValue {
+	attrs := []otlpKeyValue{
+		otlpAttr("trusera.event.id", event.ID),
+		otlpAttr("trusera.event.type", string(event.Type)),
+		otlpAttr("trusera.event.name", event.Name),
+	}
+
+	add := func(prefix string, m map[string]any) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prefix == "trusera.metadata." && (k == "trace_id" || k == "span_id") {
+				continue
+			}
+			attrs = append(attrs, otlpAttr(prefix+k, m[k]))
+		}
+	}
+	add("trusera.payload.", event.Payload)
+	add("trusera.metadata.", event.Metadata)
+	return attrs
+}
+
+// eventTime parses an event timestamp, falling back to now
+func eventTime(event Event) time.Time {
+	if t, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,232 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+type traceKey struct{}
+
+func testSpanContext(ctx context.Context) (string, string) {
+	if ids, ok := ctx.Value(traceKey{}).([2]string); ok {
+		return ids[0], ids[1]
+	}
+	return "", ""
+}
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		header  string
+		traceID string
+		spanID  string
+	}{
+		{"00-" + testTraceID + "-" + testSpanID + "-01", testTraceID, testSpanID},
+		{"00-" + strings.ToUpper(testTraceID) + "-" + testSpanID + "-00", testTraceID, testSpanID},
+		{"00-00000000000000000000000000000000-" + testSpanID + "-01", "", ""},
+		{"00-short-" + testSpanID + "-01", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		traceID, spanID := parseTraceParent(tt.header)
+		if traceID != tt.traceID || spanID != tt.spanID {
+			t.Errorf("parseTraceParent(%q) = %q, %q", tt.header, traceID, spanID)
+		}
+	}
+}
+
+func TestInterceptorCorrelatesTrace(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	t.Run("span context", func(t *testing.T) {
+		client := NewClient("test-key", WithSpanContext(testSpanContext))
+		defer client.Close()
+
+		ctx := context.WithValue(context.Background(), traceKey{}, [2]string{testTraceID, testSpanID})
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+
+		resp, err := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{}).Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		for _, e := range client.events {
+			if e.Metadata["trace_id"] != testTraceID || e.Metadata["span_id"] != testSpanID {
+				t.Errorf("event %s missing trace: %v", e.Name, e.Metadata)
+			}
+		}
+	})
+
+	t.Run("traceparent header", func(t *testing.T) {
+		client := NewClient("test-key")
+		defer client.Close()
+
+		req, _ := http.NewRequest(http.MethodGet, backend.URL, nil)
+		req.Header.Set("traceparent", "00-"+testTraceID+"-"+testSpanID+"-01")
+
+		resp, err := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{}).Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+
+		e, _ := findEvent(client, "response")
+		if e.Metadata["trace_id"] != testTraceID {
+			t.Errorf("expected trace from traceparent, got %v", e.Metadata)
+		}
+	})
+}
+
+// otlpCollector records OTLP/HTTP JSON requests
+type otlpCollector struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	paths  []string
+	bodies []map[string]any
+}
+
+func newOTLPCollector(t *testing.T) *otlpCollector {
+	c := &otlpCollector{}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid OTLP JSON: %v", err)
+		}
+		c.mu.Lock()
+		c.paths = append(c.paths, r.URL.Path)
+		c.bodies = append(c.bodies, body)
+		c.mu.Unlock()
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+// dig walks nested OTLP JSON through map keys and first array elements
+func dig(v any, keys ...string) any {
+	for _, k := range keys {
+		if arr, ok := v.([]any); ok {
+			if len(arr) == 0 {
+				return nil
+			}
+			v = arr[0]
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[k]
+	}
+	return v
+}
+
+func TestOTLPExportLogs(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	collector := newOTLPCollector(t)
+
+	exporter := NewOTLPExporter(collector.server.URL)
+	exporter.ServiceName = "billing-agent"
+	exporter.Headers = map[string]string{"X-Scope-OrgID": "tenant-1"}
+
+	client := NewClient("test-key", WithBaseURL(api.URL), WithOTLPExport(exporter))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search").
+		WithPayload("query", "go").
+		WithTrace(testTraceID, testSpanID))
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if len(collector.paths) != 1 || collector.paths[0] != "/v1/logs" {
+		t.Fatalf("expected one /v1/logs export, got %v", collector.paths)
+	}
+
+	body := collector.bodies[0]
+	service := dig(body, "resourceLogs", "resource", "attributes", "value", "stringValue")
+	if service != "billing-agent" {
+		t.Errorf("unexpected service.name: %v", service)
+	}
+
+	record := dig(body, "resourceLogs", "scopeLogs", "logRecords")
+	if dig(record, "traceId") != testTraceID || dig(record, "spanId") != testSpanID {
+		t.Errorf("expected trace correlation on log record: %v", record)
+	}
+	if dig(record, "body", "stringValue") != "search" {
+		t.Errorf("unexpected log body: %v", dig(record, "body"))
+	}
+}
+
+func TestOTLPExportSpans(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	collector := newOTLPCollector(t)
+
+	exporter := NewOTLPExporter(collector.server.URL)
+	exporter.Signal = OTLPSpans
+
+	client := NewClient("test-key", WithBaseURL(api.URL), WithOTLPExport(exporter))
+	defer client.Close()
+
+	client.Track(NewEvent(EventLLMInvoke, "gpt-4o").
+		WithPayload("latency_ms", 250.0).
+		WithPayload("error", "timeout").
+		WithTrace(testTraceID, testSpanID))
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	if len(collector.paths) != 1 || collector.paths[0] != "/v1/traces" {
+		t.Fatalf("expected one /v1/traces export, got %v", collector.paths)
+	}
+
+	span := dig(collector.bodies[0], "resourceSpans", "scopeSpans", "spans")
+	if dig(span, "traceId") != testTraceID || dig(span, "parentSpanId") != testSpanID {
+		t.Errorf("expected span parented to the caller's span: %v", span)
+	}
+	if dig(span, "name") != "llm_invoke gpt-4o" {
+		t.Errorf("unexpected span name: %v", dig(span, "name"))
+	}
+	if dig(span, "status", "code") != float64(2) {
+		t.Errorf("expected error status: %v", dig(span, "status"))
+	}
+	if dig(span, "startTimeUnixNano") == dig(span, "endTimeUnixNano") {
+		t.Error("expected span duration from latency_ms")
+	}
+}
+
+func TestOTLPExportErrorReturned(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL), WithOTLPExport(NewOTLPExporter(collector.URL)))
+	defer client.Close()
+
+	client.Track(NewEvent(EventDecision, "approve"))
+	err := client.Flush()
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected collector error, got %v", err)
+	}
+}
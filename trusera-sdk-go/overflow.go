@@ -0,0 +1,131 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+const defaultMaxBufferSize = 10000
+
+// OverflowPolicy determines what Track does when the event buffer is full
+type OverflowPolicy string
+
+const (
+	DropOldest  OverflowPolicy = "drop_oldest"   // Discard the oldest buffered event (default)
+	DropNewest  OverflowPolicy = "drop_newest"   // Discard the event being tracked
+	BlockCaller OverflowPolicy = "block_caller"  // Wait until a flush frees space
+	SpillToDisk OverflowPolicy = "spill_to_disk" // Write overflow to disk and send it with the next flush
+)
+
+// WithMaxBufferSize caps how many events are held in memory between flushes.
+// What happens beyond the cap is set by WithOverflowPolicy.
+func WithMaxBufferSize(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxBuffer = n
+		}
+	}
+}
+
+// WithOverflowPolicy sets how Track behaves when the buffer is full. The
+// policy does not apply with WithPersistentQueue, which already keeps every
+// event on disk.
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(c *Client) {
+		c.overflow = p
+	}
+}
+
+// WithSpillDir sets the directory used by SpillToDisk. By default a
+// temporary directory is created and removed again by Close.
+func WithSpillDir(dir string) Option {
+	return func(c *Client) {
+		c.spillDir = dir
+	}
+}
+
+// openSpill prepares the overflow directory for SpillToDisk
+func (c *Client) openSpill() error {
+	dir := c.spillDir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "trusera-spill-")
+		if err != nil {
+			return err
+		}
+		dir = tmp
+		c.spillTemp = true
+	}
+
+	q, err := c.openQueue(dir)
+	if err != nil {
+		return err
+	}
+	c.spill = q
+	return nil
+}
+
+// overflowLocked applies the overflow policy to an event that does not fit
+// in the buffer. It must be called with c.mu held and reports whether the
+// event should still be appended to the buffer. BlockCaller gives up with
+// ctx's error when ctx ends first.
+func (c *Client) overflowLocked(ctx context.Context, event Event) (bool, error) {
+	switch c.overflow {
+	case DropNewest:
+		c.drop(DropReasonOverflow, event)
+		return false, nil
+
+	case BlockCaller:
+		c.flushAsync()
+		stop := context.AfterFunc(ctx, func() {
+			c.mu.Lock()
+			c.space.Broadcast()
+			c.mu.Unlock()
+		})
+		defer stop()
+		for len(c.events) >= c.maxBuffer && !c.closed {
+			if err := ctx.Err(); err != nil {
+				c.drop(DropReasonOverflow, event)
+				return false, err
+			}
+			c.space.Wait()
+		}
+		return true, nil
+
+	case SpillToDisk:
+		if c.spill != nil {
+			err := c.spill.append(event)
+			if err == nil {
+				c.metrics.observeSpill(1)
+				c.flushAsync()
+				return false, nil
+			}
+			if c.queueErr == nil && !errors.Is(err, errQueueFull) {
+				c.queueErr = err
+			}
+		}
+		fallthrough // without a usable spill directory or room in it, fall back to dropping
+
+	default: // DropOldest
+		c.drop(DropReasonOverflow, c.events[0])
+		// Reslicing rather than shifting the buffer keeps a full buffer
+		// cheap; the next append that outgrows it moves it
+		c.events[0] = Event{}
+		c.events = c.events[1:]
+		return true, nil
+	}
+}
+
+// flushAsync starts a background flush, unless one is already waiting to
+// start and will pick up the buffer as it is now
+func (c *Client) flushAsync() {
+	if !c.flushQueued.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		c.asyncFlushes.RLock()
+		defer c.asyncFlushes.RUnlock()
+		c.flushQueued.Store(false)
+		c.handleError(c.flush(context.Background()))
+	}()
+}
@@ -0,0 +1,162 @@
+package trusera
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func bufferedNames(c *Client) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, len(c.events))
+	for i, e := range c.events {
+		names[i] = e.Name
+	}
+	return names
+}
+
+func trackNumbered(c *Client, n int) {
+	for i := 1; i <= n; i++ {
+		c.Track(NewEvent(EventToolCall, fmt.Sprint(i)))
+	}
+}
+
+func TestOverflowDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   string
+	}{
+		{DropOldest, "[3 4 5]"},
+		{DropNewest, "[1 2 3]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			client := NewClient("test-key", WithMaxBufferSize(3), WithOverflowPolicy(tt.policy))
+			defer client.Close()
+
+			trackNumbered(client, 5)
+
+			if got := fmt.Sprint(bufferedNames(client)); got != tt.want {
+				t.Errorf("buffer = %s, want %s", got, tt.want)
+			}
+
+			stats := client.Stats()
+			if stats.EventsDroppedOverflow != 2 || stats.EventsDropped != 2 {
+				t.Errorf("expected 2 overflow drops, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestOverflowBlockCaller(t *testing.T) {
+	api := newFlakyAPI(t)
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithMaxBufferSize(3),
+		WithOverflowPolicy(BlockCaller))
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		trackNumbered(client, 5)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Track stayed blocked after the buffer was flushed")
+	}
+
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := api.received(); len(got) != 5 {
+		t.Errorf("expected all 5 events delivered, got %v", got)
+	}
+	if dropped := client.Stats().EventsDropped; dropped != 0 {
+		t.Errorf("BlockCaller should not drop events, got %d", dropped)
+	}
+}
+
+func TestOverflowBlockCallerReleasedOnClose(t *testing.T) {
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithMaxBufferSize(1),
+		WithOverflowPolicy(BlockCaller))
+
+	client.Track(NewEvent(EventToolCall, "fills buffer"))
+	go client.Track(NewEvent(EventToolCall, "waits"))
+
+	closed := make(chan struct{})
+	go func() {
+		client.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close deadlocked with a blocked caller")
+	}
+}
+
+func TestOverflowSpillToDisk(t *testing.T) {
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+	dir := t.TempDir()
+
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithMaxBufferSize(2),
+		WithOverflowPolicy(SpillToDisk),
+		WithSpillDir(dir))
+
+	trackNumbered(client, 5)
+
+	if spilled := client.Stats().EventsSpilled; spilled != 3 {
+		t.Errorf("expected 3 spilled events, got %d", spilled)
+	}
+
+	api.fail.Store(false)
+	if err := client.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if got := api.received(); len(got) < 3 {
+		t.Errorf("expected spilled events to be delivered, got %v", got)
+	}
+	if left := segments(t, dir); len(left) != 0 {
+		t.Errorf("expected spill segments to be removed after delivery, got %v", left)
+	}
+}
+
+func TestOverflowSpillToDiskFull(t *testing.T) {
+	api := newFlakyAPI(t)
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithFlushInterval(time.Hour),
+		WithMaxBufferSize(2),
+		WithOverflowPolicy(SpillToDisk),
+		WithSpillDir(t.TempDir()),
+		WithMaxQueueBytes(1))
+	defer client.Close()
+
+	trackNumbered(client, 5)
+
+	stats := client.Stats()
+	if stats.EventsSpilled != 0 || stats.EventsDroppedOverflow != 3 {
+		t.Errorf("expected a full spill directory to fall back to dropping, got %+v", stats)
+	}
+	if err := client.Flush(); err != nil {
+		t.Errorf("a full spill directory should not fail the flush, got %v", err)
+	}
+	if got := api.received(); len(got) != 2 || got[0] != "4" || got[1] != "5" {
+		t.Errorf("expected the newest events delivered, got %v", got)
+	}
+}
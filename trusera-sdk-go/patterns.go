@@ -0,0 +1,241 @@
+package trusera
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Matcher reports whether a target, such as a URL, matches a pattern
+type Matcher interface {
+	Match(target string) bool
+}
+
+// CompilePattern compiles a pattern as used by Rule.Match and the
+// Exclude, Block and Allow pattern lists:
+//
+//   - "re:<expr>" matches targets the regular expression matches in full
+//   - An IP address or CIDR range, such as "10.0.0.0/8", matches targets
+//     whose host is an address in it
+//   - A pattern with "*" and no "/", such as "*.corp.example.com", is a
+//     glob matched against the target's host
+//   - Any other pattern with "*" is a glob matched against the whole target
+//   - Anything else matches targets containing it, as before
+//
+// "*" matches any run of characters, dots included. IP and host patterns
+// may end in a port or port range, such as ":443" or ":8000-8999", which
+// the target's port, explicit or implied by its scheme, must be in; IPv6
+// addresses take brackets then, as in "[fd00::/8]:443". Hosts are matched
+// case-insensitively.
+func CompilePattern(pattern string) (Matcher, error) {
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return regexMatcher{re}, nil
+	}
+	if m, ok, err := compileHostPattern(pattern); ok || err != nil {
+		return m, err
+	}
+	if strings.Contains(pattern, "*") {
+		return regexMatcher{globRegexp(pattern, "")}, nil
+	}
+	return substringMatcher(pattern), nil
+}
+
+type substringMatcher string
+
+func (m substringMatcher) Match(target string) bool {
+	return strings.Contains(target, string(m))
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) Match(target string) bool {
+	return m.re.MatchString(target)
+}
+
+type invalidPattern struct{}
+
+func (invalidPattern) Match(string) bool { return false }
+
+// hostMatcher matches the host and port of a target
+type hostMatcher struct {
+	glob   *regexp.Regexp // Set for host globs
+	prefix netip.Prefix   // Set for addresses and ranges
+	ports  portRange
+}
+
+func (m hostMatcher) Match(target string) bool {
+	host, port := targetAddr(target)
+	if host == "" || !m.ports.contains(port) {
+		return false
+	}
+	if m.glob != nil {
+		return m.glob.MatchString(host)
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && m.prefix.Contains(addr.Unmap())
+}
+
+// portRange is an inclusive range of ports; the zero range allows any
+type portRange struct {
+	lo, hi int
+}
+
+func (r portRange) contains(port string) bool {
+	if r.lo == 0 {
+		return true
+	}
+	p, err := strconv.Atoi(port)
+	return err == nil && p >= r.lo && p <= r.hi
+}
+
+// compileHostPattern compiles an IP, CIDR or host glob pattern, reporting
+// false if the pattern is none of them
+func compileHostPattern(pattern string) (Matcher, bool, error) {
+	host, ports, hasPort := pattern, "", false
+	if rest, ok := strings.CutPrefix(pattern, "["); ok {
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return nil, false, nil
+		}
+		host = rest[:end]
+		ports, hasPort = strings.CutPrefix(rest[end+1:], ":")
+		if !hasPort && rest[end+1:] != "" {
+			return nil, false, nil
+		}
+	} else if strings.Count(pattern, ":") == 1 {
+		host, ports, hasPort = strings.Cut(pattern, ":")
+	}
+
+	var m hostMatcher
+	if prefix, err := netip.ParsePrefix(host); err == nil {
+		m.prefix = prefix.Masked()
+	} else if addr, err := netip.ParseAddr(host); err == nil {
+		m.prefix = netip.PrefixFrom(addr, addr.BitLen())
+	} else if strings.Contains(host, "*") && !strings.ContainsAny(host, "/?#") {
+		m.glob = globRegexp(strings.ToLower(host), "(?i)")
+	} else {
+		return nil, false, nil
+	}
+
+	if hasPort {
+		r, err := parsePortRange(ports)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		m.ports = r
+	}
+	return m, true, nil
+}
+
+// parsePortRange parses "443" or "8000-8999"
+func parsePortRange(s string) (portRange, error) {
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	l, err1 := strconv.Atoi(lo)
+	h, err2 := strconv.Atoi(hi)
+	if err1 != nil || err2 != nil || l < 1 || h > 65535 || l > h {
+		return portRange{}, fmt.Errorf("invalid port %q", s)
+	}
+	return portRange{l, h}, nil
+}
+
+// globRegexp turns a glob into an anchored regular expression in which "*"
+// matches any run of characters
+func globRegexp(glob, flags string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile(flags + "^" + strings.Join(parts, ".*") + "$")
+}
+
+// patterns caches compiled patterns, which are reused for every request
+var patterns sync.Map
+
+// matchPattern reports whether a target matches a pattern. Invalid
+// patterns match nothing.
+func matchPattern(target, pattern string) bool {
+	return compiledPattern(pattern).Match(target)
+}
+
+// matchHost reports whether a target's host matches a host name, compared
+// exactly, or a host glob, IP or CIDR pattern
+func matchHost(target, pattern string) bool {
+	if m, ok := compiledPattern(pattern).(hostMatcher); ok {
+		return m.Match(target)
+	}
+	host, _ := targetAddr(target)
+	return host != "" && strings.EqualFold(host, strings.TrimSuffix(pattern, "."))
+}
+
+// compiledPattern returns the cached matcher for a pattern
+func compiledPattern(pattern string) Matcher {
+	if m, ok := patterns.Load(pattern); ok {
+		return m.(Matcher)
+	}
+	m, err := CompilePattern(pattern)
+	if err != nil {
+		m = invalidPattern{}
+	}
+	patterns.Store(pattern, m)
+	return m
+}
+
+// defaultPorts are the ports implied by the schemes of intercepted targets
+var defaultPorts = map[string]string{
+	"http": "80", "ws": "80",
+	"https": "443", "wss": "443", "grpc": "443",
+}
+
+// targetPath returns the path of a URL or gRPC target, without its query,
+// or "" for targets without one
+func targetPath(target string) string {
+	_, rest, ok := strings.Cut(target, "://")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimLeft(rest, "/")
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return ""
+	}
+	path, _, _ := strings.Cut(rest[i:], "?")
+	path, _, _ = strings.Cut(path, "#")
+	return path
+}
+
+// targetAddr returns the lowercase host and the port of a URL, gRPC target
+// or DNS name. The port is implied by the scheme when the target has none.
+// Targets such as SQL statements have no host.
+func targetAddr(target string) (host, port string) {
+	scheme, rest, ok := strings.Cut(target, "://")
+	if ok {
+		target = strings.TrimLeft(rest, "/")
+	} else if strings.ContainsAny(target, " \t\n") {
+		return "", ""
+	}
+	if i := strings.IndexAny(target, "/?#"); i >= 0 {
+		target = target[:i]
+	}
+	if i := strings.LastIndex(target, "@"); i >= 0 {
+		target = target[i+1:]
+	}
+	if h, p, err := net.SplitHostPort(target); err == nil {
+		target, port = h, p
+	} else if ok {
+		port = defaultPorts[strings.ToLower(scheme)]
+	}
+	return strings.ToLower(strings.Trim(target, "[].")), port
+}
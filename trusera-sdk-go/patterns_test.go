@@ -0,0 +1,114 @@
+package trusera
+
+import "testing"
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		target  string
+		want    bool
+	}{
+		{"pastebin.com", "https://pastebin.com/raw/x", true},
+		{"pastebin.com", "https://example.com", false},
+		{"", "https://example.com", true},
+
+		{"*.corp.example.com", "https://api.corp.example.com/v1", true},
+		{"*.corp.example.com", "https://a.b.CORP.example.com", true},
+		{"*.corp.example.com", "https://corp.example.com", false},
+		{"*.corp.example.com", "https://evil.com/?x=a.corp.example.com", false},
+		{"*.corp.example.com:443", "https://api.corp.example.com", true},
+		{"*.corp.example.com:443", "http://api.corp.example.com", false},
+		{"*.corp.example.com:8000-8999", "http://api.corp.example.com:8080/x", true},
+		{"*.corp.example.com:8000-8999", "http://api.corp.example.com:9000/x", false},
+
+		{"https://*.example.com/v1/*", "https://api.example.com/v1/users", true},
+		{"https://*.example.com/v1/*", "https://api.example.com/v2/users", false},
+
+		{"re:https://api\\.example\\.com/v[0-9]+/.*", "https://api.example.com/v2/x", true},
+		{"re:https://api\\.example\\.com/v[0-9]+/.*", "https://evil.com/https://api.example.com/v2/x", false},
+
+		{"10.0.0.0/8", "http://10.1.2.3:8080/x", true},
+		{"10.0.0.0/8", "http://110.1.2.3/x", false},
+		{"10.0.0.0/8", "tcp://10.9.9.9:5432", true},
+		{"10.0.0.0/8:5432", "tcp://10.9.9.9:5432", true},
+		{"10.0.0.0/8:5432", "tcp://10.9.9.9:6379", false},
+		{"169.254.169.254", "http://169.254.169.254/latest/meta-data", true},
+		{"169.254.169.254", "http://169.254.169.2540/", false},
+		{"fd00::/8", "http://[fd12::1]:80/", true},
+		{"[fd00::/8]:443", "https://[fd12::1]/", true},
+		{"[fd00::/8]:443", "http://[fd12::1]/", false},
+		{"127.0.0.1", "SELECT * FROM t WHERE ip = '127.0.0.1'", false},
+	}
+	for _, tt := range tests {
+		m, err := CompilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompilePattern(%q): %v", tt.pattern, err)
+		}
+		if got := m.Match(tt.target); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	for _, pattern := range []string{"re:(", "*.example.com:0", "*.example.com:http", "10.0.0.0/8:9000-80"} {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("CompilePattern(%q) succeeded, want an error", pattern)
+		}
+	}
+	if matchPattern("https://example.com", "re:(") {
+		t.Error("invalid pattern matched")
+	}
+}
+
+func TestTargetAddr(t *testing.T) {
+	tests := map[string][2]string{
+		"https://API.example.com:8443/v1?q=1": {"api.example.com", "8443"},
+		"https://user:pw@example.com/":        {"example.com", "443"},
+		"http://[::1]:8080/x":                 {"::1", "8080"},
+		"ws://example.com/socket":             {"example.com", "80"},
+		"dns:///grpc.example.com:443/pkg.Svc": {"grpc.example.com", "443"},
+		"tcp://10.0.0.1:5432":                 {"10.0.0.1", "5432"},
+		"example.com.":                        {"example.com", ""},
+		"SELECT * FROM users":                 {"", ""},
+		"GET session:42":                      {"", ""},
+	}
+	for target, want := range tests {
+		if host, port := targetAddr(target); host != want[0] || port != want[1] {
+			t.Errorf("targetAddr(%q) = %q, %q, want %q, %q", target, host, port, want[0], want[1])
+		}
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	tests := map[string]string{
+		"https://api.github.com/repos/a/b?x=1#y":   "/repos/a/b",
+		"https://api.github.com":                   "",
+		"dns:///grpc.example.com:443/pkg.Svc/Call": "/pkg.Svc/Call",
+		"SELECT * FROM users":                      "",
+	}
+	for target, want := range tests {
+		if got := targetPath(target); got != want {
+			t.Errorf("targetPath(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		pattern, target string
+		want            bool
+	}{
+		{"api.openai.com", "https://API.openai.com/v1", true},
+		{"api.openai.com", "https://api.openai.com.evil.com/v1", false},
+		{"api.openai.com", "https://evil.com/?u=api.openai.com", false},
+		{"*.corp.example.com", "https://a.corp.example.com", true},
+		{"10.0.0.0/8", "http://10.2.3.4:8080", true},
+		{"10.0.0.0/8", "http://example.com", false},
+	}
+	for _, tt := range tests {
+		if got := matchHost(tt.target, tt.pattern); got != tt.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", tt.target, tt.pattern, got, tt.want)
+		}
+	}
+}
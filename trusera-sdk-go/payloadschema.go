@@ -0,0 +1,151 @@
+package trusera
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// SchemaAction says what happens to an event whose payload does not match
+// its registered schema
+type SchemaAction string
+
+const (
+	SchemaAnnotate SchemaAction = "annotate" // Keep the event with payload schema_violations (default)
+	SchemaWarn     SchemaAction = "warn"     // Keep the event unchanged and log a warning; see WithLogger
+	SchemaReject   SchemaAction = "reject"   // Drop the event; TrackCtx and TrackSync return a *SchemaError
+)
+
+// SchemaError is returned for an event rejected by its payload schema
+type SchemaError struct {
+	Type       EventType
+	Name       string
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("trusera: %s event %q does not match its payload schema: %s",
+		e.Type, e.Name, strings.Join(e.Violations, "; "))
+}
+
+// schemaRegistry holds the registered payload schemas
+type schemaRegistry struct {
+	mu      sync.Mutex
+	schemas []payloadSchema
+}
+
+type payloadSchema struct {
+	eventType EventType
+	name      string
+	schema    *jsonSchema
+	action    SchemaAction
+}
+
+// RegisterPayloadSchema validates the payloads of events of a type, and of
+// one name unless name is empty, against a JSON Schema when they are
+// tracked, to catch instrumentation bugs early. The schema describes the
+// whole payload object and supports the keywords listed for RegisterTool.
+// It runs after the event hooks, so they can fix payloads up first. When
+// several schemas match an event, all are checked and the strictest action
+// of those that failed applies. Registering the same type and name again
+// replaces the schema.
+func (c *Client) RegisterPayloadSchema(eventType EventType, name string, schema json.RawMessage, action SchemaAction) error {
+	if eventType == "" {
+		return errors.New("event type is required")
+	}
+	switch action {
+	case "":
+		action = SchemaAnnotate
+	case SchemaAnnotate, SchemaWarn, SchemaReject:
+	default:
+		return fmt.Errorf("unknown schema action %q", action)
+	}
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return fmt.Errorf("failed to parse payload schema of %s %s: %w", eventType, name, err)
+	}
+
+	entry := payloadSchema{eventType: eventType, name: name, schema: compiled, action: action}
+	c.schemas.mu.Lock()
+	defer c.schemas.mu.Unlock()
+	for i, s := range c.schemas.schemas {
+		if s.eventType == eventType && s.name == name {
+			c.schemas.schemas[i] = entry
+			return nil
+		}
+	}
+	c.schemas.schemas = append(c.schemas.schemas, entry)
+	return nil
+}
+
+// checkPayload validates an event against the matching payload schemas
+func (c *Client) checkPayload(event Event) (Event, error) {
+	c.schemas.mu.Lock()
+	var matching []payloadSchema
+	for _, s := range c.schemas.schemas {
+		if s.eventType == event.Type && (s.name == "" || s.name == event.Name) {
+			matching = append(matching, s)
+		}
+	}
+	c.schemas.mu.Unlock()
+	if len(matching) == 0 {
+		return event, nil
+	}
+
+	payload, err := toJSONValue(event.Payload)
+	if err != nil {
+		return event, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	var (
+		violations []string
+		action     SchemaAction
+	)
+	for _, s := range matching {
+		v := s.schema.validate(payload, "payload")
+		if len(v) == 0 {
+			continue
+		}
+		violations = append(violations, v...)
+		if schemaStrictness(s.action) > schemaStrictness(action) {
+			action = s.action
+		}
+	}
+
+	switch {
+	case len(violations) == 0:
+		return event, nil
+	case action == SchemaReject:
+		return event, &SchemaError{Type: event.Type, Name: event.Name, Violations: violations}
+	case action == SchemaWarn:
+		c.log(slog.LevelWarn, "event payload does not match its schema",
+			"event_type", string(event.Type), "event_name", event.Name, "violations", violations)
+		return event, nil
+	default:
+		return withViolations(event, violations), nil
+	}
+}
+
+// schemaStrictness orders the actions from the most lenient
+func schemaStrictness(a SchemaAction) int {
+	switch a {
+	case SchemaWarn:
+		return 1
+	case SchemaAnnotate:
+		return 2
+	case SchemaReject:
+		return 3
+	}
+	return 0
+}
+
+// withViolations adds to an event's payload schema_violations
+func withViolations(event Event, violations []string) Event {
+	existing, _ := event.Payload["schema_violations"].([]string)
+	return event.WithPayload("schema_violations", append(existing[:len(existing):len(existing)], violations...))
+}
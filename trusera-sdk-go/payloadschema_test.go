@@ -0,0 +1,88 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterPayloadSchema(t *testing.T) {
+	logs := &logBuffer{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithLogger(slog.NewJSONHandler(logs, nil)))
+	defer client.Close()
+
+	schema := json.RawMessage(`{
+		"type": "object",
+		"required": ["model"],
+		"properties": {"tokens": {"type": "integer", "minimum": 0}}
+	}`)
+	for _, s := range []struct {
+		name   string
+		action SchemaAction
+	}{{"annotated", SchemaAnnotate}, {"warned", SchemaWarn}, {"rejected", SchemaReject}} {
+		if err := client.RegisterPayloadSchema(EventLLMInvoke, s.name, schema, s.action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"annotated", "warned", "rejected", "unchecked"} {
+		err := client.TrackCtx(context.Background(), NewEvent(EventLLMInvoke, name).WithPayload("tokens", -1))
+		var schemaErr *SchemaError
+		if rejected := errors.As(err, &schemaErr); rejected != (name == "rejected") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+
+	want := []string{"payload.model: required", "payload.tokens: less than 0"}
+	e, ok := trackedEvent(client, "annotated")
+	if !ok || !reflect.DeepEqual(e.Payload["schema_violations"], want) {
+		t.Errorf("expected the violations to be annotated, got %v", e.Payload["schema_violations"])
+	}
+	if e, ok := trackedEvent(client, "warned"); !ok || e.Payload["schema_violations"] != nil {
+		t.Errorf("expected the warned event to be kept unchanged, got %v", e.Payload)
+	}
+	if !strings.Contains(logs.buf.String(), `"event_name":"warned"`) {
+		t.Errorf("expected a warning for the warned event, got %s", logs.buf.String())
+	}
+	if _, ok := trackedEvent(client, "rejected"); ok {
+		t.Error("expected the rejected event to be dropped")
+	}
+	if got := client.Stats().EventsDroppedSchema; got != 1 {
+		t.Errorf("expected 1 schema drop, got %d", got)
+	}
+}
+
+func TestPayloadSchemaStrictestActionWins(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	client.RegisterPayloadSchema(EventToolCall, "", json.RawMessage(`{"required": ["arguments"]}`), SchemaAnnotate)
+	client.RegisterPayloadSchema(EventToolCall, "refund", json.RawMessage(`{"required": ["amount"]}`), SchemaReject)
+
+	if err := client.TrackCtx(context.Background(), NewEvent(EventToolCall, "search")); err != nil {
+		t.Errorf("expected only the type-wide schema to apply, got %v", err)
+	}
+	err := client.TrackCtx(context.Background(), NewEvent(EventToolCall, "refund"))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || len(schemaErr.Violations) != 2 {
+		t.Errorf("expected both schemas' violations in a rejection, got %v", err)
+	}
+}
+
+func TestRegisterPayloadSchemaErrors(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	if err := client.RegisterPayloadSchema(EventToolCall, "", json.RawMessage(`{"type": 1}`), SchemaReject); err == nil {
+		t.Error("expected an invalid schema error")
+	}
+	if err := client.RegisterPayloadSchema(EventToolCall, "", json.RawMessage(`{}`), "ignore"); err == nil {
+		t.Error("expected an unknown action error")
+	}
+}
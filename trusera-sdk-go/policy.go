@@ -0,0 +1,188 @@
+package trusera
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CELRule is a policy rule whose condition is a CEL expression
+type CELRule struct {
+	ID         string       `json:"id,omitempty"`     // Stable identifier reported in decisions
+	Action     PolicyAction `json:"action"`           // ActionForbid or ActionPermit
+	Expression string       `json:"expression"`       // CEL condition, e.g. `event.type == "data_access"`
+	Reason     string       `json:"reason,omitempty"` // Optional human-readable explanation
+
+	program *CELProgram
+}
+
+// celPolicyVars are the variables a policy rule may reference: those of
+// eventVars and requestVars
+var celPolicyVars = map[string]bool{"event": true, "payload": true, "metadata": true, "request": true}
+
+// CELPolicy evaluates CEL rules locally using Cedar semantics: any matching
+// forbid rule denies, otherwise the request or event is allowed.
+type CELPolicy struct {
+	rules []CELRule
+}
+
+// NewCELPolicy compiles the given rules into a policy
+func NewCELPolicy(rules ...CELRule) (*CELPolicy, error) {
+	compiled := make([]CELRule, 0, len(rules))
+
+	for i, rule := range rules {
+		if rule.Action != ActionForbid && rule.Action != ActionPermit {
+			return nil, fmt.Errorf("rule %d: invalid action %q", i, rule.Action)
+		}
+
+		prog, err := CompileCEL(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		for _, v := range prog.vars {
+			if !celPolicyVars[v] {
+				return nil, fmt.Errorf("rule %d: undeclared reference to %q", i, v)
+			}
+		}
+
+		rule.program = prog
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i)
+		}
+		compiled = append(compiled, rule)
+	}
+
+	return &CELPolicy{rules: compiled}, nil
+}
+
+// Rules returns a copy of the compiled rules
+func (p *CELPolicy) Rules() []CELRule {
+	out := make([]CELRule, len(p.rules))
+	copy(out, p.rules)
+	return out
+}
+
+// Evaluate evaluates the policy against arbitrary CEL variables.
+// A rule only applies to input that provides every variable it references,
+// so event and request rules can share a policy. Rules that select a
+// missing field or key are treated as not matching. A forbid rule that
+// fails to evaluate for any other reason, such as a type error, denies with
+// the error as its reason, so a broken rule fails closed; a permit rule
+// that fails is skipped.
+func (p *CELPolicy) Evaluate(vars map[string]any) PolicyDecision {
+	var forbidReasons, forbidMatched []string
+	var permitReasons, permitMatched []string
+
+	for _, rule := range p.rules {
+		if !rule.appliesTo(vars) {
+			continue
+		}
+		matched, err := rule.program.EvalBool(vars)
+		failed := err != nil && !errors.Is(err, errCELNoSuchKey) && rule.Action == ActionForbid
+		if !failed && (err != nil || !matched) {
+			continue
+		}
+
+		reason := rule.Reason
+		if reason == "" {
+			reason = rule.Expression
+		}
+		if failed {
+			reason = fmt.Sprintf("evaluation failed, denying: %v", err)
+		}
+		reason = fmt.Sprintf("%s [%s]: %s", rule.Action, rule.ID, reason)
+
+		if rule.Action == ActionForbid {
+			forbidReasons = append(forbidReasons, reason)
+			forbidMatched = append(forbidMatched, rule.ID)
+		} else {
+			permitReasons = append(permitReasons, reason)
+			permitMatched = append(permitMatched, rule.ID)
+		}
+	}
+
+	if len(forbidReasons) > 0 {
+		return PolicyDecision{Decision: "Deny", Reasons: forbidReasons, Matched: forbidMatched}
+	}
+
+	if len(permitReasons) > 0 {
+		return PolicyDecision{Decision: "Allow", Reasons: permitReasons, Matched: permitMatched}
+	}
+
+	return PolicyDecision{
+		Decision: "Allow",
+		Reasons:  []string{"No matching policy rules"},
+		Matched:  []string{},
+	}
+}
+
+// appliesTo reports whether vars provides every variable the rule references
+func (r CELRule) appliesTo(vars map[string]any) bool {
+	for _, v := range r.program.vars {
+		if _, ok := vars[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// EvaluateEvent evaluates the policy against an event. The expression can
+// reference event.id, event.type, event.name, event.timestamp, payload and metadata.
+func (p *CELPolicy) EvaluateEvent(event Event) PolicyDecision {
+	return p.Evaluate(eventVars(event))
+}
+
+// EvaluateRequest evaluates the policy against an outbound HTTP request. The
+// expression can reference request.method, request.url, request.scheme,
+// request.host, request.port, request.path, request.query and request.headers
+// (lower-cased header names).
+func (p *CELPolicy) EvaluateRequest(req *http.Request) PolicyDecision {
+	return p.Evaluate(requestVars(req))
+}
+
+// eventVars builds the CEL activation for an event
+func eventVars(event Event) map[string]any {
+	payload := event.Payload
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+
+	return map[string]any{
+		"event": map[string]any{
+			"id":        event.ID,
+			"type":      string(event.Type),
+			"name":      event.Name,
+			"timestamp": event.Timestamp,
+		},
+		"payload":  payload,
+		"metadata": metadata,
+	}
+}
+
+// requestVars builds the CEL activation for an HTTP request
+func requestVars(req *http.Request) map[string]any {
+	headers := make(map[string]any, len(req.Header))
+	for key, values := range req.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+
+	return map[string]any{
+		"request": map[string]any{
+			"method":  req.Method,
+			"url":     req.URL.String(),
+			"scheme":  req.URL.Scheme,
+			"host":    req.URL.Hostname(),
+			"port":    req.URL.Port(),
+			"path":    req.URL.Path,
+			"query":   req.URL.RawQuery,
+			"headers": headers,
+		},
+	}
+}
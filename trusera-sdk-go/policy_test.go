@@ -0,0 +1,196 @@
+package trusera
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewCELPolicyValidation(t *testing.T) {
+	_, err := NewCELPolicy(CELRule{Action: "deny", Expression: "true"})
+	if err == nil {
+		t.Error("expected error for invalid action")
+	}
+
+	_, err = NewCELPolicy(CELRule{Action: ActionForbid, Expression: "event.type =="})
+	if err == nil {
+		t.Error("expected error for invalid expression")
+	}
+
+	_, err = NewCELPolicy(CELRule{Action: ActionForbid, Expression: `user.name == "x"`})
+	if err == nil || !strings.Contains(err.Error(), "undeclared reference") {
+		t.Errorf("expected error for an undeclared variable, got %v", err)
+	}
+
+	_, err = NewCELPolicy(CELRule{Action: ActionForbid, Expression: `payload.tables.exists(t, t == "users")`})
+	if err == nil || !strings.Contains(err.Error(), "unsupported macro") {
+		t.Errorf("expected error for an unsupported macro, got %v", err)
+	}
+
+	policy, err := NewCELPolicy(CELRule{Action: ActionForbid, Expression: "true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if policy.Rules()[0].ID != "rule-0" {
+		t.Errorf("expected generated rule ID, got %s", policy.Rules()[0].ID)
+	}
+}
+
+func TestCELPolicyEvaluationErrors(t *testing.T) {
+	policy, err := NewCELPolicy(
+		CELRule{ID: "big-export", Action: ActionForbid, Expression: `payload.rows > 100`},
+		CELRule{ID: "admin", Action: ActionForbid, Expression: `request.path.startsWith("/admin")`},
+	)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	decision := policy.EvaluateEvent(NewEvent(EventDataAccess, "export").WithPayload("rows", "many"))
+	if decision.Decision != "Deny" || len(decision.Matched) != 1 || decision.Matched[0] != "big-export" {
+		t.Fatalf("expected the failing forbid rule to deny, got %+v", decision)
+	}
+	if !strings.Contains(decision.Reasons[0], "evaluation failed") {
+		t.Errorf("expected the error in the reason, got %v", decision.Reasons)
+	}
+
+	decision = policy.EvaluateEvent(NewEvent(EventDataAccess, "export"))
+	if decision.Decision != "Allow" || len(decision.Matched) != 0 {
+		t.Errorf("expected missing fields and request rules not to match, got %+v", decision)
+	}
+
+	overflow, _ := NewCELPolicy(CELRule{ID: "overflow", Action: ActionForbid, Expression: `payload.rows + payload.limit < 0`})
+	if decision = overflow.EvaluateEvent(NewEvent(EventDataAccess, "export").WithPayload("rows", int64(math.MaxInt64)).WithPayload("limit", 1)); decision.Decision != "Deny" {
+		t.Errorf("expected an overflowing forbid rule to deny, got %+v", decision)
+	}
+}
+
+func TestCELPolicyEvaluateEvent(t *testing.T) {
+	policy, err := NewCELPolicy(
+		CELRule{
+			ID:         "no-high-sensitivity",
+			Action:     ActionForbid,
+			Expression: `event.type == "data_access" && payload.sensitivity == "high"`,
+			Reason:     "high sensitivity data access",
+		},
+		CELRule{
+			ID:         "allow-tools",
+			Action:     ActionPermit,
+			Expression: `event.type == "tool_call"`,
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		event    Event
+		decision string
+		matched  string
+	}{
+		{
+			name:     "forbidden data access",
+			event:    NewEvent(EventDataAccess, "query").WithPayload("sensitivity", "high"),
+			decision: "Deny",
+			matched:  "no-high-sensitivity",
+		},
+		{
+			name:     "low sensitivity data access",
+			event:    NewEvent(EventDataAccess, "query").WithPayload("sensitivity", "low"),
+			decision: "Allow",
+		},
+		{
+			name:     "missing field does not match",
+			event:    NewEvent(EventDataAccess, "query"),
+			decision: "Allow",
+		},
+		{
+			name:     "permitted tool call",
+			event:    NewEvent(EventToolCall, "search"),
+			decision: "Allow",
+			matched:  "allow-tools",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := policy.EvaluateEvent(tt.event)
+
+			if decision.Decision != tt.decision {
+				t.Errorf("expected %s, got %s", tt.decision, decision.Decision)
+			}
+
+			if tt.matched != "" && (len(decision.Matched) != 1 || decision.Matched[0] != tt.matched) {
+				t.Errorf("expected matched rule %s, got %v", tt.matched, decision.Matched)
+			}
+		})
+	}
+}
+
+func TestClientCheckEventAndTrackAnnotation(t *testing.T) {
+	policy, err := NewCELPolicy(CELRule{
+		ID:         "no-deletes",
+		Action:     ActionForbid,
+		Expression: `event.type == "file_write" && payload.operation == "delete"`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	client := NewClient("test-key", WithPolicy(policy))
+	defer client.Close()
+
+	event := NewEvent(EventFileWrite, "cleanup").WithPayload("operation", "delete")
+
+	if decision := client.CheckEvent(event); decision.Decision != "Deny" {
+		t.Errorf("expected Deny, got %s", decision.Decision)
+	}
+
+	client.Track(event)
+
+	client.mu.Lock()
+	tracked := client.events[0]
+	client.mu.Unlock()
+
+	if tracked.Metadata["policy_decision"] != "Deny" {
+		t.Errorf("expected policy_decision metadata, got %v", tracked.Metadata["policy_decision"])
+	}
+}
+
+func TestInterceptorCELPolicy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	policy, err := NewCELPolicy(CELRule{
+		ID:         "no-writes",
+		Action:     ActionForbid,
+		Expression: `request.method in ["POST", "PUT", "DELETE"] && request.path.startsWith("/admin")`,
+	})
+	if err != nil {
+		t.Fatalf("failed to create policy: %v", err)
+	}
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Policy:      policy,
+	})
+
+	resp, err := httpClient.Get(backend.URL + "/admin/users")
+	if err != nil {
+		t.Fatalf("GET should be allowed: %v", err)
+	}
+	resp.Body.Close()
+
+	_, err = httpClient.Post(backend.URL+"/admin/users", "application/json", strings.NewReader("{}"))
+	if err == nil {
+		t.Fatal("expected POST to be blocked by policy")
+	}
+}
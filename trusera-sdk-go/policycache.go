@@ -0,0 +1,193 @@
+package trusera
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PolicyFailureMode decides what interceptors enforce when no remote policy
+// can be fetched and no usable cached copy exists
+type PolicyFailureMode string
+
+const (
+	PolicyFailOpen   PolicyFailureMode = "fail_open"   // Drop the remote policy and enforce only local options
+	PolicyFailClosed PolicyFailureMode = "fail_closed" // Block every intercepted request
+)
+
+// failClosedPolicy blocks everything while the real policy is unavailable
+var failClosedPolicy = &RemotePolicy{
+	Version:     "fail-closed",
+	Enforcement: ModeBlock,
+	Rules:       []Rule{{ID: "policy-unavailable", Action: RuleBlock, Reason: "remote policy unavailable"}},
+}
+
+// WithPolicyCache keeps a copy of every fetched policy in a file at path,
+// signed with the API key so a tampered copy is rejected. When a fetch fails
+// and the policy in memory is older than ttl, the cached copy is used if it
+// is younger than ttl. A zero ttl never expires either.
+func WithPolicyCache(path string, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.policyCache = path
+		c.policyTTL = ttl
+	}
+}
+
+// WithPolicyFailureMode sets what happens when a policy fetch fails and
+// neither the policy in memory nor the cache is usable. Without it the last
+// policy stays in effect.
+func WithPolicyFailureMode(mode PolicyFailureMode) Option {
+	return func(c *Client) {
+		c.policyFailure = mode
+	}
+}
+
+// cachedPolicy is the on-disk form of a fetched policy
+type cachedPolicy struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Policy    RemotePolicy `json:"policy"`
+	Signature string       `json:"signature,omitempty"`
+}
+
+// sign returns the HMAC of the entry without its signature
+func (e cachedPolicy) sign(key string) (string, error) {
+	e.Signature = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// storePolicy writes a fetched policy to the cache file
+func (c *Client) storePolicy(p *RemotePolicy, fetchedAt time.Time) error {
+	entry := cachedPolicy{FetchedAt: fetchedAt.UTC(), Policy: *p}
+	sig, err := entry.sign(c.apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign policy: %w", err)
+	}
+	entry.Signature = sig
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policy: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.policyCache), ".policy-*")
+	if err != nil {
+		return fmt.Errorf("failed to write policy cache: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write policy cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write policy cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.policyCache); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write policy cache: %w", err)
+	}
+	return nil
+}
+
+// loadPolicy reads and verifies the cache file
+func (c *Client) loadPolicy() (cachedPolicy, error) {
+	var entry cachedPolicy
+	b, err := os.ReadFile(c.policyCache)
+	if err != nil {
+		return entry, fmt.Errorf("failed to read policy cache: %w", err)
+	}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return entry, fmt.Errorf("failed to decode policy cache: %w", err)
+	}
+	want, err := entry.sign(c.apiKey)
+	if err != nil {
+		return entry, fmt.Errorf("failed to verify policy cache: %w", err)
+	}
+	if !hmac.Equal([]byte(want), []byte(entry.Signature)) {
+		return entry, errors.New("policy cache signature mismatch")
+	}
+	return entry, nil
+}
+
+// policyFetched records a successful fetch. A nil policy means the API
+// reported it unchanged. Called with policyMu held.
+func (c *Client) policyFetched(p *RemotePolicy) {
+	now := time.Now()
+	c.policyFetchedAt = now
+	c.policyFallback = ""
+
+	if p == nil {
+		// Unchanged, but a fallback may have replaced it in the meantime
+		p = c.policyCurrent
+	}
+	if p == nil {
+		return
+	}
+	c.policyCurrent = p
+	c.ApplyPolicy(p)
+	if c.policyCache != "" {
+		c.handleError(c.storePolicy(p, now))
+	}
+}
+
+// policyUnavailable falls back after a failed fetch: to the policy in memory
+// while it is fresh, then to the cache, then to the failure mode. An
+// EventPolicyFallback is tracked whenever the fallback changes. Called with
+// policyMu held.
+func (c *Client) policyUnavailable(fetchErr error) {
+	fresh := func(at time.Time) bool {
+		return c.policyTTL <= 0 || time.Since(at) < c.policyTTL
+	}
+	if c.policyCurrent != nil && fresh(c.policyFetchedAt) {
+		return
+	}
+
+	event := NewEvent(EventPolicyFallback, "policy unavailable").
+		WithPayload("error", fetchErr.Error())
+
+	var fallback string
+	if c.policyCache != "" {
+		entry, err := c.loadPolicy()
+		switch {
+		case err != nil:
+			event = event.WithPayload("cache_error", err.Error())
+		case !fresh(entry.FetchedAt):
+			event = event.WithPayload("cache_error", "cached policy expired")
+		default:
+			fallback = "cache"
+			c.ApplyPolicy(&entry.Policy)
+			event = event.
+				WithPayload("policy_version", entry.Policy.Version).
+				WithPayload("policy_age_seconds", int(time.Since(entry.FetchedAt).Seconds()))
+		}
+	}
+
+	if fallback == "" {
+		switch c.policyFailure {
+		case PolicyFailOpen:
+			fallback = string(PolicyFailOpen)
+			c.ApplyPolicy(nil)
+		case PolicyFailClosed:
+			fallback = string(PolicyFailClosed)
+			c.ApplyPolicy(failClosedPolicy)
+		default:
+			return
+		}
+	}
+
+	if fallback != c.policyFallback {
+		c.policyFallback = fallback
+		c.Track(event.WithPayload("fallback", fallback))
+	}
+}
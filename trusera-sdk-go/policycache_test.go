@@ -0,0 +1,178 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func policyCacheClient(t *testing.T, srv *policyServer, opts ...Option) *Client {
+	t.Helper()
+	api := httptest.NewServer(srv)
+	t.Cleanup(api.Close)
+
+	opts = append([]Option{WithBaseURL(api.URL), WithAgentID("agent-1"), WithFlushInterval(time.Hour)}, opts...)
+	client := NewClient("test-key", opts...)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func fallbackEvents(c *Client) []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Event
+	for _, e := range c.events {
+		if e.Type == EventPolicyFallback {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestPolicyCacheFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	srv := &policyServer{policy: RemotePolicy{Version: "v7", BlockPatterns: []string{"evil.example.com"}}}
+
+	first := policyCacheClient(t, srv, WithPolicyCache(path, time.Hour))
+	if err := first.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected policy to be cached: %v", err)
+	}
+
+	// A restarted agent that cannot reach the control plane uses the cache
+	srv.fail.Store(true)
+	second := policyCacheClient(t, srv, WithPolicyCache(path, time.Hour), WithPolicyFailureMode(PolicyFailClosed))
+	if err := second.SyncPolicy(context.Background()); err == nil {
+		t.Fatal("expected the fetch to fail")
+	}
+	if p := second.RemotePolicy(); p == nil || p.Version != "v7" {
+		t.Fatalf("expected cached policy v7, got %+v", p)
+	}
+
+	_ = second.SyncPolicy(context.Background())
+	events := fallbackEvents(second)
+	if len(events) != 1 {
+		t.Fatalf("expected one fallback event per change, got %d", len(events))
+	}
+	if events[0].Payload["fallback"] != "cache" || events[0].Payload["policy_version"] != "v7" {
+		t.Errorf("unexpected fallback event %v", events[0].Payload)
+	}
+
+	// Recovery replaces the cached policy with the live one
+	srv.fail.Store(false)
+	srv.set(RemotePolicy{Version: "v8"})
+	if err := second.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("SyncPolicy failed after recovery: %v", err)
+	}
+	if p := second.RemotePolicy(); p == nil || p.Version != "v8" {
+		t.Errorf("expected live policy v8, got %+v", p)
+	}
+}
+
+func TestPolicyCacheRejectsTamperedCopy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	srv := &policyServer{policy: RemotePolicy{Version: "v1", Enforcement: ModeBlock, BlockPatterns: []string{"evil.example.com"}}}
+
+	first := policyCacheClient(t, srv, WithPolicyCache(path, 0))
+	if err := first.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(b), `"block"`, `"log"`, 1)), 0o600)
+
+	srv.fail.Store(true)
+	second := policyCacheClient(t, srv, WithPolicyCache(path, 0), WithPolicyFailureMode(PolicyFailClosed))
+	_ = second.SyncPolicy(context.Background())
+
+	if p := second.RemotePolicy(); p != failClosedPolicy {
+		t.Fatalf("expected the fail-closed policy, got %+v", p)
+	}
+	events := fallbackEvents(second)
+	if len(events) != 1 || events[0].Payload["fallback"] != "fail_closed" {
+		t.Fatalf("expected a fail_closed event, got %v", events)
+	}
+	if msg, _ := events[0].Payload["cache_error"].(string); !strings.Contains(msg, "signature") {
+		t.Errorf("expected a signature error, got %q", msg)
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("fail-closed should block every request")
+	}))
+	defer backend.Close()
+	httpClient := WrapHTTPClient(&http.Client{}, second, InterceptorOptions{Enforcement: ModeLog})
+	if _, err := httpClient.Get(backend.URL); err == nil {
+		t.Error("expected the request to be blocked")
+	}
+}
+
+func TestPolicyFailOpenAfterTTL(t *testing.T) {
+	srv := &policyServer{policy: RemotePolicy{Version: "v1", Enforcement: ModeBlock}}
+	client := policyCacheClient(t, srv,
+		WithPolicyCache(filepath.Join(t.TempDir(), "policy.json"), time.Hour),
+		WithPolicyFailureMode(PolicyFailOpen),
+	)
+	if err := client.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+
+	// Within the TTL the policy in memory is kept without an event
+	srv.fail.Store(true)
+	_ = client.SyncPolicy(context.Background())
+	if p := client.RemotePolicy(); p == nil || p.Version != "v1" {
+		t.Fatalf("expected v1 to be kept while fresh, got %+v", p)
+	}
+	if n := len(fallbackEvents(client)); n != 0 {
+		t.Fatalf("expected no fallback events while fresh, got %d", n)
+	}
+
+	// Once both the memory and cache copies are stale, enforcement fails open
+	client.policyMu.Lock()
+	client.policyTTL = time.Nanosecond
+	client.policyMu.Unlock()
+	_ = client.SyncPolicy(context.Background())
+	if p := client.RemotePolicy(); p != nil {
+		t.Errorf("expected fail-open to drop the remote policy, got %+v", p)
+	}
+	events := fallbackEvents(client)
+	if len(events) != 1 || events[0].Payload["fallback"] != "fail_open" || events[0].Payload["cache_error"] != "cached policy expired" {
+		t.Errorf("unexpected fallback events %v", events)
+	}
+}
+
+func TestPolicyFailureWithoutConfigKeepsPolicy(t *testing.T) {
+	srv := &policyServer{}
+	srv.fail.Store(true)
+	client := policyCacheClient(t, srv)
+	client.ApplyPolicy(&RemotePolicy{Version: "manual"})
+
+	_ = client.SyncPolicy(context.Background())
+	if p := client.RemotePolicy(); p == nil || p.Version != "manual" {
+		t.Errorf("expected the last policy to stay in effect, got %+v", p)
+	}
+	if n := len(fallbackEvents(client)); n != 0 {
+		t.Errorf("expected no fallback events, got %d", n)
+	}
+}
+
+func TestPolicyFailClosedIgnoresMissingAgentID(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPolicyFailureMode(PolicyFailClosed))
+	defer client.Close()
+
+	if err := client.SyncPolicy(context.Background()); !errors.Is(err, errNoAgentID) {
+		t.Fatalf("expected the missing agent ID reported, got %v", err)
+	}
+	if p := client.RemotePolicy(); p != nil {
+		t.Errorf("expected no fail-closed policy before the agent is registered, got %+v", p)
+	}
+	if n := len(fallbackEvents(client)); n != 0 {
+		t.Errorf("expected no fallback events, got %d", n)
+	}
+}
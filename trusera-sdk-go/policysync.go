@@ -0,0 +1,177 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// RemotePolicy is an agent's enforcement policy as managed in the Trusera
+// control plane. Interceptors apply it on top of their own options: its rules
+// are checked before local rules, its patterns are added to the local lists,
+// and a non-empty Enforcement replaces the local mode.
+type RemotePolicy struct {
+	Version         string          `json:"version,omitempty"`
+	Enforcement     EnforcementMode `json:"enforcement,omitempty"`
+	Rules           []Rule          `json:"rules,omitempty"`
+	CandidateRules  []Rule          `json:"candidate_rules,omitempty"` // Trialled in ModeShadow
+	EnforcePercent  float64         `json:"enforce_percent,omitempty"` // Canary rollout; see InterceptorOptions
+	ExcludePatterns []string        `json:"exclude_patterns,omitempty"`
+	BlockPatterns   []string        `json:"block_patterns,omitempty"`
+	AllowPatterns   []string        `json:"allow_patterns,omitempty"`
+
+	// PauseEvents drops every tracked event and PauseEnforcement records
+	// violations without blocking them, until a policy clears them; see
+	// Client.Pause for an agent pausing itself
+	PauseEvents      bool `json:"pause_events,omitempty"`
+	PauseEnforcement bool `json:"pause_enforcement,omitempty"`
+}
+
+// WithPolicySync fetches the agent's policy from the API right away and then
+// every interval, applying it to all HTTP, gRPC and websocket interceptors
+// built on this client without a restart. If a fetch fails, the last policy
+// stays in effect unless WithPolicyCache or WithPolicyFailureMode say
+// otherwise. The agent ID must be set with WithAgentID or RegisterAgent;
+// with RegisterAgent, the first fetch waits for it to return.
+func WithPolicySync(interval time.Duration) Option {
+	return func(c *Client) {
+		c.policySync = interval
+	}
+}
+
+// policySyncer keeps the remote policy up to date until the client is closed.
+// Until the agent has an ID it does not fetch; RegisterAgent wakes it to
+// fetch right away.
+func (c *Client) policySyncer() {
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(c.policySync)
+	defer ticker.Stop()
+	for {
+		c.mu.Lock()
+		registered := c.agentID != ""
+		c.mu.Unlock()
+		if registered {
+			c.handleError(c.SyncPolicy(ctx))
+		}
+		select {
+		case <-ticker.C:
+		case <-c.registered:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SyncPolicy fetches the agent's policy from the API now and applies it if it
+// changed. Use it to react to a push notification from the control plane.
+// When the fetch fails, the policy cache and failure mode decide what stays
+// in effect (see WithPolicyCache).
+func (c *Client) SyncPolicy(ctx context.Context) error {
+	c.policyMu.Lock()
+	defer c.policyMu.Unlock()
+
+	p, err := c.fetchPolicy(ctx)
+	if err != nil {
+		// A missing agent ID is a setup error, not an unavailable policy
+		if ctx.Err() == nil && !errors.Is(err, errNoAgentID) {
+			c.policyUnavailable(err)
+		}
+		return err
+	}
+	c.policyFetched(p)
+	return nil
+}
+
+// errNoAgentID is returned by SyncPolicy before the agent ID is known
+var errNoAgentID = errors.New("agent ID is required to sync policy")
+
+// fetchPolicy requests the agent's policy, returning nil if it is unchanged.
+// Called with policyMu held.
+func (c *Client) fetchPolicy(ctx context.Context) (*RemotePolicy, error) {
+	c.mu.Lock()
+	agentID := c.agentID
+	c.mu.Unlock()
+	if agentID == "" {
+		return nil, errNoAgentID
+	}
+
+	endpoint, err := c.endpoint("/v1/agents/" + url.PathEscape(agentID) + "/policy")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if c.policyETag != "" && c.policyCurrent != nil {
+		req.Header.Set("If-None-Match", c.policyETag)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var p RemotePolicy
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+	c.policyETag = resp.Header.Get("ETag")
+	return &p, nil
+}
+
+// ApplyPolicy replaces the remote policy used by this client's interceptors.
+// Requests already past enforcement are unaffected. A nil policy reverts the
+// interceptors to their own options.
+func (c *Client) ApplyPolicy(p *RemotePolicy) {
+	c.remotePolicy.Store(p)
+}
+
+// RemotePolicy returns the remote policy in effect, or nil if none was applied
+func (c *Client) RemotePolicy() *RemotePolicy {
+	return c.remotePolicy.Load()
+}
+
+// merge layers a remote policy over interceptor options
+func (o InterceptorOptions) merge(p *RemotePolicy) InterceptorOptions {
+	if p == nil {
+		return o
+	}
+	if p.Enforcement != "" {
+		o.Enforcement = p.Enforcement
+	}
+	if p.EnforcePercent != 0 {
+		o.EnforcePercent = p.EnforcePercent
+	}
+	o.Rules = append(append([]Rule{}, p.Rules...), o.Rules...)
+	o.CandidateRules = append(append([]Rule{}, p.CandidateRules...), o.CandidateRules...)
+	o.ExcludePatterns = append(append([]string{}, o.ExcludePatterns...), p.ExcludePatterns...)
+	o.BlockPatterns = append(append([]string{}, o.BlockPatterns...), p.BlockPatterns...)
+	o.AllowPatterns = append(append([]string{}, o.AllowPatterns...), p.AllowPatterns...)
+	return o
+}
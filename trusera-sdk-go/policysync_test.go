@@ -0,0 +1,246 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// policyServer serves a mutable policy with ETag support
+type policyServer struct {
+	mu      sync.Mutex
+	policy  RemotePolicy
+	fetches atomic.Int32
+	notMod  atomic.Int32
+	fail    atomic.Bool
+}
+
+func (s *policyServer) set(p RemotePolicy) {
+	s.mu.Lock()
+	s.policy = p
+	s.mu.Unlock()
+}
+
+func (s *policyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/agents/agent-1/policy" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	s.fetches.Add(1)
+	if s.fail.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	s.mu.Lock()
+	p := s.policy
+	s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-key" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	etag := `"` + p.Version + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.notMod.Add(1)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	json.NewEncoder(w).Encode(p)
+}
+
+func TestSyncPolicyFetchesAndApplies(t *testing.T) {
+	srv := &policyServer{policy: RemotePolicy{
+		Version:       "v1",
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"evil.example.com"},
+	}}
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL), WithAgentID("agent-1"), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if err := client.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("SyncPolicy failed: %v", err)
+	}
+	if p := client.RemotePolicy(); p == nil || p.Version != "v1" {
+		t.Fatalf("expected policy v1, got %+v", p)
+	}
+
+	if err := client.SyncPolicy(context.Background()); err != nil {
+		t.Fatalf("second SyncPolicy failed: %v", err)
+	}
+	if srv.notMod.Load() != 1 {
+		t.Errorf("expected the unchanged policy to be served as 304, got %d", srv.notMod.Load())
+	}
+}
+
+func TestSyncPolicyErrors(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer api.Close()
+
+	client := NewClient("test-key", WithBaseURL(api.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if err := client.SyncPolicy(context.Background()); err == nil {
+		t.Error("expected an error without an agent ID")
+	}
+
+	client.ApplyPolicy(&RemotePolicy{Version: "kept"})
+	client.mu.Lock()
+	client.agentID = "agent-1"
+	client.mu.Unlock()
+	if err := client.SyncPolicy(context.Background()); err == nil {
+		t.Error("expected an error for a 500 response")
+	}
+	if p := client.RemotePolicy(); p == nil || p.Version != "kept" {
+		t.Errorf("a failed fetch should keep the last policy, got %+v", p)
+	}
+}
+
+func TestPolicySyncHotAppliesToInterceptors(t *testing.T) {
+	srv := &policyServer{policy: RemotePolicy{Version: "v1"}}
+	api := httptest.NewServer(srv)
+	defer api.Close()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	client := NewClient("test-key",
+		WithBaseURL(api.URL),
+		WithAgentID("agent-1"),
+		WithFlushInterval(time.Hour),
+		WithPolicySync(10*time.Millisecond),
+	)
+	defer client.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{Enforcement: ModeLog})
+
+	resp, err := httpClient.Get(backend.URL + "/payments")
+	if err != nil {
+		t.Fatalf("request should be allowed before the policy changes: %v", err)
+	}
+	resp.Body.Close()
+
+	srv.set(RemotePolicy{
+		Version: "v2",
+		Rules:   []Rule{{ID: "no-payments", Match: "/payments", Action: RuleBlock, Severity: "high"}},
+	})
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if p := client.RemotePolicy(); p != nil && p.Version == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("policy v2 was not synced")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := httpClient.Get(backend.URL + "/payments"); err == nil {
+		t.Fatal("expected the synced rule to block the request")
+	}
+	event, _ := trackedEventWhere(client, func(e Event) bool { return e.Payload["rule_id"] == "no-payments" })
+	if event.Metadata["policy_version"] != "v2" {
+		t.Errorf("expected policy_version v2, got %v", event.Metadata["policy_version"])
+	}
+}
+
+func TestPolicySyncAfterRegisterAgent(t *testing.T) {
+	srv := &policyServer{policy: RemotePolicy{Version: "v1"}}
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	mux.HandleFunc("/v1/agents", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"agent_id": "agent-1"})
+	})
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	var mu sync.Mutex
+	var errs []error
+	client := NewClient("test-key",
+		WithBaseURL(api.URL),
+		WithFlushInterval(time.Hour),
+		WithPolicySync(time.Hour),
+		WithErrorHandler(func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}),
+	)
+	defer client.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	if srv.fetches.Load() != 0 {
+		t.Fatal("expected no fetch before the agent is registered")
+	}
+	if _, err := client.RegisterAgent("payments", "custom"); err != nil {
+		t.Fatalf("RegisterAgent failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if p := client.RemotePolicy(); p != nil && p.Version == "v1" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected RegisterAgent to sync the policy without waiting for the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) > 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestMergeRemotePolicy(t *testing.T) {
+	local := InterceptorOptions{
+		Enforcement:   ModeWarn,
+		Rules:         []Rule{{ID: "local", Match: "a"}},
+		BlockPatterns: []string{"local.example.com"},
+	}
+	merged := local.merge(&RemotePolicy{
+		Enforcement:   ModeBlock,
+		Rules:         []Rule{{ID: "remote", Match: "a"}},
+		BlockPatterns: []string{"remote.example.com"},
+		AllowPatterns: []string{"ok.example.com"},
+	})
+
+	if merged.Enforcement != ModeBlock {
+		t.Errorf("expected the remote mode to win, got %s", merged.Enforcement)
+	}
+	if len(merged.Rules) != 2 || merged.Rules[0].ID != "remote" {
+		t.Errorf("expected remote rules first, got %+v", merged.Rules)
+	}
+	if len(merged.BlockPatterns) != 2 || len(merged.AllowPatterns) != 1 {
+		t.Errorf("expected patterns to be combined, got %+v", merged)
+	}
+	if len(local.BlockPatterns) != 1 || len(local.Rules) != 1 {
+		t.Error("merge should not modify the local options")
+	}
+	if got := local.merge(&RemotePolicy{}); got.Enforcement != ModeWarn {
+		t.Errorf("an empty remote mode should keep the local one, got %s", got.Enforcement)
+	}
+}
+
+func trackedEventWhere(c *Client, match func(Event) bool) (Event, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.events {
+		if match(e) {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
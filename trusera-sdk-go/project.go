@@ -0,0 +1,52 @@
+package trusera
+
+import "context"
+
+// WithProject routes the client's events to a Trusera project, e.g. one per
+// tenant or workspace. Events can be routed elsewhere individually with
+// Event.WithProject or ContextWithProject. Without a project, the API key's
+// default project receives the events.
+func WithProject(id string) Option {
+	return func(c *Client) {
+		c.project = id
+	}
+}
+
+// WithProject routes the event to a project other than the client's
+func (e Event) WithProject(id string) Event {
+	return e.WithMetadata("project_id", id)
+}
+
+// ContextWithProject returns a context whose events, tracked with TrackCtx,
+// are routed to a project, e.g. the tenant of a request
+func ContextWithProject(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, projectKey, id)
+}
+
+// ProjectFromContext returns the project stored in ctx, if any
+func ProjectFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(projectKey).(string)
+	return id
+}
+
+// projectOf returns the project an event is routed to
+func projectOf(event Event) string {
+	id, _ := event.Metadata["project_id"].(string)
+	return id
+}
+
+// projectRuns splits events into runs of consecutive events of the same
+// project and delivery stream, so that each request carries a single
+// project and its sequence numbers a single stream
+func projectRuns(events []Event) [][]Event {
+	var runs [][]Event
+	start := 0
+	for i := 1; i <= len(events); i++ {
+		if i == len(events) || projectOf(events[i]) != projectOf(events[start]) ||
+			events[i].Stream != events[start].Stream {
+			runs = append(runs, events[start:i])
+			start = i
+		}
+	}
+	return runs
+}
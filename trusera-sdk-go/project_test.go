@@ -0,0 +1,68 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProjectRouting(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ProjectID string  `json:"project_id"`
+			Events    []Event `json:"events"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		var names []string
+		for _, e := range body.Events {
+			names = append(names, e.Name)
+		}
+		mu.Lock()
+		requests = append(requests, body.ProjectID+":"+strings.Join(names, ","))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour), WithProject("default"))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b").WithProject("tenant-1"))
+	client.Track(NewEvent(EventToolCall, "c").WithProject("tenant-1"))
+	client.TrackCtx(ContextWithProject(context.Background(), "tenant-2"), NewEvent(EventToolCall, "d"))
+	client.Track(NewEvent(EventToolCall, "e"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "default:a tenant-1:b,c tenant-2:d default:e"
+	if got := strings.Join(requests, " "); got != want {
+		t.Errorf("expected one request per run of a project %q, got %q", want, got)
+	}
+}
+
+func TestProjectForSinks(t *testing.T) {
+	var projects []string
+	sink := SinkFunc(func(ctx context.Context, events []Event) error {
+		projects = append(projects, ProjectFromContext(ctx))
+		return nil
+	})
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink), WithSink("copy", sink))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b").WithProject("tenant-1"))
+	client.Flush()
+	if strings.Join(projects, ",") != ",,tenant-1,tenant-1" {
+		t.Errorf("expected the project in the sink context, got %q", projects)
+	}
+}
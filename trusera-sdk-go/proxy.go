@@ -0,0 +1,252 @@
+package trusera
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hopHeaders are connection-specific and not forwarded by the proxy
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// NewProxy returns an HTTP forward proxy that applies the interceptor
+// options to everything passing through it, so agents written in any
+// language can be governed by pointing HTTP_PROXY and HTTPS_PROXY at it.
+//
+// Plain HTTP requests go through the same enforcement as WrapHTTPClient,
+// including audit capture and guardrails. HTTPS traffic arrives as CONNECT
+// tunnels, which are matched against "https://host" (with the port when it
+// is not 443) and reported with the bytes they carried; their contents stay
+// encrypted. Blocked requests and tunnels get a 403 response.
+func NewProxy(truseraClient *Client, opts InterceptorOptions) http.Handler {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	base := &http.Transport{
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &proxy{
+		t:    &interceptingTransport{base: base, client: truseraClient, opts: opts},
+		dial: dialer.DialContext,
+	}
+}
+
+// proxy forwards requests and tunnels through an interceptingTransport
+type proxy struct {
+	t    *interceptingTransport
+	dial DialContextFunc
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "trusera-proxy only serves proxy requests", http.StatusBadRequest)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	removeHopHeaders(out.Header)
+	if r.ContentLength == 0 {
+		out.Body = nil
+	}
+
+	resp, err := p.t.RoundTrip(out)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrBlocked) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	copyFlushing(w, resp.Body)
+}
+
+// tunnel enforces and relays a CONNECT request
+func (p *proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	target := "https://" + strings.TrimSuffix(host, ":443")
+	v := p.t.evaluateRequest(http.MethodConnect, target)
+
+	if !v.excluded {
+		v = p.t.canary(r.Context(), target, v)
+		req := (&http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Scheme: "https", Host: host},
+			Host:   host,
+			Header: r.Header,
+		}).WithContext(r.Context())
+		blocked := v.blocked
+		policyReasons := p.t.policyReasons(req)
+		if len(policyReasons) > 0 {
+			blocked = true
+		}
+
+		event := NewEvent(EventAPICall, "CONNECT "+host).
+			WithPayload("method", http.MethodConnect).
+			WithPayload("url", target).
+			WithPayload("protocol", "https").
+			WithPayload("headers", sanitizeHeaders(r.Header)).
+			WithPayload("blocked", blocked)
+		event = p.t.client.correlateRequest(req, v.describe(event))
+		if len(policyReasons) > 0 {
+			event = event.WithPayload("policy_reasons", policyReasons)
+		}
+
+		p.t.logDecision(req.Context(), "https", http.MethodConnect, target, v, blocked, blockCause{BlockReasonPolicy, policyReasons})
+		if blocked {
+			event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+			switch v.mode {
+			case ModeBlock, ModeAllowList:
+				p.t.client.Track(event)
+				http.Error(w, v.blockedError(target, blockCause{BlockReasonPolicy, policyReasons}).Error(), http.StatusForbidden)
+				return
+			case ModeWarn:
+				event = event.WithMetadata("warning", "URL matches block pattern but allowed in warn mode")
+			}
+		} else {
+			event = event.WithPayload("enforcement_action", "allowed")
+		}
+		p.t.client.Track(event)
+	}
+
+	upstream, err := p.dial(r.Context(), "tcp", host)
+	if err != nil {
+		if !v.excluded {
+			errorEvent := NewEvent(EventAPICall, "error").
+				WithPayload("method", http.MethodConnect).
+				WithPayload("url", target).
+				WithPayload("error", err.Error())
+			p.t.client.Track(p.t.client.correlateRequest(r, errorEvent))
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	start := time.Now()
+	var sent, received atomic.Int64
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err == nil {
+		// Bytes the client sent after the CONNECT request may already be buffered
+		if n := rw.Reader.Buffered(); n > 0 {
+			buffered, _ := rw.Reader.Peek(n)
+			written, _ := upstream.Write(buffered)
+			sent.Add(int64(written))
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			n, _ := io.Copy(upstream, conn)
+			sent.Add(n)
+			closeWrite(upstream)
+		}()
+		go func() {
+			defer wg.Done()
+			n, _ := io.Copy(conn, upstream)
+			received.Add(n)
+			closeWrite(conn)
+		}()
+		wg.Wait()
+	}
+	conn.Close()
+	upstream.Close()
+
+	if !v.excluded {
+		closeEvent := NewEvent(EventAPICall, "tunnel_close").
+			WithPayload("method", http.MethodConnect).
+			WithPayload("url", target).
+			WithPayload("bytes_sent", sent.Load()).
+			WithPayload("bytes_received", received.Load()).
+			WithPayload("duration_ms", float64(time.Since(start).Microseconds())/1000)
+		p.t.client.Track(p.t.client.correlateRequest(r, closeEvent))
+	}
+}
+
+// closeWrite half-closes a connection so the peer sees EOF
+func closeWrite(conn net.Conn) {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+		return
+	}
+	conn.Close()
+}
+
+// removeHopHeaders deletes connection-specific headers, including those
+// named by the Connection header
+func removeHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			h.Del(strings.TrimSpace(name))
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// copyFlushing copies a response body, flushing after every write so that
+// streamed responses reach the client as they arrive
+func copyFlushing(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package trusera
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func proxyClient(t *testing.T, client *Client, opts InterceptorOptions) *http.Client {
+	t.Helper()
+	proxy := httptest.NewServer(NewProxy(client, opts))
+	t.Cleanup(proxy.Close)
+	proxyURL, _ := url.Parse(proxy.URL)
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+}
+
+func TestProxyForwardsAndBlocksHTTP(t *testing.T) {
+	client := dialClient(t)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Connection") != "" {
+			t.Error("hop-by-hop headers should not be forwarded")
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Backend", "yes")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(append([]byte("echo:"), body...))
+	}))
+	defer backend.Close()
+
+	httpClient := proxyClient(t, client, InterceptorOptions{Enforcement: ModeBlock, BlockPatterns: []string{"/forbidden"}})
+
+	resp, err := httpClient.Post(backend.URL+"/ok", "text/plain", strings.NewReader("hi"))
+	if err != nil {
+		t.Fatalf("proxied request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || string(body) != "echo:hi" || resp.Header.Get("X-Backend") != "yes" {
+		t.Errorf("unexpected response %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	if _, ok := trackedEvent(client, "POST "+backend.URL+"/ok"); !ok {
+		t.Error("expected the proxied request to be tracked")
+	}
+
+	resp, err = httpClient.Get(backend.URL + "/forbidden")
+	if err != nil {
+		t.Fatalf("blocked request should get a response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a blocked request, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyTunnelsCONNECT(t *testing.T) {
+	client := dialClient(t)
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer backend.Close()
+
+	proxy := httptest.NewServer(NewProxy(client, InterceptorOptions{Enforcement: ModeLog}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+	transport := backend.Client().Transport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	httpClient := &http.Client{Transport: transport}
+
+	resp, err := httpClient.Get(backend.URL)
+	if err != nil {
+		t.Fatalf("tunneled request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "secure" {
+		t.Errorf("unexpected body %q", body)
+	}
+	transport.CloseIdleConnections()
+
+	host := backend.Listener.Addr().String()
+	connect, ok := trackedEvent(client, "CONNECT "+host)
+	if !ok {
+		t.Fatal("expected the tunnel to be tracked")
+	}
+	if connect.Payload["url"] != "https://"+host || connect.Payload["enforcement_action"] != "allowed" {
+		t.Errorf("unexpected connect payload %v", connect.Payload)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if e, ok := trackedEvent(client, "tunnel_close"); ok {
+			if e.Payload["bytes_sent"].(int64) == 0 || e.Payload["bytes_received"].(int64) == 0 {
+				t.Errorf("expected tunnel byte counts, got %v", e.Payload)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a tunnel_close event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProxyBlocksCONNECT(t *testing.T) {
+	client := dialClient(t)
+	proxy := httptest.NewServer(NewProxy(client, InterceptorOptions{
+		Enforcement:   ModeAllowList,
+		AllowPatterns: []string{"https://api.openai.com"},
+	}))
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", proxy.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("CONNECT pastebin.com:443 HTTP/1.1\r\nHost: pastebin.com:443\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", resp.StatusCode)
+	}
+
+	event, ok := trackedEvent(client, "CONNECT pastebin.com:443")
+	if !ok || event.Payload["url"] != "https://pastebin.com" || event.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("unexpected blocked tunnel event %v", event.Payload)
+	}
+}
+
+func TestProxyRejectsOriginRequests(t *testing.T) {
+	client := dialClient(t)
+	proxy := httptest.NewServer(NewProxy(client, InterceptorOptions{}))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a non-proxy request, got %d", resp.StatusCode)
+	}
+}
+
+func TestRemoveHopHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Connection", "X-Custom, Keep-Alive")
+	h.Set("X-Custom", "1")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Content-Type", "text/plain")
+	removeHopHeaders(h)
+	if len(h) != 1 || h.Get("Content-Type") != "text/plain" {
+		t.Errorf("unexpected headers after removal %v", h)
+	}
+}
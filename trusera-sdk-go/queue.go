@@ -0,0 +1,371 @@
+package trusera
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const segmentSuffix = ".wal"
+
+// WithPersistentQueue writes every tracked event to a write-ahead log in dir
+// before it is buffered. Batches stay on disk until the API accepts them, so
+// events survive crashes and restarts and failed flushes are retried. Events
+// left over from a previous run are sent when the client starts.
+func WithPersistentQueue(dir string) Option {
+	return func(c *Client) {
+		c.queueDir = dir
+	}
+}
+
+// WithMaxQueueBytes caps the disk space used by WithPersistentQueue and by
+// SpillToDisk. When an event would not fit, the oldest unsent segments are
+// discarded to make room; their events are dropped with DropReasonOverflow.
+// If that is not enough, the event itself is handled like a full buffer.
+func WithMaxQueueBytes(n int64) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxQueueBytes = n
+		}
+	}
+}
+
+// errQueueFull is returned by append when an event does not fit under the
+// queue's size limit, even after discarding every segment it could
+var errQueueFull = errors.New("queue is full")
+
+// diskQueue is a segmented write-ahead log of events. Tracked events are
+// appended to the active segment; each flush seals it so that it can be sent
+// and then deleted.
+type diskQueue struct {
+	dir      string
+	cipher   *lineCipher
+	maxBytes int64          // 0 for no limit
+	drop     func(...Event) // receives the events of discarded segments
+
+	mu      sync.Mutex
+	seq     uint64
+	active  *os.File
+	size    int64  // bytes in all segments
+	sending string // segment being flushed
+	discard bool   // sending is discarded when its flush fails
+}
+
+func openDiskQueue(dir string, cipher *lineCipher) (*diskQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &diskQueue{dir: dir, cipher: cipher}
+	segments, err := q.sealed()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range segments {
+		if seq, ok := segmentSeq(path); ok && seq > q.seq {
+			q.seq = seq
+		}
+		if info, err := os.Stat(path); err == nil {
+			q.size += info.Size()
+		}
+	}
+	return q, nil
+}
+
+// openQueue opens a disk queue limited by WithMaxQueueBytes
+func (c *Client) openQueue(dir string) (*diskQueue, error) {
+	q, err := openDiskQueue(dir, c.cipher)
+	if err != nil {
+		return nil, err
+	}
+	q.maxBytes = c.maxQueueBytes
+	q.drop = func(events ...Event) { c.drop(DropReasonOverflow, events...) }
+	return q, nil
+}
+
+// append writes an event to the active segment, starting one if needed
+func (q *diskQueue) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if line, err = q.cipher.seal(line); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := int64(len(line) + 1)
+	if q.maxBytes > 0 && q.size+n > q.maxBytes && !q.makeRoomLocked(n) {
+		return errQueueFull
+	}
+
+	if q.active == nil {
+		q.seq++
+		path := filepath.Join(q.dir, fmt.Sprintf("%020d%s", q.seq, segmentSuffix))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create queue segment: %w", err)
+		}
+		q.active = f
+	}
+
+	if _, err := q.active.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue segment: %w", err)
+	}
+	q.size += n
+	return nil
+}
+
+// makeRoomLocked discards sealed segments, oldest first, until n more bytes
+// fit under the limit. The segment being sent is the oldest; it is marked
+// and discarded by the flush unless the send succeeds. The active segment
+// is kept, and nothing is discarded if n bytes would not fit even then. It
+// must be called with q.mu held and reports whether n bytes now fit.
+func (q *diskQueue) makeRoomLocked(n int64) bool {
+	segments, err := q.sealedLocked()
+	if err != nil {
+		return false
+	}
+	var discardable []string
+	sizes := make(map[string]int64)
+	free := q.maxBytes - q.size
+	for _, path := range segments {
+		info, err := os.Stat(path)
+		if err != nil || (path == q.sending && q.discard) {
+			continue
+		}
+		discardable = append(discardable, path)
+		sizes[path] = info.Size()
+		free += info.Size()
+	}
+	if free < n {
+		return false
+	}
+
+	for _, path := range discardable {
+		if q.size+n <= q.maxBytes {
+			break
+		}
+		if path == q.sending {
+			q.discard = true
+			q.size -= sizes[path]
+			continue
+		}
+		events, _ := readSegment(path, q.cipher)
+		if err := os.Remove(path); err != nil {
+			return false
+		}
+		q.size -= sizes[path]
+		if q.drop != nil && len(events) > 0 {
+			q.drop(events...)
+		}
+	}
+	return true
+}
+
+// seal syncs and closes the active segment so it can be flushed
+func (q *diskQueue) seal() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active == nil {
+		return nil
+	}
+	f := q.active
+	q.active = nil
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync queue segment: %w", err)
+	}
+	return f.Close()
+}
+
+// sealed lists segments that are no longer being written, oldest first
+func (q *diskQueue) sealed() ([]string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.sealedLocked()
+}
+
+func (q *diskQueue) sealedLocked() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue directory: %w", err)
+	}
+
+	active := ""
+	if q.active != nil {
+		active = q.active.Name()
+	}
+
+	var segments []string
+	for _, e := range entries {
+		path := filepath.Join(q.dir, e.Name())
+		if e.IsDir() || path == active {
+			continue
+		}
+		if _, ok := segmentSeq(path); ok {
+			segments = append(segments, path)
+		}
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// pending counts the events in sealed segments
+func (q *diskQueue) pending() int {
+	segments, err := q.sealed()
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, path := range segments {
+		events, _ := readSegment(path, q.cipher)
+		n += len(events)
+	}
+	return n
+}
+
+// claim marks a segment as being sent, so a discard waits for the send to
+// end. It reports false if the segment was discarded already.
+func (q *diskQueue) claim(path string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := os.Stat(path); err != nil {
+		return false
+	}
+	q.sending = path
+	return true
+}
+
+// remove deletes a segment that has been sent
+func (q *diskQueue) remove(path string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	discarded := q.discard
+	q.sending, q.discard = "", false
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if !discarded {
+		q.size -= info.Size()
+	}
+	return nil
+}
+
+// release ends a claim on a segment that could not be sent, discarding it
+// if makeRoomLocked marked it meanwhile
+func (q *diskQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	path, discarded := q.sending, q.discard
+	q.sending, q.discard = "", false
+	if !discarded {
+		return
+	}
+	events, _ := readSegment(path, q.cipher)
+	if os.Remove(path) == nil && q.drop != nil && len(events) > 0 {
+		q.drop(events...)
+	}
+}
+
+// close seals the active segment; unsent events remain on disk
+func (q *diskQueue) close() error {
+	return q.seal()
+}
+
+// segmentSeq parses the sequence number from a segment file name
+func segmentSeq(path string) (uint64, bool) {
+	name := filepath.Base(path)
+	if !strings.HasSuffix(name, segmentSuffix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+	return seq, err == nil
+}
+
+// readSegment loads the events in a segment. A torn final line left by a
+// crash mid-write is skipped, and plaintext lines in an encrypted queue are
+// dropped with ErrUnencrypted.
+func readSegment(path string, cipher *lineCipher) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queue segment: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	plaintext := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line, err := cipher.open(scanner.Bytes())
+		if errors.Is(err, ErrEncryptionKey) {
+			return nil, fmt.Errorf("failed to read queue segment: %w", err)
+		}
+		if errors.Is(err, errPlaintextLine) {
+			plaintext++
+		}
+		var event Event
+		if err != nil || json.Unmarshal(line, &event) != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return events, fmt.Errorf("failed to read queue segment: %w", err)
+	}
+	return events, unencryptedErr(path, plaintext)
+}
+
+// flushSegments sends a queue's sealed segments oldest first, deleting each
+// once the API accepts it. It stops at the first failure so ordering is preserved.
+func (c *Client) flushSegments(ctx context.Context, q *diskQueue) error {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	segments, err := q.sealed()
+	if err != nil {
+		return err
+	}
+
+	var exportErrs error
+	for _, path := range segments {
+		if !q.claim(path) {
+			continue // discarded to make room
+		}
+		events, err := readSegment(path, q.cipher)
+		if err != nil && !errors.Is(err, ErrUnencrypted) {
+			q.release()
+			return errors.Join(exportErrs, err)
+		}
+		exportErrs = errors.Join(exportErrs, err)
+		if len(events) > 0 {
+			_, sendErr, exportErr := c.deliverBatches(ctx, events)
+			exportErrs = errors.Join(exportErrs, exportErr)
+			if sendErr != nil {
+				q.release()
+				return errors.Join(exportErrs, sendErr)
+			}
+		}
+		if err := q.remove(path); err != nil {
+			return errors.Join(exportErrs, fmt.Errorf("failed to remove queue segment: %w", err))
+		}
+	}
+	return exportErrs
+}
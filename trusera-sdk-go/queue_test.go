@@ -0,0 +1,258 @@
+package trusera
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyAPI is a Trusera API stand-in that can be switched between failing and accepting
+type flakyAPI struct {
+	server *httptest.Server
+	fail   atomic.Bool
+	mu     sync.Mutex
+	names  []string
+}
+
+func newFlakyAPI(t *testing.T) *flakyAPI {
+	a := &flakyAPI{}
+	a.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload struct {
+			Events []Event `json:"events"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		a.mu.Lock()
+		for _, e := range payload.Events {
+			a.names = append(a.names, e.Name)
+		}
+		a.mu.Unlock()
+	}))
+	t.Cleanup(a.server.Close)
+	return a
+}
+
+func (a *flakyAPI) received() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.names...)
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestPersistentQueueSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+
+	first := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir))
+	for _, name := range []string{"a", "b", "c"} {
+		first.Track(NewEvent(EventToolCall, name))
+	}
+	if err := first.Close(); err == nil {
+		t.Fatal("expected flush to fail while the API is down")
+	}
+
+	if len(segments(t, dir)) == 0 {
+		t.Fatal("expected events to remain on disk")
+	}
+
+	api.fail.Store(false)
+	second := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir))
+	defer second.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(api.received()) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected persisted events to be sent on startup, got %v", api.received())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if got := api.received(); got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("unexpected order: %v", got)
+	}
+
+	if err := second.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if left := segments(t, dir); len(left) != 0 {
+		t.Errorf("expected sent segments to be deleted, got %v", left)
+	}
+}
+
+func TestPersistentQueueRetriesInOrder(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+
+	client := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir))
+	defer client.Close()
+
+	api.fail.Store(true)
+	client.Track(NewEvent(EventToolCall, "first"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	client.Track(NewEvent(EventToolCall, "second"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+
+	api.fail.Store(false)
+	client.Track(NewEvent(EventToolCall, "third"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	got := api.received()
+	if len(got) != 3 || got[0] != "first" || got[1] != "second" || got[2] != "third" {
+		t.Errorf("expected retried events in order, got %v", got)
+	}
+
+	if dropped := client.Collector().Snapshot().EventsDropped; dropped != 0 {
+		t.Errorf("persisted events should not count as dropped, got %d", dropped)
+	}
+}
+
+func TestPersistentQueueSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	api := newFlakyAPI(t)
+
+	var mu sync.Mutex
+	dropped := map[string]DropReason{}
+	client := NewClient("test-key", WithBaseURL(api.server.URL), WithPersistentQueue(dir), WithMaxQueueBytes(3000),
+		WithDropHandler(func(e Event, reason DropReason) {
+			mu.Lock()
+			dropped[e.Name] = reason
+			mu.Unlock()
+		}))
+	defer client.Close()
+
+	// Each event takes about 1.3 KB, so two fit under the limit. Each Flush
+	// waits for a flush in progress, such as the one resuming the queue when
+	// the client starts, so no segment is still being sent at the checks.
+	padding := strings.Repeat("x", 1000)
+	api.fail.Store(true)
+	for _, name := range []string{"first", "second", "third"} {
+		client.Track(NewEvent(EventToolCall, name).WithPayload("padding", padding))
+		if err := client.Flush(); err == nil {
+			t.Fatal("expected flush error")
+		}
+	}
+	if n := len(segments(t, dir)); n != 2 {
+		t.Errorf("expected the oldest segment discarded, got %d segments", n)
+	}
+
+	client.Track(NewEvent(EventToolCall, "huge").WithPayload("padding", strings.Repeat(padding, 4)))
+	api.fail.Store(false)
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	got := api.received()
+	if len(got) != 2 || got[0] != "second" || got[1] != "third" {
+		t.Errorf("expected the events that fit, got %v", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) != 2 || dropped["first"] != DropReasonOverflow || dropped["huge"] != DropReasonOverflow {
+		t.Errorf("expected the discarded and oversized events dropped as overflow, got %v", dropped)
+	}
+}
+
+func TestDiskQueueDiscardsSegmentBeingSent(t *testing.T) {
+	q, err := openDiskQueue(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("openDiskQueue failed: %v", err)
+	}
+	var dropped []string
+	q.maxBytes = 3000
+	q.drop = func(events ...Event) {
+		for _, e := range events {
+			dropped = append(dropped, e.Name)
+		}
+	}
+
+	padding := strings.Repeat("x", 1000)
+	for _, name := range []string{"first", "second"} {
+		if err := q.append(NewEvent(EventToolCall, name).WithPayload("padding", padding)); err != nil {
+			t.Fatal(err)
+		}
+		q.seal()
+	}
+	sealed, _ := q.sealed()
+	if !q.claim(sealed[0]) {
+		t.Fatal("expected to claim the oldest segment")
+	}
+
+	// The oldest segment is being sent, so it is the one marked for discard
+	if err := q.append(NewEvent(EventToolCall, "third").WithPayload("padding", padding)); err != nil {
+		t.Fatalf("expected room made for the event, got %v", err)
+	}
+	if len(dropped) != 0 {
+		t.Fatalf("expected the segment kept until its send ends, got %v dropped", dropped)
+	}
+	q.release()
+	if len(dropped) != 1 || dropped[0] != "first" {
+		t.Errorf("expected the oldest segment dropped once its send failed, got %v", dropped)
+	}
+	if left, _ := q.sealed(); len(left) != 1 || left[0] != sealed[1] {
+		t.Errorf("expected the newer segment kept, got %v", left)
+	}
+	q.close()
+}
+
+func TestReadSegmentSkipsTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "00000000000000000001"+segmentSuffix)
+	good, _ := json.Marshal(NewEvent(EventAPICall, "ok"))
+	data := append(good, '\n')
+	data = append(data, []byte(`{"id":"torn","na`)...)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := readSegment(path, nil)
+	if err != nil {
+		t.Fatalf("readSegment failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Name != "ok" {
+		t.Errorf("expected only the complete event, got %v", events)
+	}
+}
+
+func TestOpenDiskQueueContinuesSequence(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "00000000000000000007"+segmentSuffix), nil, 0o600)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600)
+
+	q, err := openDiskQueue(dir, nil)
+	if err != nil {
+		t.Fatalf("openDiskQueue failed: %v", err)
+	}
+	if err := q.append(NewEvent(EventAPICall, "x")); err != nil {
+		t.Fatal(err)
+	}
+	q.close()
+
+	if _, err := os.Stat(filepath.Join(dir, "00000000000000000008"+segmentSuffix)); err != nil {
+		t.Errorf("expected next segment to follow the existing sequence: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithMaxEventsPerSecond caps the rate at which events are buffered, so a
+// runaway agent cannot blow through the project's ingestion quota. Up to n
+// events are admitted at once, refilling at n per second. Events over the
+// rate follow the overflow policy: DropNewest drops them, DropOldest drops
+// the oldest buffered event to make room, SpillToDisk spills them and
+// BlockCaller waits for the rate to allow them. Dropped events are counted
+// with reason rate_limited. The persistent queue drops events over the rate.
+//
+// TrackSync always waits for the rate, since its callers need the event on
+// record.
+func WithMaxEventsPerSecond(n float64) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.eventRate = newRateLimit(n, n)
+		}
+	}
+}
+
+// WithMaxFlushBandwidth caps the bytes per second the client sends to the
+// API, measured after compression, so flushing a large backlog does not
+// saturate the network. A request that would exceed the bandwidth waits
+// before it is sent; a request larger than a second's worth is sent and
+// the requests after it wait for it to be paid off.
+func WithMaxFlushBandwidth(bytesPerSecond int) Option {
+	return func(c *Client) {
+		if bytesPerSecond > 0 {
+			c.bandwidth = newRateLimit(float64(bytesPerSecond), float64(bytesPerSecond))
+		}
+	}
+}
+
+// rateLimit is a token bucket that can be shared between goroutines
+type rateLimit struct {
+	mu     sync.Mutex
+	bucket tokenBucket
+}
+
+func newRateLimit(burst, perSecond float64) *rateLimit {
+	return &rateLimit{bucket: tokenBucket{capacity: burst, tokens: burst, perSecond: perSecond}}
+}
+
+// allow takes a token if one is available
+func (r *rateLimit) allow(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.bucket.take(now)
+}
+
+// reserve takes n tokens, going into debt if there are too few, and returns
+// how long the caller must wait for the debt to be paid off
+func (r *rateLimit) reserve(now time.Time, n float64) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bucket.refill(now)
+	r.bucket.tokens -= n
+	if r.bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.bucket.tokens / r.bucket.perSecond * float64(time.Second))
+}
+
+// waitForEvent waits until the event rate admits another event
+func (c *Client) waitForEvent(ctx context.Context) error {
+	if c.eventRate == nil {
+		return nil
+	}
+	if d := c.eventRate.reserve(c.now(), 1); d > 0 {
+		return sleepCtx(ctx, d)
+	}
+	return nil
+}
+
+// rateLimited reports whether an event about to be buffered exceeds the
+// event rate and must be handled by the overflow policy. BlockCaller waits
+// for the rate instead, and only fails when ctx ends first.
+func (c *Client) rateLimited(ctx context.Context, event Event) (bool, error) {
+	if c.eventRate == nil {
+		return false, nil
+	}
+	if c.overflow == BlockCaller && c.queue == nil {
+		if err := c.waitForEvent(ctx); err != nil {
+			c.drop(DropReasonRateLimited, event)
+			return false, err
+		}
+		return false, nil
+	}
+	return !c.eventRate.allow(c.now()), nil
+}
+
+// rateLimitedLocked applies the overflow policy to an event over the event
+// rate. It must be called with c.mu held and reports whether the event
+// should still be appended to the buffer.
+func (c *Client) rateLimitedLocked(event Event) bool {
+	switch {
+	case c.queue != nil:
+		// The queue's events are already on disk, so none can make room
+
+	case c.overflow == SpillToDisk && c.spill != nil:
+		err := c.spill.append(event)
+		if err == nil {
+			c.metrics.observeSpill(1)
+			return false
+		}
+		if c.queueErr == nil && !errors.Is(err, errQueueFull) {
+			c.queueErr = err
+		}
+
+	case c.overflow == DropOldest && len(c.events) > 0:
+		c.drop(DropReasonRateLimited, c.events[0])
+		c.events[0] = Event{}
+		c.events = c.events[1:]
+		return true
+	}
+	c.drop(DropReasonRateLimited, event)
+	return false
+}
+
+// throttle waits until the flush bandwidth allows sending n bytes
+func (c *Client) throttle(ctx context.Context, n int) error {
+	if c.bandwidth == nil {
+		return nil
+	}
+	if d := c.bandwidth.reserve(c.now(), float64(n)); d > 0 {
+		return sleepCtx(ctx, d)
+	}
+	return nil
+}
+
+// RateLimit caps how often the calls matching a Rule may be made, such as
+// 10 calls to an email API per minute. Calls over the limit are blocked, or
+// with Delay wait until the limit allows them; either way an EventRateLimit
+// is tracked.
+type RateLimit struct {
+	Calls int      `json:"calls"`           // Calls allowed per window, all at once if need be
+	Per   Duration `json:"per"`             // The window, e.g. "1m"
+	Delay bool     `json:"delay,omitempty"` // Wait for the limit instead of blocking
+}
+
+// limitCalls applies the rate limit of the rule a call matched. A call over
+// a blocking limit comes back blocked in block mode, whatever the rule's
+// action. Delayed calls wait until ctx ends, and are blocked if it does.
+// Limits are shared by every interceptor on the client and keyed by rule,
+// so a changed limit starts afresh.
+func (t *interceptingTransport) limitCalls(ctx context.Context, target string, v verdict) verdict {
+	if v.rule == nil || v.rule.RateLimit == nil || v.excluded || (v.blocked && v.mode.rejects()) {
+		return v
+	}
+	rl := *v.rule.RateLimit
+	if rl.Calls <= 0 || rl.Per <= 0 {
+		return v
+	}
+	limit := t.client.callLimit(v.ruleID, rl)
+
+	event := NewEvent(EventRateLimit, v.ruleID).
+		WithPayload("rule_id", v.ruleID).
+		WithPayload("target", target).
+		WithPayload("calls", rl.Calls).
+		WithPayload("per", time.Duration(rl.Per).String())
+	switch {
+	case rl.Delay:
+		wait := limit.reserve(t.client.now(), 1)
+		if wait <= 0 {
+			return v
+		}
+		event = event.WithPayload("action", "delayed").WithPayload("delay_ms", wait.Milliseconds())
+		if err := sleepCtx(ctx, wait); err == nil {
+			t.client.Track(t.client.correlate(ctx, event))
+			return v
+		}
+		event = event.WithPayload("action", "blocked").WithPayload("error", ctx.Err().Error())
+	case limit.allow(t.client.now()):
+		return v
+	default:
+		event = event.WithPayload("action", "blocked")
+	}
+	t.client.Track(t.client.correlate(ctx, event))
+
+	v.blocked, v.limited, v.reason = true, true, BlockReasonRateLimit
+	if !v.paused && !v.learning && !v.shadow {
+		v.mode = ModeBlock
+	}
+	return v
+}
+
+// callLimit returns the client's bucket for a rule's rate limit
+func (c *Client) callLimit(ruleID string, rl RateLimit) *rateLimit {
+	key := fmt.Sprintf("%s/%d/%s", ruleID, rl.Calls, time.Duration(rl.Per))
+	c.callLimitsMu.Lock()
+	defer c.callLimitsMu.Unlock()
+	limit := c.callLimits[key]
+	if limit == nil {
+		limit = newRateLimit(float64(rl.Calls), float64(rl.Calls)/time.Duration(rl.Per).Seconds())
+		if c.callLimits == nil {
+			c.callLimits = map[string]*rateLimit{}
+		}
+		c.callLimits[key] = limit
+	}
+	return limit
+}
@@ -0,0 +1,211 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a clock that only moves when advanced
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (m *manualClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *manualClock) advance(d time.Duration) {
+	m.mu.Lock()
+	m.now = m.now.Add(d)
+	m.mu.Unlock()
+}
+
+func TestMaxEventsPerSecondDropsNewest(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	var reasons []DropReason
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithClock(clock.Now), WithMaxEventsPerSecond(2), WithOverflowPolicy(DropNewest),
+		WithDropHandler(func(e Event, r DropReason) { reasons = append(reasons, r) }))
+	defer client.Close()
+
+	for i := 0; i < 5; i++ {
+		client.Track(NewEvent(EventToolCall, fmt.Sprintf("tool-%d", i)))
+	}
+	if n := len(trackedEvents(client, EventToolCall)); n != 2 {
+		t.Errorf("expected 2 events admitted, got %d", n)
+	}
+	if n := client.Stats().EventsDroppedRateLimited; n != 3 || len(reasons) != 3 || reasons[0] != DropReasonRateLimited {
+		t.Errorf("expected 3 events dropped as rate limited, got %d %v", n, reasons)
+	}
+
+	clock.advance(time.Second)
+	client.Track(NewEvent(EventToolCall, "later"))
+	if _, ok := trackedEvent(client, "later"); !ok {
+		t.Error("expected the rate to refill after a second")
+	}
+}
+
+func TestMaxEventsPerSecondDropsOldest(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithClock(clock.Now), WithMaxEventsPerSecond(2))
+	defer client.Close()
+
+	for _, name := range []string{"a", "b", "c"} {
+		client.Track(NewEvent(EventToolCall, name))
+	}
+	var names []string
+	for _, e := range trackedEvents(client, EventToolCall) {
+		names = append(names, e.Name)
+	}
+	if fmt.Sprint(names) != "[b c]" {
+		t.Errorf("expected the oldest event dropped, got %v", names)
+	}
+}
+
+func TestMaxEventsPerSecondBlocksCaller(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithMaxEventsPerSecond(20), WithOverflowPolicy(BlockCaller))
+	defer client.Close()
+
+	for i := 0; i < 20; i++ {
+		client.Track(NewEvent(EventToolCall, "burst"))
+	}
+	start := time.Now()
+	client.Track(NewEvent(EventToolCall, "waited"))
+	if waited := time.Since(start); waited < 25*time.Millisecond {
+		t.Errorf("expected the caller to wait for the rate, waited %v", waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	for i := 0; i < 5; i++ {
+		client.TrackCtx(ctx, NewEvent(EventToolCall, "burst"))
+	}
+	if err := client.TrackCtx(ctx, NewEvent(EventToolCall, "late")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+	if client.Stats().EventsDroppedRateLimited == 0 {
+		t.Error("expected the abandoned event counted as rate limited")
+	}
+}
+
+func TestMaxFlushBandwidth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour), WithMaxFlushBandwidth(10000))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "large").WithPayload("data", strings.Repeat("x", 10500)))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("expected a request over the bandwidth sent at once: %v", err)
+	}
+
+	client.Track(NewEvent(EventToolCall, "next"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.FlushCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the next request to wait for the bandwidth, got %v", err)
+	}
+}
+
+func TestRateLimitReserve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newRateLimit(100, 100)
+	if d := r.reserve(now, 60); d != 0 {
+		t.Errorf("expected no wait within the burst, got %v", d)
+	}
+	if d := r.reserve(now, 90); d != 500*time.Millisecond {
+		t.Errorf("expected a 500ms wait to pay off 50 tokens, got %v", d)
+	}
+	if d := r.reserve(now.Add(time.Second), 10); d != 0 {
+		t.Errorf("expected the debt paid off after a second, got %v", d)
+	}
+}
+
+func TestRuleRateLimitBlocks(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules: []Rule{{ID: "email", Match: "/send", Action: RuleAllow,
+			RateLimit: &RateLimit{Calls: 2, Per: Duration(time.Minute)}}},
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Get(backend.URL + "/send")
+		if err != nil {
+			t.Fatalf("call %d within the limit failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := httpClient.Get(backend.URL + "/send"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected the call over the limit blocked, got %v", err)
+	}
+	if resp, err := httpClient.Get(backend.URL + "/other"); err != nil {
+		t.Errorf("expected calls matching no limit to pass: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	limits := trackedEvents(client, EventRateLimit)
+	if len(limits) != 1 || limits[0].Payload["action"] != "blocked" || limits[0].Payload["rule_id"] != "email" {
+		t.Fatalf("expected one blocked rate limit event, got %v", limits)
+	}
+	blocked, ok := trackedEventWhere(client, func(e Event) bool { return e.Payload["rate_limited"] == true })
+	if !ok || blocked.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("expected the blocked call recorded as rate limited, got %v", blocked.Payload)
+	}
+}
+
+func TestRuleRateLimitDelays(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Rules: []Rule{{Match: backend.URL, Action: RuleAllow,
+			RateLimit: &RateLimit{Calls: 20, Per: Duration(time.Second), Delay: true}}},
+	})
+
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		resp, err := httpClient.Get(backend.URL)
+		if err != nil {
+			t.Fatalf("expected delayed calls to go through: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if waited := time.Since(start); waited < 25*time.Millisecond {
+		t.Errorf("expected the call over the limit to wait, took %v", waited)
+	}
+	limits := trackedEvents(client, EventRateLimit)
+	if len(limits) != 1 || limits[0].Payload["action"] != "delayed" {
+		t.Errorf("expected one delayed rate limit event, got %v", limits)
+	}
+}
+
+func TestRateLimitJSON(t *testing.T) {
+	var rule Rule
+	if err := json.Unmarshal([]byte(`{"match":"/send","action":"allow","rate_limit":{"calls":10,"per":"1m"}}`), &rule); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if rule.RateLimit == nil || rule.RateLimit.Calls != 10 || time.Duration(rule.RateLimit.Per) != time.Minute {
+		t.Errorf("unexpected rate limit: %+v", rule.RateLimit)
+	}
+}
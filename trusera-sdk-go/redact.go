@@ -0,0 +1,216 @@
+package trusera
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector finds one kind of sensitive value in text
+type Detector struct {
+	Name    string                  // Used in the replacement, e.g. [REDACTED:email]
+	Pattern *regexp.Regexp          // Candidate matches
+	Valid   func(match string) bool // Optional check that rejects false positives
+}
+
+// Built-in detectors. They run in this order, so card numbers are claimed
+// before the phone pattern can match part of them.
+var (
+	DetectCreditCard = Detector{
+		Name:    "credit_card",
+		Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Valid:   luhnValid,
+	}
+	DetectSSN = Detector{
+		Name:    "ssn",
+		Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		Valid:   ssnValid,
+	}
+	DetectEmail = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	}
+	DetectPhone = Detector{
+		Name:    "phone",
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{3}\)\s?|\b\d{3}[\s.\-])\d{3}[\s.\-]\d{4}\b`),
+	}
+)
+
+// DefaultDetectors returns the built-in detectors
+func DefaultDetectors() []Detector {
+	return []Detector{DetectCreditCard, DetectSSN, DetectEmail, DetectPhone}
+}
+
+// PatternDetector builds a detector from a custom regular expression
+func PatternDetector(name string, re *regexp.Regexp) Detector {
+	return Detector{Name: name, Pattern: re}
+}
+
+// Redactor scrubs sensitive values from events
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor creates a redactor using the given detectors, or
+// DefaultDetectors when none are given
+func NewRedactor(detectors ...Detector) *Redactor {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return &Redactor{detectors: detectors}
+}
+
+// WithRedaction scrubs every event's payload and metadata with r before it
+// is buffered, so sensitive values never reach the queue, the disk or the
+// network. This covers request bodies and streamed responses captured by the
+// interceptors as well. Redacted events carry metadata redacted_types
+// listing the detectors that matched.
+func WithRedaction(r *Redactor) Option {
+	return func(c *Client) {
+		// Redaction runs ahead of every other hook so none sees raw values
+		c.trackHooks = append([]EventHook{r.hook}, c.trackHooks...)
+	}
+}
+
+func (r *Redactor) hook(e *Event) (*Event, error) {
+	redacted := r.RedactEvent(*e)
+	return &redacted, nil
+}
+
+// RedactString replaces every detected value in s
+func (r *Redactor) RedactString(s string) string {
+	return r.redactString(s, nil)
+}
+
+func (r *Redactor) redactString(s string, found map[string]bool) string {
+	for _, d := range r.detectors {
+		s = d.Pattern.ReplaceAllStringFunc(s, func(m string) string {
+			if d.Valid != nil && !d.Valid(m) {
+				return m
+			}
+			if found != nil {
+				found[d.Name] = true
+			}
+			return "[REDACTED:" + d.Name + "]"
+		})
+	}
+	return s
+}
+
+// Redact returns a copy of v with every string scrubbed. Maps and slices are
+// walked recursively; other composite values are converted to their JSON form
+// first, as they would be when sent.
+func (r *Redactor) Redact(v any) any {
+	return r.redact(v, nil)
+}
+
+func (r *Redactor) redact(v any, found map[string]bool) any {
+	switch val := v.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64, json.Number:
+		return v
+	case string:
+		return r.redactString(val, found)
+	case []string:
+		out := make([]string, len(val))
+		for i, s := range val {
+			out[i] = r.redactString(s, found)
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, s := range val {
+			out[k] = r.redactString(s, found)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = r.redact(item, found)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = r.redact(item, found)
+		}
+		return out
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var generic any
+		if json.Unmarshal(b, &generic) != nil {
+			return v
+		}
+		return r.redact(generic, found)
+	}
+	return v
+}
+
+// RedactEvent returns a copy of an event with its payload and metadata scrubbed
+func (r *Redactor) RedactEvent(e Event) Event {
+	e, _ = r.redactEvent(e, "redacted_types")
+	return e
+}
+
+// redactEvent scrubs an event, recording the detectors that matched under
+// the metadata key typesKey, and returns them
+func (r *Redactor) redactEvent(e Event, typesKey string) (Event, []string) {
+	found := make(map[string]bool)
+	if e.Payload != nil {
+		e.Payload = r.redact(e.Payload, found).(map[string]any)
+	}
+	if e.Metadata != nil {
+		e.Metadata = r.redact(e.Metadata, found).(map[string]any)
+	}
+
+	if len(found) == 0 {
+		return e, nil
+	}
+	types := make([]string, 0, len(found))
+	for name := range found {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return e.WithMetadata(typesKey, types), types
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ssnValid rejects numbers the SSA never issues
+func ssnValid(s string) bool {
+	area, group, serial := s[:3], s[4:6], s[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
@@ -0,0 +1,119 @@
+package trusera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	r := NewRedactor()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"mail jane.doe@example.com now", "mail [REDACTED:email] now"},
+		{"call (415) 555-0123 or +1 415-555-0199", "call [REDACTED:phone] or [REDACTED:phone]"},
+		{"ssn 123-45-6789", "ssn [REDACTED:ssn]"},
+		{"ssn 000-12-3456 is invalid", "ssn 000-12-3456 is invalid"},
+		{"card 4111 1111 1111 1111 ok", "card [REDACTED:credit_card] ok"},
+		{"order 4111111111111112", "order 4111111111111112"}, // fails Luhn
+		{"nothing to see", "nothing to see"},
+	}
+	for _, tt := range tests {
+		if got := r.RedactString(tt.in); got != tt.want {
+			t.Errorf("RedactString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCustomDetector(t *testing.T) {
+	r := NewRedactor(PatternDetector("employee_id", regexp.MustCompile(`EMP-\d{6}`)))
+	if got := r.RedactString("ticket for EMP-004211, bob@example.com"); got != "ticket for [REDACTED:employee_id], bob@example.com" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
+
+func TestRedactEventNested(t *testing.T) {
+	type contact struct {
+		Email string `json:"email"`
+	}
+
+	original := NewEvent(EventLLMInvoke, "gpt-4").
+		WithPayload("prompt", "email me at a@b.io").
+		WithPayload("messages", []any{map[string]any{"content": "my ssn is 123-45-6789"}}).
+		WithPayload("contact", contact{Email: "c@d.io"}).
+		WithPayload("tokens", 42).
+		WithMetadata("user", "e@f.io")
+
+	redacted := NewRedactor().RedactEvent(original)
+
+	if redacted.Payload["prompt"] != "email me at [REDACTED:email]" {
+		t.Errorf("prompt not redacted: %v", redacted.Payload["prompt"])
+	}
+	msg := redacted.Payload["messages"].([]any)[0].(map[string]any)
+	if msg["content"] != "my ssn is [REDACTED:ssn]" {
+		t.Errorf("nested message not redacted: %v", msg)
+	}
+	if c := redacted.Payload["contact"].(map[string]any); c["email"] != "[REDACTED:email]" {
+		t.Errorf("struct payload not redacted: %v", c)
+	}
+	if redacted.Payload["tokens"] != 42 {
+		t.Errorf("numbers should pass through, got %v", redacted.Payload["tokens"])
+	}
+	if redacted.Metadata["user"] != "[REDACTED:email]" {
+		t.Errorf("metadata not redacted: %v", redacted.Metadata["user"])
+	}
+	if types := redacted.Metadata["redacted_types"].([]string); strings.Join(types, ",") != "email,ssn" {
+		t.Errorf("unexpected redacted_types %v", types)
+	}
+
+	if original.Payload["prompt"] != "email me at a@b.io" {
+		t.Error("RedactEvent must not modify the original event")
+	}
+}
+
+func TestWithRedactionScrubsInterceptedBodies(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	var hookSaw string
+	client := NewClient("test-key",
+		WithEventHook(func(e *Event) (*Event, error) {
+			hookSaw, _ = e.Payload["body_snippet"].(string)
+			return e, nil
+		}),
+		WithRedaction(NewRedactor()))
+	defer client.Close()
+
+	httpClient := WrapHTTPClient(nil, client, InterceptorOptions{})
+	resp, err := httpClient.Post(backend.URL, "application/json", strings.NewReader(`{"email":"jane@example.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if strings.Contains(hookSaw, "jane@example.com") {
+		t.Errorf("later hooks should only see redacted values, got %q", hookSaw)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	for _, e := range client.events {
+		if body, _ := e.Payload["body_snippet"].(string); strings.Contains(body, "jane@example.com") {
+			t.Errorf("captured body was not redacted: %q", body)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	if !luhnValid("5500-0000-0000-0004") {
+		t.Error("expected valid card number")
+	}
+	if luhnValid("1234") {
+		t.Error("short numbers are not cards")
+	}
+}
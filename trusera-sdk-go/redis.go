@@ -0,0 +1,431 @@
+package trusera
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisOptions configures a RedisHook
+type RedisOptions struct {
+	Enforcement EnforcementMode
+
+	// Rules and patterns are matched against "<operation> <key>" for every
+	// key a command touches, where the operation is read, write, delete or
+	// admin: "read secrets:" blocks reads of keys starting with "secrets:",
+	// "secrets:" blocks all access to them. A pattern with "*" is a glob
+	// matched against the whole key instead, e.g. "read user:*:token", and
+	// "re:" patterns match the whole "<operation> <key>". Keyless commands
+	// are matched against the operation and command, e.g. "admin flushall".
+	// The source keys of commands that store into another key, such as
+	// SUNIONSTORE, are matched as reads.
+	Rules           []Rule
+	BlockPatterns   []string
+	ExcludePatterns []string
+
+	// CaptureKeys includes the keys themselves in events; by default only
+	// their patterns are recorded (e.g. "user:*:profile")
+	CaptureKeys bool
+}
+
+// RedisCmd is the part of go-redis's Cmder that RedisHook uses
+type RedisCmd interface {
+	Name() string
+	Args() []any
+	Err() error
+	SetErr(error)
+}
+
+// RedisHook implements go-redis's Hook, tracking every command as an
+// EventDataAccess with the operation, the key patterns it touched and its
+// duration, and rejecting commands on blocked keys before they are sent.
+//
+// The SDK does not import go-redis; instantiate it with go-redis's types so
+// that the result satisfies redis.Hook:
+//
+//	rdb.AddHook(trusera.NewRedisHook[redis.Cmder, redis.DialHook, redis.ProcessHook,
+//		redis.ProcessPipelineHook](truseraClient, trusera.RedisOptions{
+//		Enforcement:   trusera.ModeBlock,
+//		BlockPatterns: []string{"read secrets:"},
+//	}))
+//
+// A pipeline or transaction containing a blocked command is rejected as a
+// whole in block mode.
+type RedisHook[C RedisCmd, D ~func(ctx context.Context, network, addr string) (net.Conn, error), P ~func(ctx context.Context, cmd C) error, PP ~func(ctx context.Context, cmds []C) error] struct {
+	t    *interceptingTransport
+	opts RedisOptions
+}
+
+// NewRedisHook creates a hook that tracks to client
+func NewRedisHook[C RedisCmd, D ~func(ctx context.Context, network, addr string) (net.Conn, error), P ~func(ctx context.Context, cmd C) error, PP ~func(ctx context.Context, cmds []C) error](client *Client, opts RedisOptions) *RedisHook[C, D, P, PP] {
+	rules := make([]Rule, len(opts.Rules))
+	for i, rule := range opts.Rules {
+		rule.Match = redisPattern(rule.Match)
+		rules[i] = rule
+	}
+	return &RedisHook[C, D, P, PP]{
+		t: &interceptingTransport{client: client, opts: InterceptorOptions{
+			Enforcement:     opts.Enforcement,
+			Rules:           rules,
+			BlockPatterns:   redisPatterns(opts.BlockPatterns),
+			ExcludePatterns: redisPatterns(opts.ExcludePatterns),
+		}},
+		opts: opts,
+	}
+}
+
+// DialHook leaves connections untouched
+func (h *RedisHook[C, D, P, PP]) DialHook(next D) D {
+	return next
+}
+
+// ProcessHook checks and tracks single commands
+func (h *RedisHook[C, D, P, PP]) ProcessHook(next P) P {
+	return P(func(ctx context.Context, cmd C) error {
+		call, err := h.begin(ctx, cmd, false)
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		start := time.Now()
+		err = next(ctx, cmd)
+		call.end(time.Since(start), cmd.Err())
+		return err
+	})
+}
+
+// ProcessPipelineHook checks and tracks pipelines and transactions
+func (h *RedisHook[C, D, P, PP]) ProcessPipelineHook(next PP) PP {
+	return PP(func(ctx context.Context, cmds []C) error {
+		calls := make([]*redisCall, 0, len(cmds))
+		var blockErr error
+		for _, cmd := range cmds {
+			call, err := h.begin(ctx, cmd, true)
+			if err != nil {
+				cmd.SetErr(err)
+				blockErr = err
+			}
+			calls = append(calls, call)
+		}
+		if blockErr != nil {
+			return blockErr
+		}
+
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start)
+		for i, call := range calls {
+			call.end(elapsed, cmds[i].Err())
+		}
+		return err
+	})
+}
+
+// redisCall is a command in flight; nil for excluded commands
+type redisCall struct {
+	t     *interceptingTransport
+	ctx   context.Context
+	event Event
+}
+
+// begin evaluates a command, tracking and returning an error when it is
+// rejected
+func (h *RedisHook[C, D, P, PP]) begin(ctx context.Context, cmd C, pipeline bool) (*redisCall, error) {
+	name := strings.ToLower(cmd.Name())
+	op := redisOperation(name)
+	keys := redisKeys(name, cmd.Args())
+
+	targets := make([]string, 0, len(keys))
+	dest := redisDestination(name)
+	for i, key := range keys {
+		if dest >= 0 && i != dest {
+			targets = append(targets, "read "+key)
+		} else {
+			targets = append(targets, op+" "+key)
+		}
+	}
+	if len(targets) == 0 {
+		targets = append(targets, op+" "+name)
+	}
+
+	// The first blocked key decides; a command is excluded only when all of
+	// its keys are
+	var v verdict
+	var decided string
+	excluded := true
+	for i, target := range targets {
+		kv := h.t.evaluate(target)
+		if !kv.excluded {
+			excluded = false
+		}
+		if i == 0 || kv.blocked && !v.blocked {
+			v, decided = kv, target
+		}
+	}
+	if excluded {
+		return nil, nil
+	}
+	v.excluded = false
+	v = h.t.canary(ctx, decided, v)
+
+	event := NewEvent(EventDataAccess, "redis "+strings.ToUpper(name)).
+		WithPayload("protocol", "redis").
+		WithPayload("command", name).
+		WithPayload("operation", op).
+		WithPayload("key_count", len(keys)).
+		WithPayload("blocked", v.blocked)
+	if len(keys) > 0 {
+		event = event.WithPayload("key_patterns", keyPatterns(keys))
+		if h.opts.CaptureKeys {
+			event = event.WithPayload("keys", keys)
+		}
+	}
+	if pipeline {
+		event = event.WithPayload("pipeline", true)
+	}
+	event = v.describe(event)
+
+	h.t.logDecision(ctx, "redis", "", decided, v, v.blocked)
+	if v.blocked {
+		event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+		switch v.mode {
+		case ModeBlock, ModeAllowList:
+			h.t.client.Track(h.t.client.enrich(ctx, event))
+			return nil, v.blockedError(decided)
+		case ModeWarn:
+			event = event.WithMetadata("warning", "key matches block pattern but allowed in warn mode")
+		}
+	} else {
+		event = event.WithPayload("enforcement_action", "allowed")
+	}
+	return &redisCall{t: h.t, ctx: ctx, event: event}, nil
+}
+
+// end tracks a finished command
+func (c *redisCall) end(elapsed time.Duration, err error) {
+	if c == nil {
+		return
+	}
+	event := c.event.WithPayload("duration_ms", float64(elapsed.Microseconds())/1000)
+	switch {
+	case err == nil:
+	case err.Error() == "redis: nil":
+		// go-redis reports a missing key as an error
+		event = event.WithPayload("hit", false)
+	default:
+		event = event.WithPayload("error", err.Error())
+	}
+	c.t.client.Track(c.t.client.enrich(c.ctx, event))
+}
+
+// redisWrites, redisDeletes and redisAdmin classify commands; everything
+// else is a read
+var (
+	redisWrites = wordSet("set setex setnx psetex mset msetnx getset getex append setrange setbit incr incrby incrbyfloat decr decrby " +
+		"hset hsetnx hmset hincrby hincrbyfloat lpush lpushx rpush rpushx lpop rpop lset lrem ltrim linsert lmove rpoplpush blpop brpop " +
+		"sadd srem spop smove zadd zrem zincrby zpopmin zpopmax zremrangebyscore zremrangebyrank expire pexpire expireat pexpireat " +
+		"persist rename renamenx xadd xtrim xack pfadd pfmerge copy restore geoadd sunionstore sinterstore sdiffstore zunionstore " +
+		"zinterstore zdiffstore zrangestore geosearchstore bitop bitfield blmove brpoplpush bzpopmin bzpopmax lmpop blmpop zmpop bzmpop")
+	redisDeletes = wordSet("del unlink getdel hdel xdel")
+	redisAdmin   = wordSet("flushdb flushall config shutdown debug save bgsave bgrewriteaof replicaof slaveof acl module script function migrate keys")
+
+	// redisFirstKey are commands whose first argument is their only key
+	redisFirstKey = wordSet("get set setex setnx psetex getset getex getdel append setrange getrange substr strlen setbit getbit " +
+		"bitcount bitpos bitfield bitfield_ro incr incrby incrbyfloat decr decrby " +
+		"hget hset hsetnx hmset hmget hdel hexists hgetall hkeys hvals hlen hstrlen hincrby hincrbyfloat hscan hrandfield " +
+		"lpush lpushx rpush rpushx lpop rpop lset lrem ltrim linsert lindex llen lrange lpos " +
+		"sadd srem spop smembers sismember smismember scard srandmember sscan " +
+		"zadd zrem zincrby zpopmin zpopmax zremrangebyscore zremrangebyrank zremrangebylex zscore zmscore zrank zrevrank zcard " +
+		"zcount zlexcount zrange zrangebyscore zrangebylex zrevrange zrevrangebyscore zrevrangebylex zscan zrandmember " +
+		"expire pexpire expireat pexpireat expiretime pexpiretime persist ttl pttl type dump restore " +
+		"xadd xtrim xack xdel xlen xrange xrevrange xclaim xautoclaim xpending xsetid " +
+		"pfadd geoadd geopos geodist geohash georadius_ro georadiusbymember_ro geosearch sort_ro")
+	// redisAllKeys are commands whose arguments are all keys
+	redisAllKeys = wordSet("del unlink exists touch mget watch sinter sunion sdiff pfcount pfmerge sinterstore sunionstore sdiffstore")
+	// redisTwoKeys are commands whose first two arguments are keys
+	redisTwoKeys = wordSet("rename renamenx copy smove lmove rpoplpush blmove brpoplpush zrangestore geosearchstore lcs")
+	// redisTimeoutLast are commands taking keys followed by a timeout
+	redisTimeoutLast = wordSet("blpop brpop bzpopmin bzpopmax")
+	// redisSubcommandKey are commands whose key follows a subcommand
+	redisSubcommandKey = wordSet("object memory xinfo xgroup")
+	// redisKeyValuePairs are commands taking alternating keys and values
+	redisKeyValuePairs = wordSet("mset msetnx")
+	// redisNumKeys are commands with a numkeys argument at this position,
+	// followed by that many keys
+	redisNumKeys = map[string]int{
+		"eval": 1, "evalsha": 1, "eval_ro": 1, "evalsha_ro": 1, "fcall": 1, "fcall_ro": 1,
+		"zunion": 0, "zinter": 0, "zdiff": 0, "zintercard": 0, "sintercard": 0, "lmpop": 0, "zmpop": 0,
+		"blmpop": 1, "bzmpop": 1, "zunionstore": 1, "zinterstore": 1, "zdiffstore": 1,
+	}
+	// redisStores are commands that store into their first key what they
+	// read from the others
+	redisStores = wordSet("sunionstore sinterstore sdiffstore zunionstore zinterstore zdiffstore zrangestore geosearchstore pfmerge bitop")
+	// redisKeyless are commands that take no key
+	redisKeyless = wordSet("ping echo info select auth hello client config dbsize flushdb flushall time quit multi exec discard " +
+		"script function cluster command publish subscribe psubscribe unsubscribe punsubscribe save bgsave shutdown acl module debug " +
+		"replicaof slaveof keys scan randomkey role lastsave monitor")
+)
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// redisOperation classifies a command as read, write, delete or admin
+func redisOperation(name string) string {
+	switch {
+	case redisDeletes[name]:
+		return "delete"
+	case redisWrites[name]:
+		return "write"
+	case redisAdmin[name]:
+		return "admin"
+	default:
+		return "read"
+	}
+}
+
+// redisKeys returns the keys a command touches. All the arguments of
+// commands it does not know, or cannot parse, count as keys, so that a rule
+// on a key cannot be bypassed through them.
+func redisKeys(name string, args []any) []string {
+	if len(args) < 2 || redisKeyless[name] {
+		return nil
+	}
+	rest := args[1:]
+
+	keys := rest
+	switch pos, numKeys := redisNumKeys[name]; {
+	case redisFirstKey[name]:
+		keys = rest[:1]
+	case redisAllKeys[name]:
+	case redisTwoKeys[name]:
+		keys = rest[:min(2, len(rest))]
+	case redisTimeoutLast[name]:
+		if len(rest) > 1 {
+			keys = rest[:len(rest)-1]
+		}
+	case redisSubcommandKey[name]:
+		keys = rest[1:min(2, len(rest))]
+	case redisKeyValuePairs[name]:
+		keys = nil
+		for i := 0; i < len(rest); i += 2 {
+			keys = append(keys, rest[i])
+		}
+	case numKeys:
+		// [dest | script | timeout] numkeys key [key ...] arg [arg ...]
+		if pos >= len(rest) {
+			break
+		}
+		n, err := strconv.Atoi(fmt.Sprint(rest[pos]))
+		if err != nil || n < 0 || n > len(rest)-pos-1 {
+			break
+		}
+		keys = rest[pos+1 : pos+1+n]
+		if redisStores[name] {
+			keys = append([]any{rest[0]}, keys...)
+		}
+	case name == "bitop":
+		// operation destkey key [key ...]
+		keys = rest[1:]
+	case name == "xread" || name == "xreadgroup":
+		// ... STREAMS key [key ...] id [id ...]
+		for i, arg := range rest {
+			if strings.EqualFold(fmt.Sprint(arg), "streams") {
+				ids := rest[i+1:]
+				keys = ids[:len(ids)/2]
+				break
+			}
+		}
+	}
+
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			out = append(out, k)
+		case []byte:
+			out = append(out, string(k))
+		default:
+			out = append(out, fmt.Sprint(k))
+		}
+	}
+	return out
+}
+
+// redisDestination returns the index among a command's keys of the key it
+// stores into while only reading the others, or -1
+func redisDestination(name string) int {
+	switch {
+	case name == "copy":
+		return 1
+	case redisStores[name]:
+		return 0
+	}
+	return -1
+}
+
+// redisPatterns rewrites patterns with redisPattern
+func redisPatterns(patterns []string) []string {
+	out := make([]string, len(patterns))
+	for i, p := range patterns {
+		out[i] = redisPattern(p)
+	}
+	return out
+}
+
+// redisPattern turns a key pattern, optionally prefixed with an operation,
+// into a regular expression on "<operation> <key>": plain patterns match
+// keys starting with them and globs match whole keys. Empty and "re:"
+// patterns are kept.
+func redisPattern(pattern string) string {
+	if pattern == "" || strings.HasPrefix(pattern, "re:") {
+		return pattern
+	}
+	op := "(?:read|write|delete|admin) "
+	if word, key, ok := strings.Cut(pattern, " "); ok && (word == "read" || word == "write" || word == "delete" || word == "admin") {
+		op, pattern = word+" ", key
+	}
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := strings.Join(parts, ".*")
+	if len(parts) == 1 {
+		expr += ".*"
+	}
+	return "re:" + op + expr
+}
+
+// idSegment matches key segments that vary per entity: numbers, UUIDs and
+// long hex strings
+var idSegment = regexp.MustCompile(`^(?:\d+|[0-9a-fA-F]{8}-[0-9a-fA-F-]{27}|[0-9a-fA-F]{16,})$`)
+
+// keyPatterns reduces keys to their key-space patterns, e.g. "user:42:profile"
+// to "user:*:profile", without duplicates
+func keyPatterns(keys []string) []string {
+	var patterns []string
+	seen := map[string]bool{}
+	for _, key := range keys {
+		segments := strings.Split(key, ":")
+		for i, s := range segments {
+			if idSegment.MatchString(s) {
+				segments[i] = "*"
+			}
+		}
+		pattern := strings.Join(segments, ":")
+		if !seen[pattern] {
+			seen[pattern] = true
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,239 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// The go-redis types RedisHook is instantiated with, reduced to what it uses
+
+type fakeCmder interface {
+	Name() string
+	Args() []any
+	Err() error
+	SetErr(error)
+}
+
+type (
+	fakeDialHook     func(ctx context.Context, network, addr string) (net.Conn, error)
+	fakeProcessHook  func(ctx context.Context, cmd fakeCmder) error
+	fakePipelineHook func(ctx context.Context, cmds []fakeCmder) error
+)
+
+// fakeRedisHook mirrors redis.Hook
+type fakeRedisHook interface {
+	DialHook(next fakeDialHook) fakeDialHook
+	ProcessHook(next fakeProcessHook) fakeProcessHook
+	ProcessPipelineHook(next fakePipelineHook) fakePipelineHook
+}
+
+var _ fakeRedisHook = (*RedisHook[fakeCmder, fakeDialHook, fakeProcessHook, fakePipelineHook])(nil)
+
+type fakeRedisCmd struct {
+	args []any
+	err  error
+}
+
+func newRedisCmd(args ...any) *fakeRedisCmd { return &fakeRedisCmd{args: args} }
+
+func (c *fakeRedisCmd) Name() string     { return strings.ToLower(c.args[0].(string)) }
+func (c *fakeRedisCmd) Args() []any      { return c.args }
+func (c *fakeRedisCmd) Err() error       { return c.err }
+func (c *fakeRedisCmd) SetErr(err error) { c.err = err }
+
+// fakeRedis stands in for the go-redis connection: it records commands and
+// fails GETs of missing keys with redis.Nil
+type fakeRedis struct {
+	sent []string
+}
+
+func (r *fakeRedis) process(ctx context.Context, cmd fakeCmder) error {
+	r.sent = append(r.sent, cmd.Name())
+	if cmd.Name() == "get" && cmd.Args()[1] == "missing" {
+		cmd.SetErr(errors.New("redis: nil"))
+	}
+	return cmd.Err()
+}
+
+func (r *fakeRedis) pipeline(ctx context.Context, cmds []fakeCmder) error {
+	for _, cmd := range cmds {
+		r.process(ctx, cmd)
+	}
+	return nil
+}
+
+func redisHook(client *Client, opts RedisOptions) fakeRedisHook {
+	return NewRedisHook[fakeCmder, fakeDialHook, fakeProcessHook, fakePipelineHook](client, opts)
+}
+
+func TestRedisHookTracksCommands(t *testing.T) {
+	client := dialClient(t)
+	backend := &fakeRedis{}
+	process := redisHook(client, RedisOptions{})
+	run := process.ProcessHook(backend.process)
+
+	ctx := ContextWithSessionID(context.Background(), "sess-redis")
+	if err := run(ctx, newRedisCmd("set", "user:42:profile", "{}")); err != nil {
+		t.Fatal(err)
+	}
+	run(ctx, newRedisCmd("get", "missing"))
+	run(ctx, newRedisCmd("mget", "user:1:name", "user:2:name", "cart:9f1c2d3e4a5b6c7d8e9f"))
+
+	set, ok := trackedEvent(client, "redis SET")
+	if !ok {
+		t.Fatal("SET not tracked")
+	}
+	if set.Type != EventDataAccess || set.Payload["operation"] != "write" || set.Payload["keys"] != nil ||
+		set.Metadata["session_id"] != "sess-redis" {
+		t.Errorf("unexpected SET event %v %v", set.Payload, set.Metadata)
+	}
+	if p := set.Payload["key_patterns"].([]string); len(p) != 1 || p[0] != "user:*:profile" {
+		t.Errorf("unexpected key patterns %v", p)
+	}
+
+	get, _ := trackedEvent(client, "redis GET")
+	if get.Payload["hit"] != false || get.Payload["error"] != nil || get.Payload["operation"] != "read" {
+		t.Errorf("a missing key should be a miss, not an error: %v", get.Payload)
+	}
+
+	mget, _ := trackedEvent(client, "redis MGET")
+	if p := mget.Payload["key_patterns"].([]string); strings.Join(p, ",") != "user:*:name,cart:*" || mget.Payload["key_count"] != 3 {
+		t.Errorf("unexpected MGET event %v", mget.Payload)
+	}
+}
+
+func TestRedisHookBlocksKeyPrefixes(t *testing.T) {
+	client := dialClient(t)
+	backend := &fakeRedis{}
+	hook := redisHook(client, RedisOptions{
+		Enforcement:     ModeBlock,
+		BlockPatterns:   []string{"read secrets:", "admin flushall"},
+		ExcludePatterns: []string{"read health:"},
+		CaptureKeys:     true,
+	})
+	run := hook.ProcessHook(backend.process)
+	ctx := context.Background()
+
+	get := newRedisCmd("get", "secrets:openai")
+	if err := run(ctx, get); !errors.Is(err, ErrBlocked) || !errors.Is(get.Err(), ErrBlocked) {
+		t.Errorf("expected the read to be blocked, got %v", err)
+	}
+	if err := run(ctx, newRedisCmd("set", "secrets:openai", "sk-new")); err != nil {
+		t.Errorf("writes are not blocked by a read pattern: %v", err)
+	}
+	if err := run(ctx, newRedisCmd("flushall")); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected FLUSHALL to be blocked, got %v", err)
+	}
+	if err := run(ctx, newRedisCmd("exists", "health:ping")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(backend.sent, ",") != "set,exists" {
+		t.Errorf("blocked commands reached redis: %v", backend.sent)
+	}
+
+	blocked, _ := trackedEvent(client, "redis GET")
+	if blocked.Payload["enforcement_action"] != "blocked" || blocked.Payload["keys"].([]string)[0] != "secrets:openai" {
+		t.Errorf("unexpected blocked event %v", blocked.Payload)
+	}
+	if _, ok := trackedEvent(client, "redis EXISTS"); ok {
+		t.Error("excluded keys should not be tracked")
+	}
+
+	// One blocked command rejects the whole pipeline
+	pipeline := hook.ProcessPipelineHook(backend.pipeline)
+	backend.sent = nil
+	cmds := []fakeCmder{newRedisCmd("incr", "counter"), newRedisCmd("hgetall", "secrets:db")}
+	if err := pipeline(ctx, cmds); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected the pipeline to be blocked, got %v", err)
+	}
+	if len(backend.sent) != 0 || cmds[0].Err() != nil || !errors.Is(cmds[1].Err(), ErrBlocked) {
+		t.Errorf("unexpected pipeline state: sent %v, errors %v %v", backend.sent, cmds[0].Err(), cmds[1].Err())
+	}
+
+	if err := pipeline(ctx, []fakeCmder{newRedisCmd("incr", "counter"), newRedisCmd("expire", "counter", 60)}); err != nil {
+		t.Fatal(err)
+	}
+	incr, _ := trackedEvent(client, "redis INCR")
+	if incr.Payload["pipeline"] != true || incr.Payload["enforcement_action"] != "allowed" {
+		t.Errorf("unexpected pipelined event %v", incr.Payload)
+	}
+}
+
+func TestRedisHookBlocksKeysInAnyPosition(t *testing.T) {
+	client := dialClient(t)
+	backend := &fakeRedis{}
+	run := redisHook(client, RedisOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"read secrets:", "vault:", "delete user:*:token"},
+	}).ProcessHook(backend.process)
+	ctx := context.Background()
+
+	for _, args := range [][]any{
+		{"sunionstore", "tmp", "secrets:a"},
+		{"zunionstore", "tmp", 2, "other", "secrets:a"},
+		{"blpop", "queue", "vault:q", 0},
+		{"xread", "COUNT", 10, "STREAMS", "events", "secrets:s", "0", "0"},
+		{"bitop", "and", "tmp", "secrets:bits"},
+		{"object", "encoding", "secrets:a"},
+		{"copy", "secrets:a", "tmp"},
+		{"module.get", "secrets:a"},
+		{"del", "user:42:token"},
+	} {
+		if err := run(ctx, newRedisCmd(args...)); !errors.Is(err, ErrBlocked) {
+			t.Errorf("%v: expected the command to be blocked, got %v", args, err)
+		}
+	}
+	for _, args := range [][]any{
+		{"sunionstore", "secrets:a", "public"},
+		{"get", "app:secrets:a"},
+		{"get", "mysecrets:a"},
+		{"set", "app:vault:a", 1},
+		{"del", "user:42:token:old"},
+	} {
+		if err := run(ctx, newRedisCmd(args...)); err != nil {
+			t.Errorf("%v: expected the command to be allowed, got %v", args, err)
+		}
+	}
+	if len(backend.sent) != 5 {
+		t.Errorf("blocked commands reached redis: %v", backend.sent)
+	}
+}
+
+func TestRedisKeys(t *testing.T) {
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{[]any{"get", "a"}, "a"},
+		{[]any{"del", "a", []byte("b")}, "a,b"},
+		{[]any{"mset", "a", 1, "b", 2}, "a,b"},
+		{[]any{"eval", "return 1", 2, "a", "b", "arg"}, "a,b"},
+		{[]any{"rename", "a", "b"}, "a,b"},
+		{[]any{"ping"}, ""},
+		{[]any{"config", "get", "maxmemory"}, ""},
+		{[]any{"hset", "h", "field", "v"}, "h"},
+		{[]any{"sunionstore", "d", "a", "b"}, "d,a,b"},
+		{[]any{"zinterstore", "d", 2, "a", "b", "WEIGHTS", 1, 2}, "d,a,b"},
+		{[]any{"zunion", 2, "a", "b", "WITHSCORES"}, "a,b"},
+		{[]any{"blmpop", 0, 2, "a", "b", "LEFT"}, "a,b"},
+		{[]any{"brpop", "a", "b", 5}, "a,b"},
+		{[]any{"xreadgroup", "GROUP", "g", "c", "STREAMS", "a", "b", ">", ">"}, "a,b"},
+		{[]any{"bitop", "or", "d", "a", "b"}, "d,a,b"},
+		{[]any{"memory", "usage", "a"}, "a"},
+		{[]any{"eval", "return 1", "x", "a"}, "return 1,x,a"},
+		{[]any{"custom", "a", "b"}, "a,b"},
+	}
+	for _, tt := range tests {
+		name := tt.args[0].(string)
+		if got := strings.Join(redisKeys(name, tt.args), ","); got != tt.want {
+			t.Errorf("redisKeys(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+	if redisOperation("hdel") != "delete" || redisOperation("config") != "admin" || redisOperation("zrange") != "read" ||
+		redisOperation("sunionstore") != "write" {
+		t.Error("unexpected command classification")
+	}
+}
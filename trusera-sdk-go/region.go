@@ -0,0 +1,45 @@
+package trusera
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is a Trusera deployment; events and policies stay in the region
+// the client is configured for
+type Region string
+
+const (
+	RegionUS Region = "us" // The default
+	RegionEU Region = "eu"
+)
+
+// regionURLs are the API base URLs of the regions
+var regionURLs = map[Region]string{
+	RegionUS: defaultBaseURL,
+	RegionEU: "https://api.eu.trusera.io",
+}
+
+// WithRegion sends events to a regional Trusera deployment, for data
+// residency. Region names are case-insensitive. A client configured with
+// an unknown region refuses to send anything rather than fall back to
+// another region. For self-hosted deployments, use WithBaseURL.
+func WithRegion(r Region) Option {
+	return func(c *Client) {
+		r = Region(strings.ToLower(string(r)))
+		url, ok := regionURLs[r]
+		if !ok {
+			c.baseURL, c.regionErr = "", fmt.Errorf("unknown region %q", r)
+			return
+		}
+		c.baseURL, c.regionErr = url, nil
+	}
+}
+
+// endpoint returns the URL of an API path
+func (c *Client) endpoint(path string) (string, error) {
+	if c.regionErr != nil {
+		return "", c.regionErr
+	}
+	return c.baseURL + path, nil
+}
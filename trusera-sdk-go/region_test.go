@@ -0,0 +1,47 @@
+package trusera
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRegion(t *testing.T) {
+	for _, tc := range []struct {
+		opts []Option
+		want string
+	}{
+		{nil, "https://api.trusera.io"},
+		{[]Option{WithRegion(RegionEU)}, "https://api.eu.trusera.io"},
+		{[]Option{WithRegion("EU")}, "https://api.eu.trusera.io"},
+		{[]Option{WithRegion(RegionEU), WithBaseURL("https://trusera.internal/")}, "https://trusera.internal"},
+		{[]Option{WithRegion("mars"), WithRegion(RegionUS)}, "https://api.trusera.io"},
+	} {
+		client := NewClient("test-key", append(tc.opts, WithFlushInterval(time.Hour))...)
+		if got, err := client.endpoint(""); err != nil || got != tc.want {
+			t.Errorf("expected %s, got %s (%v)", tc.want, got, err)
+		}
+		client.Close()
+	}
+}
+
+func TestUnknownRegionSendsNothing(t *testing.T) {
+	stub := &stubTransport{}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithHTTPTransport(stub),
+		WithRegion("mars"),
+		WithRetry(RetryPolicy{MaxAttempts: 3}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "x"))
+	if err := client.Flush(); err == nil || !strings.Contains(err.Error(), `unknown region "mars"`) {
+		t.Errorf("expected the unknown region to be reported, got %v", err)
+	}
+	if _, err := client.RegisterAgent("agent", "custom"); err == nil {
+		t.Error("expected registration to fail too")
+	}
+	if len(stub.requests) != 0 {
+		t.Errorf("expected no requests, got %d", len(stub.requests))
+	}
+}
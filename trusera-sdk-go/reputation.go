@@ -0,0 +1,153 @@
+package trusera
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventMaliciousHost is tracked for every request to a host that the
+// interceptor's ReputationProvider reports as malicious
+const EventMaliciousHost EventType = "malicious_host"
+
+// defaultReputationTTL is how long a reputation is cached when the provider
+// does not say
+const defaultReputationTTL = 10 * time.Minute
+
+// maxReputationCache caps the hosts whose reputation an interceptor caches
+const maxReputationCache = 10000
+
+// ReputationProvider looks up the reputation of a host, e.g. in a threat
+// intelligence feed or an internal domain reputation service. It is called
+// concurrently, with the context of the request being checked.
+type ReputationProvider interface {
+	Reputation(ctx context.Context, host string) (Reputation, error)
+}
+
+// Reputation is what a ReputationProvider knows about a host
+type Reputation struct {
+	Malicious  bool
+	Score      float64       // Provider-specific, e.g. 0 (benign) to 100 (malicious)
+	Categories []string      // e.g. "phishing" or "c2"
+	Source     string        // The feed the verdict came from
+	TTL        time.Duration // How long to cache the reputation; 10 minutes when zero
+}
+
+// reputationCache holds the reputations an interceptor has looked up
+type reputationCache struct {
+	mu      sync.Mutex
+	entries map[string]reputationEntry
+}
+
+type reputationEntry struct {
+	rep     Reputation
+	expires time.Time
+}
+
+// get returns the cached reputation of a host, if it has not expired
+func (c *reputationCache) get(host string, now time.Time) (Reputation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[host]
+	if !ok || now.After(e.expires) {
+		return Reputation{}, false
+	}
+	return e.rep, true
+}
+
+// put caches a host's reputation, making room by dropping expired entries,
+// or any entry if none has expired
+func (c *reputationCache) put(host string, rep Reputation, now time.Time) {
+	ttl := rep.TTL
+	if ttl <= 0 {
+		ttl = defaultReputationTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]reputationEntry{}
+	}
+	if len(c.entries) >= maxReputationCache {
+		for h, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, h)
+			}
+		}
+		for h := range c.entries {
+			if len(c.entries) < maxReputationCache {
+				break
+			}
+			delete(c.entries, h)
+		}
+	}
+	c.entries[host] = reputationEntry{rep: rep, expires: now.Add(ttl)}
+}
+
+// clear forgets every cached reputation
+func (c *reputationCache) clear() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+// checkReputation looks up the reputation of a request's host, through the
+// cache, and tracks an EventMaliciousHost if it is malicious. Lookups that
+// fail let the request through and are not cached.
+func (t *interceptingTransport) checkReputation(ctx context.Context, rawURL string, mode EnforcementMode) (Reputation, bool) {
+	provider := t.localOptions().Reputation
+	host, _ := targetAddr(rawURL)
+	if provider == nil || host == "" {
+		return Reputation{}, false
+	}
+
+	now := t.client.now()
+	rep, ok := t.reputations.get(host, now)
+	if !ok {
+		var err error
+		rep, err = provider.Reputation(ctx, host)
+		if err != nil {
+			t.client.log(slog.LevelWarn, "reputation lookup failed", "host", host, "error", err)
+			return Reputation{}, false
+		}
+		t.reputations.put(host, rep, now)
+	}
+	if !rep.Malicious {
+		return rep, false
+	}
+
+	action := "flagged"
+	if mode.rejects() {
+		action = "blocked"
+	}
+	event := NewEvent(EventMaliciousHost, host).
+		WithPayload("host", host).
+		WithPayload("url", rawURL).
+		WithPayload("score", rep.Score).
+		WithPayload("action", action).
+		WithMetadata("enforcement_mode", string(mode))
+	if len(rep.Categories) > 0 {
+		event = event.WithPayload("categories", rep.Categories)
+	}
+	if rep.Source != "" {
+		event = event.WithPayload("source", rep.Source)
+	}
+	if mode == ModeWarn {
+		event = event.WithMetadata("warning", "host has a malicious reputation but allowed in warn mode")
+	}
+	t.client.Track(t.client.enrich(ctx, event))
+	return rep, true
+}
+
+// reputationSummary describes a malicious reputation for an api_call event
+func reputationSummary(rep Reputation) string {
+	summary := "malicious"
+	if len(rep.Categories) > 0 {
+		summary += " (" + strings.Join(rep.Categories, ", ") + ")"
+	}
+	if rep.Source != "" {
+		summary += " per " + rep.Source
+	}
+	return summary
+}
@@ -0,0 +1,117 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeReputation struct {
+	mu      sync.Mutex
+	lookups map[string]int
+	hosts   map[string]Reputation
+	err     error
+}
+
+func (f *fakeReputation) Reputation(ctx context.Context, host string) (Reputation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lookups == nil {
+		f.lookups = map[string]int{}
+	}
+	f.lookups[host]++
+	return f.hosts[host], f.err
+}
+
+func (f *fakeReputation) count(host string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookups[host]
+}
+
+func TestReputationBlocksMaliciousHosts(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	provider := &fakeReputation{hosts: map[string]Reputation{
+		"127.0.0.1": {Malicious: true, Score: 97, Categories: []string{"c2"}, Source: "feed"},
+	}}
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{Enforcement: ModeBlock, Reputation: provider})
+
+	for i := 0; i < 3; i++ {
+		if _, err := httpClient.Get(backend.URL + "/beacon"); !errors.Is(err, ErrBlocked) {
+			t.Fatalf("expected a malicious host blocked, got %v", err)
+		}
+	}
+	if n := provider.count("127.0.0.1"); n != 1 {
+		t.Errorf("expected 1 lookup with caching, got %d", n)
+	}
+
+	events := trackedEvents(client, EventMaliciousHost)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 malicious host events, got %d", len(events))
+	}
+	e := events[0]
+	if e.Payload["host"] != "127.0.0.1" || e.Payload["action"] != "blocked" || e.Payload["source"] != "feed" ||
+		!reflect.DeepEqual(e.Payload["categories"], []string{"c2"}) {
+		t.Errorf("unexpected event payload %v", e.Payload)
+	}
+	call, _ := trackedEvent(client, "GET "+backend.URL+"/beacon")
+	if call.Payload["reputation"] != "malicious (c2) per feed" {
+		t.Errorf("unexpected api_call reputation %v", call.Payload["reputation"])
+	}
+}
+
+func TestReputationFailsOpen(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	provider := &fakeReputation{err: errors.New("feed unavailable")}
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{Enforcement: ModeBlock, Reputation: provider})
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Get(backend.URL)
+		if err != nil {
+			t.Fatalf("expected a failed lookup to allow the request, got %v", err)
+		}
+		resp.Body.Close()
+	}
+	if n := provider.count("127.0.0.1"); n != 2 {
+		t.Errorf("expected failed lookups not cached, got %d lookups", n)
+	}
+}
+
+func TestReputationCache(t *testing.T) {
+	clock := &manualClock{now: time.Unix(1000, 0)}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithClock(clock.Now))
+	defer client.Close()
+	provider := &fakeReputation{hosts: map[string]Reputation{"short.example.com": {TTL: time.Second}}}
+	transport := &interceptingTransport{client: client, opts: InterceptorOptions{Reputation: provider}}
+	interceptor := &Interceptor{t: transport}
+
+	check := func(host string) {
+		transport.checkReputation(context.Background(), "https://"+host+"/", ModeBlock)
+	}
+	check("short.example.com")
+	check("long.example.com")
+	clock.advance(2 * time.Second)
+	check("short.example.com")
+	check("long.example.com")
+	if provider.count("short.example.com") != 2 || provider.count("long.example.com") != 1 {
+		t.Errorf("unexpected lookups %v", provider.lookups)
+	}
+
+	interceptor.UpdateOptions(func(o *InterceptorOptions) {})
+	check("long.example.com")
+	if provider.count("long.example.com") != 2 {
+		t.Errorf("expected UpdateOptions to clear the cache, got %v", provider.lookups)
+	}
+}
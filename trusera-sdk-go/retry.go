@@ -0,0 +1,177 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy controls how failed flush requests are retried. Only
+// transient failures are retried: network errors, 429 and 5xx responses.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts per request, including the first
+	InitialBackoff time.Duration // Wait before the first retry
+	MaxBackoff     time.Duration // Upper bound on any wait, including Retry-After
+	Multiplier     float64       // Backoff growth factor between retries
+	Jitter         float64       // Fraction of each wait that is randomized, 0 to 1
+
+	// The retry budget is shared by all requests of a client. Every request
+	// earns BudgetRatio retries and every retry spends one. The budget starts
+	// with BudgetMin retries and banks at most twice that, so once it is spent
+	// a failing endpoint sees about (1+BudgetRatio) times the normal volume.
+	BudgetRatio float64
+	BudgetMin   int
+}
+
+// DefaultRetryPolicy is used by WithRetry for fields left at zero
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	BudgetRatio:    0.2,
+	BudgetMin:      10,
+}
+
+// WithRetry retries transient flush failures with exponential backoff and
+// jitter. Zero fields take their value from DefaultRetryPolicy.
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		d := DefaultRetryPolicy
+		if p.MaxAttempts <= 0 {
+			p.MaxAttempts = d.MaxAttempts
+		}
+		if p.InitialBackoff <= 0 {
+			p.InitialBackoff = d.InitialBackoff
+		}
+		if p.MaxBackoff <= 0 {
+			p.MaxBackoff = d.MaxBackoff
+		}
+		if p.Multiplier < 1 {
+			p.Multiplier = d.Multiplier
+		}
+		if p.Jitter < 0 || p.Jitter > 1 {
+			p.Jitter = d.Jitter
+		}
+		if p.BudgetRatio <= 0 {
+			p.BudgetRatio = d.BudgetRatio
+		}
+		if p.BudgetMin <= 0 {
+			p.BudgetMin = d.BudgetMin
+		}
+		c.retry = &p
+		c.retryBudget = newRetryBudget(p.BudgetRatio, p.BudgetMin)
+	}
+}
+
+// backoff returns the wait before the given retry (1 for the first). A
+// Retry-After sent by the API takes precedence over the computed delay.
+func (p *RetryPolicy) backoff(retry int, err error) time.Duration {
+	var se *statusError
+	if errors.As(err, &se) && se.retryAfter > 0 {
+		return min(se.retryAfter, p.MaxBackoff)
+	}
+
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= p.Multiplier
+		if d >= float64(p.MaxBackoff) {
+			d = float64(p.MaxBackoff)
+			break
+		}
+	}
+	d -= d * p.Jitter * rand.Float64()
+	return time.Duration(d)
+}
+
+// shouldRetry reports whether a failed attempt may be retried, spending
+// from the retry budget if so
+func (c *Client) shouldRetry(attempt int, err error) bool {
+	if c.retry == nil || attempt >= c.retry.MaxAttempts || !isTransient(err) {
+		return false
+	}
+	return c.retryBudget.withdraw()
+}
+
+// isTransient reports whether a send error is worth retrying. Errors caused
+// by the caller's context ending are not.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrNotAcknowledged) {
+		return true
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// sleepCtx waits for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// retryBudget is a token bucket limiting retries relative to requests
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64, minRetries int) *retryBudget {
+	m := float64(minRetries)
+	return &retryBudget{ratio: ratio, max: m * 2, tokens: m}
+}
+
+// deposit earns retry credit for a request
+func (b *retryBudget) deposit() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.tokens = min(b.tokens+b.ratio, b.max)
+	b.mu.Unlock()
+}
+
+// withdraw spends one retry, reporting false when the budget is exhausted
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
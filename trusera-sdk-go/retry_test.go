@@ -0,0 +1,137 @@
+package trusera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{
+	MaxAttempts:    4,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+// statusSequence answers with the given statuses in order, then 200
+func statusSequence(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1)) - 1
+		if n < len(statuses) {
+			w.WriteHeader(statuses[n])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	client := NewClient("test-key", WithBaseURL(server.URL), WithRetry(fastRetry))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("expected flush to succeed after retries, got %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+	if retries := client.Stats().FlushRetries; retries != 2 {
+		t.Errorf("expected 2 retries counted, got %d", retries)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	server, calls := statusSequence(t, 500, 500, 500, 500, 500)
+	client := NewClient("test-key", WithBaseURL(server.URL), WithRetry(fastRetry))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	if got := calls.Load(); got != 4 {
+		t.Errorf("expected MaxAttempts attempts, got %d", got)
+	}
+}
+
+func TestRetrySkipsPermanentFailures(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusBadRequest)
+	client := NewClient("test-key", WithBaseURL(server.URL), WithRetry(fastRetry))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected no retry for a 400, got %d attempts", got)
+	}
+}
+
+func TestRetryDisabledByDefault(t *testing.T) {
+	server, calls := statusSequence(t, http.StatusServiceUnavailable)
+	client := NewClient("test-key", WithBaseURL(server.URL))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "tool"))
+	client.Flush()
+	if got := calls.Load(); got != 1 {
+		t.Errorf("expected a single attempt without WithRetry, got %d", got)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(0.5, 2)
+	if !b.withdraw() || !b.withdraw() {
+		t.Fatal("expected the initial budget to allow two retries")
+	}
+	if b.withdraw() {
+		t.Fatal("expected the budget to be exhausted")
+	}
+
+	b.deposit()
+	b.deposit()
+	if !b.withdraw() {
+		t.Error("expected two requests to earn one retry")
+	}
+
+	for i := 0; i < 100; i++ {
+		b.deposit()
+	}
+	if b.tokens != 4 {
+		t.Errorf("expected the budget to be capped at 4, got %v", b.tokens)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5}
+
+	for retry, base := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		d := p.backoff(retry, nil)
+		if d > base || d < base/2 {
+			t.Errorf("retry %d: backoff %v outside [%v, %v]", retry, d, base/2, base)
+		}
+	}
+
+	err := &statusError{code: 503, retryAfter: 3 * time.Second}
+	if d := p.backoff(1, err); d != time.Second {
+		t.Errorf("expected Retry-After capped at MaxBackoff, got %v", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("7"); d != 7*time.Second {
+		t.Errorf("expected 7s, got %v", d)
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(future); d <= 0 || d > time.Minute {
+		t.Errorf("unexpected duration for HTTP date: %v", d)
+	}
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Errorf("expected 0 for an invalid value, got %v", d)
+	}
+}
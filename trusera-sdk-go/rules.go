@@ -0,0 +1,204 @@
+package trusera
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RuleAction is what an interceptor does with a request matching a Rule
+type RuleAction string
+
+const (
+	RuleExclude RuleAction = "exclude" // Skip interception entirely
+	RuleAllow   RuleAction = "allow"   // Permit, even in ModeAllowList
+	RuleLog     RuleAction = "log"     // Record as a violation but allow
+	RuleWarn    RuleAction = "warn"    // Record with a warning but allow
+	RuleBlock   RuleAction = "block"   // Reject with an error
+)
+
+// Rule applies its own action to the requests it matches, so one
+// interceptor can exclude some hosts, warn on others and block a third set.
+// Rules are checked in order and the first match wins, after the rules of
+// the request's host in InterceptorOptions.Hosts; requests matching no rule
+// fall back to ExcludePatterns, BlockPatterns and the Enforcement mode.
+type Rule struct {
+	ID       string     `json:"id,omitempty"`       // Reported as rule_id; defaults to rule-<index>
+	Match    string     `json:"match"`              // URL pattern, as compiled by CompilePattern; empty matches everything
+	Action   RuleAction `json:"action"`             // Unknown actions are treated as RuleBlock
+	Reason   string     `json:"reason,omitempty"`   // Why the rule exists, reported as rule_reason
+	Severity string     `json:"severity,omitempty"` // e.g. "low", "medium", "high" or "critical"
+
+	// Methods limits the rule to requests with these HTTP methods, such as
+	// "POST" and "DELETE"; all methods when empty. gRPC calls are POSTs,
+	// websocket handshakes GETs and proxy tunnels CONNECTs. Targets without
+	// a method, such as SQL statements, never match a rule with Methods.
+	Methods []string `json:"methods,omitempty"`
+
+	// Path is a pattern the URL path must also match, such as "/repos/*";
+	// any path when empty
+	Path string `json:"path,omitempty"`
+
+	// RateLimit caps how often the matching calls may be made, whatever
+	// the action; see RateLimit
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+}
+
+// verdict is the outcome of matching a request against the rules and patterns
+type verdict struct {
+	rule      *Rule
+	ruleID    string
+	mode      EnforcementMode // Enforcement mode in effect for the request
+	blocked   bool
+	excluded  bool
+	version   string      // Remote policy version, if one was applied
+	paused    bool        // The control plane paused enforcement of the verdict
+	limited   bool        // The call exceeded its rule's rate limit
+	learning  bool        // The request is only observed; see ModeLearn
+	shadow    bool        // The request is evaluated but never rejected; see ModeShadow
+	candidate bool        // The rule is one of the CandidateRules
+	reason    BlockReason // Why the verdict blocks, if it does
+
+	canaryPercent  float64 // EnforcePercent, if a canary rollout applied
+	canaryEnforced bool    // The request's session is in the enforced share
+}
+
+// evaluate matches a URL (or gRPC method) against the rules, falling back to
+// the flat pattern lists
+func (t *interceptingTransport) evaluate(target string) verdict {
+	return t.evaluateRequest("", target)
+}
+
+// evaluateRequest is evaluate for a target requested with an HTTP method
+func (t *interceptingTransport) evaluateRequest(method, target string) verdict {
+	remote := t.remotePolicy()
+	return pause(t.match(method, target, remote), remote)
+}
+
+// pause downgrades a rejecting verdict to ModeLog while the control plane
+// pauses enforcement
+func pause(v verdict, remote *RemotePolicy) verdict {
+	if remote != nil && remote.PauseEnforcement && v.mode.rejects() {
+		v.mode, v.paused = ModeLog, true
+	}
+	return v
+}
+
+// match finds the rule or pattern list a target falls under
+func (t *interceptingTransport) match(method, target string, remote *RemotePolicy) verdict {
+	opts := t.localOptions().merge(remote)
+	var version string
+	var remoteRules int
+	if remote != nil {
+		version, remoteRules = remote.Version, len(remote.Rules)
+	}
+	opts = opts.forHost(target, remoteRules)
+
+	shadow := opts.Enforcement == ModeShadow
+	if shadow {
+		opts = opts.withCandidates()
+		opts.Enforcement = ModeBlock
+	}
+
+	v := opts.verdict(method, target, version)
+	if shadow {
+		v.candidate = v.rule != nil && opts.isCandidate(v.rule)
+		v = v.shadowed()
+	}
+	if opts.Enforcement == ModeLearn || t.client.learning() {
+		v = v.observe()
+		if !v.excluded {
+			t.client.learn(method, target)
+		}
+	}
+	return v
+}
+
+// verdict finds the rule or pattern list a target falls under
+func (opts InterceptorOptions) verdict(method, target, version string) verdict {
+	for i := range opts.Rules {
+		rule := &opts.Rules[i]
+		if !rule.matches(method, target) {
+			continue
+		}
+
+		id := rule.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i)
+		}
+		return ruleVerdict(rule, id, opts.Enforcement, version)
+	}
+
+	reason := opts.blockReason(target)
+	return verdict{
+		mode:     opts.Enforcement,
+		blocked:  reason != "",
+		excluded: matchesAny(target, opts.ExcludePatterns),
+		version:  version,
+		reason:   reason,
+	}
+}
+
+// matches reports whether a rule applies to a target requested with method
+func (r *Rule) matches(method, target string) bool {
+	if len(r.Methods) > 0 && !slices.ContainsFunc(r.Methods, func(m string) bool { return strings.EqualFold(m, method) }) {
+		return false
+	}
+	if r.Path != "" && !matchPattern(targetPath(target), r.Path) {
+		return false
+	}
+	return matchPattern(target, r.Match)
+}
+
+// ruleVerdict applies a matched rule's action on top of the enforcement mode
+func ruleVerdict(rule *Rule, id string, mode EnforcementMode, version string) verdict {
+	v := verdict{rule: rule, ruleID: id, mode: mode, version: version}
+	switch rule.Action {
+	case RuleExclude:
+		v.excluded = true
+	case RuleAllow:
+	case RuleLog, RuleWarn:
+		v.blocked, v.reason = true, BlockReasonRule
+		v.mode = EnforcementMode(rule.Action)
+	default:
+		v.blocked, v.reason = true, BlockReasonRule
+		v.mode = ModeBlock
+	}
+	return v
+}
+
+// describe records the matched rule and the enforcement mode on an event
+func (v verdict) describe(event Event) Event {
+	event = event.WithMetadata("enforcement_mode", string(v.mode))
+	if v.version != "" {
+		event = event.WithMetadata("policy_version", v.version)
+	}
+	if v.paused {
+		event = event.WithMetadata("enforcement_paused", true)
+	}
+	if v.limited {
+		event = event.WithPayload("rate_limited", true)
+	}
+	if v.canaryPercent > 0 {
+		event = event.
+			WithMetadata("canary_percent", v.canaryPercent).
+			WithMetadata("canary_enforced", v.canaryEnforced)
+	}
+	if v.rule == nil {
+		return event
+	}
+
+	event = event.
+		WithPayload("rule_id", v.ruleID).
+		WithPayload("rule_action", string(v.rule.Action))
+	if v.candidate {
+		event = event.WithPayload("candidate_rule", true)
+	}
+	if v.rule.Reason != "" {
+		event = event.WithPayload("rule_reason", v.rule.Reason)
+	}
+	if v.rule.Severity != "" {
+		event = event.WithPayload("severity", v.rule.Severity)
+	}
+	return event
+}
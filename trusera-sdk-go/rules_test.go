@@ -0,0 +1,175 @@
+package trusera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvaluateRules(t *testing.T) {
+	transport := &interceptingTransport{opts: InterceptorOptions{
+		Enforcement: ModeAllowList,
+		Rules: []Rule{
+			{ID: "health", Match: "/healthz", Action: RuleExclude},
+			{Match: "api.openai.com", Action: RuleAllow},
+			{ID: "pastebin", Match: "pastebin.com", Action: RuleBlock, Reason: "exfiltration", Severity: "high"},
+			{Match: "staging.", Action: RuleWarn},
+			{Match: "legacy.", Action: "quarantine"},
+		},
+		BlockPatterns:   []string{"api.openai.com/v1/files"},
+		ExcludePatterns: []string{"/metrics"},
+		AllowPatterns:   []string{"internal.example.com"},
+	}}
+
+	tests := []struct {
+		url      string
+		ruleID   string
+		mode     EnforcementMode
+		blocked  bool
+		excluded bool
+	}{
+		{"https://svc/healthz", "health", ModeAllowList, false, true},
+		{"https://api.openai.com/v1/files", "rule-1", ModeAllowList, false, false},
+		{"https://pastebin.com/raw/x", "pastebin", ModeBlock, true, false},
+		{"https://staging.example.com", "rule-3", ModeWarn, true, false},
+		{"https://legacy.example.com", "rule-4", ModeBlock, true, false},
+		{"https://internal.example.com/metrics", "", ModeAllowList, false, true},
+		{"https://internal.example.com/v1", "", ModeAllowList, false, false},
+		{"https://unknown.example.org", "", ModeAllowList, true, false},
+	}
+	for _, tt := range tests {
+		v := transport.evaluate(tt.url)
+		if v.ruleID != tt.ruleID || v.mode != tt.mode || v.blocked != tt.blocked || v.excluded != tt.excluded {
+			t.Errorf("%s: got rule %q mode %s blocked %v excluded %v, want rule %q mode %s blocked %v excluded %v",
+				tt.url, v.ruleID, v.mode, v.blocked, v.excluded, tt.ruleID, tt.mode, tt.blocked, tt.excluded)
+		}
+	}
+}
+
+func TestRulesByMethodAndPath(t *testing.T) {
+	transport := &interceptingTransport{opts: InterceptorOptions{
+		Enforcement: ModeBlock,
+		Rules: []Rule{
+			{ID: "gh-writes", Match: "api.github.com", Methods: []string{"POST", "put", "DELETE"}, Action: RuleBlock},
+			{ID: "gh-issues", Match: "api.github.com", Path: "/repos/*/issues", Action: RuleWarn},
+			{ID: "gh-reads", Match: "api.github.com", Action: RuleAllow},
+			{ID: "payments", Match: "*.example.com", Methods: []string{"POST"}, Path: "/pay.v1.Payments/*", Action: RuleBlock},
+		},
+	}}
+
+	tests := []struct {
+		method, target string
+		ruleID         string
+		blocked        bool
+	}{
+		{"GET", "https://api.github.com/repos/a/b", "gh-reads", false},
+		{"POST", "https://api.github.com/repos/a/b/issues", "gh-writes", true},
+		{"PUT", "https://api.github.com/repos/a/b", "gh-writes", true},
+		{"GET", "https://api.github.com/repos/a/b/issues?state=open", "gh-issues", true},
+		{"GET", "https://api.github.com/repos/a/b/issues/1", "gh-reads", false},
+		{"", "https://api.github.com/repos/a/b", "gh-reads", false},
+		{"POST", "dns:///grpc.example.com:443/pay.v1.Payments/Refund", "payments", true},
+		{"POST", "dns:///grpc.example.com:443/pay.v1.Orders/List", "", false},
+	}
+	for _, tt := range tests {
+		v := transport.evaluateRequest(tt.method, tt.target)
+		if v.ruleID != tt.ruleID || v.blocked != tt.blocked {
+			t.Errorf("%s %s: got rule %q blocked %v, want rule %q blocked %v", tt.method, tt.target, v.ruleID, v.blocked, tt.ruleID, tt.blocked)
+		}
+	}
+}
+
+func TestRulesApplyPerHost(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	client := NewClient("test-key", WithBaseURL(api.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, client, InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules: []Rule{
+			{Match: "/skip", Action: RuleExclude},
+			{ID: "warn-beta", Match: "/beta", Action: RuleWarn, Reason: "beta endpoint", Severity: "low"},
+			{ID: "block-admin", Match: "/admin", Action: RuleBlock, Reason: "admin API", Severity: "critical"},
+		},
+	})
+
+	for _, path := range []string{"/skip", "/beta", "/admin", "/other"} {
+		resp, err := httpClient.Get(backend.URL + path)
+		if path == "/admin" {
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("expected /admin to be blocked")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	mu.Lock()
+	if len(calls) != 3 {
+		t.Errorf("expected 3 backend calls, got %v", calls)
+	}
+	mu.Unlock()
+
+	if _, ok := trackedEvent(client, "GET "+backend.URL+"/skip"); ok {
+		t.Error("excluded request should not be tracked")
+	}
+
+	beta, _ := trackedEvent(client, "GET "+backend.URL+"/beta")
+	if beta.Payload["rule_id"] != "warn-beta" || beta.Payload["severity"] != "low" || beta.Payload["rule_reason"] != "beta endpoint" {
+		t.Errorf("unexpected beta event payload %v", beta.Payload)
+	}
+	if beta.Metadata["enforcement_mode"] != "warn" || beta.Metadata["warning"] == nil {
+		t.Errorf("expected warn metadata, got %v", beta.Metadata)
+	}
+
+	admin, _ := trackedEvent(client, "GET "+backend.URL+"/admin")
+	if admin.Payload["rule_id"] != "block-admin" || admin.Payload["enforcement_action"] != "blocked" || admin.Payload["severity"] != "critical" {
+		t.Errorf("unexpected admin event payload %v", admin.Payload)
+	}
+
+	other, _ := trackedEvent(client, "GET "+backend.URL+"/other")
+	if _, ok := other.Payload["rule_id"]; ok || other.Payload["enforcement_action"] != "allowed" {
+		t.Errorf("unexpected payload for unmatched request %v", other.Payload)
+	}
+
+	stats := client.Stats().InterceptorDecision
+	if stats["block"] != 1 || stats["warn"] != 1 || stats["allow"] != 1 {
+		t.Errorf("unexpected decisions %v", stats)
+	}
+}
+
+func TestRulesApplyToWebSockets(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer api.Close()
+	client := NewClient("test-key", WithBaseURL(api.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	ws := NewWebSocketInterceptor(client, InterceptorOptions{
+		Enforcement: ModeLog,
+		Rules:       []Rule{{ID: "no-chat", Match: "chat.example.com", Action: RuleBlock}},
+	})
+	if err := ws.Check(context.Background(), "wss://chat.example.com/socket"); err == nil {
+		t.Error("expected websocket rule to block the connection")
+	}
+	if err := ws.Check(context.Background(), "wss://feed.example.com/socket"); err != nil {
+		t.Errorf("unexpected error for unmatched websocket: %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sampler decides whether a tracked event is kept. Events it rejects are
+// discarded before they are buffered and counted in
+// Stats().EventsDroppedSampled.
+type Sampler interface {
+	Sample(event Event) bool
+}
+
+// SamplerFunc adapts a function to the Sampler interface
+type SamplerFunc func(event Event) bool
+
+// Sample calls f(event)
+func (f SamplerFunc) Sample(event Event) bool {
+	return f(event)
+}
+
+// WithSampler reduces the volume of tracked events. The sampler runs after
+// the event hooks and the local policy, so it sees the final event. Events
+// sent with TrackSync are sampled too and report ErrEventDropped.
+func WithSampler(s Sampler) Option {
+	return func(c *Client) {
+		c.sampler = s
+	}
+}
+
+// HeadSampler keeps a fraction rate, between 0 and 1, of events regardless
+// of their content. Events with a trace_id are kept or dropped together
+// with the rest of their trace.
+func HeadSampler(rate float64) Sampler {
+	return SamplerFunc(func(event Event) bool {
+		if traceID, _ := event.Metadata["trace_id"].(string); traceID != "" {
+			return sampleKey(traceID, rate)
+		}
+		return sampleRandom(rate)
+	})
+}
+
+// TailSampler decides once an event is complete. It keeps every notable
+// event, see IsNotable, and a fraction of the routine ones.
+type TailSampler struct {
+	// Rate is the fraction, between 0 and 1, of routine events kept
+	Rate float64
+	// Keep marks further events as notable
+	Keep func(event Event) bool
+}
+
+// Sample keeps notable events and samples the rest
+func (s TailSampler) Sample(event Event) bool {
+	if IsNotable(event) || s.Keep != nil && s.Keep(event) {
+		return true
+	}
+	return sampleRandom(s.Rate)
+}
+
+// IsNotable reports whether an event records a block, a policy denial, an
+// error or a high or critical severity: the events sampling never drops
+func IsNotable(event Event) bool {
+	if blocked, _ := event.Payload["blocked"].(bool); blocked {
+		return true
+	}
+	if action, _ := event.Payload["enforcement_action"].(string); action == "blocked" {
+		return true
+	}
+	if decision, _ := event.Metadata["policy_decision"].(string); decision == "Deny" {
+		return true
+	}
+	if msg, _ := event.Payload["error"].(string); msg != "" {
+		return true
+	}
+	for _, fields := range []map[string]any{event.Payload, event.Metadata} {
+		switch severity, _ := fields["severity"].(string); strings.ToLower(severity) {
+		case "high", "critical":
+			return true
+		}
+	}
+	return false
+}
+
+// sampleRandom keeps a random fraction rate of calls
+func sampleRandom(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	return rate > 0 && rand.Float64() < rate
+}
+
+// sampleKey keeps a fraction rate of keys, always deciding the same way for
+// the same key
+func sampleKey(key string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	sum := sha256.Sum256([]byte(key))
+	return float64(binary.BigEndian.Uint64(sum[:8]))/math.MaxUint64 < rate
+}
+
+// SamplingConfig sets sampling rates and rate limits per event type, e.g.
+// every decision, 10% of LLM invocations and at most 500 data accesses a
+// minute:
+//
+//	trusera.SamplingConfig{
+//		Rates:     map[trusera.EventType]float64{trusera.EventLLMInvoke: 0.1},
+//		PerMinute: map[trusera.EventType]int{trusera.EventDataAccess: 500},
+//	}
+type SamplingConfig struct {
+	// Rates is the fraction, between 0 and 1, of events of each type kept;
+	// types not listed are all kept
+	Rates map[EventType]float64
+	// PerMinute caps the events of each type kept per minute, allowing
+	// bursts of up to a minute's worth; types not listed are not limited
+	PerMinute map[EventType]int
+	// KeepNotable exempts notable events, see IsNotable, from Rates. They
+	// still count towards PerMinute.
+	KeepNotable bool
+}
+
+// WithSampling applies a SamplingConfig; it is shorthand for
+// WithSampler(NewSampler(cfg))
+func WithSampling(cfg SamplingConfig) Option {
+	return WithSampler(NewSampler(cfg))
+}
+
+// NewSampler creates a sampler from a SamplingConfig
+func NewSampler(cfg SamplingConfig) Sampler {
+	s := &typeSampler{cfg: cfg, buckets: make(map[EventType]*tokenBucket, len(cfg.PerMinute))}
+	for t, n := range cfg.PerMinute {
+		s.buckets[t] = &tokenBucket{capacity: float64(n), tokens: float64(n), perSecond: float64(n) / 60}
+	}
+	return s
+}
+
+// typeSampler implements SamplingConfig
+type typeSampler struct {
+	cfg     SamplingConfig
+	mu      sync.Mutex
+	buckets map[EventType]*tokenBucket
+}
+
+func (s *typeSampler) Sample(event Event) bool {
+	if rate, ok := s.cfg.Rates[event.Type]; ok && !(s.cfg.KeepNotable && IsNotable(event)) {
+		if !sampleRandom(rate) {
+			return false
+		}
+	}
+
+	bucket := s.buckets[event.Type]
+	if bucket == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bucket.take(time.Now())
+}
+
+// tokenBucket admits up to capacity events at once, refilling at perSecond
+type tokenBucket struct {
+	capacity  float64
+	tokens    float64
+	perSecond float64
+	last      time.Time
+}
+
+// take consumes a token if one is available
+func (b *tokenBucket) take(now time.Time) bool {
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds the tokens accrued since the last call
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	}
+	b.last = now
+}
@@ -0,0 +1,138 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeadSamplerKeepsWholeTraces(t *testing.T) {
+	s := HeadSampler(0.5)
+	kept := 0
+	for i := 0; i < 200; i++ {
+		traceID := fmt.Sprintf("trace-%d", i)
+		first := s.Sample(NewEvent(EventToolCall, "a").WithMetadata("trace_id", traceID))
+		for j := 0; j < 3; j++ {
+			if s.Sample(NewEvent(EventLLMInvoke, "b").WithMetadata("trace_id", traceID)) != first {
+				t.Fatalf("events of %s were sampled differently", traceID)
+			}
+		}
+		if first {
+			kept++
+		}
+	}
+	if kept < 60 || kept > 140 {
+		t.Errorf("expected about half of the traces to be kept, got %d of 200", kept)
+	}
+
+	if HeadSampler(0).Sample(NewEvent(EventToolCall, "x")) || !HeadSampler(1).Sample(NewEvent(EventToolCall, "x")) {
+		t.Error("rates 0 and 1 should drop and keep everything")
+	}
+}
+
+func TestTailSamplerKeepsNotableEvents(t *testing.T) {
+	s := TailSampler{Rate: 0}
+	notable := []Event{
+		NewEvent(EventAPICall, "GET x").WithPayload("blocked", true),
+		NewEvent(EventAPICall, "GET x").WithPayload("enforcement_action", "blocked"),
+		NewEvent(EventToolCall, "x").WithPayload("error", "timeout"),
+		NewEvent(EventToolCall, "x").WithPayload("severity", "Critical"),
+		NewEvent(EventDecision, "x").WithMetadata("policy_decision", "Deny"),
+	}
+	for _, e := range notable {
+		if !s.Sample(e) {
+			t.Errorf("expected %v to be kept", e.Payload)
+		}
+	}
+	if s.Sample(NewEvent(EventToolCall, "x").WithPayload("severity", "low")) {
+		t.Error("expected routine events to be sampled at rate 0")
+	}
+
+	s.Keep = func(e Event) bool { return e.Type == EventDecision }
+	if !s.Sample(NewEvent(EventDecision, "x")) {
+		t.Error("expected Keep to mark events as notable")
+	}
+}
+
+func TestClientSampling(t *testing.T) {
+	api := newFlakyAPI(t)
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithFlushInterval(time.Hour),
+		WithSampler(TailSampler{Rate: 0}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "routine"))
+	client.Track(NewEvent(EventToolCall, "failed").WithPayload("error", "boom"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(api.received(), ","); got != "failed" {
+		t.Errorf("expected only the notable event, got %v", got)
+	}
+
+	stats := client.Stats()
+	if stats.EventsDroppedSampled != 1 || stats.EventsDropped != 1 || stats.EventsTracked[EventToolCall] != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	var buf bytes.Buffer
+	client.Collector().WriteTo(&buf)
+	if !strings.Contains(buf.String(), `trusera_events_dropped_total{reason="sampled"} 1`) {
+		t.Error("expected the sampled metric")
+	}
+
+	if _, err := client.TrackSync(context.Background(), NewEvent(EventToolCall, "sync")); !errors.Is(err, ErrEventDropped) {
+		t.Errorf("expected TrackSync to report the sampled event, got %v", err)
+	}
+}
+
+func TestSamplingConfig(t *testing.T) {
+	s := NewSampler(SamplingConfig{
+		Rates:       map[EventType]float64{EventLLMInvoke: 0, EventDecision: 1},
+		PerMinute:   map[EventType]int{EventDataAccess: 2},
+		KeepNotable: true,
+	})
+
+	if s.Sample(NewEvent(EventLLMInvoke, "chat")) {
+		t.Error("expected LLM invocations to be sampled at rate 0")
+	}
+	if !s.Sample(NewEvent(EventLLMInvoke, "chat").WithPayload("error", "overloaded")) {
+		t.Error("expected KeepNotable to exempt errors from the rate")
+	}
+	if !s.Sample(NewEvent(EventDecision, "d")) || !s.Sample(NewEvent(EventToolCall, "unlisted")) {
+		t.Error("expected listed and unlisted types at rate 1 to be kept")
+	}
+
+	kept := 0
+	for i := 0; i < 5; i++ {
+		if s.Sample(NewEvent(EventDataAccess, "users")) {
+			kept++
+		}
+	}
+	if kept != 2 {
+		t.Errorf("expected 2 data accesses per minute, kept %d", kept)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	b := &tokenBucket{capacity: 60, tokens: 60, perSecond: 1}
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		if !b.take(now) {
+			t.Fatalf("expected a burst of 60, stopped at %d", i)
+		}
+	}
+	if b.take(now) {
+		t.Error("expected the bucket to be empty")
+	}
+	if !b.take(now.Add(time.Second)) || b.take(now.Add(time.Second)) {
+		t.Error("expected one token after a second")
+	}
+	if b.take(now.Add(time.Hour)); b.tokens != 59 {
+		t.Errorf("expected the refill to stop at capacity, got %v tokens", b.tokens+1)
+	}
+}
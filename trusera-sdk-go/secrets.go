@@ -0,0 +1,81 @@
+package trusera
+
+import (
+	"regexp"
+)
+
+// Built-in secret detectors
+var (
+	DetectAWSAccessKey = Detector{
+		Name:    "aws_access_key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	}
+	DetectAWSSecretKey = Detector{
+		Name:    "aws_secret_key",
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["'\s:=]+[A-Za-z0-9/+=]{40}`),
+	}
+	DetectGitHubToken = Detector{
+		Name:    "github_token",
+		Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	}
+	DetectJWT = Detector{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	}
+	DetectPrivateKey = Detector{
+		Name:    "private_key",
+		Pattern: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`),
+	}
+)
+
+// SecretDetectors returns the built-in secret detectors
+func SecretDetectors() []Detector {
+	return []Detector{DetectPrivateKey, DetectAWSAccessKey, DetectAWSSecretKey, DetectGitHubToken, DetectJWT}
+}
+
+// SecretScanOptions configures WithSecretScrubbing
+type SecretScanOptions struct {
+	Detectors []Detector // Defaults to SecretDetectors()
+
+	// EmitAlerts tracks an EventSecretExposure event, linked to the scrubbed
+	// event through parent_event_id, whenever a secret is found
+	EmitAlerts bool
+}
+
+// WithSecretScrubbing masks credentials such as cloud keys, tokens and
+// private keys in every event's payload and metadata before it is buffered.
+// Scrubbed events carry metadata secret_types naming what was found. Like
+// WithRedaction it runs ahead of the other event hooks.
+func WithSecretScrubbing(opts SecretScanOptions) Option {
+	return func(c *Client) {
+		detectors := opts.Detectors
+		if len(detectors) == 0 {
+			detectors = SecretDetectors()
+		}
+		scanner := NewRedactor(detectors...)
+
+		hook := func(e *Event) (*Event, error) {
+			scrubbed, types := scanner.redactEvent(*e, "secret_types")
+			if len(types) > 0 && opts.EmitAlerts {
+				c.Track(secretExposure(scrubbed, types))
+			}
+			return &scrubbed, nil
+		}
+		c.trackHooks = append([]EventHook{hook}, c.trackHooks...)
+	}
+}
+
+// secretExposure builds the alert for secrets found in an event
+func secretExposure(source Event, types []string) Event {
+	alert := NewEvent(EventSecretExposure, "secret_exposure").
+		WithPayload("secret_types", types).
+		WithPayload("source_event_type", string(source.Type)).
+		WithPayload("source_event_name", source.Name).
+		WithMetadata("parent_event_id", source.ID)
+	for _, key := range []string{"trace_id", "span_id", "session_id"} {
+		if v, ok := source.Metadata[key]; ok {
+			alert = alert.WithMetadata(key, v)
+		}
+	}
+	return alert
+}
@@ -0,0 +1,123 @@
+package trusera
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Session groups the events of one agent run or conversation. Events tracked
+// through it, or with TrackCtx and its Context, get the session's
+// session_id, a sequence number and a parent_event_id, so the run can be
+// reconstructed as a tree: the session's start event is the root, and
+// Step makes an event the parent of the events tracked under it.
+type Session struct {
+	client  *Client
+	id      string
+	name    string
+	ctx     context.Context
+	startID string
+	start   time.Time
+	seq     atomic.Int64
+	ended   atomic.Bool
+}
+
+// StartSession starts a session and tracks its start event. A session
+// started within another session's context records it as
+// parent_session_id, and its start event is a child of the current parent
+// event there.
+func (c *Client) StartSession(ctx context.Context, name string) *Session {
+	s := &Session{client: c, id: c.newID(), name: name, start: c.now()}
+
+	event := NewEvent(EventSession, name).WithPayload("action", "start")
+	if parent := sessionFromContext(ctx); parent != nil {
+		event = event.WithMetadata("parent_session_id", parent.id)
+	}
+	if id := ParentEventFromContext(ctx); id != "" {
+		event = event.WithMetadata("parent_event_id", id)
+	}
+	event = event.WithMetadata("session_id", s.id).WithMetadata("sequence", s.next())
+	s.startID = event.ID
+
+	ctx = context.WithValue(ContextWithSessionID(ctx, s.id), activeSessionKey, s)
+	s.ctx = ContextWithParentEvent(ctx, s.startID)
+	c.handleError(c.TrackCtx(s.ctx, event))
+	return s
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Context returns a context carrying the session; events tracked with it
+// are children of the session's start event
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Track tracks an event in the session. Its parent is the parent event of
+// ctx when ctx descends from Context or Step, else the start event.
+func (s *Session) Track(ctx context.Context, event Event) error {
+	return s.client.TrackCtx(s.bind(ctx), event)
+}
+
+// Step tracks an event in the session and returns a context whose events
+// are its children
+func (s *Session) Step(ctx context.Context, event Event) (context.Context, error) {
+	ctx = s.bind(ctx)
+	err := s.client.TrackCtx(ctx, event)
+	return ContextWithParentEvent(ctx, event.ID), err
+}
+
+// End tracks the session's end event with its duration, the number of
+// events in the session and err, if any. Later calls do nothing.
+func (s *Session) End(err error) {
+	if !s.ended.CompareAndSwap(false, true) {
+		return
+	}
+	event := NewEvent(EventSession, s.name).
+		WithPayload("action", "end").
+		WithPayload("duration_ms", float64(s.client.now().Sub(s.start).Microseconds())/1000).
+		WithPayload("events", s.seq.Load()+1)
+	if err != nil {
+		event = event.WithPayload("error", err.Error())
+	}
+	s.client.handleError(s.client.TrackCtx(s.ctx, event))
+	s.client.endBudget(s.id)
+}
+
+// bind attaches the session to ctx unless ctx already belongs to it
+func (s *Session) bind(ctx context.Context) context.Context {
+	if ctx == nil {
+		return s.ctx
+	}
+	if sessionFromContext(ctx) == s {
+		return ctx
+	}
+	ctx = context.WithValue(ContextWithSessionID(ctx, s.id), activeSessionKey, s)
+	return ContextWithParentEvent(ctx, s.startID)
+}
+
+// next returns the session's next sequence number
+func (s *Session) next() int64 {
+	return s.seq.Add(1) - 1
+}
+
+// sessionFromContext returns the Session whose context ctx descends from
+func sessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(activeSessionKey).(*Session)
+	return s
+}
+
+// ContextWithParentEvent returns a context whose events record id as their
+// parent_event_id
+func ContextWithParentEvent(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, parentEventKey, id)
+}
+
+// ParentEventFromContext returns the parent event ID stored in ctx, if any
+func ParentEventFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(parentEventKey).(string)
+	return id
+}
@@ -0,0 +1,95 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSessionBuildsEventTree(t *testing.T) {
+	client := dialClient(t)
+	sess := client.StartSession(context.Background(), "support-chat")
+
+	plan := NewEvent(EventDecision, "plan")
+	stepCtx, err := sess.Step(context.Background(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.TrackCtx(stepCtx, NewEvent(EventToolCall, "search"))
+	client.TrackCtx(sess.Context(), NewEvent(EventLLMInvoke, "answer"))
+	sess.End(errors.New("user left"))
+	sess.End(nil)
+
+	start, _ := trackedEventWhere(client, func(e Event) bool { return e.Type == EventSession && e.Payload["action"] == "start" })
+	search, _ := trackedEvent(client, "search")
+	answer, _ := trackedEvent(client, "answer")
+	end, ok := trackedEventWhere(client, func(e Event) bool { return e.Type == EventSession && e.Payload["action"] == "end" })
+	if !ok {
+		t.Fatal("expected an end event")
+	}
+
+	for _, e := range []Event{start, plan, search, answer, end} {
+		tracked, _ := trackedEventWhere(client, func(x Event) bool { return x.ID == e.ID })
+		if tracked.Metadata["session_id"] != sess.ID() {
+			t.Errorf("%s: expected session_id %s, got %v", e.Name, sess.ID(), tracked.Metadata["session_id"])
+		}
+	}
+	plan, _ = trackedEvent(client, "plan")
+
+	parents := map[string]any{
+		"plan":   start.ID,
+		"search": plan.ID,
+		"answer": start.ID,
+	}
+	for name, want := range parents {
+		e, _ := trackedEvent(client, name)
+		if e.Metadata["parent_event_id"] != want {
+			t.Errorf("%s: parent_event_id = %v, want %v", name, e.Metadata["parent_event_id"], want)
+		}
+	}
+	if _, ok := start.Metadata["parent_event_id"]; ok {
+		t.Error("the start event should be the root")
+	}
+
+	sequences := []any{start.Metadata["sequence"], plan.Metadata["sequence"], search.Metadata["sequence"], answer.Metadata["sequence"], end.Metadata["sequence"]}
+	for i, seq := range sequences {
+		if seq != int64(i) {
+			t.Errorf("expected sequence %d, got %v", i, seq)
+		}
+	}
+	if end.Payload["error"] != "user left" || end.Payload["events"] != int64(5) {
+		t.Errorf("unexpected end payload %v", end.Payload)
+	}
+	if n := len(trackedEvents(client, EventSession)); n != 2 {
+		t.Errorf("End should track once, got %d session events", n)
+	}
+}
+
+func TestNestedSession(t *testing.T) {
+	client := dialClient(t)
+	outer := client.StartSession(context.Background(), "run")
+	stepCtx, _ := outer.Step(outer.Context(), NewEvent(EventToolCall, "delegate"))
+	inner := client.StartSession(stepCtx, "sub-agent")
+
+	delegate, _ := trackedEvent(client, "delegate")
+	start, _ := trackedEvent(client, "sub-agent")
+	if start.Metadata["parent_session_id"] != outer.ID() || start.Metadata["parent_event_id"] != delegate.ID {
+		t.Errorf("unexpected nested start metadata %v", start.Metadata)
+	}
+	if start.Metadata["session_id"] != inner.ID() || start.Metadata["sequence"] != int64(0) {
+		t.Errorf("the nested session should number its own events, got %v", start.Metadata)
+	}
+}
+
+// trackedEvents returns the buffered events of a type
+func trackedEvents(c *Client, typ EventType) []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Event
+	for _, e := range c.events {
+		if e.Type == typ {
+			out = append(out, e)
+		}
+	}
+	return out
+}
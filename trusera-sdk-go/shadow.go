@@ -0,0 +1,49 @@
+package trusera
+
+import "fmt"
+
+// withCandidates puts the candidate rules ahead of the others for ModeShadow.
+// The other rules are named by their index first, so their IDs are the same
+// as outside shadow mode.
+func (o InterceptorOptions) withCandidates() InterceptorOptions {
+	if len(o.CandidateRules) == 0 {
+		return o
+	}
+	rules := make([]Rule, 0, len(o.CandidateRules)+len(o.Rules))
+	for i, rule := range o.CandidateRules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("candidate-%d", i)
+		}
+		rules = append(rules, rule)
+	}
+	for i, rule := range o.Rules {
+		if rule.ID == "" {
+			rule.ID = fmt.Sprintf("rule-%d", i)
+		}
+		rules = append(rules, rule)
+	}
+	o.Rules = rules
+	return o
+}
+
+// shadowed puts a verdict evaluated as ModeBlock in ModeShadow, which
+// records what would be blocked but never rejects. Verdicts of log and warn
+// rules keep their mode, as they would not be blocked either.
+func (v verdict) shadowed() verdict {
+	if v.mode.rejects() {
+		v.mode = ModeShadow
+	}
+	v.shadow = true
+	return v
+}
+
+// isCandidate reports whether a rule is one of the candidate rules put
+// ahead by withCandidates
+func (o InterceptorOptions) isCandidate(rule *Rule) bool {
+	for i := range o.CandidateRules {
+		if rule == &o.Rules[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package trusera
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShadowMode(t *testing.T) {
+	var hits int
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits++ }))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{
+		Enforcement:    ModeShadow,
+		Rules:          []Rule{{Match: "/admin", Action: RuleBlock}, {Match: "/beta", Action: RuleWarn}},
+		CandidateRules: []Rule{{Match: "/export", Action: RuleBlock, Reason: "bulk export"}},
+		BlockPatterns:  []string{"/internal"},
+	})
+	for _, path := range []string{"/admin", "/beta", "/export", "/internal", "/public"} {
+		resp, err := httpClient.Get(backend.URL + path)
+		if err != nil {
+			t.Fatalf("expected shadow mode to allow %s, got %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	if hits != 5 {
+		t.Fatalf("expected every request forwarded, got %d", hits)
+	}
+
+	for _, tc := range []struct {
+		path, action, mode, ruleID string
+		candidate                  bool
+	}{
+		{"/admin", "would_block", "shadow", "rule-0", false},
+		{"/beta", "blocked", "warn", "rule-1", false},
+		{"/export", "would_block", "shadow", "candidate-0", true},
+		{"/internal", "would_block", "shadow", "", false},
+		{"/public", "allowed", "shadow", "", false},
+	} {
+		e, ok := trackedEvent(client, "GET "+backend.URL+tc.path)
+		if !ok {
+			t.Fatalf("no event for %s", tc.path)
+		}
+		if e.Payload["enforcement_action"] != tc.action || e.Metadata["enforcement_mode"] != tc.mode {
+			t.Errorf("%s: expected %s in %s mode, got %v %v", tc.path, tc.action, tc.mode, e.Payload, e.Metadata)
+		}
+		if id, _ := e.Payload["rule_id"].(string); id != tc.ruleID {
+			t.Errorf("%s: expected rule %q, got %q", tc.path, tc.ruleID, id)
+		}
+		if (e.Payload["candidate_rule"] == true) != tc.candidate {
+			t.Errorf("%s: unexpected candidate_rule %v", tc.path, e.Payload["candidate_rule"])
+		}
+	}
+}
+
+func TestShadowModeIgnoresCandidatesOtherwise(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}), WithDecisionLog())
+	defer client.Close()
+
+	opts := InterceptorOptions{
+		Enforcement:    ModeBlock,
+		CandidateRules: []Rule{{ID: "no-export", Match: "/export", Action: RuleBlock}},
+	}
+	httpClient := CreateInterceptedClient(client, opts)
+	resp, err := httpClient.Get(backend.URL + "/export")
+	if err != nil {
+		t.Fatalf("expected candidate rules ignored in block mode, got %v", err)
+	}
+	resp.Body.Close()
+
+	opts.Enforcement = ModeShadow
+	resp, err = CreateInterceptedClient(client, opts).Get(backend.URL + "/export")
+	if err != nil {
+		t.Fatalf("expected shadow mode to allow the request, got %v", err)
+	}
+	resp.Body.Close()
+
+	events := trackedEvents(client, EventPolicyDecision)
+	if len(events) != 2 || events[0].Payload["outcome"] != DecisionAllow ||
+		events[1].Payload["outcome"] != DecisionWouldBlock || events[1].Payload["rule_id"] != "no-export" {
+		t.Errorf("unexpected decisions %v", events)
+	}
+}
+
+func TestShadowModeRemoteCandidates(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	client.ApplyPolicy(&RemotePolicy{
+		Version:        "v2",
+		Enforcement:    ModeShadow,
+		CandidateRules: []Rule{{ID: "remote-candidate", Match: "/upload", Action: RuleBlock}},
+	})
+
+	resp, err := CreateInterceptedClient(client, InterceptorOptions{Enforcement: ModeBlock}).Get(backend.URL + "/upload")
+	if err != nil {
+		t.Fatalf("expected the remote shadow mode to allow the request, got %v", err)
+	}
+	resp.Body.Close()
+	e, _ := trackedEvent(client, "GET "+backend.URL+"/upload")
+	if e.Payload["enforcement_action"] != "would_block" || e.Payload["rule_id"] != "remote-candidate" {
+		t.Errorf("unexpected event %v", e.Payload)
+	}
+}
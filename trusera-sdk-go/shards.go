@@ -0,0 +1,117 @@
+package trusera
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// WithBufferShards splits the event buffer into n shards, each with its own
+// lock, so that hundreds of goroutines tracking events at once do not queue
+// on a single mutex. Events are spread across the shards in turn and carry
+// a ticket, by which a flush merges them back into the order they were
+// tracked in. Each shard holds up to the buffer size divided by n; when a
+// shard is full, DropNewest drops the new event and any other overflow
+// policy drops the shard's oldest.
+//
+// Shards are bypassed, and the single buffer used, with the persistent
+// queue, WithOrderedDelivery, WithMaxBatchBytes, WithMaxBatchAge and the
+// BlockCaller and SpillToDisk overflow policies, which all need one view of
+// the buffer.
+func WithBufferShards(n int) Option {
+	return func(c *Client) {
+		if n > 1 {
+			c.shards = make([]bufferShard, n)
+		}
+	}
+}
+
+// bufferShard is one part of a sharded buffer
+type bufferShard struct {
+	mu      sync.Mutex
+	entries []shardEntry
+	_       [32]byte // Keeps neighbouring shards' locks off one cache line
+}
+
+// shardEntry is a buffered event and its place in the tracking order
+type shardEntry struct {
+	ticket uint64
+	event  Event
+}
+
+// useShards drops the shards if the client's options need a single buffer
+func (c *Client) useShards() {
+	if c.queue != nil || c.ordered != nil || c.maxBatchBytes > 0 || c.maxBatchAge > 0 ||
+		c.overflow == BlockCaller || c.overflow == SpillToDisk {
+		c.shards = nil
+	}
+}
+
+// enqueueShard buffers a prepared event in the next shard. An event over
+// the event rate is handled like one overflowing its shard.
+func (c *Client) enqueueShard(event Event, limited bool) (Event, bool) {
+	ticket := c.tickets.Add(1)
+	s := &c.shards[ticket%uint64(len(c.shards))]
+	limit := max(c.maxBuffer/len(c.shards), 1)
+	reason := DropReasonOverflow
+	if limited {
+		reason = DropReasonRateLimited
+	}
+
+	s.mu.Lock()
+	if len(s.entries) >= limit || limited {
+		if c.overflow == DropNewest || len(s.entries) == 0 {
+			s.mu.Unlock()
+			c.drop(reason, event)
+			return event, false
+		}
+		c.drop(reason, s.entries[0].event)
+		s.entries[0] = shardEntry{}
+		s.entries = s.entries[1:]
+		c.sharded.Add(-1)
+	}
+	s.entries = append(s.entries, shardEntry{ticket: ticket, event: event})
+	s.mu.Unlock()
+
+	if c.sharded.Add(1) >= int64(c.flushSize) {
+		c.flushAsync()
+	}
+	return event, true
+}
+
+// drainShards empties the shards, returning their events in tracking order
+func (c *Client) drainShards() []Event {
+	var entries []shardEntry
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		entries = append(entries, s.entries...)
+		c.sharded.Add(-int64(len(s.entries)))
+		clear(s.entries)
+		s.entries = s.entries[:0]
+		s.mu.Unlock()
+	}
+	// Sorting small keys rather than the entries saves moving whole events
+	type key struct {
+		ticket uint64
+		index  int
+	}
+	keys := make([]key, len(entries))
+	for i, e := range entries {
+		keys[i] = key{e.ticket, i}
+	}
+	slices.SortFunc(keys, func(a, b key) int {
+		return cmp.Compare(a.ticket, b.ticket)
+	})
+
+	events := make([]Event, len(entries))
+	for i, k := range keys {
+		events[i] = entries[k.index].event
+	}
+	return events
+}
+
+// shardedDepth returns the number of events in the shards
+func (c *Client) shardedDepth() int {
+	return int(c.sharded.Load())
+}
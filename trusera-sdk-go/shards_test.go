@@ -0,0 +1,84 @@
+package trusera
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBufferShardsKeepTrackingOrder(t *testing.T) {
+	sink := &memorySink{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink), WithBufferShards(4))
+	defer client.Close()
+
+	var want []string
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("event-%d", i)
+		want = append(want, name)
+		client.Track(NewEvent(EventToolCall, name))
+	}
+	if depth := client.Stats().BufferDepth; depth != 10 {
+		t.Errorf("expected 10 buffered events, got %d", depth)
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := sink.names(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected events in tracking order, got %v", got)
+	}
+}
+
+func TestBufferShardsConcurrent(t *testing.T) {
+	sink := &memorySink{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink), WithBufferShards(8))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 40; i++ {
+				client.Track(NewEvent(EventToolCall, "concurrent"))
+			}
+		}()
+	}
+	wg.Wait()
+	client.Close()
+
+	if n := len(sink.names()); n != 2000 {
+		t.Errorf("expected 2000 events delivered, got %d", n)
+	}
+}
+
+func TestBufferShardsOverflow(t *testing.T) {
+	sink := &memorySink{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink),
+		WithBufferShards(2), WithMaxBufferSize(4), WithOverflowPolicy(DropNewest))
+	defer client.Close()
+
+	for i := 0; i < 6; i++ {
+		client.Track(NewEvent(EventToolCall, fmt.Sprintf("event-%d", i)))
+	}
+	if n := client.Stats().EventsDroppedOverflow; n != 2 {
+		t.Errorf("expected 2 events dropped, got %d", n)
+	}
+	client.Flush()
+	if got := fmt.Sprint(sink.names()); got != "[event-0 event-1 event-2 event-3]" {
+		t.Errorf("expected the newest events dropped, got %s", got)
+	}
+}
+
+func TestBufferShardsBypassed(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithBufferShards(4), WithOrderedDelivery())
+	defer client.Close()
+
+	if client.shards != nil {
+		t.Error("expected ordered delivery to use the single buffer")
+	}
+	client.Track(NewEvent(EventToolCall, "ordered"))
+	if _, ok := trackedEvent(client, "ordered"); !ok {
+		t.Error("expected the event in the single buffer")
+	}
+}
@@ -0,0 +1,170 @@
+package trusera
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Signature algorithms
+const (
+	SigEd25519    = "ed25519"
+	SigHMACSHA256 = "hmac-sha256"
+)
+
+// ErrInvalidSignature is returned by VerifyEvent when an event is unsigned
+// or was modified after it was signed
+var ErrInvalidSignature = errors.New("invalid event signature")
+
+// Signature is an event's signature over its canonical form, see
+// CanonicalEvent
+type Signature struct {
+	Alg   string `json:"alg"`
+	KeyID string `json:"kid,omitempty"`
+	Value string `json:"sig"` // Base64 (standard encoding)
+}
+
+// Signer signs the canonical form of events
+type Signer interface {
+	Sign(data []byte) (Signature, error)
+}
+
+// Verifier checks signatures made by a Signer
+type Verifier interface {
+	Verify(data []byte, sig Signature) error
+}
+
+// HMACSigner signs events with a shared secret, e.g. one per agent. The
+// verifier needs the same key.
+type HMACSigner struct {
+	KeyID string
+	Key   []byte
+}
+
+// Sign returns the HMAC-SHA256 of data
+func (s HMACSigner) Sign(data []byte) (Signature, error) {
+	mac := hmac.New(sha256.New, s.Key)
+	mac.Write(data)
+	return Signature{Alg: SigHMACSHA256, KeyID: s.KeyID, Value: base64.StdEncoding.EncodeToString(mac.Sum(nil))}, nil
+}
+
+// Verify checks an HMAC-SHA256 signature of data
+func (s HMACSigner) Verify(data []byte, sig Signature) error {
+	if sig.Alg != SigHMACSHA256 || sig.KeyID != s.KeyID {
+		return ErrInvalidSignature
+	}
+	want, _ := s.Sign(data)
+	if !hmac.Equal([]byte(want.Value), []byte(sig.Value)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Ed25519Signer signs events with a private key, so that auditors can
+// verify them with the public key alone, see Ed25519Verifier
+type Ed25519Signer struct {
+	KeyID string
+	Key   ed25519.PrivateKey
+}
+
+// Sign returns the Ed25519 signature of data
+func (s Ed25519Signer) Sign(data []byte) (Signature, error) {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return Signature{}, errors.New("invalid Ed25519 private key")
+	}
+	return Signature{Alg: SigEd25519, KeyID: s.KeyID, Value: base64.StdEncoding.EncodeToString(ed25519.Sign(s.Key, data))}, nil
+}
+
+// Verify checks a signature with the signer's public key
+func (s Ed25519Signer) Verify(data []byte, sig Signature) error {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return errors.New("invalid Ed25519 private key")
+	}
+	return Ed25519Verifier{KeyID: s.KeyID, Key: s.Key.Public().(ed25519.PublicKey)}.Verify(data, sig)
+}
+
+// Ed25519Verifier checks signatures made by an Ed25519Signer
+type Ed25519Verifier struct {
+	KeyID string
+	Key   ed25519.PublicKey
+}
+
+// Verify checks an Ed25519 signature of data
+func (v Ed25519Verifier) Verify(data []byte, sig Signature) error {
+	if sig.Alg != SigEd25519 || sig.KeyID != v.KeyID || len(v.Key) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Value)
+	if err != nil || !ed25519.Verify(v.Key, data, raw) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// WithEventSigning signs every tracked event, so that auditors can verify
+// with VerifyEvent that records were not modified after they were emitted.
+// Events are signed last, after the event hooks; send hooks that modify
+// events invalidate their signatures.
+func WithEventSigning(s Signer) Option {
+	return func(c *Client) {
+		c.signer = s
+	}
+}
+
+// CanonicalEvent returns the bytes an event's signature covers: its JSON
+// encoding without the signature and delivery sequence, with object keys sorted, no whitespace
+// and no HTML escaping. Numbers keep their JSON text, so an event decoded
+// from a received batch has the same canonical form as the one sent.
+func CanonicalEvent(e Event) ([]byte, error) {
+	e.Signature, e.Stream, e.Sequence = nil, "", 0
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// SignEvent signs an event in place
+func SignEvent(e *Event, s Signer) error {
+	data, err := CanonicalEvent(*e)
+	if err != nil {
+		return err
+	}
+	sig, err := s.Sign(data)
+	if err != nil {
+		return fmt.Errorf("failed to sign event: %w", err)
+	}
+	e.Signature = &sig
+	return nil
+}
+
+// VerifyEvent checks an event's signature, returning ErrInvalidSignature
+// if it is missing or does not match the event
+func VerifyEvent(e Event, v Verifier) error {
+	if e.Signature == nil {
+		return ErrInvalidSignature
+	}
+	data, err := CanonicalEvent(e)
+	if err != nil {
+		return err
+	}
+	return v.Verify(data, *e.Signature)
+}
@@ -0,0 +1,92 @@
+package trusera
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanonicalEvent(t *testing.T) {
+	e := NewEvent(EventToolCall, "a<b>").WithPayload("z", 1).WithPayload("a", 0.5)
+	e.ID, e.Timestamp = "id-1", "2026-01-02T03:04:05Z"
+	e.Metadata = nil
+	e.Signature = &Signature{Alg: SigHMACSHA256, Value: "x"}
+
+	data, err := CanonicalEvent(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"id-1","name":"a<b>","payload":{"a":0.5,"z":1},"timestamp":"2026-01-02T03:04:05Z","type":"tool_call"}`
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+}
+
+func TestSignAndVerifyEvent(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	for _, tc := range []struct {
+		signer   Signer
+		verifier Verifier
+	}{
+		{HMACSigner{KeyID: "agent-1", Key: []byte("secret")}, HMACSigner{KeyID: "agent-1", Key: []byte("secret")}},
+		{Ed25519Signer{KeyID: "agent-1", Key: priv}, Ed25519Verifier{KeyID: "agent-1", Key: pub}},
+		{Ed25519Signer{KeyID: "agent-1", Key: priv}, Ed25519Signer{KeyID: "agent-1", Key: priv}},
+	} {
+		e := NewEvent(EventToolCall, "refund").WithPayload("amount", 19.99)
+		if err := SignEvent(&e, tc.signer); err != nil {
+			t.Fatal(err)
+		}
+
+		// Verification survives the trip through a batch
+		data, _ := json.Marshal(e)
+		var received Event
+		json.Unmarshal(data, &received)
+		if err := VerifyEvent(received, tc.verifier); err != nil {
+			t.Errorf("%s: expected a valid signature, got %v", e.Signature.Alg, err)
+		}
+
+		received.Payload["amount"] = 1999.0
+		if err := VerifyEvent(received, tc.verifier); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected tampering to be detected, got %v", e.Signature.Alg, err)
+		}
+	}
+
+	e := NewEvent(EventToolCall, "x")
+	if err := VerifyEvent(e, HMACSigner{Key: []byte("k")}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected an unsigned event to fail, got %v", err)
+	}
+	SignEvent(&e, HMACSigner{KeyID: "agent-1", Key: []byte("k")})
+	if err := VerifyEvent(e, HMACSigner{KeyID: "agent-2", Key: []byte("k")}); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected another agent's key to fail, got %v", err)
+	}
+}
+
+func TestClientSignsEvents(t *testing.T) {
+	signer := HMACSigner{KeyID: "agent-1", Key: []byte("secret")}
+	client := NewClient("test-key",
+		WithFlushInterval(time.Hour),
+		WithEventHook(func(e *Event) (*Event, error) {
+			out := e.WithPayload("hooked", true)
+			return &out, nil
+		}),
+		WithEventSigning(signer))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "search"))
+	e, _ := trackedEvent(client, "search")
+	if e.Signature == nil || e.Payload["hooked"] != true {
+		t.Fatalf("expected a signed event after the hooks, got %+v", e)
+	}
+	if err := VerifyEvent(e, signer); err != nil {
+		t.Error(err)
+	}
+
+	bad := NewClient("test-key", WithFlushInterval(time.Hour), WithEventSigning(Ed25519Signer{}))
+	defer bad.Close()
+	if _, err := bad.TrackSync(context.Background(), NewEvent(EventToolCall, "x")); err == nil {
+		t.Error("expected a signing failure to be reported")
+	}
+}
@@ -0,0 +1,96 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Sink receives batches of flushed events. Send must be safe for concurrent
+// use.
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface
+type SinkFunc func(ctx context.Context, events []Event) error
+
+// Send calls f(ctx, events)
+func (f SinkFunc) Send(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+// namedSink is a secondary sink registered with WithSink
+type namedSink struct {
+	name string
+	sink Sink
+}
+
+// WithSink fans every flushed batch out to an additional sink, such as a
+// local file or a message queue, alongside the Trusera API. Secondary sinks
+// are independent of the primary and of each other: a failing sink does not
+// stop the others, its errors are returned by Flush without the batch being
+// retried or dead-lettered, and its deliveries are counted under name in
+// Stats().Sinks.
+func WithSink(name string, s Sink) Option {
+	return func(c *Client) {
+		c.sinks = append(c.sinks, namedSink{name: name, sink: s})
+	}
+}
+
+// WithPrimarySink replaces the Trusera API as the primary sink: the one whose
+// delivery decides whether a batch was sent. Retries, the circuit breaker,
+// dead letters and the persistent queue apply to it as they do to the API.
+// Errors are retried when they are transient, e.g. wrap a *url.Error.
+func WithPrimarySink(s Sink) Option {
+	return func(c *Client) {
+		c.primary = s
+	}
+}
+
+// WithAPISink keeps sending batches to the Trusera API as a secondary sink
+// named "trusera" after WithPrimarySink replaced it. Requests use the
+// client's credentials and compression but are not retried.
+func WithAPISink() Option {
+	return func(c *Client) {
+		c.sinks = append(c.sinks, namedSink{name: "trusera", sink: SinkFunc(func(ctx context.Context, events []Event) error {
+			body, err := c.marshalBatch(c.agentID, events)
+			if err != nil {
+				return err
+			}
+			_, err = c.postNegotiated(ctx, body)
+			return err
+		})})
+	}
+}
+
+// fanOut sends a batch to every secondary sink, returning their joined errors
+func (c *Client) fanOut(ctx context.Context, events []Event) error {
+	ctx = c.sinkContext(ctx, events)
+	var errs []error
+	for _, s := range c.sinks {
+		err := s.sink.Send(ctx, events)
+		c.metrics.observeSink(s.name, len(events), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", s.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sinkContext carries the agent ID and the batch's project to a Sink
+func (c *Client) sinkContext(ctx context.Context, events []Event) context.Context {
+	ctx = context.WithValue(ctx, agentIDKey, c.agentID)
+	if len(events) > 0 && projectOf(events[0]) != "" {
+		ctx = ContextWithProject(ctx, projectOf(events[0]))
+	}
+	return ctx
+}
+
+// SinkStats counts the deliveries to one secondary sink
+type SinkStats struct {
+	EventsSent   uint64
+	EventsFailed uint64
+	Failures     uint64 // Batches the sink failed to accept
+	LastError    string
+}
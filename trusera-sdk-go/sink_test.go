@@ -0,0 +1,122 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySink records the batches it receives and fails while fail is set
+type memorySink struct {
+	mu      sync.Mutex
+	fail    error
+	batches [][]Event
+}
+
+func (s *memorySink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail != nil {
+		return s.fail
+	}
+	s.batches = append(s.batches, append([]Event(nil), events...))
+	return nil
+}
+
+func (s *memorySink) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, batch := range s.batches {
+		for _, e := range batch {
+			names = append(names, e.Name)
+		}
+	}
+	return names
+}
+
+func TestSinksFanOut(t *testing.T) {
+	api := newFlakyAPI(t)
+	archive := &memorySink{}
+	broken := &memorySink{fail: errors.New("broker unavailable")}
+
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithFlushInterval(time.Hour),
+		WithSink("archive", archive),
+		WithSink("kafka", broken))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b"))
+	err := client.Flush()
+	if err == nil || !strings.Contains(err.Error(), "sink kafka: broker unavailable") {
+		t.Errorf("expected the failing sink's error, got %v", err)
+	}
+
+	if got := strings.Join(api.received(), ","); got != "a,b" {
+		t.Errorf("a failing sink should not affect the API, got %q", got)
+	}
+	if got := strings.Join(archive.names(), ","); got != "a,b" {
+		t.Errorf("a failing sink should not affect the others, got %q", got)
+	}
+
+	stats := client.Stats()
+	if stats.EventsFlushed != 2 || stats.EventsDropped != 0 {
+		t.Errorf("sink failures should not count as dropped, got %+v", stats)
+	}
+	if s := stats.Sinks["archive"]; s.EventsSent != 2 || s.Failures != 0 {
+		t.Errorf("unexpected archive stats %+v", s)
+	}
+	if s := stats.Sinks["kafka"]; s.EventsFailed != 2 || s.Failures != 1 || s.LastError != "broker unavailable" {
+		t.Errorf("unexpected kafka stats %+v", s)
+	}
+
+	var b strings.Builder
+	client.Collector().WriteTo(&b)
+	if !strings.Contains(b.String(), `trusera_sink_events_total{sink="kafka",result="failed"} 2`) {
+		t.Errorf("expected sink metrics, got\n%s", b.String())
+	}
+}
+
+func TestPrimarySinkReplacesAPI(t *testing.T) {
+	api := newFlakyAPI(t)
+	attempts := 0
+	primary := &memorySink{}
+	client := NewClient("test-key",
+		WithBaseURL(api.server.URL),
+		WithFlushInterval(time.Hour),
+		WithRetry(RetryPolicy{InitialBackoff: time.Millisecond}),
+		WithPrimarySink(SinkFunc(func(ctx context.Context, events []Event) error {
+			attempts++
+			if attempts == 1 {
+				return &url.Error{Op: "Post", URL: "file:///events", Err: errors.New("temporarily unavailable")}
+			}
+			return primary.Send(ctx, events)
+		})),
+		WithAPISink())
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "x"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if attempts != 2 || strings.Join(primary.names(), ",") != "x" {
+		t.Errorf("expected a retried delivery to the primary sink, got %d attempts and %v", attempts, primary.names())
+	}
+	if got := api.received(); len(got) != 1 || got[0] != "x" {
+		t.Errorf("expected the API sink to receive the batch, got %v", got)
+	}
+	stats := client.Stats()
+	if stats.FlushRetries != 1 || stats.Sinks["trusera"].EventsSent != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if _, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "sync")); err != nil {
+		t.Errorf("TrackSync through the primary sink failed: %v", err)
+	}
+}
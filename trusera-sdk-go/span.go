@@ -0,0 +1,87 @@
+package trusera
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Span times an operation and tracks it as one event when it ends:
+//
+//	span := client.StartSpan(trusera.EventToolCall, "search")
+//	defer func() { span.End(err) }()
+//
+// The event gets started_at and ended_at (RFC 3339 with nanoseconds),
+// duration_ms, and a status of "ok" or "error" with the error message.
+type Span struct {
+	client *Client
+	ctx    context.Context
+	start  time.Time
+
+	mu    sync.Mutex
+	event Event
+	ended bool
+}
+
+// StartSpan starts a span for an event of the given type
+func (c *Client) StartSpan(eventType EventType, name string) *Span {
+	return c.StartSpanCtx(context.Background(), eventType, name)
+}
+
+// StartSpanCtx starts a span whose event is enriched from ctx like
+// TrackCtx when it ends
+func (c *Client) StartSpanCtx(ctx context.Context, eventType EventType, name string) *Span {
+	return &Span{client: c, ctx: ctx, start: c.now(), event: NewEvent(eventType, name)}
+}
+
+// ID returns the ID of the span's event
+func (s *Span) ID() string {
+	return s.event.ID
+}
+
+// Context returns a context derived from the span's whose events record the
+// span as their parent_event_id
+func (s *Span) Context() context.Context {
+	return ContextWithParentEvent(s.ctx, s.event.ID)
+}
+
+// SetPayload adds a payload field to the span's event
+func (s *Span) SetPayload(key string, value any) *Span {
+	s.mu.Lock()
+	s.event = s.event.WithPayload(key, value)
+	s.mu.Unlock()
+	return s
+}
+
+// SetMetadata adds a metadata field to the span's event
+func (s *Span) SetMetadata(key string, value any) *Span {
+	s.mu.Lock()
+	s.event = s.event.WithMetadata(key, value)
+	s.mu.Unlock()
+	return s
+}
+
+// End records the span's duration and err, if any, and tracks its event.
+// Later calls do nothing. It returns the error of TrackCtx.
+func (s *Span) End(err error) error {
+	end := s.client.now()
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return nil
+	}
+	s.ended = true
+	event := s.event.
+		WithPayload("started_at", s.start.UTC().Format(time.RFC3339Nano)).
+		WithPayload("ended_at", end.UTC().Format(time.RFC3339Nano)).
+		WithPayload("duration_ms", float64(end.Sub(s.start).Microseconds())/1000)
+	if err != nil {
+		event = event.WithPayload("status", "error").WithPayload("error", err.Error())
+	} else {
+		event = event.WithPayload("status", "ok")
+	}
+	s.mu.Unlock()
+
+	return s.client.TrackCtx(s.ctx, event)
+}
@@ -0,0 +1,56 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpanRecordsDuration(t *testing.T) {
+	client := dialClient(t)
+
+	span := client.StartSpan(EventToolCall, "search").SetPayload("query", "weather")
+	time.Sleep(5 * time.Millisecond)
+	if err := span.End(nil); err != nil {
+		t.Fatal(err)
+	}
+	span.End(errors.New("ignored"))
+
+	e, ok := trackedEvent(client, "search")
+	if !ok {
+		t.Fatal("expected the span's event")
+	}
+	if e.ID != span.ID() || e.Payload["status"] != "ok" || e.Payload["query"] != "weather" {
+		t.Errorf("unexpected payload %v", e.Payload)
+	}
+	if d, _ := e.Payload["duration_ms"].(float64); d < 5 {
+		t.Errorf("expected at least 5ms, got %v", d)
+	}
+	start, err1 := time.Parse(time.RFC3339Nano, e.Payload["started_at"].(string))
+	end, err2 := time.Parse(time.RFC3339Nano, e.Payload["ended_at"].(string))
+	if err1 != nil || err2 != nil || end.Sub(start) < 5*time.Millisecond {
+		t.Errorf("unexpected timestamps %v %v", e.Payload["started_at"], e.Payload["ended_at"])
+	}
+	if n := len(trackedEvents(client, EventToolCall)); n != 1 {
+		t.Errorf("End should track once, got %d events", n)
+	}
+}
+
+func TestSpanErrorAndParent(t *testing.T) {
+	client := dialClient(t)
+	ctx := ContextWithSessionID(context.Background(), "sess-1")
+
+	parent := client.StartSpanCtx(ctx, EventDecision, "plan")
+	child := client.StartSpanCtx(parent.Context(), EventToolCall, "fetch")
+	child.End(errors.New("timeout"))
+	parent.End(nil)
+
+	e, _ := trackedEvent(client, "fetch")
+	if e.Payload["status"] != "error" || e.Payload["error"] != "timeout" {
+		t.Errorf("unexpected payload %v", e.Payload)
+	}
+	if e.Metadata["parent_event_id"] != parent.ID() || e.Metadata["session_id"] != "sess-1" {
+		t.Errorf("unexpected metadata %v", e.Metadata)
+	}
+}
@@ -0,0 +1,719 @@
+package trusera
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DBOptions configures database/sql instrumentation
+type DBOptions struct {
+	Enforcement EnforcementMode
+
+	// Rules and ExcludePatterns are matched against the query fingerprint,
+	// e.g. "SELECT * FROM users WHERE id = ?"
+	Rules           []Rule
+	ExcludePatterns []string
+
+	// BlockStatements lists statement types to reject, e.g. "DROP", "TRUNCATE".
+	// Each statement of a query is checked, after any WITH clause.
+	BlockStatements []string
+	// BlockUnboundedWrites rejects DELETE and UPDATE statements without a
+	// WHERE clause
+	BlockUnboundedWrites bool
+}
+
+var (
+	wrapDBMu    sync.Mutex
+	wrapDBCount int
+)
+
+// WrapDB registers a database/sql driver that instruments driverName and
+// returns the name to open it with:
+//
+//	name, err := trusera.WrapDB("postgres", client, trusera.DBOptions{
+//		Enforcement:          trusera.ModeBlock,
+//		BlockUnboundedWrites: true,
+//	})
+//	db, err := sql.Open(name, dsn)
+//
+// Every statement is tracked as an EventDataAccess with a normalized
+// fingerprint of the query (literals replaced by ?), its statement type and
+// tables, the rows returned or affected and its duration. Query arguments
+// are never recorded. In block and allow-list modes blocked statements fail
+// without reaching the database.
+func WrapDB(driverName string, truseraClient *Client, opts DBOptions) (string, error) {
+	// sql.Open looks up the driver without connecting
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to look up driver %q: %w", driverName, err)
+	}
+	base := db.Driver()
+	db.Close()
+
+	wrapDBMu.Lock()
+	defer wrapDBMu.Unlock()
+	wrapDBCount++
+	name := fmt.Sprintf("trusera-%s-%d", driverName, wrapDBCount)
+	sql.Register(name, WrapDriver(base, truseraClient, opts))
+	return name, nil
+}
+
+// WrapDriver instruments a database/sql driver like WrapDB, for use with
+// sql.Register
+func WrapDriver(d driver.Driver, truseraClient *Client, opts DBOptions) driver.Driver {
+	return &dbDriver{base: d, db: newDBInterceptor(truseraClient, opts)}
+}
+
+// WrapConnector instruments a connector like WrapDB, for use with sql.OpenDB
+func WrapConnector(c driver.Connector, truseraClient *Client, opts DBOptions) driver.Connector {
+	return &dbConnector{base: c, driver: &dbDriver{base: c.Driver(), db: newDBInterceptor(truseraClient, opts)}}
+}
+
+// dbInterceptor checks and tracks statements
+type dbInterceptor struct {
+	t    *interceptingTransport
+	opts DBOptions
+}
+
+func newDBInterceptor(truseraClient *Client, opts DBOptions) *dbInterceptor {
+	return &dbInterceptor{
+		t: &interceptingTransport{client: truseraClient, opts: InterceptorOptions{
+			Enforcement:     opts.Enforcement,
+			Rules:           opts.Rules,
+			ExcludePatterns: opts.ExcludePatterns,
+		}},
+		opts: opts,
+	}
+}
+
+// dbCall is one statement execution in progress
+type dbCall struct {
+	db    *dbInterceptor
+	ctx   context.Context
+	event Event
+	start time.Time
+}
+
+// begin checks a statement, returning nil for excluded statements. Blocked
+// statements are tracked and rejected with an error.
+func (d *dbInterceptor) begin(ctx context.Context, query string) (*dbCall, error) {
+	fingerprint := fingerprintQuery(query)
+	v := d.t.evaluate(fingerprint)
+	if v.excluded {
+		return nil, nil
+	}
+	v = d.t.canary(ctx, fingerprint, v)
+
+	statement := statementType(fingerprint)
+	event := NewEvent(EventDataAccess, "sql "+fingerprint).
+		WithPayload("protocol", "sql").
+		WithPayload("statement", statement).
+		WithPayload("fingerprint", fingerprint)
+	if tables := queryTables(fingerprint); len(tables) > 0 {
+		event = event.WithPayload("tables", tables)
+	}
+	event = v.describe(event)
+
+	blocked := v.blocked
+	var statementReasons []string
+	if v.rule == nil {
+		if reason := d.statementBlocked(fingerprint); reason != "" {
+			blocked = true
+			statementReasons = []string{reason}
+			event = event.WithPayload("block_reason", reason)
+		}
+	}
+	event = event.WithPayload("blocked", blocked)
+
+	d.t.logDecision(ctx, "sql", "", fingerprint, v, blocked, blockCause{BlockReasonStatement, statementReasons})
+	if blocked {
+		event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+		switch v.mode {
+		case ModeBlock, ModeAllowList:
+			d.t.client.Track(d.t.client.enrich(ctx, event))
+			return nil, v.blockedError(fingerprint, blockCause{BlockReasonStatement, statementReasons})
+		case ModeWarn:
+			event = event.WithMetadata("warning", "statement matches block rules but allowed in warn mode")
+		}
+	} else {
+		event = event.WithPayload("enforcement_action", "allowed")
+	}
+	return &dbCall{db: d, ctx: ctx, event: event, start: time.Now()}, nil
+}
+
+// statementBlocked returns why a query is blocked by its statement types,
+// if it is. Every statement of a multi-statement query is checked, by the
+// statement that follows any common table expressions.
+func (d *dbInterceptor) statementBlocked(fingerprint string) string {
+	for _, stmt := range splitStatements(fingerprint) {
+		statement, body := mainStatement(stmt)
+		for _, s := range d.opts.BlockStatements {
+			if strings.EqualFold(s, statement) {
+				return statement + " statements are blocked"
+			}
+		}
+		if d.opts.BlockUnboundedWrites && (statement == "DELETE" || statement == "UPDATE") && !whereClause.MatchString(body) {
+			return statement + " without WHERE"
+		}
+	}
+	return ""
+}
+
+// end tracks a finished statement; rows is the number of rows returned or
+// affected, or -1 when unknown
+func (c *dbCall) end(rows int64, err error) {
+	if c == nil {
+		return
+	}
+	event := c.event.WithPayload("duration_ms", float64(time.Since(c.start).Microseconds())/1000)
+	if rows >= 0 {
+		event = event.WithPayload("rows", rows)
+	}
+	if err != nil {
+		event = event.WithPayload("error", err.Error())
+	}
+	c.db.t.client.Track(c.db.t.client.enrich(c.ctx, event))
+}
+
+// exec runs an Exec through the interceptor
+func (d *dbInterceptor) exec(ctx context.Context, query string, run func() (driver.Result, error)) (driver.Result, error) {
+	call, err := d.begin(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := run()
+	if err == driver.ErrSkip {
+		return nil, err // database/sql retries through a prepared statement
+	}
+	rows := int64(-1)
+	if err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			rows = n
+		}
+	}
+	call.end(rows, err)
+	return res, err
+}
+
+// query runs a Query through the interceptor; the event is tracked once the
+// rows are closed
+func (d *dbInterceptor) query(ctx context.Context, q string, run func() (driver.Rows, error)) (driver.Rows, error) {
+	call, err := d.begin(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := run()
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	if err != nil {
+		call.end(-1, err)
+		return nil, err
+	}
+	if call == nil {
+		return rows, nil
+	}
+	return &dbRows{Rows: rows, call: call}, nil
+}
+
+// dbDriver wraps a driver.Driver
+type dbDriver struct {
+	base driver.Driver
+	db   *dbInterceptor
+}
+
+func (d *dbDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.base.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &dbConn{Conn: conn, db: d.db}, nil
+}
+
+func (d *dbDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	if dc, ok := d.base.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &dbConnector{base: c, driver: d}, nil
+	}
+	return &dbConnector{dsn: dsn, driver: d}, nil
+}
+
+// dbConnector wraps a driver.Connector, or opens a DSN when base is nil
+type dbConnector struct {
+	base   driver.Connector
+	dsn    string
+	driver *dbDriver
+}
+
+func (c *dbConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.base == nil {
+		return c.driver.Open(c.dsn)
+	}
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &dbConn{Conn: conn, db: c.driver.db}, nil
+}
+
+func (c *dbConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the wrapped connector if it holds resources
+func (c *dbConnector) Close() error {
+	if closer, ok := c.base.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// dbConn wraps a driver.Conn, forwarding the optional interfaces the
+// underlying connection implements
+type dbConn struct {
+	driver.Conn
+	db *dbInterceptor
+}
+
+func (c *dbConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &dbStmt{Stmt: stmt, db: c.db, query: query}, nil
+}
+
+func (c *dbConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &dbStmt{Stmt: stmt, db: c.db, query: query}, nil
+}
+
+func (c *dbConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+}
+
+func (c *dbConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return c.db.exec(ctx, query, func() (driver.Result, error) {
+		return ec.ExecContext(ctx, query, args)
+	})
+}
+
+func (c *dbConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	return c.db.query(ctx, query, func() (driver.Rows, error) {
+		return qc.QueryContext(ctx, query, args)
+	})
+}
+
+func (c *dbConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *dbConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *dbConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *dbConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// dbStmt wraps a prepared statement
+type dbStmt struct {
+	driver.Stmt
+	db    *dbInterceptor
+	query string
+}
+
+func (s *dbStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.db.exec(context.Background(), s.query, func() (driver.Result, error) {
+		return s.Stmt.Exec(args) //nolint:staticcheck // required by driver.Stmt
+	})
+}
+
+func (s *dbStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.query(context.Background(), s.query, func() (driver.Rows, error) {
+		return s.Stmt.Query(args) //nolint:staticcheck // required by driver.Stmt
+	})
+}
+
+func (s *dbStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	sc, ok := s.Stmt.(driver.StmtExecContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.db.exec(ctx, s.query, func() (driver.Result, error) {
+			return s.Stmt.Exec(values) //nolint:staticcheck // fallback for drivers without ExecContext
+		})
+	}
+	return s.db.exec(ctx, s.query, func() (driver.Result, error) {
+		return sc.ExecContext(ctx, args)
+	})
+}
+
+func (s *dbStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	sc, ok := s.Stmt.(driver.StmtQueryContext)
+	if !ok {
+		values, err := namedValues(args)
+		if err != nil {
+			return nil, err
+		}
+		return s.db.query(ctx, s.query, func() (driver.Rows, error) {
+			return s.Stmt.Query(values) //nolint:staticcheck // fallback for drivers without QueryContext
+		})
+	}
+	return s.db.query(ctx, s.query, func() (driver.Rows, error) {
+		return sc.QueryContext(ctx, args)
+	})
+}
+
+func (s *dbStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValues converts arguments for drivers without context support
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("driver does not support named parameter %q", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// dbRows counts rows as they are read and tracks the query on Close
+type dbRows struct {
+	driver.Rows
+	call   *dbCall
+	count  int64
+	err    error
+	closed atomic.Bool
+}
+
+func (r *dbRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.count++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *dbRows) Close() error {
+	err := r.Rows.Close()
+	if r.closed.CompareAndSwap(false, true) {
+		r.call.end(r.count, r.err)
+	}
+	return err
+}
+
+func (r *dbRows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *dbRows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *dbRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *dbRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *dbRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *dbRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *dbRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+var (
+	valueList   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	tupleList   = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	whereClause = regexp.MustCompile(`(?i)\bWHERE\b`)
+	identQuotes = strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "")
+	tableRef    = regexp.MustCompile("(?i)\\b(?:FROM|JOIN|INTO|UPDATE|TRUNCATE(?:\\s+TABLE)?|TABLE)\\s+([\\w.\"`\\[\\]]+)")
+)
+
+// fingerprintQuery normalizes a query so that executions differing only in
+// literal values share one fingerprint: comments are removed, string and
+// numeric literals and placeholders become ?, value lists collapse to (?)
+// and whitespace is collapsed.
+func fingerprintQuery(query string) string {
+	var b strings.Builder
+	space := false
+	var last byte
+	emit := func(s string) {
+		if space && b.Len() > 0 && last != '(' && s != ")" && s != "," {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+		last = s[len(s)-1]
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			space = true
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+			space = true
+		case c == '\'':
+			i++
+			for i < len(query) {
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+			emit("?")
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			// A type cast, not a placeholder
+			emit("::")
+			i += 2
+		case c == '$' || c == ':' || c == '@' || c == '?':
+			// Placeholders: $1, :name, @p1 and ?
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			if c != '?' && j == i+1 {
+				emit(query[i:j])
+			} else {
+				emit("?")
+			}
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			emit("?")
+			i = j
+		case isIdentByte(c):
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			emit(query[i:j])
+			i = j
+		default:
+			emit(string(c))
+			if c == ',' {
+				space = true
+			}
+			i++
+		}
+	}
+
+	out := valueList.ReplaceAllString(b.String(), "(?)")
+	out = tupleList.ReplaceAllString(out, "(?)")
+	return strings.TrimSuffix(strings.TrimSpace(out), ";")
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// statementType returns the keyword of the first statement of a query,
+// uppercased
+func statementType(fingerprint string) string {
+	verb, _ := mainStatement(fingerprint)
+	return verb
+}
+
+// splitStatements splits a fingerprint into its statements at the
+// semicolons outside parentheses and quoted identifiers
+func splitStatements(fingerprint string) []string {
+	var stmts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(fingerprint); i++ {
+		switch c := fingerprint[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ';' && depth <= 0:
+			stmts = append(stmts, fingerprint[start:i])
+			start = i + 1
+		}
+	}
+	stmts = append(stmts, fingerprint[start:])
+
+	out := stmts[:0]
+	for _, s := range stmts {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// mainStatement returns the keyword of a statement, uppercased, and the
+// statement from that keyword on. Leading parentheses and the common table
+// expressions of a WITH clause are skipped, so "WITH x AS (...) DELETE ..."
+// is a DELETE.
+func mainStatement(stmt string) (string, string) {
+	stmt = strings.TrimLeft(stmt, "( ")
+	verb := strings.ToUpper(leadingWord(stmt))
+	if verb != "WITH" {
+		return verb, stmt
+	}
+	// After a parenthesized CTE body at the top level, the next word that is
+	// not part of the CTE syntax starts the main statement
+	depth, closed := 0, false
+	for i := len(verb); i < len(stmt); i++ {
+		switch c := stmt[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			closed = depth == 0
+		case c == ',' && depth == 0:
+			closed = false
+		case closed && depth == 0 && isIdentByte(c):
+			word := leadingWord(stmt[i:])
+			switch strings.ToUpper(word) {
+			case "AS", "NOT", "MATERIALIZED":
+				i += len(word) - 1
+			default:
+				return strings.ToUpper(word), stmt[i:]
+			}
+		}
+	}
+	return verb, stmt
+}
+
+// leadingWord returns the identifier at the start of s
+func leadingWord(s string) string {
+	i := 0
+	for i < len(s) && isIdentByte(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+// queryTables returns the tables a query references, in order of appearance
+func queryTables(fingerprint string) []string {
+	var tables []string
+	seen := map[string]bool{}
+	for _, m := range tableRef.FindAllStringSubmatch(fingerprint, -1) {
+		name := identQuotes.Replace(m[1])
+		if name == "" || name == "?" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tables = append(tables, name)
+	}
+	return tables
+}
@@ -0,0 +1,313 @@
+package trusera
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDB records the statements that reach it. Queries return rows rows;
+// execs affect rows rows.
+type fakeDB struct {
+	mu       sync.Mutex
+	executed []string
+	rows     int
+	context  bool // implement the context interfaces on connections
+}
+
+func (d *fakeDB) ran(query string) {
+	d.mu.Lock()
+	d.executed = append(d.executed, query)
+	d.mu.Unlock()
+}
+
+func (d *fakeDB) Open(string) (driver.Conn, error) {
+	if d.context {
+		return &fakeCtxConn{fakeConn{d}}, nil
+	}
+	return &fakeConn{d}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeCtxConn struct{ fakeConn }
+
+func (c *fakeCtxConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.ran(query)
+	return driver.RowsAffected(c.db.rows), nil
+}
+
+func (c *fakeCtxConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.db.ran(query)
+	if strings.Contains(query, "broken") {
+		return nil, errors.New("syntax error")
+	}
+	return &fakeRows{left: c.db.rows}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.db.ran(s.query)
+	return driver.RowsAffected(s.db.rows), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.db.ran(s.query)
+	return &fakeRows{left: s.db.rows}, nil
+}
+
+type fakeRows struct{ left int }
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	dest[0] = int64(r.left)
+	return nil
+}
+
+func openTestDB(t *testing.T, client *Client, fake *fakeDB, opts DBOptions) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(WrapConnector(&fakeConnector{fake}, client, opts))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return c.db.Open("") }
+func (c *fakeConnector) Driver() driver.Driver                        { return c.db }
+
+func TestWrapDBTracksStatements(t *testing.T) {
+	for _, withContext := range []bool{false, true} {
+		client := dialClient(t)
+		fake := &fakeDB{rows: 3, context: withContext}
+		db := openTestDB(t, client, fake, DBOptions{})
+
+		ctx := ContextWithSessionID(context.Background(), "sess-db")
+		rows, err := db.QueryContext(ctx, "SELECT id FROM users WHERE name = 'alice' AND age > 30", "x")
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		n := 0
+		for rows.Next() {
+			n++
+		}
+		rows.Close()
+		if n != 3 {
+			t.Errorf("expected 3 rows, got %d", n)
+		}
+
+		if _, err := db.ExecContext(ctx, "UPDATE users SET active = false WHERE id IN (1, 2, 3)"); err != nil {
+			t.Fatalf("exec failed: %v", err)
+		}
+
+		query, ok := trackedEvent(client, "sql SELECT id FROM users WHERE name = ? AND age > ?")
+		if !ok {
+			t.Fatalf("query not tracked (context=%v)", withContext)
+		}
+		if query.Type != EventDataAccess || query.Payload["statement"] != "SELECT" || query.Payload["rows"] != int64(3) ||
+			query.Metadata["session_id"] != "sess-db" || query.Payload["duration_ms"] == nil {
+			t.Errorf("unexpected query event %v %v", query.Payload, query.Metadata)
+		}
+		if tables, _ := query.Payload["tables"].([]string); len(tables) != 1 || tables[0] != "users" {
+			t.Errorf("unexpected tables %v", query.Payload["tables"])
+		}
+
+		update, ok := trackedEvent(client, "sql UPDATE users SET active = false WHERE id IN (?)")
+		if !ok || update.Payload["rows"] != int64(3) || update.Payload["enforcement_action"] != "allowed" {
+			t.Errorf("unexpected update event %v", update.Payload)
+		}
+	}
+}
+
+func TestWrapDBBlocksStatements(t *testing.T) {
+	client := dialClient(t)
+	fake := &fakeDB{context: true}
+	db := openTestDB(t, client, fake, DBOptions{
+		Enforcement:          ModeBlock,
+		BlockStatements:      []string{"drop"},
+		BlockUnboundedWrites: true,
+		Rules:                []Rule{{ID: "no-secrets", Match: "FROM secrets", Action: RuleBlock}},
+		ExcludePatterns:      []string{"SELECT ?"},
+	})
+
+	for _, query := range []string{
+		"DELETE FROM sessions",
+		"drop table users",
+		"SELECT * FROM secrets",
+	} {
+		if _, err := db.Exec(query); !errors.Is(err, ErrBlocked) {
+			t.Errorf("%q: expected the statement to be blocked, got %v", query, err)
+		}
+	}
+	if _, err := db.Exec("DELETE FROM sessions WHERE expires < now()"); err != nil {
+		t.Errorf("bounded delete should be allowed: %v", err)
+	}
+	if _, err := db.Exec("SELECT 1"); err != nil {
+		t.Errorf("excluded statement failed: %v", err)
+	}
+
+	fake.mu.Lock()
+	executed := fake.executed
+	fake.mu.Unlock()
+	if len(executed) != 2 {
+		t.Errorf("blocked statements reached the database: %v", executed)
+	}
+
+	unbounded, _ := trackedEvent(client, "sql DELETE FROM sessions")
+	if unbounded.Payload["block_reason"] != "DELETE without WHERE" || unbounded.Payload["enforcement_action"] != "blocked" {
+		t.Errorf("unexpected unbounded delete event %v", unbounded.Payload)
+	}
+	secrets, _ := trackedEvent(client, "sql SELECT * FROM secrets")
+	if secrets.Payload["rule_id"] != "no-secrets" {
+		t.Errorf("expected the rule to be reported, got %v", secrets.Payload)
+	}
+	if _, ok := trackedEvent(client, "sql SELECT ?"); ok {
+		t.Error("excluded statements should not be tracked")
+	}
+}
+
+func TestWrapDBBlocksEveryStatement(t *testing.T) {
+	for _, opts := range []DBOptions{
+		{Enforcement: ModeBlock, BlockStatements: []string{"DELETE"}},
+		{Enforcement: ModeBlock, BlockUnboundedWrites: true},
+	} {
+		client := dialClient(t)
+		fake := &fakeDB{context: true}
+		db := openTestDB(t, client, fake, opts)
+
+		for _, query := range []string{
+			"SELECT 1; DELETE FROM users",
+			"WITH x AS (SELECT 1) DELETE FROM users",
+			"WITH x(id) AS (SELECT id FROM t WHERE id > 1), y AS NOT MATERIALIZED (SELECT 2) DELETE FROM users",
+		} {
+			if _, err := db.Exec(query); !errors.Is(err, ErrBlocked) {
+				t.Errorf("%+v %q: expected the statement to be blocked, got %v", opts, query, err)
+			}
+		}
+		if _, err := db.Exec("SELECT 1; SELECT ';' FROM (SELECT 2)"); err != nil {
+			t.Errorf("%+v: reads should be allowed: %v", opts, err)
+		}
+	}
+}
+
+func TestWrapDBWarnModeAndErrors(t *testing.T) {
+	client := dialClient(t)
+	fake := &fakeDB{context: true}
+	db := openTestDB(t, client, fake, DBOptions{Enforcement: ModeWarn, BlockStatements: []string{"TRUNCATE"}})
+
+	if _, err := db.Exec("TRUNCATE audit_log"); err != nil {
+		t.Fatalf("warn mode should run the statement: %v", err)
+	}
+	warned, _ := trackedEvent(client, "sql TRUNCATE audit_log")
+	if warned.Metadata["warning"] == nil || warned.Payload["tables"] == nil {
+		t.Errorf("unexpected warn event %v %v", warned.Payload, warned.Metadata)
+	}
+
+	if _, err := db.Query("SELECT broken"); err == nil {
+		t.Fatal("expected the query error")
+	}
+	failed, _ := trackedEvent(client, "sql SELECT broken")
+	if failed.Payload["error"] != "syntax error" {
+		t.Errorf("expected the error to be tracked, got %v", failed.Payload)
+	}
+}
+
+func TestWrapDBRegistersDriver(t *testing.T) {
+	if !slices.Contains(sql.Drivers(), "trusera-fake") {
+		sql.Register("trusera-fake", &fakeDB{rows: 1})
+	}
+	client := dialClient(t)
+
+	name, err := WrapDB("trusera-fake", client, DBOptions{})
+	if err != nil {
+		t.Fatalf("WrapDB failed: %v", err)
+	}
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var id int
+	if err := db.QueryRow("SELECT id FROM agents LIMIT 1").Scan(&id); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if _, ok := trackedEvent(client, "sql SELECT id FROM agents LIMIT ?"); !ok {
+		t.Error("expected the query to be tracked")
+	}
+
+	if _, err := WrapDB("no-such-driver", client, DBOptions{}); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+}
+
+func TestFingerprintQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "SELECT * FROM users WHERE id = ?"},
+		{"select  name\n\tfrom users -- comment\nwhere email = 'a''b@example.com';", "select name from users where email = ?"},
+		{"INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y')", "INSERT INTO t (a, b) VALUES (?)"},
+		{"SELECT * FROM t WHERE id IN ($1, $2, $3) /* hint */ AND v = :name", "SELECT * FROM t WHERE id IN (?) AND v = ?"},
+		{"SELECT created_at::date FROM events2 WHERE score > -1.5", "SELECT created_at::date FROM events2 WHERE score > -?"},
+		{"UPDATE t SET n = @p1 WHERE id = ?", "UPDATE t SET n = ? WHERE id = ?"},
+	}
+	for _, tt := range tests {
+		if got := fingerprintQuery(tt.query); got != tt.want {
+			t.Errorf("fingerprintQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQueryTables(t *testing.T) {
+	got := queryTables(`SELECT * FROM "public".orders o JOIN customers c ON c.id = o.customer_id`)
+	if strings.Join(got, ",") != "public.orders,customers" {
+		t.Errorf("unexpected tables %v", got)
+	}
+	if got := queryTables("INSERT INTO logs (msg) VALUES (?)"); len(got) != 1 || got[0] != "logs" {
+		t.Errorf("unexpected tables %v", got)
+	}
+	if got := queryTables("TRUNCATE TABLE [audit]"); len(got) != 1 || got[0] != "audit" {
+		t.Errorf("unexpected tables %v", got)
+	}
+	if statementType("(SELECT 1) UNION (SELECT 2)") != "SELECT" {
+		t.Error("expected the statement type to ignore leading parentheses")
+	}
+	if got := statementType("WITH RECURSIVE t(n) AS (SELECT ? UNION ALL SELECT n FROM t) UPDATE x SET n = ?"); got != "UPDATE" {
+		t.Errorf("expected common table expressions to be skipped, got %q", got)
+	}
+	if got := splitStatements(`SELECT ?; INSERT INTO "a;b" VALUES (?);`); len(got) != 2 || got[1] != `INSERT INTO "a;b" VALUES (?)` {
+		t.Errorf("unexpected statements %q", got)
+	}
+}
@@ -0,0 +1,354 @@
+package trusera
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// isEventStream reports whether a Content-Type header denotes server-sent events
+func isEventStream(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// EventStreamOptions configures WrapEventStream
+type EventStreamOptions struct {
+	ChunkEvents bool              // Track an llm_stream_chunk event per server-sent event
+	Enrich      func(Event) Event // Applied to the aggregated llm_stream event before it is tracked
+	// CountTokens counts the streamed text when the stream reports no usage.
+	// The count is reported as completion_tokens with tokens_estimated set.
+	CountTokens func(text string) int
+}
+
+// WrapEventStream wraps a text/event-stream response body so that an
+// aggregated llm_stream event is tracked once the stream is fully read or
+// closed. Integrations use it to report streamed completions.
+func WrapEventStream(body io.ReadCloser, client *Client, req *http.Request, opts EventStreamOptions) io.ReadCloser {
+	s := newSSEBody(body, client, req.Method, req.URL.String(), opts.ChunkEvents, client.now())
+	s.enrich = opts.Enrich
+	s.countTokens = opts.CountTokens
+	return s
+}
+
+// sseBody wraps a text/event-stream response body, parsing events as the
+// caller reads them and reporting an aggregated LLM invocation at the end
+type sseBody struct {
+	body        io.ReadCloser
+	client      *Client
+	method      string
+	url         string
+	chunkEvents bool
+	enrich      func(Event) Event
+	countTokens func(string) int
+	start       time.Time
+
+	mu         sync.Mutex
+	line       []byte   // partial line carried between reads
+	data       []string // data lines of the event being assembled
+	chunks     int
+	firstChunk time.Duration
+	content    strings.Builder
+	model      string
+	finish     string
+	usage      map[string]any
+	toolCalls  []*streamToolCall
+	done       bool
+}
+
+// streamToolCall accumulates a tool invocation spread across stream chunks
+type streamToolCall struct {
+	index     int
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// toolCall returns the accumulated call for a content/tool index, creating it if needed
+func (s *sseBody) toolCall(index int) *streamToolCall {
+	for _, tc := range s.toolCalls {
+		if tc.index == index {
+			return tc
+		}
+	}
+	tc := &streamToolCall{index: index}
+	s.toolCalls = append(s.toolCalls, tc)
+	return tc
+}
+
+func newSSEBody(body io.ReadCloser, client *Client, method, url string, chunkEvents bool, start time.Time) *sseBody {
+	return &sseBody{
+		body:        body,
+		client:      client,
+		method:      method,
+		url:         url,
+		chunkEvents: chunkEvents,
+		start:       start,
+		usage:       make(map[string]any),
+	}
+}
+
+func (s *sseBody) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if n > 0 {
+		s.mu.Lock()
+		s.feed(p[:n])
+		s.mu.Unlock()
+	}
+	if err != nil {
+		s.finishStream(err)
+	}
+	return n, err
+}
+
+func (s *sseBody) Close() error {
+	err := s.body.Close()
+	s.finishStream(nil)
+	return err
+}
+
+// feed splits input into lines and dispatches complete events
+func (s *sseBody) feed(p []byte) {
+	s.line = append(s.line, p...)
+	for {
+		idx := bytes.IndexByte(s.line, '\n')
+		if idx < 0 {
+			return
+		}
+		line := strings.TrimRight(string(s.line[:idx]), "\r")
+		s.line = s.line[idx+1:]
+
+		switch {
+		case line == "":
+			s.dispatch()
+		case strings.HasPrefix(line, "data:"):
+			s.data = append(s.data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// dispatch handles one complete server-sent event
+func (s *sseBody) dispatch() {
+	if len(s.data) == 0 {
+		return
+	}
+	data := strings.Join(s.data, "\n")
+	s.data = s.data[:0]
+
+	if data == "[DONE]" {
+		return
+	}
+
+	s.chunks++
+	if s.chunks == 1 {
+		s.firstChunk = s.client.now().Sub(s.start)
+	}
+
+	var text string
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(data), &doc); err == nil {
+		text = s.extract(doc)
+	}
+	s.content.WriteString(text)
+
+	if s.chunkEvents {
+		chunk := NewEvent(EventLLMInvoke, "llm_stream_chunk").
+			WithPayload("url", s.url).
+			WithPayload("index", s.chunks-1).
+			WithPayload("content", text)
+		s.client.Track(chunk)
+	}
+}
+
+// extract pulls model, text, finish reason and usage from OpenAI- and
+// Anthropic-style streaming chunks, returning the chunk's text delta
+func (s *sseBody) extract(doc map[string]any) string {
+	if model, ok := doc["model"].(string); ok && model != "" {
+		s.model = model
+	}
+	s.mergeUsage(doc["usage"])
+
+	var text string
+
+	// OpenAI chat completions: choices[].delta.content / choices[].finish_reason
+	if choices, ok := doc["choices"].([]any); ok {
+		for _, c := range choices {
+			choice, _ := c.(map[string]any)
+			if delta, ok := choice["delta"].(map[string]any); ok {
+				if content, ok := delta["content"].(string); ok {
+					text += content
+				}
+				s.extractOpenAIToolCalls(delta)
+			}
+			if t, ok := choice["text"].(string); ok {
+				text += t
+			}
+			if reason, ok := choice["finish_reason"].(string); ok && reason != "" {
+				s.finish = reason
+			}
+		}
+	}
+
+	// Anthropic messages: message_start, content_block_delta, message_delta
+	switch doc["type"] {
+	case "message_start":
+		if msg, ok := doc["message"].(map[string]any); ok {
+			if model, ok := msg["model"].(string); ok {
+				s.model = model
+			}
+			s.mergeUsage(msg["usage"])
+		}
+	case "content_block_start":
+		if block, ok := doc["content_block"].(map[string]any); ok && block["type"] == "tool_use" {
+			index, _ := doc["index"].(float64)
+			tc := s.toolCall(int(index))
+			tc.id, _ = block["id"].(string)
+			tc.name, _ = block["name"].(string)
+		}
+	case "content_block_delta":
+		if delta, ok := doc["delta"].(map[string]any); ok {
+			if t, ok := delta["text"].(string); ok {
+				text += t
+			}
+			if partial, ok := delta["partial_json"].(string); ok {
+				index, _ := doc["index"].(float64)
+				s.toolCall(int(index)).arguments.WriteString(partial)
+			}
+		}
+	case "message_delta":
+		if delta, ok := doc["delta"].(map[string]any); ok {
+			if reason, ok := delta["stop_reason"].(string); ok {
+				s.finish = reason
+			}
+		}
+	}
+
+	return text
+}
+
+// extractOpenAIToolCalls accumulates delta.tool_calls fragments
+func (s *sseBody) extractOpenAIToolCalls(delta map[string]any) {
+	calls, ok := delta["tool_calls"].([]any)
+	if !ok {
+		return
+	}
+	for _, c := range calls {
+		call, _ := c.(map[string]any)
+		index, _ := call["index"].(float64)
+		tc := s.toolCall(int(index))
+		if id, ok := call["id"].(string); ok && id != "" {
+			tc.id = id
+		}
+		if fn, ok := call["function"].(map[string]any); ok {
+			if name, ok := fn["name"].(string); ok && name != "" {
+				tc.name = name
+			}
+			if args, ok := fn["arguments"].(string); ok {
+				tc.arguments.WriteString(args)
+			}
+		}
+	}
+}
+
+func (s *sseBody) mergeUsage(v any) {
+	usage, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	for key, val := range usage {
+		if n, ok := val.(float64); ok {
+			s.usage[key] = int(n)
+		}
+	}
+}
+
+// finishStream reports the aggregated invocation exactly once
+func (s *sseBody) finishStream(readErr error) {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+
+	// Flush a trailing event that was not followed by a blank line
+	if len(s.line) > 0 {
+		s.feed([]byte("\n"))
+	}
+	s.dispatch()
+
+	event := NewEvent(EventLLMInvoke, "llm_stream").
+		WithPayload("method", s.method).
+		WithPayload("url", s.url).
+		WithPayload("streamed", true).
+		WithPayload("chunks", s.chunks).
+		WithPayload("content_length", s.content.Len()).
+		WithPayload("latency_ms", float64(s.client.now().Sub(s.start).Microseconds())/1000)
+
+	if s.chunks > 0 {
+		event = event.WithPayload("time_to_first_chunk_ms", float64(s.firstChunk.Microseconds())/1000)
+	}
+	if s.model != "" {
+		event = event.WithPayload("model", s.model)
+	}
+	if s.finish != "" {
+		event = event.WithPayload("finish_reason", s.finish)
+	}
+	if len(s.usage) > 0 {
+		event = event.WithPayload("usage", s.usage)
+		prompt, completion := tokenCounts(s.usage)
+		event = event.WithPayload("prompt_tokens", prompt).
+			WithPayload("completion_tokens", completion).
+			WithPayload("total_tokens", prompt+completion)
+	} else if s.countTokens != nil && s.content.Len() > 0 {
+		completion := s.countTokens(s.content.String())
+		event = event.WithPayload("completion_tokens", completion).
+			WithPayload("total_tokens", completion).
+			WithPayload("tokens_estimated", true)
+	}
+	if len(s.toolCalls) > 0 {
+		calls := make([]map[string]any, 0, len(s.toolCalls))
+		for _, tc := range s.toolCalls {
+			call := map[string]any{"id": tc.id, "name": tc.name}
+			var input any
+			if err := json.Unmarshal([]byte(tc.arguments.String()), &input); err == nil {
+				call["input"] = input
+			} else if tc.arguments.Len() > 0 {
+				call["input"] = tc.arguments.String()
+			}
+			calls = append(calls, call)
+		}
+		event = event.WithPayload("tool_calls", calls)
+	}
+	if readErr != nil && readErr != io.EOF {
+		event = event.WithPayload("error", readErr.Error())
+	}
+	s.mu.Unlock()
+
+	if s.enrich != nil {
+		event = s.enrich(event)
+	}
+	s.client.Track(event)
+}
+
+// tokenCounts normalizes OpenAI and Anthropic usage field names
+func tokenCounts(usage map[string]any) (prompt, completion int) {
+	for _, key := range []string{"prompt_tokens", "input_tokens"} {
+		if n, ok := usage[key].(int); ok {
+			prompt = n
+			break
+		}
+	}
+	for _, key := range []string{"completion_tokens", "output_tokens"} {
+		if n, ok := usage[key].(int); ok {
+			completion = n
+			break
+		}
+	}
+	return prompt, completion
+}
@@ -0,0 +1,263 @@
+package trusera
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func findEvent(c *Client, name string) (Event, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.events {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+func TestIsEventStream(t *testing.T) {
+	tests := map[string]bool{
+		"text/event-stream":                true,
+		"text/event-stream; charset=utf-8": true,
+		"application/json":                 false,
+		"":                                 false,
+	}
+
+	for contentType, want := range tests {
+		if got := isEventStream(contentType); got != want {
+			t.Errorf("isEventStream(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestInterceptorOpenAIStream(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, token := range []string{"Hel", "lo", "!"} {
+			fmt.Fprintf(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+			flusher.Flush()
+		}
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4o\",\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer backend.Close()
+
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{
+		Enforcement:       ModeLog,
+		StreamChunkEvents: true,
+	})
+
+	resp, err := httpClient.Get(backend.URL + "/v1/chat/completions")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if len(body) == 0 {
+		t.Fatal("expected caller to receive the stream unchanged")
+	}
+
+	stream, ok := findEvent(truseraClient, "llm_stream")
+	if !ok {
+		t.Fatal("expected aggregated llm_stream event")
+	}
+
+	if stream.Type != EventLLMInvoke {
+		t.Errorf("expected EventLLMInvoke, got %s", stream.Type)
+	}
+
+	checks := map[string]any{
+		"model":             "gpt-4o",
+		"finish_reason":     "stop",
+		"chunks":            5,
+		"content_length":    len("Hello!"),
+		"prompt_tokens":     12,
+		"completion_tokens": 3,
+		"total_tokens":      15,
+	}
+	for key, want := range checks {
+		if stream.Payload[key] != want {
+			t.Errorf("payload[%s] = %v, want %v", key, stream.Payload[key], want)
+		}
+	}
+
+	if _, ok := findEvent(truseraClient, "llm_stream_chunk"); !ok {
+		t.Error("expected chunk events when StreamChunkEvents is set")
+	}
+}
+
+func TestInterceptorAnthropicStream(t *testing.T) {
+	events := []string{
+		`event: message_start` + "\n" + `data: {"type":"message_start","message":{"model":"claude-sonnet-4-5","usage":{"input_tokens":25,"output_tokens":1}}}`,
+		`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi there"}}`,
+		`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			fmt.Fprint(w, e+"\r\n\r\n")
+		}
+	}))
+	defer backend.Close()
+
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{})
+
+	resp, err := httpClient.Post(backend.URL+"/v1/messages", "application/json", nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	stream, ok := findEvent(truseraClient, "llm_stream")
+	if !ok {
+		t.Fatal("expected aggregated llm_stream event")
+	}
+
+	if stream.Payload["model"] != "claude-sonnet-4-5" {
+		t.Errorf("unexpected model: %v", stream.Payload["model"])
+	}
+
+	if stream.Payload["finish_reason"] != "end_turn" {
+		t.Errorf("unexpected finish reason: %v", stream.Payload["finish_reason"])
+	}
+
+	if stream.Payload["prompt_tokens"] != 25 || stream.Payload["completion_tokens"] != 4 {
+		t.Errorf("unexpected token counts: %v", stream.Payload)
+	}
+
+	if _, ok := findEvent(truseraClient, "llm_stream_chunk"); ok {
+		t.Error("chunk events should be off by default")
+	}
+}
+
+func TestSSEStreamClosedEarly(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+	}))
+	defer backend.Close()
+
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{})
+
+	resp, err := httpClient.Get(backend.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	resp.Body.Close()
+
+	truseraClient.mu.Lock()
+	count := 0
+	for _, e := range truseraClient.events {
+		if e.Name == "llm_stream" {
+			count++
+		}
+	}
+	truseraClient.mu.Unlock()
+
+	if count != 1 {
+		t.Errorf("expected exactly one llm_stream event, got %d", count)
+	}
+}
+
+func TestSSEToolCalls(t *testing.T) {
+	anthropic := []string{
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\": "}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+	}
+	openai := []string{
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"search","arguments":"{\"q\":"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go\"}"}}]}}]}`,
+	}
+
+	tests := []struct {
+		name   string
+		chunks []string
+		id     string
+		tool   string
+		key    string
+		value  string
+	}{
+		{"anthropic", anthropic, "toolu_1", "get_weather", "city", "Paris"},
+		{"openai", openai, "call_1", "search", "q", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, c := range tt.chunks {
+					fmt.Fprint(w, c+"\n\n")
+				}
+			}))
+			defer backend.Close()
+
+			truseraClient := NewClient("test-key")
+			defer truseraClient.Close()
+
+			httpClient := WrapHTTPClient(&http.Client{}, truseraClient, InterceptorOptions{})
+			resp, err := httpClient.Get(backend.URL)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			stream, _ := findEvent(truseraClient, "llm_stream")
+			calls, ok := stream.Payload["tool_calls"].([]map[string]any)
+			if !ok || len(calls) != 1 {
+				t.Fatalf("expected 1 tool call, got %v", stream.Payload["tool_calls"])
+			}
+
+			if calls[0]["id"] != tt.id || calls[0]["name"] != tt.tool {
+				t.Errorf("unexpected tool call: %v", calls[0])
+			}
+
+			input, _ := calls[0]["input"].(map[string]any)
+			if input[tt.key] != tt.value {
+				t.Errorf("expected reassembled input, got %v", calls[0]["input"])
+			}
+		})
+	}
+}
+
+func TestSSECountTokensWithoutUsage(t *testing.T) {
+	truseraClient := NewClient("test-key")
+	defer truseraClient.Close()
+
+	stream := "data: {\"choices\":[{\"delta\":{\"content\":\"one two three\"}}]}\n\ndata: [DONE]\n\n"
+	req := httptest.NewRequest(http.MethodPost, "https://api.openai.com/v1/chat/completions", nil)
+	body := WrapEventStream(io.NopCloser(strings.NewReader(stream)), truseraClient, req, EventStreamOptions{
+		CountTokens: func(text string) int { return len(strings.Fields(text)) },
+	})
+	io.Copy(io.Discard, body)
+	body.Close()
+
+	e, ok := findEvent(truseraClient, "llm_stream")
+	if !ok {
+		t.Fatal("expected an llm_stream event")
+	}
+	if e.Payload["completion_tokens"] != 3 || e.Payload["tokens_estimated"] != true {
+		t.Errorf("expected 3 estimated completion tokens, got %v", e.Payload)
+	}
+}
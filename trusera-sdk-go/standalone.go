@@ -243,8 +243,7 @@ func (t *standaloneTransport) RoundTrip(req *http.Request) (*http.Response, erro
 // shouldExclude checks if URL matches any exclude patterns
 func (t *standaloneTransport) shouldExclude(urlStr string) bool {
 	for _, pattern := range t.interceptor.excludePatterns {
-		// Support both substring match and regex-like patterns
-		if strings.Contains(urlStr, pattern) {
+		if matchPattern(urlStr, pattern) {
 			return true
 		}
 	}
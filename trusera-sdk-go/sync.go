@@ -0,0 +1,72 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// TrackSync sends a single event immediately, bypassing the buffer, and
+// returns the ID the API acknowledged it under. It is meant for events that
+// must be on record before the agent proceeds, such as high-value decisions.
+// The event is enriched from ctx like TrackCtx, and retries, compression and
+// the circuit breaker apply as for flushes.
+//
+// If a hook drops the event, TrackSync returns ErrEventDropped, or the
+// hook's error if it vetoed the event. With WithMaxEventsPerSecond,
+// TrackSync waits for the rate to allow the event.
+//
+// A non-empty ID means the API accepted the event; an error alongside it
+// comes from a secondary sink such as WithOTLPExport.
+func (c *Client) TrackSync(ctx context.Context, event Event) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if c.ordered != nil {
+		return c.trackSyncOrdered(ctx, event)
+	}
+	event, ok, err := c.prepare(c.enrich(ctx, event))
+	if !ok {
+		return "", dropErr(err)
+	}
+	if err := c.waitForEvent(ctx); err != nil {
+		return "", err
+	}
+	batch, err := c.beforeSend([]Event{event})
+	if len(batch) == 0 {
+		return "", dropErr(err)
+	}
+
+	reply, sendErr, exportErr := c.deliver(ctx, batch)
+	if sendErr != nil {
+		return "", sendErr
+	}
+	return ackedID(reply, event.ID), exportErr
+}
+
+// dropErr reports a hook's veto, or ErrEventDropped if it dropped silently
+func dropErr(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrEventDropped
+}
+
+// ackedID reads the event ID from an ingestion reply. The API answers with
+// either {"event_ids": [...]} or {"event_id": "..."}; when it sends neither,
+// the event was stored under the ID the SDK assigned.
+func ackedID(reply []byte, fallback string) string {
+	var ack struct {
+		EventID  string   `json:"event_id"`
+		EventIDs []string `json:"event_ids"`
+	}
+	if json.Unmarshal(reply, &ack) != nil {
+		return fallback
+	}
+	if len(ack.EventIDs) > 0 && ack.EventIDs[0] != "" {
+		return ack.EventIDs[0]
+	}
+	if ack.EventID != "" {
+		return ack.EventID
+	}
+	return fallback
+}
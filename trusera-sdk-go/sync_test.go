@@ -0,0 +1,74 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrackSync(t *testing.T) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Events []Event `json:"events"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received = payload.Events
+		w.Write([]byte(`{"event_ids":["srv-123"]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL))
+	defer client.Close()
+
+	ctx := ContextWithSessionID(context.Background(), "sess-1")
+	id, err := client.TrackSync(ctx, NewEvent(EventDecision, "approve_refund"))
+	if err != nil {
+		t.Fatalf("TrackSync failed: %v", err)
+	}
+	if id != "srv-123" {
+		t.Errorf("expected server event ID, got %q", id)
+	}
+
+	if len(received) != 1 || received[0].Metadata["session_id"] != "sess-1" {
+		t.Errorf("expected the enriched event to be sent immediately, got %v", received)
+	}
+	if depth := client.Stats().BufferDepth; depth != 0 {
+		t.Errorf("TrackSync should bypass the buffer, got depth %d", depth)
+	}
+}
+
+func TestTrackSyncError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL))
+	defer client.Close()
+
+	id, err := client.TrackSync(context.Background(), NewEvent(EventDecision, "approve_refund"))
+	if err == nil || id != "" {
+		t.Errorf("expected an error and no ID, got %q, %v", id, err)
+	}
+}
+
+func TestAckedID(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{`{"event_ids":["a","b"]}`, "a"},
+		{`{"event_id":"c"}`, "c"},
+		{`{"status":"ok"}`, "local"},
+		{``, "local"},
+		{`not json`, "local"},
+	}
+	for _, tt := range tests {
+		if got := ackedID([]byte(tt.reply), "local"); got != tt.want {
+			t.Errorf("ackedID(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package trusera
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// requestTiming records the phases of an intercepted request with
+// httptrace. Phases that did not happen, such as DNS for a reused
+// connection, stay zero.
+type requestTiming struct {
+	now func() time.Time
+
+	mu           sync.Mutex
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// traceRequest returns req with a trace that records its timing
+func (t *interceptingTransport) traceRequest(req *http.Request) (*http.Request, *requestTiming) {
+	rt := &requestTiming{now: t.client.now}
+	mark := func(at *time.Time) {
+		now := rt.now()
+		rt.mu.Lock()
+		if at.IsZero() {
+			*at = now
+		}
+		rt.mu.Unlock()
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&rt.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&rt.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&rt.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&rt.connectDone) },
+		TLSHandshakeStart:    func() { mark(&rt.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&rt.tlsDone) },
+		GotFirstResponseByte: func() { mark(&rt.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			rt.mu.Lock()
+			rt.reused = info.Reused
+			rt.mu.Unlock()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), rt
+}
+
+// describe adds the request's duration since start and the timing of each
+// phase to an event
+func (rt *requestTiming) describe(event Event, start time.Time) Event {
+	end := rt.now()
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	event = event.
+		WithPayload("duration_ms", millis(end.Sub(start))).
+		WithPayload("connection_reused", rt.reused)
+	phases := []struct {
+		key        string
+		start, end time.Time
+	}{
+		{"dns_ms", rt.dnsStart, rt.dnsDone},
+		{"connect_ms", rt.connectStart, rt.connectDone},
+		{"tls_ms", rt.tlsStart, rt.tlsDone},
+		{"ttfb_ms", start, rt.firstByte},
+	}
+	for _, p := range phases {
+		if !p.start.IsZero() && !p.end.IsZero() {
+			event = event.WithPayload(p.key, millis(p.end.Sub(p.start)))
+		}
+	}
+	return event
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,68 @@
+package trusera
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInterceptedRequestTiming(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer backend.Close()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := WrapHTTPClient(backend.Client(), client, InterceptorOptions{})
+
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Post(backend.URL, "text/plain", strings.NewReader("ping"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	var responses []Event
+	for _, e := range trackedEvents(client, EventAPICall) {
+		if e.Name == "response" {
+			responses = append(responses, e)
+		}
+	}
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 response events, got %d", len(responses))
+	}
+	first, second := responses[0].Payload, responses[1].Payload
+	for _, key := range []string{"duration_ms", "connect_ms", "tls_ms", "ttfb_ms"} {
+		if _, ok := first[key].(float64); !ok {
+			t.Errorf("expected %s on a new connection, got %v", key, first[key])
+		}
+	}
+	if first["request_bytes"] != 4 || first["response_bytes"] != 5 || first["status_code"] != http.StatusOK {
+		t.Errorf("expected sizes and status recorded, got %v", first)
+	}
+	if second["connection_reused"] != true || second["connect_ms"] != nil {
+		t.Errorf("expected the second request on a reused connection, got %v", second)
+	}
+}
+
+func TestInterceptedErrorTiming(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	httpClient := CreateInterceptedClient(client, InterceptorOptions{})
+
+	if _, err := httpClient.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatal("expected the request to fail")
+	}
+	event, ok := trackedEvent(client, "error")
+	if !ok {
+		t.Fatal("expected an error event")
+	}
+	if _, ok := event.Payload["duration_ms"].(float64); !ok {
+		t.Errorf("expected the failed request timed, got %v", event.Payload)
+	}
+}
@@ -0,0 +1,38 @@
+package trusera
+
+import "crypto/tls"
+
+// WithTLSConfig sets the TLS configuration used to reach the Trusera API,
+// e.g. to trust a private CA with RootCAs or to rotate client certificates
+// with GetClientCertificate. The config is cloned; without MinVersion, TLS
+// 1.2 is required.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		certs := c.tlsCertificates()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		c.tlsConfig = cfg.Clone()
+		c.tlsConfig.Certificates = append(c.tlsConfig.Certificates, certs...)
+	}
+}
+
+// WithClientCertificate authenticates the client to the Trusera API with a
+// certificate, for endpoints that require mutual TLS. Load it with
+// tls.LoadX509KeyPair. It can be combined with WithTLSConfig in any order.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) {
+		if c.tlsConfig == nil {
+			c.tlsConfig = &tls.Config{}
+		}
+		c.tlsConfig.Certificates = append(c.tlsConfig.Certificates, cert)
+	}
+}
+
+// tlsCertificates returns the client certificates configured so far
+func (c *Client) tlsCertificates() []tls.Certificate {
+	if c.tlsConfig == nil {
+		return nil
+	}
+	return c.tlsConfig.Certificates
+}
@@ -0,0 +1,95 @@
+package trusera
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// selfSignedCert creates a client certificate for tests
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "agent-1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestMutualTLS(t *testing.T) {
+	cert, leaf := selfSignedCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(leaf)
+
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	// Without a certificate the handshake fails
+	anonymous := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour),
+		WithTLSConfig(&tls.Config{RootCAs: roots}))
+	anonymous.Track(NewEvent(EventToolCall, "x"))
+	if err := anonymous.Flush(); err == nil {
+		t.Error("expected the server to require a client certificate")
+	}
+	anonymous.Close()
+
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour),
+		WithClientCertificate(cert),
+		WithTLSConfig(&tls.Config{RootCAs: roots}))
+	defer client.Close()
+	client.Track(NewEvent(EventToolCall, "x"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if peer != "agent-1" {
+		t.Errorf("expected the client certificate, got %q", peer)
+	}
+}
+
+func TestTLSConfigDefaults(t *testing.T) {
+	cfg := &tls.Config{ServerName: "ingest.internal"}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithTLSConfig(cfg))
+	defer client.Close()
+
+	transport := client.httpClient.Transport.(*http.Transport)
+	if transport.TLSClientConfig.MinVersion != tls.VersionTLS12 || transport.TLSClientConfig.ServerName != "ingest.internal" {
+		t.Errorf("unexpected config %+v", transport.TLSClientConfig)
+	}
+	if cfg.MinVersion != 0 {
+		t.Error("expected the caller's config not to be modified")
+	}
+	if transport.Proxy == nil {
+		t.Error("expected the default transport's proxy settings to be kept")
+	}
+}
@@ -0,0 +1,209 @@
+package trusera
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// EventTLSAnomaly is tracked for every HTTPS request whose connection does
+// not meet its host's TLSPolicy, which can mean the traffic is being
+// intercepted
+const EventTLSAnomaly EventType = "tls_anomaly"
+
+// TLSPolicy is what a host's TLS connections are expected to look like, as
+// set in HostPolicy.TLS. A connection that differs is a TLS anomaly: it is
+// tracked as an EventTLSAnomaly and, when the host's enforcement mode
+// rejects, the connection is closed before the request is sent.
+type TLSPolicy struct {
+	// Pins are the SPKI hashes, as returned by SPKIHash, of which the
+	// host's certificate chain must include at least one. Pin a backup key
+	// or the issuing CA too, so a certificate rotation does not lock the
+	// agent out.
+	Pins []string `json:"pins,omitempty"`
+
+	// Issuers are the common names or organizations the issuer of the
+	// host's certificate may have, such as "Let's Encrypt"
+	Issuers []string `json:"issuers,omitempty"`
+
+	// MinVersion is the oldest TLS version expected, such as
+	// tls.VersionTLS13
+	MinVersion uint16 `json:"min_version,omitempty"`
+}
+
+// SPKIHash returns the pin of a certificate's public key: "sha256/" and the
+// base64 SHA-256 hash of its SubjectPublicKeyInfo, as used by HPKP and curl
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// anomalies lists how a connection differs from the policy
+func (p *TLSPolicy) anomalies(state tls.ConnectionState) []string {
+	var found []string
+	if p.MinVersion != 0 && state.Version < p.MinVersion {
+		found = append(found, "tls_version")
+	}
+	if len(state.PeerCertificates) == 0 {
+		if len(p.Pins) > 0 || len(p.Issuers) > 0 {
+			found = append(found, "no_certificate")
+		}
+		return found
+	}
+
+	if len(p.Pins) > 0 {
+		chains := state.VerifiedChains
+		if len(chains) == 0 {
+			chains = [][]*x509.Certificate{state.PeerCertificates}
+		}
+		pinned := false
+		for _, chain := range chains {
+			for _, cert := range chain {
+				pinned = pinned || slices.Contains(p.Pins, SPKIHash(cert))
+			}
+		}
+		if !pinned {
+			found = append(found, "pin_mismatch")
+		}
+	}
+
+	if len(p.Issuers) > 0 {
+		issuer := state.PeerCertificates[0].Issuer
+		names := append([]string{issuer.CommonName}, issuer.Organization...)
+		if !slices.ContainsFunc(p.Issuers, func(want string) bool {
+			return slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, want) })
+		}) {
+			found = append(found, "unexpected_issuer")
+		}
+	}
+	return found
+}
+
+// errTLSAnomaly is the cause of a request aborted for a TLS anomaly
+var errTLSAnomaly = errors.New("TLS anomaly")
+
+// tlsCheck compares the connection of one HTTPS request with its host's
+// TLSPolicy
+type tlsCheck struct {
+	policy  *TLSPolicy
+	verdict verdict
+	reject  bool
+	cancel  context.CancelCauseFunc
+
+	mu        sync.Mutex
+	checked   bool
+	anomalies []string
+	state     tls.ConnectionState
+}
+
+// checkTLS returns req with a trace that checks its connection against the
+// TLS policy of its host, or a nil check if there is none. In a rejecting
+// mode, a connection with anomalies is closed before the request is sent.
+func (t *interceptingTransport) checkTLS(req *http.Request, v verdict) (*http.Request, *tlsCheck) {
+	if !strings.EqualFold(req.URL.Scheme, "https") && !strings.EqualFold(req.URL.Scheme, "wss") {
+		return req, nil
+	}
+	_, hp, ok := t.localOptions().hostPolicy(strings.ToLower(req.URL.Hostname()))
+	if !ok || hp.TLS == nil {
+		return req, nil
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+	check := &tlsCheck{policy: hp.TLS, verdict: v, reject: v.mode.rejects(), cancel: cancel}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn, ok := info.Conn.(interface{ ConnectionState() tls.ConnectionState })
+			if !ok {
+				return
+			}
+			if check.inspect(conn.ConnectionState()) && check.reject {
+				cancel(errTLSAnomaly)
+				info.Conn.Close()
+			}
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace)), check
+}
+
+// inspect records the anomalies of a connection state, reporting whether
+// there were any
+func (c *tlsCheck) inspect(state tls.ConnectionState) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked {
+		c.checked, c.state = true, state
+		c.anomalies = c.policy.anomalies(state)
+	}
+	return len(c.anomalies) > 0
+}
+
+// finish checks the response's connection if the trace did not, tracks an
+// EventTLSAnomaly if there were anomalies and, if they are rejected,
+// replaces the result with a BlockedError
+func (c *tlsCheck) finish(t *interceptingTransport, req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if resp != nil && resp.TLS != nil {
+		c.inspect(*resp.TLS)
+	}
+	c.mu.Lock()
+	anomalies, state := c.anomalies, c.state
+	c.mu.Unlock()
+	if len(anomalies) > 0 {
+		c.track(t, req, anomalies, state)
+	}
+
+	switch {
+	case len(anomalies) > 0 && c.reject:
+		if resp != nil {
+			resp.Body.Close()
+		}
+		c.cancel(errTLSAnomaly)
+		return nil, c.verdict.blockedError(req.URL.String(), blockCause{BlockReasonTLSAnomaly, anomalies})
+	case resp == nil:
+		c.cancel(nil)
+	case resp.StatusCode != http.StatusSwitchingProtocols:
+		resp.Body = cancelBody{resp.Body, c.cancel}
+	}
+	return resp, err
+}
+
+// track records the anomalies of a request's connection
+func (c *tlsCheck) track(t *interceptingTransport, req *http.Request, anomalies []string, state tls.ConnectionState) {
+	action := "flagged"
+	if c.reject {
+		action = "blocked"
+	}
+	event := NewEvent(EventTLSAnomaly, req.URL.Hostname()).
+		WithPayload("host", req.URL.Hostname()).
+		WithPayload("url", req.URL.String()).
+		WithPayload("anomalies", anomalies).
+		WithPayload("tls_version", tls.VersionName(state.Version)).
+		WithPayload("action", action)
+	if len(state.PeerCertificates) > 0 {
+		leaf := state.PeerCertificates[0]
+		event = event.
+			WithPayload("issuer", leaf.Issuer.String()).
+			WithPayload("spki", SPKIHash(leaf))
+	}
+	t.client.Track(t.client.correlateRequest(req, event))
+}
+
+// cancelBody cancels a request's context once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelCauseFunc
+}
+
+func (b cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
@@ -0,0 +1,128 @@
+package trusera
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func tlsPolicyClient(t *testing.T, server *httptest.Server, opts InterceptorOptions) (*Client, *http.Client) {
+	t.Helper()
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	t.Cleanup(func() { client.Close() })
+	return client, WrapHTTPClient(server.Client(), client, opts)
+}
+
+func TestTLSPolicyPins(t *testing.T) {
+	var served atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served.Add(1) }))
+	defer server.Close()
+
+	client, httpClient := tlsPolicyClient(t, server, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Hosts: map[string]HostPolicy{
+			"127.0.0.1": {TLS: &TLSPolicy{Pins: []string{SPKIHash(server.Certificate())}}},
+		},
+	})
+	for i := 0; i < 2; i++ {
+		resp, err := httpClient.Get(server.URL)
+		if err != nil {
+			t.Fatalf("expected the pinned host reachable, got %v", err)
+		}
+		resp.Body.Close()
+	}
+	if n := len(trackedEvents(client, EventTLSAnomaly)); n != 0 {
+		t.Errorf("expected no TLS anomalies, got %d", n)
+	}
+	if served.Load() != 2 {
+		t.Errorf("expected 2 requests served, got %d", served.Load())
+	}
+}
+
+func TestTLSPolicyBlocksPinMismatch(t *testing.T) {
+	var served atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served.Add(1) }))
+	defer server.Close()
+
+	client, httpClient := tlsPolicyClient(t, server, InterceptorOptions{
+		Enforcement: ModeBlock,
+		Hosts: map[string]HostPolicy{
+			"127.0.0.1": {TLS: &TLSPolicy{Pins: []string{"sha256/AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}},
+		},
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := httpClient.Get(server.URL + "/secret"); !errors.Is(err, ErrBlocked) {
+			t.Fatalf("expected a pin mismatch blocked, got %v", err)
+		}
+	}
+	if served.Load() != 0 {
+		t.Errorf("expected no request sent, got %d", served.Load())
+	}
+
+	events := trackedEvents(client, EventTLSAnomaly)
+	if len(events) != 2 {
+		t.Fatalf("expected 2 TLS anomaly events, got %d", len(events))
+	}
+	e := events[0]
+	if !reflect.DeepEqual(e.Payload["anomalies"], []string{"pin_mismatch"}) || e.Payload["action"] != "blocked" ||
+		e.Payload["spki"] != SPKIHash(server.Certificate()) || e.Payload["host"] != "127.0.0.1" {
+		t.Errorf("unexpected event payload %v", e.Payload)
+	}
+}
+
+func TestTLSPolicyFlagsAnomaliesInLogMode(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	client, httpClient := tlsPolicyClient(t, server, InterceptorOptions{
+		Enforcement: ModeLog,
+		Hosts: map[string]HostPolicy{
+			"127.0.0.1": {TLS: &TLSPolicy{Issuers: []string{"Let's Encrypt"}, MinVersion: tls.VersionTLS13}},
+		},
+	})
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected log mode to allow, got %v", err)
+	}
+	resp.Body.Close()
+
+	events := trackedEvents(client, EventTLSAnomaly)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 TLS anomaly event, got %d", len(events))
+	}
+	e := events[0]
+	if !reflect.DeepEqual(e.Payload["anomalies"], []string{"tls_version", "unexpected_issuer"}) ||
+		e.Payload["action"] != "flagged" || e.Payload["tls_version"] != "TLS 1.2" {
+		t.Errorf("unexpected event payload %v", e.Payload)
+	}
+}
+
+func TestTLSPolicyAnomalies(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	cert := server.Certificate()
+
+	p := &TLSPolicy{
+		Pins:       []string{"sha256/other", SPKIHash(cert)},
+		Issuers:    []string{"other", strings.ToUpper(cert.Issuer.Organization[0])},
+		MinVersion: tls.VersionTLS12,
+	}
+	state := tls.ConnectionState{Version: tls.VersionTLS13, PeerCertificates: []*x509.Certificate{cert}}
+	if got := p.anomalies(state); got != nil {
+		t.Errorf("expected no anomalies, got %v", got)
+	}
+	if got := p.anomalies(tls.ConnectionState{Version: tls.VersionTLS13}); !reflect.DeepEqual(got, []string{"no_certificate"}) {
+		t.Errorf("expected a missing certificate reported, got %v", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Package tokens counts LLM tokens, so that usage and cost stay accurate
+// for providers and streams that do not report usage.
+//
+// Exact counts need the vocabulary of the model's encoding. The SDK does
+// not embed the vocabularies; load tiktoken's files and register them:
+//
+//	f, _ := os.Open("o200k_base.tiktoken")
+//	enc, err := tokens.LoadEncoding("o200k_base", f)
+//	tokens.Register(enc)
+//
+// Without a registered encoding, ForModel falls back to Estimate.
+package tokens
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counter counts the tokens of a text
+type Counter interface {
+	Count(text string) int
+}
+
+// CounterFunc adapts a function to the Counter interface
+type CounterFunc func(text string) int
+
+// Count calls f(text)
+func (f CounterFunc) Count(text string) int {
+	return f(text)
+}
+
+// Pre-tokenization patterns of tiktoken's encodings. Go's regexp has no
+// lookahead, so the \s+(?!\S) alternative is applied by split.
+var (
+	cl100kPattern = regexp.MustCompile(`^(?:(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+)`)
+	o200kPattern  = regexp.MustCompile(`^(?:[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+)`)
+)
+
+// Encoding is a byte-pair encoding in tiktoken's format
+type Encoding struct {
+	name    string
+	pattern *regexp.Regexp
+	ranks   map[string]int
+}
+
+// LoadEncoding reads a tiktoken vocabulary file, whose lines hold a
+// base64-encoded token and its rank. The name selects the pre-tokenization
+// pattern: o200k_base or, for any other name, cl100k_base's.
+func LoadEncoding(name string, r io.Reader) (*Encoding, error) {
+	enc := &Encoding{name: name, pattern: cl100kPattern, ranks: make(map[string]int)}
+	if name == "o200k_base" {
+		enc.pattern = o200kPattern
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid vocabulary line %d", line)
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on line %d: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on line %d: %w", line, err)
+		}
+		enc.ranks[string(b)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+	if len(enc.ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary %s is empty", name)
+	}
+	return enc, nil
+}
+
+// Name returns the encoding's name
+func (e *Encoding) Name() string {
+	return e.name
+}
+
+// Encode returns the token IDs of a text. Special tokens such as
+// <|endoftext|> are encoded as ordinary text.
+func (e *Encoding) Encode(text string) []int {
+	var ids []int
+	for _, piece := range split(e.pattern, text) {
+		ids = append(ids, e.bpe(piece)...)
+	}
+	return ids
+}
+
+// Count returns the number of tokens in a text
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// bpe merges the bytes of a piece by rank, lowest first
+func (e *Encoding) bpe(piece string) []int {
+	if id, ok := e.ranks[piece]; ok {
+		return []int{id}
+	}
+
+	parts := make([]string, len(piece))
+	for i := range parts {
+		parts[i] = piece[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := e.ranks[parts[i]+parts[i+1]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	ids := make([]int, 0, len(parts))
+	for _, p := range parts {
+		if id, ok := e.ranks[p]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// split pre-tokenizes a text. Runs of whitespace followed by a non-space
+// leave their last character to the next piece, as \s+(?!\S) does.
+func split(pattern *regexp.Regexp, text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := pattern.FindStringIndex(text)
+		end := len(text)
+		if loc != nil && loc[1] > 0 {
+			end = loc[1]
+		} else {
+			_, end = utf8.DecodeRuneInString(text)
+		}
+
+		piece := text[:end]
+		if end < len(text) && isSpace(piece) && !strings.HasSuffix(piece, "\n") && !strings.HasSuffix(piece, "\r") {
+			next, _ := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(next) {
+				if _, size := utf8.DecodeLastRuneInString(piece); size < len(piece) {
+					end -= size
+					piece = text[:end]
+				}
+			}
+		}
+		pieces = append(pieces, piece)
+		text = text[end:]
+	}
+	return pieces
+}
+
+func isSpace(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Estimate approximates the token count of a text in the cl100k_base and
+// o200k_base encodings without their vocabularies. English prose is
+// usually within 10–15% of the exact count.
+func Estimate(text string) int {
+	n := 0
+	for _, piece := range split(cl100kPattern, text) {
+		n += estimatePiece(piece)
+	}
+	return n
+}
+
+// estimatePiece assumes words of up to seven bytes are one token, longer
+// ones take a token per further five bytes, and each non-ASCII letter is
+// one token
+func estimatePiece(piece string) int {
+	ascii, other := 0, 0
+	for _, r := range piece {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	n := other
+	switch {
+	case ascii == 0:
+	case ascii <= 7:
+		n++
+	default:
+		n += 1 + (ascii-7+4)/5
+	}
+	return n
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Counter{}
+)
+
+// Register makes an encoding available to ForModel under its name
+func Register(enc *Encoding) {
+	registryMu.Lock()
+	registry[enc.name] = enc
+	registryMu.Unlock()
+}
+
+// EncodingForModel returns the tiktoken encoding an OpenAI model uses, or ""
+// for models tiktoken does not cover
+func EncodingForModel(model string) string {
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4.1"), strings.HasPrefix(model, "gpt-4.5"),
+		strings.HasPrefix(model, "gpt-5"), strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"), strings.HasPrefix(model, "o4"):
+		return "o200k_base"
+	case strings.HasPrefix(model, "gpt-4"), strings.HasPrefix(model, "gpt-3.5"), strings.HasPrefix(model, "text-embedding-"):
+		return "cl100k_base"
+	}
+	return ""
+}
+
+// ForModel returns the registered encoding of a model, falling back to
+// Estimate
+func ForModel(model string) Counter {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if c, ok := registry[EncodingForModel(model)]; ok {
+		return c
+	}
+	return CounterFunc(Estimate)
+}
+
+// Count counts the tokens of a text for a model
+func Count(model, text string) int {
+	return ForModel(model).Count(text)
+}
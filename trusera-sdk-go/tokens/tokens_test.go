@@ -0,0 +1,105 @@
+package tokens
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testVocabulary returns a vocabulary of all single bytes plus a few merges
+func testVocabulary() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, merge := range []string{"he", "ll", "hell", " w", " wor", "or"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	return b.String()
+}
+
+func TestSplit(t *testing.T) {
+	cases := map[string][]string{
+		"Hello world":      {"Hello", " world"},
+		"a  b":             {"a", " ", " b"},
+		"I'm 12345!":       {"I", "'m", " ", "123", "45", "!"},
+		"line\n\nnext":     {"line", "\n\n", "next"},
+		"trailing   ":      {"trailing", "   "},
+		"naïve café":       {"naïve", " café"},
+		"x = f(y);\n":      {"x", " =", " f", "(y", ");\n"},
+		"  indented\tcode": {" ", " indented", "\tcode"},
+	}
+	for in, want := range cases {
+		if got := split(cl100kPattern, in); !reflect.DeepEqual(got, want) {
+			t.Errorf("split(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := split(o200kPattern, "HelloWorld"); !reflect.DeepEqual(got, []string{"Hello", "World"}) {
+		t.Errorf("o200k should split on case changes, got %q", got)
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	enc, err := LoadEncoding("test", strings.NewReader(testVocabulary()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := enc.Encode("hello world")
+	want := []int{258, 'o', 260, 'l', 'd'}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode = %v, want %v", got, want)
+	}
+	if enc.Count("hello world") != 5 || enc.Name() != "test" {
+		t.Error("unexpected count or name")
+	}
+
+	if _, err := LoadEncoding("bad", strings.NewReader("!!! 1\n")); err == nil {
+		t.Error("expected an error for invalid base64")
+	}
+	if _, err := LoadEncoding("empty", strings.NewReader("")); err == nil {
+		t.Error("expected an error for an empty vocabulary")
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	// Exact cl100k_base counts
+	cases := map[string]int{
+		"The quick brown fox jumps over the lazy dog.": 10,
+		"Hello, world!": 4,
+	}
+	for text, exact := range cases {
+		got := Estimate(text)
+		if got < exact*8/10 || got > exact*12/10+1 {
+			t.Errorf("Estimate(%q) = %d, expected about %d", text, got, exact)
+		}
+	}
+	if Estimate("internationalization") >= Estimate("international organization") {
+		t.Error("expected long words to count as fewer tokens than several words")
+	}
+	if Estimate("") != 0 {
+		t.Error("expected 0 tokens for an empty text")
+	}
+}
+
+func TestForModel(t *testing.T) {
+	if EncodingForModel("gpt-4o-mini") != "o200k_base" || EncodingForModel("gpt-3.5-turbo") != "cl100k_base" ||
+		EncodingForModel("claude-sonnet-4-5") != "" {
+		t.Error("unexpected encodings")
+	}
+	if Count("gpt-4o", "Hello, world!") != Estimate("Hello, world!") {
+		t.Error("expected the estimate without a registered encoding")
+	}
+
+	enc, _ := LoadEncoding("o200k_base", strings.NewReader(testVocabulary()))
+	Register(enc)
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "o200k_base")
+		registryMu.Unlock()
+	}()
+	if ForModel("gpt-4o") != Counter(enc) || Count("gpt-4o", "hello") != 2 {
+		t.Error("expected the registered encoding")
+	}
+}
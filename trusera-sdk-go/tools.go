@@ -0,0 +1,149 @@
+package trusera
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// RiskTier rates the harm a tool can do
+type RiskTier string
+
+const (
+	RiskLow      RiskTier = "low"
+	RiskMedium   RiskTier = "medium"
+	RiskHigh     RiskTier = "high"
+	RiskCritical RiskTier = "critical"
+)
+
+// ToolInfo describes a tool the agent can call
+type ToolInfo struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"input_schema,omitempty"`  // JSON Schema of the arguments
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"` // JSON Schema of the result
+	Risk         RiskTier        `json:"risk,omitempty"`
+}
+
+// toolRegistry holds the registered tools and their compiled schemas
+type toolRegistry struct {
+	mu    sync.Mutex
+	tools []registeredTool
+}
+
+type registeredTool struct {
+	info          ToolInfo
+	input, output *jsonSchema
+}
+
+// toolInputKeys and toolOutputKeys are the payload fields that hold a tool
+// call's arguments and result in the integrations
+var (
+	toolInputKeys  = []string{"arguments", "input", "args"}
+	toolOutputKeys = []string{"output", "result"}
+)
+
+// RegisterTool declares a tool the agent can call. Its schemas support the
+// common JSON Schema keywords: type, enum, const, properties, required,
+// additionalProperties, items, minimum, maximum, minLength, maxLength,
+// pattern, minItems, maxItems, allOf, anyOf and oneOf.
+//
+// Once any tool is registered, EventToolCall events are checked: calls of a
+// registered tool get its risk_tier, and schema_violations listing how their
+// arguments (payload arguments, input or args) or result (output or result)
+// do not conform; calls of other tools get tool_registered false.
+func (c *Client) RegisterTool(info ToolInfo) error {
+	if info.Name == "" {
+		return errors.New("tool name is required")
+	}
+	tool := registeredTool{info: info}
+	var err error
+	if len(info.InputSchema) > 0 {
+		if tool.input, err = compileSchema(info.InputSchema); err != nil {
+			return fmt.Errorf("failed to parse input schema of %s: %w", info.Name, err)
+		}
+	}
+	if len(info.OutputSchema) > 0 {
+		if tool.output, err = compileSchema(info.OutputSchema); err != nil {
+			return fmt.Errorf("failed to parse output schema of %s: %w", info.Name, err)
+		}
+	}
+
+	c.tools.mu.Lock()
+	defer c.tools.mu.Unlock()
+	for i, t := range c.tools.tools {
+		if t.info.Name == info.Name {
+			c.tools.tools[i] = tool
+			return nil
+		}
+	}
+	c.tools.tools = append(c.tools.tools, tool)
+	return nil
+}
+
+// Tools returns the registered tools
+func (c *Client) Tools() []ToolInfo {
+	c.tools.mu.Lock()
+	defer c.tools.mu.Unlock()
+	tools := make([]ToolInfo, len(c.tools.tools))
+	for i, t := range c.tools.tools {
+		tools[i] = t.info
+	}
+	return tools
+}
+
+// checkTool validates a tool call against the registered tool
+func (c *Client) checkTool(event Event) Event {
+	if event.Type != EventToolCall {
+		return event
+	}
+	c.tools.mu.Lock()
+	registered := len(c.tools.tools) > 0
+	var tool *registeredTool
+	for i := range c.tools.tools {
+		if c.tools.tools[i].info.Name == event.Name {
+			t := c.tools.tools[i]
+			tool = &t
+			break
+		}
+	}
+	c.tools.mu.Unlock()
+
+	if tool == nil {
+		if registered {
+			event = event.WithPayload("tool_registered", false)
+		}
+		return event
+	}
+	if tool.info.Risk != "" {
+		event = event.WithPayload("risk_tier", string(tool.info.Risk))
+	}
+
+	var violations []string
+	violations = append(violations, validatePayload(event, tool.input, toolInputKeys)...)
+	violations = append(violations, validatePayload(event, tool.output, toolOutputKeys)...)
+	if len(violations) > 0 {
+		event = withViolations(event, violations)
+	}
+	return event
+}
+
+// validatePayload validates the first of keys present in the payload
+func validatePayload(event Event, schema *jsonSchema, keys []string) []string {
+	if schema == nil {
+		return nil
+	}
+	for _, key := range keys {
+		v, ok := event.Payload[key]
+		if !ok {
+			continue
+		}
+		value, err := toJSONValue(v)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %v", key, err)}
+		}
+		return schema.validate(value, key)
+	}
+	return nil
+}
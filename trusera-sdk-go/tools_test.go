@@ -0,0 +1,82 @@
+package trusera
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const refundSchema = `{
+	"type": "object",
+	"properties": {
+		"order_id": {"type": "string", "pattern": "^ord_[0-9]+$"},
+		"amount": {"type": "number", "exclusiveMinimum": 0, "maximum": 500},
+		"reason": {"enum": ["damaged", "late", "other"]},
+		"notify": {"type": "boolean"}
+	},
+	"required": ["order_id", "amount"],
+	"additionalProperties": false
+}`
+
+func TestRegisterTool(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if err := client.RegisterTool(ToolInfo{InputSchema: json.RawMessage(`{}`)}); err == nil {
+		t.Error("expected a tool without a name to be rejected")
+	}
+	if err := client.RegisterTool(ToolInfo{Name: "x", InputSchema: json.RawMessage(`{"type": 1}`)}); err == nil {
+		t.Error("expected an invalid schema to be rejected")
+	}
+	if err := client.RegisterTool(ToolInfo{Name: "x", InputSchema: json.RawMessage(`{"pattern": "("}`)}); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+	if err := client.RegisterTool(ToolInfo{Name: "refund", InputSchema: json.RawMessage(refundSchema), Risk: RiskHigh}); err != nil {
+		t.Fatal(err)
+	}
+	if tools := client.Tools(); len(tools) != 1 || tools[0].Risk != RiskHigh {
+		t.Errorf("unexpected tools %+v", tools)
+	}
+}
+
+func TestToolCallValidation(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour))
+	defer client.Close()
+	client.RegisterTool(ToolInfo{
+		Name:         "refund",
+		InputSchema:  json.RawMessage(refundSchema),
+		OutputSchema: json.RawMessage(`{"type": "object", "required": ["refund_id"]}`),
+		Risk:         RiskHigh,
+	})
+
+	client.Track(NewEvent(EventToolCall, "refund").
+		WithPayload("arguments", map[string]any{"order_id": "ord_42", "amount": 19.99}).
+		WithPayload("output", map[string]any{"refund_id": "re_1"}))
+	client.Track(NewEvent(EventToolCall, "refund").
+		WithPayload("input", `{"order_id": "42", "amount": 0, "reason": "changed mind", "coupon": "X"}`).
+		WithPayload("output", map[string]any{}))
+	client.Track(NewEvent(EventToolCall, "search"))
+
+	events := trackedEvents(client, EventToolCall)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 tool calls, got %d", len(events))
+	}
+	if events[0].Payload["risk_tier"] != "high" || events[0].Payload["schema_violations"] != nil {
+		t.Errorf("expected a conforming call, got %v", events[0].Payload)
+	}
+	want := []string{
+		"input.amount: not greater than 0",
+		"input.coupon: not allowed",
+		`input.order_id: does not match ^ord_[0-9]+$`,
+		`input.reason: must be one of ["damaged","late","other"]`,
+		"output.refund_id: required",
+	}
+	if got, _ := events[1].Payload["schema_violations"].([]string); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected violations:\n%s", strings.Join(got, "\n"))
+	}
+	if events[2].Payload["tool_registered"] != false {
+		t.Errorf("expected an unregistered tool to be flagged, got %v", events[2].Payload)
+	}
+}
@@ -0,0 +1,15 @@
+package trusera
+
+import "context"
+
+// Tracker is the event-tracking surface of *Client. Application code that
+// accepts a Tracker rather than a *Client can be tested with
+// truseratest.Recorder instead of a live client.
+type Tracker interface {
+	Track(event Event)
+	TrackCtx(ctx context.Context, event Event) error
+	TrackSync(ctx context.Context, event Event) (string, error)
+	Flush() error
+}
+
+var _ Tracker = (*Client)(nil)
@@ -0,0 +1,24 @@
+package trusera
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClientIsTracker(t *testing.T) {
+	sink := &memorySink{}
+	var tracker Tracker = NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink))
+	defer tracker.(*Client).Close()
+
+	tracker.Track(NewEvent(EventToolCall, "search"))
+	if err := tracker.TrackCtx(context.Background(), NewEvent(EventToolCall, "fetch")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if names := sink.names(); len(names) != 2 {
+		t.Errorf("expected both events to be delivered, got %v", names)
+	}
+}
@@ -0,0 +1,40 @@
+package trusera
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// WithHTTPTransport sets the http.RoundTripper used for flushes and policy
+// fetches, e.g. to route them through an egress proxy, add headers or stub
+// the API in tests. WithTLSConfig and WithClientCertificate apply to an
+// *http.Transport, which is cloned first, and are ignored for other
+// RoundTrippers.
+func WithHTTPTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = rt
+	}
+}
+
+// applyTransport installs the configured transport and TLS settings on the
+// client's HTTP client
+func (c *Client) applyTransport() {
+	rt := c.transport
+	if c.tlsConfig != nil {
+		if c.tlsConfig.MinVersion == 0 {
+			c.tlsConfig.MinVersion = tls.VersionTLS12
+		}
+		base, ok := rt.(*http.Transport)
+		if rt == nil {
+			base, ok = http.DefaultTransport.(*http.Transport)
+		}
+		if ok {
+			t := base.Clone()
+			t.TLSClientConfig = c.tlsConfig
+			rt = t
+		}
+	}
+	if rt != nil {
+		c.httpClient.Transport = rt
+	}
+}
@@ -0,0 +1,63 @@
+package trusera
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubTransport answers every request itself and records it
+type stubTransport struct {
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func (s *stubTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	s.mu.Unlock()
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Request: r}, nil
+}
+
+func TestWithHTTPTransport(t *testing.T) {
+	stub := &stubTransport{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithHTTPTransport(stub))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "x"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(stub.requests) != 1 || stub.requests[0].URL.Host != "api.trusera.io" {
+		t.Fatalf("expected the flush to use the transport, got %d requests", len(stub.requests))
+	}
+	if got := stub.requests[0].Header.Get("Authorization"); got != "Bearer test-key" {
+		t.Errorf("expected the API key header, got %q", got)
+	}
+}
+
+func TestWithHTTPTransportAndTLS(t *testing.T) {
+	base := &http.Transport{MaxIdleConns: 7}
+	client := NewClient("test-key", WithFlushInterval(time.Hour),
+		WithHTTPTransport(base),
+		WithTLSConfig(&tls.Config{ServerName: "ingest.internal"}))
+	defer client.Close()
+
+	transport := client.httpClient.Transport.(*http.Transport)
+	if transport == base || transport.MaxIdleConns != 7 || transport.TLSClientConfig.ServerName != "ingest.internal" {
+		t.Errorf("expected a clone of the transport with the TLS config, got %+v", transport)
+	}
+
+	stub := &stubTransport{}
+	custom := NewClient("test-key", WithFlushInterval(time.Hour),
+		WithTLSConfig(&tls.Config{}),
+		WithHTTPTransport(stub))
+	defer custom.Close()
+	if custom.httpClient.Transport != stub {
+		t.Error("expected other RoundTrippers to be used as they are")
+	}
+}
@@ -0,0 +1,210 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"unicode/utf8"
+)
+
+// truncationMarker precedes the hash of a truncated value's full content
+const truncationMarker = "…truncated, full hash: sha256:"
+
+// minTruncatedBytes is the shortest prefix WithTruncation cuts strings to
+// while shrinking an event to MaxEventBytes
+const minTruncatedBytes = 64
+
+// TruncationOptions configures WithTruncation. Zero limits are not enforced.
+type TruncationOptions struct {
+	// MaxFieldBytes caps every string in the payload, however deeply nested
+	MaxFieldBytes int
+
+	// FieldLimits caps the strings under specific top-level payload keys,
+	// overriding MaxFieldBytes
+	FieldLimits map[string]int
+
+	// MaxEventBytes caps an event's JSON encoding. Larger events have their
+	// longest payload strings cut until they fit; if that is not enough,
+	// the payload is replaced by a single truncated field.
+	MaxEventBytes int
+}
+
+// WithTruncation limits the size of tracked events, so that one huge prompt
+// or response cannot blow up batches or the disk queue. A truncated string
+// keeps its first bytes, cut on a character boundary, followed by
+// "…truncated, full hash: sha256:<hex>", the hash of the full value, which
+// still correlates it with copies kept elsewhere. Truncated events get
+// metadata truncated=true. Limits apply after the event hooks and redaction.
+func WithTruncation(opts TruncationOptions) Option {
+	return func(c *Client) {
+		c.truncation = &opts
+	}
+}
+
+// truncate enforces the truncation limits on an event
+func (c *Client) truncate(event Event) Event {
+	opts := c.truncation
+	if opts == nil || len(event.Payload) == 0 {
+		return event
+	}
+
+	truncated := false
+	if opts.MaxFieldBytes > 0 || len(opts.FieldLimits) > 0 {
+		payload := make(map[string]any, len(event.Payload))
+		for k, v := range event.Payload {
+			limit, ok := opts.FieldLimits[k]
+			if !ok {
+				limit = opts.MaxFieldBytes
+			}
+			if limit > 0 {
+				v = truncateValue(v, limit, &truncated)
+			}
+			payload[k] = v
+		}
+		event.Payload = payload
+	}
+
+	if opts.MaxEventBytes > 0 {
+		event = shrinkEvent(event, opts.MaxEventBytes, &truncated)
+	}
+	if truncated {
+		event = event.WithMetadata("truncated", true)
+	}
+	return event
+}
+
+// shrinkEvent cuts an event's strings until its encoding fits in maxBytes
+func shrinkEvent(event Event, maxBytes int, truncated *bool) Event {
+	size := encodedSize(event)
+	if size <= maxBytes {
+		return event
+	}
+
+	limit := longestString(event.Payload) / 2
+	for ; limit >= minTruncatedBytes && size > maxBytes; limit /= 2 {
+		payload := make(map[string]any, len(event.Payload))
+		for k, v := range event.Payload {
+			payload[k] = truncateValue(v, limit, truncated)
+		}
+		event.Payload = payload
+		size = encodedSize(event)
+	}
+	if size > maxBytes {
+		full, _ := json.Marshal(event.Payload)
+		event.Payload = map[string]any{"truncated": truncateString(string(full), 0)}
+		*truncated = true
+	}
+	return event
+}
+
+// truncateValue cuts every string in v to limit bytes. Composite values
+// other than maps and slices are converted to their JSON form first.
+func truncateValue(v any, limit int, truncated *bool) any {
+	switch val := v.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64, json.Number:
+		return v
+	case string:
+		if len(val) <= limit {
+			return val
+		}
+		out := truncateString(val, limit)
+		if out != val {
+			*truncated = true
+		}
+		return out
+	case []string:
+		out := make([]any, len(val))
+		for i, s := range val {
+			out[i] = truncateValue(s, limit, truncated)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = truncateValue(item, limit, truncated)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = truncateValue(item, limit, truncated)
+		}
+		return out
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Pointer:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var generic any
+		if json.Unmarshal(b, &generic) != nil {
+			return v
+		}
+		return truncateValue(generic, limit, truncated)
+	}
+	return v
+}
+
+// truncateString keeps at most limit bytes of s, on a character boundary,
+// followed by the marker and the hash of s. A string truncated before keeps
+// the hash of its original value.
+func truncateString(s string, limit int) string {
+	var prefix, tail string
+	if n, ok := truncatedAt(s); ok {
+		if n <= limit {
+			return s
+		}
+		prefix, tail = s[:n], s[n:]
+	} else {
+		sum := sha256.Sum256([]byte(s))
+		prefix, tail = s, truncationMarker+hex.EncodeToString(sum[:])
+	}
+	limit = min(limit, len(prefix))
+	for limit > 0 && limit < len(prefix) && !utf8.RuneStart(prefix[limit]) {
+		limit--
+	}
+	return prefix[:limit] + tail
+}
+
+// truncatedAt returns where the marker of an already truncated string starts
+func truncatedAt(s string) (int, bool) {
+	n := len(s) - 2*sha256.Size - len(truncationMarker)
+	if n < 0 || s[n:n+len(truncationMarker)] != truncationMarker {
+		return 0, false
+	}
+	return n, true
+}
+
+// longestString returns the length of the longest string in v
+func longestString(v any) int {
+	longest := 0
+	switch val := v.(type) {
+	case string:
+		return len(val)
+	case []string:
+		for _, s := range val {
+			longest = max(longest, len(s))
+		}
+	case map[string]any:
+		for _, item := range val {
+			longest = max(longest, longestString(item))
+		}
+	case []any:
+		for _, item := range val {
+			longest = max(longest, longestString(item))
+		}
+	}
+	return longest
+}
+
+// encodedSize returns the length of an event's JSON encoding
+func encodedSize(event Event) int {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
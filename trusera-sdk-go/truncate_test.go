@@ -0,0 +1,67 @@
+package trusera
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTruncationFieldLimits(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithTruncation(TruncationOptions{MaxFieldBytes: 10, FieldLimits: map[string]int{"prompt": 2}}))
+	defer client.Close()
+
+	prompt := "héllo world, this prompt is long"
+	client.Track(NewEvent(EventLLMInvoke, "chat").
+		WithPayload("prompt", prompt).
+		WithPayload("messages", []map[string]string{{"content": strings.Repeat("x", 20)}}).
+		WithPayload("model", "gpt-4o"))
+
+	e, _ := trackedEvent(client, "chat")
+	sum := sha256.Sum256([]byte(prompt))
+	if got := e.Payload["prompt"]; got != "h"+truncationMarker+hex.EncodeToString(sum[:]) {
+		t.Errorf("expected the prompt cut on a character boundary with its hash, got %q", got)
+	}
+	content := e.Payload["messages"].([]any)[0].(map[string]any)["content"].(string)
+	if !strings.HasPrefix(content, strings.Repeat("x", 10)+"…truncated, full hash: sha256:") {
+		t.Errorf("expected nested strings to be truncated, got %q", content)
+	}
+	if e.Payload["model"] != "gpt-4o" || e.Metadata["truncated"] != true {
+		t.Errorf("expected short fields kept and the event marked, got %v %v", e.Payload, e.Metadata)
+	}
+
+	client.Track(NewEvent(EventLLMInvoke, "short").WithPayload("prompt", "hi"))
+	if e, _ := trackedEvent(client, "short"); e.Metadata["truncated"] != nil {
+		t.Errorf("expected a small event to be left alone, got %v", e.Metadata)
+	}
+}
+
+func TestWithTruncationMaxEventBytes(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}),
+		WithTruncation(TruncationOptions{MaxFieldBytes: 4000, MaxEventBytes: 1024}))
+	defer client.Close()
+
+	client.Track(NewEvent(EventLLMInvoke, "huge").
+		WithPayload("prompt", strings.Repeat("p", 10000)).
+		WithPayload("completion", strings.Repeat("c", 3000)).
+		WithPayload("model", "gpt-4o"))
+	e, _ := trackedEvent(client, "huge")
+	data, _ := json.Marshal(e)
+	if len(data) > 1024 {
+		t.Errorf("expected the event to fit in 1024 bytes, got %d", len(data))
+	}
+	sum := sha256.Sum256([]byte(strings.Repeat("p", 10000)))
+	if prompt := e.Payload["prompt"].(string); !strings.HasSuffix(prompt, hex.EncodeToString(sum[:])) {
+		t.Errorf("expected the hash of the original prompt to survive shrinking, got %q", prompt)
+	}
+
+	numbers := make([]int, 1000)
+	client.Track(NewEvent(EventToolCall, "numbers").WithPayload("values", numbers))
+	e, _ = trackedEvent(client, "numbers")
+	if got, _ := e.Payload["truncated"].(string); !strings.HasPrefix(got, truncationMarker) || len(e.Payload) != 1 {
+		t.Errorf("expected a payload without strings to be replaced, got %v", e.Payload)
+	}
+}
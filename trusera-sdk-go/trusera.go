@@ -2,11 +2,19 @@ package trusera
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,23 +28,135 @@ const (
 type Client struct {
 	apiKey     string
 	baseURL    string
+	regionErr  error // set by WithRegion for an unknown region
 	agentID    string
+	state      AgentState // announced by Pause, Resume and Deregister; guarded by mu
+	project    string
 	httpClient *http.Client
+	tlsConfig  *tls.Config
+	transport  http.RoundTripper
 	events     []Event
 	mu         sync.Mutex
 	flushSize  int
 	done       chan struct{}
+	registered chan struct{} // signalled by RegisterAgent, for the policy syncer
 	ticker     *time.Ticker
 	wg         sync.WaitGroup
+	policy     atomic.Pointer[CELPolicy] // replaced when a config file is reloaded
+
+	spanContext SpanContextFunc
+	metrics     *clientMetrics
+
+	primary Sink // replaces the Trusera API when set
+	sinks   []namedSink
+	offline *FileSink // set by WithOfflineMode, closed with the client
+
+	queueDir      string
+	queue         *diskQueue
+	queueErr      error      // first write-ahead log failure, reported by the next Flush
+	queueMu       sync.Mutex // serializes queue flushes
+	maxQueueBytes int64      // caps the queue and spill directories; see WithMaxQueueBytes
+
+	maxBuffer int
+	overflow  OverflowPolicy
+	space     *sync.Cond // signalled when a flush empties the buffer
+	closed    bool
+	spillDir  string
+	spill     *diskQueue
+	spillTemp bool
+
+	maxBatchBytes int
+	maxBatchAge   time.Duration
+	flushWorkers  int
+	flushMu       sync.Mutex            // Serializes flushes whose order matters
+	flushQueued   atomic.Bool           // A flushAsync goroutine has not started flushing yet
+	asyncFlushes  sync.RWMutex          // Read-held by flushAsync goroutines while they flush, held by FlushCtx
+	eventRate     *rateLimit            // See WithMaxEventsPerSecond
+	bandwidth     *rateLimit            // See WithMaxFlushBandwidth
+	callLimits    map[string]*rateLimit // Rule rate limits by rule and limit
+	callLimitsMu  sync.Mutex
+	budget        *budgetState // See WithBudget
+	learner       learner      // See ModeLearn and WithLearning
+
+	shards        []bufferShard // See WithBufferShards
+	tickets       atomic.Uint64 // Orders the events in the shards
+	sharded       atomic.Int64  // Events in the shards
+	bufferedBytes int           // serialized size of buffered events, tracked when maxBatchBytes is set
+	ageTimer      *time.Timer   // flushes the buffer once maxBatchAge elapses
+
+	compressor   Compressor
+	uncompressed atomic.Bool // set once the API rejects compressed requests
+
+	retry       *RetryPolicy
+	retryBudget *retryBudget
+	breaker     *circuitBreaker
+	deadLetters *deadLetterFile
+
+	trackHooks []EventHook
+	sendHooks  []EventHook
+	sampler    Sampler
+	models     modelRegistry
+	datasets   datasetRegistry
+	tools      toolRegistry
+	schemas    schemaRegistry
+	truncation *TruncationOptions
+	dedup      *deduplicator
+	ordered    *orderedDelivery
+
+	clock       func() time.Time // See WithClock; nil means time.Now
+	nextID      func() string    // See WithIDGenerator
+	idMu        sync.Mutex
+	assignedIDs map[string]string // Replaced event IDs and their generated IDs
+
+	started   time.Time    // With a monotonic clock reading
+	skew      atomic.Int64 // Estimated API clock skew in nanoseconds
+	skewKnown atomic.Bool
+	signer    Signer
+	chain     *hashChain
+
+	errorHandler func(error)
+	logHandler   slog.Handler
+	logLevel     slog.Leveler
+	logger       *slog.Logger
+	dryRunOut    io.Writer
+	dropHandler  func(Event, DropReason)
+
+	manifest    *Manifest
+	manifestErr error // returned by RegisterAgent
+
+	keyProvider        KeyProvider
+	plaintextMigration bool        // See WithPlaintextMigration
+	cipher             *lineCipher // encrypts events written to disk; nil writes plaintext
+
+	policySync      time.Duration
+	policyMu        sync.Mutex // serializes policy fetches and guards the fields below
+	policyETag      string
+	policyCurrent   *RemotePolicy // last policy fetched from the API
+	policyFetchedAt time.Time
+	policyCache     string
+	policyTTL       time.Duration
+	policyFailure   PolicyFailureMode
+	policyFallback  string // fallback in effect, reported once per change
+	remotePolicy    atomic.Pointer[RemotePolicy]
+
+	configPath   string // set by NewClientFromConfig
+	configMu     sync.Mutex
+	configSum    [sha256.Size]byte // of the file last read
+	configStatic Config            // settings that need a restart to change
+	configPolicy atomic.Pointer[RemotePolicy]
+	redactor     atomic.Pointer[Redactor]
+
+	decisionLog bool // set by WithDecisionLog
 }
 
 // Option configures a Client
 type Option func(*Client)
 
-// WithBaseURL sets the Trusera API base URL
+// WithBaseURL sets the Trusera API base URL, e.g. of a self-hosted
+// deployment; it overrides WithRegion
 func WithBaseURL(url string) Option {
 	return func(c *Client) {
-		c.baseURL = url
+		c.baseURL, c.regionErr = strings.TrimRight(url, "/"), nil
 	}
 }
 
@@ -66,6 +186,13 @@ func WithBatchSize(n int) Option {
 	}
 }
 
+// WithPolicy sets a local CEL policy that is evaluated for every tracked event
+func WithPolicy(p *CELPolicy) Option {
+	return func(c *Client) {
+		c.policy.Store(p)
+	}
+}
+
 // NewClient creates a Trusera monitoring client
 func NewClient(apiKey string, opts ...Option) *Client {
 	c := &Client{
@@ -75,107 +202,510 @@ func NewClient(apiKey string, opts ...Option) *Client {
 		events:     make([]Event, 0, defaultBatchSize),
 		flushSize:  defaultBatchSize,
 		done:       make(chan struct{}),
+		registered: make(chan struct{}, 1),
 		ticker:     time.NewTicker(defaultFlushInterval),
+		metrics:    newClientMetrics(),
+		maxBuffer:  defaultMaxBufferSize,
+		overflow:   DropOldest,
 	}
+	c.space = sync.NewCond(&c.mu)
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.logger = newLogger(c.logHandler, c.logLevel)
+	c.applyClock()
+	if c.ordered != nil {
+		c.ordered.stream = c.newID()
+	}
+	c.applyTransport()
+	if c.dryRunOut != nil {
+		c.applyDryRun()
+	}
+	if c.manifest != nil {
+		c.manifestErr = c.applyManifest()
+	}
+
+	if c.keyProvider != nil {
+		c.cipher = loadLineCipher(c.keyProvider)
+		c.cipher.migrate = c.plaintextMigration
+	}
+	if c.deadLetters != nil {
+		c.deadLetters.cipher = c.cipher
+	}
+	if c.offline != nil {
+		c.offline.cipher = c.cipher
+	}
+	if c.chain != nil {
+		c.chain.cipher = c.cipher
+	}
+
+	if c.queueDir != "" {
+		c.queue, c.queueErr = c.openQueue(c.queueDir)
+	}
+	if c.overflow == SpillToDisk && c.queue == nil {
+		c.queueErr = c.openSpill()
+	}
+	if c.cipher != nil && c.cipher.err != nil {
+		c.queueErr = errors.Join(c.queueErr, c.cipher.err)
+	}
+	if c.queueErr != nil {
+		c.log(slog.LevelWarn, "failed to open the event queue", "error", c.queueErr)
+	}
+	c.useShards()
 
 	c.wg.Add(1)
 	go c.backgroundFlusher()
 
+	if c.policySync > 0 {
+		c.wg.Add(1)
+		go c.policySyncer()
+	}
+
+	if c.learner.period > 0 {
+		c.wg.Add(1)
+		go c.learnFor()
+	}
+
 	return c
 }
 
 // backgroundFlusher periodically flushes events
 func (c *Client) backgroundFlusher() {
 	defer c.wg.Done()
+
+	// Resume sending events persisted by a previous run
+	if c.queue != nil {
+		c.handleError(c.Flush())
+	}
+
 	for {
 		select {
 		case <-c.ticker.C:
-			_ = c.Flush()
+			c.handleError(c.Flush())
 		case <-c.done:
 			return
 		}
 	}
 }
 
+// CheckEvent evaluates the client's policy against an event without tracking it.
+// Agents can call this before performing an action to decide whether to proceed.
+func (c *Client) CheckEvent(event Event) PolicyDecision {
+	policy := c.policy.Load()
+	if policy == nil {
+		return PolicyDecision{
+			Decision: "Allow",
+			Reasons:  []string{"No policy configured"},
+			Matched:  []string{},
+		}
+	}
+	return policy.EvaluateEvent(event)
+}
+
 // Track queues an event for sending
 func (c *Client) Track(event Event) {
+	c.handleError(c.track(context.Background(), event))
+}
+
+// prepare runs the event hooks, the local policy and the sampler on an event,
+// chains and signs it and counts it as tracked. It returns false when emission is paused,
+// a hook dropped or vetoed the event, the sampler rejected it or signing failed.
+func (c *Client) prepare(event Event) (Event, bool, error) {
+	if c.emissionPaused(event) {
+		c.drop(DropReasonPaused, event)
+		return event, false, nil
+	}
+	if c.project != "" && projectOf(event) == "" {
+		event = event.WithProject(c.project)
+	}
+	event = c.stamp(event)
+	event, ok, err := runHooks(c.trackHooks, event)
+	if !ok {
+		c.drop(DropReasonHook, event)
+		return event, false, err
+	}
+	c.accrue(event)
+	if c.dedup != nil && c.dedup.duplicate(event) {
+		c.drop(DropReasonDuplicate, event)
+		return event, false, nil
+	}
+	if event, err = c.checkPayload(event); err != nil {
+		c.drop(DropReasonSchema, event)
+		return event, false, err
+	}
+	event = c.checkModel(event)
+	event = c.describeDataset(event)
+	event = c.checkTool(event)
+	event = c.checkDomain(event)
+	event = c.truncate(event)
+
+	if policy := c.policy.Load(); policy != nil {
+		decision := policy.EvaluateEvent(event)
+		if decision.Decision == "Deny" {
+			event = event.WithMetadata("policy_decision", decision.Decision).
+				WithMetadata("policy_reasons", decision.Reasons)
+		}
+	}
+
+	if c.sampler != nil && !c.sampler.Sample(event) {
+		c.drop(DropReasonSampled, event)
+		return event, false, nil
+	}
+
+	event.Monotonic = int64(c.monotonic())
+
+	var checkpoint *Event
+	if c.chain != nil {
+		if event, checkpoint, err = c.chain.link(event); err != nil {
+			return event, false, err
+		}
+	}
+	if c.signer != nil {
+		if err := SignEvent(&event, c.signer); err != nil {
+			return event, false, err
+		}
+	}
+	if c.chain != nil {
+		c.chain.record(event)
+	}
+
+	c.metrics.observeTrack(event)
+	if checkpoint != nil {
+		c.Track(*checkpoint)
+	}
+	return event, true, nil
+}
+
+// track queues an event, giving up if ctx ends while BlockCaller waits for space
+func (c *Client) track(ctx context.Context, event Event) error {
+	event, ok, err := c.prepare(event)
+	if !ok {
+		return err
+	}
+	_, _, err = c.enqueue(ctx, event)
+	return err
+}
+
+// enqueue buffers a prepared event, returning it as buffered. It reports
+// false if the overflow policy discarded it, for the buffer or the event
+// rate.
+func (c *Client) enqueue(ctx context.Context, event Event) (Event, bool, error) {
+	limited, err := c.rateLimited(ctx, event)
+	if err != nil {
+		return event, false, err
+	}
+	if c.shards != nil {
+		event, ok := c.enqueueShard(event, limited)
+		return event, ok, nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if limited && !c.rateLimitedLocked(event) {
+		return event, false, nil
+	}
+	event = c.sequenceLocked(event)
+	if c.queue != nil {
+		if err := c.queue.append(event); errors.Is(err, errQueueFull) {
+			c.drop(DropReasonOverflow, event)
+			return event, false, nil
+		} else if err != nil && c.queueErr == nil {
+			c.queueErr = err
+		}
+	} else if len(c.events) >= c.maxBuffer {
+		if keep, err := c.overflowLocked(ctx, event); !keep {
+			return event, false, err
+		}
+	}
 	c.events = append(c.events, event)
 
-	if len(c.events) >= c.flushSize {
-		go func() {
-			_ = c.Flush()
-		}()
+	if c.bufferedLocked(event) {
+		c.flushAsync()
 	}
+	return event, true, nil
 }
 
 // Flush sends all queued events to the API
 func (c *Client) Flush() error {
+	return c.FlushCtx(context.Background())
+}
+
+// FlushCtx sends all queued events to the API. Requests and retry waits
+// are abandoned when ctx is done. Background flushes that already took
+// events from the buffer are waited for, so that every event queued before
+// the call has been sent when it returns.
+func (c *Client) FlushCtx(ctx context.Context) error {
+	c.asyncFlushes.Lock()
+	defer c.asyncFlushes.Unlock()
+	return c.flush(ctx)
+}
+
+// flush is FlushCtx without waiting for background flushes
+func (c *Client) flush(ctx context.Context) error {
+	if c.ordered != nil || c.flushWorkers > 1 {
+		// A later flush must not overtake this one
+		c.flushMu.Lock()
+		defer c.flushMu.Unlock()
+	}
+
 	c.mu.Lock()
-	if len(c.events) == 0 {
+	c.resetBatchLocked()
+	if c.queue != nil {
+		sealErr := errors.Join(c.queueErr, c.queue.seal())
+		c.queueErr = nil
+		c.events = c.events[:0]
 		c.mu.Unlock()
-		return nil
+		return errors.Join(sealErr, c.flushSegments(ctx, c.queue))
 	}
 
 	events := make([]Event, len(c.events))
 	copy(events, c.events)
 	c.events = c.events[:0]
+	c.space.Broadcast()
+
+	var spillErr error
+	if c.spill != nil {
+		spillErr = errors.Join(c.queueErr, c.spill.seal())
+		c.queueErr = nil
+	}
 	c.mu.Unlock()
+	if c.shards != nil {
+		events = append(events, c.drainShards()...)
+	}
 
+	var err error
+	if len(events) > 0 {
+		undelivered, sendErr, exportErr := c.deliverBatches(ctx, events)
+		if len(undelivered) > 0 && (c.ordered == nil || !c.requeue(undelivered)) {
+			c.drop(DropReasonFlushError, undelivered...)
+		}
+		err = errors.Join(sendErr, exportErr)
+	}
+	if c.spill != nil {
+		err = errors.Join(err, spillErr, c.flushSegments(ctx, c.spill))
+	}
+	return err
+}
+
+// deliver sends a batch to the primary sink and fans it out to the
+// secondary sinks, returning the API's response body
+func (c *Client) deliver(ctx context.Context, events []Event) (resp []byte, sendErr, exportErr error) {
+	start := time.Now()
+	resp, sendErr = c.send(ctx, events)
+	c.metrics.observeFlush(len(events), time.Since(start), sendErr)
+	if sendErr == nil {
+		c.log(slog.LevelDebug, "flushed events", "count", len(events), "duration", time.Since(start))
+	}
+
+	exportErr = c.fanOut(ctx, events)
+	return resp, sendErr, exportErr
+}
+
+// marshalBatch builds the body of an events request
+func (c *Client) marshalBatch(agentID string, events []Event) ([]byte, error) {
 	payload := map[string]interface{}{
-		"agent_id": c.agentID,
+		"agent_id": agentID,
 		"events":   events,
+		"clock":    c.batchClock(),
+	}
+	if d := delivery(events); d != nil {
+		payload["delivery"] = d
+	}
+	if len(events) > 0 && projectOf(events[0]) != "" {
+		payload["project_id"] = projectOf(events[0])
 	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal events: %w", err)
+		return nil, fmt.Errorf("failed to marshal events: %w", err)
+	}
+	return body, nil
+}
+
+// send delivers a batch of events to the primary sink, the Trusera API
+// unless WithPrimarySink replaced it, and returns the API's response body
+func (c *Client) send(ctx context.Context, events []Event) ([]byte, error) {
+	var attempt func() ([]byte, error)
+	if c.primary != nil {
+		sinkCtx := c.sinkContext(ctx, events)
+		attempt = func() ([]byte, error) {
+			return nil, c.primary.Send(sinkCtx, events)
+		}
+	} else {
+		body, err := c.marshalBatch(c.agentID, events)
+		if err != nil {
+			return nil, err
+		}
+		bodies := [][]byte{body}
+		if c.maxBatchBytes > 0 && len(body) > c.maxBatchBytes && len(events) == 1 {
+			chunks, err := c.chunkBodies(events[0])
+			if err != nil {
+				return nil, err
+			}
+			if chunks != nil {
+				bodies = chunks
+			}
+		}
+		attempt = func() ([]byte, error) {
+			var reply []byte
+			for _, body := range bodies {
+				if reply, err = c.postNegotiated(ctx, body); err != nil {
+					return reply, err
+				}
+			}
+			return reply, c.checkAck(reply, events)
+		}
+	}
+
+	if c.breaker != nil && !c.breaker.allow() {
+		c.metrics.observeShortCircuit()
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := c.retrying(ctx, attempt)
+	if err == nil && c.primary != nil && len(events) > 0 {
+		// A sink acknowledges a batch by accepting it
+		c.acknowledge(events[0].Stream, events[len(events)-1].Sequence)
+	}
+	if c.breaker != nil {
+		from := c.breaker.current()
+		c.breaker.record(err)
+		if to := c.breaker.current(); to != from {
+			level := slog.LevelInfo
+			if to == CircuitOpen {
+				level = slog.LevelWarn
+			}
+			c.log(level, "circuit breaker state changed", "from", string(from), "to", string(to))
+		}
+	}
+	return resp, err
+}
+
+// retrying runs attempt, retrying it under the client's retry policy
+func (c *Client) retrying(ctx context.Context, attempt func() ([]byte, error)) ([]byte, error) {
+	c.retryBudget.deposit()
+	resp, err := attempt()
+	for retry := 1; err != nil && c.shouldRetry(retry, err); retry++ {
+		c.metrics.observeRetry()
+		c.log(slog.LevelInfo, "retrying flush", "retry", retry, "error", err)
+		if werr := sleepCtx(ctx, c.retry.backoff(retry, err)); werr != nil {
+			err = errors.Join(err, werr)
+			break
+		}
+		resp, err = attempt()
+	}
+	return resp, err
+}
+
+// postNegotiated sends a request body with the configured compression,
+// falling back to an uncompressed body if the API rejects the encoding
+func (c *Client) postNegotiated(ctx context.Context, body []byte) ([]byte, error) {
+	if c.compressor != nil && !c.uncompressed.Load() {
+		resp, err := c.post(ctx, body, c.compressor)
+		var se *statusError
+		if !errors.As(err, &se) || se.code != http.StatusUnsupportedMediaType {
+			return resp, err
+		}
+		// The API does not accept this encoding; fall back for good
+		c.uncompressed.Store(true)
+	}
+	return c.post(ctx, body, nil)
+}
+
+// statusError reports an error status returned by the API
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+	body       string // start of the response body
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API returned status %d", e.code)
+}
+
+// post sends an events request body, compressing it with comp if non-nil,
+// and returns the response body
+func (c *Client) post(ctx context.Context, body []byte, comp Compressor) ([]byte, error) {
+	key := idempotencyKey(body)
+	if comp != nil {
+		compressed, err := compress(comp, body)
+		if err != nil {
+			return nil, err
+		}
+		body = compressed
+	}
+	if err := c.throttle(ctx, len(body)); err != nil {
+		return nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/v1/events", bytes.NewReader(body))
+	endpoint, err := c.endpoint("/v1/events")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Idempotency-Key", key)
+	if comp != nil {
+		req.Header.Set("Content-Encoding", comp.Encoding())
+	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send events: %w", err)
+		return nil, fmt.Errorf("failed to send events: %w", err)
 	}
 	defer resp.Body.Close()
+	c.observeSkew(resp, start)
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API returned status %d", resp.StatusCode)
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &statusError{
+			code:       resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			body:       strings.TrimSpace(string(msg)),
+		}
 	}
 
-	return nil
+	// The events were accepted, so a failure to read the reply is not an error
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return reply, nil
 }
 
-// RegisterAgent registers an agent with Trusera, returns agent ID
+// RegisterAgent registers an agent with Trusera, returns agent ID. The
+// manifest set by WithManifest, if any, is sent along.
 func (c *Client) RegisterAgent(name, framework string) (string, error) {
 	if name == "" {
 		return "", errors.New("agent name is required")
 	}
+	if c.manifestErr != nil {
+		return "", c.manifestErr
+	}
 
-	payload := map[string]string{
+	payload := map[string]any{
 		"name":      name,
 		"framework": framework,
 	}
+	if c.manifest != nil {
+		payload["manifest"] = c.manifest
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/v1/agents", bytes.NewReader(body))
+	endpoint, err := c.endpoint("/v1/agents")
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -204,15 +734,81 @@ func (c *Client) RegisterAgent(name, framework string) (string, error) {
 	c.mu.Lock()
 	c.agentID = result.AgentID
 	c.mu.Unlock()
+	select {
+	case c.registered <- struct{}{}:
+	default:
+	}
 
 	return result.AgentID, nil
 }
 
 // Close flushes remaining events and stops background goroutine
 func (c *Client) Close() error {
+	_, err := c.CloseCtx(context.Background())
+	return err
+}
+
+// DrainReport accounts for what became of the client's events while it
+// was closed
+type DrainReport struct {
+	Buffered     int // Events in memory when Close began
+	Flushed      int // Delivered while draining
+	DeadLettered int // Rejected and written to the dead-letter file
+	Persisted    int // Left in the persistent queue or spill directory for the next run
+	Dropped      int // Lost because they could not be delivered or kept
+	Duration     time.Duration
+}
+
+// CloseCtx stops the background goroutines and drains the buffer, giving up
+// on requests and retry waits when ctx is done, e.g. at a shutdown
+// deadline. The report says how many events were flushed and how many were
+// kept on disk or lost.
+func (c *Client) CloseCtx(ctx context.Context) (DrainReport, error) {
+	start := time.Now()
+	before := c.Stats()
+	report := DrainReport{Buffered: before.BufferDepth}
+
 	c.ticker.Stop()
 	close(c.done)
 	c.wg.Wait()
 
-	return c.Flush()
+	if c.chain != nil {
+		for _, checkpoint := range c.chain.checkpoints() {
+			c.Track(checkpoint)
+		}
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.space.Broadcast()
+	c.mu.Unlock()
+
+	err := c.FlushCtx(ctx)
+	for _, q := range []*diskQueue{c.queue, c.spill} {
+		if q != nil {
+			report.Persisted += q.pending()
+		}
+	}
+	if c.queue != nil {
+		err = errors.Join(err, c.queue.close())
+	}
+	if c.spill != nil {
+		err = errors.Join(err, c.spill.close())
+		if c.spillTemp {
+			os.Remove(c.spill.dir) // only succeeds once every spilled event was sent
+		}
+	}
+	if c.offline != nil {
+		err = errors.Join(err, c.offline.Close())
+	}
+	if c.chain != nil {
+		err = errors.Join(err, c.chain.close())
+	}
+
+	after := c.Stats()
+	report.Flushed = int(after.EventsFlushed - before.EventsFlushed)
+	report.DeadLettered = int(after.EventsDeadLettered - before.EventsDeadLettered)
+	report.Dropped = int(after.EventsDroppedFlush - before.EventsDroppedFlush)
+	report.Duration = time.Since(start)
+	return report, err
 }
@@ -1,7 +1,9 @@
 package trusera
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"sync"
@@ -291,3 +293,66 @@ func TestClose(t *testing.T) {
 		t.Errorf("expected events to be flushed on close, got %d remaining", eventCount)
 	}
 }
+
+func TestCloseCtxReport(t *testing.T) {
+	sink := &memorySink{}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink))
+	for i := 0; i < 3; i++ {
+		client.Track(NewEvent(EventToolCall, "search"))
+	}
+
+	report, err := client.CloseCtx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Buffered != 3 || report.Flushed != 3 || report.Dropped != 0 || report.Persisted != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(sink.names()) != 3 {
+		t.Errorf("expected 3 events delivered, got %d", len(sink.names()))
+	}
+}
+
+func TestCloseCtxReportsDroppedEvents(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{fail: errors.New("down")}))
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b"))
+
+	report, err := client.CloseCtx(context.Background())
+	if err == nil {
+		t.Error("expected the failed drain to be reported")
+	}
+	if report.Buffered != 2 || report.Flushed != 0 || report.Dropped != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestCloseCtxDeadline(t *testing.T) {
+	api := newFlakyAPI(t)
+	api.fail.Store(true)
+	client := NewClient("test-key", WithBaseURL(api.server.URL), WithFlushInterval(time.Hour),
+		WithRetry(RetryPolicy{MaxAttempts: 100, InitialBackoff: time.Second}))
+	client.Track(NewEvent(EventToolCall, "a"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report, err := client.CloseCtx(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline to cut the drain short, got %v", err)
+	}
+	if report.Dropped != 1 || report.Duration > time.Second {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestCloseCtxReportsPersistedEvents(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour),
+		WithPersistentQueue(t.TempDir()), WithPrimarySink(&memorySink{fail: errors.New("down")}))
+	client.Track(NewEvent(EventToolCall, "a"))
+	client.Track(NewEvent(EventToolCall, "b"))
+
+	report, _ := client.CloseCtx(context.Background())
+	if report.Persisted != 2 || report.Dropped != 0 {
+		t.Errorf("expected the events to be kept on disk, got %+v", report)
+	}
+}
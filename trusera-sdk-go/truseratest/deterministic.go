@@ -0,0 +1,35 @@
+package truseratest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NewClock returns a clock for trusera.WithClock that reads start first and
+// advances by step on every reading, so durations are never zero and runs
+// are reproducible. It is safe for concurrent use.
+func NewClock(start time.Time, step time.Duration) func() time.Time {
+	var mu sync.Mutex
+	next := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now := next
+		next = next.Add(step)
+		return now
+	}
+}
+
+// SequentialIDs returns an ID generator for trusera.WithIDGenerator that
+// yields prefix-1, prefix-2 and so on. It is safe for concurrent use.
+func SequentialIDs(prefix string) func() string {
+	var mu sync.Mutex
+	n := 0
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return fmt.Sprintf("%s-%d", prefix, n)
+	}
+}
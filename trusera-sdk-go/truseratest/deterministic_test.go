@@ -0,0 +1,49 @@
+package truseratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func TestNewClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start, time.Second)
+	if got := clock(); !got.Equal(start) {
+		t.Errorf("expected the first reading at start, got %v", got)
+	}
+	if got := clock(); !got.Equal(start.Add(time.Second)) {
+		t.Errorf("expected the clock to step, got %v", got)
+	}
+}
+
+func TestSequentialIDs(t *testing.T) {
+	next := SequentialIDs("evt")
+	if a, b := next(), next(); a != "evt-1" || b != "evt-2" {
+		t.Errorf("expected evt-1 and evt-2, got %s and %s", a, b)
+	}
+}
+
+func TestDeterministicEventStream(t *testing.T) {
+	run := func() []byte {
+		client, rec := NewClient(t,
+			trusera.WithClock(NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)),
+			trusera.WithIDGenerator(SequentialIDs("evt")))
+		span := client.StartSpan(trusera.EventToolCall, "search")
+		client.TrackCtx(span.Context(), trusera.NewEvent(trusera.EventLLMInvoke, "answer").WithPayload("model", "gpt-4o"))
+		span.End(nil)
+		data, err := json.Marshal(rec.Events())
+		if err != nil {
+			t.Fatalf("failed to marshal events: %v", err)
+		}
+		return data
+	}
+
+	first, second := run(), run()
+	if !bytes.Equal(first, second) {
+		t.Errorf("expected byte-identical event streams:\n%s\n%s", first, second)
+	}
+}
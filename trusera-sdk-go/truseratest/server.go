@@ -0,0 +1,248 @@
+package truseratest
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// Server is a fake Trusera API. It registers agents, serves the policy set
+// with SetPolicy, records delivered events and agent state changes, and can
+// fail requests to simulate an outage. It acknowledges the sequence numbers
+// of trusera.WithOrderedDelivery, recording a redelivered event once, and
+// reassembles events split into chunks by trusera.WithMaxBatchBytes. It
+// reports the delivered events and their cost_usd as usage, against the
+// quotas set with SetQuota. Point a client at it with
+// trusera.WithBaseURL(server.URL).
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	agents  []Agent
+	events  []trusera.Event
+	seen    map[string]map[uint64]bool // Sequence numbers received per stream
+	highest map[string]uint64
+	chunks  *trusera.ChunkAssembler
+	states  map[string]trusera.AgentState
+	policy  *trusera.RemotePolicy
+	version int
+	status  int
+	quota   trusera.Usage
+}
+
+// Agent is an agent registered with a Server
+type Agent struct {
+	ID        string
+	Name      string
+	Framework string
+}
+
+// NewServer starts a fake API that is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		states:  map[string]trusera.AgentState{},
+		seen:    map[string]map[uint64]bool{},
+		highest: map[string]uint64{},
+		chunks:  trusera.NewChunkAssembler(),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetPolicy sets the policy served to every agent; nil serves an empty one
+func (s *Server) SetPolicy(p *trusera.RemotePolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+	s.version++
+}
+
+// SetQuota sets the event and USD quotas reported by the usage endpoint;
+// zero is unlimited
+func (s *Server) SetQuota(events int64, costUSD float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quota.EventQuota, s.quota.CostQuotaUSD = events, costUSD
+}
+
+// Fail makes every request fail with status until Fail(0) is called
+func (s *Server) Fail(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// Events returns the events delivered so far
+func (s *Server) Events() []trusera.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]trusera.Event(nil), s.events...)
+}
+
+// Agents returns the registered agents
+func (s *Server) Agents() []Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Agent(nil), s.agents...)
+}
+
+// State returns the lifecycle state an agent last announced
+func (s *Server) State(agentID string) trusera.AgentState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state, ok := s.states[agentID]; ok {
+		return state
+	}
+	return trusera.AgentActive
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	agentID, rest, _ := strings.Cut(strings.TrimPrefix(path, "agents/"), "/")
+	switch {
+	case r.Method == http.MethodPost && path == "events":
+		s.receiveEvents(w, r)
+	case r.Method == http.MethodPost && path == "agents":
+		s.register(w, r)
+	case r.Method == http.MethodGet && rest == "policy":
+		s.servePolicy(w, r)
+	case r.Method == http.MethodGet && path == "usage":
+		s.serveUsage(w)
+	case r.Method == http.MethodPut && rest == "state":
+		var body struct {
+			State trusera.AgentState `json:"state"`
+		}
+		if json.NewDecoder(r.Body).Decode(&body) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.states[agentID] = body.State
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "agents/") && rest == "":
+		s.states[agentID] = trusera.AgentDeregistered
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// receiveEvents records a batch. Called with mu held.
+func (s *Server) receiveEvents(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var batch struct {
+		Events []trusera.Event `json:"events"`
+	}
+	if err := json.NewDecoder(body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	reply := map[string]any{}
+	ids := make([]string, 0, len(batch.Events))
+	for _, e := range batch.Events {
+		e, complete, err := s.chunks.Add(e)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !complete {
+			continue
+		}
+		ids = append(ids, e.ID)
+		if e.Sequence == 0 {
+			s.events = append(s.events, e)
+			continue
+		}
+		if high := s.highest[e.Stream]; e.Sequence > high+1 && reply["expected_sequence"] == nil {
+			reply["expected_sequence"] = high + 1
+		}
+		reply["acked_sequence"] = e.Sequence
+		if s.seen[e.Stream] == nil {
+			s.seen[e.Stream] = map[uint64]bool{}
+		}
+		if !s.seen[e.Stream][e.Sequence] {
+			s.seen[e.Stream][e.Sequence] = true
+			s.events = append(s.events, e)
+		}
+		s.highest[e.Stream] = max(s.highest[e.Stream], e.Sequence)
+	}
+	reply["event_ids"] = ids
+	json.NewEncoder(w).Encode(reply)
+}
+
+// register registers an agent. Called with mu held.
+func (s *Server) register(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name      string `json:"name"`
+		Framework string `json:"framework"`
+	}
+	if json.NewDecoder(r.Body).Decode(&body) != nil || body.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	agent := Agent{ID: fmt.Sprintf("agent-%d", len(s.agents)+1), Name: body.Name, Framework: body.Framework}
+	s.agents = append(s.agents, agent)
+	json.NewEncoder(w).Encode(map[string]string{"agent_id": agent.ID})
+}
+
+// servePolicy serves the policy with an ETag of its version. Called with
+// mu held.
+func (s *Server) servePolicy(w http.ResponseWriter, r *http.Request) {
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	policy := s.policy
+	if policy == nil {
+		policy = &trusera.RemotePolicy{}
+	}
+	w.Header().Set("ETag", etag)
+	json.NewEncoder(w).Encode(policy)
+}
+
+// serveUsage reports the delivered events and their cost. Called with mu
+// held.
+func (s *Server) serveUsage(w http.ResponseWriter) {
+	usage := s.quota
+	usage.Events = int64(len(s.events))
+	for _, e := range s.events {
+		if usd, ok := e.Payload["cost_usd"].(float64); ok {
+			usage.CostUSD += usd
+		}
+	}
+	json.NewEncoder(w).Encode(usage)
+}
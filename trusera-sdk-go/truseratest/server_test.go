@@ -0,0 +1,136 @@
+package truseratest
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	server.SetPolicy(&trusera.RemotePolicy{Enforcement: trusera.ModeBlock, BlockPatterns: []string{"pastebin.com"}})
+
+	client := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL),
+		trusera.WithFlushInterval(time.Hour), trusera.WithCompression(trusera.Gzip))
+	defer client.Close()
+
+	agentID, err := client.RegisterAgent("payments", "custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agents := server.Agents(); len(agents) != 1 || agents[0].ID != agentID || agents[0].Name != "payments" {
+		t.Errorf("expected the agent to be registered, got %+v", agents)
+	}
+
+	if err := client.SyncPolicy(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if p := client.RemotePolicy(); p == nil || p.Enforcement != trusera.ModeBlock {
+		t.Errorf("expected the served policy, got %+v", p)
+	}
+
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "calculator"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if events := server.Events(); len(events) != 1 || events[0].Name != "calculator" {
+		t.Errorf("expected the event to be delivered, got %+v", events)
+	}
+
+	if err := client.Pause(context.Background(), "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+	if state := server.State(agentID); state != trusera.AgentPaused {
+		t.Errorf("expected the agent to be paused, got %s", state)
+	}
+}
+
+func TestServerFail(t *testing.T) {
+	server := NewServer(t)
+	client := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL), trusera.WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	server.Fail(http.StatusServiceUnavailable)
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "calculator"))
+	if err := client.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	server.Fail(0)
+	if _, err := client.RegisterAgent("payments", "custom"); err != nil {
+		t.Errorf("expected the server to recover, got %v", err)
+	}
+}
+
+func TestServerOrderedDelivery(t *testing.T) {
+	server := NewServer(t)
+	client := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL),
+		trusera.WithFlushInterval(time.Hour), trusera.WithOrderedDelivery())
+	defer client.Close()
+
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "first"))
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "second"))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got := client.Acknowledged(); got != 2 {
+		t.Errorf("expected sequence 2 acknowledged, got %d", got)
+	}
+
+	// A redelivered event is recorded once
+	events := server.Events()
+	replay := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL), trusera.WithFlushInterval(time.Hour))
+	defer replay.Close()
+	for _, e := range events {
+		replay.Track(e)
+	}
+	replay.Flush()
+	if n := len(server.Events()); n != 2 {
+		t.Errorf("expected redelivered events recorded once, got %d events", n)
+	}
+}
+
+func TestServerReassemblesChunks(t *testing.T) {
+	server := NewServer(t)
+	client := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL),
+		trusera.WithFlushInterval(time.Hour), trusera.WithMaxBatchBytes(1024), trusera.WithOrderedDelivery())
+	defer client.Close()
+
+	prompt := strings.Repeat("x", 5000)
+	client.Track(trusera.NewEvent(trusera.EventLLMInvoke, "huge").WithPayload("prompt", prompt))
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	events := server.Events()
+	if len(events) != 1 || events[0].Payload["prompt"] != prompt {
+		t.Fatalf("expected the chunked event recorded whole, got %d events", len(events))
+	}
+	if got := client.Acknowledged(); got != 1 {
+		t.Errorf("expected the chunked event acknowledged, got %d", got)
+	}
+}
+
+func TestServerUsage(t *testing.T) {
+	server := NewServer(t)
+	server.SetQuota(10, 5)
+	client := trusera.NewClient("test-key", trusera.WithBaseURL(server.URL), trusera.WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	client.Track(trusera.NewEvent(trusera.EventLLMInvoke, "gpt-4o").WithPayload("cost_usd", 1.5))
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "search"))
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := client.GetUsage(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Events != 2 || usage.EventsRemaining() != 8 || usage.CostUSD != 1.5 || usage.CostRemaining() != 3.5 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
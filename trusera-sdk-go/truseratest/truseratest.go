@@ -0,0 +1,171 @@
+// Package truseratest helps unit test code instrumented with the Trusera SDK.
+//
+// A Recorder captures events in memory and asserts on them. Pass it where
+// the code under test accepts a trusera.Tracker, or use NewClient to get a
+// real *trusera.Client, with its hooks, redaction and policies, that
+// delivers to a Recorder instead of the API:
+//
+//	client, rec := truseratest.NewClient(t)
+//	runAgent(client)
+//	rec.AssertTracked(t, trusera.EventToolCall, "calculator")
+//
+// Server is a fake Trusera API for tests of registration, policy sync and
+// delivery over HTTP.
+package truseratest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// Recorder records events in memory. It implements trusera.Tracker and
+// trusera.Sink and is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []trusera.Event
+	client *trusera.Client
+}
+
+var (
+	_ trusera.Tracker = (*Recorder)(nil)
+	_ trusera.Sink    = (*Recorder)(nil)
+)
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewClient creates a client that delivers to a Recorder instead of the
+// API. It only flushes when the recorder is read or Flush is called, and it
+// is closed when the test ends. opts are applied before the recorder is
+// installed as the primary sink.
+func NewClient(t testing.TB, opts ...trusera.Option) (*trusera.Client, *Recorder) {
+	t.Helper()
+	r := NewRecorder()
+	opts = append([]trusera.Option{trusera.WithFlushInterval(time.Hour)}, opts...)
+	opts = append(opts, trusera.WithPrimarySink(r))
+	r.client = trusera.NewClient("test-key", opts...)
+	t.Cleanup(func() {
+		r.client.Close()
+	})
+	return r.client, r
+}
+
+// Track records an event
+func (r *Recorder) Track(event trusera.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// TrackCtx records an event unless ctx is done
+func (r *Recorder) TrackCtx(ctx context.Context, event trusera.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.Track(event)
+	return nil
+}
+
+// TrackSync records an event and returns its ID
+func (r *Recorder) TrackSync(ctx context.Context, event trusera.Event) (string, error) {
+	if err := r.TrackCtx(ctx, event); err != nil {
+		return "", err
+	}
+	return event.ID, nil
+}
+
+// Flush flushes the client created by NewClient, if any
+func (r *Recorder) Flush() error {
+	if r.client == nil {
+		return nil
+	}
+	return r.client.Flush()
+}
+
+// Send records a batch delivered by a client
+func (r *Recorder) Send(ctx context.Context, events []trusera.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, events...)
+	return nil
+}
+
+// Events returns the recorded events in order, flushing the client first
+func (r *Recorder) Events() []trusera.Event {
+	r.Flush()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]trusera.Event(nil), r.events...)
+}
+
+// Find returns the recorded events of a type and name; an empty name
+// matches any
+func (r *Recorder) Find(eventType trusera.EventType, name string) []trusera.Event {
+	var found []trusera.Event
+	for _, e := range r.Events() {
+		if e.Type == eventType && (name == "" || e.Name == name) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Reset discards the recorded events
+func (r *Recorder) Reset() {
+	r.Flush()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// AssertTracked fails the test unless an event of the type and name was
+// recorded, and returns the first one for further checks
+func (r *Recorder) AssertTracked(t testing.TB, eventType trusera.EventType, name string) trusera.Event {
+	t.Helper()
+	found := r.Find(eventType, name)
+	if len(found) == 0 {
+		t.Errorf("expected a %s event named %q, got %s", eventType, name, r.summary())
+		return trusera.Event{}
+	}
+	return found[0]
+}
+
+// AssertNotTracked fails the test if an event of the type and name was
+// recorded
+func (r *Recorder) AssertNotTracked(t testing.TB, eventType trusera.EventType, name string) {
+	t.Helper()
+	if found := r.Find(eventType, name); len(found) > 0 {
+		t.Errorf("expected no %s event named %q, got %d", eventType, name, len(found))
+	}
+}
+
+// AssertCount fails the test unless exactly want events of the type were
+// recorded
+func (r *Recorder) AssertCount(t testing.TB, eventType trusera.EventType, want int) {
+	t.Helper()
+	if got := len(r.Find(eventType, "")); got != want {
+		t.Errorf("expected %d %s events, got %d: %s", want, eventType, got, r.summary())
+	}
+}
+
+// summary lists the recorded events for failure messages
+func (r *Recorder) summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return "no events"
+	}
+	names := make([]string, len(r.events))
+	for i, e := range r.events {
+		names[i] = fmt.Sprintf("%s/%s", e.Type, e.Name)
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
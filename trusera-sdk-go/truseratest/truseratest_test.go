@@ -0,0 +1,78 @@
+package truseratest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	trusera "github.com/Trusera/ai-bom/trusera-sdk-go"
+)
+
+// fakeT captures failures of assertions that are expected to fail
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func TestRecorderAsTracker(t *testing.T) {
+	rec := NewRecorder()
+	var tracker trusera.Tracker = rec
+
+	tracker.Track(trusera.NewEvent(trusera.EventToolCall, "calculator"))
+	id, err := tracker.TrackSync(context.Background(), trusera.NewEvent(trusera.EventDecision, "approve"))
+	if err != nil || id == "" {
+		t.Fatalf("expected the event ID, got %q, %v", id, err)
+	}
+
+	e := rec.AssertTracked(t, trusera.EventToolCall, "calculator")
+	if e.Name != "calculator" {
+		t.Errorf("expected the matching event, got %+v", e)
+	}
+	rec.AssertNotTracked(t, trusera.EventToolCall, "search")
+	rec.AssertCount(t, trusera.EventDecision, 1)
+
+	rec.Reset()
+	rec.AssertCount(t, trusera.EventToolCall, 0)
+}
+
+func TestNewClient(t *testing.T) {
+	client, rec := NewClient(t, trusera.WithEventHook(func(e *trusera.Event) (*trusera.Event, error) {
+		if e.Name == "secret" {
+			return nil, nil
+		}
+		return e, nil
+	}))
+
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "calculator").WithPayload("input", "2+2"))
+	client.Track(trusera.NewEvent(trusera.EventToolCall, "secret"))
+
+	e := rec.AssertTracked(t, trusera.EventToolCall, "calculator")
+	if e.Payload["input"] != "2+2" {
+		t.Errorf("expected the payload, got %v", e.Payload)
+	}
+	rec.AssertNotTracked(t, trusera.EventToolCall, "secret")
+}
+
+func TestAssertionFailures(t *testing.T) {
+	rec := NewRecorder()
+	rec.Track(trusera.NewEvent(trusera.EventToolCall, "calculator"))
+
+	ft := &fakeT{TB: t}
+	rec.AssertTracked(ft, trusera.EventToolCall, "search")
+	rec.AssertNotTracked(ft, trusera.EventToolCall, "calculator")
+	rec.AssertCount(ft, trusera.EventToolCall, 2)
+
+	if len(ft.failures) != 3 {
+		t.Fatalf("expected 3 failures, got %v", ft.failures)
+	}
+	if !strings.Contains(ft.failures[0], `expected a tool_call event named "search", got [tool_call/calculator]`) {
+		t.Errorf("expected the recorded events in the message, got %q", ft.failures[0])
+	}
+}
@@ -0,0 +1,177 @@
+package trusera
+
+import (
+	"net/http"
+	"time"
+)
+
+// The typed events below build an Event with the payload fields the SDK's
+// own integrations use, so hand-written instrumentation matches them
+// without a chain of WithPayload calls. Zero fields are left out:
+//
+//	client.Track(trusera.ToolCall{Name: "calculator", Args: args, Result: 35}.Event())
+
+// LLMInvocation is a call to a language model
+type LLMInvocation struct {
+	Model            string // Also the event name; "llm" when empty
+	Provider         string // e.g. "openai"
+	Prompt           string
+	Completion       string
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // In USD
+	Latency          time.Duration
+	FinishReason     string
+	Err              error
+}
+
+// Event builds an EventLLMInvoke
+func (l LLMInvocation) Event() Event {
+	name := l.Model
+	if name == "" {
+		name = "llm"
+	}
+	p := typedPayload{}
+	p.str("model", l.Model)
+	p.str("provider", l.Provider)
+	p.str("prompt", l.Prompt)
+	p.str("completion", l.Completion)
+	if l.PromptTokens != 0 || l.CompletionTokens != 0 {
+		p["prompt_tokens"] = l.PromptTokens
+		p["completion_tokens"] = l.CompletionTokens
+		p["total_tokens"] = l.PromptTokens + l.CompletionTokens
+	}
+	if l.Cost != 0 {
+		p["cost_usd"] = l.Cost
+	}
+	p.duration("latency_ms", l.Latency)
+	p.str("finish_reason", l.FinishReason)
+	p.err(l.Err)
+	return p.event(EventLLMInvoke, name)
+}
+
+// ToolCall is the invocation of a tool. Args and Result are validated
+// against the schemas of a tool registered under Name; see RegisterTool.
+type ToolCall struct {
+	Name     string
+	Args     any
+	Result   any
+	Err      error
+	Duration time.Duration
+}
+
+// Event builds an EventToolCall
+func (t ToolCall) Event() Event {
+	p := typedPayload{}
+	p.value("arguments", t.Args)
+	p.value("result", t.Result)
+	p.err(t.Err)
+	p.duration("duration_ms", t.Duration)
+	return p.event(EventToolCall, t.Name)
+}
+
+// DataAccess is a read or write of a data store
+type DataAccess struct {
+	Name      string // e.g. "load_orders"
+	Database  string
+	Operation string // e.g. "select"
+	Query     string
+	Rows      int
+	Err       error
+	Duration  time.Duration
+}
+
+// Event builds an EventDataAccess
+func (d DataAccess) Event() Event {
+	p := typedPayload{}
+	p.str("database", d.Database)
+	p.str("operation", d.Operation)
+	p.str("query", d.Query)
+	if d.Rows != 0 {
+		p["rows"] = d.Rows
+	}
+	p.err(d.Err)
+	p.duration("duration_ms", d.Duration)
+	return p.event(EventDataAccess, d.Name)
+}
+
+// APICall is an outbound API request, for clients the HTTP interceptor
+// cannot wrap
+type APICall struct {
+	Method     string // GET when empty
+	URL        string
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+}
+
+// Event builds an EventAPICall named like the interceptor's, "METHOD URL"
+func (a APICall) Event() Event {
+	method := a.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	p := typedPayload{"method": method, "url": a.URL}
+	if a.StatusCode != 0 {
+		p["status_code"] = a.StatusCode
+	}
+	p.err(a.Err)
+	p.duration("duration_ms", a.Duration)
+	return p.event(EventAPICall, method+" "+a.URL)
+}
+
+// Decision is a choice the agent made
+type Decision struct {
+	Name       string // e.g. "approve_purchase"
+	Outcome    string // e.g. "approved"
+	Confidence float64
+	Reasoning  string
+	Inputs     map[string]any // What the decision was based on
+}
+
+// Event builds an EventDecision
+func (d Decision) Event() Event {
+	p := typedPayload{}
+	p.str("outcome", d.Outcome)
+	if d.Confidence != 0 {
+		p["confidence"] = d.Confidence
+	}
+	p.str("reasoning", d.Reasoning)
+	if len(d.Inputs) > 0 {
+		p["inputs"] = d.Inputs
+	}
+	return p.event(EventDecision, d.Name)
+}
+
+// typedPayload collects the non-zero fields of a typed event
+type typedPayload map[string]any
+
+func (p typedPayload) str(key, value string) {
+	if value != "" {
+		p[key] = value
+	}
+}
+
+func (p typedPayload) value(key string, value any) {
+	if value != nil {
+		p[key] = value
+	}
+}
+
+func (p typedPayload) duration(key string, d time.Duration) {
+	if d != 0 {
+		p[key] = float64(d.Microseconds()) / 1000
+	}
+}
+
+func (p typedPayload) err(err error) {
+	if err != nil {
+		p["error"] = err.Error()
+	}
+}
+
+func (p typedPayload) event(eventType EventType, name string) Event {
+	event := NewEvent(eventType, name)
+	event.Payload = p
+	return event
+}
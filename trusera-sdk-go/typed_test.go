@@ -0,0 +1,93 @@
+package trusera
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypedEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     Event
+		eventType EventType
+		eventName string
+		payload   map[string]any
+	}{
+		{
+			name: "llm invocation",
+			event: LLMInvocation{
+				Model: "gpt-4o", Provider: "openai", PromptTokens: 150, CompletionTokens: 75,
+				Cost: 0.0045, Latency: 1500 * time.Millisecond, FinishReason: "stop",
+			}.Event(),
+			eventType: EventLLMInvoke,
+			eventName: "gpt-4o",
+			payload: map[string]any{
+				"model": "gpt-4o", "provider": "openai", "prompt_tokens": 150, "completion_tokens": 75,
+				"total_tokens": 225, "cost_usd": 0.0045, "latency_ms": 1500.0, "finish_reason": "stop",
+			},
+		},
+		{
+			name:      "failed llm invocation",
+			event:     LLMInvocation{Err: errors.New("rate limited")}.Event(),
+			eventType: EventLLMInvoke,
+			eventName: "llm",
+			payload:   map[string]any{"error": "rate limited"},
+		},
+		{
+			name:      "tool call",
+			event:     ToolCall{Name: "calculator", Args: []int{5, 7}, Result: 35, Duration: time.Millisecond}.Event(),
+			eventType: EventToolCall,
+			eventName: "calculator",
+			payload:   map[string]any{"arguments": []int{5, 7}, "result": 35, "duration_ms": 1.0},
+		},
+		{
+			name:      "data access",
+			event:     DataAccess{Name: "load_orders", Database: "postgres", Operation: "select", Rows: 3}.Event(),
+			eventType: EventDataAccess,
+			eventName: "load_orders",
+			payload:   map[string]any{"database": "postgres", "operation": "select", "rows": 3},
+		},
+		{
+			name:      "api call",
+			event:     APICall{URL: "https://api.stripe.com/v1/refunds", StatusCode: 200}.Event(),
+			eventType: EventAPICall,
+			eventName: "GET https://api.stripe.com/v1/refunds",
+			payload:   map[string]any{"method": "GET", "url": "https://api.stripe.com/v1/refunds", "status_code": 200},
+		},
+		{
+			name:      "decision",
+			event:     Decision{Name: "approve_purchase", Outcome: "approved", Confidence: 0.95}.Event(),
+			eventType: EventDecision,
+			eventName: "approve_purchase",
+			payload:   map[string]any{"outcome": "approved", "confidence": 0.95},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.event.Type != tt.eventType || tt.event.Name != tt.eventName {
+				t.Errorf("expected %s %q, got %s %q", tt.eventType, tt.eventName, tt.event.Type, tt.event.Name)
+			}
+			if tt.event.ID == "" || tt.event.Timestamp == "" {
+				t.Error("expected an ID and a timestamp")
+			}
+			if !reflect.DeepEqual(map[string]any(tt.event.Payload), tt.payload) {
+				t.Errorf("expected payload %v, got %v", tt.payload, tt.event.Payload)
+			}
+		})
+	}
+}
+
+func TestTypedEventIsCheckedLikeAnyOther(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}))
+	defer client.Close()
+	client.RegisterTool(ToolInfo{Name: "refund", InputSchema: json.RawMessage(`{"required": ["amount"]}`)})
+
+	client.Track(ToolCall{Name: "refund", Args: map[string]any{"order": "A1"}}.Event())
+	if e, _ := trackedEvent(client, "refund"); e.Payload["schema_violations"] == nil {
+		t.Errorf("expected the arguments to be validated, got %v", e.Payload)
+	}
+}
@@ -0,0 +1,85 @@
+package trusera
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Usage is the event volume and LLM spend the API has recorded for the
+// current billing period, and the quotas they count against
+type Usage struct {
+	PeriodStart  time.Time `json:"period_start"`
+	PeriodEnd    time.Time `json:"period_end"`
+	Events       int64     `json:"events"`                   // Events ingested this period
+	EventQuota   int64     `json:"event_quota,omitempty"`    // Zero when unlimited
+	CostUSD      float64   `json:"cost_usd"`                 // LLM spend reported this period
+	CostQuotaUSD float64   `json:"cost_quota_usd,omitempty"` // Zero when unlimited
+}
+
+// EventsRemaining returns how many more events the quota allows this
+// period, or -1 if it is unlimited
+func (u Usage) EventsRemaining() int64 {
+	if u.EventQuota <= 0 {
+		return -1
+	}
+	return max(u.EventQuota-u.Events, 0)
+}
+
+// CostRemaining returns how many more USD the quota allows this period, or
+// -1 if it is unlimited
+func (u Usage) CostRemaining() float64 {
+	if u.CostQuotaUSD <= 0 {
+		return -1
+	}
+	return max(u.CostQuotaUSD-u.CostUSD, 0)
+}
+
+// GetUsage fetches the current period's usage from the API, so an agent can
+// adapt as it nears a quota, e.g. by switching to a cheaper model. It is
+// scoped to the client's agent and project, when set. Events still in the
+// buffer are not counted.
+func (c *Client) GetUsage(ctx context.Context) (Usage, error) {
+	c.mu.Lock()
+	agentID := c.agentID
+	c.mu.Unlock()
+
+	endpoint, err := c.endpoint("/v1/usage")
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	query := url.Values{}
+	if agentID != "" {
+		query.Set("agent_id", agentID)
+	}
+	if c.project != "" {
+		query.Set("project_id", c.project)
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to fetch usage: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Usage{}, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	var u Usage
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&u); err != nil {
+		return Usage{}, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return u, nil
+}
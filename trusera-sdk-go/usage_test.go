@@ -0,0 +1,53 @@
+package trusera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetUsage(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/usage" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write([]byte(`{"period_start":"2026-10-01T00:00:00Z","period_end":"2026-11-01T00:00:00Z",
+			"events":1200,"event_quota":1000,"cost_usd":12.5,"cost_quota_usd":0}`))
+	}))
+	defer server.Close()
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour),
+		WithAgentID("agent-1"), WithProject("billing"))
+	defer client.Close()
+
+	usage, err := client.GetUsage(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get usage: %v", err)
+	}
+	if query != "agent_id=agent-1&project_id=billing" {
+		t.Errorf("expected the usage scoped to the agent and project, got %q", query)
+	}
+	if usage.Events != 1200 || usage.CostUSD != 12.5 || usage.PeriodEnd.Month() != time.November {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+	if usage.EventsRemaining() != 0 || usage.CostRemaining() != -1 {
+		t.Errorf("expected no events and unlimited cost remaining, got %d and %v", usage.EventsRemaining(), usage.CostRemaining())
+	}
+}
+
+func TestGetUsageError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	client := NewClient("test-key", WithBaseURL(server.URL), WithFlushInterval(time.Hour))
+	defer client.Close()
+
+	if _, err := client.GetUsage(context.Background()); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
@@ -0,0 +1,350 @@
+package trusera
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DialContextFunc matches net.Dialer.DialContext
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WebSocketInterceptor tracks websocket connections and applies enforcement to
+// the upgrade URL. It works with any websocket library by wrapping either the
+// network connection (for dialers such as gorilla/websocket) or the HTTP
+// transport (for libraries that upgrade through an http.Client).
+type WebSocketInterceptor struct {
+	t *interceptingTransport
+}
+
+// NewWebSocketInterceptor creates a websocket interceptor using the same
+// options as WrapHTTPClient
+func NewWebSocketInterceptor(truseraClient *Client, opts InterceptorOptions) *WebSocketInterceptor {
+	return &WebSocketInterceptor{t: &interceptingTransport{client: truseraClient, opts: opts}}
+}
+
+// Check records a connection attempt to rawURL and returns an error if the
+// URL is blocked in block mode
+func (w *WebSocketInterceptor) Check(ctx context.Context, rawURL string) error {
+	v := w.t.evaluateRequest(http.MethodGet, rawURL)
+	if v.excluded {
+		return nil
+	}
+	v = w.t.canary(ctx, rawURL, v)
+	v = w.t.limitCalls(ctx, rawURL, v)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	req := (&http.Request{Method: http.MethodGet, URL: u, Header: http.Header{}}).WithContext(ctx)
+
+	blocked := v.blocked
+	policyReasons := w.t.policyReasons(req)
+	if len(policyReasons) > 0 {
+		blocked = true
+	}
+
+	event := NewEvent(EventAPICall, "websocket "+rawURL).
+		WithPayload("protocol", "websocket").
+		WithPayload("url", rawURL).
+		WithPayload("blocked", blocked)
+	event = v.describe(event)
+
+	if len(policyReasons) > 0 {
+		event = event.WithPayload("policy_reasons", policyReasons)
+	}
+
+	w.t.logDecision(ctx, "websocket", http.MethodGet, rawURL, v, blocked, blockCause{BlockReasonPolicy, policyReasons})
+	if blocked {
+		event = event.WithPayload("enforcement_action", v.mode.blockAction())
+
+		switch v.mode {
+		case ModeBlock, ModeAllowList:
+			w.t.client.Track(event)
+			return v.blockedError(rawURL, blockCause{BlockReasonPolicy, policyReasons})
+		case ModeWarn:
+			event = event.WithMetadata("warning", "websocket URL matches block pattern but allowed in warn mode")
+		}
+	} else {
+		event = event.WithPayload("enforcement_action", "allowed")
+	}
+
+	w.t.client.Track(event)
+	return nil
+}
+
+// DialContext returns a dial function for connecting to rawURL. It enforces
+// policy before dialing and counts websocket frames on the returned connection.
+// For gorilla/websocket set it as Dialer.NetDialContext for ws:// URLs, or as
+// Dialer.NetDialTLSContext with a TLS dial function for wss:// URLs so that
+// frames are observed after decryption. A nil dial uses net.Dialer.
+func (w *WebSocketInterceptor) DialContext(rawURL string, dial DialContextFunc) DialContextFunc {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := w.Check(ctx, rawURL); err != nil {
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if w.t.evaluateRequest(http.MethodGet, rawURL).excluded {
+			return conn, nil
+		}
+
+		stats := newWebSocketStats(w.t.client, rawURL, true)
+		return &wsConn{Conn: conn, stats: stats}, nil
+	}
+}
+
+// WrapTransport returns a RoundTripper for websocket libraries that perform
+// the upgrade through an http.Client. Upgrade requests are enforced and the
+// frames exchanged over a 101 Switching Protocols response are counted.
+func (w *WebSocketInterceptor) WrapTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &wsTransport{base: base, w: w}
+}
+
+type wsTransport struct {
+	base http.RoundTripper
+	w    *WebSocketInterceptor
+}
+
+// RoundTrip enforces policy on upgrade requests and wraps the upgraded stream
+func (t *wsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rawURL := req.URL.String()
+	if err := t.w.Check(req.Context(), rawURL); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols || t.w.t.evaluateRequest(http.MethodGet, rawURL).excluded {
+		return resp, err
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return resp, nil
+	}
+
+	resp.Body = &wsBody{rwc: rwc, stats: newWebSocketStats(t.w.t.client, rawURL, false)}
+	return resp, nil
+}
+
+// wsBody counts frames on an upgraded HTTP response body
+type wsBody struct {
+	rwc   io.ReadWriteCloser
+	stats *webSocketStats
+}
+
+func (b *wsBody) Read(p []byte) (int, error) {
+	n, err := b.rwc.Read(p)
+	b.stats.received(p[:n])
+	return n, err
+}
+
+func (b *wsBody) Write(p []byte) (int, error) {
+	n, err := b.rwc.Write(p)
+	b.stats.sent(p[:n])
+	return n, err
+}
+
+func (b *wsBody) Close() error {
+	err := b.rwc.Close()
+	b.stats.close()
+	return err
+}
+
+// wsConn counts frames on a dialed network connection
+type wsConn struct {
+	net.Conn
+	stats *webSocketStats
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.stats.received(p[:n])
+	return n, err
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.stats.sent(p[:n])
+	return n, err
+}
+
+func (c *wsConn) Close() error {
+	err := c.Conn.Close()
+	c.stats.close()
+	return err
+}
+
+// webSocketStats aggregates per-connection counters and reports them on close
+type webSocketStats struct {
+	client   *Client
+	url      string
+	start    time.Time
+	mu       sync.Mutex
+	in       wsFrameCounter
+	out      wsFrameCounter
+	closed   bool
+	closeMsg bool
+}
+
+func newWebSocketStats(client *Client, rawURL string, handshake bool) *webSocketStats {
+	return &webSocketStats{
+		client: client,
+		url:    rawURL,
+		start:  time.Now(),
+		in:     wsFrameCounter{inHandshake: handshake},
+		out:    wsFrameCounter{inHandshake: handshake},
+	}
+}
+
+func (s *webSocketStats) received(p []byte) {
+	s.mu.Lock()
+	s.in.feed(p)
+	s.mu.Unlock()
+}
+
+func (s *webSocketStats) sent(p []byte) {
+	s.mu.Lock()
+	s.out.feed(p)
+	s.mu.Unlock()
+}
+
+// close emits the connection summary event once
+func (s *webSocketStats) close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	event := NewEvent(EventAPICall, "websocket_close").
+		WithPayload("protocol", "websocket").
+		WithPayload("url", s.url).
+		WithPayload("messages_sent", s.out.messages).
+		WithPayload("messages_received", s.in.messages).
+		WithPayload("bytes_sent", s.out.payloadBytes).
+		WithPayload("bytes_received", s.in.payloadBytes).
+		WithPayload("close_frame_sent", s.out.closeFrames > 0).
+		WithPayload("close_frame_received", s.in.closeFrames > 0).
+		WithPayload("duration_ms", float64(time.Since(s.start).Microseconds())/1000)
+	s.mu.Unlock()
+
+	s.client.Track(event)
+}
+
+// wsFrameCounter incrementally parses RFC 6455 frame headers from one direction
+// of a connection, counting complete data messages and payload bytes
+type wsFrameCounter struct {
+	inHandshake  bool   // skip the HTTP upgrade exchange first
+	tail         []byte // handshake bytes kept to match \r\n\r\n across reads
+	header       []byte // partially received frame header
+	remaining    uint64 // payload bytes left in the current frame
+	messages     int
+	payloadBytes uint64
+	closeFrames  int
+}
+
+var handshakeEnd = []byte("\r\n\r\n")
+
+func (f *wsFrameCounter) feed(p []byte) {
+	if f.inHandshake {
+		buf := append(f.tail, p...)
+		idx := bytes.Index(buf, handshakeEnd)
+		if idx < 0 {
+			if len(buf) > len(handshakeEnd)-1 {
+				buf = buf[len(buf)-(len(handshakeEnd)-1):]
+			}
+			f.tail = append(f.tail[:0], buf...)
+			return
+		}
+		f.inHandshake = false
+		f.tail = nil
+		p = buf[idx+len(handshakeEnd):]
+	}
+
+	for len(p) > 0 {
+		if f.remaining > 0 {
+			n := f.remaining
+			if uint64(len(p)) < n {
+				n = uint64(len(p))
+			}
+			f.remaining -= n
+			p = p[n:]
+			continue
+		}
+
+		f.header = append(f.header, p[0])
+		p = p[1:]
+		f.parseHeader()
+	}
+}
+
+// parseHeader completes the current frame header once enough bytes are buffered
+func (f *wsFrameCounter) parseHeader() {
+	if len(f.header) < 2 {
+		return
+	}
+
+	need := 2
+	lenByte := f.header[1] & 0x7F
+	switch lenByte {
+	case 126:
+		need += 2
+	case 127:
+		need += 8
+	}
+	masked := f.header[1]&0x80 != 0
+	if masked {
+		need += 4
+	}
+	if len(f.header) < need {
+		return
+	}
+
+	var length uint64
+	switch lenByte {
+	case 126:
+		length = uint64(binary.BigEndian.Uint16(f.header[2:4]))
+	case 127:
+		length = binary.BigEndian.Uint64(f.header[2:10])
+	default:
+		length = uint64(lenByte)
+	}
+
+	fin := f.header[0]&0x80 != 0
+	opcode := f.header[0] & 0x0F
+
+	switch {
+	case opcode == 0x8:
+		f.closeFrames++
+	case opcode >= 0x8:
+		// ping/pong control frames are not messages
+	case fin:
+		f.messages++
+	}
+	if opcode < 0x8 {
+		f.payloadBytes += length
+	}
+
+	f.remaining = length
+	f.header = f.header[:0]
+}
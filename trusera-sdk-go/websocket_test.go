@@ -0,0 +1,213 @@
+package trusera
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wsFrame builds a websocket frame with an optional client mask
+func wsFrame(opcode byte, fin, masked bool, payload []byte) []byte {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+
+	if masked {
+		key := []byte{1, 2, 3, 4}
+		frame = append(frame, key...)
+		for i, b := range payload {
+			frame = append(frame, b^key[i%4])
+		}
+		return frame
+	}
+	return append(frame, payload...)
+}
+
+func lastEvent(c *Client) Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.events[len(c.events)-1]
+}
+
+func TestWSFrameCounter(t *testing.T) {
+	var stream []byte
+	stream = append(stream, []byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n")...)
+	stream = append(stream, wsFrame(0x1, true, false, []byte("hello"))...)
+	stream = append(stream, wsFrame(0x2, false, false, make([]byte, 300))...)
+	stream = append(stream, wsFrame(0x0, true, false, make([]byte, 70000))...)
+	stream = append(stream, wsFrame(0x9, true, false, []byte("ping"))...)
+	stream = append(stream, wsFrame(0x8, true, false, []byte{0x03, 0xE8})...)
+
+	// Feed one byte at a time to exercise partial headers and handshake splits
+	counter := wsFrameCounter{inHandshake: true}
+	for i := range stream {
+		counter.feed(stream[i : i+1])
+	}
+
+	if counter.messages != 2 {
+		t.Errorf("expected 2 messages, got %d", counter.messages)
+	}
+
+	if counter.payloadBytes != 5+300+70000 {
+		t.Errorf("expected %d payload bytes, got %d", 5+300+70000, counter.payloadBytes)
+	}
+
+	if counter.closeFrames != 1 {
+		t.Errorf("expected 1 close frame, got %d", counter.closeFrames)
+	}
+}
+
+func TestWebSocketDialContext(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	ws := NewWebSocketInterceptor(client, InterceptorOptions{Enforcement: ModeBlock})
+
+	clientSide, serverSide := net.Pipe()
+	dial := ws.DialContext("ws://agent.example.com/realtime", func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return clientSide, nil
+	})
+
+	conn, err := dial(context.Background(), "tcp", "agent.example.com:80")
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+
+	go func() {
+		reader := bufio.NewReader(serverSide)
+		// Consume the client handshake and one masked frame
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				break
+			}
+		}
+		io.ReadFull(reader, make([]byte, len(wsFrame(0x1, true, true, []byte("hi")))))
+
+		serverSide.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n\r\n"))
+		serverSide.Write(wsFrame(0x1, true, false, []byte("reply-1")))
+		serverSide.Write(wsFrame(0x1, true, false, []byte("reply-2")))
+		serverSide.Close()
+	}()
+
+	conn.Write([]byte("GET /realtime HTTP/1.1\r\nHost: agent.example.com\r\nUpgrade: websocket\r\n\r\n"))
+	conn.Write(wsFrame(0x1, true, true, []byte("hi")))
+	io.ReadAll(conn)
+	conn.Close()
+
+	summary := lastEvent(client)
+	if summary.Name != "websocket_close" {
+		t.Fatalf("expected websocket_close event, got %s", summary.Name)
+	}
+
+	if summary.Payload["messages_sent"] != 1 {
+		t.Errorf("expected 1 message sent, got %v", summary.Payload["messages_sent"])
+	}
+
+	if summary.Payload["messages_received"] != 2 {
+		t.Errorf("expected 2 messages received, got %v", summary.Payload["messages_received"])
+	}
+}
+
+func TestWebSocketBlocked(t *testing.T) {
+	client := NewClient("test-key")
+	defer client.Close()
+
+	ws := NewWebSocketInterceptor(client, InterceptorOptions{
+		Enforcement:   ModeBlock,
+		BlockPatterns: []string{"evil.example.com"},
+	})
+
+	dialed := false
+	dial := ws.DialContext("wss://evil.example.com/socket", func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = true
+		return nil, nil
+	})
+
+	if _, err := dial(context.Background(), "tcp", "evil.example.com:443"); err == nil {
+		t.Error("expected blocked websocket dial")
+	}
+
+	if dialed {
+		t.Error("underlying dialer should not be called for blocked URL")
+	}
+
+	if err := ws.Check(context.Background(), "wss://fine.example.com/socket"); err != nil {
+		t.Errorf("unexpected error for allowed URL: %v", err)
+	}
+}
+
+func TestWebSocketWrapTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Write(wsFrame(0x1, true, false, []byte(`{"type":"session.created"}`)))
+		rw.Flush()
+
+		io.ReadFull(rw, make([]byte, len(wsFrame(0x1, true, true, []byte("ping")))))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	defer client.Close()
+
+	ws := NewWebSocketInterceptor(client, InterceptorOptions{})
+	httpClient := &http.Client{Transport: ws.WrapTransport(nil)}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/realtime", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("upgrade failed: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	rwc := resp.Body.(io.ReadWriter)
+	buf := make([]byte, 64)
+	n, _ := rwc.Read(buf)
+	if !strings.Contains(string(buf[:n]), "session.created") {
+		t.Errorf("unexpected frame payload: %q", buf[:n])
+	}
+	rwc.Write(wsFrame(0x1, true, true, []byte("ping")))
+	resp.Body.Close()
+
+	summary := lastEvent(client)
+	if summary.Payload["messages_received"] != 1 || summary.Payload["messages_sent"] != 1 {
+		t.Errorf("unexpected counts: %v", summary.Payload)
+	}
+}
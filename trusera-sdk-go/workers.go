@@ -0,0 +1,77 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+)
+
+// WithFlushWorkers sends up to n batches of a flush in parallel, for agents
+// that track events faster than one request at a time can deliver them.
+// The events of a session, per their session_id metadata, are always sent
+// by the same worker, in the order they were tracked; other events are
+// spread across the workers in runs of at least a batch. A worker stops at
+// its first failed request, leaving the other workers' batches unaffected.
+// The default is a single worker. WithOrderedDelivery always uses one.
+func WithFlushWorkers(n int) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.flushWorkers = n
+		}
+	}
+}
+
+// deliverConcurrently splits events among the flush workers and delivers
+// each share in order
+func (c *Client) deliverConcurrently(ctx context.Context, events []Event) (undelivered []Event, sendErr, exportErr error) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, share := range c.partition(events) {
+		if len(share) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(share []Event) {
+			defer wg.Done()
+			u, s, e := c.deliverInOrder(ctx, share)
+			mu.Lock()
+			defer mu.Unlock()
+			undelivered = append(undelivered, u...)
+			sendErr = errors.Join(sendErr, s)
+			exportErr = errors.Join(exportErr, e)
+		}(share)
+	}
+	wg.Wait()
+	return undelivered, sendErr, exportErr
+}
+
+// partition splits events into one share per flush worker. A session's
+// events share a worker; the rest fill the workers in runs of at least a
+// batch, so small flushes still go out in one request.
+func (c *Client) partition(events []Event) [][]Event {
+	n := c.flushWorkers
+	shares := make([][]Event, n)
+	var loose []Event
+	for _, e := range events {
+		id, _ := e.Metadata["session_id"].(string)
+		if id == "" {
+			loose = append(loose, e)
+			continue
+		}
+		h := fnv.New32a()
+		h.Write([]byte(id))
+		i := h.Sum32() % uint32(n)
+		shares[i] = append(shares[i], e)
+	}
+
+	run := max(c.flushSize, (len(loose)+n-1)/n)
+	for i := 0; len(loose) > 0; i++ {
+		k := min(run, len(loose))
+		shares[i] = append(shares[i], loose[:k]...)
+		loose = loose[k:]
+	}
+	return shares
+}
@@ -0,0 +1,106 @@
+package trusera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowSink records batches after a delay, failing those of one session
+type slowSink struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	failing  string
+	received map[string][]string // Event names per session
+}
+
+func (s *slowSink) Send(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inFlight--
+	for _, e := range events {
+		if id, _ := e.Metadata["session_id"].(string); id != "" && id == s.failing {
+			return errors.New("sink unavailable")
+		}
+	}
+	for _, e := range events {
+		id, _ := e.Metadata["session_id"].(string)
+		s.received[id] = append(s.received[id], e.Name)
+	}
+	return nil
+}
+
+func TestFlushWorkersPreserveSessionOrder(t *testing.T) {
+	sink := &slowSink{received: map[string][]string{}}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink),
+		WithFlushWorkers(4), WithBatchSize(2))
+	defer client.Close()
+
+	for i := 0; i < 5; i++ {
+		for _, session := range []string{"a", "b", "c", "d"} {
+			client.Track(NewEvent(EventToolCall, fmt.Sprintf("%s-%d", session, i)).WithMetadata("session_id", session))
+		}
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.peak < 2 {
+		t.Errorf("expected batches sent in parallel, peak was %d", sink.peak)
+	}
+	for _, session := range []string{"a", "b", "c", "d"} {
+		want := fmt.Sprintf("[%[1]s-0 %[1]s-1 %[1]s-2 %[1]s-3 %[1]s-4]", session)
+		if got := fmt.Sprint(sink.received[session]); got != want {
+			t.Errorf("expected session %s in order %s, got %s", session, want, got)
+		}
+	}
+}
+
+func TestFlushWorkersIsolateFailures(t *testing.T) {
+	sink := &slowSink{received: map[string][]string{}, failing: "a"}
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(sink), WithFlushWorkers(8))
+	defer client.Close()
+
+	client.Track(NewEvent(EventToolCall, "a-0").WithMetadata("session_id", "a"))
+	client.Track(NewEvent(EventToolCall, "b-0").WithMetadata("session_id", "b"))
+	client.Track(NewEvent(EventToolCall, "loose"))
+
+	if err := client.Flush(); err == nil {
+		t.Error("expected the failing session's batch to fail the flush")
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.received["a"]) != 0 || len(sink.received["b"]) != 1 || len(sink.received[""]) != 1 {
+		t.Errorf("expected only session a lost, got %v", sink.received)
+	}
+	if n := client.Stats().EventsDroppedFlush; n != 1 {
+		t.Errorf("expected 1 event dropped, got %d", n)
+	}
+}
+
+func TestPartitionKeepsSmallFlushesTogether(t *testing.T) {
+	client := NewClient("test-key", WithFlushInterval(time.Hour), WithPrimarySink(&memorySink{}), WithFlushWorkers(4))
+	defer client.Close()
+
+	events := make([]Event, 10)
+	for i := range events {
+		events[i] = NewEvent(EventToolCall, "loose")
+	}
+	shares := client.partition(events)
+	if len(shares[0]) != 10 {
+		t.Errorf("expected a flush smaller than a batch kept in one share, got %d", len(shares[0]))
+	}
+}
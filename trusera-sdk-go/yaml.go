@@ -0,0 +1,385 @@
+package trusera
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// decodeYAML parses the subset of YAML used by configuration files: block
+// mappings and sequences, flow collections on a single line, quoted and
+// plain scalars, and comments. Anchors, tags, block scalars and multiple
+// documents are not supported. Mappings decode to map[string]any and
+// sequences to []any, as with encoding/json.
+func decodeYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text[0] == '#' || (text == "---" && len(p.lines) == 0) {
+			continue
+		}
+		if text[0] == '\t' {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(raw) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	line := p.lines[len(p.lines)-1].num
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].num
+	}
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// block parses the mapping or sequence starting at the current line
+func (p *yamlParser) block(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := splitKey(p.lines[p.pos].text); ok {
+		return p.mapping(indent)
+	}
+	line := p.lines[p.pos]
+	p.pos++
+	return line.value(line.text)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitKey(line.text)
+		if !ok {
+			return nil, p.errorf("expected a key")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		var (
+			v   any
+			err error
+		)
+		switch {
+		case rest != "":
+			v, err = line.value(rest)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			v, err = p.block(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text):
+			// A sequence may sit at the same indentation as its key
+			v, err = p.sequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	s := []any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" || rest[0] == '#' {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				v, err := p.block(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				s = append(s, v)
+			} else {
+				s = append(s, nil)
+			}
+			continue
+		}
+
+		// Reparse the rest of the line as a nested block, e.g. the first
+		// key of a mapping, indented where it starts
+		p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.text) - len(rest), text: rest}
+		v, err := p.block(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, v)
+	}
+	return s, nil
+}
+
+// value parses an inline value on the line
+func (l yamlLine) value(text string) (any, error) {
+	v, err := yamlValue(text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", l.num, err)
+	}
+	return v, nil
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey splits a "key: value" line, reporting false if it is not one
+func splitKey(text string) (key, rest string, ok bool) {
+	var end int
+	switch text[0] {
+	case '"', '\'':
+		n, err := quotedEnd(text)
+		if err != nil {
+			return "", "", false
+		}
+		if key, err = unquoteYAML(text[:n]); err != nil {
+			return "", "", false
+		}
+		end = n
+		if !strings.HasPrefix(text[end:], ":") {
+			return "", "", false
+		}
+	case '[', '{', '#':
+		return "", "", false
+	default:
+		i := strings.Index(text, ": ")
+		if i < 0 {
+			if !strings.HasSuffix(text, ":") {
+				return "", "", false
+			}
+			i = len(text) - 1
+		}
+		if j := strings.Index(text, " #"); j >= 0 && j < i {
+			return "", "", false
+		}
+		key, end = text[:i], i
+	}
+	rest = text[end+1:]
+	if rest != "" && rest[0] != ' ' {
+		return "", "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "#") {
+		rest = ""
+	}
+	return key, rest, true
+}
+
+// yamlValue parses an inline value: a flow collection or a scalar, with an
+// optional trailing comment
+func yamlValue(text string) (any, error) {
+	switch text[0] {
+	case '[', '{':
+		f := &flowParser{s: text}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := trailing(text[f.i:]); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case '"', '\'':
+		n, err := quotedEnd(text)
+		if err != nil {
+			return nil, err
+		}
+		if err := trailing(text[n:]); err != nil {
+			return nil, err
+		}
+		return unquoteYAML(text[:n])
+	case '|', '>':
+		return nil, errors.New("block scalars are not supported; use a quoted string")
+	case '&', '*', '!':
+		return nil, errors.New("anchors, aliases and tags are not supported")
+	}
+	if i := strings.Index(text, " #"); i >= 0 {
+		text = strings.TrimSpace(text[:i])
+	}
+	return plainScalar(text), nil
+}
+
+// trailing checks that only a comment follows a value
+func trailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && rest[0] != '#' {
+		return fmt.Errorf("unexpected %q after value", rest)
+	}
+	return nil
+}
+
+// quotedEnd returns the length of the quoted string text starts with
+func quotedEnd(text string) (int, error) {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case text[i] == q && q == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++ // '' is an escaped quote
+		case text[i] == q:
+			return i + 1, nil
+		}
+	}
+	return 0, errors.New("unterminated quoted string")
+}
+
+func unquoteYAML(s string) (string, error) {
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s", s)
+	}
+	return u, nil
+}
+
+var yamlNumber = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?$`)
+
+// plainScalar resolves an unquoted scalar to null, a boolean, a number or a
+// string
+func plainScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlNumber.MatchString(s) {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// flowParser parses a single-line flow collection such as [a, "b"] or
+// {k: v}
+type flowParser struct {
+	s string
+	i int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *flowParser) value() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, errors.New("unterminated flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		s := []any{}
+		err := f.items(']', func() error {
+			v, err := f.value()
+			s = append(s, v)
+			return err
+		})
+		return s, err
+	case '{':
+		m := make(map[string]any)
+		err := f.items('}', func() error {
+			key, err := f.scalar(":")
+			if err != nil {
+				return err
+			}
+			k := fmt.Sprint(key)
+			f.skipSpace()
+			if f.i >= len(f.s) || f.s[f.i] != ':' {
+				return fmt.Errorf("expected ':' after key %q", k)
+			}
+			f.i++
+			m[k], err = f.value()
+			return err
+		})
+		return m, err
+	}
+	return f.scalar("")
+}
+
+// items parses comma-separated items up to the closing delimiter
+func (f *flowParser) items(closing byte, item func() error) error {
+	f.i++ // opening delimiter
+	f.skipSpace()
+	if f.i < len(f.s) && f.s[f.i] == closing {
+		f.i++
+		return nil
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return errors.New("unterminated flow collection")
+		}
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+		case closing:
+			f.i++
+			return nil
+		default:
+			return fmt.Errorf("unexpected %q in flow collection", f.s[f.i])
+		}
+	}
+}
+
+// scalar parses a quoted or plain scalar inside a flow collection. Plain
+// scalars end at a comma, a closing delimiter or any byte in stop.
+func (f *flowParser) scalar(stop string) (any, error) {
+	f.skipSpace()
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		n, err := quotedEnd(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		s, err := unquoteYAML(f.s[f.i : f.i+n])
+		f.i += n
+		return s, err
+	}
+	start := f.i
+	for f.i < len(f.s) && !strings.ContainsRune(",]}"+stop, rune(f.s[f.i])) {
+		f.i++
+	}
+	return plainScalar(strings.TrimSpace(f.s[start:f.i])), nil
+}
@@ -0,0 +1,76 @@
+package trusera
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	doc := `---
+name: agent # trailing comment
+count: 3
+ratio: 0.5
+enabled: true
+nothing: ~
+url: https://api.trusera.io/v1
+quoted: "a: b # not a comment"
+single: 'it''s'
+flow: [one, "two", 3]
+inline: {a: 1, b: [x, y]}
+nested:
+  deeper:
+    key: value
+list:
+- plain
+- key: k1
+  other: o1
+-
+  key: k2
+items:
+  - [1, 2]
+  - - inner
+`
+	got, err := decodeYAML([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":    "agent",
+		"count":   int64(3),
+		"ratio":   0.5,
+		"enabled": true,
+		"nothing": nil,
+		"url":     "https://api.trusera.io/v1",
+		"quoted":  "a: b # not a comment",
+		"single":  "it's",
+		"flow":    []any{"one", "two", int64(3)},
+		"inline":  map[string]any{"a": int64(1), "b": []any{"x", "y"}},
+		"nested":  map[string]any{"deeper": map[string]any{"key": "value"}},
+		"list": []any{
+			"plain",
+			map[string]any{"key": "k1", "other": "o1"},
+			map[string]any{"key": "k2"},
+		},
+		"items": []any{[]any{int64(1), int64(2)}, []any{"inner"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected\n%#v\ngot\n%#v", want, got)
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	for doc, want := range map[string]string{
+		"a: 1\na: 2\n":          `line 2: duplicate key "a"`,
+		"a:\n  b: 1\n   c: 2\n": "line 3: unexpected indentation",
+		"a: \"open\n":           "line 1: unterminated quoted string",
+		"a: [1, 2\n":            "line 1: unterminated flow collection",
+		"a: |\n  text\n":        "block scalars are not supported",
+		"a: *ref\n":             "anchors, aliases and tags are not supported",
+		"a: 1\njust text\n":     "line 2: expected a key",
+	} {
+		if _, err := decodeYAML([]byte(doc)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected an error containing %q, got %v", doc, want, err)
+		}
+	}
+}